@@ -0,0 +1,203 @@
+package blockstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	blocks "github.com/ipfs/go-ipfs/blocks"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	"gx/ipfs/QmRg1gKTHzc3CZXSKzem8aR4E3TubFhbgXwfVuWnSK5CC5/go-metrics-interface"
+	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+)
+
+// denied accesses are logged on their own logger, so they can be audited
+// without turning on the debug output of the blockstore
+var auditlog = logging.Logger("blockstore/deny")
+
+// ErrBlockDenied is returned when accessing a block of the deny list.
+var ErrBlockDenied = errors.New("blockstore: block is on the deny list")
+
+// denyDigestSize is the number of bytes of the sha256 digest kept per entry
+const denyDigestSize = 16
+
+type denyDigest [denyDigestSize]byte
+
+// DenyList is a set of blocks that must never be stored nor served. Entries
+// are identified by the sha256 digest of their multihash, so a list can be
+// distributed without the CIDs it blocks, and both CID versions of a block
+// match the same entry. Only a prefix of the digest is kept in memory.
+type DenyList struct {
+	digests []denyDigest // sorted
+}
+
+// ParseDenyList reads a deny list with one entry per line: a CID, or
+// "sha256:" followed by the hex encoded sha256 digest of a multihash. Empty
+// lines and lines starting with '#' are ignored.
+func ParseDenyList(r io.Reader) (*DenyList, error) {
+	d := new(DenyList)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		var dg denyDigest
+		if strings.HasPrefix(line, "sha256:") {
+			h, err := hex.DecodeString(line[len("sha256:"):])
+			if err != nil || len(h) != sha256.Size {
+				return nil, fmt.Errorf("deny list line %d: invalid sha256 digest", n)
+			}
+			copy(dg[:], h)
+		} else {
+			c, err := cid.Decode(line)
+			if err != nil {
+				return nil, fmt.Errorf("deny list line %d: %s", n, err)
+			}
+			dg = digestFor(c)
+		}
+		d.digests = append(d.digests, dg)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Sort(byDigest(d.digests))
+	return d, nil
+}
+
+// Denies returns whether c is on the list
+func (d *DenyList) Denies(c *cid.Cid) bool {
+	dg := digestFor(c)
+	i := sort.Search(len(d.digests), func(i int) bool {
+		return bytes.Compare(d.digests[i][:], dg[:]) >= 0
+	})
+	return i < len(d.digests) && d.digests[i] == dg
+}
+
+// Len returns the number of entries of the list
+func (d *DenyList) Len() int {
+	return len(d.digests)
+}
+
+func digestFor(c *cid.Cid) denyDigest {
+	var dg denyDigest
+	h := sha256.Sum256(c.Hash())
+	copy(dg[:], h[:])
+	return dg
+}
+
+type byDigest []denyDigest
+
+func (s byDigest) Len() int           { return len(s) }
+func (s byDigest) Less(i, j int) bool { return bytes.Compare(s[i][:], s[j][:]) < 0 }
+func (s byDigest) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// denyBlockstore refuses to store or return the blocks of a deny list.
+// Denied blocks stored before they were added to the list are deleted
+// whenever they are looked up or listed.
+type denyBlockstore struct {
+	Blockstore
+	deny *DenyList
+
+	hits metrics.Counter
+}
+
+// NewDenyBlockstore wraps bs so that the blocks denied by d can never be
+// written to it or read from it.
+func NewDenyBlockstore(ctx context.Context, bs Blockstore, d *DenyList) Blockstore {
+	return &denyBlockstore{
+		Blockstore: bs,
+		deny:       d,
+		hits: metrics.NewCtx(ctx, "deny.hits_total",
+			"Number of accesses to blocks on the deny list").Counter(),
+	}
+}
+
+func (b *denyBlockstore) denied(op string, c *cid.Cid) bool {
+	if c == nil || !b.deny.Denies(c) {
+		return false
+	}
+	b.hits.Inc()
+	auditlog.Warningf("denied %s of block %s", op, c)
+	return true
+}
+
+// purge deletes c from the underlying blockstore, if it was stored there
+func (b *denyBlockstore) purge(c *cid.Cid) {
+	err := b.Blockstore.DeleteBlock(c)
+	switch err {
+	case nil:
+		auditlog.Warningf("deleted stored block %s", c)
+	case ErrNotFound, ds.ErrNotFound:
+	default:
+		auditlog.Errorf("failed to delete denied block %s: %s", c, err)
+	}
+}
+
+func (b *denyBlockstore) Has(c *cid.Cid) (bool, error) {
+	if b.denied("has", c) {
+		b.purge(c)
+		return false, nil
+	}
+	return b.Blockstore.Has(c)
+}
+
+func (b *denyBlockstore) Get(c *cid.Cid) (blocks.Block, error) {
+	if b.denied("get", c) {
+		b.purge(c)
+		return nil, ErrBlockDenied
+	}
+	return b.Blockstore.Get(c)
+}
+
+func (b *denyBlockstore) Put(bl blocks.Block) error {
+	if b.denied("put", bl.Cid()) {
+		return ErrBlockDenied
+	}
+	return b.Blockstore.Put(bl)
+}
+
+// PutMany stores none of the blocks if one of them is denied
+func (b *denyBlockstore) PutMany(bls []blocks.Block) error {
+	for _, bl := range bls {
+		if b.denied("put", bl.Cid()) {
+			return ErrBlockDenied
+		}
+	}
+	return b.Blockstore.PutMany(bls)
+}
+
+func (b *denyBlockstore) AllKeysChan(ctx context.Context) (<-chan *cid.Cid, error) {
+	ch, err := b.Blockstore.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *cid.Cid)
+	go func() {
+		defer close(out)
+		for c := range ch {
+			if b.denied("listing", c) {
+				b.purge(c)
+				continue
+			}
+			select {
+			case out <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
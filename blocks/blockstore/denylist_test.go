@@ -0,0 +1,107 @@
+package blockstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-ipfs/blocks"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	syncds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+)
+
+func TestParseDenyList(t *testing.T) {
+	b1 := blocks.NewBlock([]byte("denied by cid"))
+	b2 := blocks.NewBlock([]byte("denied by digest"))
+	b3 := blocks.NewBlock([]byte("allowed"))
+
+	digest := sha256.Sum256(b2.Cid().Hash())
+	list := "# comment\n\n" + b1.Cid().String() + "\nsha256:" + hex.EncodeToString(digest[:]) + "\n"
+	d, err := ParseDenyList(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", d.Len())
+	}
+
+	if !d.Denies(b1.Cid()) || !d.Denies(b2.Cid()) {
+		t.Fatal("expected the listed blocks to be denied")
+	}
+	if d.Denies(b3.Cid()) {
+		t.Fatal("expected other blocks to be allowed")
+	}
+
+	// entries match the multihash, whatever the cid version
+	v1 := cid.NewCidV1(cid.DagProtobuf, b1.Cid().Hash())
+	if !d.Denies(v1) {
+		t.Fatal("expected the CIDv1 of a denied block to be denied")
+	}
+
+	if _, err := ParseDenyList(strings.NewReader("sha256:abcd\n")); err == nil {
+		t.Fatal("expected a short digest to be rejected")
+	}
+	if _, err := ParseDenyList(strings.NewReader("notacid\n")); err == nil {
+		t.Fatal("expected an invalid cid to be rejected")
+	}
+}
+
+func TestDenyBlockstore(t *testing.T) {
+	denied := blocks.NewBlock([]byte("denied"))
+	allowed := blocks.NewBlock([]byte("allowed"))
+
+	base := NewBlockstore(syncds.MutexWrap(ds.NewMapDatastore()))
+	// stored before the block was denied
+	if err := base.Put(denied); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := ParseDenyList(strings.NewReader(denied.Cid().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs := NewDenyBlockstore(context.Background(), base, d)
+
+	if err := bs.Put(denied); err != ErrBlockDenied {
+		t.Fatalf("expected %s, got %v", ErrBlockDenied, err)
+	}
+	if err := bs.PutMany([]blocks.Block{allowed, denied}); err != ErrBlockDenied {
+		t.Fatalf("expected %s, got %v", ErrBlockDenied, err)
+	}
+	if has, _ := base.Has(allowed.Cid()); has {
+		t.Fatal("expected PutMany to store nothing when a block is denied")
+	}
+	if err := bs.Put(allowed); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := bs.AllKeysChan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []*cid.Cid
+	for c := range keys {
+		listed = append(listed, c)
+	}
+	if len(listed) != 1 || !listed[0].Equals(allowed.Cid()) {
+		t.Fatalf("expected only the allowed block to be listed, got %v", listed)
+	}
+
+	// the denied block stored earlier was deleted when it was listed
+	if has, _ := base.Has(denied.Cid()); has {
+		t.Fatal("expected the denied block to be deleted")
+	}
+	if has, err := bs.Has(denied.Cid()); has || err != nil {
+		t.Fatalf("expected the denied block to be missing, got %v, %v", has, err)
+	}
+	if _, err := bs.Get(denied.Cid()); err != ErrBlockDenied {
+		t.Fatalf("expected %s, got %v", ErrBlockDenied, err)
+	}
+	if _, err := bs.Get(allowed.Cid()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -178,6 +178,9 @@ func (s *blockService) GetBlocks(ctx context.Context, ks []*cid.Cid) <-chan bloc
 		var misses []*cid.Cid
 		for _, c := range ks {
 			hit, err := s.blockstore.Get(c)
+			if err == blockstore.ErrBlockDenied {
+				continue
+			}
 			if err != nil {
 				misses = append(misses, c)
 				continue
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"crypto/tls"
 	"errors"
 	_ "expvar"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"path/filepath"
 	"sort"
 	"sync"
 
@@ -17,10 +20,16 @@ import (
 	corehttp "github.com/ipfs/go-ipfs/core/corehttp"
 	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
 	"github.com/ipfs/go-ipfs/core/corerouting"
+	corespec "github.com/ipfs/go-ipfs/core/corespec"
+	features "github.com/ipfs/go-ipfs/features"
 	nodeMount "github.com/ipfs/go-ipfs/fuse/node"
+	loader "github.com/ipfs/go-ipfs/plugin/loader"
+	repo "github.com/ipfs/go-ipfs/repo"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 	migrate "github.com/ipfs/go-ipfs/repo/fsrepo/migrations"
+	rotate "github.com/ipfs/go-ipfs/thirdparty/rotate"
 
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 	mprome "gx/ipfs/QmSk46nSD78YiuNojYMS8NW6hSCjH95JajqqzzoychZgef/go-metrics-prometheus"
 	"gx/ipfs/QmX3QZ5jHEPidwUrymXV1iSCSUhdGxj15sm2gP4jKMef7B/client_golang/prometheus"
 	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
@@ -38,11 +47,13 @@ const (
 	migrateKwd                = "migrate"
 	mountKwd                  = "mount"
 	offlineKwd                = "offline"
+	preflightKwd              = "preflight"
 	routingOptionKwd          = "routing"
 	routingOptionSupernodeKwd = "supernode"
 	routingOptionDHTClientKwd = "dhtclient"
 	routingOptionDHTKwd       = "dht"
 	routingOptionNoneKwd      = "none"
+	specKwd                   = "spec"
 	unencryptTransportKwd     = "disable-transport-encryption"
 	unrestrictedApiAccessKwd  = "unrestricted-api"
 	writableKwd               = "writable"
@@ -106,6 +117,35 @@ You can setup CORS headers the same way:
 	ipfs config --json API.HTTPHeaders.Access-Control-Allow-Methods '["PUT", "GET", "POST"]'
 	ipfs config --json API.HTTPHeaders.Access-Control-Allow-Credentials '["true"]'
 
+Serving websites over HTTPS
+
+The gateway can serve websites on your own domains over HTTPS, without a
+reverse proxy. Set the listen address in Gateway.TLS.Address and describe
+each domain in Gateway.TLS.Hosts, with its certificate and key:
+
+	ipfs config Gateway.TLS.Address /ip4/0.0.0.0/tcp/443
+	ipfs config --json Gateway.TLS.Hosts '{"example.com": {"CertFile": "/etc/ssl/example.com.crt", "KeyFile": "/etc/ssl/example.com.key"}}'
+
+A domain serves its dnslink record, or the path set in its Path key. The
+certificates are reloaded when their files change, e.g. after a renewal.
+
+Health checks
+
+The API server answers on /livez for as long as the daemon is running, and
+on /readyz once the repo is readable, the swarm is listening and the node
+has connected to a bootstrap peer (when any are configured). Both reply
+with 200 when healthy, 503 otherwise, and a JSON body detailing each check:
+
+	curl http://127.0.0.1:5001/readyz
+
+Preflight checks
+
+Before starting, the daemon checks the file descriptor limit, that the repo
+can be written to, that the system clock is set, that the addresses in the
+config are valid and that their ports are free. Each problem found is
+printed along with how to fix it, and the daemon doesn't start if one of
+them would make it fail. The checks can be skipped with --preflight=false.
+
 Shutdown
 
 To shutdown the daemon, send a SIGINT signal to it (e.g. by pressing 'Ctrl-C')
@@ -132,6 +172,27 @@ be enabled by running the daemon as:
 This will later be transitioned into a config option once it gets out of the
 'experimental' stage.
 
+Node spec
+
+The --spec option takes a JSON file describing the state the node must be
+in, and converges the node to it on start. The config values and the keys
+are written to the repo before the node starts, then the missing pins are
+added, the peers connected to and the names published in the background:
+
+    {
+      "Config": {"Datastore.StorageMax": "50GB"},
+      "Keys": {"site": "keys/site.key"},
+      "Pins": ["/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"],
+      "Peering": ["/ip4/10.0.0.2/tcp/4001/ipfs/QmSoLer265NRgSp2LA3dPaeykiS1J6DifTC88f5uVQKNAd"],
+      "Names": {"site": "/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"}
+    }
+
+The key files hold keys in the format of the keystore, and relative paths
+are relative to the spec file. State not listed in the spec, such as other
+pins, is left alone. Names already published with the path of the spec are
+not published again, and are republished like the node's own. The node
+keeps reconnecting to the peers of the spec while it runs.
+
 DEPRECATION NOTICE
 
 Previously, ipfs used an environment variable as seen below:
@@ -156,9 +217,11 @@ Headers.
 		cmds.BoolOption(enableGCKwd, "Enable automatic periodic repo garbage collection").Default(false),
 		cmds.BoolOption(adjustFDLimitKwd, "Check and raise file descriptor limits if needed").Default(true),
 		cmds.BoolOption(offlineKwd, "Run offline. Do not connect to the rest of the network but provide local API.").Default(false),
+		cmds.BoolOption(preflightKwd, "Check the system and the config before starting, and explain how to fix the problems found.").Default(true),
 		cmds.BoolOption(migrateKwd, "If true, assume yes at the migrate prompt. If false, assume no."),
 		cmds.BoolOption(enableFloodSubKwd, "Instantiate the ipfs daemon with the experimental pubsub feature enabled."),
 		cmds.BoolOption(enableMultiplexKwd, "Add the experimental 'go-multiplex' stream muxer to libp2p on construction.").Default(true),
+		cmds.StringOption(specKwd, "Path to a JSON node spec file the node is converged to on start."),
 		// TODO: add way to override addresses. tricky part: updating the config if also --init.
 		// cmds.StringOption(apiAddrKwd, "Address for the daemon rpc API (overrides config)"),
 		// cmds.StringOption(swarmAddrKwd, "Address for the swarm socket (overrides config)"),
@@ -180,12 +243,22 @@ func defaultMux(path string) corehttp.ServeOption {
 
 var fileDescriptorCheck = func() error { return nil }
 
+// fileDescriptorLimit returns the current file descriptor limit, it is nil
+// on platforms where it is unknown
+var fileDescriptorLimit func() (uint64, error)
+
 func daemonFunc(req cmds.Request, res cmds.Response) {
 	// Inject metrics before we do anything
 
-	err := mprome.Inject()
+	injected, err := loader.InjectMetrics()
 	if err != nil {
-		log.Errorf("Injecting prometheus handler for metrics failed with message: %s\n", err.Error())
+		log.Errorf("Injecting plugin metrics failed with message: %s\n", err.Error())
+	}
+	if !injected {
+		err = mprome.Inject()
+		if err != nil {
+			log.Errorf("Injecting prometheus handler for metrics failed with message: %s\n", err.Error())
+		}
 	}
 
 	// let the user know we're going.
@@ -277,25 +350,57 @@ func daemonFunc(req cmds.Request, res cmds.Response) {
 		break
 	}
 
+	// apply the node spec to the repo - if the user provided the --spec flag.
+	// the config must be updated before it is loaded
+	spec, err := loadSpec(req, repo)
+	if err != nil {
+		res.SetError(err, cmds.ErrNormal)
+		repo.Close() // because ownership hasn't been transferred to the node
+		return
+	}
+
 	cfg, err := ctx.GetConfig()
 	if err != nil {
 		res.SetError(err, cmds.ErrNormal)
 		return
 	}
 
+	// certificates are obtained by a plugin, refuse to start without one
+	// rather than serve hosts without certificates
+	if cfg.Gateway.TLS.ACME.Enabled && !loader.ACMELoaded() {
+		res.SetError(errNoACMEPlugin, cmds.ErrNormal)
+		repo.Close() // because ownership hasn't been transferred to the node
+		return
+	}
+
 	offline, _, _ := req.Option(offlineKwd).Bool()
-	pubsub, _, _ := req.Option(enableFloodSubKwd).Bool()
-	mplex, _, _ := req.Option(enableMultiplexKwd).Bool()
+
+	// check the environment now rather than failing later with errors
+	// harder to act on
+	if check, _, _ := req.Option(preflightKwd).Bool(); check {
+		problems := preflight(ctx.ConfigRoot, cfg, offline, managefd)
+		if err := reportPreflight(os.Stdout, problems); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			repo.Close() // because ownership hasn't been transferred to the node
+			return
+		}
+	}
+
+	// flags only override the config when given
+	extraOpts := make(map[string]bool)
+	if pubsub, found, _ := req.Option(enableFloodSubKwd).Bool(); found {
+		extraOpts[features.Pubsub] = pubsub
+	}
+	if mplex, found, _ := req.Option(enableMultiplexKwd).Bool(); found {
+		extraOpts[features.Mplex] = mplex
+	}
 
 	// Start assembling node config
 	ncfg := &core.BuildCfg{
 		Repo:      repo,
 		Permament: true, // It is temporary way to signify that node is permament
 		Online:    !offline,
-		ExtraOpts: map[string]bool{
-			"pubsub": pubsub,
-			"mplex":  mplex,
-		},
+		ExtraOpts: extraOpts,
 		//TODO(Kubuxu): refactor Online vs Offline by adding Permanent vs Ephemeral
 	}
 
@@ -395,11 +500,32 @@ func daemonFunc(req cmds.Request, res cmds.Response) {
 		return
 	}
 
+	// open the gateway access log - if it is set in the config
+	accessLog, err := openAccessLog(req)
+	if err != nil {
+		res.SetError(err, cmds.ErrNormal)
+		return
+	}
+	if accessLog != nil {
+		defer accessLog.Close()
+	}
+
 	// construct http gateway - if it is set in the config
 	var gwErrc <-chan error
 	if len(cfg.Addresses.Gateway) > 0 {
 		var err error
-		err, gwErrc = serveHTTPGateway(req)
+		err, gwErrc = serveHTTPGateway(req, accessLog)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+	}
+
+	// construct https gateway for the hosts of the operator - if it is set in the config
+	var tlsErrc <-chan error
+	if len(cfg.Gateway.TLS.Address) > 0 {
+		var err error
+		err, tlsErrc = serveTLSGateway(req, accessLog)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -410,9 +536,21 @@ func daemonFunc(req cmds.Request, res cmds.Response) {
 	prometheus.MustRegister(&corehttp.IpfsNodeCollector{Node: node})
 
 	fmt.Printf("Daemon is ready\n")
+
+	// converge to the node spec in the background, pins may take long to fetch
+	if spec != nil {
+		go func() {
+			if err := spec.Converge(req.Context(), node); err != nil {
+				fmt.Printf("Node spec not fully applied: %s\n", err)
+				return
+			}
+			fmt.Printf("Node spec applied\n")
+		}()
+	}
+
 	// collect long-running errors and block for shutdown
 	// TODO(cryptix): our fuse currently doesnt follow this pattern for graceful shutdown
-	for err := range merge(apiErrc, gwErrc, gcErrc) {
+	for err := range merge(apiErrc, gwErrc, tlsErrc, gcErrc) {
 		if err != nil {
 			log.Error(err)
 			res.SetError(err, cmds.ErrNormal)
@@ -456,7 +594,11 @@ func serveHTTPApi(req cmds.Request) (error, <-chan error) {
 	if err != nil {
 		return fmt.Errorf("serveHTTPApi: Option(%s) failed: %s", unrestrictedApiAccessKwd, err), nil
 	}
-	gatewayOpt := corehttp.GatewayOption(false, corehttp.WebUIPaths...)
+	webui, err := corehttp.WebUIPathFromConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("serveHTTPApi: %s", err), nil
+	}
+	gatewayOpt := corehttp.GatewayOption(false, append(corehttp.WebUIPaths, webui)...)
 	if unrestricted {
 		gatewayOpt = corehttp.GatewayOption(true, "/ipfs", "/ipns")
 	}
@@ -464,13 +606,14 @@ func serveHTTPApi(req cmds.Request) (error, <-chan error) {
 	var opts = []corehttp.ServeOption{
 		corehttp.MetricsCollectionOption("api"),
 		corehttp.CommandsOption(*req.InvocContext()),
-		corehttp.WebUIOption,
+		corehttp.RedirectOption("webui", webui),
 		gatewayOpt,
 		corehttp.VersionOption(),
 		defaultMux("/debug/vars"),
 		defaultMux("/debug/pprof/"),
 		corehttp.MetricsScrapingOption("/debug/metrics/prometheus"),
 		corehttp.LogOption(),
+		corehttp.HealthOption(),
 	}
 
 	if len(cfg.Gateway.RootRedirect) > 0 {
@@ -486,6 +629,10 @@ func serveHTTPApi(req cmds.Request) (error, <-chan error) {
 		return fmt.Errorf("serveHTTPApi: SetAPIAddr() failed: %s", err), nil
 	}
 
+	if cfg.API.WebUI.Pin {
+		go pinWebUI(node, webui)
+	}
+
 	errc := make(chan error)
 	go func() {
 		errc <- corehttp.Serve(node, apiLis.NetListener(), opts...)
@@ -494,6 +641,18 @@ func serveHTTPApi(req cmds.Request) (error, <-chan error) {
 	return nil, errc
 }
 
+// pinWebUI fetches and pins the WebUI served by the API, so that it is served
+// from the local blockstore
+func pinWebUI(node *core.IpfsNode, webui string) {
+	defer node.Blockstore.PinLock().Unlock()
+
+	if _, err := corerepo.Pin(node, node.Context(), []string{webui}, true); err != nil {
+		log.Errorf("failed to pin the webui %s: %s", webui, err)
+		return
+	}
+	log.Infof("pinned the webui %s", webui)
+}
+
 // printSwarmAddrs prints the addresses of the host
 func printSwarmAddrs(node *core.IpfsNode) {
 	if !node.OnlineMode() {
@@ -512,7 +671,7 @@ func printSwarmAddrs(node *core.IpfsNode) {
 }
 
 // serveHTTPGateway collects options, creates listener, prints status message and starts serving requests
-func serveHTTPGateway(req cmds.Request) (error, <-chan error) {
+func serveHTTPGateway(req cmds.Request, accessLog io.Writer) (error, <-chan error) {
 	cfg, err := req.InvocContext().GetConfig()
 	if err != nil {
 		return fmt.Errorf("serveHTTPGateway: GetConfig() failed: %s", err), nil
@@ -555,6 +714,9 @@ func serveHTTPGateway(req cmds.Request) (error, <-chan error) {
 	if len(cfg.Gateway.RootRedirect) > 0 {
 		opts = append(opts, corehttp.RedirectOption("", cfg.Gateway.RootRedirect))
 	}
+	if accessLog != nil {
+		opts = append([]corehttp.ServeOption{corehttp.AccessLogOption(accessLog)}, opts...)
+	}
 
 	node, err := req.InvocContext().ConstructNode()
 	if err != nil {
@@ -569,6 +731,109 @@ func serveHTTPGateway(req cmds.Request) (error, <-chan error) {
 	return nil, errc
 }
 
+// openAccessLog opens the access log of the gateways, nil if there is none
+func openAccessLog(req cmds.Request) (io.WriteCloser, error) {
+	cfg, err := req.InvocContext().GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("openAccessLog: GetConfig() failed: %s", err)
+	}
+
+	alc := cfg.Gateway.AccessLog
+	if alc.Path == "" {
+		return nil, nil
+	}
+
+	p := alc.Path
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(req.InvocContext().ConfigRoot, p)
+	}
+
+	var maxSize uint64
+	if alc.MaxSize != "" {
+		maxSize, err = humanize.ParseBytes(alc.MaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("openAccessLog: invalid Gateway.AccessLog.MaxSize: %s", err)
+		}
+	}
+
+	f, err := rotate.Open(p, int64(maxSize), alc.MaxFiles)
+	if err != nil {
+		return nil, fmt.Errorf("openAccessLog: %s", err)
+	}
+	fmt.Printf("Gateway access log: %s\n", p)
+	return f, nil
+}
+
+// errNoACMEPlugin is returned when starting with Gateway.TLS.ACME enabled,
+// which needs an ACME plugin, and none is loaded
+var errNoACMEPlugin = errors.New("Gateway.TLS.ACME is enabled, but no ACME plugin is loaded: go-ipfs has no built in ACME client, see docs/plugins.md")
+
+// serveTLSGateway serves the websites of Gateway.TLS.Hosts over TLS
+func serveTLSGateway(req cmds.Request, accessLog io.Writer) (error, <-chan error) {
+	cfg, err := req.InvocContext().GetConfig()
+	if err != nil {
+		return fmt.Errorf("serveTLSGateway: GetConfig() failed: %s", err), nil
+	}
+
+	tlsMaddr, err := ma.NewMultiaddr(cfg.Gateway.TLS.Address)
+	if err != nil {
+		return fmt.Errorf("serveTLSGateway: invalid address: %q (err: %s)", cfg.Gateway.TLS.Address, err), nil
+	}
+
+	var acmeManager corehttp.CertManager
+	if acmeHosts := corehttp.ACMEHosts(cfg.Gateway.TLS.ACME, cfg.Gateway.TLS.Hosts); len(acmeHosts) > 0 {
+		acmeDir := cfg.Gateway.TLS.ACME.CacheDir
+		if acmeDir == "" {
+			acmeDir = corehttp.DefaultACMECacheDir
+		}
+		if !filepath.IsAbs(acmeDir) {
+			acmeDir = filepath.Join(req.InvocContext().ConfigRoot, acmeDir)
+		}
+		m, ok, err := loader.ACMEManager(cfg.Gateway.TLS.ACME, acmeHosts, acmeDir)
+		if err != nil {
+			return fmt.Errorf("serveTLSGateway: %s", err), nil
+		}
+		if !ok {
+			return fmt.Errorf("serveTLSGateway: %s", errNoACMEPlugin), nil
+		}
+		acmeManager = m
+	}
+
+	tlsCfg, err := corehttp.HostsTLSConfig(cfg.Gateway.TLS.Hosts, acmeManager)
+	if err != nil {
+		return fmt.Errorf("serveTLSGateway: %s", err), nil
+	}
+
+	tlsLis, err := manet.Listen(tlsMaddr)
+	if err != nil {
+		return fmt.Errorf("serveTLSGateway: manet.Listen(%s) failed: %s", tlsMaddr, err), nil
+	}
+	// we might have listened to /tcp/0 - lets see what we are listing on
+	tlsMaddr = tlsLis.Multiaddr()
+	fmt.Printf("Gateway (TLS) server listening on %s\n", tlsMaddr)
+
+	var opts = []corehttp.ServeOption{
+		corehttp.MetricsCollectionOption("tls-gateway"),
+		corehttp.GatewayHostsOption(cfg.Gateway.TLS.Hosts),
+		corehttp.GatewayOption(false, "/ipfs", "/ipns"),
+	}
+	if accessLog != nil {
+		opts = append([]corehttp.ServeOption{corehttp.AccessLogOption(accessLog)}, opts...)
+	}
+
+	node, err := req.InvocContext().ConstructNode()
+	if err != nil {
+		return fmt.Errorf("serveTLSGateway: ConstructNode() failed: %s", err), nil
+	}
+
+	errc := make(chan error)
+	go func() {
+		errc <- corehttp.Serve(node, tls.NewListener(tlsLis.NetListener(), tlsCfg), opts...)
+		close(errc)
+	}()
+	return nil, errc
+}
+
 //collects options and opens the fuse mountpoint
 func mountFuse(req cmds.Request) error {
 	cfg, err := req.InvocContext().GetConfig()
@@ -606,6 +871,27 @@ func mountFuse(req cmds.Request) error {
 	return nil
 }
 
+// loadSpec reads the node spec given with --spec, if any, and applies its
+// config and keys to r
+func loadSpec(req cmds.Request, r repo.Repo) (*corespec.Spec, error) {
+	specPath, found, err := req.Option(specKwd).String()
+	if err != nil {
+		return nil, fmt.Errorf("loadSpec: req.Option(%s) failed: %s", specKwd, err)
+	}
+	if !found || specPath == "" {
+		return nil, nil
+	}
+
+	spec, err := corespec.Load(specPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := spec.Apply(r); err != nil {
+		return nil, fmt.Errorf("applying the node spec: %s", err)
+	}
+	return spec, nil
+}
+
 func maybeRunGC(req cmds.Request, node *core.IpfsNode) (error, <-chan error) {
 	enableGC, _, err := req.Option(enableGCKwd).Bool()
 	if err != nil {
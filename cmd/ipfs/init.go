@@ -32,6 +32,13 @@ located at ~/.ipfs. To change the repo location, set the $IPFS_PATH
 environment variable:
 
     export IPFS_PATH=/path/to/ipfsrepo
+
+With --encrypt, the blocks, the rest of the datastore and the keystore are
+encrypted on disk with a repo key. The repo key is protected by a passphrase,
+read from $IPFS_REPO_PASSPHRASE or printed by the command of the
+Datastore.Encryption.PassphraseCommand config. The same passphrase is needed
+every time the repo is opened. Encryption can't be enabled on an existing
+repo. The private key of the node, in the config file, is not encrypted.
 `,
 	},
 	Arguments: []cmds.Argument{
@@ -40,6 +47,7 @@ environment variable:
 	Options: []cmds.Option{
 		cmds.IntOption("bits", "b", "Number of bits to use in the generated RSA private key.").Default(nBitsForKeypairDefault),
 		cmds.BoolOption("empty-repo", "e", "Don't add and pin help files to the local storage.").Default(false),
+		cmds.BoolOption("encrypt", "Encrypt the repo on disk, see above.").Default(false),
 
 		// TODO need to decide whether to expose the override as a file or a
 		// directory. That is: should we allow the user to also specify the
@@ -79,6 +87,12 @@ environment variable:
 			return
 		}
 
+		encrypt, _, err := req.Option("encrypt").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
 		var conf *config.Config
 
 		f := req.Files()
@@ -96,7 +110,7 @@ environment variable:
 			}
 		}
 
-		if err := doInit(os.Stdout, req.InvocContext().ConfigRoot, empty, nBitsForKeypair, conf); err != nil {
+		if err := doInit(os.Stdout, req.InvocContext().ConfigRoot, empty, encrypt, nBitsForKeypair, conf); err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
@@ -108,10 +122,10 @@ Reinitializing would overwrite your keys.
 `)
 
 func initWithDefaults(out io.Writer, repoRoot string) error {
-	return doInit(out, repoRoot, false, nBitsForKeypairDefault, nil)
+	return doInit(out, repoRoot, false, false, nBitsForKeypairDefault, nil)
 }
 
-func doInit(out io.Writer, repoRoot string, empty bool, nBitsForKeypair int, conf *config.Config) error {
+func doInit(out io.Writer, repoRoot string, empty, encrypt bool, nBitsForKeypair int, conf *config.Config) error {
 	if _, err := fmt.Fprintf(out, "initializing IPFS node at %s\n", repoRoot); err != nil {
 		return err
 	}
@@ -131,6 +145,9 @@ func doInit(out io.Writer, repoRoot string, empty bool, nBitsForKeypair int, con
 			return err
 		}
 	}
+	if encrypt {
+		conf.Datastore.Encryption.Enabled = true
+	}
 
 	if err := fsrepo.Init(repoRoot, conf); err != nil {
 		return err
@@ -104,8 +104,10 @@ var cmdDetailsMap = map[*cmds.Command]cmdDetails{
 	commandsClientCmd:                     {doesNotUseRepo: true},
 	commands.CommandsDaemonCmd:            {doesNotUseRepo: true},
 	commands.VersionCmd:                   {doesNotUseConfigAsInput: true, doesNotUseRepo: true}, // must be permitted to run before init
+	commands.CidCmd:                       {doesNotUseConfigAsInput: true, doesNotUseRepo: true},
 	commands.LogCmd:                       {cannotRunOnClient: true},
 	commands.ActiveReqsCmd:                {cannotRunOnClient: true},
 	commands.RepoFsckCmd:                  {cannotRunOnDaemon: true},
 	commands.ConfigCmd.Subcommand("edit"): {cannotRunOnDaemon: true, doesNotUseRepo: true},
+	commands.KeyCmd.Subcommand("rotate"):  {cannotRunOnDaemon: true, doesNotUseRepo: true},
 }
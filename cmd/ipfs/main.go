@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime/pprof"
 	"strings"
 	"sync"
@@ -22,6 +23,7 @@ import (
 	cmdsHttp "github.com/ipfs/go-ipfs/commands/http"
 	core "github.com/ipfs/go-ipfs/core"
 	coreCmds "github.com/ipfs/go-ipfs/core/commands"
+	loader "github.com/ipfs/go-ipfs/plugin/loader"
 	repo "github.com/ipfs/go-ipfs/repo"
 	config "github.com/ipfs/go-ipfs/repo/config"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
@@ -153,6 +155,13 @@ func mainRet() int {
 		return 0
 	}
 
+	// load the plugins before anything gets to use the repo
+	pluginDir := filepath.Join(invoc.req.InvocContext().ConfigRoot, "plugins")
+	if _, err := loader.LoadPlugins(pluginDir); err != nil {
+		printErr(err)
+		return 1
+	}
+
 	// ok, finally, run the command invocation.
 	intrh, ctx := invoc.SetupInterruptHandler(ctx)
 	defer intrh.Close()
@@ -170,6 +179,10 @@ func mainRet() int {
 
 	// everything went better than expected :)
 	_, err = io.Copy(os.Stdout, output)
+	if terr := cmds.TimeoutError(invoc.req); terr != nil {
+		// streamed output was cut short by the --timeout deadline
+		err = terr
+	}
 	if err != nil {
 		printErr(err)
 		return 1
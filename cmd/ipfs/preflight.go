@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+	"gx/ipfs/Qmf1Gq7N45Rpuw7ev47uWgH6dLPtdnvcMRNPkVBwqjLJg2/go-multiaddr-net"
+)
+
+// minClockTime is earlier than any sane clock. A clock before it most
+// likely was never set, e.g. on a device without a battery backed clock.
+var minClockTime = time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// clockSkewTolerance is how far in the future of the clock the repo files
+// may have been modified before the clock is suspected to have gone back
+const clockSkewTolerance = time.Hour
+
+// repoDirs are the directories of the repo the daemon writes to
+var repoDirs = []string{"", "blocks", "datastore", "keystore"}
+
+// preflightProblem is a problem found before starting the daemon, along with
+// how to fix it. Fatal problems keep the daemon from starting.
+type preflightProblem struct {
+	Fatal   bool
+	Problem string
+	Fix     string
+}
+
+// preflight checks that the daemon can run on the repo at repoPath, with
+// the config cfg, before it fails later with errors harder to act on
+func preflight(repoPath string, cfg *config.Config, offline, managefd bool) []preflightProblem {
+	var problems []preflightProblem
+	problems = append(problems, checkFdLimit(managefd)...)
+	problems = append(problems, checkRepoWritable(repoPath)...)
+	problems = append(problems, checkClock(time.Now(), repoPath)...)
+	problems = append(problems, checkConfigFile(repoPath)...)
+
+	// ports can only be checked once their addresses are known to be valid
+	cfgProblems := checkConfig(cfg)
+	problems = append(problems, cfgProblems...)
+	if len(cfgProblems) == 0 {
+		problems = append(problems, checkPorts(cfg, offline)...)
+	}
+	return problems
+}
+
+// reportPreflight prints the problems to w, and returns an error if any of
+// them is fatal
+func reportPreflight(w io.Writer, problems []preflightProblem) error {
+	fatal := 0
+	for _, p := range problems {
+		kind := "warning"
+		if p.Fatal {
+			kind = "error"
+			fatal++
+		}
+		fmt.Fprintf(w, "Preflight %s: %s\n", kind, p.Problem)
+		fmt.Fprintf(w, "  To fix it: %s\n", p.Fix)
+	}
+	if fatal > 0 {
+		return fmt.Errorf("%d preflight checks failed, see above how to fix them or run with --%s=false", fatal, preflightKwd)
+	}
+	return nil
+}
+
+func checkFdLimit(managefd bool) []preflightProblem {
+	if fileDescriptorLimit == nil {
+		return nil
+	}
+	limit, err := fileDescriptorLimit()
+	if err != nil {
+		log.Debugf("preflight: %s", err)
+		return nil
+	}
+	if limit >= ipfsFileDescNum {
+		return nil
+	}
+
+	fix := fmt.Sprintf("raise it with 'ulimit -n %d' in the shell starting the daemon, or in the service definition (e.g. LimitNOFILE for systemd)", ipfsFileDescNum)
+	if !managefd {
+		fix += fmt.Sprintf(", or run without --%s=false", adjustFDLimitKwd)
+	}
+	return []preflightProblem{{
+		Problem: fmt.Sprintf("the file descriptor limit is %d, below the %d needed to keep many connections open", limit, ipfsFileDescNum),
+		Fix:     fix,
+	}}
+}
+
+func checkRepoWritable(repoPath string) []preflightProblem {
+	var problems []preflightProblem
+	for _, d := range repoDirs {
+		dir := filepath.Join(repoPath, d)
+		if _, err := os.Stat(dir); os.IsNotExist(err) && d != "" {
+			continue
+		}
+		if err := tryWrite(dir); err != nil {
+			problems = append(problems, preflightProblem{
+				Fatal:   true,
+				Problem: fmt.Sprintf("can't write to %s: %s", dir, err),
+				Fix:     fmt.Sprintf("make sure %s is owned and writable by the user running the daemon, and that its disk isn't full or mounted read-only", dir),
+			})
+		}
+	}
+	return problems
+}
+
+// tryWrite creates, syncs and removes a file in dir
+func tryWrite(dir string) error {
+	f, err := ioutil.TempFile(dir, ".preflight-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write([]byte{0}); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// checkClock checks that the clock isn't obviously wrong. IPNS records are
+// published with an end of validity computed from it, and the records of
+// other peers are checked against it.
+func checkClock(now time.Time, repoPath string) []preflightProblem {
+	const fix = "set the system clock, and keep it synchronized, e.g. with NTP. Otherwise the IPNS records published expire early or late, and valid records of other peers are rejected"
+
+	if now.Before(minClockTime) {
+		return []preflightProblem{{
+			Fatal:   true,
+			Problem: fmt.Sprintf("the system clock is set to %s", now.Format(time.RFC3339)),
+			Fix:     fix,
+		}}
+	}
+
+	// the config is written by every change to the repo settings, so it
+	// can't have been modified after now
+	cfgPath, err := config.Filename(repoPath)
+	if err != nil {
+		return nil
+	}
+	st, err := os.Stat(cfgPath)
+	if err != nil {
+		return nil
+	}
+	if st.ModTime().After(now.Add(clockSkewTolerance)) {
+		return []preflightProblem{{
+			Problem: fmt.Sprintf("the config was modified at %s, after the current time %s: the system clock went back",
+				st.ModTime().Format(time.RFC3339), now.Format(time.RFC3339)),
+			Fix: fix,
+		}}
+	}
+	return nil
+}
+
+// listenAddr is an address the daemon listens on, with the config key
+// setting it
+type listenAddr struct {
+	key  string
+	addr string
+}
+
+func listenAddrs(cfg *config.Config, offline bool) []listenAddr {
+	var addrs []listenAddr
+	for _, a := range []listenAddr{
+		{"Addresses.API", cfg.Addresses.API},
+		{"Addresses.Gateway", cfg.Addresses.Gateway},
+		{"Gateway.TLS.Address", cfg.Gateway.TLS.Address},
+	} {
+		if a.addr != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	if !offline {
+		for _, a := range cfg.Addresses.Swarm {
+			addrs = append(addrs, listenAddr{"Addresses.Swarm", a})
+		}
+	}
+	return addrs
+}
+
+func checkConfig(cfg *config.Config) []preflightProblem {
+	var problems []preflightProblem
+
+	if cfg.Identity.PrivKey != "" {
+		if err := checkIdentity(cfg.Identity); err != nil {
+			problems = append(problems, preflightProblem{
+				Fatal:   true,
+				Problem: err.Error(),
+				Fix:     "restore the Identity section of the config from a backup of the repo. The peer ID must be the one of the private key",
+			})
+		}
+	}
+
+	seen := make(map[string]string)
+	for _, a := range listenAddrs(cfg, true) {
+		if _, err := ma.NewMultiaddr(a.addr); err != nil {
+			problems = append(problems, preflightProblem{
+				Fatal:   true,
+				Problem: fmt.Sprintf("%s %q is not a valid multiaddr: %s", a.key, a.addr, err),
+				Fix:     fmt.Sprintf("set it with 'ipfs config %s', e.g. to /ip4/127.0.0.1/tcp/8080", a.key),
+			})
+			continue
+		}
+		if other, ok := seen[a.addr]; ok && !strings.HasSuffix(a.addr, "/tcp/0") {
+			problems = append(problems, preflightProblem{
+				Fatal:   true,
+				Problem: fmt.Sprintf("%s and %s both listen on %s", other, a.key, a.addr),
+				Fix:     fmt.Sprintf("give %s another port with 'ipfs config %s'", a.key, a.key),
+			})
+			continue
+		}
+		seen[a.addr] = a.key
+	}
+	for _, a := range cfg.Addresses.Swarm {
+		if _, err := ma.NewMultiaddr(a); err != nil {
+			problems = append(problems, preflightProblem{
+				Fatal:   true,
+				Problem: fmt.Sprintf("Addresses.Swarm %q is not a valid multiaddr: %s", a, err),
+				Fix:     "fix it with 'ipfs config --json Addresses.Swarm', e.g. to [\"/ip4/0.0.0.0/tcp/4001\"]",
+			})
+		}
+	}
+	return problems
+}
+
+// checkConfigFile reports the problems of the config file found by
+// 'ipfs config check'. The listening addresses are left to checkConfig.
+func checkConfigFile(repoPath string) []preflightProblem {
+	filename, err := config.Filename(repoPath)
+	if err != nil {
+		return nil
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		// the repo could not be opened without its config
+		return nil
+	}
+
+	var problems []preflightProblem
+	for _, p := range config.CheckBytes(data) {
+		if strings.HasPrefix(p.Key, "Addresses.") {
+			continue
+		}
+		if p.Warning {
+			problems = append(problems, preflightProblem{
+				Problem: fmt.Sprintf("config %s: %s", p.Key, p.Message),
+				Fix:     "fix or remove the field with 'ipfs config edit', the node ignores it",
+			})
+			continue
+		}
+		problems = append(problems, preflightProblem{
+			Fatal:   true,
+			Problem: fmt.Sprintf("config %s: %s", p.Key, p.Message),
+			Fix:     "fix it with 'ipfs config edit', 'ipfs config check' lists all the problems of the config",
+		})
+	}
+	return problems
+}
+
+func checkIdentity(ident config.Identity) error {
+	sk, err := ident.DecodePrivateKey("")
+	if err != nil {
+		return fmt.Errorf("Identity.PrivKey can't be decoded: %s", err)
+	}
+	id, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		return fmt.Errorf("Identity.PrivKey is invalid: %s", err)
+	}
+	if id.Pretty() != ident.PeerID {
+		return fmt.Errorf("Identity.PeerID %s is not the ID of Identity.PrivKey, %s", ident.PeerID, id.Pretty())
+	}
+	return nil
+}
+
+// checkPorts checks that the TCP addresses to listen on are free. The
+// addresses must be valid.
+func checkPorts(cfg *config.Config, offline bool) []preflightProblem {
+	var problems []preflightProblem
+	for _, a := range listenAddrs(cfg, offline) {
+		if err := tryListen(a.addr); err != nil {
+			problems = append(problems, preflightProblem{
+				Fatal:   true,
+				Problem: fmt.Sprintf("can't listen on %s %s: %s", a.key, a.addr, err),
+				Fix:     fmt.Sprintf("stop the process using the port, e.g. another daemon with a different IPFS_PATH, or change the address with 'ipfs config %s'", a.key),
+			})
+		}
+	}
+	return problems
+}
+
+// tryListen listens on the TCP address addr, and closes the listener right
+// away. Other addresses are skipped.
+func tryListen(addr string) error {
+	maddr, err := ma.NewMultiaddr(addr)
+	if err != nil {
+		return err
+	}
+	network, host, err := manet.DialArgs(maddr)
+	if err != nil || !strings.HasPrefix(network, "tcp") {
+		return nil
+	}
+	if _, port, err := net.SplitHostPort(host); err != nil || port == "0" {
+		return nil
+	}
+	l, err := net.Listen(network, host)
+	if err != nil {
+		return err
+	}
+	return l.Close()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
+func TestCheckClock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preflight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfgPath := filepath.Join(dir, config.DefaultConfigFile)
+	if err := ioutil.WriteFile(cfgPath, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if p := checkClock(now, dir); len(p) != 0 {
+		t.Fatalf("unexpected problems: %v", p)
+	}
+	if p := checkClock(time.Unix(0, 0), dir); len(p) != 1 || !p[0].Fatal {
+		t.Fatalf("expected an unset clock to be fatal, got %v", p)
+	}
+	if p := checkClock(now.Add(-24*time.Hour), dir); len(p) != 1 || p[0].Fatal {
+		t.Fatalf("expected a warning for a clock gone back, got %v", p)
+	}
+}
+
+func TestCheckConfigAddresses(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Addresses.API = "/ip4/127.0.0.1/tcp/5001"
+	cfg.Addresses.Gateway = "/ip4/127.0.0.1/tcp/8080"
+	cfg.Addresses.Swarm = []string{"/ip4/0.0.0.0/tcp/4001"}
+	if p := checkConfig(cfg); len(p) != 0 {
+		t.Fatalf("unexpected problems: %v", p)
+	}
+
+	cfg.Addresses.Gateway = cfg.Addresses.API
+	if p := checkConfig(cfg); len(p) != 1 {
+		t.Fatalf("expected a shared address to be reported, got %v", p)
+	}
+
+	cfg.Addresses.Gateway = "/ip4/127.0.0.1/tcp/0"
+	cfg.Addresses.Swarm = []string{"/ip4/0.0.0.0/tpc/4001"}
+	if p := checkConfig(cfg); len(p) != 1 {
+		t.Fatalf("expected an invalid swarm address to be reported, got %v", p)
+	}
+}
+
+func TestCheckConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preflight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := `{"Addresses": {"API": "nope"}, "Ipns": {"RecordLifetime": "forever"}, "Foo": 1}`
+	cfgPath := filepath.Join(dir, config.DefaultConfigFile)
+	if err := ioutil.WriteFile(cfgPath, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the address is left to checkConfig
+	p := checkConfigFile(dir)
+	if len(p) != 2 {
+		t.Fatalf("expected 2 problems, got %v", p)
+	}
+	if p[0].Fatal || !p[1].Fatal {
+		t.Fatalf("expected the unknown field to be a warning and the lifetime an error, got %v", p)
+	}
+}
+
+func TestCheckPorts(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	cfg := &config.Config{}
+	cfg.Addresses.API = "/ip4/127.0.0.1/tcp/0"
+	cfg.Addresses.Swarm = []string{"/ip4/127.0.0.1/tcp/" + strconv.Itoa(port)}
+	if p := checkPorts(cfg, true); len(p) != 0 {
+		t.Fatalf("swarm ports must not be checked offline, got %v", p)
+	}
+	p := checkPorts(cfg, false)
+	if len(p) != 1 || !p[0].Fatal {
+		t.Fatalf("expected the busy port to be reported, got %v", p)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 func init() {
 	fileDescriptorCheck = checkAndSetUlimit
+	fileDescriptorLimit = getUlimit
 }
 
 func checkAndSetUlimit() error {
@@ -42,3 +43,11 @@ func checkAndSetUlimit() error {
 
 	return nil
 }
+
+func getUlimit() (uint64, error) {
+	var rLimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rLimit); err != nil {
+		return 0, fmt.Errorf("error getting rlimit: %s", err)
+	}
+	return uint64(rLimit.Cur), nil
+}
@@ -80,16 +80,58 @@ func ParseArgs(req cmds.Request, inputs []string, stdin *os.File, argDefs []cmds
 		}
 	}
 
+	var opts files.SerialFileOptions
+
 	// if '--hidden' is provided, enumerate hidden paths
 	hiddenOpt := req.Option("hidden")
-	hidden := false
 	if hiddenOpt != nil {
-		hidden, _, err = hiddenOpt.Bool()
+		opts.Hidden, _, err = hiddenOpt.Bool()
 		if err != nil {
 			return nil, nil, u.ErrCast()
 		}
 	}
-	return parseArgs(inputs, stdin, argDefs, recursive, hidden, root)
+
+	// if '--exclude' is provided, leave the matching paths out of
+	// recursive adds
+	excludeOpt := req.Option("exclude")
+	if excludeOpt != nil {
+		exclude, found, err := excludeOpt.String()
+		if err != nil {
+			return nil, nil, u.ErrCast()
+		}
+		if found && exclude != "" {
+			opts.Filter, err = files.NewFilter(strings.Split(exclude, ","))
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	// '--symlinks' selects whether symlinks are preserved, followed or
+	// skipped
+	symlinksOpt := req.Option("symlinks")
+	if symlinksOpt != nil {
+		symlinks, found, err := symlinksOpt.String()
+		if err != nil {
+			return nil, nil, u.ErrCast()
+		}
+		if found {
+			opts.Symlinks, err = files.ParseSymlinkMode(symlinks)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	// if '--skip-special' is provided, leave out pipes, sockets and devices
+	skipSpecialOpt := req.Option("skip-special")
+	if skipSpecialOpt != nil {
+		opts.SkipSpecial, _, err = skipSpecialOpt.Bool()
+		if err != nil {
+			return nil, nil, u.ErrCast()
+		}
+	}
+	return parseArgs(inputs, stdin, argDefs, recursive, opts, root)
 }
 
 // Parse a command line made up of sub-commands, short arguments, long arguments and positional arguments
@@ -259,7 +301,7 @@ func parseOpts(args []string, root *cmds.Command) (
 
 const msgStdinInfo = "ipfs: Reading from %s; send Ctrl-d to stop."
 
-func parseArgs(inputs []string, stdin *os.File, argDefs []cmds.Argument, recursive, hidden bool, root *cmds.Command) ([]string, []files.File, error) {
+func parseArgs(inputs []string, stdin *os.File, argDefs []cmds.Argument, recursive bool, opts files.SerialFileOptions, root *cmds.Command) ([]string, []files.File, error) {
 	// ignore stdin on Windows
 	if osh.IsWindows() {
 		stdin = nil
@@ -332,7 +374,7 @@ func parseArgs(inputs []string, stdin *os.File, argDefs []cmds.Argument, recursi
 					fpath = stdin.Name()
 					file = files.NewReaderFile("", fpath, r, nil)
 				} else {
-					nf, err := appendFile(fpath, argDef, recursive, hidden)
+					nf, err := appendFile(fpath, argDef, recursive, opts)
 					if err != nil {
 						return nil, nil, err
 					}
@@ -402,7 +444,7 @@ const notRecursiveFmtStr = "'%s' is a directory, use the '-%s' flag to specify d
 const dirNotSupportedFmtStr = "Invalid path '%s', argument '%s' does not support directories"
 const winDriveLetterFmtStr = "%q is a drive letter, not a drive path"
 
-func appendFile(fpath string, argDef *cmds.Argument, recursive, hidden bool) (files.File, error) {
+func appendFile(fpath string, argDef *cmds.Argument, recursive bool, opts files.SerialFileOptions) (files.File, error) {
 	// resolve Windows relative dot paths like `X:.\somepath`
 	if osh.IsWindows() {
 		if len(fpath) >= 3 && fpath[1:3] == ":." {
@@ -433,6 +475,13 @@ func appendFile(fpath string, argDef *cmds.Argument, recursive, hidden bool) (fi
 		return nil, err
 	}
 
+	if stat.Mode()&os.ModeSymlink != 0 && opts.Symlinks == files.SymlinkFollow {
+		stat, err = os.Stat(fpath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if stat.IsDir() {
 		if !argDef.Recursive {
 			return nil, fmt.Errorf(dirNotSupportedFmtStr, fpath, argDef.Name)
@@ -443,10 +492,10 @@ func appendFile(fpath string, argDef *cmds.Argument, recursive, hidden bool) (fi
 	}
 
 	if osh.IsWindows() {
-		return windowsParseFile(fpath, hidden, stat)
+		return windowsParseFile(fpath, opts, stat)
 	}
 
-	return files.NewSerialFile(path.Base(fpath), fpath, hidden, stat)
+	return files.NewSerialFileWithOptions(path.Base(fpath), fpath, opts, stat)
 }
 
 // Inform the user if a file is waiting on input
@@ -499,7 +548,7 @@ func (r *messageReader) Close() error {
 	return r.r.Close()
 }
 
-func windowsParseFile(fpath string, hidden bool, stat os.FileInfo) (files.File, error) {
+func windowsParseFile(fpath string, opts files.SerialFileOptions, stat os.FileInfo) (files.File, error) {
 	// special cases for Windows drive roots i.e. `X:\` and their long form `\\?\X:\`
 	// drive path must be preserved as `X:\` (or it's longform) and not converted to `X:`, `X:.`, `\`, or `/` here
 	switch len(fpath) {
@@ -510,7 +559,7 @@ func windowsParseFile(fpath string, hidden bool, stat os.FileInfo) (files.File,
 		}
 		// `X:\` needs to preserve the `\`, path.Base(filepath.ToSlash(fpath)) results in `X:` which is not valid
 		if fpath[1:3] == ":\\" {
-			return files.NewSerialFile(fpath, fpath, hidden, stat)
+			return files.NewSerialFileWithOptions(fpath, fpath, opts, stat)
 		}
 	case 6:
 		// `\\?\X:` long prefix form of `X:`, still ambiguous
@@ -521,9 +570,9 @@ func windowsParseFile(fpath string, hidden bool, stat os.FileInfo) (files.File,
 		// `\\?\X:\` long prefix form is translated into short form `X:\`
 		if fpath[:4] == "\\\\?\\" && fpath[5] == ':' && fpath[6] == '\\' {
 			fpath = string(fpath[4]) + ":\\"
-			return files.NewSerialFile(fpath, fpath, hidden, stat)
+			return files.NewSerialFileWithOptions(fpath, fpath, opts, stat)
 		}
 	}
 
-	return files.NewSerialFile(path.Base(filepath.ToSlash(fpath)), fpath, hidden, stat)
+	return files.NewSerialFileWithOptions(path.Base(filepath.ToSlash(fpath)), fpath, opts, stat)
 }
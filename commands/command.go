@@ -74,7 +74,13 @@ type Command struct {
 	// the Run Function.
 	//
 	// ie. If command Run returns &Block{}, then Command.Type == &Block{}
-	Type        interface{}
+	Type interface{}
+
+	// Events, when set, converts the output values of the command to frames
+	// of the event protocol, which are sent instead of the values when the
+	// request sets --events. Values converted to nil are dropped.
+	Events func(v interface{}) *Event
+
 	Subcommands map[string]*Command
 }
 
@@ -113,8 +119,19 @@ func (c *Command) Call(req Request) Response {
 		return res
 	}
 
+	err = checkEvents(req, cmd)
+	if err != nil {
+		res.SetError(err, ErrClient)
+		return res
+	}
+
 	cmd.Run(req, res)
 	if res.Error() != nil {
+		// report running out of time instead of whatever error the
+		// canceled operation happened to return
+		if err := TimeoutError(req); err != nil && res.Error().Code != ErrClient {
+			res.SetError(err, ErrNormal)
+		}
 		return res
 	}
 
@@ -150,6 +167,15 @@ func (c *Command) Call(req Request) Response {
 		}
 	}
 
+	if EventsRequested(req) {
+		events, err := eventChannel(req.Context(), output, cmd.Events)
+		if err != nil {
+			res.SetError(err, ErrNormal)
+			return res
+		}
+		res.SetOutput(events)
+	}
+
 	return res
 }
 
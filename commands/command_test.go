@@ -1,6 +1,9 @@
 package commands
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func noop(req Request, res Response) {
 	return
@@ -194,3 +197,90 @@ func TestHelpProcessing(t *testing.T) {
 		t.Error("LongDescription was not set on basis of ShortDescription")
 	}
 }
+
+func TestTimeoutError(t *testing.T) {
+	cmd := Command{
+		Run: func(req Request, res Response) {
+			<-req.Context().Done()
+			res.SetError(req.Context().Err(), ErrNormal)
+		},
+	}
+
+	opts, _ := cmd.GetOptions(nil)
+	req, _ := NewRequest(nil, nil, nil, nil, nil, opts)
+	req.SetOption(TimeoutOpt, "10ms")
+	if err := req.SetRootContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	res := cmd.Call(req)
+	if res.Error() == nil {
+		t.Fatal("expected the command to time out")
+	}
+	if msg := res.Error().Message; msg != "command timed out after 10ms" {
+		t.Fatalf("unexpected error: %s", msg)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	cmd := Command{
+		Run: func(req Request, res Response) {
+			out := make(chan interface{}, 3)
+			out <- 1
+			out <- 2
+			out <- 3
+			close(out)
+			res.SetOutput((<-chan interface{})(out))
+		},
+		Events: func(v interface{}) *Event {
+			switch v.(int) {
+			case 1:
+				return ProgressEvent("a", 1, 2, "things")
+			case 2:
+				return nil
+			default:
+				return ResultEvent(v)
+			}
+		},
+	}
+
+	opts, _ := cmd.GetOptions(nil)
+	req, _ := NewRequest(nil, nil, nil, nil, nil, opts)
+	req.SetOption(EventsOpt, true)
+	req.SetOption(EncShort, "json")
+	if err := req.SetRootContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	res := cmd.Call(req)
+	if res.Error() != nil {
+		t.Fatal(res.Error())
+	}
+
+	var events []*Event
+	for v := range res.Output().(<-chan interface{}) {
+		events = append(events, v.(*Event))
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Type != EventProgress || events[0].Done != 1 || events[0].Total != 2 {
+		t.Fatalf("unexpected progress event: %+v", events[0])
+	}
+	if events[1].Type != EventResult || events[1].Result != 3 {
+		t.Fatalf("unexpected result event: %+v", events[1])
+	}
+
+	req.SetOption(EncShort, "text")
+	res = cmd.Call(req)
+	if res.Error() == nil || res.Error().Message != ErrEventsEncoding.Error() {
+		t.Fatalf("expected encoding error, got: %v", res.Error())
+	}
+
+	cmd.Events = nil
+	req.SetOption(EncShort, "json")
+	res = cmd.Call(req)
+	if res.Error() == nil || res.Error().Message != ErrEventsUnsupported.Error() {
+		t.Fatalf("expected unsupported error, got: %v", res.Error())
+	}
+}
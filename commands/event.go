@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"context"
+	"errors"
+)
+
+// EventType is the type of a frame of the event protocol
+type EventType string
+
+const (
+	// EventProgress reports how far along a command is
+	EventProgress EventType = "progress"
+	// EventWarning reports a problem that does not stop the command
+	EventWarning EventType = "warning"
+	// EventResult carries a value of the command's regular output
+	EventResult EventType = "result"
+)
+
+// Event is a frame of the event protocol. When a request sets --events,
+// commands that support it stream Events instead of their regular output
+// values, so that clients can tell progress updates from results without
+// knowing the output type of every command.
+type Event struct {
+	Type EventType
+
+	// Name is the item a progress event is about, e.g. a file name
+	Name string `json:",omitempty"`
+	// Done is the amount of work completed so far, in Unit
+	Done int64 `json:",omitempty"`
+	// Total is the total amount of work, in Unit, when it is known
+	Total int64 `json:",omitempty"`
+	// Unit is the unit of Done and Total, e.g. "bytes" or "blocks"
+	Unit string `json:",omitempty"`
+
+	// Message is the text of a warning event
+	Message string `json:",omitempty"`
+
+	// Result is the output value carried by a result event
+	Result interface{} `json:",omitempty"`
+}
+
+// ProgressEvent returns a progress event
+func ProgressEvent(name string, done, total int64, unit string) *Event {
+	return &Event{Type: EventProgress, Name: name, Done: done, Total: total, Unit: unit}
+}
+
+// WarningEvent returns a warning event
+func WarningEvent(msg string) *Event {
+	return &Event{Type: EventWarning, Message: msg}
+}
+
+// ResultEvent returns a result event carrying v
+func ResultEvent(v interface{}) *Event {
+	return &Event{Type: EventResult, Result: v}
+}
+
+// ErrEventsEncoding is returned when events are requested with an encoding
+// other than JSON
+var ErrEventsEncoding = ClientError("--events requires --encoding=json")
+
+// ErrEventsUnsupported is returned when events are requested from a command
+// that does not produce them
+var ErrEventsUnsupported = ClientError("this command does not support --events")
+
+// EventsRequested returns whether the request asks for the event protocol
+func EventsRequested(req Request) bool {
+	ev, _, _ := req.Option(EventsOpt).Bool()
+	return ev
+}
+
+// checkEvents returns an error if the request asks for events that cmd can't
+// produce or that can't be encoded
+func checkEvents(req Request, cmd *Command) error {
+	if !EventsRequested(req) {
+		return nil
+	}
+	if cmd.Events == nil {
+		return ErrEventsUnsupported
+	}
+
+	enc, _, err := req.Option(EncShort).String()
+	if err != nil {
+		return err
+	}
+	if EncodingType(enc) != JSON {
+		return ErrEventsEncoding
+	}
+	return nil
+}
+
+// eventChannel converts the values of a command's output to events
+func eventChannel(ctx context.Context, output interface{}, conv func(interface{}) *Event) (<-chan interface{}, error) {
+	out := make(chan interface{})
+
+	in, ok := output.(<-chan interface{})
+	if !ok {
+		// single value outputs become a single result
+		if output == nil {
+			return nil, errors.New("command returned no output")
+		}
+		go func() {
+			defer close(out)
+			if ev := conv(output); ev != nil {
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+				}
+			}
+		}()
+		return out, nil
+	}
+
+	go func() {
+		defer close(out)
+		for v := range in {
+			ev := conv(v)
+			if ev == nil {
+				continue
+			}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				// let the command finish and close its channel
+				for range in {
+				}
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,209 @@
+package files
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of the file listing the paths to leave out of
+// a recursive add. It is read from every directory that is added and uses
+// the same syntax as a .gitignore file.
+const IgnoreFileName = ".ipfsignore"
+
+// Filter decides which paths are left out when serializing a directory
+// tree. Rules follow gitignore semantics: patterns without a slash match a
+// name at any depth, patterns containing a slash are relative to the
+// directory that declared them, a trailing slash only matches directories,
+// '**' matches any number of directories and a leading '!' re-includes a
+// previously excluded path. The last matching rule wins, and the patterns
+// given to NewFilter take precedence over those from ignore files.
+type Filter struct {
+	rules     []filterRule
+	overrides []filterRule
+}
+
+type filterRule struct {
+	// base is the directory the rule was declared in, relative to the
+	// root of the filter, with no leading or trailing slash.
+	base     string
+	segments []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// NewFilter returns a filter for the given patterns, relative to the root
+// of the tree being filtered.
+func NewFilter(patterns []string) (*Filter, error) {
+	f, err := (*Filter)(nil).With("", patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{overrides: f.rules}, nil
+}
+
+// With returns a new filter containing the rules of f followed by the given
+// patterns, declared in the directory base. f may be nil.
+func (f *Filter) With(base string, patterns []string) (*Filter, error) {
+	nf := &Filter{}
+	if f != nil {
+		nf.rules = append(nf.rules, f.rules...)
+		nf.overrides = f.overrides
+	}
+
+	base = strings.Trim(path.Clean("/"+base), "/")
+	for _, p := range patterns {
+		r, ok, err := parseFilterRule(base, p)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			nf.rules = append(nf.rules, r)
+		}
+	}
+	return nf, nil
+}
+
+// WithIgnoreFile returns f extended by the rules of the ignore file in the
+// directory dir, whose path relative to the root of the filter is base. If
+// there is no ignore file, f is returned unchanged.
+func (f *Filter) WithIgnoreFile(base, dir string) (*Filter, error) {
+	fi, err := os.Open(filepath.Join(dir, IgnoreFileName))
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	var patterns []string
+	scan := bufio.NewScanner(fi)
+	for scan.Scan() {
+		patterns = append(patterns, scan.Text())
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+
+	nf, err := f.With(base, patterns)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filepath.Join(dir, IgnoreFileName), err)
+	}
+	return nf, nil
+}
+
+// Excludes reports whether the path p, relative to the root of the filter,
+// should be left out.
+func (f *Filter) Excludes(p string, isDir bool) bool {
+	if f == nil {
+		return false
+	}
+
+	p = strings.Trim(path.Clean("/"+p), "/")
+	excluded := false
+	for _, rules := range [][]filterRule{f.rules, f.overrides} {
+		for _, r := range rules {
+			if r.matches(p, isDir) {
+				excluded = !r.negate
+			}
+		}
+	}
+	return excluded
+}
+
+func parseFilterRule(base, pattern string) (filterRule, bool, error) {
+	r := filterRule{base: base}
+
+	pattern = strings.TrimRight(pattern, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return r, false, nil
+	}
+
+	switch {
+	case strings.HasPrefix(pattern, "!"):
+		r.negate = true
+		pattern = pattern[1:]
+	case strings.HasPrefix(pattern, `\`):
+		// escaped leading '#' or '!'
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		r.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+
+	// a slash anywhere but at the end anchors the pattern to its base
+	if strings.Contains(pattern, "/") {
+		r.anchored = true
+		pattern = strings.TrimLeft(pattern, "/")
+	}
+
+	if pattern == "" {
+		return r, false, nil
+	}
+
+	r.segments = strings.Split(pattern, "/")
+	for _, s := range r.segments {
+		if s == "**" {
+			continue
+		}
+		if _, err := path.Match(s, ""); err != nil {
+			return r, false, fmt.Errorf("invalid pattern %q: %s", pattern, err)
+		}
+	}
+	return r, true, nil
+}
+
+func (r filterRule) matches(p string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	if r.base != "" {
+		if !strings.HasPrefix(p, r.base+"/") {
+			return false
+		}
+		p = p[len(r.base)+1:]
+	}
+
+	parts := strings.Split(p, "/")
+	if !r.anchored {
+		// an unanchored pattern is a single segment matched against the
+		// name of the path, at any depth
+		ok, _ := path.Match(r.segments[0], parts[len(parts)-1])
+		return ok
+	}
+	return matchSegments(r.segments, parts)
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return len(parts) > 0
+			}
+			for i := 0; i < len(parts); i++ {
+				if matchSegments(pattern, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
@@ -0,0 +1,154 @@
+package files
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilterExcludes(t *testing.T) {
+	f, err := NewFilter([]string{
+		"# comment",
+		"",
+		"*.o",
+		"!keep.o",
+		"build/",
+		"/top.txt",
+		"docs/*.tmp",
+		"**/cache",
+		"logs/**",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path     string
+		isDir    bool
+		excluded bool
+	}{
+		{"a.o", false, true},
+		{"src/deep/a.o", false, true},
+		{"src/keep.o", false, false},
+		{"a.c", false, false},
+		{"build", true, true},
+		{"src/build", true, true},
+		{"build", false, false},
+		{"top.txt", false, true},
+		{"src/top.txt", false, false},
+		{"docs/a.tmp", false, true},
+		{"src/docs/a.tmp", false, false},
+		{"cache", true, true},
+		{"a/b/cache", false, true},
+		{"logs", true, false},
+		{"logs/today", false, true},
+		{"logs/a/b", false, true},
+	}
+
+	for _, c := range cases {
+		if got := f.Excludes(c.path, c.isDir); got != c.excluded {
+			t.Errorf("Excludes(%q, %t) = %t, expected %t", c.path, c.isDir, got, c.excluded)
+		}
+	}
+}
+
+func TestFilterBase(t *testing.T) {
+	f, err := (*Filter)(nil).With("sub", []string{"/a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path     string
+		excluded bool
+	}{
+		{"a", false},
+		{"sub/a", true},
+		{"sub/x/a", false},
+		{"b", false},
+		{"sub/x/b", true},
+	}
+
+	for _, c := range cases {
+		if got := f.Excludes(c.path, false); got != c.excluded {
+			t.Errorf("Excludes(%q) = %t, expected %t", c.path, got, c.excluded)
+		}
+	}
+}
+
+func TestFilterInvalidPattern(t *testing.T) {
+	if _, err := NewFilter([]string{"[a"}); err == nil {
+		t.Fatal("expected an error for an invalid pattern")
+	}
+}
+
+func TestFilteredSerialFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filtered-serial-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, data string) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a.txt", "a")
+	write("a.o", "a")
+	write("build/out", "out")
+	write("sub/c.log", "c")
+	write("sub/d/a.log", "a")
+	write("sub/e/d/a.log", "a")
+	write("sub/"+IgnoreFileName, "*.log\n/d/\n")
+
+	stat, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := NewFilter([]string{"*.o", "build/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sf, err := NewSerialFileWithOptions("root", dir, SerialFileOptions{Filter: filter}, stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := walkNames(t, sf)
+	expected := []string{"root/a.txt", "root/sub", "root/sub/e", "root/sub/e/d"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+	for i := range names {
+		if names[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, names)
+		}
+	}
+}
+
+func TestFilterOverrides(t *testing.T) {
+	f, err := NewFilter([]string{"!keep.o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err = f.With("src", []string{"*.o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Excludes("src/keep.o", false) {
+		t.Fatal("patterns given to NewFilter should take precedence")
+	}
+	if !f.Excludes("src/other.o", false) {
+		t.Fatal("expected src/other.o to be excluded")
+	}
+}
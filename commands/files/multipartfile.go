@@ -6,6 +6,7 @@ import (
 	"mime"
 	"mime/multipart"
 	"net/url"
+	"os"
 )
 
 const (
@@ -52,6 +53,7 @@ func NewFileFromPart(part *multipart.Part) (File, error) {
 			filename: f.FileName(),
 			abspath:  part.Header.Get("abspath"),
 			fullpath: f.FullPath(),
+			stat:     statFromHeader(f.FileName(), part.Header, false),
 		}, nil
 	}
 
@@ -64,6 +66,15 @@ func NewFileFromPart(part *multipart.Part) (File, error) {
 	return f, nil
 }
 
+// Stat returns the permission bits and modification time sent along with a
+// directory, or nil if there are none.
+func (f *MultipartFile) Stat() os.FileInfo {
+	if f.Part == nil {
+		return nil
+	}
+	return statFromHeader(f.FileName(), f.Part.Header, true)
+}
+
 func (f *MultipartFile) IsDirectory() bool {
 	return f.Mediatype == multipartFormdataType || f.Mediatype == applicationDirectory
 }
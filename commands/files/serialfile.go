@@ -10,20 +10,81 @@ import (
 	"syscall"
 )
 
+// SymlinkMode selects how symlinks found while serializing a directory are
+// handled.
+type SymlinkMode int
+
+const (
+	// SymlinkPreserve serializes symlinks as symlinks.
+	SymlinkPreserve SymlinkMode = iota
+	// SymlinkFollow serializes the file or directory a symlink points to.
+	SymlinkFollow
+	// SymlinkSkip leaves symlinks out.
+	SymlinkSkip
+)
+
+// ParseSymlinkMode parses the name of a SymlinkMode: "preserve", "follow"
+// or "skip".
+func ParseSymlinkMode(s string) (SymlinkMode, error) {
+	switch s {
+	case "preserve":
+		return SymlinkPreserve, nil
+	case "follow":
+		return SymlinkFollow, nil
+	case "skip":
+		return SymlinkSkip, nil
+	default:
+		return 0, fmt.Errorf("unknown symlink mode %q, expected preserve, follow or skip", s)
+	}
+}
+
+// SerialFileOptions controls which entries of a directory are serialized
+// and how.
+type SerialFileOptions struct {
+	// Hidden includes files whose name starts with a dot.
+	Hidden bool
+	// Filter leaves out the paths it excludes, along with those excluded
+	// by ignore files found in the tree. It may be nil.
+	Filter *Filter
+	// Symlinks selects how symlinks inside directories are handled.
+	// Symlinks given as the root of the tree are always preserved, unless
+	// they are followed.
+	Symlinks SymlinkMode
+	// SkipSpecial leaves out named pipes, sockets and devices rather than
+	// failing on them.
+	SkipSpecial bool
+}
+
 // serialFile implements File, and reads from a path on the OS filesystem.
 // No more than one file will be opened at a time (directories will advance
 // to the next file when NextFile() is called).
 type serialFile struct {
-	name              string
-	path              string
-	files             []os.FileInfo
-	stat              os.FileInfo
-	current           *File
-	handleHiddenFiles bool
+	name    string
+	path    string
+	files   []os.FileInfo
+	stat    os.FileInfo
+	current *File
+	opts    SerialFileOptions
+
+	// rel is the path of the file relative to the root of the serialized
+	// tree, which filter rules are matched against.
+	rel string
+	// parents holds the directories leading to this one, to detect cycles
+	// when following symlinks.
+	parents []os.FileInfo
 }
 
 func NewSerialFile(name, path string, hidden bool, stat os.FileInfo) (File, error) {
+	return NewSerialFileWithOptions(name, path, SerialFileOptions{Hidden: hidden}, stat)
+}
+
+// NewSerialFileWithOptions is like NewSerialFile, but serializes the tree
+// according to opts.
+func NewSerialFileWithOptions(name, path string, opts SerialFileOptions, stat os.FileInfo) (File, error) {
+	return newSerialFile(name, path, "", opts, nil, stat)
+}
 
+func newSerialFile(name, path, rel string, opts SerialFileOptions, parents []os.FileInfo, stat os.FileInfo) (File, error) {
 	switch mode := stat.Mode(); {
 	case mode.IsRegular():
 		file, err := os.Open(path)
@@ -32,13 +93,37 @@ func NewSerialFile(name, path string, hidden bool, stat os.FileInfo) (File, erro
 		}
 		return NewReaderPathFile(name, path, file, stat)
 	case mode.IsDir():
+		for _, p := range parents {
+			if os.SameFile(p, stat) {
+				return nil, fmt.Errorf("symlink cycle at %s", name)
+			}
+		}
+
 		// for directories, stat all of the contents first, so we know what files to
 		// open when NextFile() is called
 		contents, err := ioutil.ReadDir(path)
 		if err != nil {
 			return nil, err
 		}
-		return &serialFile{name, path, contents, stat, nil, hidden}, nil
+		opts.Filter, err = opts.Filter.WithIgnoreFile(rel, path)
+		if err != nil {
+			return nil, err
+		}
+		return &serialFile{
+			name:    name,
+			path:    path,
+			files:   contents,
+			stat:    stat,
+			opts:    opts,
+			rel:     rel,
+			parents: append(parents[:len(parents):len(parents)], stat),
+		}, nil
+	case mode&os.ModeSymlink != 0 && opts.Symlinks == SymlinkFollow:
+		target, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		return newSerialFile(name, path, rel, opts, parents, target)
 	case mode&os.ModeSymlink != 0:
 		target, err := os.Readlink(path)
 		if err != nil {
@@ -68,15 +153,16 @@ func (f *serialFile) NextFile() (File, error) {
 		return nil, io.EOF
 	}
 
-	stat := f.files[0]
-	f.files = f.files[1:]
-
-	for !f.handleHiddenFiles && strings.HasPrefix(stat.Name(), ".") {
+	var stat os.FileInfo
+	for stat == nil {
 		if len(f.files) == 0 {
 			return nil, io.EOF
 		}
 
-		stat = f.files[0]
+		stat, err = f.entry(f.files[0])
+		if err != nil {
+			return nil, err
+		}
 		f.files = f.files[1:]
 	}
 
@@ -87,7 +173,7 @@ func (f *serialFile) NextFile() (File, error) {
 	// recursively call the constructor on the next file
 	// if it's a regular file, we will open it as a ReaderFile
 	// if it's a directory, files in it will be opened serially
-	sf, err := NewSerialFile(fileName, filePath, f.handleHiddenFiles, stat)
+	sf, err := newSerialFile(fileName, filePath, f.childRel(stat), f.opts, f.parents, stat)
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +183,46 @@ func (f *serialFile) NextFile() (File, error) {
 	return sf, nil
 }
 
+// entry returns the stat of the directory entry to serialize, or nil if the
+// entry should be left out. Followed symlinks are replaced with the stat of
+// their target.
+func (f *serialFile) entry(stat os.FileInfo) (os.FileInfo, error) {
+	if !f.opts.Hidden && strings.HasPrefix(stat.Name(), ".") {
+		return nil, nil
+	}
+
+	mode := stat.Mode()
+	switch {
+	case mode&os.ModeSymlink != 0:
+		switch f.opts.Symlinks {
+		case SymlinkSkip:
+			return nil, nil
+		case SymlinkFollow:
+			target, err := os.Stat(filepath.Join(f.path, stat.Name()))
+			if err != nil {
+				return nil, err
+			}
+			stat = target
+		}
+	case !mode.IsRegular() && !mode.IsDir():
+		if f.opts.SkipSpecial {
+			return nil, nil
+		}
+	}
+
+	if f.opts.Filter.Excludes(f.childRel(stat), stat.IsDir()) {
+		return nil, nil
+	}
+	return stat, nil
+}
+
+func (f *serialFile) childRel(stat os.FileInfo) string {
+	if f.rel == "" {
+		return stat.Name()
+	}
+	return f.rel + "/" + stat.Name()
+}
+
 func (f *serialFile) FileName() string {
 	return f.name
 }
@@ -0,0 +1,139 @@
+package files
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// walkNames returns the sorted names of all files and directories below f.
+func walkNames(t *testing.T, f File) []string {
+	var names []string
+	var walk func(f File)
+	walk = func(f File) {
+		for {
+			nf, err := f.NextFile()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, nf.FileName())
+			if nf.IsDirectory() {
+				walk(nf)
+			}
+		}
+	}
+	walk(f)
+
+	sort.Strings(names)
+	return names
+}
+
+func symlinkTree(t *testing.T) (string, os.FileInfo) {
+	dir, err := ioutil.TempDir("", "serial-file-symlinks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "sub", "e"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub", filepath.Join(dir, "dirlink")); err != nil {
+		t.Fatal(err)
+	}
+
+	stat, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, stat
+}
+
+func TestSerialFileSymlinkModes(t *testing.T) {
+	cases := []struct {
+		mode     SymlinkMode
+		expected []string
+	}{
+		{SymlinkPreserve, []string{"root/a.txt", "root/dirlink", "root/link", "root/sub", "root/sub/e"}},
+		{SymlinkFollow, []string{"root/a.txt", "root/dirlink", "root/dirlink/e", "root/link", "root/sub", "root/sub/e"}},
+		{SymlinkSkip, []string{"root/a.txt", "root/sub", "root/sub/e"}},
+	}
+
+	for _, c := range cases {
+		dir, stat := symlinkTree(t)
+		defer os.RemoveAll(dir)
+
+		sf, err := NewSerialFileWithOptions("root", dir, SerialFileOptions{Symlinks: c.mode}, stat)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := walkNames(t, sf)
+		if !reflect.DeepEqual(names, c.expected) {
+			t.Errorf("mode %d: expected %v, got %v", c.mode, c.expected, names)
+		}
+	}
+}
+
+func TestSerialFileSymlinkCycle(t *testing.T) {
+	dir, stat := symlinkTree(t)
+	defer os.RemoveAll(dir)
+
+	if err := os.Symlink("..", filepath.Join(dir, "sub", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	sf, err := NewSerialFileWithOptions("root", dir, SerialFileOptions{Symlinks: SymlinkFollow}, stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var walk func(f File) error
+	walk = func(f File) error {
+		for {
+			nf, err := f.NextFile()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if nf.IsDirectory() {
+				if err := walk(nf); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := walk(sf); err == nil {
+		t.Fatal("expected an error for a symlink cycle")
+	}
+}
+
+func TestParseSymlinkMode(t *testing.T) {
+	for s, m := range map[string]SymlinkMode{"preserve": SymlinkPreserve, "follow": SymlinkFollow, "skip": SymlinkSkip} {
+		got, err := ParseSymlinkMode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != m {
+			t.Fatalf("ParseSymlinkMode(%q) = %d, expected %d", s, got, m)
+		}
+	}
+
+	if _, err := ParseSymlinkMode("nope"); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
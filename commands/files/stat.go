@@ -0,0 +1,105 @@
+package files
+
+import (
+	"fmt"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Multipart headers carrying the permission bits and the modification time
+// of a file, so they survive the trip to the daemon.
+const (
+	modeHeader  = "Mode"
+	mtimeHeader = "Mtime"
+)
+
+// SetStatHeaders records the permission bits and modification time of st in
+// header, for NewFileFromPart to pick up.
+func SetStatHeaders(header textproto.MIMEHeader, st os.FileInfo) {
+	header.Set(modeHeader, strconv.FormatUint(uint64(unixMode(st.Mode())), 8))
+
+	mtime := st.ModTime()
+	header.Set(mtimeHeader, fmt.Sprintf("%d.%09d", mtime.Unix(), mtime.Nanosecond()))
+}
+
+// unixMode returns the permission, setuid, setgid and sticky bits of mode
+// as a unix mode.
+func unixMode(mode os.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= 01000
+	}
+	return m
+}
+
+// fileMode is the inverse of unixMode.
+func fileMode(m uint32) os.FileMode {
+	mode := os.FileMode(m & 0777)
+	if m&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if m&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if m&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// statFromHeader returns the file info recorded in header by SetStatHeaders,
+// or nil if there is none.
+func statFromHeader(name string, header textproto.MIMEHeader, isDir bool) os.FileInfo {
+	modeStr, mtimeStr := header.Get(modeHeader), header.Get(mtimeHeader)
+	if modeStr == "" || mtimeStr == "" {
+		return nil
+	}
+
+	m, err := strconv.ParseUint(modeStr, 8, 32)
+	if err != nil {
+		return nil
+	}
+
+	parts := strings.SplitN(mtimeStr, ".", 2)
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil
+	}
+	var nsec int64
+	if len(parts) == 2 {
+		nsec, err = strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil
+		}
+	}
+
+	mode := fileMode(uint32(m))
+	if isDir {
+		mode |= os.ModeDir
+	}
+	return &partStat{name: name, mode: mode, mtime: time.Unix(sec, nsec)}
+}
+
+// partStat is the file info of a file received as a multipart part. Its
+// size is unknown.
+type partStat struct {
+	name  string
+	mode  os.FileMode
+	mtime time.Time
+}
+
+func (s *partStat) Name() string       { return s.name }
+func (s *partStat) Size() int64        { return 0 }
+func (s *partStat) Mode() os.FileMode  { return s.mode }
+func (s *partStat) ModTime() time.Time { return s.mtime }
+func (s *partStat) IsDir() bool        { return s.mode.IsDir() }
+func (s *partStat) Sys() interface{}   { return nil }
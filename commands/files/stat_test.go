@@ -0,0 +1,41 @@
+package files
+
+import (
+	"net/textproto"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStatHeaders(t *testing.T) {
+	st := &partStat{
+		name:  "file",
+		mode:  0755 | os.ModeSetgid,
+		mtime: time.Unix(1500000000, 7),
+	}
+
+	header := make(textproto.MIMEHeader)
+	SetStatHeaders(header, st)
+	if header.Get(modeHeader) != "2755" {
+		t.Fatalf("expected mode 2755, got %s", header.Get(modeHeader))
+	}
+
+	got := statFromHeader("file", header, false)
+	if got == nil {
+		t.Fatal("expected a stat from the headers")
+	}
+	if got.Mode() != st.mode {
+		t.Fatalf("expected mode %s, got %s", st.mode, got.Mode())
+	}
+	if !got.ModTime().Equal(st.mtime) {
+		t.Fatalf("expected mtime %s, got %s", st.mtime, got.ModTime())
+	}
+
+	if dir := statFromHeader("dir", header, true); !dir.IsDir() {
+		t.Fatal("expected a directory")
+	}
+
+	if statFromHeader("file", make(textproto.MIMEHeader), false) != nil {
+		t.Fatal("expected no stat without headers")
+	}
+}
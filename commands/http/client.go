@@ -11,6 +11,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
 	config "github.com/ipfs/go-ipfs/repo/config"
@@ -59,6 +60,11 @@ func (c *client) Send(req cmds.Request) (cmds.Response, error) {
 		return nil, err
 	}
 
+	// events are decoded as such, so they can only be re-encoded as json
+	if cmds.EventsRequested(req) && cmds.EncodingType(previousUserProvidedEncoding) != cmds.JSON {
+		return nil, cmds.ErrEventsEncoding
+	}
+
 	// override with json to send to server
 	req.SetOption(cmds.EncShort, cmds.JSON)
 
@@ -94,7 +100,7 @@ func (c *client) Send(req cmds.Request) (cmds.Response, error) {
 	}
 	httpReq.Header.Set(uaHeader, config.ApiVersion)
 
-	httpReq.Cancel = req.Context().Done()
+	httpReq.Cancel = cancelChan(req.Context())
 	httpReq.Close = true
 
 	httpRes, err := c.httpClient.Do(httpReq)
@@ -118,6 +124,30 @@ func (c *client) Send(req cmds.Request) (cmds.Response, error) {
 	return res, nil
 }
 
+// timeoutGrace is how long the client waits past the --timeout deadline for
+// the daemon to report the timeout before dropping the connection.
+const timeoutGrace = 5 * time.Second
+
+// cancelChan returns a channel closed when the HTTP request to the daemon
+// must be abandoned. The daemon enforces --timeout itself, so when the
+// deadline passes the connection is kept open a little longer to receive
+// its error instead of failing with a dropped connection.
+func cancelChan(ctx context.Context) <-chan struct{} {
+	if _, ok := ctx.Deadline(); !ok {
+		return ctx.Done()
+	}
+
+	cancel := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		if ctx.Err() == context.DeadlineExceeded {
+			time.Sleep(timeoutGrace)
+		}
+		close(cancel)
+	}()
+	return cancel
+}
+
 func getQuery(req cmds.Request) (string, error) {
 	query := url.Values{}
 	for k, v := range req.Options() {
@@ -237,6 +267,9 @@ func readStreamedJson(req cmds.Request, rr io.Reader, out chan<- interface{}, re
 	defer close(out)
 	dec := json.NewDecoder(rr)
 	outputType := reflect.TypeOf(req.Command().Type)
+	if cmds.EventsRequested(req) {
+		outputType = reflect.TypeOf(cmds.Event{})
+	}
 
 	ctx := req.Context()
 
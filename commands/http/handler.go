@@ -284,6 +284,10 @@ func sendResponse(w http.ResponseWriter, r *http.Request, res cmds.Response, req
 
 	w.WriteHeader(status)
 	err = flushCopy(w, out)
+	if err == nil && status == http.StatusOK {
+		// streamed output is cut short by the deadline without error
+		err = cmds.TimeoutError(req)
+	}
 	if err != nil {
 		log.Error("err: ", err)
 		w.Header().Set(StreamErrHeader, sanitizedErrStr(err))
@@ -98,6 +98,9 @@ func (mfr *MultiFileReader) Read(buf []byte) (written int, err error) {
 			if rf, ok := file.(*files.ReaderFile); ok {
 				header.Set("abspath", rf.AbsPath())
 			}
+			if sf, ok := file.(files.StatFile); ok && sf.Stat() != nil {
+				files.SetStatHeaders(header, sf.Stat())
+			}
 
 			_, err := mfr.mpWriter.CreatePart(header)
 			if err != nil {
@@ -188,19 +188,22 @@ const (
 	RecLong    = "recursive"
 	ChanOpt    = "stream-channels"
 	TimeoutOpt = "timeout"
+	EventsOpt  = "events"
 )
 
 // options that are used by this package
 var OptionEncodingType = StringOption(EncLong, EncShort, "The encoding type the output should be encoded with (json, xml, or text)")
 var OptionRecursivePath = BoolOption(RecLong, RecShort, "Add directory paths recursively").Default(false)
 var OptionStreamChannels = BoolOption(ChanOpt, "Stream channel output")
-var OptionTimeout = StringOption(TimeoutOpt, "set a global timeout on the command")
+var OptionTimeout = StringOption(TimeoutOpt, "Set a global timeout on the command, e.g. \"30s\". The command fails once it expires.")
+var OptionEvents = BoolOption(EventsOpt, "Stream typed progress, warning and result events, for commands that support them. Requires --encoding=json.")
 
 // global options, added to every command
 var globalOptions = []Option{
 	OptionEncodingType,
 	OptionStreamChannels,
 	OptionTimeout,
+	OptionEvents,
 }
 
 // the above array of Options, wrapped in a Command
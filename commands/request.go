@@ -278,6 +278,18 @@ func getContext(base context.Context, req Request) (context.Context, error) {
 	return ctx, nil
 }
 
+// TimeoutError returns the error reported for a request that ran past the
+// deadline set with the global --timeout option, or nil if it did not.
+func TimeoutError(req Request) error {
+	ctx := req.Context()
+	if ctx == nil || ctx.Err() != context.DeadlineExceeded {
+		return nil
+	}
+
+	tout, _, _ := req.Option(TimeoutOpt).String()
+	return fmt.Errorf("command timed out after %s", tout)
+}
+
 func (r *request) InvocContext() *Context {
 	return &r.ctx
 }
@@ -0,0 +1,251 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	p2pbhost "gx/ipfs/QmQA5mdxru8Bh6dpC9PJfSkumqnmHgJX7knxSgBo5Lpime/go-libp2p/p2p/host/basic"
+	identify "gx/ipfs/QmQA5mdxru8Bh6dpC9PJfSkumqnmHgJX7knxSgBo5Lpime/go-libp2p/p2p/protocol/identify"
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	mamask "gx/ipfs/QmSMZwvs3n4GBikZ7hKzT17c3bk65FmyZo2JqtJ16swqCv/multiaddr-filter"
+	p2phost "gx/ipfs/QmUywuGNZoUKV8B9iyvup9bPkLiMrhTsyVMkeSXW5VxAfC/go-libp2p-host"
+	swarm "gx/ipfs/QmVkDnNm71vYyY6s6rXwtmyDYis3WkKyrEhMECwT6R12uJ/go-libp2p-swarm"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// announceCheckInterval is how often announced addresses are compared with
+// the addresses other peers observe us at
+const announceCheckInterval = time.Minute * 10
+
+// AddrPolicy decides which addresses may be used. An address is allowed if
+// it is in none of the denied networks and, when allowed networks are
+// given, in one of them. Addresses without an IP part are always allowed.
+type AddrPolicy struct {
+	Allow []*net.IPNet
+	Deny  []*net.IPNet
+}
+
+// ParseAddrPolicy builds an AddrPolicy from lists of multiaddr netmasks,
+// e.g. "/ip4/10.0.0.0/ipcidr/8". It returns nil if both lists are empty.
+func ParseAddrPolicy(allow, deny []string) (*AddrPolicy, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+
+	parse := func(masks []string) ([]*net.IPNet, error) {
+		var out []*net.IPNet
+		for _, s := range masks {
+			f, err := mamask.NewMask(s)
+			if err != nil {
+				return nil, fmt.Errorf("incorrectly formatted address mask in config: %s", s)
+			}
+			out = append(out, f)
+		}
+		return out, nil
+	}
+
+	p := new(AddrPolicy)
+	var err error
+	if p.Allow, err = parse(allow); err != nil {
+		return nil, err
+	}
+	if p.Deny, err = parse(deny); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Allowed returns whether the policy allows the given address
+func (p *AddrPolicy) Allowed(a ma.Multiaddr) bool {
+	ip := addrIP(a)
+	if ip == nil {
+		return true
+	}
+
+	for _, n := range p.Deny {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true
+	}
+	for _, n := range p.Allow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the addresses allowed by the policy
+func (p *AddrPolicy) Filter(addrs []ma.Multiaddr) []ma.Multiaddr {
+	var out []ma.Multiaddr
+	for _, a := range addrs {
+		if p.Allowed(a) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// addrIP returns the IP of the given address, or nil if it has none
+func addrIP(a ma.Multiaddr) net.IP {
+	for _, c := range ma.Split(a) {
+		p := c.Protocols()[0]
+		if p.Code == ma.P_IP4 || p.Code == ma.P_IP6 {
+			return net.ParseIP(strings.TrimPrefix(c.String(), "/"+p.Name+"/"))
+		}
+	}
+	return nil
+}
+
+// policyPeerstore is the view of the peerstore given to the swarm when a
+// dial policy is set. The swarm only dials the addresses it finds in the
+// peerstore, so hiding the forbidden ones keeps them from being dialed.
+type policyPeerstore struct {
+	pstore.Peerstore
+	self   peer.ID
+	policy *AddrPolicy
+}
+
+func (ps *policyPeerstore) Addrs(p peer.ID) []ma.Multiaddr {
+	addrs := ps.Peerstore.Addrs(p)
+	if p == ps.self {
+		return addrs
+	}
+	return ps.policy.Filter(addrs)
+}
+
+// AnnounceHost is the host of the node when an announce policy or announced
+// addresses are set. It replaces or filters all the addresses the host
+// announces: the listen addresses, the addresses peers observe it at and
+// the ones mapped by the NAT, like the AddrsFactory of later basic hosts.
+type AnnounceHost struct {
+	*p2pbhost.BasicHost
+	policy   *AddrPolicy
+	announce []ma.Multiaddr
+
+	ids *identify.IDService
+}
+
+// NewAnnounceHost wraps bh, announcing the addresses announce instead of
+// its own if any are given, and only the addresses allowed by policy if it
+// isn't nil.
+func NewAnnounceHost(bh *p2pbhost.BasicHost, policy *AddrPolicy, announce []ma.Multiaddr) *AnnounceHost {
+	h := &AnnounceHost{
+		BasicHost: bh,
+		policy:    policy,
+		announce:  announce,
+	}
+	// the identify service of the basic host tells peers about its own
+	// addresses, this one replaces its handler to tell them about ours
+	h.ids = identify.NewIDService(h)
+	return h
+}
+
+// Addrs returns the addresses to announce
+func (h *AnnounceHost) Addrs() []ma.Multiaddr {
+	addrs := h.announce
+	if len(addrs) == 0 {
+		addrs = h.BasicHost.Addrs()
+	}
+	if h.policy != nil {
+		addrs = h.policy.Filter(addrs)
+	}
+	return addrs
+}
+
+// basicHost returns the basic host behind h, which is either h itself or
+// wrapped in an AnnounceHost
+func basicHost(h p2phost.Host) (*p2pbhost.BasicHost, bool) {
+	switch h := h.(type) {
+	case *p2pbhost.BasicHost:
+		return h, true
+	case *AnnounceHost:
+		return h.BasicHost, true
+	default:
+		return nil, false
+	}
+}
+
+// SwarmNetwork returns the swarm network behind the given network
+func SwarmNetwork(n inet.Network) (*swarm.Network, bool) {
+	sn, ok := n.(*swarm.Network)
+	return sn, ok
+}
+
+// addrPolicyOpts reads the address policy from the config
+func addrPolicyOpts(cfg *config.Config, opts *ConstructPeerHostOpts) error {
+	var err error
+	pol := cfg.Swarm.AddrPolicy
+	opts.DialPolicy, err = ParseAddrPolicy(pol.DialAllow, pol.DialDeny)
+	if err != nil {
+		return err
+	}
+	opts.AnnouncePolicy, err = ParseAddrPolicy(pol.AnnounceAllow, pol.AnnounceDeny)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range cfg.Addresses.Announce {
+		a, err := ma.NewMultiaddr(s)
+		if err != nil {
+			return fmt.Errorf("incorrectly formatted announce address in config: %s", s)
+		}
+		opts.Announce = append(opts.Announce, a)
+	}
+	return nil
+}
+
+// checkAnnounced periodically warns about announced addresses that no peer
+// has observed us at. They are likely wrong, e.g. a port forwarding that is
+// not set up, and peers will fail to dial them.
+func checkAnnounced(ctx context.Context, h p2phost.Host, announce []ma.Multiaddr) {
+	bh, ok := basicHost(h)
+	if !ok || len(announce) == 0 {
+		return
+	}
+
+	t := time.NewTicker(announceCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+
+		observed := bh.IDService().OwnObservedAddrs()
+		if len(observed) == 0 {
+			continue
+		}
+		for _, a := range announce {
+			if !addrObserved(a, observed) {
+				log.Warningf("announced address %s has not been observed by any peer", a)
+			}
+		}
+	}
+}
+
+// addrObserved returns whether a has the same IP as one of the observed
+// addresses. Ports are not compared, as NATs usually remap them.
+func addrObserved(a ma.Multiaddr, observed []ma.Multiaddr) bool {
+	ip := addrIP(a)
+	if ip == nil {
+		return true
+	}
+	for _, o := range observed {
+		if oip := addrIP(o); oip != nil && oip.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	p2pbhost "gx/ipfs/QmQA5mdxru8Bh6dpC9PJfSkumqnmHgJX7knxSgBo5Lpime/go-libp2p/p2p/host/basic"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	testutil "gx/ipfs/Qma2j8dYePrvN5DoNgwh1uAuu3FFtEtrUQFmr737ws8nCp/go-libp2p-netutil"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+func mustAddrs(t *testing.T, ss ...string) []ma.Multiaddr {
+	var out []ma.Multiaddr
+	for _, s := range ss {
+		a, err := ma.NewMultiaddr(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func TestAddrPolicy(t *testing.T) {
+	if p, err := ParseAddrPolicy(nil, nil); p != nil || err != nil {
+		t.Fatal("empty policy should be nil")
+	}
+
+	if _, err := ParseAddrPolicy([]string{"10.0.0.0/8"}, nil); err == nil {
+		t.Fatal("expected an error for a malformed mask")
+	}
+
+	p, err := ParseAddrPolicy(
+		[]string{"/ip4/10.0.0.0/ipcidr/8", "/ip6/fd00::/ipcidr/8"},
+		[]string{"/ip4/10.1.0.0/ipcidr/16"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		"/ip4/10.0.0.1/tcp/4001":  true,
+		"/ip4/10.1.0.1/tcp/4001":  false,
+		"/ip4/1.2.3.4/tcp/4001":   false,
+		"/ip6/fd00::1/tcp/4001":   true,
+		"/ip6/::1/tcp/4001":       false,
+		"/ip4/10.2.3.4/udp/1/utp": true,
+	}
+	for s, allowed := range cases {
+		if p.Allowed(mustAddrs(t, s)[0]) != allowed {
+			t.Errorf("expected Allowed(%s) to be %t", s, allowed)
+		}
+	}
+
+	out := p.Filter(mustAddrs(t, "/ip4/10.0.0.1/tcp/1", "/ip4/1.2.3.4/tcp/1"))
+	if len(out) != 1 || out[0].String() != "/ip4/10.0.0.1/tcp/1" {
+		t.Fatalf("unexpected filter output: %s", out)
+	}
+}
+
+func TestPolicyPeerstore(t *testing.T) {
+	self := peer.ID("self")
+	other := peer.ID("other")
+
+	ps := pstore.NewPeerstore()
+	addrs := mustAddrs(t, "/ip4/10.0.0.1/tcp/1", "/ip4/1.2.3.4/tcp/1")
+	ps.AddAddrs(self, addrs, pstore.PermanentAddrTTL)
+	ps.AddAddrs(other, addrs, pstore.PermanentAddrTTL)
+
+	p, err := ParseAddrPolicy(nil, []string{"/ip4/10.0.0.0/ipcidr/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pps := &policyPeerstore{Peerstore: ps, self: self, policy: p}
+
+	if len(pps.Addrs(self)) != 2 {
+		t.Fatal("own addresses should not be filtered")
+	}
+	out := pps.Addrs(other)
+	if len(out) != 1 || out[0].String() != "/ip4/1.2.3.4/tcp/1" {
+		t.Fatalf("unexpected addresses: %s", out)
+	}
+}
+
+func TestAddrObserved(t *testing.T) {
+	observed := mustAddrs(t, "/ip4/1.2.3.4/tcp/52000")
+
+	if !addrObserved(mustAddrs(t, "/ip4/1.2.3.4/tcp/4001")[0], observed) {
+		t.Fatal("address with an observed IP should count as observed")
+	}
+	if addrObserved(mustAddrs(t, "/ip4/5.6.7.8/tcp/4001")[0], observed) {
+		t.Fatal("address with another IP should not count as observed")
+	}
+}
+
+func TestAnnounceHost(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := ParseAddrPolicy(nil, []string{"/ip4/10.0.0.0/ipcidr/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	announce := mustAddrs(t, "/ip4/1.2.3.4/tcp/4001", "/ip4/10.0.0.1/tcp/4001")
+	a := NewAnnounceHost(p2pbhost.New(testutil.GenSwarmNetwork(t, ctx)), p, announce)
+	b := p2pbhost.New(testutil.GenSwarmNetwork(t, ctx))
+
+	addrs := a.Addrs()
+	if len(addrs) != 1 || !addrs[0].Equal(announce[0]) {
+		t.Fatalf("unexpected announced addresses: %s", addrs)
+	}
+
+	// peers learn the announced addresses through identify
+	err = b.Connect(ctx, pstore.PeerInfo{ID: a.ID(), Addrs: a.Network().ListenAddresses()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, addr := range b.Peerstore().Addrs(a.ID()) {
+		if addr.Equal(announce[1]) {
+			t.Fatal("peer learned a denied address")
+		}
+		if addr.Equal(announce[0]) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("peer did not learn the announced address")
+	}
+}
@@ -0,0 +1,449 @@
+// Package autonat lets a node find out whether it is reachable from the
+// public internet, by asking the peers it is connected to to dial it back
+// on a new connection.
+package autonat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+	host "gx/ipfs/QmUywuGNZoUKV8B9iyvup9bPkLiMrhTsyVMkeSXW5VxAfC/go-libp2p-host"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+var log = logging.Logger("autonat")
+
+// ProtocolID is the protocol dial back requests are made with
+const ProtocolID = protocol.ID("/ipfs/autonat/0.1.0")
+
+var (
+	// DialBackTimeout bounds the time a dial back may take
+	DialBackTimeout = time.Second * 15
+
+	// ProbeInterval is the time between two reachability checks once the
+	// reachability is known
+	ProbeInterval = time.Minute * 15
+
+	// RetryInterval is the time between two reachability checks while the
+	// reachability is unknown
+	RetryInterval = time.Minute
+
+	// MaxDialBacks is the number of dial backs the service makes at once,
+	// requests over it are refused
+	MaxDialBacks = 8
+
+	// PeerRequestInterval is the time a peer must wait between two dial back
+	// requests to the service
+	PeerRequestInterval = time.Second * 30
+)
+
+// probePeers is the number of peers asked to dial back at every check
+const probePeers = 3
+
+const (
+	// maxMessageSize bounds the size of dial back requests and responses
+	maxMessageSize = 8 << 10
+
+	// maxRequestAddrs is the number of addresses a dial back request may
+	// hold
+	maxRequestAddrs = 32
+)
+
+var (
+	// ErrDialBackFailed is returned by DialBack when the peer could not
+	// dial us back on any of our addresses
+	ErrDialBackFailed = errors.New("peer could not dial back")
+
+	// ErrDialBackRefused is returned by DialBack when the peer refused to
+	// try, e.g. because it is busy. It tells nothing about our
+	// reachability.
+	ErrDialBackRefused = errors.New("peer refused to dial back")
+)
+
+// Reachability is whether the node can be dialed from the outside
+type Reachability int
+
+const (
+	Unknown Reachability = iota
+	Public
+	Private
+)
+
+func (r Reachability) String() string {
+	switch r {
+	case Public:
+		return "public"
+	case Private:
+		return "private"
+	default:
+		return "unknown"
+	}
+}
+
+type request struct {
+	Addrs []string
+}
+
+type response struct {
+	Addr  string `json:",omitempty"` // the address we were dialed at
+	Error string `json:",omitempty"` // why the dial back failed
+
+	// Refused is why the service did not try to dial back
+	Refused string `json:",omitempty"`
+}
+
+// Service answers the dial back requests of other peers. Dial backs are
+// made from a separate host, so that they use a new connection rather than
+// the one the request came in on. At most MaxDialBacks are made at once,
+// and a peer may only make a request every PeerRequestInterval.
+type Service struct {
+	newDialer func() (host.Host, error)
+	sem       chan struct{}
+
+	lk       sync.Mutex
+	dialer   host.Host
+	requests map[peer.ID]time.Time // time of the last request of each peer
+}
+
+// NewService registers a dial back service on h. newDialer is called once,
+// on the first request, to create the host dial backs are made from.
+func NewService(h host.Host, newDialer func() (host.Host, error)) *Service {
+	s := &Service{
+		newDialer: newDialer,
+		sem:       make(chan struct{}, MaxDialBacks),
+		requests:  make(map[peer.ID]time.Time),
+	}
+	h.SetStreamHandler(ProtocolID, s.handleStream)
+	return s
+}
+
+func (s *Service) handleStream(st inet.Stream) {
+	defer st.Close()
+
+	var req request
+	if err := json.NewDecoder(io.LimitReader(st, maxMessageSize)).Decode(&req); err != nil {
+		log.Debugf("bad dial back request: %s", err)
+		return
+	}
+
+	var resp response
+	if len(req.Addrs) > maxRequestAddrs {
+		resp = response{Refused: "too many addresses"}
+	} else {
+		resp = s.dialBack(st.Conn(), req.Addrs)
+	}
+	if err := json.NewEncoder(st).Encode(&resp); err != nil {
+		log.Debugf("failed to answer dial back request: %s", err)
+	}
+}
+
+func (s *Service) dialBack(c inet.Conn, addrs []string) response {
+	// The IP of a relayed connection is the one of the relay, which tells
+	// nothing about the peer
+	if isRelayAddr(c.RemoteMultiaddr()) {
+		return response{Refused: "request made over a relay"}
+	}
+
+	// Only dial addresses on the IP the request came from, so that the
+	// service cannot be used to make us dial arbitrary hosts.
+	obsIP := addrIP(c.RemoteMultiaddr())
+	var dial []ma.Multiaddr
+	for _, s := range addrs {
+		a, err := ma.NewMultiaddr(s)
+		if err != nil || isRelayAddr(a) {
+			continue
+		}
+		if ip := addrIP(a); ip != nil && ip.Equal(obsIP) {
+			dial = append(dial, a)
+		}
+	}
+	if len(dial) == 0 {
+		return response{Refused: "no dialable address"}
+	}
+
+	p := c.RemotePeer()
+	dialer, err := s.admit(p, time.Now())
+	if err != nil {
+		return response{Refused: err.Error()}
+	}
+
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	default:
+		return response{Refused: "too many dial back requests"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), DialBackTimeout)
+	defer cancel()
+
+	err = dialer.Connect(ctx, pstore.PeerInfo{ID: p, Addrs: dial})
+	if err != nil {
+		return response{Error: err.Error()}
+	}
+
+	var addr string
+	for _, dc := range dialer.Network().ConnsToPeer(p) {
+		addr = dc.RemoteMultiaddr().String()
+		// don't keep the connection around, the next dial back must be
+		// made from scratch
+		dc.Close()
+	}
+	return response{Addr: addr}
+}
+
+// admit returns the host to dial peer p back from, unless p made a request
+// less than PeerRequestInterval before now.
+func (s *Service) admit(p peer.ID, now time.Time) (host.Host, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if last, ok := s.requests[p]; ok && now.Sub(last) < PeerRequestInterval {
+		return nil, errors.New("too many requests from this peer")
+	}
+	for q, last := range s.requests {
+		if now.Sub(last) >= PeerRequestInterval {
+			delete(s.requests, q)
+		}
+	}
+	s.requests[p] = now
+
+	if s.dialer == nil {
+		d, err := s.newDialer()
+		if err != nil {
+			log.Errorf("failed to create dial back host: %s", err)
+			return nil, errors.New("dial back unavailable")
+		}
+		s.dialer = d
+	}
+	return s.dialer, nil
+}
+
+// DialBack asks peer p to dial h back, and returns the address it managed
+// to dial. It returns ErrDialBackFailed if p tried and failed, and
+// ErrDialBackRefused if p did not try.
+func DialBack(ctx context.Context, h host.Host, p peer.ID) (ma.Multiaddr, error) {
+	ctx, cancel := context.WithTimeout(ctx, DialBackTimeout*2)
+	defer cancel()
+
+	st, err := h.NewStream(ctx, p, ProtocolID)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	var req request
+	for _, a := range h.Addrs() {
+		req.Addrs = append(req.Addrs, a.String())
+	}
+	if err := json.NewEncoder(st).Encode(&req); err != nil {
+		return nil, err
+	}
+
+	var resp response
+	done := make(chan error, 1)
+	go func() {
+		done <- json.NewDecoder(io.LimitReader(st, maxMessageSize)).Decode(&resp)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, err
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if resp.Refused != "" {
+		log.Debugf("%s refused to dial back: %s", p, resp.Refused)
+		return nil, ErrDialBackRefused
+	}
+	if resp.Error != "" {
+		log.Debugf("%s could not dial back: %s", p, resp.Error)
+		return nil, ErrDialBackFailed
+	}
+	return ma.NewMultiaddr(resp.Addr)
+}
+
+// Client keeps track of the reachability of a host by regularly asking
+// the peers it is connected to to dial it back.
+type Client struct {
+	h host.Host
+
+	lk     sync.Mutex
+	status Reachability
+	addr   ma.Multiaddr
+	subs   []chan Reachability
+}
+
+// NewClient creates a client checking the reachability of h. Checks only
+// start once Run is called.
+func NewClient(h host.Host) *Client {
+	return &Client{h: h}
+}
+
+// Status returns the current reachability, and for a public node the
+// address it was last dialed back at
+func (c *Client) Status() (Reachability, ma.Multiaddr) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	return c.status, c.addr
+}
+
+// Subscribe returns a channel receiving the new reachability every time it
+// changes. Slow readers only get the latest change.
+func (c *Client) Subscribe() <-chan Reachability {
+	ch := make(chan Reachability, 1)
+	c.lk.Lock()
+	c.subs = append(c.subs, ch)
+	c.lk.Unlock()
+	return ch
+}
+
+// Run checks the reachability until the context is cancelled
+func (c *Client) Run(ctx context.Context) {
+	// give the node some time to connect to peers first
+	wait := RetryInterval
+	for {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+
+		c.probe(ctx)
+
+		if st, _ := c.Status(); st == Unknown {
+			wait = RetryInterval
+		} else {
+			wait = ProbeInterval
+		}
+	}
+}
+
+func (c *Client) probe(ctx context.Context) {
+	peers := c.h.Network().Peers()
+	for i := range peers {
+		j := rand.Intn(i + 1)
+		peers[i], peers[j] = peers[j], peers[i]
+	}
+
+	var asked, failed int
+	var addr ma.Multiaddr
+	for _, p := range peers {
+		if asked == probePeers || addr != nil {
+			break
+		}
+
+		a, err := DialBack(ctx, c.h, p)
+		switch err {
+		case nil:
+			asked++
+			addr = a
+		case ErrDialBackFailed:
+			asked++
+			failed++
+		default:
+			// most likely the peer does not run the service, or refused
+			// to dial back: ask another one
+			log.Debugf("dial back request to %s failed: %s", p, err)
+		}
+	}
+
+	c.update(addr, failed)
+}
+
+// update sets the reachability from the result of a check: public if any
+// peer could dial back, private if at least two peers tried and none could.
+func (c *Client) update(addr ma.Multiaddr, failed int) {
+	status := Unknown
+	switch {
+	case addr != nil:
+		status = Public
+	case failed >= 2:
+		status = Private
+	}
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if status == Unknown {
+		return
+	}
+	c.addr = addr
+	if status == c.status {
+		return
+	}
+	c.status = status
+
+	log.Infof("reachability is now %s", status)
+	for _, ch := range c.subs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- status
+	}
+}
+
+// PublicOnly returns a host through which stream handlers can be set that
+// only accept streams while c does not find the node private. This lets a
+// node stop serving protocols, such as the DHT, that expect to be able to
+// dial it back.
+func PublicOnly(h host.Host, c *Client) host.Host {
+	return &publicOnlyHost{Host: h, c: c}
+}
+
+type publicOnlyHost struct {
+	host.Host
+	c *Client
+}
+
+func (h *publicOnlyHost) gate(handler inet.StreamHandler) inet.StreamHandler {
+	return func(s inet.Stream) {
+		if st, _ := h.c.Status(); st == Private {
+			s.Close()
+			return
+		}
+		handler(s)
+	}
+}
+
+func (h *publicOnlyHost) SetStreamHandler(pid protocol.ID, handler inet.StreamHandler) {
+	h.Host.SetStreamHandler(pid, h.gate(handler))
+}
+
+func (h *publicOnlyHost) SetStreamHandlerMatch(pid protocol.ID, m func(string) bool, handler inet.StreamHandler) {
+	h.Host.SetStreamHandlerMatch(pid, m, h.gate(handler))
+}
+
+// isRelayAddr returns whether a goes through a circuit relay
+func isRelayAddr(a ma.Multiaddr) bool {
+	return a != nil && strings.Contains(a.String(), "/p2p-circuit")
+}
+
+// addrIP returns the IP of the given address, or nil if it has none
+func addrIP(a ma.Multiaddr) net.IP {
+	if a == nil {
+		return nil
+	}
+	for _, c := range ma.Split(a) {
+		p := c.Protocols()[0]
+		if p.Code == ma.P_IP4 || p.Code == ma.P_IP6 {
+			return net.ParseIP(strings.TrimPrefix(c.String(), "/"+p.Name+"/"))
+		}
+	}
+	return nil
+}
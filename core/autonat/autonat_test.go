@@ -0,0 +1,170 @@
+package autonat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mocknet "gx/ipfs/QmQA5mdxru8Bh6dpC9PJfSkumqnmHgJX7knxSgBo5Lpime/go-libp2p/p2p/net/mock"
+	host "gx/ipfs/QmUywuGNZoUKV8B9iyvup9bPkLiMrhTsyVMkeSXW5VxAfC/go-libp2p-host"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// setup returns a client host connected to a host running the service, and
+// the host the service dials back from
+func setup(t *testing.T, ctx context.Context) (mocknet.Mocknet, host.Host, host.Host, host.Host) {
+	mn := mocknet.New(ctx)
+	var hosts []host.Host
+	for i := 0; i < 3; i++ {
+		h, err := mn.GenPeer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hosts = append(hosts, h)
+	}
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	client, server, dialer := hosts[0], hosts[1], hosts[2]
+	NewService(server, func() (host.Host, error) { return dialer, nil })
+
+	err := client.Connect(ctx, pstore.PeerInfo{ID: server.ID(), Addrs: server.Addrs()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mn, client, server, dialer
+}
+
+func TestDialBack(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the client asks twice in a row
+	oldInterval := PeerRequestInterval
+	defer func() { PeerRequestInterval = oldInterval }()
+	PeerRequestInterval = 0
+
+	mn, client, server, dialer := setup(t, ctx)
+
+	addr, err := DialBack(ctx, client, server.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, a := range client.Addrs() {
+		if a.Equal(addr) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("dialed back at unknown address %s", addr)
+	}
+	if len(dialer.Network().ConnsToPeer(client.ID())) != 0 {
+		t.Fatal("dial back connection was kept open")
+	}
+
+	if err := mn.UnlinkPeers(dialer.ID(), client.ID()); err != nil {
+		t.Fatal(err)
+	}
+	_, err = DialBack(ctx, client, server.ID())
+	if err != ErrDialBackFailed {
+		t.Fatalf("expected ErrDialBackFailed, got %v", err)
+	}
+}
+
+func TestDialBackRefused(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, client, server, _ := setup(t, ctx)
+
+	if _, err := DialBack(ctx, client, server.ID()); err != nil {
+		t.Fatal(err)
+	}
+	// asking again right away is refused, which is not a failed dial back
+	if _, err := DialBack(ctx, client, server.ID()); err != ErrDialBackRefused {
+		t.Fatalf("expected ErrDialBackRefused, got %v", err)
+	}
+}
+
+func TestServiceAdmit(t *testing.T) {
+	s := &Service{
+		newDialer: func() (host.Host, error) { return nil, nil },
+		requests:  make(map[peer.ID]time.Time),
+	}
+	p1, p2 := peer.ID("peer1"), peer.ID("peer2")
+	now := time.Now()
+
+	if _, err := s.admit(p1, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.admit(p1, now.Add(time.Second)); err == nil {
+		t.Fatal("expected the second request of a peer to be refused")
+	}
+	if _, err := s.admit(p2, now.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.admit(p1, now.Add(PeerRequestInterval)); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.requests) != 1 {
+		t.Fatalf("expected the old requests to be forgotten, %d left", len(s.requests))
+	}
+}
+
+func TestRelayAddrsNotDialed(t *testing.T) {
+	relayed, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ/p2p-circuit")
+	if err != nil {
+		// the multiaddr library doesn't know circuit addresses
+		t.Skip(err)
+	}
+	if !isRelayAddr(relayed) {
+		t.Fatal("expected a circuit address to be a relay address")
+	}
+	direct, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/4001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isRelayAddr(direct) {
+		t.Fatal("expected a tcp address not to be a relay address")
+	}
+}
+
+func TestClientStatus(t *testing.T) {
+	c := NewClient(nil)
+	sub := c.Subscribe()
+
+	if st, _ := c.Status(); st != Unknown {
+		t.Fatalf("initial status is %s", st)
+	}
+
+	// a single failure is not conclusive
+	c.update(nil, 1)
+	if st, _ := c.Status(); st != Unknown {
+		t.Fatalf("status is %s after one failure", st)
+	}
+
+	c.update(nil, 2)
+	if st, _ := c.Status(); st != Private {
+		t.Fatalf("status is %s after two failures", st)
+	}
+	if st := <-sub; st != Private {
+		t.Fatalf("subscriber got %s", st)
+	}
+
+	addr, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/4001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.update(addr, 2)
+	st, a := c.Status()
+	if st != Public || !a.Equal(addr) {
+		t.Fatalf("status is %s at %s after a dial back", st, a)
+	}
+	if st := <-sub; st != Public {
+		t.Fatalf("subscriber got %s", st)
+	}
+}
@@ -51,6 +51,15 @@ type BootstrapConfig struct {
 	// for the bootstrap process to use. This makes it possible for clients
 	// to control the peers the process uses at any moment.
 	BootstrapPeers func() []pstore.PeerInfo
+
+	// FallbackPeers, if set, returns the peers to bootstrap from when none
+	// of the BootstrapPeers could be reached.
+	FallbackPeers func() []pstore.PeerInfo
+
+	// CachedPeers, if set, returns peers to connect to before the
+	// BootstrapPeers, such as the peers the node knew before it restarted.
+	// Their addresses must already be in the peerstore.
+	CachedPeers func() []peer.ID
 }
 
 // DefaultBootstrapConfig specifies default sane parameters for bootstrapping.
@@ -109,15 +118,8 @@ func Bootstrap(n *IpfsNode, cfg BootstrapConfig) (io.Closer, error) {
 }
 
 func bootstrapRound(ctx context.Context, host host.Host, cfg BootstrapConfig) error {
-
-	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectionTimeout)
-	defer cancel()
 	id := host.ID()
 
-	// get bootstrap peers from config. retrieving them here makes
-	// sure we remain observant of changes to client configuration.
-	peers := cfg.BootstrapPeers()
-
 	// determine how many bootstrap connections to open
 	connected := host.Network().Peers()
 	if len(connected) >= cfg.MinPeerThreshold {
@@ -128,6 +130,37 @@ func bootstrapRound(ctx context.Context, host host.Host, cfg BootstrapConfig) er
 	}
 	numToDial := cfg.MinPeerThreshold - len(connected)
 
+	// peers we knew are most likely to be close and reachable
+	if cfg.CachedPeers != nil {
+		numToDial -= bootstrapDialCached(ctx, host, cfg, cfg.CachedPeers(), numToDial)
+		if numToDial <= 0 {
+			return nil
+		}
+	}
+
+	// get bootstrap peers from config. retrieving them here makes
+	// sure we remain observant of changes to client configuration.
+	err := bootstrapDialSome(ctx, host, cfg, cfg.BootstrapPeers(), numToDial)
+	if err == nil || cfg.FallbackPeers == nil {
+		return err
+	}
+
+	// none of the primary peers answered, try the fallback list
+	fallback := cfg.FallbackPeers()
+	if len(fallback) == 0 {
+		return err
+	}
+	log.Warningf("bootstrap peers unreachable (%s), using the fallback peers", err)
+	return bootstrapDialSome(ctx, host, cfg, fallback, numToDial)
+}
+
+// bootstrapDialSome connects to up to numToDial of the given peers we are not
+// connected to yet.
+func bootstrapDialSome(ctx context.Context, host host.Host, cfg BootstrapConfig, peers []pstore.PeerInfo, numToDial int) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectionTimeout)
+	defer cancel()
+	id := host.ID()
+
 	// filter out bootstrap nodes we are already connected to
 	var notConnected []pstore.PeerInfo
 	for _, p := range peers {
@@ -153,6 +186,54 @@ func bootstrapRound(ctx context.Context, host host.Host, cfg BootstrapConfig) er
 	return nil
 }
 
+// maxCachedDialsPerDial bounds the number of cached peers tried for every
+// connection needed
+const maxCachedDialsPerDial = 4
+
+// bootstrapDialCached connects to up to numToDial of the given peers, in
+// order, and returns the number of new connections. Peers that can't be
+// reached are replaced by the next ones, within limits.
+func bootstrapDialCached(ctx context.Context, host host.Host, cfg BootstrapConfig, peers []peer.ID, numToDial int) int {
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectionTimeout)
+	defer cancel()
+
+	count, tried := 0, 0
+	for len(peers) > 0 && count < numToDial && tried < maxCachedDialsPerDial*numToDial {
+		var batch []peer.ID
+		for len(peers) > 0 && len(batch) < numToDial-count {
+			p := peers[0]
+			peers = peers[1:]
+			if p != host.ID() && host.Network().Connectedness(p) != inet.Connected {
+				batch = append(batch, p)
+			}
+		}
+		tried += len(batch)
+
+		var wg sync.WaitGroup
+		connected := make(chan struct{}, len(batch))
+		for _, p := range batch {
+			wg.Add(1)
+			go func(p peer.ID) {
+				defer wg.Done()
+				if err := host.Connect(ctx, pstore.PeerInfo{ID: p}); err != nil {
+					log.Debugf("failed to connect to cached peer %s: %s", p, err)
+					return
+				}
+				connected <- struct{}{}
+			}(p)
+		}
+		wg.Wait()
+		count += len(connected)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	log.Debugf("%s connected to %d of %d cached peers tried", host.ID(), count, tried)
+	return count
+}
+
 func bootstrapConnect(ctx context.Context, ph host.Host, peers []pstore.PeerInfo) error {
 	if len(peers) < 1 {
 		return ErrNotEnoughBootstrapPeers
@@ -5,28 +5,37 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	bstore "github.com/ipfs/go-ipfs/blocks/blockstore"
 	bserv "github.com/ipfs/go-ipfs/blockservice"
+	hooks "github.com/ipfs/go-ipfs/core/hooks"
 	offline "github.com/ipfs/go-ipfs/exchange/offline"
+	features "github.com/ipfs/go-ipfs/features"
 	filestore "github.com/ipfs/go-ipfs/filestore"
 	dag "github.com/ipfs/go-ipfs/merkledag"
 	path "github.com/ipfs/go-ipfs/path"
 	pin "github.com/ipfs/go-ipfs/pin"
+	gc "github.com/ipfs/go-ipfs/pin/gc"
 	repo "github.com/ipfs/go-ipfs/repo"
 	cfg "github.com/ipfs/go-ipfs/repo/config"
+	txn "github.com/ipfs/go-ipfs/repo/txn"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
 	uio "github.com/ipfs/go-ipfs/unixfs/io"
 
 	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
 	dsync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
 	metrics "gx/ipfs/QmRg1gKTHzc3CZXSKzem8aR4E3TubFhbgXwfVuWnSK5CC5/go-metrics-interface"
 	goprocessctx "gx/ipfs/QmSF8fPo3jgVBAy8fpdjjYqgG87dkJgUprRBHRd2tmfgpP/goprocess/context"
 	retry "gx/ipfs/QmUaGhKyLgTuYDdQsbKST1tYr2CVoix59rqaxdxqk2UbfK/retry-datastore"
 	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
 )
 
@@ -34,7 +43,7 @@ type BuildCfg struct {
 	// If online is set, the node will have networking enabled
 	Online bool
 
-	// ExtraOpts is a map of extra options used to configure the ipfs nodes creation
+	// ExtraOpts enables or disables features by name, overriding the config
 	ExtraOpts map[string]bool
 
 	// If permament then node should run more expensive processes
@@ -47,14 +56,10 @@ type BuildCfg struct {
 	Routing RoutingOption
 	Host    HostOption
 	Repo    repo.Repo
-}
-
-func (cfg *BuildCfg) getOpt(key string) bool {
-	if cfg.ExtraOpts == nil {
-		return false
-	}
 
-	return cfg.ExtraOpts[key]
+	// Clock drives periodic tasks such as ipns republishing. Defaults to
+	// the system clock.
+	Clock clock.Clock
 }
 
 func (cfg *BuildCfg) fillDefaults() error {
@@ -83,6 +88,10 @@ func (cfg *BuildCfg) fillDefaults() error {
 		cfg.Host = DefaultHostOption
 	}
 
+	if cfg.Clock == nil {
+		cfg.Clock = clock.New()
+	}
+
 	return nil
 }
 
@@ -129,6 +138,7 @@ func NewNode(ctx context.Context, cfg *BuildCfg) (*IpfsNode, error) {
 		mode:      offlineMode,
 		Repo:      cfg.Repo,
 		ctx:       ctx,
+		clock:     cfg.Clock,
 		Peerstore: pstore.NewPeerstore(),
 	}
 	if cfg.Online {
@@ -155,6 +165,28 @@ func isTooManyFDError(err error) bool {
 	return false
 }
 
+// loadDenyList reads the deny list at p, relative to the repo unless absolute
+func loadDenyList(r repo.Repo, p string) (*bstore.DenyList, error) {
+	if pr, ok := r.(interface {
+		Path() string
+	}); ok && !filepath.IsAbs(p) {
+		p = filepath.Join(pr.Path(), p)
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Datastore.DenyList: %s", err)
+	}
+	defer f.Close()
+
+	deny, err := bstore.ParseDenyList(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Datastore.DenyList: %s", err)
+	}
+	log.Infof("denying %d blocks listed in %s", deny.Len(), p)
+	return deny, nil
+}
+
 func setupNode(ctx context.Context, n *IpfsNode, cfg *BuildCfg) error {
 	// setup local peer ID (private key is loaded in online setup)
 	if err := n.loadID(); err != nil {
@@ -176,26 +208,62 @@ func setupNode(ctx context.Context, n *IpfsNode, cfg *BuildCfg) error {
 		return err
 	}
 
-	// TEMP: setting global sharding switch here
-	uio.UseHAMTSharding = conf.Experimental.ShardingEnabled
+	// the deny list sits right above the datastore, so that the caches
+	// can't bypass it. The filestore, which stores the blocks added with
+	// --nocopy outside of it, gets its own wrapper below.
+	var base bstore.Blockstore = bs
+	var deny *bstore.DenyList
+	if conf.Datastore.DenyList != "" {
+		deny, err = loadDenyList(n.Repo, conf.Datastore.DenyList)
+		if err != nil {
+			return err
+		}
+		base = bstore.NewDenyBlockstore(ctx, bs, deny)
+	}
+
+	n.Features = features.New(conf.Experimental, cfg.ExtraOpts)
+
+	n.IpnsValidator, err = newIpnsValidator(conf, n.Clock())
+	if err != nil {
+		return err
+	}
+
+	// TEMP: setting global sharding switch here. The low memory mode is
+	// passed to bitswap when it is constructed.
+	uio.UseHAMTSharding = n.Features.Enabled(features.Sharding)
+	if conf.Unixfs.ShardingThreshold != "" {
+		threshold, err := humanize.ParseBytes(conf.Unixfs.ShardingThreshold)
+		if err != nil {
+			return fmt.Errorf("invalid Unixfs.ShardingThreshold: %s", err)
+		}
+		uio.HAMTShardingSize = int(threshold)
+	}
+	if conf.Unixfs.PrefetchWindow != 0 {
+		uio.DefaultPrefetchWindow = conf.Unixfs.PrefetchWindow
+	}
 
 	opts.HasBloomFilterSize = conf.Datastore.BloomFilterSize
 	if !cfg.Permament {
 		opts.HasBloomFilterSize = 0
 	}
 
-	cbs, err := bstore.CachedBlockstore(ctx, bs, opts)
+	cbs, err := bstore.CachedBlockstore(ctx, base, opts)
 	if err != nil {
 		return err
 	}
 
 	n.BaseBlocks = cbs
 	n.GCLocker = bstore.NewGCLocker()
+	n.GCLeases = gc.NewLeases(n.GCLocker, n.Clock())
 	n.Blockstore = bstore.NewGCBlockstore(cbs, n.GCLocker)
 
-	if conf.Experimental.FilestoreEnabled {
+	if n.Features.Enabled(features.Filestore) {
 		n.Filestore = filestore.NewFilestore(bs, n.Repo.FileManager())
-		n.Blockstore = bstore.NewGCBlockstore(n.Filestore, n.GCLocker)
+		var fbs bstore.Blockstore = n.Filestore
+		if deny != nil {
+			fbs = bstore.NewDenyBlockstore(ctx, n.Filestore, deny)
+		}
+		n.Blockstore = bstore.NewGCBlockstore(fbs, n.GCLocker)
 	}
 
 	rcfg, err := n.Repo.Config()
@@ -209,7 +277,7 @@ func setupNode(ctx context.Context, n *IpfsNode, cfg *BuildCfg) error {
 
 	if cfg.Online {
 		do := setupDiscoveryOption(rcfg.Discovery)
-		if err := n.startOnlineServices(ctx, cfg.Routing, cfg.Host, do, cfg.getOpt("pubsub"), cfg.getOpt("mplex")); err != nil {
+		if err := n.startOnlineServices(ctx, cfg.Routing, cfg.Host, do, n.Features.Enabled(features.Pubsub), n.Features.Enabled(features.Mplex)); err != nil {
 			return err
 		}
 	} else {
@@ -220,20 +288,93 @@ func setupNode(ctx context.Context, n *IpfsNode, cfg *BuildCfg) error {
 	n.DAG = dag.NewDAGService(n.Blocks)
 
 	internalDag := dag.NewDAGService(bserv.New(n.Blockstore, offline.Exchange(n.Blockstore)))
-	n.Pinning, err = pin.LoadPinner(n.Repo.Datastore(), n.DAG, internalDag)
+
+	// the roots of the pin set and of the files API are updated atomically,
+	// an update interrupted by a crash before its root block reached the
+	// disk is rolled back here
+	roots, err := txn.NewRoots(n.Repo.Datastore(), rootsJournal(n.Repo), pin.DatastoreKey, filesRootDatastoreKey)
+	if err != nil {
+		return err
+	}
+	written := func(c *cid.Cid) bool {
+		has, err := n.Blockstore.Has(c)
+		return err == nil && has
+	}
+	err = roots.Recover(map[ds.Key]txn.Check{
+		pin.DatastoreKey:      written,
+		filesRootDatastoreKey: written,
+	})
+	if err != nil {
+		return err
+	}
+
+	n.Pinning, err = pin.LoadPinner(roots, n.DAG, internalDag)
 	if err != nil {
 		// TODO: we should move towards only running 'NewPinner' explicity on
 		// node init instead of implicitly here as a result of the pinner keys
 		// not being found in the datastore.
 		// this is kinda sketchy and could cause data loss
-		n.Pinning = pin.NewPinner(n.Repo.Datastore(), n.DAG, internalDag)
+		n.Pinning = pin.NewPinner(roots, n.DAG, internalDag)
+	}
+	n.Hooks, err = hooks.FromConfig(conf)
+	if err != nil {
+		return err
+	}
+	for _, url := range conf.Pinning.Hooks {
+		n.PinHooks = append(n.PinHooks, &pin.HTTPHook{URL: url})
+	}
+	ph := pinHooks{n: n, timeout: hooks.DefaultTimeout}
+	if conf.Pinning.HooksTimeout != "" {
+		ph.timeout, err = time.ParseDuration(conf.Pinning.HooksTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid Pinning.HooksTimeout: %s", err)
+		}
 	}
+	n.Hooks.AddWithTimeout([]string{hooks.OpPinAdd, hooks.OpPinRm, hooks.OpPinUpdate}, false, 0, ph)
 	n.Resolver = path.NewBasicResolver(n.DAG)
 
-	err = n.loadFilesRoot()
+	err = n.loadFilesRoot(roots)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// pinHooks runs the PinHooks of a node before the pin operations, with the
+// intent they are given as arguments. Each may run for timeout. The veto is
+// reported by the Hooks.
+type pinHooks struct {
+	n       *IpfsNode
+	timeout time.Duration
+}
+
+func (h pinHooks) Run(ctx context.Context, ev *hooks.Event) error {
+	in, ok := ev.Args.(*pin.Intent)
+	if !ok {
+		return nil
+	}
+	for _, ph := range h.n.PinHooks {
+		if err := h.run(ctx, ph, in); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h pinHooks) run(ctx context.Context, ph pin.Hook, in *pin.Intent) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	return ph.PinIntent(ctx, in)
+}
+
+// rootsJournal returns the path of the journal of the roots of r, "" if r
+// isn't on disk
+func rootsJournal(r repo.Repo) string {
+	if pr, ok := r.(interface {
+		Path() string
+	}); ok {
+		return filepath.Join(pr.Path(), txn.JournalFile)
+	}
+	return ""
+}
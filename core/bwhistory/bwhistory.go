@@ -0,0 +1,310 @@
+// Package bwhistory implements a bandwidth metrics reporter that keeps
+// track of the traffic of every (peer, protocol) pair, and retains a
+// history of periodic samples in a ring buffer. The counters of the pairs
+// idle for the whole history are dropped, so the totals of a peer start
+// from zero again when it comes back after that long.
+package bwhistory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
+
+	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+	metrics "gx/ipfs/QmdibiN2wzuuXXz4JvqQ1ZGW3eUkoAy1AWznHFau6iePCc/go-libp2p-metrics"
+)
+
+// DefaultWindow is the default length of time the history covers
+const DefaultWindow = time.Hour
+
+// DefaultInterval is the default time between two samples
+const DefaultInterval = time.Minute
+
+type pair struct {
+	peer  peer.ID
+	proto protocol.ID
+}
+
+type totals struct {
+	in, out int64
+}
+
+// counter counts the traffic of a pair
+type counter struct {
+	totals
+
+	// changed is set when the counter changed since the last sample, and
+	// active is the time of the last sample it had changed before
+	changed bool
+	active  time.Time
+}
+
+// sample is a snapshot of all counters at a point in time
+type sample struct {
+	time  time.Time
+	all   totals
+	pairs map[pair]totals
+}
+
+// Filter selects the traffic to report on. Empty fields match everything,
+// so the zero Filter selects all traffic.
+type Filter struct {
+	Peer     peer.ID
+	Protocol protocol.ID
+}
+
+func (f Filter) match(p pair) bool {
+	return (f.Peer == "" || f.Peer == p.peer) &&
+		(f.Protocol == "" || f.Protocol == p.proto)
+}
+
+// Point is the traffic matching a Filter at a point in time. Rates are
+// averaged since the previous point.
+type Point struct {
+	Time time.Time
+	metrics.Stats
+}
+
+// Reporter is a metrics.Reporter that forwards everything to the wrapped
+// reporter, while also counting traffic per (peer, protocol) pair and
+// sampling all counters at a fixed interval.
+type Reporter struct {
+	metrics.Reporter
+
+	lk    sync.Mutex
+	all   totals
+	pairs map[pair]*counter
+
+	// Clock drives the sampling ticker and dates the samples. It must be
+	// set before Run is called.
+	Clock clock.Clock
+
+	interval time.Duration
+	history  []sample // ring buffer, oldest sample at 'next' once full
+	next     int
+	full     bool
+}
+
+// New returns a Reporter wrapping r, that keeps the samples taken every
+// interval over the given window.
+func New(r metrics.Reporter, window, interval time.Duration) *Reporter {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	size := int(window / interval)
+	if size < 1 {
+		size = 1
+	}
+
+	return &Reporter{
+		Reporter: r,
+		pairs:    make(map[pair]*counter),
+		Clock:    clock.New(),
+		interval: interval,
+		history:  make([]sample, size),
+	}
+}
+
+func (r *Reporter) LogSentMessage(size int64) {
+	r.Reporter.LogSentMessage(size)
+
+	r.lk.Lock()
+	r.all.out += size
+	r.lk.Unlock()
+}
+
+func (r *Reporter) LogRecvMessage(size int64) {
+	r.Reporter.LogRecvMessage(size)
+
+	r.lk.Lock()
+	r.all.in += size
+	r.lk.Unlock()
+}
+
+func (r *Reporter) LogSentMessageStream(size int64, proto protocol.ID, p peer.ID) {
+	r.Reporter.LogSentMessageStream(size, proto, p)
+
+	r.lk.Lock()
+	r.counter(p, proto).out += size
+	r.lk.Unlock()
+}
+
+func (r *Reporter) LogRecvMessageStream(size int64, proto protocol.ID, p peer.ID) {
+	r.Reporter.LogRecvMessageStream(size, proto, p)
+
+	r.lk.Lock()
+	r.counter(p, proto).in += size
+	r.lk.Unlock()
+}
+
+// counter returns the counter for the given pair, marked as changed, the
+// caller must hold the lock.
+func (r *Reporter) counter(p peer.ID, proto protocol.ID) *counter {
+	k := pair{peer: p, proto: proto}
+	c, ok := r.pairs[k]
+	if !ok {
+		c = new(counter)
+		r.pairs[k] = c
+	}
+	c.changed = true
+	return c
+}
+
+// Interval returns the time between two samples
+func (r *Reporter) Interval() time.Duration {
+	return r.interval
+}
+
+// Run samples the counters every interval until the context is cancelled.
+func (r *Reporter) Run(ctx context.Context) {
+	t := r.Clock.NewTicker(r.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case now := <-t.C():
+			r.sample(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Reporter) sample(now time.Time) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.evict(now)
+	r.history[r.next] = r.snapshot(now)
+	r.next++
+	if r.next == len(r.history) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// evict drops the counters of the pairs that haven't changed for the whole
+// history, the caller must hold the lock. The samples still holding them
+// are told apart from the ones taken after by delta.
+func (r *Reporter) evict(now time.Time) {
+	window := time.Duration(len(r.history)) * r.interval
+	for k, c := range r.pairs {
+		if c.changed {
+			c.changed = false
+			c.active = now
+		} else if now.Sub(c.active) >= window {
+			delete(r.pairs, k)
+		}
+	}
+}
+
+// snapshot copies the current counters, the caller must hold the lock.
+func (r *Reporter) snapshot(now time.Time) sample {
+	s := sample{
+		time:  now,
+		all:   r.all,
+		pairs: make(map[pair]totals, len(r.pairs)),
+	}
+	for k, t := range r.pairs {
+		s.pairs[k] = t.totals
+	}
+	return s
+}
+
+// samples returns the retained samples, oldest first, the caller must hold
+// the lock.
+func (r *Reporter) samples() []sample {
+	if !r.full {
+		return r.history[:r.next]
+	}
+	out := make([]sample, 0, len(r.history))
+	out = append(out, r.history[r.next:]...)
+	return append(out, r.history[:r.next]...)
+}
+
+func (s sample) totals(f Filter) totals {
+	if f == (Filter{}) {
+		return s.all
+	}
+
+	var t totals
+	for k, v := range s.pairs {
+		if f.match(k) {
+			t.in += v.in
+			t.out += v.out
+		}
+	}
+	return t
+}
+
+func point(prev *sample, cur sample, f Filter) Point {
+	t := cur.totals(f)
+	p := Point{
+		Time: cur.time,
+		Stats: metrics.Stats{
+			TotalIn:  t.in,
+			TotalOut: t.out,
+		},
+	}
+
+	if prev != nil {
+		if secs := cur.time.Sub(prev.time).Seconds(); secs > 0 {
+			d := delta(*prev, cur, f)
+			p.RateIn = float64(d.in) / secs
+			p.RateOut = float64(d.out) / secs
+		}
+	}
+	return p
+}
+
+// delta returns the traffic matching the filter between prev and cur. A
+// pair missing from cur was dropped for being idle, and one missing from
+// prev was counted from zero.
+func delta(prev, cur sample, f Filter) totals {
+	if f == (Filter{}) {
+		return totals{in: cur.all.in - prev.all.in, out: cur.all.out - prev.all.out}
+	}
+
+	var d totals
+	for k, v := range cur.pairs {
+		if f.match(k) {
+			pv := prev.pairs[k]
+			d.in += v.in - pv.in
+			d.out += v.out - pv.out
+		}
+	}
+	return d
+}
+
+// Current returns the traffic matching the filter right now, with rates
+// averaged since the last sample.
+func (r *Reporter) Current(f Filter) Point {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	var prev *sample
+	if s := r.samples(); len(s) > 0 {
+		prev = &s[len(s)-1]
+	}
+	return point(prev, r.snapshot(r.Clock.Now()), f)
+}
+
+// History returns the retained series of samples for the traffic matching
+// the filter, oldest first.
+func (r *Reporter) History(f Filter) []Point {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	samples := r.samples()
+	out := make([]Point, len(samples))
+	for i := range samples {
+		var prev *sample
+		if i > 0 {
+			prev = &samples[i-1]
+		}
+		out[i] = point(prev, samples[i], f)
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package bwhistory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
+
+	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+	metrics "gx/ipfs/QmdibiN2wzuuXXz4JvqQ1ZGW3eUkoAy1AWznHFau6iePCc/go-libp2p-metrics"
+)
+
+func TestPeerAndProtocolFilter(t *testing.T) {
+	r := New(metrics.NewBandwidthCounter(), time.Minute, time.Second)
+
+	p1, p2 := peer.ID("peer1"), peer.ID("peer2")
+	bitswap, dht := protocol.ID("/ipfs/bitswap"), protocol.ID("/ipfs/dht")
+
+	r.LogRecvMessageStream(100, bitswap, p1)
+	r.LogRecvMessageStream(10, dht, p1)
+	r.LogSentMessageStream(1000, bitswap, p2)
+
+	cases := []struct {
+		f       Filter
+		in, out int64
+	}{
+		{Filter{Peer: p1}, 110, 0},
+		{Filter{Protocol: bitswap}, 100, 1000},
+		{Filter{Peer: p1, Protocol: bitswap}, 100, 0},
+		{Filter{Peer: p2, Protocol: dht}, 0, 0},
+	}
+
+	for _, c := range cases {
+		p := r.Current(c.f)
+		if p.TotalIn != c.in || p.TotalOut != c.out {
+			t.Errorf("%v: expected %d/%d, got %d/%d", c.f, c.in, c.out, p.TotalIn, p.TotalOut)
+		}
+	}
+}
+
+func TestHistoryRingBuffer(t *testing.T) {
+	r := New(metrics.NewBandwidthCounter(), 3*time.Second, time.Second)
+	p1 := peer.ID("peer1")
+	f := Filter{Peer: p1}
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		r.LogRecvMessageStream(10, "/ipfs/bitswap", p1)
+		r.sample(start.Add(time.Duration(i) * time.Second))
+	}
+
+	h := r.History(f)
+	if len(h) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(h))
+	}
+
+	for i, p := range h {
+		if exp := int64(30 + 10*i); p.TotalIn != exp {
+			t.Fatalf("sample %d: expected %d in, got %d", i, exp, p.TotalIn)
+		}
+		if !p.Time.Equal(start.Add(time.Duration(i+2) * time.Second)) {
+			t.Fatalf("sample %d has the wrong time", i)
+		}
+		if i > 0 && p.RateIn != 10 {
+			t.Fatalf("sample %d: expected rate of 10, got %f", i, p.RateIn)
+		}
+	}
+}
+
+func TestIdlePairsEvicted(t *testing.T) {
+	r := New(metrics.NewBandwidthCounter(), 3*time.Second, time.Second)
+	p1, p2 := peer.ID("peer1"), peer.ID("peer2")
+	f := Filter{Peer: p1}
+
+	start := time.Now()
+	r.LogRecvMessageStream(100, "/ipfs/bitswap", p1)
+	for i := 0; i < 5; i++ {
+		r.LogRecvMessageStream(10, "/ipfs/bitswap", p2)
+		r.sample(start.Add(time.Duration(i) * time.Second))
+	}
+
+	if _, ok := r.pairs[pair{peer: p1, proto: "/ipfs/bitswap"}]; ok {
+		t.Fatal("expected the idle pair to be dropped")
+	}
+	if len(r.pairs) != 1 {
+		t.Fatalf("expected 1 pair left, got %d", len(r.pairs))
+	}
+
+	// the peer comes back, its counter starts from zero
+	r.LogRecvMessageStream(20, "/ipfs/bitswap", p1)
+	r.sample(start.Add(5 * time.Second))
+
+	for i, p := range r.History(f) {
+		if p.RateIn < 0 {
+			t.Fatalf("sample %d: negative rate %f", i, p.RateIn)
+		}
+	}
+	if p := r.Current(f); p.TotalIn != 20 {
+		t.Fatalf("expected 20 in since the peer came back, got %d", p.TotalIn)
+	}
+}
+
+func TestRunMockClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	clk := clock.NewMock(start)
+	r := New(metrics.NewBandwidthCounter(), 3*time.Second, time.Second)
+	r.Clock = clk
+	p1 := peer.ID("peer1")
+
+	go r.Run(ctx)
+	clk.BlockUntil(1)
+
+	r.LogRecvMessageStream(10, "/ipfs/bitswap", p1)
+	clk.Add(time.Second)
+
+	var h []Point
+	for i := 0; i < 100 && len(h) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		h = r.History(Filter{Peer: p1})
+	}
+	if len(h) != 1 {
+		t.Fatalf("expected 1 sample, got %d", len(h))
+	}
+	if !h[0].Time.Equal(start.Add(time.Second)) {
+		t.Fatalf("expected the sample to be dated by the clock, got %s", h[0].Time)
+	}
+	if h[0].TotalIn != 10 {
+		t.Fatalf("expected 10 in, got %d", h[0].TotalIn)
+	}
+}
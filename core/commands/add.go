@@ -4,21 +4,22 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"strings"
 
 	bstore "github.com/ipfs/go-ipfs/blocks/blockstore"
 	blockservice "github.com/ipfs/go-ipfs/blockservice"
 	cmds "github.com/ipfs/go-ipfs/commands"
 	files "github.com/ipfs/go-ipfs/commands/files"
 	core "github.com/ipfs/go-ipfs/core"
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
 	"github.com/ipfs/go-ipfs/core/coreunix"
 	offline "github.com/ipfs/go-ipfs/exchange/offline"
+	features "github.com/ipfs/go-ipfs/features"
 	dag "github.com/ipfs/go-ipfs/merkledag"
 	dagtest "github.com/ipfs/go-ipfs/merkledag/test"
 	mfs "github.com/ipfs/go-ipfs/mfs"
 	ft "github.com/ipfs/go-ipfs/unixfs"
+	uio "github.com/ipfs/go-ipfs/unixfs/io"
 
-	mh "gx/ipfs/QmVGtdTZdTFaLsaj2RwdVG8jcjNNcp1DE914DKZ2kHmXHw/go-multihash"
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	"gx/ipfs/QmeWjRodbcZFKe5tMN7poEx3izym6osrLSnTLf9UjJZBbs/pb"
 )
@@ -27,21 +28,27 @@ import (
 var ErrDepthLimitExceeded = fmt.Errorf("depth limit exceeded")
 
 const (
-	quietOptionName       = "quiet"
-	quieterOptionName     = "quieter"
-	silentOptionName      = "silent"
-	progressOptionName    = "progress"
-	trickleOptionName     = "trickle"
-	wrapOptionName        = "wrap-with-directory"
-	hiddenOptionName      = "hidden"
-	onlyHashOptionName    = "only-hash"
-	chunkerOptionName     = "chunker"
-	pinOptionName         = "pin"
-	rawLeavesOptionName   = "raw-leaves"
-	noCopyOptionName      = "nocopy"
-	fstoreCacheOptionName = "fscache"
-	cidVersionOptionName  = "cid-version"
-	hashOptionName        = "hash"
+	quietOptionName         = "quiet"
+	quieterOptionName       = "quieter"
+	silentOptionName        = "silent"
+	progressOptionName      = "progress"
+	trickleOptionName       = "trickle"
+	wrapOptionName          = "wrap-with-directory"
+	hiddenOptionName        = "hidden"
+	onlyHashOptionName      = "only-hash"
+	chunkerOptionName       = "chunker"
+	pinOptionName           = "pin"
+	rawLeavesOptionName     = "raw-leaves"
+	noCopyOptionName        = "nocopy"
+	fstoreCacheOptionName   = "fscache"
+	cidVersionOptionName    = "cid-version"
+	hashOptionName          = "hash"
+	cidProfileOptionName    = "cid-profile"
+	excludeOptionName       = "exclude"
+	symlinksOptionName      = "symlinks"
+	skipSpecialOptionName   = "skip-special"
+	preserveModeOptionName  = "preserve-mode"
+	preserveMtimeOptionName = "preserve-mtime"
 )
 
 const adderOutChanSize = 8
@@ -71,6 +78,43 @@ its filename. For example:
 You can now refer to the added file in a gateway, like so:
 
   /ipfs/QmaG4FuMqEBnQNn3C8XJ5bpW8kLs7zq2ZXgHptJHbKDDVx/example.jpg
+
+The '--cid-profile' option fixes every parameter that affects the
+resulting hashes (cid version, hash function, chunker, layout, raw
+leaves and directory sharding threshold) to a named set, so the same data
+yields the same hashes on any machine and with any later release. It
+cannot be combined with the options it controls, and overrides
+Unixfs.ShardingThreshold. The available profiles are:
+
+  v0          cidv0, sha2-256, size-262144, balanced, no raw leaves
+  v1          cidv1, sha2-256, size-262144, balanced, raw leaves
+  v1-blake2b  cidv1, blake2b-256, size-262144, balanced, raw leaves
+
+All of them shard the directories above 256KiB. Experimental directory
+sharding must be disabled when using a profile.
+
+When adding recursively, paths can be left out with '--exclude', a
+comma-separated list of patterns, and with '.ipfsignore' files in the
+added directories. Both use the same syntax as .gitignore files: a
+pattern without a slash matches a name at any depth, a pattern with a
+slash is relative to the directory it was declared in, a trailing
+slash only matches directories, '**' matches any number of directories
+and a leading '!' includes a previously excluded path again. For example:
+
+  > ipfs add -r --exclude='*.o,/build/' myproject
+
+Patterns given with '--exclude' take precedence over '.ipfsignore'
+files. Excluded paths are never read or hashed.
+
+Symlinks inside added directories are stored as symlinks by default.
+Use '--symlinks=follow' to add the files and directories they point to
+instead, or '--symlinks=skip' to leave them out. Named pipes, sockets and
+devices cannot be added; '--skip-special' leaves them out instead of
+failing.
+
+'--preserve-mode' and '--preserve-mtime' record the permission bits and
+the modification time of added files and directories. 'ipfs get' and the
+/ipfs mount restore them. Recording them changes the resulting hashes.
 `,
 	},
 
@@ -87,13 +131,20 @@ You can now refer to the added file in a gateway, like so:
 		cmds.BoolOption(onlyHashOptionName, "n", "Only chunk and hash - do not write to disk."),
 		cmds.BoolOption(wrapOptionName, "w", "Wrap files with a directory object."),
 		cmds.BoolOption(hiddenOptionName, "H", "Include files that are hidden. Only takes effect on recursive add."),
+		cmds.StringOption(excludeOptionName, "Comma-separated gitignore-style patterns of paths to leave out. Only takes effect on recursive add."),
+		cmds.StringOption(symlinksOptionName, "How to add symlinks: preserve, follow or skip.").Default("preserve"),
+		cmds.BoolOption(skipSpecialOptionName, "Leave out named pipes, sockets and devices. Only takes effect on recursive add."),
+		cmds.BoolOption(preserveModeOptionName, "Record the permission bits of files and directories."),
+		cmds.BoolOption(preserveMtimeOptionName, "Record the modification time of files and directories."),
 		cmds.StringOption(chunkerOptionName, "s", "Chunking algorithm to use."),
 		cmds.BoolOption(pinOptionName, "Pin this object when adding.").Default(true),
 		cmds.BoolOption(rawLeavesOptionName, "Use raw blocks for leaf nodes. (experimental)"),
 		cmds.BoolOption(noCopyOptionName, "Add the file using filestore. (experimental)"),
 		cmds.BoolOption(fstoreCacheOptionName, "Check the filestore for pre-existing blocks. (experimental)"),
 		cmds.IntOption(cidVersionOptionName, "Cid version. Non-zero value will change default of 'raw-leaves' to true. (experimental)").Default(0),
-		cmds.StringOption(hashOptionName, "Hash function to use. Will set Cid version to 1 if used. (experimental)").Default("sha2-256"),
+		cmds.StringOption(hashOptionName, "Hash function to use. Will set Cid version to 1 if used. Default: Unixfs.HashFunction config, or sha2-256. (experimental)"),
+		cmds.StringOption(cidProfileOptionName, "Use a fixed set of parameters for reproducible hashes. Cannot be combined with options it controls."),
+		cmdenv.OfflineOption,
 	},
 	PreRun: func(req cmds.Request) error {
 		quiet, _, _ := req.Option(quietOptionName).Bool()
@@ -137,17 +188,12 @@ You can now refer to the added file in a gateway, like so:
 		return nil
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		n, err := req.InvocContext().GetNode()
+		n, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		cfg, err := n.Repo.Config()
-		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
-			return
-		}
 		// check if repo will exceed storage limit if added
 		// TODO: this doesn't handle the case if the hashed file is already in blocks (deduplicated)
 		// TODO: conditional GC is disabled due to it is somehow not possible to pass the size to the daemon
@@ -157,6 +203,9 @@ You can now refer to the added file in a gateway, like so:
 		//}
 
 		progress, _, _ := req.Option(progressOptionName).Bool()
+		if cmds.EventsRequested(req) {
+			progress = true
+		}
 		trickle, _, _ := req.Option(trickleOptionName).Bool()
 		wrap, _, _ := req.Option(wrapOptionName).Bool()
 		hash, _, _ := req.Option(onlyHashOptionName).Bool()
@@ -169,8 +218,11 @@ You can now refer to the added file in a gateway, like so:
 		fscache, _, _ := req.Option(fstoreCacheOptionName).Bool()
 		cidVer, _, _ := req.Option(cidVersionOptionName).Int()
 		hashFunStr, hfset, _ := req.Option(hashOptionName).String()
+		profileName, profileSet, _ := req.Option(cidProfileOptionName).String()
+		preserveMode, _, _ := req.Option(preserveModeOptionName).Bool()
+		preserveMtime, _, _ := req.Option(preserveMtimeOptionName).Bool()
 
-		if nocopy && !cfg.Experimental.FilestoreEnabled {
+		if nocopy && !n.Features.Enabled(features.Filestore) {
 			res.SetError(errors.New("filestore is not enabled, see https://git.io/vy4XN"),
 				cmds.ErrClient)
 			return
@@ -185,7 +237,46 @@ You can now refer to the added file in a gateway, like so:
 			return
 		}
 
-		if hfset && cidVer == 0 {
+		var profile coreunix.CidProfile
+		if profileSet {
+			profile, err = coreunix.LookupCidProfile(profileName)
+			if err != nil {
+				res.SetError(err, cmds.ErrClient)
+				return
+			}
+
+			for _, opt := range []string{chunkerOptionName, trickleOptionName, rawLeavesOptionName, cidVersionOptionName, hashOptionName, preserveModeOptionName, preserveMtimeOptionName} {
+				if req.Option(opt).Found() {
+					res.SetError(fmt.Errorf("option '--%s' cannot be combined with '--%s'", opt, cidProfileOptionName), cmds.ErrClient)
+					return
+				}
+			}
+
+			if uio.UseHAMTSharding {
+				res.SetError(errors.New("cid profiles cannot be used with directory sharding enabled"), cmds.ErrClient)
+				return
+			}
+
+			cidVer = profile.CidVersion
+			hashFunStr = profile.HashFunction
+			rawblks, rbset = profile.RawLeaves, true
+			chunker = profile.Chunker
+			trickle = profile.Trickle
+		}
+
+		if !hfset && !profileSet {
+			cfg, err := n.Repo.Config()
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			hashFunStr = cfg.Unixfs.HashFunction
+			if hashFunStr == "" {
+				hashFunStr = dag.DefaultHashFunction
+			}
+		}
+
+		if (hfset || hashFunStr != dag.DefaultHashFunction) && cidVer == 0 {
 			cidVer = 1
 		}
 
@@ -193,21 +284,12 @@ You can now refer to the added file in a gateway, like so:
 			rawblks = true
 		}
 
-		prefix, err := dag.PrefixForCidVersion(cidVer)
+		prefix, err := dag.PrefixForHash(cidVer, hashFunStr)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		hashFunCode, ok := mh.Names[strings.ToLower(hashFunStr)]
-		if !ok {
-			res.SetError(fmt.Errorf("unrecognized hash function: %s", strings.ToLower(hashFunStr)), cmds.ErrNormal)
-			return
-		}
-
-		prefix.MhType = hashFunCode
-		prefix.MhLength = -1
-
 		if hash {
 			nilnode, err := core.NewNode(n.Context(), &core.BuildCfg{
 				//TODO: need this to be true or all files
@@ -245,6 +327,7 @@ You can now refer to the added file in a gateway, like so:
 		res.SetOutput((<-chan interface{})(outChan))
 
 		fileAdder.Out = outChan
+		fileAdder.Hooks = n.Hooks
 		fileAdder.Chunker = chunker
 		fileAdder.Progress = progress
 		fileAdder.Hidden = hidden
@@ -255,6 +338,14 @@ You can now refer to the added file in a gateway, like so:
 		fileAdder.RawLeaves = rawblks
 		fileAdder.NoCopy = nocopy
 		fileAdder.Prefix = &prefix
+		fileAdder.PreserveMode = preserveMode
+		fileAdder.PreserveMtime = preserveMtime
+		if profileSet {
+			if err := profile.Apply(fileAdder); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
 
 		if hash {
 			md := dagtest.Mock()
@@ -307,7 +398,7 @@ You can now refer to the added file in a gateway, like so:
 		}()
 	},
 	PostRun: func(req cmds.Request, res cmds.Response) {
-		if res.Error() != nil {
+		if res.Error() != nil || cmds.EventsRequested(req) {
 			return
 		}
 		outChan, ok := res.Output().(<-chan interface{})
@@ -323,6 +414,14 @@ You can now refer to the added file in a gateway, like so:
 
 		progress, _, _ := req.Option(progressOptionName).Bool()
 
+		profileName, profileSet, _ := req.Option(cidProfileOptionName).String()
+		silent, _, _ := req.Option(silentOptionName).Bool()
+		if profileSet && !quiet && !silent {
+			if profile, err := coreunix.LookupCidProfile(profileName); err == nil {
+				fmt.Fprintf(res.Stderr(), "using cid profile %s\n", profile)
+			}
+		}
+
 		var bar *pb.ProgressBar
 		if progress {
 			bar = pb.New64(0).SetUnits(pb.U_BYTES)
@@ -405,4 +504,14 @@ You can now refer to the added file in a gateway, like so:
 		}
 	},
 	Type: coreunix.AddedObject{},
+	Events: func(v interface{}) *cmds.Event {
+		out, ok := v.(*coreunix.AddedObject)
+		if !ok {
+			return nil
+		}
+		if out.Hash == "" {
+			return cmds.ProgressEvent(out.Name, out.Bytes, 0, "bytes")
+		}
+		return cmds.ResultEvent(out)
+	},
 }
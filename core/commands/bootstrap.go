@@ -3,6 +3,7 @@ package commands
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"sort"
 
@@ -32,9 +33,11 @@ Running 'ipfs bootstrap' with no arguments will run 'ipfs bootstrap list'.
 	Type:       bootstrapListCmd.Type,
 
 	Subcommands: map[string]*cmds.Command{
-		"list": bootstrapListCmd,
-		"add":  bootstrapAddCmd,
-		"rm":   bootstrapRemoveCmd,
+		"list":    bootstrapListCmd,
+		"add":     bootstrapAddCmd,
+		"rm":      bootstrapRemoveCmd,
+		"check":   bootstrapCheckCmd,
+		"profile": bootstrapProfileCmd,
 	},
 }
 
@@ -429,6 +432,264 @@ func bootstrapRemoveAll(r repo.Repo, cfg *config.Config) ([]config.BootstrapPeer
 	return removed, nil
 }
 
+// BootstrapProfilesOutput lists the bootstrap profiles
+type BootstrapProfilesOutput struct {
+	Profiles []string
+	Fallback string
+}
+
+var bootstrapProfileCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Manage named bootstrap lists.",
+		ShortDescription: `
+Bootstrap profiles are named bootstrap lists kept in the config, e.g. one
+for a private network and one for the public network. 'use' makes one of
+them the bootstrap list. If the BootstrapFallback config option names a
+profile, the daemon bootstraps from its peers whenever none of the
+bootstrap list can be reached.
+` + bootstrapSecurityWarning,
+	},
+
+	Subcommands: map[string]*cmds.Command{
+		"ls":   bootstrapProfileLsCmd,
+		"show": bootstrapProfileShowCmd,
+		"save": bootstrapProfileSaveCmd,
+		"use":  bootstrapProfileUseCmd,
+		"rm":   bootstrapProfileRmCmd,
+	},
+}
+
+var bootstrapProfileLsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the bootstrap profiles.",
+	},
+
+	Run: func(req cmds.Request, res cmds.Response) {
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		out := &BootstrapProfilesOutput{Fallback: cfg.BootstrapFallback}
+		for name := range cfg.BootstrapProfiles {
+			out.Profiles = append(out.Profiles, name)
+		}
+		sort.Strings(out.Profiles)
+		res.SetOutput(out)
+	},
+	Type: BootstrapProfilesOutput{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			v, ok := res.Output().(*BootstrapProfilesOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			for _, name := range v.Profiles {
+				if name == v.Fallback {
+					fmt.Fprintf(buf, "%s (fallback)\n", name)
+				} else {
+					fmt.Fprintln(buf, name)
+				}
+			}
+			return buf, nil
+		},
+	},
+}
+
+var bootstrapProfileShowCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the peers of a bootstrap profile.",
+	},
+
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, false, "Name of the profile."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		peers, err := cfg.BootstrapProfilePeers(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		res.SetOutput(&BootstrapOutput{config.BootstrapPeerStrings(peers)})
+	},
+	Type: BootstrapOutput{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: bootstrapMarshaler,
+	},
+}
+
+var bootstrapProfileSaveCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Save a bootstrap profile.",
+		ShortDescription: `
+Saves the given peers, or the current bootstrap list if none is given, as
+the named profile, replacing any profile of the same name.
+`,
+	},
+
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, false, "Name of the profile."),
+		cmds.StringArg("peer", false, true, peerOptionDesc),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		name := req.Arguments()[0]
+		var peers []config.BootstrapPeer
+		if len(req.Arguments()) > 1 {
+			peers, err = config.ParseBootstrapPeers(req.Arguments()[1:])
+		} else {
+			peers, err = cfg.BootstrapPeers()
+		}
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if len(peers) == 0 {
+			res.SetError(errors.New("no bootstrap peers to save"), cmds.ErrClient)
+			return
+		}
+
+		if cfg.BootstrapProfiles == nil {
+			cfg.BootstrapProfiles = make(map[string][]string)
+		}
+		cfg.BootstrapProfiles[name] = config.BootstrapPeerStrings(peers)
+		if err := r.SetConfig(cfg); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(&BootstrapOutput{config.BootstrapPeerStrings(peers)})
+	},
+	Type: BootstrapOutput{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: bootstrapMarshaler,
+	},
+}
+
+var bootstrapProfileUseCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Replace the bootstrap list with a profile.",
+		ShortDescription: `
+Replaces the bootstrap list with the peers of the named profile and
+outputs them. Save the current list with 'ipfs bootstrap profile save'
+first to keep it.
+` + bootstrapSecurityWarning,
+	},
+
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, false, "Name of the profile."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		peers, err := cfg.BootstrapProfilePeers(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		cfg.SetBootstrapPeers(peers)
+		if err := r.SetConfig(cfg); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(&BootstrapOutput{config.BootstrapPeerStrings(peers)})
+	},
+	Type: BootstrapOutput{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: bootstrapMarshaler,
+	},
+}
+
+var bootstrapProfileRmCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline:          "Remove a bootstrap profile.",
+		ShortDescription: "Outputs the peers of the removed profile.",
+	},
+
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, false, "Name of the profile."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		name := req.Arguments()[0]
+		removed, ok := cfg.BootstrapProfiles[name]
+		if !ok {
+			res.SetError(fmt.Errorf("no bootstrap profile named %q", name), cmds.ErrNormal)
+			return
+		}
+		if name == cfg.BootstrapFallback {
+			res.SetError(fmt.Errorf("%q is the fallback profile, unset BootstrapFallback first", name), cmds.ErrNormal)
+			return
+		}
+
+		delete(cfg.BootstrapProfiles, name)
+		if err := r.SetConfig(cfg); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(&BootstrapOutput{removed})
+	},
+	Type: BootstrapOutput{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: bootstrapMarshaler,
+	},
+}
+
 const bootstrapSecurityWarning = `
 SECURITY WARNING:
 
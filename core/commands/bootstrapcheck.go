@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	swarm "gx/ipfs/QmVkDnNm71vYyY6s6rXwtmyDYis3WkKyrEhMECwT6R12uJ/go-libp2p-swarm"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+)
+
+// BootstrapCheckResult is the health of one bootstrap peer
+type BootstrapCheckResult struct {
+	Peer            string
+	Reachable       bool
+	Latency         string `json:",omitempty"`
+	Transport       string `json:",omitempty"`
+	AgentVersion    string `json:",omitempty"`
+	ProtocolVersion string `json:",omitempty"`
+	Error           string `json:",omitempty"`
+}
+
+// BootstrapCheckOutput is the output of 'ipfs bootstrap check'
+type BootstrapCheckOutput struct {
+	Results []BootstrapCheckResult
+}
+
+var bootstrapCheckCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Check that the bootstrap peers are reachable.",
+		ShortDescription: `
+Dials each peer of the bootstrap list, or of the given profile, at its
+bootstrap address and reports whether it answered, its ping latency, the
+transport used and the agent and protocol versions it announced.
+Peers to check can also be given as arguments.
+`,
+	},
+
+	Arguments: []cmds.Argument{
+		cmds.StringArg("peer", false, true, peerOptionDesc),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("profile", "Check the peers of this bootstrap profile."),
+		cmds.StringOption("timeout", "Time to wait for each peer.").Default("10s"),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if !n.OnlineMode() {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+
+		tout, _, _ := req.Option("timeout").String()
+		timeout, err := time.ParseDuration(tout)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		profile, _, _ := req.Option("profile").String()
+		var peers []config.BootstrapPeer
+		switch {
+		case len(req.Arguments()) > 0:
+			peers, err = config.ParseBootstrapPeers(req.Arguments())
+		case profile != "":
+			cfg, cerr := n.Repo.Config()
+			if cerr != nil {
+				res.SetError(cerr, cmds.ErrNormal)
+				return
+			}
+			peers, err = cfg.BootstrapProfilePeers(profile)
+		default:
+			cfg, cerr := n.Repo.Config()
+			if cerr != nil {
+				res.SetError(cerr, cmds.ErrNormal)
+				return
+			}
+			peers, err = cfg.BootstrapPeers()
+		}
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		var swrm *swarm.Swarm
+		if snet, ok := core.SwarmNetwork(n.PeerHost.Network()); ok {
+			swrm = snet.Swarm()
+		}
+
+		out := &BootstrapCheckOutput{Results: make([]BootstrapCheckResult, len(peers))}
+		var wg sync.WaitGroup
+		for i, bp := range peers {
+			wg.Add(1)
+			go func(i int, bp config.BootstrapPeer) {
+				defer wg.Done()
+				out.Results[i] = checkBootstrapPeer(req.Context(), n, swrm, bp, timeout)
+			}(i, bp)
+		}
+		wg.Wait()
+
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*BootstrapCheckOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
+			for _, r := range out.Results {
+				if !r.Reachable {
+					fmt.Fprintf(w, "%s\tunreachable\t%s\t\n", r.Peer, r.Error)
+					continue
+				}
+				lat := r.Latency
+				if lat == "" {
+					lat = "n/a"
+				}
+				fmt.Fprintf(w, "%s\tok\t%s\t%s\t%s\t%s\t\n", r.Peer, lat, r.Transport, r.ProtocolVersion, r.AgentVersion)
+			}
+			w.Flush()
+			return buf, nil
+		},
+	},
+	Type: BootstrapCheckOutput{},
+}
+
+// checkBootstrapPeer dials bp at its bootstrap address and pings it
+func checkBootstrapPeer(ctx context.Context, n *core.IpfsNode, swrm *swarm.Swarm, bp config.BootstrapPeer, timeout time.Duration) BootstrapCheckResult {
+	r := BootstrapCheckResult{
+		Peer:      bp.String(),
+		Transport: transportName(bp.Transport()),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// a previous failure must not keep us from dialing again
+	if swrm != nil {
+		swrm.Backoff().Clear(bp.ID())
+	}
+	pi := pstore.PeerInfo{ID: bp.ID(), Addrs: []ma.Multiaddr{bp.Transport()}}
+	if err := n.PeerHost.Connect(ctx, pi); err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Reachable = true
+
+	// the identify protocol ran when the connection was opened
+	if v, err := n.Peerstore.Get(pi.ID, "AgentVersion"); err == nil {
+		r.AgentVersion, _ = v.(string)
+	}
+	if v, err := n.Peerstore.Get(pi.ID, "ProtocolVersion"); err == nil {
+		r.ProtocolVersion, _ = v.(string)
+	}
+
+	pings, err := n.Ping.Ping(ctx, pi.ID)
+	if err != nil {
+		r.Error = fmt.Sprintf("ping: %s", err)
+		return r
+	}
+	select {
+	case t, ok := <-pings:
+		if ok {
+			r.Latency = t.String()
+		}
+	case <-ctx.Done():
+		r.Error = "ping: timed out"
+	}
+	return r
+}
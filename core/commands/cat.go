@@ -1,13 +1,21 @@
 package commands
 
 import (
+	"fmt"
 	"io"
+	"os"
+	"strings"
+	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
 	core "github.com/ipfs/go-ipfs/core"
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
 	coreunix "github.com/ipfs/go-ipfs/core/coreunix"
+	path "github.com/ipfs/go-ipfs/path"
+	uio "github.com/ipfs/go-ipfs/unixfs/io"
 
 	context "context"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 )
 
 const progressBarMinSize = 1024 * 1024 * 8 // show progress bar for outputs > 8MiB
@@ -16,13 +24,39 @@ var CatCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline:          "Show IPFS object data.",
 		ShortDescription: "Displays the data contained by an IPFS or IPNS object(s) at the given path.",
+		LongDescription: `
+Displays the data contained by an IPFS or IPNS object(s) at the given path.
+
+When several paths are given, their contents are concatenated, and
+--offset and --length select a byte range of the concatenation. Only the
+blocks needed for that range are fetched:
+
+  > ipfs cat --offset 1024 --length 512 /ipfs/QmHash
+
+With --tail, 'ipfs cat' does not exit at the end of the file. It keeps
+re-resolving the given IPNS name every --tail-interval and writes the bytes
+appended to the file since the last resolution. This is meant for logs
+that are updated by appending to the file and republishing the name:
+
+  > ipfs cat --tail /ipns/QmPeerID/service.log
+
+The new version of the file is expected to start with the old one; if it
+gets shorter, the command fails.
+`,
 	},
 
 	Arguments: []cmds.Argument{
 		cmds.StringArg("ipfs-path", true, true, "The path to the IPFS object(s) to be outputted.").EnableStdin(),
 	},
+	Options: []cmds.Option{
+		cmdenv.OfflineOption,
+		cmds.IntOption("offset", "o", "Byte offset to begin reading from.").Default(0),
+		cmds.IntOption("length", "l", "Maximum number of bytes to read, -1 for everything.").Default(-1),
+		cmds.BoolOption("tail", "Keep streaming bytes appended to an IPNS-published file.").Default(false),
+		cmds.StringOption("tail-interval", "How often to re-resolve the name with --tail.").Default("10s"),
+	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		node, err := req.InvocContext().GetNode()
+		node, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -35,7 +69,45 @@ var CatCmd = &cmds.Command{
 			}
 		}
 
-		readers, length, err := cat(req.Context(), node, req.Arguments())
+		offset, _, err := req.Option("offset").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if offset < 0 {
+			res.SetError(fmt.Errorf("cannot specify negative offset"), cmds.ErrNormal)
+			return
+		}
+
+		max, _, err := req.Option("length").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if max < -1 {
+			res.SetError(fmt.Errorf("cannot specify negative length"), cmds.ErrNormal)
+			return
+		}
+
+		tail, _, err := req.Option("tail").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if tail {
+			r, err := tailOutput(req, node, int64(offset))
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			if max >= 0 {
+				r = io.LimitReader(r, int64(max))
+			}
+			res.SetOutput(r)
+			return
+		}
+
+		readers, length, err := cat(req.Context(), node, req.Arguments(), int64(offset), int64(max))
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -65,16 +137,179 @@ var CatCmd = &cmds.Command{
 	},
 }
 
-func cat(ctx context.Context, node *core.IpfsNode, paths []string) ([]io.Reader, uint64, error) {
+// cat opens the given paths and returns readers for the range of their
+// concatenation starting at offset and spanning at most max bytes, or
+// everything when max is negative
+func cat(ctx context.Context, node *core.IpfsNode, paths []string, offset, max int64) ([]io.Reader, uint64, error) {
 	readers := make([]io.Reader, 0, len(paths))
 	length := uint64(0)
 	for _, fpath := range paths {
+		if max == 0 {
+			break
+		}
+
 		read, err := coreunix.Cat(ctx, node, fpath)
 		if err != nil {
 			return nil, 0, err
 		}
-		readers = append(readers, read)
-		length += uint64(read.Size())
+
+		size := int64(read.Size())
+		if offset >= size {
+			offset -= size
+			read.Close()
+			continue
+		}
+		if _, err := read.Seek(offset, os.SEEK_SET); err != nil {
+			return nil, 0, err
+		}
+		count := size - offset
+		offset = 0
+
+		if max >= 0 && count > max {
+			count = max
+		}
+		if max > 0 {
+			max -= count
+		}
+
+		readers = append(readers, io.LimitReader(read, count))
+		length += uint64(count)
 	}
 	return readers, length, nil
 }
+
+func tailOutput(req cmds.Request, node *core.IpfsNode, offset int64) (io.Reader, error) {
+	if len(req.Arguments()) != 1 {
+		return nil, fmt.Errorf("--tail takes exactly one path")
+	}
+	p := req.Arguments()[0]
+	if !strings.HasPrefix(p, "/ipns/") {
+		return nil, fmt.Errorf("--tail only works on /ipns paths, got %q", p)
+	}
+
+	ival, _, err := req.Option("tail-interval").String()
+	if err != nil {
+		return nil, err
+	}
+	interval, err := time.ParseDuration(ival)
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("tail interval must be positive, got %s", interval)
+	}
+
+	return newTailReader(req.Context(), node, p, offset, interval)
+}
+
+// tailReader reads a file published under an IPNS name and, instead of
+// returning io.EOF at its end, waits for the name to point at a longer
+// version of the file and continues from where it stopped.
+type tailReader struct {
+	ctx      context.Context
+	node     *core.IpfsNode
+	path     string
+	interval time.Duration
+
+	cur  uio.DagReader
+	root *cid.Cid
+	pos  int64
+}
+
+func newTailReader(ctx context.Context, node *core.IpfsNode, p string, offset int64, interval time.Duration) (*tailReader, error) {
+	tr := &tailReader{
+		ctx:      ctx,
+		node:     node,
+		path:     p,
+		interval: interval,
+		pos:      offset,
+	}
+
+	read, root, err := tr.open()
+	if err != nil {
+		return nil, err
+	}
+	if size := int64(read.Size()); tr.pos > size {
+		tr.pos = size
+	}
+	if _, err := read.Seek(tr.pos, os.SEEK_SET); err != nil {
+		read.Close()
+		return nil, err
+	}
+	tr.cur = read
+	tr.root = root
+	return tr, nil
+}
+
+func (tr *tailReader) open() (uio.DagReader, *cid.Cid, error) {
+	r := &path.Resolver{
+		DAG:         tr.node.DAG,
+		ResolveOnce: uio.ResolveUnixfsOnce,
+	}
+
+	nd, err := core.Resolve(tr.ctx, tr.node.Namesys, r, path.Path(tr.path))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	read, err := uio.NewDagReader(tr.ctx, nd, tr.node.DAG)
+	if err != nil {
+		return nil, nil, err
+	}
+	return read, nd.Cid(), nil
+}
+
+func (tr *tailReader) Read(p []byte) (int, error) {
+	for {
+		n, err := tr.cur.Read(p)
+		tr.pos += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		select {
+		case <-tr.ctx.Done():
+			tr.cur.Close()
+			return 0, tr.ctx.Err()
+		case <-time.After(tr.interval):
+		}
+
+		if err := tr.update(); err != nil {
+			tr.cur.Close()
+			return 0, err
+		}
+	}
+}
+
+// update re-resolves the name and switches to the new version of the file
+// if it changed
+func (tr *tailReader) update() error {
+	read, root, err := tr.open()
+	if err != nil {
+		// names can fail to resolve for a while, e.g. while the
+		// record is being republished
+		log.Warningf("cat --tail: resolving %s: %s", tr.path, err)
+		return nil
+	}
+	if root.Equals(tr.root) {
+		read.Close()
+		return nil
+	}
+
+	if size := int64(read.Size()); size < tr.pos {
+		read.Close()
+		return fmt.Errorf("%s was truncated from %d to %d bytes", tr.path, tr.pos, size)
+	}
+	if _, err := read.Seek(tr.pos, os.SEEK_SET); err != nil {
+		read.Close()
+		return err
+	}
+
+	tr.cur.Close()
+	tr.cur = read
+	tr.root = root
+	return nil
+}
@@ -0,0 +1,487 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+
+	mh "gx/ipfs/QmVGtdTZdTFaLsaj2RwdVG8jcjNNcp1DE914DKZ2kHmXHw/go-multihash"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	multibase "gx/ipfs/QmcxkxTVuURV2Ptse8TvkqH5BQDwV62X1x19JqqvbBzwUM/go-multibase"
+)
+
+var CidCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Convert and discover properties of CIDs.",
+		ShortDescription: `
+'ipfs cid' inspects and converts CIDs (content identifiers), without needing
+an ipfs repo.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"format": cidFmtCmd,
+		"base32": base32Cmd,
+		"bases":  basesCmd,
+		"codecs": codecsCmd,
+		"hashes": hashesCmd,
+	},
+}
+
+const cidFormatOptionDescription = `Printf style format string:
+
+  %% literal %
+  %b multibase name
+  %B multibase code
+  %v version string ("cidv0" or "cidv1")
+  %V version number
+  %c codec name
+  %C codec code
+  %h multihash name
+  %H multihash code
+  %L hash digest length
+  %m multihash encoded in base58btc
+  %d hash digest encoded in hex
+  %s the CID, in the requested base
+  %S the CID, in the requested base, without the multibase prefix
+  %P the CID prefix: cidv<version>-<codec>-<multihash>-<length>
+`
+
+var cidFmtCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Format and convert CIDs in various useful ways.",
+		ShortDescription: `
+'ipfs cid format' prints CIDs according to a format string, optionally
+converting them to another CID version or base.
+`,
+		LongDescription: `
+'ipfs cid format' prints CIDs according to a format string, optionally
+converting them to another CID version or base.
+
+` + cidFormatOptionDescription + `
+CIDs can also be read from stdin, one per line. A CID that cannot be parsed or
+converted is reported on stderr, and the other CIDs are still processed.
+
+Converting to CIDv0 only works for protobuf CIDs using a sha2-256 hash, and
+CIDv0 can only be encoded in base58btc: a CIDv0 is converted to CIDv1 when
+another base is requested.
+
+EXAMPLES
+
+Print the multihash of a CID:
+
+  $ ipfs cid format -f "%h %L %d" QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn
+  sha2-256 32 59948439065f29619ef41280cbb932be52c56d99c5966b65e0111239f098bbef
+
+Convert a CID to CIDv1 in base32:
+
+  $ ipfs cid format -v 1 -b base32 QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn
+  bafybeiczsscdsbs7ffqz55asqdf3smv6klcw3gofszvwlyarci47bgf354
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("cid", true, true, "CIDs to format.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("f", "Printf style format string.").Default("%s"),
+		cmds.StringOption("v", "CID version to convert to."),
+		cmds.StringOption("b", "Multibase to display CIDs in."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		fmtStr, _, _ := req.Option("f").String()
+		verStr, _, _ := req.Option("v").String()
+		baseStr, _, _ := req.Option("b").String()
+
+		opts := cidFormatOpts{fmtStr: fmtStr, verConv: -1}
+
+		if baseStr != "" {
+			enc, ok := multibaseNames[baseStr]
+			if !ok {
+				res.SetError(fmt.Errorf("unknown multibase: %s", baseStr), cmds.ErrClient)
+				return
+			}
+			opts.newBase = enc
+			opts.hasBase = true
+		}
+
+		switch verStr {
+		case "":
+			// convert to v1 when a base v0 cannot be encoded in is requested
+			if opts.hasBase && opts.newBase != multibase.Base58BTC {
+				opts.verConv = 1
+			}
+		case "0":
+			if opts.hasBase && opts.newBase != multibase.Base58BTC {
+				res.SetError(fmt.Errorf("cidv0 can only be encoded in base58btc"), cmds.ErrClient)
+				return
+			}
+			opts.verConv = 0
+		case "1":
+			opts.verConv = 1
+		default:
+			res.SetError(fmt.Errorf("invalid cid version: %s", verStr), cmds.ErrClient)
+			return
+		}
+
+		res.SetOutput(formatCids(req.Arguments(), opts))
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			outChan, ok := res.Output().(<-chan interface{})
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			err := procCidFormatOutput(outChan, res.Stdout(), res.Stderr())
+			if err != nil {
+				return nil, err
+			}
+			return nil, nil
+		},
+	},
+	Type: CidFormatRes{},
+}
+
+type CidFormatRes struct {
+	CidStr    string // Original Cid String passed in
+	Formatted string // Formated Result
+	ErrorMsg  string // Error
+}
+
+var base32Cmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Convert CIDs to base 32 CID version 1.",
+		ShortDescription: `
+'ipfs cid base32' converts CIDs to CIDv1 encoded in base32, which is suitable
+for case insensitive contexts such as subdomains. It is a shorthand for
+'ipfs cid format -v 1 -b base32'.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("cid", true, true, "CIDs to convert.").EnableStdin(),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		opts := cidFormatOpts{
+			fmtStr:  "%s",
+			newBase: multibase.Base32,
+			hasBase: true,
+			verConv: 1,
+		}
+		res.SetOutput(formatCids(req.Arguments(), opts))
+	},
+	Marshalers: cidFmtCmd.Marshalers,
+	Type:       cidFmtCmd.Type,
+}
+
+type cidFormatOpts struct {
+	fmtStr  string
+	newBase multibase.Encoding
+	hasBase bool
+	verConv int // -1 to keep the version
+}
+
+func formatCids(args []string, opts cidFormatOpts) <-chan interface{} {
+	outChan := make(chan interface{}, len(args))
+	for _, s := range args {
+		res := &CidFormatRes{CidStr: s}
+		out, err := formatCid(s, opts)
+		if err != nil {
+			res.ErrorMsg = err.Error()
+		} else {
+			res.Formatted = out
+		}
+		outChan <- res
+	}
+	close(outChan)
+	return outChan
+}
+
+func procCidFormatOutput(in <-chan interface{}, sout io.Writer, serr io.Writer) error {
+	someFailed := false
+	for v := range in {
+		r, ok := v.(*CidFormatRes)
+		if !ok {
+			return u.ErrCast()
+		}
+		if r.ErrorMsg != "" {
+			someFailed = true
+			fmt.Fprintf(serr, "%s: %s\n", r.CidStr, r.ErrorMsg)
+		} else {
+			fmt.Fprintln(sout, r.Formatted)
+		}
+	}
+	if someFailed {
+		return fmt.Errorf("some CIDs could not be formatted")
+	}
+	return nil
+}
+
+func formatCid(s string, opts cidFormatOpts) (string, error) {
+	s = strings.TrimSpace(s)
+	c, err := cid.Decode(s)
+	if err != nil {
+		return "", err
+	}
+
+	base := multibase.Encoding(multibase.Base58BTC)
+	if c.Prefix().Version != 0 {
+		base, _, err = multibase.Decode(s)
+		if err != nil {
+			return "", err
+		}
+	}
+	if opts.hasBase {
+		base = opts.newBase
+	}
+
+	switch opts.verConv {
+	case 0:
+		c, err = toCidV0(c)
+		if err != nil {
+			return "", err
+		}
+	case 1:
+		c = cid.NewCidV1(c.Type(), c.Hash())
+	}
+
+	return fmtCid(opts.fmtStr, base, c)
+}
+
+func toCidV0(c *cid.Cid) (*cid.Cid, error) {
+	if c.Type() != cid.DagProtobuf {
+		return nil, fmt.Errorf("can't convert non-protobuf cid to cidv0")
+	}
+	dec, err := mh.Decode(c.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if dec.Code != mh.SHA2_256 || dec.Length != 32 {
+		return nil, fmt.Errorf("can't convert cid to cidv0: cidv0 requires a sha2-256 hash of length 32")
+	}
+	return cid.NewCidV0(c.Hash()), nil
+}
+
+// fmtCid formats c according to fmtStr, see cidFormatOptionDescription
+func fmtCid(fmtStr string, base multibase.Encoding, c *cid.Cid) (string, error) {
+	p := c.Prefix()
+	dec, err := mh.Decode(c.Hash())
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	for i := 0; i < len(fmtStr); i++ {
+		if fmtStr[i] != '%' {
+			out.WriteByte(fmtStr[i])
+			continue
+		}
+		i++
+		if i >= len(fmtStr) {
+			return "", fmt.Errorf("premature end of format string")
+		}
+
+		switch fmtStr[i] {
+		case '%':
+			out.WriteByte('%')
+		case 'b':
+			out.WriteString(multibaseName(base))
+		case 'B':
+			out.WriteByte(byte(base))
+		case 'v':
+			fmt.Fprintf(&out, "cidv%d", p.Version)
+		case 'V':
+			fmt.Fprintf(&out, "%d", p.Version)
+		case 'c':
+			out.WriteString(codecName(p.Codec))
+		case 'C':
+			fmt.Fprintf(&out, "%d", p.Codec)
+		case 'h':
+			out.WriteString(hashName(dec.Code))
+		case 'H':
+			fmt.Fprintf(&out, "%d", dec.Code)
+		case 'L':
+			fmt.Fprintf(&out, "%d", dec.Length)
+		case 'm':
+			out.WriteString(c.Hash().B58String())
+		case 'd':
+			out.WriteString(hex.EncodeToString(dec.Digest))
+		case 's', 'S':
+			str, err := encodeCid(c, base)
+			if err != nil {
+				return "", err
+			}
+			if fmtStr[i] == 'S' && p.Version != 0 {
+				str = str[1:]
+			}
+			out.WriteString(str)
+		case 'P':
+			fmt.Fprintf(&out, "cidv%d-%s-%s-%d", p.Version, codecName(p.Codec), hashName(dec.Code), dec.Length)
+		default:
+			return "", fmt.Errorf("unrecognized specifier in format string: %%%c", fmtStr[i])
+		}
+	}
+	return out.String(), nil
+}
+
+func encodeCid(c *cid.Cid, base multibase.Encoding) (string, error) {
+	if c.Prefix().Version == 0 {
+		if base != multibase.Base58BTC {
+			return "", fmt.Errorf("cidv0 can only be encoded in base58btc")
+		}
+		return c.String(), nil
+	}
+	return multibase.Encode(base, c.Bytes())
+}
+
+var multibaseNames = map[string]multibase.Encoding{
+	"base16":       multibase.Base16,
+	"base32":       multibase.Base32,
+	"base58btc":    multibase.Base58BTC,
+	"base58flickr": multibase.Base58Flickr,
+	"base64":       multibase.Base64,
+}
+
+func multibaseName(enc multibase.Encoding) string {
+	for name, e := range multibaseNames {
+		if e == enc {
+			return name
+		}
+	}
+	return fmt.Sprintf("?%c", byte(enc))
+}
+
+var codecNames = map[uint64]string{
+	cid.Raw:          "raw",
+	cid.DagProtobuf:  "protobuf",
+	cid.DagCBOR:      "cbor",
+	cid.GitRaw:       "git-raw",
+	cid.EthBlock:     "eth-block",
+	cid.EthTx:        "eth-tx",
+	cid.BitcoinBlock: "bitcoin-block",
+	cid.BitcoinTx:    "bitcoin-tx",
+}
+
+func codecName(codec uint64) string {
+	if name, ok := codecNames[codec]; ok {
+		return name
+	}
+	return fmt.Sprintf("codec-%x", codec)
+}
+
+func hashName(code int) string {
+	if name, ok := mh.Codes[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("hash-%x", code)
+}
+
+type CodeAndName struct {
+	Code int
+	Name string
+}
+
+type CodeAndNameList struct {
+	Entries []CodeAndName
+}
+
+type byCode []CodeAndName
+
+func (s byCode) Len() int           { return len(s) }
+func (s byCode) Less(i, j int) bool { return s[i].Code < s[j].Code }
+func (s byCode) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+var codeAndNameMarshalers = cmds.MarshalerMap{
+	cmds.Text: func(res cmds.Response) (io.Reader, error) {
+		list, ok := res.Output().(*CodeAndNameList)
+		if !ok {
+			return nil, u.ErrCast()
+		}
+
+		buf := new(bytes.Buffer)
+		w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
+		for _, e := range list.Entries {
+			fmt.Fprintf(w, "%d\t%s\n", e.Code, e.Name)
+		}
+		w.Flush()
+		return buf, nil
+	},
+}
+
+var basesCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List available multibase encodings.",
+		ShortDescription: `
+'ipfs cid bases' lists the multibase encodings CIDs can be converted to, with
+the character prefixing the CIDs encoded in them.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		var out CodeAndNameList
+		for name, enc := range multibaseNames {
+			out.Entries = append(out.Entries, CodeAndName{Code: int(enc), Name: name})
+		}
+		sort.Sort(byCode(out.Entries))
+		res.SetOutput(&out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			list, ok := res.Output().(*CodeAndNameList)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
+			for _, e := range list.Entries {
+				fmt.Fprintf(w, "%c\t%s\n", e.Code, e.Name)
+			}
+			w.Flush()
+			return buf, nil
+		},
+	},
+	Type: CodeAndNameList{},
+}
+
+var codecsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List known CID codecs.",
+		ShortDescription: `
+'ipfs cid codecs' lists the CID codecs ipfs knows the name of.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		var out CodeAndNameList
+		for code, name := range codecNames {
+			out.Entries = append(out.Entries, CodeAndName{Code: int(code), Name: name})
+		}
+		sort.Sort(byCode(out.Entries))
+		res.SetOutput(&out)
+	},
+	Marshalers: codeAndNameMarshalers,
+	Type:       CodeAndNameList{},
+}
+
+var hashesCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List known multihash functions.",
+		ShortDescription: `
+'ipfs cid hashes' lists the multihash functions ipfs knows the name of.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		var out CodeAndNameList
+		for code, name := range mh.Codes {
+			out.Entries = append(out.Entries, CodeAndName{Code: code, Name: name})
+		}
+		sort.Sort(byCode(out.Entries))
+		res.SetOutput(&out)
+	},
+	Marshalers: codeAndNameMarshalers,
+	Type:       CodeAndNameList{},
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"testing"
+
+	multibase "gx/ipfs/QmcxkxTVuURV2Ptse8TvkqH5BQDwV62X1x19JqqvbBzwUM/go-multibase"
+)
+
+const (
+	testCidV0 = "QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"
+	testCidV1 = "bafybeiczsscdsbs7ffqz55asqdf3smv6klcw3gofszvwlyarci47bgf354"
+)
+
+func TestCidFormat(t *testing.T) {
+	cases := []struct {
+		in   string
+		opts cidFormatOpts
+		out  string
+	}{
+		{testCidV0, cidFormatOpts{fmtStr: "%s", verConv: -1}, testCidV0},
+		{testCidV0, cidFormatOpts{fmtStr: "%P", verConv: -1}, "cidv0-protobuf-sha2-256-32"},
+		{testCidV0, cidFormatOpts{fmtStr: "%b %v %c %h %L", verConv: -1}, "base58btc cidv0 protobuf sha2-256 32"},
+		{testCidV0, cidFormatOpts{fmtStr: "%d", verConv: -1}, "59948439065f29619ef41280cbb932be52c56d99c5966b65e0111239f098bbef"},
+		{testCidV0, cidFormatOpts{fmtStr: "%m", verConv: -1}, testCidV0},
+		{testCidV0, cidFormatOpts{fmtStr: "%s", newBase: multibase.Base32, hasBase: true, verConv: 1}, testCidV1},
+		{testCidV1, cidFormatOpts{fmtStr: "%s", verConv: 0}, testCidV0},
+		{testCidV1, cidFormatOpts{fmtStr: "%b %S", verConv: -1}, "base32 " + testCidV1[1:]},
+		{testCidV1, cidFormatOpts{fmtStr: "100%%", verConv: -1}, "100%"},
+	}
+
+	for _, c := range cases {
+		out, err := formatCid(c.in, c.opts)
+		if err != nil {
+			t.Errorf("formatting %s with %q: %s", c.in, c.opts.fmtStr, err)
+			continue
+		}
+		if out != c.out {
+			t.Errorf("formatting %s with %q: expected %q, got %q", c.in, c.opts.fmtStr, c.out, out)
+		}
+	}
+}
+
+func TestCidFormatErrors(t *testing.T) {
+	cases := []struct {
+		in   string
+		opts cidFormatOpts
+	}{
+		{"notacid", cidFormatOpts{fmtStr: "%s", verConv: -1}},
+		{testCidV0, cidFormatOpts{fmtStr: "%x", verConv: -1}},
+		{testCidV0, cidFormatOpts{fmtStr: "%", verConv: -1}},
+		{testCidV0, cidFormatOpts{fmtStr: "%s", newBase: multibase.Base32, hasBase: true, verConv: -1}},
+	}
+
+	for _, c := range cases {
+		if _, err := formatCid(c.in, c.opts); err == nil {
+			t.Errorf("expected an error formatting %s with %q", c.in, c.opts.fmtStr)
+		}
+	}
+}
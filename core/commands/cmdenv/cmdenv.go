@@ -0,0 +1,34 @@
+// Package cmdenv holds the helpers shared by the command packages to get
+// at the environment a command runs in.
+package cmdenv
+
+import (
+	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+)
+
+// OfflineOptionName is the name of OfflineOption
+const OfflineOptionName = "offline"
+
+// OfflineOption is accepted by the commands that can work with the local
+// repo alone, so they can be told not to touch the network even when the
+// daemon is online.
+var OfflineOption = cmds.BoolOption(OfflineOptionName, "Only use the local repo: do not fetch from or announce to the network.").Default(false)
+
+// GetNode returns the node to run req against: an offline view of it if
+// --offline was passed, the node itself otherwise.
+func GetNode(req cmds.Request) (*core.IpfsNode, error) {
+	n, err := req.InvocContext().GetNode()
+	if err != nil {
+		return nil, err
+	}
+
+	off, _, err := req.Option(OfflineOptionName).Bool()
+	if err != nil {
+		return nil, err
+	}
+	if off {
+		return n.Offline()
+	}
+	return n, nil
+}
@@ -9,10 +9,12 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"reflect"
 	"strings"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
 	repo "github.com/ipfs/go-ipfs/repo"
+	common "github.com/ipfs/go-ipfs/repo/common"
 	config "github.com/ipfs/go-ipfs/repo/config"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 
@@ -22,6 +24,10 @@ import (
 type ConfigField struct {
 	Key   string
 	Value interface{}
+
+	// Warnings are the problems of the key that was set, e.g. not being a
+	// known field
+	Warnings []string `json:",omitempty"`
 }
 
 var ConfigCmd = &cmds.Command{
@@ -67,6 +73,10 @@ Set the value of the 'Datastore.Path' key:
 			return
 		default:
 		}
+		if len(args) == 1 && isSecret(key) {
+			res.SetError(fmt.Errorf("cannot show DNSLink credentials through API"), cmds.ErrNormal)
+			return
+		}
 
 		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
 		if err != nil {
@@ -77,21 +87,27 @@ Set the value of the 'Datastore.Path' key:
 
 		var output *ConfigField
 		if len(args) == 2 {
-			value := args[1]
+			var value interface{} = args[1]
 
 			if parseJson, _, _ := req.Option("json").Bool(); parseJson {
 				var jsonVal interface{}
-				if err := json.Unmarshal([]byte(value), &jsonVal); err != nil {
+				if err := json.Unmarshal([]byte(args[1]), &jsonVal); err != nil {
 					err = fmt.Errorf("failed to unmarshal json. %s", err)
 					res.SetError(err, cmds.ErrNormal)
 					return
 				}
-
-				output, err = setConfig(r, key, jsonVal)
+				value = jsonVal
 			} else if isbool, _, _ := req.Option("bool").Bool(); isbool {
-				output, err = setConfig(r, key, value == "true")
-			} else {
-				output, err = setConfig(r, key, value)
+				value = args[1] == "true"
+			}
+
+			if err := checkPrograms(r, key, value); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			output, err = setConfig(r, key, value)
+			if err == nil {
+				output.Warnings, err = configWarnings(req.InvocContext().ConfigRoot, key)
 			}
 		} else {
 			output, err = getConfig(r, key)
@@ -100,12 +116,22 @@ Set the value of the 'Datastore.Path' key:
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+		if isSecret(key) {
+			output.Value = nil
+		} else {
+			scrubSecrets(key, output.Value)
+		}
 		res.SetOutput(output)
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
 			if len(res.Request().Arguments()) == 2 {
-				return nil, nil // dont output anything
+				// only output the warnings
+				vf, ok := res.Output().(*ConfigField)
+				if !ok || len(vf.Warnings) == 0 {
+					return nil, nil
+				}
+				return strings.NewReader(strings.Join(vf.Warnings, "\n") + "\n"), nil
 			}
 
 			v := res.Output()
@@ -131,6 +157,7 @@ Set the value of the 'Datastore.Path' key:
 		"show":    configShowCmd,
 		"edit":    configEditCmd,
 		"replace": configReplaceCmd,
+		"check":   configCheckCmd,
 	},
 }
 
@@ -168,6 +195,7 @@ included in the output of this command.
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+		scrubSecrets("", cfg)
 
 		output, err := config.HumanOutput(cfg)
 		if err != nil {
@@ -220,6 +248,60 @@ func scrubValue(m map[string]interface{}, key []string) error {
 	return nil
 }
 
+// isSecret returns whether key is one of config.DNSLinkSecretSelectors
+func isSecret(key string) bool {
+	for _, sel := range config.DNSLinkSecretSelectors {
+		if strings.EqualFold(key, sel) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrubSecrets removes the DNSLink credentials from value, the value of the
+// config key, "" for the whole config. Unlike scrubValue, it ignores the
+// credentials missing from value.
+func scrubSecrets(key string, value interface{}) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	prefix := ""
+	if key != "" {
+		prefix = strings.ToLower(key) + "."
+	}
+
+	for _, sel := range config.DNSLinkSecretSelectors {
+		if !strings.HasPrefix(strings.ToLower(sel), prefix) {
+			continue
+		}
+		path := strings.Split(sel[len(prefix):], ".")
+		cur := m
+		for i, k := range path {
+			// encoding/json matches the keys of the file case-insensitively
+			found := ""
+			for mk := range cur {
+				if strings.EqualFold(mk, k) {
+					found = mk
+					break
+				}
+			}
+			if found == "" {
+				break
+			}
+			if i == len(path)-1 {
+				delete(cur, found)
+				break
+			}
+			next, ok := cur[found].(map[string]interface{})
+			if !ok {
+				break
+			}
+			cur = next
+		}
+	}
+}
+
 var configEditCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Open the config file for editing in $EDITOR.",
@@ -239,8 +321,79 @@ variable set to your preferred text editor.
 		err = editConfig(filename)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
+			return
 		}
+
+		// the edits are saved, report what needs another edit
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		res.SetOutput(&ConfigCheckOutput{Problems: config.CheckBytes(data)})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: configCheckMarshaler,
 	},
+	Type: ConfigCheckOutput{},
+}
+
+// ConfigCheckOutput is the output of 'ipfs config check'
+type ConfigCheckOutput struct {
+	Problems []config.Problem
+}
+
+var configCheckCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Check the config file for errors.",
+		ShortDescription: `
+'ipfs config check' validates the config file against the fields and the
+types the node expects, and checks the values of durations and addresses.
+Errors stop the node from using the config. Unknown fields are ignored by
+the node, they are reported as warnings since they are often misspelled
+keys, and fail the check with --strict.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("strict", "Fail on warnings too.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		filename, err := config.Filename(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		res.SetOutput(&ConfigCheckOutput{Problems: config.CheckBytes(data)})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: configCheckMarshaler,
+	},
+	Type: ConfigCheckOutput{},
+}
+
+// configCheckMarshaler lists the problems found, and fails if there are
+// errors, or warnings with --strict
+func configCheckMarshaler(res cmds.Response) (io.Reader, error) {
+	v, ok := res.Output().(*ConfigCheckOutput)
+	if !ok {
+		return nil, u.ErrCast()
+	}
+
+	strict, _, _ := res.Request().Option("strict").Bool()
+	if len(config.Errors(v.Problems)) > 0 || (strict && len(v.Problems) > 0) {
+		return nil, config.ProblemsError(v.Problems)
+	}
+
+	buf := new(bytes.Buffer)
+	for _, p := range v.Problems {
+		fmt.Fprintln(buf, p)
+	}
+	return buf, nil
 }
 
 var configReplaceCmd = &cmds.Command{
@@ -289,6 +442,50 @@ func getConfig(r repo.Repo, key string) (*ConfigField, error) {
 	}, nil
 }
 
+// errPrograms is returned when changing a program run by the node through
+// the API
+var errPrograms = errors.New("cannot change Datastore.Encryption.PassphraseCommand or the Exec of Hooks through API, edit the config file instead")
+
+// programs returns the programs the node runs, set in cfg
+func programs(cfg *config.Config) [][]string {
+	var out [][]string
+	if len(cfg.Datastore.Encryption.PassphraseCommand) > 0 {
+		out = append(out, cfg.Datastore.Encryption.PassphraseCommand)
+	}
+	for _, h := range cfg.Hooks {
+		if len(h.Exec) > 0 {
+			out = append(out, h.Exec)
+		}
+	}
+	return out
+}
+
+// checkPrograms refuses setting key to value if it changes a program run by
+// the node: anyone reaching the API could run their own
+func checkPrograms(r repo.Repo, key string, value interface{}) error {
+	cur, err := r.Config()
+	if err != nil {
+		return err
+	}
+	m, err := config.ToMap(cur)
+	if err != nil {
+		return err
+	}
+	if err := common.MapSetKV(m, key, value); err != nil {
+		return nil // setting the key fails as well
+	}
+	next, err := config.FromMap(m)
+	if err != nil {
+		// a string, the only value converted when set, can't be a
+		// program or hold one: setting it fails as well
+		return nil
+	}
+	if !reflect.DeepEqual(programs(cur), programs(next)) {
+		return errPrograms
+	}
+	return nil
+}
+
 func setConfig(r repo.Repo, key string, value interface{}) (*ConfigField, error) {
 	err := r.SetConfigKey(key, value)
 	if err != nil {
@@ -308,15 +505,52 @@ func editConfig(filename string) error {
 	return cmd.Run()
 }
 
+// configWarnings returns the warnings about key in the config file of the
+// repo at root
+func configWarnings(root, key string) ([]string, error) {
+	filename, err := config.Filename(root)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for _, p := range config.ProblemsAt(config.CheckBytes(data), key) {
+		if p.Warning {
+			warnings = append(warnings, p.String())
+		}
+	}
+	return warnings, nil
+}
+
 func replaceConfig(r repo.Repo, file io.Reader) error {
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	if err := config.ProblemsError(config.Errors(config.CheckBytes(data))); err != nil {
+		return err
+	}
+
 	var cfg config.Config
-	if err := json.NewDecoder(file).Decode(&cfg); err != nil {
+	if err := json.Unmarshal(data, &cfg); err != nil {
 		return errors.New("failed to decode file as config")
 	}
 	if len(cfg.Identity.PrivKey) != 0 {
 		return errors.New("setting private key with API is not supported")
 	}
 
+	cur, err := r.Config()
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(programs(&cfg), programs(cur)) {
+		return errPrograms
+	}
+
 	keyF, err := getConfig(r, config.PrivKeySelector)
 	if err != nil {
 		return fmt.Errorf("Failed to get PrivKey")
@@ -329,5 +563,13 @@ func replaceConfig(r repo.Repo, file io.Reader) error {
 
 	cfg.Identity.PrivKey = pkstr
 
+	// 'ipfs config show' leaves the DNSLink credentials out, keep them
+	if cfg.DNSLink.Cloudflare.APIToken == "" {
+		cfg.DNSLink.Cloudflare.APIToken = cur.DNSLink.Cloudflare.APIToken
+	}
+	if cfg.DNSLink.RFC2136.Secret == "" {
+		cfg.DNSLink.RFC2136.Secret = cur.DNSLink.RFC2136.Secret
+	}
+
 	return r.SetConfig(&cfg)
 }
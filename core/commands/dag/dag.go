@@ -6,8 +6,12 @@ import (
 	"io/ioutil"
 	"strings"
 
+	blocks "github.com/ipfs/go-ipfs/blocks"
 	cmds "github.com/ipfs/go-ipfs/commands"
-	path "github.com/ipfs/go-ipfs/path"
+	core "github.com/ipfs/go-ipfs/core"
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	coreapi "github.com/ipfs/go-ipfs/core/coreapi"
+	dag "github.com/ipfs/go-ipfs/merkledag"
 
 	ipldcbor "gx/ipfs/QmNrbCt8j9DT5W9Pmjy2SdudT9k8GpaDr4sRuFix3BXhgR/go-ipld-cbor"
 	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
@@ -25,8 +29,13 @@ to deprecate and replace the existing 'ipfs object' command moving forward.
 		`,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"put": DagPutCmd,
-		"get": DagGetCmd,
+		"put":   DagPutCmd,
+		"get":   DagGetCmd,
+		"diff":  DagDiffCmd,
+		"patch": DagPatchCmd,
+	},
+	Options: []cmds.Option{
+		cmdenv.OfflineOption,
 	},
 }
 
@@ -48,9 +57,10 @@ into an object of the specified format.
 	Options: []cmds.Option{
 		cmds.StringOption("format", "f", "Format that the object will be added as.").Default("cbor"),
 		cmds.StringOption("input-enc", "Format that the input object will be.").Default("json"),
+		cmds.StringOption("hash", "Hash function to use. Default: Unixfs.HashFunction config, or sha2-256."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		n, err := req.InvocContext().GetNode()
+		n, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -64,42 +74,41 @@ into an object of the specified format.
 
 		ienc, _, _ := req.Option("input-enc").String()
 		format, _, _ := req.Option("format").String()
+		hashFunStr, hashFunSet, _ := req.Option("hash").String()
 
-		switch ienc {
-		case "json":
-			nd, err := convertJsonToType(fi, format)
+		if !hashFunSet {
+			cfg, err := n.Repo.Config()
 			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
-
-			c, err := n.DAG.Add(nd)
-			if err != nil {
-				res.SetError(err, cmds.ErrNormal)
-				return
+			hashFunStr = cfg.Unixfs.HashFunction
+			if hashFunStr == "" {
+				hashFunStr = dag.DefaultHashFunction
 			}
+		}
 
-			res.SetOutput(&OutputObject{Cid: c})
-			return
+		var nd node.Node
+		switch ienc {
+		case "json":
+			nd, err = convertJsonToType(fi, format)
 		case "raw":
-			nd, err := convertRawToType(fi, format)
-			if err != nil {
-				res.SetError(err, cmds.ErrNormal)
-				return
-			}
-
-			c, err := n.DAG.Add(nd)
-			if err != nil {
-				res.SetError(err, cmds.ErrNormal)
-				return
-			}
-
-			res.SetOutput(&OutputObject{Cid: c})
-			return
+			nd, err = convertRawToType(fi, format)
 		default:
-			res.SetError(fmt.Errorf("unrecognized input encoding: %s", ienc), cmds.ErrNormal)
+			err = fmt.Errorf("unrecognized input encoding: %s", ienc)
+		}
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+
+		c, err := addWithHash(n, nd, hashFunStr)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(&OutputObject{Cid: c})
 	},
 	Type: OutputObject{},
 	Marshalers: cmds.MarshalerMap{
@@ -119,44 +128,71 @@ var DagGetCmd = &cmds.Command{
 		Tagline: "Get a dag node from ipfs.",
 		ShortDescription: `
 'ipfs dag get' fetches a dag node from ipfs and prints it out in the specifed format.
+The path can go through the fields of dag-cbor objects, e.g. <cid>/foo/0 prints
+the first element of the list under the 'foo' key of the object.
 `,
 	},
 	Arguments: []cmds.Argument{
 		cmds.StringArg("ref", true, false, "The object to get").EnableStdin(),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		n, err := req.InvocContext().GetNode()
+		n, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		p, err := path.ParsePath(req.Arguments()[0])
+		p, err := coreapi.ParsePath(req.Arguments()[0])
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		obj, rem, err := n.Resolver.ResolveToLastNode(req.Context(), p)
+		nd, rest, err := coreapi.NewCoreAPI(n).ResolveToLastNode(req.Context(), p)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		var out interface{} = obj
-		if len(rem) > 0 {
-			final, _, err := obj.Resolve(rem)
+		var out interface{} = nd
+		if len(rest) > 0 {
+			out, _, err = nd.Resolve(rest)
 			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
-			out = final
 		}
-
 		res.SetOutput(out)
 	},
 }
 
+// addWithHash adds nd to the node's blockservice, hashed with the given
+// hash function
+func addWithHash(n *core.IpfsNode, nd node.Node, hashFunStr string) (*cid.Cid, error) {
+	code, err := dag.HashFunctionCode(hashFunStr)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := nd.Cid().Prefix()
+	if prefix.MhType == code {
+		return n.DAG.Add(nd)
+	}
+
+	prefix.MhType = code
+	prefix.MhLength = -1
+	c, err := prefix.Sum(nd.RawData())
+	if err != nil {
+		return nil, err
+	}
+
+	blk, err := blocks.NewBlockWithCid(nd.RawData(), c)
+	if err != nil {
+		return nil, err
+	}
+	return n.Blocks.AddBlock(blk)
+}
+
 func convertJsonToType(r io.Reader, format string) (node.Node, error) {
 	switch format {
 	case "cbor", "dag-cbor":
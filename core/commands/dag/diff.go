@@ -0,0 +1,172 @@
+package dagcmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	coreunix "github.com/ipfs/go-ipfs/core/coreunix"
+	dagutils "github.com/ipfs/go-ipfs/merkledag/utils"
+	path "github.com/ipfs/go-ipfs/path"
+
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
+)
+
+// DiffOutput is the output of 'dag diff'
+type DiffOutput struct {
+	Before     *cid.Cid
+	After      *cid.Cid
+	SizeBefore uint64
+	SizeAfter  uint64
+	Changes    []*coreunix.Change
+
+	// Blocks is only set with --blocks
+	Blocks *coreunix.BlockStats `json:",omitempty"`
+}
+
+var DagDiffCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the differences between two DAGs.",
+		ShortDescription: `
+'ipfs dag diff' lists the entries added, removed and changed between two
+DAGs, with their sizes, e.g. to review a website deploy before publishing it.
+`,
+		LongDescription: `
+'ipfs dag diff' lists the entries added, removed and changed between two
+DAGs, with their sizes, e.g. to review a website deploy before publishing it.
+
+Unixfs directories, sharded or not, are compared entry by entry and
+recursed into, directories are printed with a trailing '/'. Any other
+object, files included, is reported as a whole when it changed. Sizes are
+cumulative DAG sizes.
+
+With --blocks, the blocks of both DAGs are also compared, which tells how
+much data is shared between them, and how much a node holding the first
+DAG has to fetch to replicate the second. This walks both DAGs entirely.
+
+Example:
+
+   > ipfs dag diff --human $OLD_SITE $NEW_SITE
+   + css/        QmNew1            +1.2 kB
+   ~ index.html  QmOld2 -> QmNew2  +32 B
+   - old.html    QmOld3            -4.1 kB
+   Total: 1.2 MB -> 1.2 MB (-2.9 kB)
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("before", true, false, "The DAG to diff against."),
+		cmds.StringArg("after", true, false, "The DAG to diff."),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("blocks", "Compare the blocks of both DAGs.").Default(false),
+		cmds.BoolOption("human", "Print sizes in human readable form.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := cmdenv.GetNode(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		ctx := req.Context()
+		var nodes []node.Node
+		for _, arg := range req.Arguments() {
+			p, err := path.ParsePath(arg)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			nd, err := core.Resolve(ctx, n.Namesys, n.Resolver, p)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			nodes = append(nodes, nd)
+		}
+		a, b := nodes[0], nodes[1]
+
+		out := &DiffOutput{Before: a.Cid(), After: b.Cid()}
+		if out.SizeBefore, err = a.Size(); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if out.SizeAfter, err = b.Size(); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		out.Changes, err = coreunix.Diff(ctx, n.DAG, a, b)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if blocks, _, _ := req.Option("blocks").Bool(); blocks {
+			out.Blocks, err = coreunix.CompareBlocks(ctx, n.DAG, a.Cid(), b.Cid())
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
+		res.SetOutput(out)
+	},
+	Type: DiffOutput{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*DiffOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			human, _, err := res.Request().Option("human").Bool()
+			if err != nil {
+				return nil, err
+			}
+			size := func(s uint64) string {
+				if human {
+					return humanize.Bytes(s)
+				}
+				return fmt.Sprintf("%d B", s)
+			}
+			delta := func(before, after uint64) string {
+				if after >= before {
+					return "+" + size(after-before)
+				}
+				return "-" + size(before-after)
+			}
+
+			buf := new(bytes.Buffer)
+			wtr := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+			for _, c := range out.Changes {
+				name := c.Path
+				if c.Dir {
+					name += "/"
+				}
+				switch c.Type {
+				case dagutils.Add:
+					fmt.Fprintf(wtr, "+ %s\t%s\t%s\n", name, c.After, delta(0, c.SizeAfter))
+				case dagutils.Remove:
+					fmt.Fprintf(wtr, "- %s\t%s\t%s\n", name, c.Before, delta(c.SizeBefore, 0))
+				case dagutils.Mod:
+					fmt.Fprintf(wtr, "~ %s\t%s -> %s\t%s\n", name, c.Before, c.After, delta(c.SizeBefore, c.SizeAfter))
+				}
+			}
+			wtr.Flush()
+
+			fmt.Fprintf(buf, "Total: %s -> %s (%s)\n", size(out.SizeBefore), size(out.SizeAfter), delta(out.SizeBefore, out.SizeAfter))
+			if out.Blocks != nil {
+				fmt.Fprintf(buf, "Blocks: %s shared, %s only before, %s only after\n",
+					size(out.Blocks.Shared), size(out.Blocks.OnlyBefore), size(out.Blocks.OnlyAfter))
+			}
+			return buf, nil
+		},
+	},
+}
@@ -0,0 +1,486 @@
+package dagcmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	dag "github.com/ipfs/go-ipfs/merkledag"
+	path "github.com/ipfs/go-ipfs/path"
+	ft "github.com/ipfs/go-ipfs/unixfs"
+	uio "github.com/ipfs/go-ipfs/unixfs/io"
+
+	ipldcbor "gx/ipfs/QmNrbCt8j9DT5W9Pmjy2SdudT9k8GpaDr4sRuFix3BXhgR/go-ipld-cbor"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
+)
+
+var DagPatchCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Create a new dag node based on an existing one.",
+		ShortDescription: `
+'ipfs dag patch <cmd> <root> <path> ...' builds a new DAG from root with a
+single change at path, and prints the cid of the new root.
+
+Only the nodes along the path are fetched and rewritten: the rest of the
+DAG is linked to as is, so patching a large DAG is cheap. Paths go through
+unixfs directories, sharded or not, other protobuf nodes and dag-cbor
+nodes, following links and fields within them alike.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"add-link": dagPatchAddLinkCmd,
+		"rm-link":  dagPatchRmLinkCmd,
+		"set":      dagPatchSetCmd,
+	},
+}
+
+var dagPatchAddLinkCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Link a node at a path.",
+		ShortDescription: `
+Sets the entry at path to a link to ref, replacing any existing one. In a
+unixfs directory this adds a directory entry; in a dag-cbor node this sets
+the field to a link.
+
+Example:
+
+    $ ipfs dag patch add-link $ROOT docs/index.html $PAGE
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("root", true, false, "The DAG to patch."),
+		cmds.StringArg("path", true, false, "Where to add the link, relative to root."),
+		cmds.StringArg("ref", true, false, "The node to link to."),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("create", "p", "Create missing intermediate directories or maps.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		create, _, _ := req.Option("create").Bool()
+		runPatch(req, res, func(n *core.IpfsNode) (*patchOp, error) {
+			target, err := resolveArg(req.Context(), n, req.Arguments()[2])
+			if err != nil {
+				return nil, err
+			}
+			return &patchOp{kind: patchAddLink, target: target, create: create}, nil
+		})
+	},
+	Type: OutputObject{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: patchMarshaler,
+	},
+}
+
+var dagPatchRmLinkCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Remove the entry at a path.",
+		ShortDescription: `
+Removes the link, directory entry or dag-cbor field at path.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("root", true, false, "The DAG to patch."),
+		cmds.StringArg("path", true, false, "The entry to remove, relative to root."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		runPatch(req, res, func(*core.IpfsNode) (*patchOp, error) {
+			return &patchOp{kind: patchRmLink}, nil
+		})
+	},
+	Type: OutputObject{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: patchMarshaler,
+	},
+}
+
+var dagPatchSetCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Set a field of a dag-cbor node.",
+		ShortDescription: `
+Sets the dag-cbor field at path to a JSON value. Links are written as
+{"/": "<cid>"}, as with 'ipfs dag put'.
+
+Example:
+
+    $ ipfs dag patch set $ROOT meta/version '"1.2.0"'
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("root", true, false, "The DAG to patch."),
+		cmds.StringArg("path", true, false, "The field to set, relative to root."),
+		cmds.StringArg("value", true, false, "The JSON value to set it to."),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("create", "p", "Create missing intermediate maps.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		create, _, _ := req.Option("create").Bool()
+		runPatch(req, res, func(*core.IpfsNode) (*patchOp, error) {
+			v, err := parseCborValue(req.Arguments()[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value: %s", err)
+			}
+			return &patchOp{kind: patchSet, value: v, create: create}, nil
+		})
+	},
+	Type: OutputObject{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: patchMarshaler,
+	},
+}
+
+func patchMarshaler(res cmds.Response) (io.Reader, error) {
+	out, ok := res.Output().(*OutputObject)
+	if !ok {
+		return nil, u.ErrCast()
+	}
+	return strings.NewReader(out.Cid.String() + "\n"), nil
+}
+
+// runPatch applies the operation built by mkop to the root and path
+// arguments of req
+func runPatch(req cmds.Request, res cmds.Response, mkop func(*core.IpfsNode) (*patchOp, error)) {
+	n, err := cmdenv.GetNode(req)
+	if err != nil {
+		res.SetError(err, cmds.ErrNormal)
+		return
+	}
+	ctx := req.Context()
+
+	root, err := resolveArg(ctx, n, req.Arguments()[0])
+	if err != nil {
+		res.SetError(err, cmds.ErrNormal)
+		return
+	}
+
+	segs := path.SplitList(strings.Trim(req.Arguments()[1], "/"))
+	if len(segs) == 0 || segs[0] == "" {
+		res.SetError(fmt.Errorf("path must not be empty"), cmds.ErrClient)
+		return
+	}
+
+	op, err := mkop(n)
+	if err != nil {
+		res.SetError(err, cmds.ErrNormal)
+		return
+	}
+
+	nnd, err := patchNode(ctx, n.DAG, root, segs, op)
+	if err != nil {
+		res.SetError(err, cmds.ErrNormal)
+		return
+	}
+
+	c, err := n.DAG.Add(nnd)
+	if err != nil {
+		res.SetError(err, cmds.ErrNormal)
+		return
+	}
+	res.SetOutput(&OutputObject{Cid: c})
+}
+
+func resolveArg(ctx context.Context, n *core.IpfsNode, arg string) (node.Node, error) {
+	p, err := path.ParsePath(arg)
+	if err != nil {
+		return nil, err
+	}
+	return core.Resolve(ctx, n.Namesys, n.Resolver, p)
+}
+
+const (
+	patchAddLink = iota
+	patchRmLink
+	patchSet
+)
+
+// patchOp is the change made at the end of a patch path
+type patchOp struct {
+	kind   int
+	target node.Node   // for patchAddLink
+	value  interface{} // for patchSet
+	create bool
+}
+
+// patchNode returns a copy of nd with op applied at path. Every rewritten
+// node below nd is added to ds, nd's replacement is left to the caller.
+func patchNode(ctx context.Context, ds dag.DAGService, nd node.Node, segs []string, op *patchOp) (node.Node, error) {
+	switch nd := nd.(type) {
+	case *dag.ProtoNode:
+		return patchProtoNode(ctx, ds, nd, segs, op)
+	case *ipldcbor.Node:
+		return patchCborNode(ctx, ds, nd, segs, op)
+	default:
+		return nil, fmt.Errorf("cannot patch %s: only protobuf and dag-cbor nodes can be patched", nd.Cid())
+	}
+}
+
+func patchProtoNode(ctx context.Context, ds dag.DAGService, nd *dag.ProtoNode, segs []string, op *patchOp) (node.Node, error) {
+	dir, err := protoDirectory(ds, nd)
+	if err != nil {
+		return nil, err
+	}
+	name := segs[0]
+
+	if len(segs) == 1 {
+		switch op.kind {
+		case patchAddLink:
+			return setProtoLink(ctx, dir, nd, name, op.target)
+		case patchRmLink:
+			if dir != nil {
+				if err := dir.RemoveChild(ctx, name); err != nil {
+					if err == os.ErrNotExist || err == dag.ErrLinkNotFound {
+						return nil, fmt.Errorf("no link named %q", name)
+					}
+					return nil, err
+				}
+				return dir.GetNode()
+			}
+			nnd := nd.Copy().(*dag.ProtoNode)
+			if err := nnd.RemoveNodeLink(name); err != nil {
+				return nil, fmt.Errorf("no link named %q", name)
+			}
+			return nnd, nil
+		default:
+			return nil, fmt.Errorf("cannot set fields of protobuf nodes, use add-link")
+		}
+	}
+
+	var child node.Node
+	if dir != nil {
+		child, err = dir.Find(ctx, name)
+	} else {
+		child, err = nd.GetLinkedNode(ctx, ds, name)
+	}
+	switch {
+	case err == nil:
+	case (err == os.ErrNotExist || err == dag.ErrLinkNotFound) && op.create:
+		if dir != nil {
+			child = ft.EmptyDirNode()
+		} else {
+			child = new(dag.ProtoNode)
+		}
+	case err == os.ErrNotExist || err == dag.ErrLinkNotFound:
+		return nil, fmt.Errorf("no link named %q", name)
+	default:
+		return nil, err
+	}
+
+	nchild, err := patchNode(ctx, ds, child, segs[1:], op)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ds.Add(nchild); err != nil {
+		return nil, err
+	}
+	return setProtoLink(ctx, dir, nd, name, nchild)
+}
+
+// setProtoLink points the link name of nd, or of its directory view dir
+// if it has one, to child
+func setProtoLink(ctx context.Context, dir *uio.Directory, nd *dag.ProtoNode, name string, child node.Node) (node.Node, error) {
+	if dir != nil {
+		if err := dir.AddChild(ctx, name, child); err != nil {
+			return nil, err
+		}
+		return dir.GetNode()
+	}
+
+	nnd := nd.Copy().(*dag.ProtoNode)
+	_ = nnd.RemoveNodeLink(name)
+	if err := nnd.AddNodeLinkClean(name, child); err != nil {
+		return nil, err
+	}
+	return nnd, nil
+}
+
+// protoDirectory returns nd as a unixfs directory, or nil if it isn't one
+func protoDirectory(ds dag.DAGService, nd *dag.ProtoNode) (*uio.Directory, error) {
+	pbd, err := ft.FromBytes(nd.Data())
+	if err != nil {
+		// not unixfs
+		return nil, nil
+	}
+
+	switch pbd.GetType() {
+	case ft.TDirectory, ft.THAMTShard:
+		return uio.NewDirectoryFromNode(ds, nd)
+	default:
+		return nil, nil
+	}
+}
+
+// patchCborNode decodes nd, applies op to the decoded object in place and
+// encodes it again. Unlike a round trip through the JSON form of the node,
+// this leaves the values off the path as they are, e.g. byte strings or
+// integers too large for a float64.
+func patchCborNode(ctx context.Context, ds dag.DAGService, nd *ipldcbor.Node, segs []string, op *patchOp) (node.Node, error) {
+	var obj interface{}
+	if err := ipldcbor.DecodeInto(nd.RawData(), &obj); err != nil {
+		return nil, err
+	}
+
+	obj, err := patchValue(ctx, ds, obj, segs, op)
+	if err != nil {
+		return nil, err
+	}
+	return ipldcbor.WrapObject(obj)
+}
+
+// patchValue applies op at path within the decoded value v of a dag-cbor
+// node, following links out of the node when it meets one
+func patchValue(ctx context.Context, ds dag.DAGService, v interface{}, segs []string, op *patchOp) (interface{}, error) {
+	name := segs[0]
+
+	var child interface{}
+	var found bool
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		child, found = v[name]
+	case map[string]interface{}:
+		child, found = v[name]
+	case []interface{}:
+		i, err := strconv.Atoi(name)
+		if err != nil || i < 0 || i >= len(v) {
+			return nil, fmt.Errorf("no index %q in array of %d elements", name, len(v))
+		}
+		child, found = v[i], true
+	default:
+		return nil, fmt.Errorf("cannot patch %q: not a map or an array", name)
+	}
+
+	if len(segs) == 1 {
+		switch op.kind {
+		case patchAddLink:
+			return setValue(v, name, op.target.Cid()), nil
+		case patchSet:
+			return setValue(v, name, op.value), nil
+		default:
+			if !found {
+				return nil, fmt.Errorf("no field named %q", name)
+			}
+			return removeValue(v, name), nil
+		}
+	}
+
+	if !found {
+		if !op.create {
+			return nil, fmt.Errorf("no field named %q", name)
+		}
+		child = map[string]interface{}{}
+	}
+
+	if c, ok := child.(*cid.Cid); ok {
+		lnd, err := ds.Get(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		nlnd, err := patchNode(ctx, ds, lnd, segs[1:], op)
+		if err != nil {
+			return nil, err
+		}
+		nc, err := ds.Add(nlnd)
+		if err != nil {
+			return nil, err
+		}
+		return setValue(v, name, nc), nil
+	}
+
+	nchild, err := patchValue(ctx, ds, child, segs[1:], op)
+	if err != nil {
+		return nil, err
+	}
+	return setValue(v, name, nchild), nil
+}
+
+// setValue sets the entry name of the map or array v, which patchValue
+// already checked
+func setValue(v interface{}, name string, val interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		v[name] = val
+	case map[string]interface{}:
+		v[name] = val
+	case []interface{}:
+		i, _ := strconv.Atoi(name)
+		v[i] = val
+	}
+	return v
+}
+
+// removeValue removes the entry name of the map or array v, which
+// patchValue already checked
+func removeValue(v interface{}, name string) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		delete(v, name)
+		return v
+	case map[string]interface{}:
+		delete(v, name)
+		return v
+	case []interface{}:
+		i, _ := strconv.Atoi(name)
+		return append(v[:i], v[i+1:]...)
+	}
+	return v
+}
+
+// parseCborValue parses the JSON value s into the value to encode in a
+// dag-cbor node: links, written {"/": "<cid>"}, become cids, and integers
+// are kept exact rather than turned into float64s.
+func parseCborValue(s string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the value")
+	}
+	return cborValue(v)
+}
+
+func cborValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u, nil
+		}
+		return v.Float64()
+	case map[string]interface{}:
+		if s, ok := v["/"].(string); ok && len(v) == 1 {
+			return cid.Decode(s)
+		}
+		for k, e := range v {
+			ce, err := cborValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = ce
+		}
+		return v, nil
+	case []interface{}:
+		for i, e := range v {
+			ce, err := cborValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = ce
+		}
+		return v, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,101 @@
+package dagcmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	mdtest "github.com/ipfs/go-ipfs/merkledag/test"
+
+	ipldcbor "gx/ipfs/QmNrbCt8j9DT5W9Pmjy2SdudT9k8GpaDr4sRuFix3BXhgR/go-ipld-cbor"
+)
+
+func TestPatchCborKeepsValues(t *testing.T) {
+	ctx := context.Background()
+	ds := mdtest.Mock()
+
+	const big = uint64(1<<60 + 1)
+	raw := []byte{0, 1, 2, 0xff}
+	nd, err := ipldcbor.WrapObject(map[string]interface{}{
+		"big":   big,
+		"neg":   int64(-1<<62 - 3),
+		"raw":   raw,
+		"child": map[string]interface{}{"x": "a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := parseCborValue(`{"y": 9007199254740993}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pnd, err := patchNode(ctx, ds, nd, []string{"child", "x"}, &patchOp{kind: patchSet, value: set})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var obj map[string]interface{}
+	if err := ipldcbor.DecodeInto(pnd.RawData(), &obj); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := obj["big"].(uint64); !ok || v != big {
+		t.Fatalf("expected big to be %d, got %#v", big, obj["big"])
+	}
+	if v, ok := obj["neg"].(int64); !ok || v != -1<<62-3 {
+		t.Fatalf("expected neg to be %d, got %#v", int64(-1<<62-3), obj["neg"])
+	}
+	if v, ok := obj["raw"].([]byte); !ok || !bytes.Equal(v, raw) {
+		t.Fatalf("expected raw to be %v, got %#v", raw, obj["raw"])
+	}
+
+	// setting the field back gives the original node, byte for byte
+	back, err := patchNode(ctx, ds, pnd, []string{"child", "x"}, &patchOp{kind: patchSet, value: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(back.RawData(), nd.RawData()) {
+		t.Fatalf("expected %s after patching back, got %s", nd.Cid(), back.Cid())
+	}
+}
+
+func TestPatchCborLinks(t *testing.T) {
+	ctx := context.Background()
+	ds := mdtest.Mock()
+
+	leaf, err := ipldcbor.WrapObject(map[string]interface{}{"v": uint64(1<<63 + 5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.Add(leaf); err != nil {
+		t.Fatal(err)
+	}
+	root, err := ipldcbor.WrapObject(map[string]interface{}{"leaf": leaf.Cid()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proot, err := patchNode(ctx, ds, root, []string{"leaf", "w"}, &patchOp{kind: patchSet, value: "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lnk, _, err := proot.ResolveLink([]string{"leaf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pleaf, err := ds.Get(ctx, lnk.Cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var obj map[string]interface{}
+	if err := ipldcbor.DecodeInto(pleaf.RawData(), &obj); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := obj["v"].(uint64); !ok || v != 1<<63+5 {
+		t.Fatalf("expected v to be %d, got %#v", uint64(1<<63+5), obj["v"])
+	}
+	if obj["w"] != "new" {
+		t.Fatalf("expected w to be set, got %#v", obj["w"])
+	}
+}
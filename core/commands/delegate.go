@@ -0,0 +1,224 @@
+package commands
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	namesys "github.com/ipfs/go-ipfs/namesys"
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
+
+	crypto "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// DelegationOutput is the output of 'ipfs name delegate'
+type DelegationOutput struct {
+	// Name is the name the delegation is for
+	Name string
+	// Delegate is the PeerID of the key it delegates to
+	Delegate string
+	// Expires is the EOL of the delegation
+	Expires string
+	// Delegation is the encoded delegation, to pass to 'ipfs name publish'
+	Delegation string
+}
+
+// PubKeyOutput is the output of 'ipfs name delegate pubkey'
+type PubKeyOutput struct {
+	Id     string
+	PubKey string
+}
+
+var NameDelegateCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Let another key publish to an IPNS name.",
+		ShortDescription: `
+'ipfs name delegate' signs, with the key of a name, a delegation letting
+another key publish records for the name until it expires. Records
+published under a delegation carry it, and resolvers check it against the
+key of the name, so the machine publishing a name can be replaced without
+changing the name or copying its key around.
+`,
+		LongDescription: `
+'ipfs name delegate' signs, with the key of a name, a delegation letting
+another key publish records for the name until it expires. Records
+published under a delegation carry it, and resolvers check it against the
+key of the name, so the machine publishing a name can be replaced without
+changing the name or copying its key around.
+
+On the publishing machine, create a key and print its public key:
+
+  > ipfs key gen --type=ed25519 deploy
+  > ipfs name delegate pubkey --key=deploy
+  CAESIBL5...
+
+On the machine holding the key of the name, delegate to it:
+
+  > ipfs name delegate --key=mysite --lifetime=720h CAESIBL5...
+  CiQIARIg...
+
+Then publish from the publishing machine with the delegation:
+
+  > ipfs name publish --key=deploy --delegation=CiQIARIg... /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+  Published to QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n: /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+
+Records can't outlive the delegation they were published under: create a
+new delegation before the current one expires.
+
+Only nodes running a version of go-ipfs that knows delegations can resolve
+records published under one. Older resolvers ignore the delegation and check
+the signature of the record against the key of the name, which fails, so
+they can't resolve the name.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("pubkey", true, false, "Public key to delegate to, as printed by 'ipfs name delegate pubkey'."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("key", "k", "Name of the key of the IPNS name, or its PeerID. Default: <<default>>.").Default("self"),
+		cmds.StringOption("lifetime", "t", "Time duration that the delegation will be valid for. <<default>>").Default("720h"),
+	},
+	Subcommands: map[string]*cmds.Command{
+		"pubkey": nameDelegatePubKeyCmd,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		kname, _, _ := req.Option("key").String()
+		owner, err := keylookup(n, kname)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		lifetime, _, _ := req.Option("lifetime").String()
+		d, err := time.ParseDuration(lifetime)
+		if err != nil {
+			res.SetError(fmt.Errorf("error parsing lifetime option: %s", err), cmds.ErrNormal)
+			return
+		}
+
+		pkb, err := base64.StdEncoding.DecodeString(req.Arguments()[0])
+		if err != nil {
+			res.SetError(fmt.Errorf("invalid public key: %s", err), cmds.ErrClient)
+			return
+		}
+		delegate, err := crypto.UnmarshalPublicKey(pkb)
+		if err != nil {
+			res.SetError(fmt.Errorf("invalid public key: %s", err), cmds.ErrClient)
+			return
+		}
+
+		eol := time.Now().Add(d)
+		del, err := namesys.CreateDelegation(owner, delegate, eol)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		data, err := proto.Marshal(del)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		name, err := peer.IDFromPrivateKey(owner)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		did, err := peer.IDFromPublicKey(delegate)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(&DelegationOutput{
+			Name:       name.Pretty(),
+			Delegate:   did.Pretty(),
+			Expires:    u.FormatRFC3339(eol),
+			Delegation: base64.StdEncoding.EncodeToString(data),
+		})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			v, ok := res.Output().(*DelegationOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+			return strings.NewReader(v.Delegation + "\n"), nil
+		},
+	},
+	Type: DelegationOutput{},
+}
+
+var nameDelegatePubKeyCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Print the public key to delegate an IPNS name to.",
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("key", "k", "Name of the key, or its PeerID. Default: <<default>>.").Default("self"),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		kname, _, _ := req.Option("key").String()
+		k, err := keylookup(n, kname)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		pkb, err := crypto.MarshalPublicKey(k.GetPublic())
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		id, err := peer.IDFromPrivateKey(k)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(&PubKeyOutput{
+			Id:     id.Pretty(),
+			PubKey: base64.StdEncoding.EncodeToString(pkb),
+		})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			v, ok := res.Output().(*PubKeyOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+			return strings.NewReader(v.PubKey + "\n"), nil
+		},
+	},
+	Type: PubKeyOutput{},
+}
+
+// parseDelegation decodes a delegation printed by 'ipfs name delegate'
+func parseDelegation(s string) (*pb.Delegation, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delegation: %s", err)
+	}
+	d := new(pb.Delegation)
+	if err := proto.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("invalid delegation: %s", err)
+	}
+	return d, nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
@@ -14,7 +15,6 @@ import (
 
 	routing "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing"
 	notif "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing/notifications"
-	ipdht "gx/ipfs/QmRmroYSdievxnjiuy99C8BzShNstdEWcEF3LQHF7fUbez/go-libp2p-kad-dht"
 	b58 "gx/ipfs/QmT8rehPR3F6bmwL6zjUN8XpiDBFFpMP2myPdC6ApsWfJf/go-base58"
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
@@ -37,13 +37,39 @@ var DhtCmd = &cmds.Command{
 		"get":       getValueDhtCmd,
 		"put":       putValueDhtCmd,
 		"provide":   provideRefDhtCmd,
+		"table":     tableDhtCmd,
 	},
 }
 
+// DhtQueryStats summarizes a DHT query
+type DhtQueryStats struct {
+	// Peers is the number of closest peers found
+	Peers int
+
+	// Queried is the number of peers queried, of which Responses answered
+	// and Errors failed
+	Queried   int
+	Responses int
+	Errors    int
+
+	Duration time.Duration
+}
+
+// closestPeersFinder is implemented by the DHT, and by the routing system
+// of the node wrapping it
+type closestPeersFinder interface {
+	GetClosestPeers(ctx context.Context, key string) (<-chan peer.ID, error)
+}
+
 var queryDhtCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
-		Tagline:          "Find the closest Peer IDs to a given Peer ID by querying the DHT.",
-		ShortDescription: "Outputs a list of newline-delimited Peer IDs.",
+		Tagline: "Find the closest Peer IDs to a given Peer ID by querying the DHT.",
+		ShortDescription: `
+Outputs a list of newline-delimited Peer IDs. With --stats, the statistics of
+the query follow them. With --enc=json, the query events and the statistics
+are printed as JSON objects as they come. The command exits with an error
+when no peer is found.
+`,
 	},
 
 	Arguments: []cmds.Argument{
@@ -51,6 +77,7 @@ var queryDhtCmd = &cmds.Command{
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("verbose", "v", "Print extra information.").Default(false),
+		cmds.BoolOption("stats", "s", "Output the statistics of the query.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
@@ -59,7 +86,13 @@ var queryDhtCmd = &cmds.Command{
 			return
 		}
 
-		dht, ok := n.Routing.(*ipdht.IpfsDHT)
+		// query through the routing system, so the query counts in
+		// 'ipfs stats dht'
+		if _, ok := n.DHT(); !ok {
+			res.SetError(ErrNotDHT, cmds.ErrNormal)
+			return
+		}
+		cpf, ok := n.Routing.(closestPeersFinder)
 		if !ok {
 			res.SetError(ErrNotDHT, cmds.ErrNormal)
 			return
@@ -69,8 +102,10 @@ var queryDhtCmd = &cmds.Command{
 		ctx := notif.RegisterForQueryEvents(req.Context(), events)
 
 		k := string(b58.Decode(req.Arguments()[0]))
+		withStats, _, _ := req.Option("stats").Bool()
 
-		closestPeers, err := dht.GetClosestPeers(ctx, k)
+		start := time.Now()
+		closestPeers, err := cpf.GetClosestPeers(ctx, k)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -91,9 +126,29 @@ var queryDhtCmd = &cmds.Command{
 
 		go func() {
 			defer close(outChan)
+
+			var stats DhtQueryStats
 			for e := range events {
+				switch e.Type {
+				case notif.FinalPeer:
+					stats.Peers++
+				case notif.SendingQuery:
+					stats.Queried++
+				case notif.PeerResponse:
+					stats.Responses++
+				case notif.QueryError:
+					stats.Errors++
+				}
 				outChan <- e
 			}
+
+			if withStats {
+				stats.Duration = time.Since(start)
+				outChan <- &stats
+			}
+			if stats.Peers == 0 {
+				res.SetError(errors.New("no peers found"), cmds.ErrNormal)
+			}
 		}()
 	},
 	Marshalers: cmds.MarshalerMap{
@@ -112,6 +167,11 @@ var queryDhtCmd = &cmds.Command{
 			}
 
 			marshal := func(v interface{}) (io.Reader, error) {
+				if st, ok := v.(*DhtQueryStats); ok {
+					return strings.NewReader(fmt.Sprintf("Found %d peers in %s, queried %d peers: %d responses, %d errors\n",
+						st.Peers, st.Duration, st.Queried, st.Responses, st.Errors)), nil
+				}
+
 				obj, ok := v.(*notif.QueryEvent)
 				if !ok {
 					return nil, u.ErrCast()
@@ -153,8 +213,7 @@ var findProvidersDhtCmd = &cmds.Command{
 			return
 		}
 
-		dht, ok := n.Routing.(*ipdht.IpfsDHT)
-		if !ok {
+		if _, ok := n.DHT(); !ok {
 			res.SetError(ErrNotDHT, cmds.ErrNormal)
 			return
 		}
@@ -173,7 +232,7 @@ var findProvidersDhtCmd = &cmds.Command{
 			return
 		}
 
-		pchan := dht.FindProvidersAsync(ctx, c, numProviders)
+		pchan := n.Routing.FindProvidersAsync(ctx, c, numProviders)
 		go func() {
 			defer close(outChan)
 			for e := range events {
@@ -410,8 +469,7 @@ var findPeerDhtCmd = &cmds.Command{
 			return
 		}
 
-		dht, ok := n.Routing.(*ipdht.IpfsDHT)
-		if !ok {
+		if _, ok := n.DHT(); !ok {
 			res.SetError(ErrNotDHT, cmds.ErrNormal)
 			return
 		}
@@ -437,7 +495,7 @@ var findPeerDhtCmd = &cmds.Command{
 
 		go func() {
 			defer close(events)
-			pi, err := dht.FindPeer(ctx, pid)
+			pi, err := n.Routing.FindPeer(ctx, pid)
 			if err != nil {
 				notif.PublishQueryEvent(ctx, &notif.QueryEvent{
 					Type:  notif.QueryError,
@@ -517,8 +575,7 @@ Different key types can specify other 'best' rules.
 			return
 		}
 
-		dht, ok := n.Routing.(*ipdht.IpfsDHT)
-		if !ok {
+		if _, ok := n.DHT(); !ok {
 			res.SetError(ErrNotDHT, cmds.ErrNormal)
 			return
 		}
@@ -544,7 +601,7 @@ Different key types can specify other 'best' rules.
 
 		go func() {
 			defer close(events)
-			val, err := dht.GetValue(ctx, dhtkey)
+			val, err := n.Routing.GetValue(ctx, dhtkey)
 			if err != nil {
 				notif.PublishQueryEvent(ctx, &notif.QueryEvent{
 					Type:  notif.QueryError,
@@ -635,8 +692,7 @@ NOTE: A value may not exceed 2048 bytes.
 			return
 		}
 
-		dht, ok := n.Routing.(*ipdht.IpfsDHT)
-		if !ok {
+		if _, ok := n.DHT(); !ok {
 			res.SetError(ErrNotDHT, cmds.ErrNormal)
 			return
 		}
@@ -664,7 +720,7 @@ NOTE: A value may not exceed 2048 bytes.
 
 		go func() {
 			defer close(events)
-			err := dht.PutValue(ctx, key, []byte(data))
+			err := n.Routing.PutValue(ctx, key, []byte(data))
 			if err != nil {
 				notif.PublishQueryEvent(ctx, &notif.QueryEvent{
 					Type:  notif.QueryError,
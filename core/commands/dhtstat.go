@@ -0,0 +1,268 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	routingstats "github.com/ipfs/go-ipfs/core/routingstats"
+
+	ipdht "gx/ipfs/QmRmroYSdievxnjiuy99C8BzShNstdEWcEF3LQHF7fUbez/go-libp2p-kad-dht"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	kb "gx/ipfs/QmaQG6fJdzn2532WHoPdVwKqftXr6iCSr5NtWyGi1BHytT/go-libp2p-kbucket"
+)
+
+// DHTTablePeer is a peer of the DHT routing table
+type DHTTablePeer struct {
+	ID string
+	// Bucket is the number of leading bits the peer's DHT key shares with
+	// ours
+	Bucket    int
+	Age       time.Duration // since the oldest open connection to the peer
+	Latency   time.Duration
+	Direction string `json:",omitempty"`
+}
+
+// DHTTable is the output of 'ipfs dht table'
+type DHTTable struct {
+	Peers []DHTTablePeer
+}
+
+// DHTBucket is the number of peers of a bucket of the routing table
+type DHTBucket struct {
+	Bucket int
+	Peers  int
+}
+
+// DHTQueryStats are the statistics of one type of DHT query
+type DHTQueryStats struct {
+	Type        string
+	Queries     uint64
+	Succeeded   uint64
+	Failed      uint64
+	SuccessRate float64
+	AvgHops     float64
+	AvgLatency  time.Duration
+	MaxLatency  time.Duration
+}
+
+// DHTStats is the output of 'ipfs stats dht'
+type DHTStats struct {
+	Buckets []DHTBucket
+	Queries []DHTQueryStats
+}
+
+var statDhtCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Print DHT routing table and query statistics.",
+		ShortDescription: `
+'ipfs stats dht' prints the number of peers of each bucket of the DHT
+routing table, and statistics on the queries made since the daemon started,
+per type of query: how many succeeded, their average and maximum latency,
+and the average number of peers that answered them (hops).
+
+The latency of FindProviders queries is the time to the first provider.
+Use '--enc=json' to feed the statistics to a monitoring system, durations
+are then given in nanoseconds. 'ipfs dht table' lists the peers of the
+routing table.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if !n.OnlineMode() {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+		dht, ok := n.DHT()
+		if !ok {
+			res.SetError(ErrNotDHT, cmds.ErrNormal)
+			return
+		}
+
+		out := new(DHTStats)
+		counts := make(map[int]int)
+		for _, p := range dhtTablePeers(n, dht) {
+			counts[p.Bucket]++
+		}
+		for b, c := range counts {
+			out.Buckets = append(out.Buckets, DHTBucket{Bucket: b, Peers: c})
+		}
+		sort.Sort(byBucket(out.Buckets))
+
+		if rs, ok := n.Routing.(*routingstats.Router); ok {
+			for _, s := range rs.Stats() {
+				out.Queries = append(out.Queries, DHTQueryStats{
+					Type:        s.Type,
+					Queries:     s.Queries,
+					Succeeded:   s.Succeeded,
+					Failed:      s.Failed,
+					SuccessRate: s.SuccessRate(),
+					AvgHops:     s.AvgHops(),
+					AvgLatency:  s.AvgLatency,
+					MaxLatency:  s.MaxLatency,
+				})
+			}
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*DHTStats)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
+			fmt.Fprintln(w, "Routing table")
+			fmt.Fprintln(w, "  bucket\tpeers\t")
+			total := 0
+			for _, b := range out.Buckets {
+				fmt.Fprintf(w, "  %d\t%d\t\n", b.Bucket, b.Peers)
+				total += b.Peers
+			}
+			fmt.Fprintf(w, "  total\t%d\t\n", total)
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "Queries")
+			fmt.Fprintln(w, "  type\tqueries\tsuccess\tavg hops\tavg latency\tmax latency\t")
+			for _, q := range out.Queries {
+				fmt.Fprintf(w, "  %s\t%d\t%.1f%%\t%.1f\t%s\t%s\t\n", q.Type, q.Queries,
+					100*q.SuccessRate, q.AvgHops, roundDuration(q.AvgLatency), roundDuration(q.MaxLatency))
+			}
+			w.Flush()
+			return buf, nil
+		},
+	},
+	Type: DHTStats{},
+}
+
+var tableDhtCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the peers of the DHT routing table.",
+		ShortDescription: `
+Lists the peers of the DHT routing table with their bucket, how long they
+have been connected, their latency and the direction of their oldest
+connection. The bucket of a peer is the number of leading bits its DHT
+key shares with the key of this node.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.IntOption("bucket", "b", "Only list the peers of this bucket.").Default(-1),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if !n.OnlineMode() {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+		dht, ok := n.DHT()
+		if !ok {
+			res.SetError(ErrNotDHT, cmds.ErrNormal)
+			return
+		}
+
+		bucket, _, _ := req.Option("bucket").Int()
+		out := new(DHTTable)
+		for _, p := range dhtTablePeers(n, dht) {
+			if bucket >= 0 && p.Bucket != bucket {
+				continue
+			}
+			out.Peers = append(out.Peers, p)
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*DHTTable)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
+			for _, p := range out.Peers {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t\n", p.Bucket, p.ID,
+					roundDuration(p.Age), roundDuration(p.Latency), p.Direction)
+			}
+			w.Flush()
+			return buf, nil
+		},
+	},
+	Type: DHTTable{},
+}
+
+// dhtTablePeers lists the peers of the routing table of dht, by bucket
+func dhtTablePeers(n *core.IpfsNode, dht *ipdht.IpfsDHT) []DHTTablePeer {
+	self := kb.ConvertPeerID(n.Identity)
+	now := time.Now()
+
+	var out []DHTTablePeer
+	for _, p := range n.Peerstore.Peers() {
+		if p == n.Identity || dht.FindLocal(p).ID == "" {
+			continue
+		}
+
+		tp := DHTTablePeer{
+			ID:      p.Pretty(),
+			Bucket:  kb.CommonPrefixLen(self, kb.ConvertPeerID(p)),
+			Latency: n.Peerstore.LatencyEWMA(p),
+		}
+		if n.ConnTracker != nil {
+			for _, c := range n.PeerHost.Network().ConnsToPeer(p) {
+				info := n.ConnTracker.Info(c)
+				if info.Opened.IsZero() {
+					continue
+				}
+				if age := now.Sub(info.Opened); age > tp.Age {
+					tp.Age = age
+					tp.Direction = info.Direction.String()
+				}
+			}
+		}
+		out = append(out, tp)
+	}
+	sort.Sort(byBucketPeer(out))
+	return out
+}
+
+// roundDuration drops the digits of d that don't matter to a reader
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d > time.Minute:
+		return d - d%time.Second
+	case d > time.Second:
+		return d - d%time.Millisecond
+	default:
+		return d - d%time.Microsecond
+	}
+}
+
+type byBucket []DHTBucket
+
+func (s byBucket) Len() int           { return len(s) }
+func (s byBucket) Less(i, j int) bool { return s[i].Bucket < s[j].Bucket }
+func (s byBucket) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type byBucketPeer []DHTTablePeer
+
+func (s byBucketPeer) Len() int { return len(s) }
+func (s byBucketPeer) Less(i, j int) bool {
+	if s[i].Bucket != s[j].Bucket {
+		return s[i].Bucket < s[j].Bucket
+	}
+	return s[i].ID < s[j].ID
+}
+func (s byBucketPeer) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	features "github.com/ipfs/go-ipfs/features"
+
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+)
+
+type FeatureOutput struct {
+	Name        string
+	Description string
+	Stability   string
+	Enabled     bool
+	Source      string // where the state comes from: default, config, env or flag
+	Env         string `json:",omitempty"`
+}
+
+type FeatureList struct {
+	Features []FeatureOutput
+}
+
+var FeaturesCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Inspect optional features.",
+		ShortDescription: `
+'ipfs features' lists the optional, mostly experimental, features of ipfs and
+whether they are enabled.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"ls": featuresLsCmd,
+	},
+}
+
+var featuresLsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List optional features and whether they are enabled.",
+		ShortDescription: `
+'ipfs features ls' lists the optional features of ipfs, whether they are
+enabled, how stable they are, and where their state comes from.
+`,
+		LongDescription: `
+'ipfs features ls' lists the optional features of ipfs, whether they are
+enabled, how stable they are, and where their state comes from.
+
+Features are enabled or disabled in the Experimental.Features section of the
+config, e.g.:
+
+  ipfs config --json Experimental.Features.pubsub true
+
+Some features can also be enabled with an environment variable, or for a
+single daemon run with a flag such as 'ipfs daemon --enable-pubsub-experiment'.
+Flags take precedence over environment variables, which take precedence over
+the config.
+
+When the daemon is running, the features of the daemon are listed.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		var out FeatureList
+		for _, f := range features.All() {
+			out.Features = append(out.Features, FeatureOutput{
+				Name:        f.Name,
+				Description: f.Description,
+				Stability:   string(f.Stability),
+				Enabled:     n.Features.Enabled(f.Name),
+				Source:      n.Features.Source(f.Name),
+				Env:         f.Env,
+			})
+		}
+		res.SetOutput(&out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			list, ok := res.Output().(*FeatureList)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			w := tabwriter.NewWriter(buf, 1, 2, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tENABLED\tSTABILITY\tSOURCE\tDESCRIPTION")
+			for _, f := range list.Features {
+				desc := f.Description
+				if f.Env != "" {
+					desc += fmt.Sprintf(" (env: %s)", f.Env)
+				}
+				fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\n", f.Name, f.Enabled, f.Stability, f.Source, desc)
+			}
+			w.Flush()
+			return buf, nil
+		},
+	},
+	Type: FeatureList{},
+}
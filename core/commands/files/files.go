@@ -8,17 +8,26 @@ import (
 	"io"
 	"os"
 	gopath "path"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	bservice "github.com/ipfs/go-ipfs/blockservice"
 	cmds "github.com/ipfs/go-ipfs/commands"
 	core "github.com/ipfs/go-ipfs/core"
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	offline "github.com/ipfs/go-ipfs/exchange/offline"
 	dag "github.com/ipfs/go-ipfs/merkledag"
 	mfs "github.com/ipfs/go-ipfs/mfs"
 	path "github.com/ipfs/go-ipfs/path"
 	ft "github.com/ipfs/go-ipfs/unixfs"
 	uio "github.com/ipfs/go-ipfs/unixfs/io"
 
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
@@ -39,10 +48,14 @@ of consistency guarantees. If the daemon is unexpectedly killed before running
 'ipfs files flush' on the files in question, then data may be lost. This also
 applies to running 'ipfs repo gc' concurrently with '--flush=false'
 operations.
+
+All subcommands also accept '--offline', in which case /ipfs/ paths are only
+resolved from blocks in the local repo, even when the daemon is online.
 `,
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("f", "flush", "Flush target and ancestors after write.").Default(true),
+		cmdenv.OfflineOption,
 	},
 	Subcommands: map[string]*cmds.Command{
 		"read":  FilesReadCmd,
@@ -54,6 +67,8 @@ operations.
 		"stat":  FilesStatCmd,
 		"rm":    FilesRmCmd,
 		"flush": FilesFlushCmd,
+		"chmod": FilesChmodCmd,
+		"touch": FilesTouchCmd,
 	},
 }
 
@@ -62,6 +77,21 @@ var formatError = errors.New("Format was set by multiple options. Only one forma
 var FilesStatCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Display file status.",
+		ShortDescription: `
+Display the status of a file or directory in the mutable namespace, or of any
+object given as an /ipfs/ path.
+
+With --with-local, the dag under the path is walked using only the blocks
+available locally, and the amount of data (and number of blocks) present
+locally is reported next to the total size. Nothing is fetched from the
+network, and the walk can be interrupted at any time. The amount found so far
+is reported while the dag is walked, and the status once it is done. Blocks
+appearing several times in the dag are counted every time, like in the
+cumulative size.
+
+If the permission bits or the modification time of a file or directory
+were recorded when it was added, they are shown as well.
+`,
 	},
 
 	Arguments: []cmds.Argument{
@@ -69,7 +99,7 @@ var FilesStatCmd = &cmds.Command{
 	},
 	Options: []cmds.Option{
 		cmds.StringOption("format", "Print statistics in given format. Allowed tokens: "+
-			"<hash> <size> <cumulsize> <type> <childs>. Conflicts with other format options.").Default(
+			"<hash> <size> <cumulsize> <type> <childs> <mode> <mtime>. Conflicts with other format options.").Default(
 			`<hash>
 Size: <size>
 CumulativeSize: <cumulsize>
@@ -77,15 +107,17 @@ ChildBlocks: <childs>
 Type: <type>`),
 		cmds.BoolOption("hash", "Print only hash. Implies '--format=<hash>'. Conflicts with other format options.").Default(false),
 		cmds.BoolOption("size", "Print only size. Implies '--format=<cumulsize>'. Conflicts with other format options.").Default(false),
+		cmds.BoolOption("with-local", "Compute the amount of the dag that is local, and if possible the total size.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 
 		_, err := statGetFormatOptions(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrClient)
+			return
 		}
 
-		node, err := req.InvocContext().GetNode()
+		node, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -97,24 +129,111 @@ Type: <type>`),
 			return
 		}
 
-		fsn, err := mfs.Lookup(node.FilesRoot, path)
+		withLocal, _, _ := req.Option("with-local").Bool()
+
+		var dagserv dag.DAGService
+		if withLocal {
+			// an offline DAGService will not fetch from the network
+			dagserv = dag.NewDAGService(bservice.New(
+				node.Blockstore,
+				offline.Exchange(node.Blockstore),
+			))
+		} else {
+			dagserv = node.DAG
+		}
+
+		nd, err := getNodeFromPath(req.Context(), node, dagserv, path)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		o, err := statNode(node.DAG, fsn)
+		o, err := statNode(nd)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		res.SetOutput(o)
+		if !withLocal {
+			res.SetOutput(o)
+			return
+		}
+
+		ctx := req.Context()
+		w := newLocalWalker(dagserv)
+		done := make(chan error, 1)
+		var st localStat
+		go func() {
+			var err error
+			st, err = w.walk(ctx, nd)
+			done <- err
+		}()
+
+		out := make(chan interface{})
+		res.SetOutput((<-chan interface{})(out))
+		go func() {
+			ticker := time.NewTicker(500 * time.Millisecond)
+			defer ticker.Stop()
+			defer close(out)
+			for {
+				select {
+				case err := <-done:
+					if err != nil {
+						res.SetError(err, cmds.ErrNormal)
+						return
+					}
+					o.WithLocality = true
+					o.Local = st.local
+					o.SizeLocal = st.size
+					o.BlocksLocal = st.blocks
+					out <- o
+					return
+				case <-ticker.C:
+					size, blocks := w.progress()
+					out <- &Object{
+						Hash:         o.Hash,
+						WithLocality: true,
+						InProgress:   true,
+						SizeLocal:    size,
+						BlocksLocal:  blocks,
+					}
+				}
+			}
+		}()
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-
-			out := res.Output().(*Object)
+			var out *Object
+			switch v := res.Output().(type) {
+			case *Object:
+				out = v
+			case <-chan interface{}:
+				progressLine := false
+				for o0 := range v {
+					o := o0.(*Object)
+					if !o.InProgress {
+						out = o
+						continue
+					}
+					if progressLine {
+						fmt.Fprintf(res.Stderr(), "\r")
+					}
+					fmt.Fprintf(res.Stderr(), "Local so far: %s in %d blocks",
+						humanize.Bytes(o.SizeLocal), o.BlocksLocal)
+					progressLine = true
+				}
+				if progressLine {
+					fmt.Fprintf(res.Stderr(), "\n")
+				}
+				if res.Error() != nil {
+					return nil, res.Error()
+				}
+				if out == nil {
+					return nil, errors.New("no status was returned")
+				}
+			default:
+				return nil, u.ErrCast()
+			}
 			buf := new(bytes.Buffer)
 
 			s, _ := statGetFormatOptions(res.Request())
@@ -123,18 +242,58 @@ Type: <type>`),
 			s = strings.Replace(s, "<cumulsize>", fmt.Sprintf("%d", out.CumulativeSize), -1)
 			s = strings.Replace(s, "<childs>", fmt.Sprintf("%d", out.Blocks), -1)
 			s = strings.Replace(s, "<type>", out.Type, -1)
+			s = strings.Replace(s, "<mode>", out.Mode, -1)
+			s = strings.Replace(s, "<mtime>", out.Mtime, -1)
 
 			fmt.Fprintln(buf, s)
+
+			if isDefaultStatFormat(res.Request()) {
+				if out.Mode != "" {
+					fmt.Fprintf(buf, "Mode: %s\n", out.Mode)
+				}
+				if out.Mtime != "" {
+					fmt.Fprintf(buf, "Mtime: %s\n", out.Mtime)
+				}
+			}
+
+			if out.WithLocality {
+				fmt.Fprintf(buf, "Local: %s of %s (%.2f%%)\n",
+					humanize.Bytes(out.SizeLocal),
+					humanize.Bytes(out.CumulativeSize),
+					localPercent(out),
+				)
+				fmt.Fprintf(buf, "LocalBlocks: %d\n", out.BlocksLocal)
+			}
+
 			return buf, nil
 		},
 	},
 	Type: Object{},
 }
 
+// localPercent returns the percentage of the dag of out that is local
+func localPercent(out *Object) float64 {
+	if out.CumulativeSize == 0 {
+		if out.Local {
+			return 100
+		}
+		return 0
+	}
+	return 100.0 * float64(out.SizeLocal) / float64(out.CumulativeSize)
+}
+
 func moreThanOne(a, b, c bool) bool {
 	return a && b || b && c || a && c
 }
 
+// isDefaultStatFormat reports whether no format option was given to stat.
+func isDefaultStatFormat(req cmds.Request) bool {
+	hash, _, _ := req.Option("hash").Bool()
+	size, _, _ := req.Option("size").Bool()
+	_, found, _ := req.Option("format").String()
+	return !hash && !size && !found
+}
+
 func statGetFormatOptions(req cmds.Request) (string, error) {
 
 	hash, _, _ := req.Option("hash").Bool()
@@ -154,12 +313,7 @@ func statGetFormatOptions(req cmds.Request) (string, error) {
 	}
 }
 
-func statNode(ds dag.DAGService, fsn mfs.FSNode) (*Object, error) {
-	nd, err := fsn.GetNode()
-	if err != nil {
-		return nil, err
-	}
-
+func statNode(nd node.Node) (*Object, error) {
 	c := nd.Cid()
 
 	pbnd, ok := nd.(*dag.ProtoNode)
@@ -178,22 +332,104 @@ func statNode(ds dag.DAGService, fsn mfs.FSNode) (*Object, error) {
 	}
 
 	var ndtype string
-	switch fsn.Type() {
-	case mfs.TDir:
+	switch d.GetType() {
+	case ft.TDirectory, ft.THAMTShard:
 		ndtype = "directory"
-	case mfs.TFile:
+	case ft.TFile, ft.TMetadata, ft.TRaw:
 		ndtype = "file"
 	default:
-		return nil, fmt.Errorf("Unrecognized node type: %s", fsn.Type())
+		return nil, fmt.Errorf("Unrecognized node type: %s", d.GetType())
 	}
 
-	return &Object{
+	o := &Object{
 		Hash:           c.String(),
 		Blocks:         len(nd.Links()),
 		Size:           d.GetFilesize(),
 		CumulativeSize: cumulsize,
 		Type:           ndtype,
-	}, nil
+	}
+	if d.Mode != nil {
+		o.Mode = fmt.Sprintf("%04o", d.GetMode())
+	}
+	if mtime := ft.ModTime(d); !mtime.IsZero() {
+		o.Mtime = mtime.UTC().Format(time.RFC3339Nano)
+	}
+	return o, nil
+}
+
+// localStat is what a localWalker found under a node: whether the whole
+// dag is local, and the size and number of the local blocks
+type localStat struct {
+	local  bool
+	size   uint64
+	blocks int
+}
+
+// localWalker walks dags using only the locally available blocks. Blocks
+// appearing several times are counted every time, like in the cumulative
+// size of a node, but each subdag is only walked once.
+type localWalker struct {
+	// the amount of local data found so far, for progress reports. First in
+	// the struct, to be aligned for atomic operations.
+	size   uint64
+	blocks int64
+
+	dagserv dag.DAGService
+	walked  map[string]localStat
+}
+
+func newLocalWalker(dagserv dag.DAGService) *localWalker {
+	return &localWalker{dagserv: dagserv, walked: make(map[string]localStat)}
+}
+
+// progress returns the size and number of the local blocks found so far
+func (w *localWalker) progress() (uint64, int) {
+	return atomic.LoadUint64(&w.size), int(atomic.LoadInt64(&w.blocks))
+}
+
+func (w *localWalker) found(st localStat) {
+	atomic.AddUint64(&w.size, st.size)
+	atomic.AddInt64(&w.blocks, int64(st.blocks))
+}
+
+// walk walks the dag under nd
+func (w *localWalker) walk(ctx context.Context, nd node.Node) (localStat, error) {
+	// Start with the block data size
+	st := localStat{local: true, size: uint64(len(nd.RawData())), blocks: 1}
+	w.found(localStat{size: st.size, blocks: 1})
+
+	for _, link := range nd.Links() {
+		if err := ctx.Err(); err != nil {
+			return st, err
+		}
+
+		childSt, ok := w.walked[link.Cid.KeyString()]
+		if ok {
+			w.found(childSt)
+		} else {
+			child, err := w.dagserv.Get(ctx, link.Cid)
+			if err == dag.ErrNotFound {
+				st.local = false
+				continue
+			}
+			if err != nil {
+				return st, err
+			}
+
+			childSt, err = w.walk(ctx, child)
+			if err != nil {
+				return st, err
+			}
+			w.walked[link.Cid.KeyString()] = childSt
+		}
+
+		// Recursively add the child size
+		st.local = st.local && childSt.local
+		st.size += childSt.size
+		st.blocks += childSt.blocks
+	}
+
+	return st, nil
 }
 
 var FilesCpCmd = &cmds.Command{
@@ -205,7 +441,7 @@ var FilesCpCmd = &cmds.Command{
 		cmds.StringArg("dest", true, false, "Destination to copy object to."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		node, err := req.InvocContext().GetNode()
+		node, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -230,7 +466,7 @@ var FilesCpCmd = &cmds.Command{
 			dst += gopath.Base(src)
 		}
 
-		nd, err := getNodeFromPath(req.Context(), node, src)
+		nd, err := getNodeFromPath(req.Context(), node, node.DAG, src)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -252,7 +488,7 @@ var FilesCpCmd = &cmds.Command{
 	},
 }
 
-func getNodeFromPath(ctx context.Context, node *core.IpfsNode, p string) (node.Node, error) {
+func getNodeFromPath(ctx context.Context, node *core.IpfsNode, dagservice dag.DAGService, p string) (node.Node, error) {
 	switch {
 	case strings.HasPrefix(p, "/ipfs/"):
 		np, err := path.ParsePath(p)
@@ -261,7 +497,7 @@ func getNodeFromPath(ctx context.Context, node *core.IpfsNode, p string) (node.N
 		}
 
 		resolver := &path.Resolver{
-			DAG:         node.DAG,
+			DAG:         dagservice,
 			ResolveOnce: uio.ResolveUnixfsOnce,
 		}
 
@@ -282,6 +518,13 @@ type Object struct {
 	CumulativeSize uint64
 	Blocks         int
 	Type           string
+	Mode           string `json:",omitempty"`
+	Mtime          string `json:",omitempty"`
+	WithLocality   bool   `json:",omitempty"`
+	InProgress     bool   `json:",omitempty"` // only the local amounts found so far are set
+	Local          bool   `json:",omitempty"`
+	SizeLocal      uint64 `json:",omitempty"`
+	BlocksLocal    int    `json:",omitempty"`
 }
 
 type FilesLsOutput struct {
@@ -330,7 +573,7 @@ Examples:
 			return
 		}
 
-		nd, err := req.InvocContext().GetNode()
+		nd, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -419,7 +662,7 @@ Examples:
 		cmds.IntOption("count", "n", "Maximum number of bytes to read."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		n, err := req.InvocContext().GetNode()
+		n, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -527,7 +770,7 @@ Example:
 		cmds.StringArg("dest", true, false, "Destination path for file to be moved to."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		n, err := req.InvocContext().GetNode()
+		n, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -560,7 +803,9 @@ Write data to a file in a given filesystem. This command allows you to specify
 a beginning offset to write to. The entire length of the input will be written.
 
 If the '--create' option is specified, the file will be created if it does not
-exist. Nonexistant intermediate directories will not be created.
+exist. Nonexistant intermediate directories will not be created. New files are
+hashed with the function given by '--hash', or the Unixfs.HashFunction config;
+existing files keep the hash function they were created with.
 
 If the '--flush' option is set to false, changes will not be propogated to the
 merkledag root. This can make operations much faster when doing a large number
@@ -587,6 +832,8 @@ stat' on the file or any of its ancestors.
 		cmds.BoolOption("create", "e", "Create the file if it does not exist."),
 		cmds.BoolOption("truncate", "t", "Truncate the file to size zero before writing."),
 		cmds.IntOption("count", "n", "Maximum number of bytes to read."),
+		cidVersionOption,
+		hashOption,
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		path, err := checkPath(req.Arguments()[0])
@@ -599,7 +846,7 @@ stat' on the file or any of its ancestors.
 		trunc, _, _ := req.Option("truncate").Bool()
 		flush, _, _ := req.Option("flush").Bool()
 
-		nd, err := req.InvocContext().GetNode()
+		nd, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -615,7 +862,13 @@ stat' on the file or any of its ancestors.
 			return
 		}
 
-		fi, err := getFileHandle(nd.FilesRoot, path, create)
+		prefix, err := getPrefix(req, nd)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		fi, err := getFileHandle(nd.FilesRoot, path, create, prefix)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -699,9 +952,11 @@ Examples:
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("parents", "p", "No error if existing, make parent directories as needed."),
+		cidVersionOption,
+		hashOption,
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		n, err := req.InvocContext().GetNode()
+		n, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -716,7 +971,17 @@ Examples:
 
 		flush, _, _ := req.Option("flush").Bool()
 
-		err = mfs.Mkdir(n.FilesRoot, dirtomake, dashp, flush)
+		prefix, err := getPrefix(req, n)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		err = mfs.Mkdir(n.FilesRoot, dirtomake, mfs.MkdirOpts{
+			Mkparents: dashp,
+			Flush:     flush,
+			Prefix:    prefix,
+		})
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -737,7 +1002,7 @@ are run with the '--flush=false'.
 		cmds.StringArg("path", false, false, "Path to flush. Default: '/'."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		nd, err := req.InvocContext().GetNode()
+		nd, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -756,6 +1021,123 @@ are run with the '--flush=false'.
 	},
 }
 
+var FilesChmodCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Change the permission bits of a file or directory.",
+		ShortDescription: `
+Records the permission bits of a file or directory, given in octal, keeping
+its modification time. This changes its hash and the hashes of its parents.
+A mode of 0 removes the permission bits.
+
+    $ ipfs files chmod 0644 /test/file
+    $ ipfs files stat --format="<mode>" /test/file
+    0644
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("mode", true, false, "Permission bits in octal, e.g. 0755."),
+		cmds.StringArg("path", true, false, "Path to the file or directory."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		nd, err := cmdenv.GetNode(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		mode, err := parseMode(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+		path, err := checkPath(req.Arguments()[1])
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		flush, _, _ := req.Option("flush").Bool()
+		if err := mfs.Chmod(nd.FilesRoot, path, mode, flush); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+	},
+}
+
+var FilesTouchCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Change the modification time of a file or directory.",
+		ShortDescription: `
+Records the modification time of a file or directory, keeping its permission
+bits. The time defaults to now, '--mtime' sets it in seconds since the unix
+epoch. This changes its hash and the hashes of its parents. '--clear' removes
+the modification time.
+
+    $ ipfs files touch --mtime=1500000000 /test/file
+    $ ipfs files stat --format="<mtime>" /test/file
+    2017-07-14T02:40:00Z
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("path", true, false, "Path to the file or directory."),
+	},
+	Options: []cmds.Option{
+		cmds.IntOption("mtime", "m", "Modification time in seconds since the unix epoch. Default: now."),
+		cmds.IntOption("mtime-nsecs", "Nanoseconds of the modification time.").Default(0),
+		cmds.BoolOption("clear", "Remove the modification time.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		nd, err := cmdenv.GetNode(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		path, err := checkPath(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		secs, found, _ := req.Option("mtime").Int()
+		nsecs, _, _ := req.Option("mtime-nsecs").Int()
+		clear, _, _ := req.Option("clear").Bool()
+		if nsecs < 0 || nsecs >= int(time.Second) {
+			res.SetError(fmt.Errorf("--mtime-nsecs must be between 0 and 999999999"), cmds.ErrClient)
+			return
+		}
+		if clear && found {
+			res.SetError(fmt.Errorf("--clear and --mtime are mutually exclusive"), cmds.ErrClient)
+			return
+		}
+
+		var mtime time.Time
+		switch {
+		case clear:
+		case found:
+			mtime = time.Unix(int64(secs), int64(nsecs))
+		default:
+			mtime = time.Now()
+		}
+
+		flush, _, _ := req.Option("flush").Bool()
+		if err := mfs.Touch(nd.FilesRoot, path, mtime, flush); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+	},
+}
+
+// parseMode parses permission bits given in octal, including the setuid,
+// setgid and sticky bits
+func parseMode(s string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 07777 {
+		return 0, fmt.Errorf("invalid mode %q, expected octal permission bits such as 0755", s)
+	}
+	return ft.FileMode(uint32(m)), nil
+}
+
 var FilesRmCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Remove a file.",
@@ -778,7 +1160,7 @@ Remove files or directories.
 		cmds.BoolOption("recursive", "r", "Recursively remove directories."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		nd, err := req.InvocContext().GetNode()
+		nd, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -860,7 +1242,48 @@ Remove files or directories.
 	},
 }
 
-func getFileHandle(r *mfs.Root, path string, create bool) (*mfs.File, error) {
+var cidVersionOption = cmds.IntOption("cid-version", "Cid version to use for new nodes. (experimental)")
+var hashOption = cmds.StringOption("hash", "Hash function to use for new nodes. Will set Cid version to 1 if used. Default: Unixfs.HashFunction config, or sha2-256. (experimental)")
+
+// getPrefix returns the CID prefix of the nodes to create, from the
+// cid-version and hash options or the config. It returns nil when the
+// default prefix of the files root should be used.
+func getPrefix(req cmds.Request, n *core.IpfsNode) (*cid.Prefix, error) {
+	cidVer, cidVerSet, err := req.Option("cid-version").Int()
+	if err != nil {
+		return nil, err
+	}
+	hashFunStr, hashFunSet, err := req.Option("hash").String()
+	if err != nil {
+		return nil, err
+	}
+
+	if !hashFunSet {
+		cfg, err := n.Repo.Config()
+		if err != nil {
+			return nil, err
+		}
+		hashFunStr = cfg.Unixfs.HashFunction
+	}
+
+	if !cidVerSet && hashFunStr == "" {
+		return nil, nil
+	}
+	if hashFunStr == "" {
+		hashFunStr = dag.DefaultHashFunction
+	}
+	if !cidVerSet && (hashFunSet || hashFunStr != dag.DefaultHashFunction) {
+		cidVer = 1
+	}
+
+	prefix, err := dag.PrefixForHash(cidVer, hashFunStr)
+	if err != nil {
+		return nil, err
+	}
+	return &prefix, nil
+}
+
+func getFileHandle(r *mfs.Root, path string, create bool, prefix *cid.Prefix) (*mfs.File, error) {
 
 	target, err := mfs.Lookup(r, path)
 	switch err {
@@ -889,6 +1312,9 @@ func getFileHandle(r *mfs.Root, path string, create bool) (*mfs.File, error) {
 		}
 
 		nd := dag.NodeWithData(ft.FilePBData(nil, 0))
+		if prefix != nil {
+			nd.SetPrefix(prefix)
+		}
 		err = pdir.AddChild(fname, nd)
 		if err != nil {
 			return nil, err
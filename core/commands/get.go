@@ -34,6 +34,10 @@ To output a TAR archive instead of unpacked files, use '--archive' or '-a'.
 
 To compress the output with GZIP compression, use '--compress' or '-C'. You
 may also specify the level of compression by specifying '-l=<1-9>'.
+
+Symlinks are only created when they point inside the output directory. Use
+'--symlinks=preserve' to create all of them as they are, or
+'--symlinks=skip' to create none.
 `,
 	},
 
@@ -45,9 +49,14 @@ may also specify the level of compression by specifying '-l=<1-9>'.
 		cmds.BoolOption("archive", "a", "Output a TAR archive.").Default(false),
 		cmds.BoolOption("compress", "C", "Compress the output with GZIP compression.").Default(false),
 		cmds.IntOption("compression-level", "l", "The level of compression (1-9).").Default(-1),
+		cmds.StringOption("symlinks", "Which symlinks to create: safe, preserve or skip.").Default("safe"),
 	},
 	PreRun: func(req cmds.Request) error {
 		_, err := getCompressOptions(req)
+		if err != nil {
+			return err
+		}
+		_, err = getSymlinkMode(req)
 		return err
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
@@ -118,6 +127,12 @@ may also specify the level of compression by specifying '-l=<1-9>'.
 			return
 		}
 
+		symlinks, err := getSymlinkMode(req)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
 		archive, _, _ := req.Option("archive").Bool()
 
 		gw := getWriter{
@@ -126,6 +141,7 @@ may also specify the level of compression by specifying '-l=<1-9>'.
 			Archive:     archive,
 			Compression: cmplvl,
 			Size:        int64(res.Length()),
+			Symlinks:    symlinks,
 		}
 
 		if err := gw.Write(outReader, outPath); err != nil {
@@ -178,6 +194,7 @@ type getWriter struct {
 	Archive     bool
 	Compression int
 	Size        int64
+	Symlinks    tar.SymlinkMode
 }
 
 func (gw *getWriter) Write(r io.Reader, fpath string) error {
@@ -225,7 +242,7 @@ func (gw *getWriter) writeExtracted(r io.Reader, fpath string) error {
 	defer bar.Finish()
 	defer bar.Set64(gw.Size)
 
-	extractor := &tar.Extractor{fpath, bar.Add64}
+	extractor := &tar.Extractor{Path: fpath, Progress: bar.Add64, Symlinks: gw.Symlinks}
 	return extractor.Extract(r)
 }
 
@@ -242,3 +259,8 @@ func getCompressOptions(req cmds.Request) (int, error) {
 	}
 	return cmplvl, nil
 }
+
+func getSymlinkMode(req cmds.Request) (tar.SymlinkMode, error) {
+	symlinks, _, _ := req.Option("symlinks").String()
+	return tar.ParseSymlinkMode(symlinks)
+}
@@ -36,6 +36,7 @@ type IdOutput struct {
 	Addresses       []string
 	AgentVersion    string
 	ProtocolVersion string
+	Reachability    string `json:",omitempty"`
 }
 
 var IDCmd = &cmds.Command{
@@ -51,6 +52,10 @@ If no peer is specified, prints out information for local peers.
 <pver>: Protocol version.
 <pubkey>: Public key.
 <addrs>: Addresses (newline delimited).
+<reach>: Reachability of the local node: public, private or unknown.
+
+The reachability of the local node is found by asking connected peers to
+dial it back. It is only known while the daemon is running.
 
 EXAMPLE:
 
@@ -132,6 +137,7 @@ EXAMPLE:
 				output = strings.Replace(output, "<pver>", val.ProtocolVersion, -1)
 				output = strings.Replace(output, "<pubkey>", val.PublicKey, -1)
 				output = strings.Replace(output, "<addrs>", strings.Join(val.Addresses, "\n"), -1)
+				output = strings.Replace(output, "<reach>", val.Reachability, -1)
 				output = strings.Replace(output, "\\n", "\n", -1)
 				output = strings.Replace(output, "\\t", "\t", -1)
 				return strings.NewReader(output), nil
@@ -207,6 +213,10 @@ func printSelf(node *core.IpfsNode) (interface{}, error) {
 			info.Addresses = append(info.Addresses, s)
 		}
 	}
+	if node.AutoNAT != nil {
+		reach, _ := node.AutoNAT.Status()
+		info.Reachability = reach.String()
+	}
 	info.ProtocolVersion = identify.LibP2PVersion
 	info.AgentVersion = identify.ClientVersion
 	return info, nil
@@ -3,6 +3,7 @@ package commands
 import (
 	"bytes"
 	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -11,6 +12,12 @@ import (
 	"text/tabwriter"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
+	hooks "github.com/ipfs/go-ipfs/core/hooks"
+	repo "github.com/ipfs/go-ipfs/repo"
+	config "github.com/ipfs/go-ipfs/repo/config"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 
 	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
 	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
@@ -30,6 +37,9 @@ var KeyCmd = &cmds.Command{
   > ipfs key list
   self
   mykey
+
+'ipfs key rotate' replaces the node's identity with a new key, keeping the
+old one in the keystore.
 		`,
 	},
 	Subcommands: map[string]*cmds.Command{
@@ -37,6 +47,10 @@ var KeyCmd = &cmds.Command{
 		"list":   keyListCmd,
 		"rename": keyRenameCmd,
 		"rm":     keyRmCmd,
+		"rotate": keyRotateCmd,
+	},
+	Options: []cmds.Option{
+		cmdenv.OfflineOption,
 	},
 }
 
@@ -57,6 +71,13 @@ type KeyRenameOutput struct {
 	Overwrite bool
 }
 
+// KeyRotateOutput define the output type of keyRotateCmd
+type KeyRotateOutput struct {
+	Old   string // name of the old identity key in the keystore
+	OldId string
+	NewId string
+}
+
 var keyGenCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Create a new keypair",
@@ -69,7 +90,7 @@ var keyGenCmd = &cmds.Command{
 		cmds.StringArg("name", true, false, "name of key to create"),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		n, err := req.InvocContext().GetNode()
+		n, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -98,54 +119,24 @@ var keyGenCmd = &cmds.Command{
 			return
 		}
 
-		var sk ci.PrivKey
-		var pk ci.PubKey
-
-		switch typ {
-		case "rsa":
-			if !sizefound {
-				res.SetError(fmt.Errorf("please specify a key size with --size"), cmds.ErrNormal)
-				return
-			}
-
-			priv, pub, err := ci.GenerateKeyPairWithReader(ci.RSA, size, rand.Reader)
-			if err != nil {
-				res.SetError(err, cmds.ErrNormal)
-				return
-			}
-
-			sk = priv
-			pk = pub
-		case "ed25519":
-			priv, pub, err := ci.GenerateEd25519Key(rand.Reader)
-			if err != nil {
-				res.SetError(err, cmds.ErrNormal)
-				return
-			}
-
-			sk = priv
-			pk = pub
-		default:
-			res.SetError(fmt.Errorf("unrecognized key type: %s", typ), cmds.ErrNormal)
+		if typ == "rsa" && !sizefound {
+			res.SetError(fmt.Errorf("please specify a key size with --size"), cmds.ErrNormal)
 			return
 		}
 
-		err = n.Repo.Keystore().Put(name, sk)
-		if err != nil {
+		hookArgs := &keyGenHookArgs{Name: name, Type: typ, Size: size}
+		if err := n.Hooks.Pre(req.Context(), hooks.OpKeyGen, hookArgs); err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		pid, err := peer.IDFromPublicKey(pk)
+		out, err := genKey(n, name, typ, size)
+		n.Hooks.Post(req.Context(), hooks.OpKeyGen, hookArgs, out, err)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
-
-		res.SetOutput(&KeyOutput{
-			Name: name,
-			Id:   pid.Pretty(),
-		})
+		res.SetOutput(out)
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
@@ -168,7 +159,7 @@ var keyListCmd = &cmds.Command{
 		cmds.BoolOption("l", "Show extra information about keys."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		n, err := req.InvocContext().GetNode()
+		n, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -224,7 +215,7 @@ var keyRenameCmd = &cmds.Command{
 		cmds.BoolOption("force", "f", "Allow to overwrite an existing key."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		n, err := req.InvocContext().GetNode()
+		n, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -328,7 +319,7 @@ var keyRmCmd = &cmds.Command{
 		cmds.BoolOption("l", "Show extra information about keys."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		n, err := req.InvocContext().GetNode()
+		n, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -376,6 +367,198 @@ var keyRmCmd = &cmds.Command{
 	Type: KeyOutputList{},
 }
 
+var keyRotateCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Replace the node's identity with a new keypair",
+		ShortDescription: `
+'ipfs key rotate' generates a new identity key for the node, for example to
+move from RSA to Ed25519, and keeps the old key in the keystore under the
+name given with --oldkey. The old key is added to Ipns.RepublishKeys, so
+the IPNS record last published with it keeps being republished and the old
+name keeps resolving.
+
+  > ipfs key rotate --type=ed25519 -o old-self
+
+The node's peer ID changes. The daemon must not be running.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("oldkey", "o", "Keystore name to keep the old identity key under."),
+		cmds.StringOption("type", "t", "type of the key to create [rsa, ed25519]").Default("ed25519"),
+		cmds.IntOption("size", "s", "size of the key to generate").Default(2048),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		oldName, _, _ := req.Option("oldkey").String()
+		if oldName == "" {
+			res.SetError(errors.New("please name the old key with --oldkey"), cmds.ErrClient)
+			return
+		}
+		if oldName == "self" {
+			res.SetError(errors.New("cannot store the old key with name 'self'"), cmds.ErrClient)
+			return
+		}
+		typ, _, _ := req.Option("type").String()
+		size, _, _ := req.Option("size").Int()
+
+		r, err := fsrepo.Open(req.InvocContext().ConfigRoot)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		defer r.Close()
+
+		cfg, err := r.Config()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		// the identity key is not encrypted, even in encrypted repos
+		oldSk, err := cfg.Identity.DecodePrivateKey("")
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		oldId, err := peer.IDFromPrivateKey(oldSk)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		// no node runs, the hooks are the ones of the config
+		hs, err := hooks.FromConfig(cfg)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		hookArgs := &keyRotateHookArgs{Old: oldName, OldId: oldId.Pretty(), Type: typ, Size: size}
+		if err := hs.Pre(req.Context(), hooks.OpKeyRotate, hookArgs); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		out, err := rotateKey(r, cfg, oldSk, oldName, typ, size)
+		hs.Post(req.Context(), hooks.OpKeyRotate, hookArgs, out, err)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		out.OldId = oldId.Pretty()
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			k, ok := res.Output().(*KeyRotateOutput)
+			if !ok {
+				return nil, fmt.Errorf("expected a KeyRotateOutput as command result")
+			}
+
+			buf := new(bytes.Buffer)
+			fmt.Fprintf(buf, "Identity rotated to %s\n", k.NewId)
+			fmt.Fprintf(buf, "Old identity %s kept as key %s\n", k.OldId, k.Old)
+			return buf, nil
+		},
+	},
+	Type: KeyRotateOutput{},
+}
+
+// generateKey generates a keypair of the given type, size only applies to
+// RSA keys
+func generateKey(typ string, size int) (ci.PrivKey, ci.PubKey, error) {
+	switch typ {
+	case "rsa":
+		return ci.GenerateKeyPairWithReader(ci.RSA, size, rand.Reader)
+	case "ed25519":
+		return ci.GenerateEd25519Key(rand.Reader)
+	default:
+		return nil, nil, fmt.Errorf("unrecognized key type: %s", typ)
+	}
+}
+
+// keyGenHookArgs are the arguments of 'ipfs key gen' passed to hooks
+type keyGenHookArgs struct {
+	Name string
+	Type string
+	Size int `json:",omitempty"`
+}
+
+// genKey generates a key of the given type and size, and stores it in the
+// keystore of n under name
+func genKey(n *core.IpfsNode, name, typ string, size int) (*KeyOutput, error) {
+	sk, pk, err := generateKey(typ, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := n.Repo.Keystore().Put(name, sk); err != nil {
+		return nil, err
+	}
+
+	pid, err := peer.IDFromPublicKey(pk)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyOutput{
+		Name: name,
+		Id:   pid.Pretty(),
+	}, nil
+}
+
+// keyRotateHookArgs are the arguments of 'ipfs key rotate' passed to hooks
+type keyRotateHookArgs struct {
+	Old   string
+	OldId string
+	Type  string
+	Size  int `json:",omitempty"`
+}
+
+// rotateKey replaces the identity of the repo r, whose config is cfg, with
+// a new key of the given type and size, and keeps the old key oldSk in the
+// keystore under oldName. OldId isn't set in the returned output.
+func rotateKey(r repo.Repo, cfg *config.Config, oldSk ci.PrivKey, oldName, typ string, size int) (*KeyRotateOutput, error) {
+	ks := r.Keystore()
+	if exist, err := ks.Has(oldName); err != nil {
+		return nil, err
+	} else if exist {
+		return nil, fmt.Errorf("a key named %s already exists", oldName)
+	}
+
+	sk, pk, err := generateKey(typ, size)
+	if err != nil {
+		return nil, err
+	}
+	id, err := peer.IDFromPublicKey(pk)
+	if err != nil {
+		return nil, err
+	}
+	skbytes, err := sk.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// keep the old key before the config stops referencing it
+	if err := ks.Put(oldName, oldSk); err != nil {
+		return nil, err
+	}
+
+	updated := *cfg
+	updated.Identity = config.Identity{
+		PeerID:  id.Pretty(),
+		PrivKey: base64.StdEncoding.EncodeToString(skbytes),
+	}
+	updated.Ipns.RepublishKeys = append(append([]string(nil), cfg.Ipns.RepublishKeys...), oldName)
+
+	// the config file is replaced atomically, it either holds the old
+	// identity or the new one
+	if err := r.SetConfig(&updated); err != nil {
+		if err := ks.Delete(oldName); err != nil {
+			log.Errorf("failed to remove the old key %s from the keystore: %s", oldName, err)
+		}
+		return nil, err
+	}
+
+	return &KeyRotateOutput{Old: oldName, NewId: id.Pretty()}, nil
+}
+
 func keyOutputListMarshaler(res cmds.Response) (io.Reader, error) {
 	withId, _, _ := res.Request().Option("l").Bool()
 
@@ -16,6 +16,7 @@ import (
 	uio "github.com/ipfs/go-ipfs/unixfs/io"
 	unixfspb "github.com/ipfs/go-ipfs/unixfs/pb"
 
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
@@ -44,6 +45,26 @@ the following format:
   <link base58 hash> <link size in bytes> <link name>
 
 The JSON output contains type information.
+`,
+		LongDescription: `
+Displays the contents of an IPFS or IPNS object(s) at the given path, with
+the following format:
+
+  <link base58 hash> <link size in bytes> <link name>
+
+The JSON output contains type information.
+
+By default, the whole listing is output once all the entries are known. With
+--stream, every entry is output as soon as it is found while traversing the
+directory, which makes listing huge sharded directories practical. The
+columns of the streamed text output are not aligned.
+
+Finding the type of an entry requires fetching its root block, which
+--resolve-type=false skips. Sizes are read from the directory itself, and
+--size=false leaves them out of the output. With both, 'ipfs ls' fetches
+nothing but the blocks of the directory:
+
+  > ipfs ls --stream --size=false --resolve-type=false /ipfs/QmHash
 `,
 	},
 
@@ -53,6 +74,8 @@ The JSON output contains type information.
 	Options: []cmds.Option{
 		cmds.BoolOption("headers", "v", "Print table headers (Hash, Size, Name).").Default(false),
 		cmds.BoolOption("resolve-type", "Resolve linked objects to find out their types.").Default(true),
+		cmds.BoolOption("size", "Print the size of the linked objects.").Default(true),
+		cmds.BoolOption("stream", "s", "Output entries as they are found.").Default(false),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		nd, err := req.InvocContext().GetNode()
@@ -73,6 +96,18 @@ The JSON output contains type information.
 			return
 		}
 
+		size, _, err := req.Option("size").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		stream, _, err := req.Option("stream").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
 		dserv := nd.DAG
 		if !resolve {
 			offlineexch := offline.Exchange(nd.Blockstore)
@@ -82,7 +117,7 @@ The JSON output contains type information.
 
 		paths := req.Arguments()
 
-		var dagnodes []node.Node
+		var dirs []*uio.Directory
 		for _, fpath := range paths {
 			p, err := path.ParsePath(fpath)
 			if err != nil {
@@ -100,17 +135,79 @@ The JSON output contains type information.
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
-			dagnodes = append(dagnodes, dagnode)
-		}
 
-		output := make([]LsObject, len(req.Arguments()))
-		for i, dagnode := range dagnodes {
 			dir, err := uio.NewDirectoryFromNode(nd.DAG, dagnode)
 			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
 				return
 			}
+			dirs = append(dirs, dir)
+		}
+
+		lsLink := func(link *node.Link) (LsLink, error) {
+			t := unixfspb.Data_DataType(-1)
+
+			linkNode, err := link.GetNode(req.Context(), dserv)
+			if err == merkledag.ErrNotFound && !resolve {
+				// not an error
+				linkNode = nil
+			} else if err != nil {
+				return LsLink{}, err
+			}
+
+			if pn, ok := linkNode.(*merkledag.ProtoNode); ok {
+				d, err := unixfs.FromBytes(pn.Data())
+				if err != nil {
+					return LsLink{}, err
+				}
+
+				t = d.GetType()
+			}
+
+			out := LsLink{
+				Name: link.Name,
+				Hash: link.Cid.String(),
+				Type: t,
+			}
+			if size {
+				out.Size = link.Size
+			}
+			return out, nil
+		}
 
+		if stream {
+			out := make(chan interface{})
+			res.SetOutput((<-chan interface{})(out))
+
+			go func() {
+				defer close(out)
+
+				for i, dir := range dirs {
+					err := dir.ForEachLink(req.Context(), func(link *node.Link) error {
+						l, err := lsLink(link)
+						if err != nil {
+							return err
+						}
+
+						obj := LsObject{Hash: paths[i], Links: []LsLink{l}}
+						select {
+						case out <- &LsOutput{[]LsObject{obj}}:
+							return nil
+						case <-req.Context().Done():
+							return req.Context().Err()
+						}
+					})
+					if err != nil {
+						res.SetError(err, cmds.ErrNormal)
+						return
+					}
+				}
+			}()
+			return
+		}
+
+		output := make([]LsObject, len(dirs))
+		for i, dir := range dirs {
 			links, err := dir.Links(req.Context())
 			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
@@ -123,32 +220,11 @@ The JSON output contains type information.
 			}
 
 			for j, link := range links {
-				t := unixfspb.Data_DataType(-1)
-
-				linkNode, err := link.GetNode(req.Context(), dserv)
-				if err == merkledag.ErrNotFound && !resolve {
-					// not an error
-					linkNode = nil
-				} else if err != nil {
+				output[i].Links[j], err = lsLink(link)
+				if err != nil {
 					res.SetError(err, cmds.ErrNormal)
 					return
 				}
-
-				if pn, ok := linkNode.(*merkledag.ProtoNode); ok {
-					d, err := unixfs.FromBytes(pn.Data())
-					if err != nil {
-						res.SetError(err, cmds.ErrNormal)
-						return
-					}
-
-					t = d.GetType()
-				}
-				output[i].Links[j] = LsLink{
-					Name: link.Name,
-					Hash: link.Cid.String(),
-					Size: link.Size,
-					Type: t,
-				}
 			}
 		}
 
@@ -156,32 +232,96 @@ The JSON output contains type information.
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-
 			headers, _, _ := res.Request().Option("headers").Bool()
-			output := res.Output().(*LsOutput)
-			buf := new(bytes.Buffer)
-			w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
-			for _, object := range output.Objects {
-				if len(output.Objects) > 1 {
-					fmt.Fprintf(w, "%s:\n", object.Hash)
-				}
-				if headers {
-					fmt.Fprintln(w, "Hash\tSize\tName")
-				}
-				for _, link := range object.Links {
-					if link.Type == unixfspb.Data_Directory {
-						link.Name += "/"
+			size, _, _ := res.Request().Option("size").Bool()
+			multiple := len(res.Request().Arguments()) > 1
+
+			switch output := res.Output().(type) {
+			case *LsOutput:
+				buf := new(bytes.Buffer)
+				w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
+				for _, object := range output.Objects {
+					if multiple {
+						fmt.Fprintf(w, "%s:\n", object.Hash)
+					}
+					if headers {
+						writeLsHeaders(w, size)
+					}
+					for _, link := range object.Links {
+						writeLsLink(w, link, size)
+					}
+					if multiple {
+						fmt.Fprintln(w)
 					}
-					fmt.Fprintf(w, "%s\t%v\t%s\n", link.Hash, link.Size, link.Name)
 				}
-				if len(output.Objects) > 1 {
-					fmt.Fprintln(w)
+				w.Flush()
+
+				return buf, nil
+
+			case <-chan interface{}:
+				// the objects of the stream hold one link each, the
+				// headers are printed when the first link of an object
+				// comes
+				last := ""
+				marshal := func(v interface{}) (io.Reader, error) {
+					obj, ok := v.(*LsOutput)
+					if !ok {
+						return nil, u.ErrCast()
+					}
+
+					buf := new(bytes.Buffer)
+					w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
+					for _, object := range obj.Objects {
+						if object.Hash != last {
+							if multiple {
+								if last != "" {
+									fmt.Fprintln(w)
+								}
+								fmt.Fprintf(w, "%s:\n", object.Hash)
+							}
+							if headers {
+								writeLsHeaders(w, size)
+							}
+							last = object.Hash
+						}
+						for _, link := range object.Links {
+							writeLsLink(w, link, size)
+						}
+					}
+					w.Flush()
+
+					return buf, nil
 				}
-			}
-			w.Flush()
 
-			return buf, nil
+				return &cmds.ChannelMarshaler{
+					Channel:   output,
+					Marshaler: marshal,
+					Res:       res,
+				}, nil
+
+			default:
+				return nil, u.ErrCast()
+			}
 		},
 	},
 	Type: LsOutput{},
 }
+
+func writeLsHeaders(w io.Writer, size bool) {
+	if size {
+		fmt.Fprintln(w, "Hash\tSize\tName")
+	} else {
+		fmt.Fprintln(w, "Hash\tName")
+	}
+}
+
+func writeLsLink(w io.Writer, link LsLink, size bool) {
+	if link.Type == unixfspb.Data_Directory {
+		link.Name += "/"
+	}
+	if size {
+		fmt.Fprintf(w, "%s\t%v\t%s\n", link.Hash, link.Size, link.Name)
+	} else {
+		fmt.Fprintf(w, "%s\t%s\n", link.Hash, link.Name)
+	}
+}
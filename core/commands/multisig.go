@@ -0,0 +1,469 @@
+package commands
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	namesys "github.com/ipfs/go-ipfs/namesys"
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
+	path "github.com/ipfs/go-ipfs/path"
+
+	crypto "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// PolicyOutput is the output of 'ipfs name multisig policy'
+type PolicyOutput struct {
+	Name      string
+	Threshold int
+	Keys      []string
+	Expires   string
+	// Policy is the encoded policy, to pass to 'ipfs name multisig propose'
+	Policy string
+}
+
+// MultisigRecordOutput describes a record being signed by the keys of a
+// signature policy
+type MultisigRecordOutput struct {
+	Name       string
+	Value      string
+	Signatures int
+	Threshold  int
+	// Record is the encoded record, to pass to the next signer or to
+	// 'ipfs name multisig publish'
+	Record string
+}
+
+var NameMultisigCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Publish IPNS records signed by several keys.",
+		ShortDescription: `
+'ipfs name multisig' publishes records for a name that must be signed by M
+of N keys, as listed in a signature policy signed by the key of the name.
+Resolvers only accept such records once they carry enough signatures.
+`,
+		LongDescription: `
+'ipfs name multisig' publishes records for a name that must be signed by M
+of N keys, as listed in a signature policy signed by the key of the name.
+Resolvers only accept such records once they carry enough signatures.
+
+The key of the name keeps the last word: records it signs alone are still
+valid, so keep it offline once the policy is signed.
+
+Each signer prints the public key of their signing key:
+
+  > ipfs name delegate pubkey --key=alice
+  CAESIBL5...
+
+The holder of the key of the name signs a policy requiring 2 of them:
+
+  > ipfs name multisig policy --key=registry --threshold=2 CAESIBL5... CAESIOq1... CAESIK2w...
+  CiQIARIg...
+
+A signer proposes a new value, and passes the record on:
+
+  > ipfs name multisig propose --key=alice --policy=CiQIARIg... /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+  CgUvaXBm...
+
+Another signer adds their signature, after which the record can be
+published by anyone:
+
+  > ipfs name multisig sign --key=bob CgUvaXBm...
+  CgUvaXBm...
+  > ipfs name multisig publish CgUvaXBm...
+  Published to QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n: /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+
+Only nodes running a version of go-ipfs that knows signature policies can
+resolve records published under one. Older resolvers ignore the policy and
+check the signature of the record against the key of the name, which fails,
+so they can't resolve the name.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"policy":  multisigPolicyCmd,
+		"propose": multisigProposeCmd,
+		"sign":    multisigSignCmd,
+		"publish": multisigPublishCmd,
+	},
+}
+
+var multisigPolicyCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Require records of a name to be signed by several keys.",
+		ShortDescription: `
+Signs, with the key of the name, a policy requiring its records to carry
+signatures from --threshold of the given public keys, as printed by
+'ipfs name delegate pubkey'.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("pubkey", true, true, "Public keys allowed to sign records."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("key", "k", "Name of the key of the IPNS name, or its PeerID. Default: <<default>>.").Default("self"),
+		cmds.IntOption("threshold", "m", "Number of signatures a record needs."),
+		cmds.StringOption("lifetime", "t", "Time duration that the policy will be valid for. <<default>>").Default("8760h"),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		kname, _, _ := req.Option("key").String()
+		owner, err := keylookup(n, kname)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		threshold, found, err := req.Option("threshold").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if !found {
+			res.SetError(errors.New("please specify the number of signatures with --threshold"), cmds.ErrClient)
+			return
+		}
+
+		lifetime, _, _ := req.Option("lifetime").String()
+		d, err := time.ParseDuration(lifetime)
+		if err != nil {
+			res.SetError(fmt.Errorf("error parsing lifetime option: %s", err), cmds.ErrNormal)
+			return
+		}
+
+		out := &PolicyOutput{Threshold: threshold}
+		var keys []crypto.PubKey
+		for _, arg := range req.Arguments() {
+			pkb, err := base64.StdEncoding.DecodeString(arg)
+			if err != nil {
+				res.SetError(fmt.Errorf("invalid public key: %s", err), cmds.ErrClient)
+				return
+			}
+			pk, err := crypto.UnmarshalPublicKey(pkb)
+			if err != nil {
+				res.SetError(fmt.Errorf("invalid public key: %s", err), cmds.ErrClient)
+				return
+			}
+			id, err := peer.IDFromPublicKey(pk)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			keys = append(keys, pk)
+			out.Keys = append(out.Keys, id.Pretty())
+		}
+
+		eol := time.Now().Add(d)
+		pol, err := namesys.CreatePolicy(owner, keys, threshold, eol)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		data, err := proto.Marshal(pol)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		name, err := peer.IDFromPrivateKey(owner)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		out.Name = name.Pretty()
+		out.Expires = u.FormatRFC3339(eol)
+		out.Policy = base64.StdEncoding.EncodeToString(data)
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			v, ok := res.Output().(*PolicyOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+			return strings.NewReader(v.Policy + "\n"), nil
+		},
+	},
+	Type: PolicyOutput{},
+}
+
+var multisigProposeCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Create a record to be signed by the keys of a policy.",
+		ShortDescription: `
+Creates a record of <ipfs-path> for the name of --policy, following the
+last one published, and signs it with --key.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("ipfs-path", true, false, "ipfs path of the object to be published."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("policy", "Signature policy printed by 'ipfs name multisig policy'."),
+		cmds.StringOption("key", "k", "Name of the key to sign with, or its PeerID. Default: <<default>>.").Default("self"),
+		cmds.StringOption("lifetime", "t", "Time duration that the record will be valid for. <<default>>").Default("24h"),
+		cmds.BoolOption("resolve", "Resolve given path before publishing.").Default(true),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		ctx := req.Context()
+
+		if !n.OnlineMode() {
+			if err := n.SetupOfflineRouting(); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
+		polstr, found, _ := req.Option("policy").String()
+		if !found {
+			res.SetError(errors.New("please specify the signature policy with --policy"), cmds.ErrClient)
+			return
+		}
+		data, err := base64.StdEncoding.DecodeString(polstr)
+		if err != nil {
+			res.SetError(fmt.Errorf("invalid policy: %s", err), cmds.ErrClient)
+			return
+		}
+		pol := new(pb.SignaturePolicy)
+		if err := proto.Unmarshal(data, pol); err != nil {
+			res.SetError(fmt.Errorf("invalid policy: %s", err), cmds.ErrClient)
+			return
+		}
+
+		kname, _, _ := req.Option("key").String()
+		k, err := keylookup(n, kname)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		lifetime, _, _ := req.Option("lifetime").String()
+		d, err := time.ParseDuration(lifetime)
+		if err != nil {
+			res.SetError(fmt.Errorf("error parsing lifetime option: %s", err), cmds.ErrNormal)
+			return
+		}
+
+		pth, err := path.ParsePath(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if verify, _, _ := req.Option("resolve").Bool(); verify {
+			if _, err := core.Resolve(ctx, n.Namesys, n.Resolver, pth); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
+		tp, err := thresholdPublisher(n)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		e, err := tp.NewThresholdEntry(ctx, pol, pth, time.Now().Add(d))
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if err := namesys.SignThresholdEntry(k, e); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		out, err := multisigRecordOutput(e)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: multisigRecordMarshaler,
+	},
+	Type: MultisigRecordOutput{},
+}
+
+var multisigSignCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Add a signature to a record of a signature policy.",
+		ShortDescription: `
+Signs a record created by 'ipfs name multisig propose' with --key, and
+prints it with the signature added.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("record", true, false, "The record to sign."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("key", "k", "Name of the key to sign with, or its PeerID. Default: <<default>>.").Default("self"),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		e, err := parseMultisigRecord(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		kname, _, _ := req.Option("key").String()
+		k, err := keylookup(n, kname)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if err := namesys.SignThresholdEntry(k, e); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		out, err := multisigRecordOutput(e)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: multisigRecordMarshaler,
+	},
+	Type: MultisigRecordOutput{},
+}
+
+var multisigPublishCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Publish a record carrying enough signatures for its policy.",
+		ShortDescription: `
+Publishes a record signed with 'ipfs name multisig propose' and 'sign'.
+It fails if the record doesn't carry enough signatures yet.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("record", true, false, "The record to publish."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if !n.OnlineMode() {
+			if err := n.SetupOfflineRouting(); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
+		e, err := parseMultisigRecord(req.Arguments()[0])
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
+		tp, err := thresholdPublisher(n)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if err := tp.PublishThresholdEntry(req.Context(), e); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		name, err := namesys.PolicyName(e.GetPolicy())
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		res.SetOutput(&IpnsEntry{
+			Name:  name.Pretty(),
+			Value: string(e.GetValue()),
+		})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			v, ok := res.Output().(*IpnsEntry)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+			return strings.NewReader(fmt.Sprintf("Published to %s: %s\n", v.Name, v.Value)), nil
+		},
+	},
+	Type: IpnsEntry{},
+}
+
+func thresholdPublisher(n *core.IpfsNode) (namesys.ThresholdPublisher, error) {
+	if n.Namesys == nil {
+		return nil, errNotOnline
+	}
+	tp, ok := n.Namesys.(namesys.ThresholdPublisher)
+	if !ok {
+		return nil, errors.New("the name system does not support signature policies")
+	}
+	return tp, nil
+}
+
+func parseMultisigRecord(s string) (*pb.IpnsEntry, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record: %s", err)
+	}
+	e := new(pb.IpnsEntry)
+	if err := proto.Unmarshal(data, e); err != nil {
+		return nil, fmt.Errorf("invalid record: %s", err)
+	}
+	if e.GetPolicy() == nil {
+		return nil, errors.New("record has no signature policy")
+	}
+	return e, nil
+}
+
+func multisigRecordOutput(e *pb.IpnsEntry) (*MultisigRecordOutput, error) {
+	data, err := proto.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	name, err := namesys.PolicyName(e.GetPolicy())
+	if err != nil {
+		return nil, err
+	}
+	return &MultisigRecordOutput{
+		Name:       name.Pretty(),
+		Value:      string(e.GetValue()),
+		Signatures: len(e.GetPolicySignatures()),
+		Threshold:  int(e.GetPolicy().GetThreshold()),
+		Record:     base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+func multisigRecordMarshaler(res cmds.Response) (io.Reader, error) {
+	v, ok := res.Output().(*MultisigRecordOutput)
+	if !ok {
+		return nil, u.ErrCast()
+	}
+	return strings.NewReader(v.Record + "\n"), nil
+}
@@ -1,10 +1,16 @@
 package commands
 
-import cmds "github.com/ipfs/go-ipfs/commands"
+import (
+	cmds "github.com/ipfs/go-ipfs/commands"
+	dnslink "github.com/ipfs/go-ipfs/namesys/dnslink"
+)
 
 type IpnsEntry struct {
 	Name  string
 	Value string
+
+	// DNSLink is the dnslink record updated along with the name, if any
+	DNSLink *dnslink.Update `json:",omitempty"`
 }
 
 var NameCmd = &cmds.Command{
@@ -56,7 +62,12 @@ Resolve the value of a dnslink:
 	},
 
 	Subcommands: map[string]*cmds.Command{
-		"publish": PublishCmd,
-		"resolve": IpnsCmd,
+		"publish":    PublishCmd,
+		"resolve":    IpnsCmd,
+		"delegate":   NameDelegateCmd,
+		"local":      NameLocalCmd,
+		"multisig":   NameMultisigCmd,
+		"put":        NamePutCmd,
+		"quarantine": NameQuarantineCmd,
 	},
 }
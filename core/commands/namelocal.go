@@ -0,0 +1,262 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	namesys "github.com/ipfs/go-ipfs/namesys"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// LocalRecordOutput describes an IPNS record of the local datastore
+type LocalRecordOutput struct {
+	Name     string
+	Value    string
+	Sequence uint64
+	EOL      string `json:",omitempty"`
+	Expired  bool
+	// Own is set for the records of the node's keys, which the republisher
+	// keeps alive
+	Own bool
+}
+
+// LocalRecordList is the output of 'ipfs name local ls' and 'rm'
+type LocalRecordList struct {
+	Records []LocalRecordOutput
+}
+
+var NameLocalCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Inspect the IPNS records stored in the local datastore.",
+		ShortDescription: `
+The datastore keeps the IPNS records this node published, which the
+republisher refreshes, and the records the DHT stores for other nodes.
+'ipfs name local' lists them and removes the stale ones.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"ls": nameLocalLsCmd,
+		"rm": nameLocalRmCmd,
+	},
+}
+
+var nameLocalLsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the IPNS records stored in the local datastore.",
+		ShortDescription: `
+Lists the IPNS records of the local datastore with their value, sequence
+number and EOL. Records published with the node's keys are marked 'own',
+expired ones 'expired'.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("expired", "Only list expired records.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		recs, err := localRecords(n)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		expired, _, _ := req.Option("expired").Bool()
+		out := new(LocalRecordList)
+		for _, r := range recs {
+			if expired && !r.Expired {
+				continue
+			}
+			out.Records = append(out.Records, r)
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: localRecordsMarshaler,
+	},
+	Type: LocalRecordList{},
+}
+
+var nameLocalRmCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Remove IPNS records from the local datastore.",
+		ShortDescription: `
+Removes the records of the given names, or all the expired records with
+--expired, and lists the removed records. Removing the record of one of
+the node's own keys stops the republisher from refreshing it, so it
+requires --force.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", false, true, "Names of the records to remove."),
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("expired", "Remove all the expired records.").Default(false),
+		cmds.BoolOption("force", "f", "Allow removing the records of the node's own keys.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		expired, _, _ := req.Option("expired").Bool()
+		force, _, _ := req.Option("force").Bool()
+		if expired == (len(req.Arguments()) > 0) {
+			res.SetError(errors.New("pass either names or --expired"), cmds.ErrClient)
+			return
+		}
+
+		recs, err := localRecords(n)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		byName := make(map[string]LocalRecordOutput, len(recs))
+		for _, r := range recs {
+			byName[r.Name] = r
+		}
+
+		var rm []LocalRecordOutput
+		if expired {
+			for _, r := range recs {
+				if r.Expired && (!r.Own || force) {
+					rm = append(rm, r)
+				}
+			}
+		} else {
+			for _, name := range req.Arguments() {
+				r, ok := byName[name]
+				if !ok {
+					res.SetError(fmt.Errorf("no local record for %s", name), cmds.ErrNormal)
+					return
+				}
+				if r.Own && !force {
+					res.SetError(fmt.Errorf("%s is one of the node's own names, use --force to remove it", name), cmds.ErrNormal)
+					return
+				}
+				rm = append(rm, r)
+			}
+		}
+
+		out := new(LocalRecordList)
+		dstore := n.Repo.Datastore()
+		for _, r := range rm {
+			id, err := peer.IDB58Decode(r.Name)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			if err := namesys.RemoveLocalRecord(dstore, id); err != nil && err != ds.ErrNotFound {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			out.Records = append(out.Records, r)
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: localRecordsMarshaler,
+	},
+	Type: LocalRecordList{},
+}
+
+// localRecords lists the records of the datastore of n
+func localRecords(n *core.IpfsNode) ([]LocalRecordOutput, error) {
+	own, err := ownNames(n)
+	if err != nil {
+		return nil, err
+	}
+
+	recs, err := namesys.LocalRecords(n.Repo.Datastore())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	out := make([]LocalRecordOutput, 0, len(recs))
+	for _, r := range recs {
+		o := LocalRecordOutput{
+			Name:     r.Name.Pretty(),
+			Value:    string(r.Entry.GetValue()),
+			Sequence: r.Entry.GetSequence(),
+			Own:      own[r.Name],
+		}
+		if eol, ok := r.EOL(); ok {
+			o.EOL = u.FormatRFC3339(eol)
+			o.Expired = now.After(eol)
+		}
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+// ownNames returns the names of the node's identity and keystore keys
+func ownNames(n *core.IpfsNode) (map[peer.ID]bool, error) {
+	own := map[peer.ID]bool{n.Identity: true}
+
+	ks := n.Repo.Keystore()
+	names, err := ks.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		sk, err := ks.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		id, err := peer.IDFromPrivateKey(sk)
+		if err != nil {
+			return nil, err
+		}
+		own[id] = true
+	}
+	return own, nil
+}
+
+func localRecordsMarshaler(res cmds.Response) (io.Reader, error) {
+	list, ok := res.Output().(*LocalRecordList)
+	if !ok {
+		return nil, u.ErrCast()
+	}
+
+	buf := new(bytes.Buffer)
+	w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
+	for _, r := range list.Records {
+		var flags []string
+		if r.Own {
+			flags = append(flags, "own")
+		}
+		if r.Expired {
+			flags = append(flags, "expired")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t\n", r.Name, r.Value, r.Sequence, r.EOL, joinFlags(flags))
+	}
+	w.Flush()
+	return buf, nil
+}
+
+func joinFlags(flags []string) string {
+	s := ""
+	for i, f := range flags {
+		if i > 0 {
+			s += ","
+		}
+		s += f
+	}
+	return s
+}
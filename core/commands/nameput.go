@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+	namesys "github.com/ipfs/go-ipfs/namesys"
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
+
+	routing "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing"
+	crypto "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+var NamePutCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Publish an IPNS record signed elsewhere.",
+		ShortDescription: `
+'ipfs name put' publishes a record of an IPNS name that was signed by the
+holder of its key, e.g. in a browser, without handing the key over. The
+node checks the record, puts it in the routing system and rebroadcasts it
+until it expires.
+`,
+		LongDescription: `
+'ipfs name put' publishes a record of an IPNS name that was signed by the
+holder of its key, e.g. in a browser, without handing the key over. The
+node checks the record, puts it in the routing system and rebroadcasts it
+until it expires.
+
+The record is a serialized IpnsEntry, encoded in base64. It is accepted if
+it is signed by the key of the name, by a key it delegated to, or by enough
+keys of its signature policy, and if its sequence number isn't older than
+the last record of the name. The public key of the name is looked up in the
+routing system unless it is given with --pubkey, as printed by
+'ipfs name delegate pubkey', or carried by the delegation or the policy of
+the record. The sequence number must be greater than the one of the last
+record, and the record must have an EOL within Ipns.MaxRecordLifetime.
+
+The node rebroadcasts the records of at most Ipns.MaxSignedNames names.
+The command is only served by the API, not by the gateway, as it makes the
+node store and rebroadcast the records for anyone. Serving it to web apps
+through the gateway waits for the API to authenticate its clients:
+
+  > curl -X POST "http://127.0.0.1:5001/api/v0/name/put?arg=QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n&arg=CjQvaXBm..."
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("name", true, false, "PeerID of the IPNS name."),
+		cmds.StringArg("record", true, false, "The signed record, encoded in base64."),
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("pubkey", "Public key of the name, encoded in base64."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		namePut(req, res)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: namePutMarshaler,
+	},
+	Type: IpnsEntry{},
+}
+
+// namePut runs 'ipfs name put'
+func namePut(req cmds.Request, res cmds.Response) {
+	n, err := req.InvocContext().GetNode()
+	if err != nil {
+		res.SetError(err, cmds.ErrNormal)
+		return
+	}
+	if !n.OnlineMode() {
+		if err := n.SetupOfflineRouting(); err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+	}
+
+	id, err := peer.IDB58Decode(strings.TrimPrefix(req.Arguments()[0], "/ipns/"))
+	if err != nil {
+		res.SetError(fmt.Errorf("invalid name: %s", err), cmds.ErrClient)
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(req.Arguments()[1])
+	if err != nil {
+		res.SetError(fmt.Errorf("invalid record: %s", err), cmds.ErrClient)
+		return
+	}
+	e := new(pb.IpnsEntry)
+	if err := proto.Unmarshal(data, e); err != nil {
+		res.SetError(fmt.Errorf("invalid record: %s", err), cmds.ErrClient)
+		return
+	}
+
+	pkopt, _, _ := req.Option("pubkey").String()
+	pk, err := namePubKey(req.Context(), n, id, e, pkopt)
+	if err != nil {
+		res.SetError(err, cmds.ErrClient)
+		return
+	}
+
+	sp, ok := n.Namesys.(namesys.SignedPublisher)
+	if !ok {
+		res.SetError(errors.New("the name system does not support signed records"), cmds.ErrNormal)
+		return
+	}
+	// the name is added before publishing, so that a refused name isn't
+	// put in the routing system for nothing
+	release := func() {}
+	if n.IpnsRepub != nil {
+		release, err = n.IpnsRepub.ReserveSignedName(pk)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+	}
+	if err := sp.PublishSignedEntry(req.Context(), pk, e); err != nil {
+		release()
+		res.SetError(err, cmds.ErrNormal)
+		return
+	}
+
+	res.SetOutput(&IpnsEntry{
+		Name:  id.Pretty(),
+		Value: string(e.GetValue()),
+	})
+}
+
+// namePubKey returns the public key of the name id: the one given, the
+// owner of the delegation or the policy of e, or the one of the routing
+// system
+func namePubKey(ctx context.Context, n *core.IpfsNode, id peer.ID, e *pb.IpnsEntry, given string) (crypto.PubKey, error) {
+	var pkb []byte
+	switch {
+	case given != "":
+		b, err := base64.StdEncoding.DecodeString(given)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %s", err)
+		}
+		pkb = b
+	case e.GetDelegation() != nil:
+		pkb = e.GetDelegation().GetOwner()
+	case e.GetPolicy() != nil:
+		pkb = e.GetPolicy().GetOwner()
+	default:
+		pk, err := routing.GetPublicKey(n.Routing, ctx, []byte(id))
+		if err != nil {
+			return nil, fmt.Errorf("public key of %s not found, pass it with --pubkey: %s", id.Pretty(), err)
+		}
+		return pk, nil
+	}
+
+	pk, err := crypto.UnmarshalPublicKey(pkb)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %s", err)
+	}
+	if !id.MatchesPublicKey(pk) {
+		return nil, fmt.Errorf("the public key is not the one of %s", id.Pretty())
+	}
+	return pk, nil
+}
+
+func namePutMarshaler(res cmds.Response) (io.Reader, error) {
+	v, ok := res.Output().(*IpnsEntry)
+	if !ok {
+		return nil, u.ErrCast()
+	}
+	return strings.NewReader(fmt.Sprintf("Published to %s: %s\n", v.Name, v.Value)), nil
+}
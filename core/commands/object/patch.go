@@ -21,6 +21,9 @@ var ObjectPatchCmd = &cmds.Command{
 'ipfs object patch <root> <cmd> <args>' is a plumbing command used to
 build custom DAG objects. It mutates objects, creating new objects as a
 result. This is the Merkle-DAG version of modifying an object.
+
+Deprecated: use 'ipfs dag patch', which also patches dag-cbor nodes and
+sharded directories, and follows paths below root.
 `,
 	},
 	Arguments: []cmds.Argument{},
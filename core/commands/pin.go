@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
 	core "github.com/ipfs/go-ipfs/core"
+	cmdenv "github.com/ipfs/go-ipfs/core/commands/cmdenv"
 	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
 	dag "github.com/ipfs/go-ipfs/merkledag"
 	path "github.com/ipfs/go-ipfs/path"
@@ -41,6 +43,11 @@ type AddPinOutput struct {
 	Progress int `json:",omitempty"`
 }
 
+type UpdatePinOutput struct {
+	Pins     []string
+	Progress int `json:",omitempty"`
+}
+
 var addPinCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline:          "Pin objects to local storage.",
@@ -53,6 +60,7 @@ var addPinCmd = &cmds.Command{
 	Options: []cmds.Option{
 		cmds.BoolOption("recursive", "r", "Recursively pin the object linked to by the specified object(s).").Default(true),
 		cmds.BoolOption("progress", "Show progress"),
+		cmds.StringOption("allocations", "Comma separated list of peers this pin is allocated to, recorded with the pin."),
 	},
 	Type: AddPinOutput{},
 	Run: func(req cmds.Request, res cmds.Response) {
@@ -71,9 +79,17 @@ var addPinCmd = &cmds.Command{
 			return
 		}
 		showProgress, _, _ := req.Option("progress").Bool()
+		if cmds.EventsRequested(req) {
+			showProgress = true
+		}
+
+		var allocations []string
+		if allocStr, _, _ := req.Option("allocations").String(); allocStr != "" {
+			allocations = strings.Split(allocStr, ",")
+		}
 
 		if !showProgress {
-			added, err := corerepo.Pin(n, req.Context(), req.Arguments(), recursive)
+			added, err := corerepo.PinWithAllocations(n, req.Context(), req.Arguments(), recursive, allocations)
 			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
 				return
@@ -88,7 +104,7 @@ var addPinCmd = &cmds.Command{
 		ch := make(chan []*cid.Cid)
 		go func() {
 			defer close(ch)
-			added, err := corerepo.Pin(n, ctx, req.Arguments(), recursive)
+			added, err := corerepo.PinWithAllocations(n, ctx, req.Arguments(), recursive, allocations)
 			if err != nil {
 				res.SetError(err, cmds.ErrNormal)
 				return
@@ -167,6 +183,7 @@ var addPinCmd = &cmds.Command{
 			return buf, nil
 		},
 	},
+	Events: pinEvent,
 }
 
 var rmPinCmd = &cmds.Command{
@@ -273,9 +290,10 @@ Example:
 	Options: []cmds.Option{
 		cmds.StringOption("type", "t", "The type of pinned keys to list. Can be \"direct\", \"indirect\", \"recursive\", or \"all\".").Default("all"),
 		cmds.BoolOption("quiet", "q", "Write just hashes of objects.").Default(false),
+		cmdenv.OfflineOption,
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
-		n, err := req.InvocContext().GetNode()
+		n, err := cmdenv.GetNode(req)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
@@ -325,6 +343,8 @@ Example:
 			for k, v := range keys.Keys {
 				if quiet {
 					fmt.Fprintf(out, "%s\n", k)
+				} else if len(v.Allocations) > 0 {
+					fmt.Fprintf(out, "%s %s %s\n", k, v.Type, strings.Join(v.Allocations, ","))
 				} else {
 					fmt.Fprintf(out, "%s %s\n", k, v.Type)
 				}
@@ -341,6 +361,18 @@ var updatePinCmd = &cmds.Command{
 Updates one pin to another, making sure that all objects in the new pin are
 local.  Then removes the old pin. This is an optimized version of adding the
 new pin and removing the old one.
+`,
+		LongDescription: `
+Updates one pin to another, making sure that all objects in the new pin are
+local.  Then removes the old pin. This is an optimized version of adding the
+new pin and removing the old one.
+
+Only the parts of the new DAG that differ from the old one are traversed and
+fetched, everything shared between the two versions is assumed to be present
+already since it is pinned. This makes updating a large pinned tree (e.g. a
+website) after a small change cheap.
+
+Use --progress to report the number of objects fetched so far.
 `,
 	},
 
@@ -350,8 +382,9 @@ new pin and removing the old one.
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("unpin", "Remove the old pin.").Default(true),
+		cmds.BoolOption("progress", "Show progress"),
 	},
-	Type: PinOutput{},
+	Type: UpdatePinOutput{},
 	Run: func(req cmds.Request, res cmds.Response) {
 		n, err := req.InvocContext().GetNode()
 		if err != nil {
@@ -364,6 +397,10 @@ new pin and removing the old one.
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+		showProgress, _, _ := req.Option("progress").Bool()
+		if cmds.EventsRequested(req) {
+			showProgress = true
+		}
 
 		from, err := path.ParsePath(req.Arguments()[0])
 		if err != nil {
@@ -389,26 +426,114 @@ new pin and removing the old one.
 			return
 		}
 
-		err = n.Pinning.Update(req.Context(), fromc, toc, unpin)
-		if err != nil {
-			res.SetError(err, cmds.ErrNormal)
+		update := func(ctx context.Context) error {
+			defer n.Blockstore.PinLock().Unlock()
+
+			return corerepo.Update(n, ctx, fromc, toc, unpin)
+		}
+		pins := []string{from.String(), to.String()}
+
+		if !showProgress {
+			if err := update(req.Context()); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			res.SetOutput(&UpdatePinOutput{Pins: pins})
 			return
 		}
 
-		res.SetOutput(&PinOutput{Pins: []string{from.String(), to.String()}})
+		v := new(dag.ProgressTracker)
+		ctx := v.DeriveContext(req.Context())
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- update(ctx)
+		}()
+		out := make(chan interface{})
+		res.SetOutput((<-chan interface{})(out))
+		go func() {
+			ticker := time.NewTicker(500 * time.Millisecond)
+			defer ticker.Stop()
+			defer close(out)
+			for {
+				select {
+				case err := <-errCh:
+					if err != nil {
+						res.SetError(err, cmds.ErrNormal)
+						return
+					}
+					if pv := v.Value(); pv != 0 {
+						out <- &UpdatePinOutput{Progress: pv}
+					}
+					out <- &UpdatePinOutput{Pins: pins}
+					return
+				case <-ticker.C:
+					out <- &UpdatePinOutput{Progress: v.Value()}
+				case <-ctx.Done():
+					res.SetError(ctx.Err(), cmds.ErrNormal)
+					return
+				}
+			}
+		}()
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
-			added, ok := res.Output().(*PinOutput)
-			if !ok {
+			var updated []string
+
+			switch out := res.Output().(type) {
+			case *UpdatePinOutput:
+				updated = out.Pins
+			case <-chan interface{}:
+				progressLine := false
+				for r0 := range out {
+					r := r0.(*UpdatePinOutput)
+					if r.Pins != nil {
+						updated = r.Pins
+					} else {
+						if progressLine {
+							fmt.Fprintf(res.Stderr(), "\r")
+						}
+						fmt.Fprintf(res.Stderr(), "Fetched/Processed %d nodes", r.Progress)
+						progressLine = true
+					}
+				}
+				if progressLine {
+					fmt.Fprintf(res.Stderr(), "\n")
+				}
+				if res.Error() != nil {
+					return nil, res.Error()
+				}
+			default:
+				return nil, u.ErrCast()
+			}
+
+			if len(updated) != 2 {
 				return nil, u.ErrCast()
 			}
 
 			buf := new(bytes.Buffer)
-			fmt.Fprintf(buf, "updated %s to %s\n", added.Pins[0], added.Pins[1])
+			fmt.Fprintf(buf, "updated %s to %s\n", updated[0], updated[1])
 			return buf, nil
 		},
 	},
+	Events: pinEvent,
+}
+
+// pinEvent converts the output of 'pin add' and 'pin update' to events
+func pinEvent(v interface{}) *cmds.Event {
+	switch out := v.(type) {
+	case *AddPinOutput:
+		if out.Pins == nil {
+			return cmds.ProgressEvent("", int64(out.Progress), 0, "nodes")
+		}
+		return cmds.ResultEvent(out)
+	case *UpdatePinOutput:
+		if out.Pins == nil {
+			return cmds.ProgressEvent("", int64(out.Progress), 0, "nodes")
+		}
+		return cmds.ResultEvent(out)
+	}
+	return nil
 }
 
 var verifyPinCmd = &cmds.Command{
@@ -470,7 +595,8 @@ var verifyPinCmd = &cmds.Command{
 }
 
 type RefKeyObject struct {
-	Type string
+	Type        string
+	Allocations []string `json:",omitempty"`
 }
 
 type RefKeyList struct {
@@ -511,8 +637,14 @@ func pinLsKeys(args []string, typeStr string, ctx context.Context, n *core.IpfsN
 		default:
 			pinType = "indirect through " + pinType
 		}
+		allocations, err := pin.Allocations(n.Repo.Datastore(), c)
+		if err != nil {
+			return nil, err
+		}
+
 		keys[c.String()] = RefKeyObject{
-			Type: pinType,
+			Type:        pinType,
+			Allocations: allocations,
 		}
 	}
 
@@ -531,13 +663,32 @@ func pinLsAll(typeStr string, ctx context.Context, n *core.IpfsNode) (map[string
 		}
 	}
 
+	AddAllocations := func(keyList []*cid.Cid) error {
+		for _, c := range keyList {
+			allocations, err := pin.Allocations(n.Repo.Datastore(), c)
+			if err != nil {
+				return err
+			}
+			if len(allocations) == 0 {
+				continue
+			}
+			obj := keys[c.String()]
+			obj.Allocations = allocations
+			keys[c.String()] = obj
+		}
+		return nil
+	}
+
 	if typeStr == "direct" || typeStr == "all" {
 		AddToResultKeys(n.Pinning.DirectKeys(), "direct")
+		if err := AddAllocations(n.Pinning.DirectKeys()); err != nil {
+			return nil, err
+		}
 	}
 	if typeStr == "indirect" || typeStr == "all" {
 		set := cid.NewSet()
 		for _, k := range n.Pinning.RecursiveKeys() {
-			err := dag.EnumerateChildren(n.Context(), n.DAG.GetLinks, k, set.Visit)
+			err := dag.EnumerateChildren(ctx, n.DAG.GetLinks, k, set.Visit)
 			if err != nil {
 				return nil, err
 			}
@@ -546,6 +697,9 @@ func pinLsAll(typeStr string, ctx context.Context, n *core.IpfsNode) (map[string
 	}
 	if typeStr == "recursive" || typeStr == "all" {
 		AddToResultKeys(n.Pinning.RecursiveKeys(), "recursive")
+		if err := AddAllocations(n.Pinning.RecursiveKeys()); err != nil {
+			return nil, err
+		}
 	}
 
 	return keys, nil
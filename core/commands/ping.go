@@ -24,6 +24,23 @@ type PingResult struct {
 	Success bool
 	Time    time.Duration
 	Text    string
+
+	// Stats is set on the last result, once the pings are done
+	Stats *PingStats `json:",omitempty"`
+}
+
+// PingStats summarizes the pings sent to a peer
+type PingStats struct {
+	Sent     int
+	Received int
+
+	// Loss is the percentage of pings without a pong
+	Loss float64
+
+	// round-trip times of the pongs received
+	Min time.Duration
+	Avg time.Duration
+	Max time.Duration
 }
 
 var PingCmd = &cmds.Command{
@@ -33,6 +50,13 @@ var PingCmd = &cmds.Command{
 'ipfs ping' is a tool to test sending data to other nodes. It finds nodes
 via the routing system, sends pings, waits for pongs, and prints out round-
 trip latency information.
+
+The last result holds the statistics of the pings. With --enc=json, each
+result is printed as a JSON object as it comes. The command exits with an
+error when the peer can't be found or no pong is received, which makes it
+usable in health checks:
+
+  > ipfs ping -n 3 --enc=json <peer ID> >/dev/null || echo unreachable
 		`,
 	},
 	Arguments: []cmds.Argument{
@@ -56,7 +80,13 @@ trip latency information.
 				}
 
 				buf := new(bytes.Buffer)
-				if len(obj.Text) > 0 {
+				if st := obj.Stats; st != nil {
+					fmt.Fprintf(buf, "%d pings sent, %d pongs received, %.0f%% loss\n", st.Sent, st.Received, st.Loss)
+					if st.Received > 0 {
+						fmt.Fprintf(buf, "Round-trip min/avg/max: %.2f/%.2f/%.2f ms\n",
+							st.Min.Seconds()*1000, st.Avg.Seconds()*1000, st.Max.Seconds()*1000)
+					}
+				} else if len(obj.Text) > 0 {
 					buf = bytes.NewBufferString(obj.Text + "\n")
 				} else if obj.Success {
 					fmt.Fprintf(buf, "Pong received: time=%.2f ms\n", obj.Time.Seconds()*1000)
@@ -102,14 +132,20 @@ trip latency information.
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+		if numPings <= 0 {
+			res.SetError(fmt.Errorf("ping count must be positive, got %d", numPings), cmds.ErrClient)
+			return
+		}
 
-		outChan := pingPeer(ctx, n, peerID, numPings)
+		outChan := pingPeer(ctx, n, peerID, numPings, res)
 		res.SetOutput(outChan)
 	},
 	Type: PingResult{},
 }
 
-func pingPeer(ctx context.Context, n *core.IpfsNode, pid peer.ID, numPings int) <-chan interface{} {
+// pingPeer streams the results of the pings to pid. The error of res is set
+// before the channel is closed if the peer is unreachable.
+func pingPeer(ctx context.Context, n *core.IpfsNode, pid peer.ID, numPings int, res cmds.Response) <-chan interface{} {
 	outChan := make(chan interface{})
 	go func() {
 		defer close(outChan)
@@ -125,7 +161,7 @@ func pingPeer(ctx context.Context, n *core.IpfsNode, pid peer.ID, numPings int)
 			defer cancel()
 			p, err := n.Routing.FindPeer(ctx, pid)
 			if err != nil {
-				outChan <- &PingResult{Text: fmt.Sprintf("Peer lookup error: %s", err)}
+				res.SetError(fmt.Errorf("Peer lookup error: %s", err), cmds.ErrNormal)
 				return
 			}
 			n.Peerstore.AddAddrs(p.ID, p.Addrs, pstore.TempAddrTTL)
@@ -141,38 +177,53 @@ func pingPeer(ctx context.Context, n *core.IpfsNode, pid peer.ID, numPings int)
 		pings, err := n.Ping.Ping(ctx, pid)
 		if err != nil {
 			log.Debugf("Ping error: %s", err)
-			outChan <- &PingResult{
-				Success: false,
-				Text:    fmt.Sprintf("Ping error: %s", err),
-			}
+			res.SetError(fmt.Errorf("Ping error: %s", err), cmds.ErrNormal)
 			return
 		}
 
-		var done bool
+		var stats PingStats
 		var total time.Duration
-		for i := 0; i < numPings && !done; i++ {
+		for stats.Sent < numPings {
+			stats.Sent++
+
+			var t time.Duration
+			var ok bool
 			select {
 			case <-ctx.Done():
-				done = true
+			case t, ok = <-pings:
+			}
+			if !ok {
+				// the stream failed or timed out, the ping was lost
 				break
-			case t, ok := <-pings:
-				if !ok {
-					done = true
-					break
-				}
+			}
 
-				outChan <- &PingResult{
-					Success: true,
-					Time:    t,
-				}
-				total += t
-				time.Sleep(time.Second)
+			outChan <- &PingResult{
+				Success: true,
+				Time:    t,
+			}
+			stats.Received++
+			total += t
+			if stats.Min == 0 || t < stats.Min {
+				stats.Min = t
 			}
+			if t > stats.Max {
+				stats.Max = t
+			}
+			time.Sleep(time.Second)
+		}
+
+		stats.Loss = float64(stats.Sent-stats.Received) * 100 / float64(stats.Sent)
+		if stats.Received > 0 {
+			stats.Avg = total / time.Duration(stats.Received)
 		}
-		averagems := total.Seconds() * 1000 / float64(numPings)
 		outChan <- &PingResult{
-			Success: true,
-			Text:    fmt.Sprintf("Average latency: %.2fms", averagems),
+			Success: stats.Received > 0,
+			Text:    fmt.Sprintf("Average latency: %.2fms", stats.Avg.Seconds()*1000),
+			Stats:   &stats,
+		}
+
+		if stats.Received == 0 {
+			res.SetError(fmt.Errorf("no pong received from %s", pid.Pretty()), cmds.ErrNormal)
 		}
 	}()
 	return outChan
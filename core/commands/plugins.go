@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	plugin "github.com/ipfs/go-ipfs/plugin"
+	loader "github.com/ipfs/go-ipfs/plugin/loader"
+
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+)
+
+type PluginOutput struct {
+	Name    string
+	Version string
+	Types   []string
+}
+
+type PluginList struct {
+	APIVersion int
+	Plugins    []PluginOutput
+}
+
+var PluginsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Inspect loaded plugins.",
+		ShortDescription: `
+'ipfs plugins' lists the plugins extending ipfs with datastores, IPLD formats
+and metrics exporters.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"ls": pluginsLsCmd,
+	},
+}
+
+var pluginsLsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List loaded plugins.",
+		ShortDescription: `
+'ipfs plugins ls' lists the loaded plugins, their version, and what they
+provide: datastores, IPLD formats (ipld) or metrics exporters.
+`,
+		LongDescription: `
+'ipfs plugins ls' lists the loaded plugins, their version, and what they
+provide: datastores, IPLD formats (ipld) or metrics exporters.
+
+Plugins are either compiled into ipfs, or built as Go plugins (.so files) and
+placed in the 'plugins' directory of the repo, e.g. ~/.ipfs/plugins. A .so
+plugin must export an 'APIVersion' int, equal to the plugin API version of
+ipfs, and a 'Plugins' []plugin.Plugin.
+
+Loading .so plugins requires ipfs to be built with cgo, on linux, with go 1.8
+or later.
+
+When the daemon is running, the plugins of the daemon are listed.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		out := PluginList{APIVersion: plugin.APIVersion}
+		for _, pl := range loader.Loaded() {
+			out.Plugins = append(out.Plugins, PluginOutput{
+				Name:    pl.Name(),
+				Version: pl.Version(),
+				Types:   loader.Types(pl),
+			})
+		}
+		res.SetOutput(&out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			list, ok := res.Output().(*PluginList)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			w := tabwriter.NewWriter(buf, 1, 2, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tVERSION\tTYPES")
+			for _, p := range list.Plugins {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, p.Version, strings.Join(p.Types, ","))
+			}
+			w.Flush()
+			return buf, nil
+		},
+	},
+	Type: PluginList{},
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
+
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+)
+
+// PowerOutput is the output of 'ipfs power'
+type PowerOutput struct {
+	Mode string
+}
+
+var PowerCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show or change the power mode of the node.",
+		ShortDescription: `
+Without argument, prints the power mode of the daemon: 'normal' or 'low'.
+With one, switches the daemon to that mode.
+
+In low power mode the node stops answering DHT requests, reprovides and
+republishes IPNS records less often, keeps fewer connections and wakes up
+less often for background work. It is meant for nodes running on battery
+or on metered networks, the settings are in the Power config section.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("mode", false, false, "Power mode to switch to: 'normal' or 'low'."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if !n.OnlineMode() {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+
+		if len(req.Arguments()) > 0 {
+			mode, err := core.ParsePowerMode(req.Arguments()[0])
+			if err != nil {
+				res.SetError(err, cmds.ErrClient)
+				return
+			}
+			if err := n.SetPowerMode(mode); err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+		}
+
+		res.SetOutput(&PowerOutput{Mode: string(n.PowerMode())})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*PowerOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+			return strings.NewReader(fmt.Sprintln(out.Mode)), nil
+		},
+	},
+	Type: PowerOutput{},
+}
@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	coreapi "github.com/ipfs/go-ipfs/core/coreapi"
+	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+	dag "github.com/ipfs/go-ipfs/merkledag"
+
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+)
+
+type PrefetchOutput struct {
+	Path     string `json:",omitempty"`
+	Progress int    `json:",omitempty"`
+}
+
+var PrefetchCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Fetch objects into the local blockstore without pinning them.",
+		ShortDescription: `
+Fetches the whole DAG under each given path into the local blockstore, without
+pinning it. The fetched blocks may be removed by the next garbage collection.
+`,
+		LongDescription: `
+Fetches the whole DAG under each given path into the local blockstore, without
+pinning it. The fetched blocks may be removed by the next garbage collection.
+
+This replaces the 'ipfs pin add' followed by 'ipfs pin rm' dance (or
+'ipfs refs -r') to warm up the local blockstore.
+
+Blocks that are already present locally are not fetched again, so an
+interrupted prefetch can be resumed by simply running it again.
+
+Use --concurrency to set how many blocks are fetched in parallel, and
+--progress to report the number of blocks processed so far.
+`,
+	},
+
+	Arguments: []cmds.Argument{
+		cmds.StringArg("ipfs-path", true, true, "Path to object(s) to be fetched.").EnableStdin(),
+	},
+	Options: []cmds.Option{
+		cmds.IntOption("concurrency", "c", "Number of blocks to fetch in parallel.").Default(dag.FetchGraphConcurrency),
+		cmds.BoolOption("progress", "p", "Stream progress data."),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		concurrency, _, err := req.Option("concurrency").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if concurrency <= 0 {
+			res.SetError(fmt.Errorf("concurrency must be positive"), cmds.ErrClient)
+			return
+		}
+
+		showProgress, _, _ := req.Option("progress").Bool()
+
+		var paths []coreiface.Path
+		for _, arg := range req.Arguments() {
+			p, err := coreapi.ParsePath(arg)
+			if err != nil {
+				res.SetError(err, cmds.ErrNormal)
+				return
+			}
+			paths = append(paths, p)
+		}
+
+		api := coreapi.NewCoreAPI(n).Dag()
+
+		out := make(chan interface{})
+		res.SetOutput((<-chan interface{})(out))
+
+		go func() {
+			defer close(out)
+
+			for _, p := range paths {
+				var lk sync.Mutex
+				var last int
+
+				opts := coreiface.PrefetchOptions{Concurrency: concurrency}
+				if showProgress {
+					// only report every so often, not for every block
+					opts.Progress = func(blocks int) {
+						lk.Lock()
+						defer lk.Unlock()
+						if blocks-last < 16 {
+							return
+						}
+						last = blocks
+						select {
+						case out <- &PrefetchOutput{Progress: blocks}:
+						case <-req.Context().Done():
+						}
+					}
+				}
+
+				err := api.Prefetch(req.Context(), p, opts)
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
+
+				select {
+				case out <- &PrefetchOutput{Path: p.String()}:
+				case <-req.Context().Done():
+					return
+				}
+			}
+		}()
+	},
+	Type: PrefetchOutput{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			outChan, ok := res.Output().(<-chan interface{})
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			progressLine := false
+			marshal := func(v interface{}) (io.Reader, error) {
+				obj, ok := v.(*PrefetchOutput)
+				if !ok {
+					return nil, u.ErrCast()
+				}
+
+				if obj.Path == "" {
+					if progressLine {
+						fmt.Fprintf(res.Stderr(), "\r")
+					}
+					fmt.Fprintf(res.Stderr(), "Fetched/Processed %d nodes", obj.Progress)
+					progressLine = true
+					return nil, nil
+				}
+
+				if progressLine {
+					fmt.Fprintf(res.Stderr(), "\n")
+					progressLine = false
+				}
+				return strings.NewReader(fmt.Sprintf("fetched %s\n", obj.Path)), nil
+			}
+
+			return &cmds.ChannelMarshaler{
+				Channel:   outChan,
+				Marshaler: marshal,
+				Res:       res,
+			}, nil
+		},
+	},
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	probes "github.com/ipfs/go-ipfs/core/probes"
+
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+)
+
+var errProbesDisabled = errors.New("the self-probes are disabled, enable them with 'ipfs config --json Probes.Enabled true' and restart the daemon")
+
+// ProbesOutput is the output of 'ipfs diag probes'
+type ProbesOutput struct {
+	Stats   []probes.Stats
+	Results []probes.Result `json:",omitempty"`
+}
+
+var probesDiagCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Print the results of the self-probes.",
+		ShortDescription: `
+'ipfs diag probes' prints the statistics of the self-probes, which
+periodically measure how the rest of the network sees this node:
+
+  provider  the time from providing a block of the pinned content, picked by
+            a random walk, to one of the peers closest to it in the DHT
+            listing this node as its provider
+  ipns      the time for the peers closest to an IPNS name published by this
+            node to return its latest record, without the local records and
+            caches the node resolves its own names with
+
+The probes are enabled with the Probes section of the config, and their
+latencies are exported as metrics. --run runs them once right away, and
+--verbose lists the results of the recent probes. Use '--enc=json' to feed
+the statistics to a monitoring system, durations are then given in
+nanoseconds.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("run", "Run the probes once before printing their results.").Default(false),
+		cmds.BoolOption("verbose", "v", "List the results of the recent probes.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if !n.OnlineMode() {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+		if n.Prober == nil {
+			res.SetError(errProbesDisabled, cmds.ErrClient)
+			return
+		}
+
+		run, _, err := req.Option("run").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		verbose, _, err := req.Option("verbose").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if run {
+			n.Prober.RunOnce(req.Context())
+		}
+
+		out := &ProbesOutput{Stats: n.Prober.Stats()}
+		if verbose {
+			out.Results = n.Prober.Results()
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*ProbesOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
+			fmt.Fprintln(w, "kind\truns\tfailures\tlast\tavg latency\tmax latency\t")
+			for _, s := range out.Stats {
+				fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t\n", s.Kind, s.Runs, s.Failures,
+					roundDuration(s.Last), roundDuration(s.AvgLatency), roundDuration(s.MaxLatency))
+			}
+			if len(out.Results) > 0 {
+				fmt.Fprintln(w)
+				fmt.Fprintln(w, "time\tkind\tkey\tlatency\terror\t")
+				for _, r := range out.Results {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", r.Time.Format("2006-01-02 15:04:05"),
+						r.Kind, r.Key, roundDuration(r.Latency), r.Error)
+				}
+			}
+			w.Flush()
+			return buf, nil
+		},
+	},
+	Type: ProbesOutput{},
+}
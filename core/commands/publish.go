@@ -11,6 +11,9 @@ import (
 	cmds "github.com/ipfs/go-ipfs/commands"
 	core "github.com/ipfs/go-ipfs/core"
 	keystore "github.com/ipfs/go-ipfs/keystore"
+	namesys "github.com/ipfs/go-ipfs/namesys"
+	dnslink "github.com/ipfs/go-ipfs/namesys/dnslink"
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
 	path "github.com/ipfs/go-ipfs/path"
 
 	crypto "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
@@ -54,6 +57,24 @@ Alternatively, publish an <ipfs-path> using a valid PeerID(as listed by 'ipfs ke
  > ipfs name publish --key=QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
   Published to QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n: /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
 
+When the DNSLink section of the config names a domain, publishing with the
+key it follows ('self' by default) also points the _dnslink TXT record of
+the domain at the published path, through the configured DNS provider:
+
+  > ipfs config --json DNSLink '{"Domain": "example.com", "Provider": "cloudflare",
+      "Cloudflare": {"APIToken": "...", "ZoneID": "..."}}'
+  > ipfs name publish --dnslink-dry-run /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+  Published to QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n: /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+  Would update _dnslink.example.com: dnslink=/ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+
+Use --dnslink=false to leave the record untouched.
+
+Publish to a name whose key is kept elsewhere, with a key it delegated to
+(see 'ipfs name delegate'):
+
+  > ipfs name publish --key=deploy --delegation=CiQIARIg... /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+  Published to QmbCMUZw6JFeZ7Wp9jkzbye3Fzp2GGcPgC3nmeUjfVF87n: /ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy
+
 `,
 	},
 
@@ -68,6 +89,9 @@ Alternatively, publish an <ipfs-path> using a valid PeerID(as listed by 'ipfs ke
     "ns", "us" (or "µs"), "ms", "s", "m", "h".`).Default("24h"),
 		cmds.StringOption("ttl", "Time duration this record should be cached for (caution: experimental)."),
 		cmds.StringOption("key", "k", "Name of the key to be used or a valid PeerID, as listed by 'ipfs key list -l'. Default: <<default>>.").Default("self"),
+		cmds.BoolOption("dnslink", "Update the dnslink record configured in DNSLink, if any.").Default(true),
+		cmds.BoolOption("dnslink-dry-run", "Show the dnslink record update without making it."),
+		cmds.StringOption("delegation", "Publish to the name that delegated to --key, with the delegation printed by 'ipfs name delegate'."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		log.Debug("begin publish")
@@ -128,32 +152,88 @@ Alternatively, publish an <ipfs-path> using a valid PeerID(as listed by 'ipfs ke
 			return
 		}
 
+		if del, found, _ := req.Option("delegation").String(); found {
+			popts.delegation, err = parseDelegation(del)
+			if err != nil {
+				res.SetError(err, cmds.ErrClient)
+				return
+			}
+		}
+
 		pth, err := path.ParsePath(pstr)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
+		updater, err := dnslinkUpdater(req, n, kname)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
 		output, err := publish(ctx, n, k, pth, popts)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
+
+		if updater != nil {
+			output.DNSLink, err = updater.Update(ctx, pth)
+			if err != nil {
+				res.SetError(fmt.Errorf("published to %s, but failed to update dnslink: %s", output.Name, err), cmds.ErrNormal)
+				return
+			}
+		}
 		res.SetOutput(output)
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
 			v := res.Output().(*IpnsEntry)
 			s := fmt.Sprintf("Published to %s: %s\n", v.Name, v.Value)
+			if u := v.DNSLink; u != nil {
+				verb := "Updated"
+				if u.DryRun {
+					verb = "Would update"
+				}
+				s += fmt.Sprintf("%s %s: %s\n", verb, u.Record, u.Value)
+			}
 			return strings.NewReader(s), nil
 		},
 	},
 	Type: IpnsEntry{},
 }
 
+// dnslinkUpdater returns the updater of the dnslink record following the
+// key kname, or nil if there is none.
+func dnslinkUpdater(req cmds.Request, n *core.IpfsNode, kname string) (*dnslink.Updater, error) {
+	if update, _, _ := req.Option("dnslink").Bool(); !update {
+		return nil, nil
+	}
+
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	dcfg := cfg.DNSLink
+	if dryRun, _, _ := req.Option("dnslink-dry-run").Bool(); dryRun {
+		dcfg.DryRun = true
+	}
+
+	u, err := dnslink.New(dcfg)
+	if err != nil || u == nil || u.Key != kname {
+		return nil, err
+	}
+	return u, nil
+}
+
 type publishOpts struct {
 	verifyExists bool
 	pubValidTime time.Duration
+
+	// delegation, if set, lets k publish to the name that delegated to it
+	delegation *pb.Delegation
 }
 
 func publish(ctx context.Context, n *core.IpfsNode, k crypto.PrivKey, ref path.Path, opts *publishOpts) (*IpnsEntry, error) {
@@ -167,6 +247,10 @@ func publish(ctx context.Context, n *core.IpfsNode, k crypto.PrivKey, ref path.P
 	}
 
 	eol := time.Now().Add(opts.pubValidTime)
+	if opts.delegation != nil {
+		return publishDelegated(ctx, n, k, ref, eol, opts.delegation)
+	}
+
 	err := n.Namesys.PublishWithEOL(ctx, k, ref, eol)
 	if err != nil {
 		return nil, err
@@ -183,6 +267,26 @@ func publish(ctx context.Context, n *core.IpfsNode, k crypto.PrivKey, ref path.P
 	}, nil
 }
 
+func publishDelegated(ctx context.Context, n *core.IpfsNode, k crypto.PrivKey, ref path.Path, eol time.Time, d *pb.Delegation) (*IpnsEntry, error) {
+	dp, ok := n.Namesys.(namesys.DelegatedPublisher)
+	if !ok {
+		return nil, errors.New("the name system does not support delegations")
+	}
+	if err := dp.PublishDelegated(ctx, k, d, ref, eol); err != nil {
+		return nil, err
+	}
+
+	pid, err := namesys.DelegationName(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IpnsEntry{
+		Name:  pid.Pretty(),
+		Value: ref.String(),
+	}, nil
+}
+
 func keylookup(n *core.IpfsNode, k string) (crypto.PrivKey, error) {
 
 	res, err := n.GetKey(k)
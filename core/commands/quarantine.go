@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+	namesys "github.com/ipfs/go-ipfs/namesys"
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
+
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// RejectedRecord is an IPNS record the node rejected
+type RejectedRecord struct {
+	Name   string
+	Reason string
+	Time   time.Time
+	// Value and Sequence are set when the record could be decoded
+	Value    string `json:",omitempty"`
+	Sequence uint64 `json:",omitempty"`
+	Record   []byte
+}
+
+// RejectedRecordList is the output of 'ipfs name quarantine'
+type RejectedRecordList struct {
+	Records []RejectedRecord
+}
+
+var NameQuarantineCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the IPNS records this node rejected.",
+		ShortDescription: `
+Lists the last IPNS records the node rejected when storing or fetching
+them from the DHT, oldest first: records too large, expired, with an EOL
+too far in the future, older than one already resolved for the same name,
+or malformed. The records themselves are part of the JSON output
+(--enc=json), for inspection.
+
+The number of records rejected for each reason is also exported as the
+ipns.rejected_<reason>_total metrics.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		v := n.IpnsValidator
+		if v == nil {
+			v = namesys.DefaultValidator
+		}
+
+		out := new(RejectedRecordList)
+		for _, r := range v.Rejected() {
+			rr := RejectedRecord{
+				Name:   strings.TrimPrefix(r.Key, "/ipns/"),
+				Reason: r.Reason,
+				Time:   r.Time,
+				Record: r.Record,
+			}
+			if id, err := peer.IDFromBytes([]byte(rr.Name)); err == nil {
+				rr.Name = id.Pretty()
+			} else {
+				rr.Name = fmt.Sprintf("%q", r.Key)
+			}
+
+			e := new(pb.IpnsEntry)
+			if err := proto.Unmarshal(r.Record, e); err == nil {
+				rr.Value = string(e.GetValue())
+				rr.Sequence = e.GetSequence()
+			}
+			out.Records = append(out.Records, rr)
+		}
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			list, ok := res.Output().(*RejectedRecordList)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			w := tabwriter.NewWriter(buf, 1, 2, 1, ' ', 0)
+			for _, r := range list.Records {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t\n", r.Time.Format(time.RFC3339), r.Name, len(r.Record), r.Reason)
+			}
+			w.Flush()
+			return buf, nil
+		},
+	},
+	Type: RejectedRecordList{},
+}
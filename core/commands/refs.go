@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	gopath "path"
 	"strings"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
@@ -42,6 +44,30 @@ with the following format:
   <link base58 hash>
 
 NOTE: List all references recursively by using the flag '-r'.
+`,
+		LongDescription: `
+Lists the hashes of all the links an IPFS or IPNS object(s) contains,
+with the following format:
+
+  <link base58 hash>
+
+NOTE: List all references recursively by using the flag '-r'.
+
+The --max-depth option limits how deep a recursive listing goes, a depth of 1
+lists the direct links only. Blocks below the maximum depth are not fetched.
+When used together with --unique, a ref seen again closer to the root than
+before is still explored to the maximum depth.
+
+The --select option takes a '/' separated selector of link name glob patterns
+(as understood by Go's path.Match). The first pattern is matched against the
+names of the links of the root object, the second one against the links one
+level below, and so on. Links whose names don't match are neither listed nor
+followed, and levels deeper than the selector are listed in full. For example,
+'--select="docs/*.html"' follows only the link named 'docs' and lists the
+html files below it.
+
+Edges can be streamed with the link names by using the <linkname> token of
+--format, e.g. --format="<src> <dst> <linkname>".
 `,
 	},
 	Subcommands: map[string]*cmds.Command{
@@ -55,6 +81,8 @@ NOTE: List all references recursively by using the flag '-r'.
 		cmds.BoolOption("edges", "e", "Emit edge format: `<from> -> <to>`.").Default(false),
 		cmds.BoolOption("unique", "u", "Omit duplicate refs from output.").Default(false),
 		cmds.BoolOption("recursive", "r", "Recursively list links of child nodes.").Default(false),
+		cmds.IntOption("max-depth", "Only for recursive refs, limits fetch and listing to the given depth.").Default(-1),
+		cmds.StringOption("select", "Only list and follow links whose names match the given '/' separated glob patterns."),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 		ctx := req.Context()
@@ -82,6 +110,28 @@ NOTE: List all references recursively by using the flag '-r'.
 			return
 		}
 
+		maxDepth, _, err := req.Option("max-depth").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		if !recursive {
+			maxDepth = 1 // write only direct refs
+		}
+
+		selectStr, _, err := req.Option("select").String()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		selector, err := parseRefsSelector(selectStr)
+		if err != nil {
+			res.SetError(err, cmds.ErrClient)
+			return
+		}
+
 		edges, _, err := req.Option("edges").Bool()
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
@@ -110,12 +160,13 @@ NOTE: List all references recursively by using the flag '-r'.
 			defer close(out)
 
 			rw := RefWriter{
-				out:       out,
-				DAG:       n.DAG,
-				Ctx:       ctx,
-				Unique:    unique,
-				PrintFmt:  format,
-				Recursive: recursive,
+				out:      out,
+				DAG:      n.DAG,
+				Ctx:      ctx,
+				Unique:   unique,
+				PrintFmt: format,
+				MaxDepth: maxDepth,
+				Selector: selector,
 			}
 
 			for _, o := range objs {
@@ -223,41 +274,80 @@ type RefWriter struct {
 	DAG dag.DAGService
 	Ctx context.Context
 
-	Unique    bool
-	Recursive bool
-	PrintFmt  string
+	Unique   bool
+	MaxDepth int
+	PrintFmt string
+
+	// Selector holds the link name patterns to match at each depth,
+	// see parseRefsSelector
+	Selector []string
 
-	seen *cid.Set
+	seen map[string]int
+}
+
+// parseRefsSelector splits a selector into one glob pattern per depth and
+// checks that the patterns are valid
+func parseRefsSelector(s string) ([]string, error) {
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, "/")
+	for _, p := range parts {
+		if _, err := gopath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid selector pattern %q: %s", p, err)
+		}
+	}
+	return parts, nil
 }
 
 // WriteRefs writes refs of the given object to the underlying writer.
 func (rw *RefWriter) WriteRefs(n node.Node) (int, error) {
-	if rw.Recursive {
-		return rw.writeRefsRecursive(n)
-	}
-	return rw.writeRefsSingle(n)
+	return rw.writeRefsRecursive(n, 0)
 }
 
-func (rw *RefWriter) writeRefsRecursive(n node.Node) (int, error) {
+func (rw *RefWriter) writeRefsRecursive(n node.Node, depth int) (int, error) {
 	nc := n.Cid()
 
+	var links []*node.Link
+	for _, l := range n.Links() {
+		if rw.selected(l.Name, depth) {
+			links = append(links, l)
+		}
+	}
+
+	// children at the maximum depth are listed, but not fetched
+	var getters []dag.NodeGetter
+	if rw.MaxDepth < 0 || depth+1 < rw.MaxDepth {
+		cids := make([]*cid.Cid, len(links))
+		for i, l := range links {
+			cids[i] = l.Cid
+		}
+		getters = dag.GetNodes(rw.Ctx, rw.DAG, cids)
+	}
+
 	var count int
-	for i, ng := range dag.GetDAG(rw.Ctx, rw.DAG, n) {
-		lc := n.Links()[i].Cid
-		if rw.skip(lc) {
-			continue
+	for i, l := range links {
+		goDeeper, shouldWrite := rw.visit(l.Cid, depth+1)
+
+		if shouldWrite {
+			if err := rw.WriteEdge(nc, l.Cid, l.Name); err != nil {
+				return count, err
+			}
+			count++
 		}
 
-		if err := rw.WriteEdge(nc, lc, n.Links()[i].Name); err != nil {
-			return count, err
+		if !goDeeper {
+			continue
 		}
 
-		nd, err := ng.Get(rw.Ctx)
+		nd, err := getters[i].Get(rw.Ctx)
 		if err != nil {
 			return count, err
 		}
 
-		c, err := rw.writeRefsRecursive(nd)
+		c, err := rw.writeRefsRecursive(nd, depth+1)
 		count += c
 		if err != nil {
 			return count, err
@@ -266,43 +356,72 @@ func (rw *RefWriter) writeRefsRecursive(n node.Node) (int, error) {
 	return count, nil
 }
 
-func (rw *RefWriter) writeRefsSingle(n node.Node) (int, error) {
-	c := n.Cid()
-
-	if rw.skip(c) {
-		return 0, nil
+// selected returns whether a link of a node at the given depth matches the
+// selector
+func (rw *RefWriter) selected(name string, depth int) bool {
+	if depth >= len(rw.Selector) {
+		return true
 	}
 
-	count := 0
-	for _, l := range n.Links() {
-		lc := l.Cid
-		if rw.skip(lc) {
-			continue
-		}
+	ok, _ := gopath.Match(rw.Selector[depth], name)
+	return ok
+}
 
-		if err := rw.WriteEdge(c, lc, l.Name); err != nil {
-			return count, err
-		}
-		count++
+// visit returns two values:
+// - the first boolean is true if we should keep traversing the DAG
+// - the second boolean is true if we should print the CID
+//
+// visit will do branch pruning depending on rw.MaxDepth, previously visited
+// cids and whether rw.Unique is set. i.e. rw.Unique = false and
+// rw.MaxDepth = -1 disables any pruning. But setting rw.Unique to true will
+// prune already visited branches at the cost of keeping as set of visited
+// CIDs in memory.
+func (rw *RefWriter) visit(c *cid.Cid, depth int) (bool, bool) {
+	atMaxDepth := rw.MaxDepth >= 0 && depth == rw.MaxDepth
+	overMaxDepth := rw.MaxDepth >= 0 && depth > rw.MaxDepth
+
+	// Shortcut when we are over max depth. In practice, this
+	// only applies when calling refs with --maxDepth=0, as root's
+	// children are already over max depth. Otherwise nothing should
+	// hit this.
+	if overMaxDepth {
+		return false, false
 	}
-	return count, nil
-}
 
-// skip returns whether to skip a cid
-func (rw *RefWriter) skip(c *cid.Cid) bool {
+	// We can shortcut right away if we don't need unique output:
+	//   - we keep traversing when not at max depth
+	//   - always print
 	if !rw.Unique {
-		return false
+		return !atMaxDepth, true
 	}
 
+	// Unique == true from this point.
+	// Thus, we keep track of seen Cids, and their depth.
 	if rw.seen == nil {
-		rw.seen = cid.NewSet()
+		rw.seen = make(map[string]int)
 	}
-
-	has := rw.seen.Has(c)
-	if !has {
-		rw.seen.Add(c)
+	key := c.KeyString()
+	oldDepth, ok := rw.seen[key]
+
+	// Unique == true && depth < MaxDepth (or unlimited) from this point
+
+	// Branch pruning cases:
+	// - We saw the Cid before and either:
+	//   - Depth is unlimited (MaxDepth = -1)
+	//   - We saw it higher (smaller depth) in the DAG (means we must have
+	//     explored deep enough before)
+	// Because we saw the CID, we don't print it again.
+	if ok && (rw.MaxDepth < 0 || oldDepth <= depth) {
+		return false, false
 	}
-	return has
+
+	// Final case, we must keep exploring the DAG from this CID
+	// (unless we hit the depth limit).
+	// We note down its depth because it was either not seen
+	// or is lower than last time.
+	// We print if it was not seen.
+	rw.seen[key] = depth
+	return !atMaxDepth, !ok
 }
 
 // Write one edge
@@ -16,6 +16,7 @@ import (
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 	lockfile "github.com/ipfs/go-ipfs/repo/fsrepo/lock"
 
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 )
@@ -35,6 +36,7 @@ var RepoCmd = &cmds.Command{
 	Subcommands: map[string]*cmds.Command{
 		"gc":      repoGcCmd,
 		"stat":    repoStatCmd,
+		"du":      repoDuCmd,
 		"fsck":    RepoFsckCmd,
 		"version": repoVersionCmd,
 		"verify":  repoVerifyCmd,
@@ -68,6 +70,10 @@ order to reclaim hard disk space.
 		}
 
 		streamErrors, _, _ := res.Request().Option("stream-errors").Bool()
+		if cmds.EventsRequested(req) {
+			// errors are reported as warning events
+			streamErrors = true
+		}
 
 		gcOutChan := corerepo.GarbageCollectAsync(n, req.Context())
 
@@ -137,6 +143,16 @@ order to reclaim hard disk space.
 			}, nil
 		},
 	},
+	Events: func(v interface{}) *cmds.Event {
+		out, ok := v.(*GcResult)
+		if !ok {
+			return nil
+		}
+		if out.Error != "" {
+			return cmds.WarningEvent(out.Error)
+		}
+		return cmds.ResultEvent(out)
+	},
 }
 
 var repoStatCmd = &cmds.Command{
@@ -206,6 +222,103 @@ Version         string The repo version.
 	},
 }
 
+var repoDuCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show what is using the disk space of the repo.",
+		ShortDescription: `
+'ipfs repo du' attributes the size of the blocks in the repo to what keeps
+them around: pins, the pin index, the MFS root ('ipfs files'), or nothing,
+in which case the next 'ipfs repo gc' removes them. Each block is counted in
+the first of these it belongs to.
+
+It also lists the largest pins, by the size of the blocks under them. The
+unique size of a pin only counts the blocks no other pin references, which
+is roughly the space removing the pin would free.
+
+Finally, it lists the size of the datastore by namespace.
+
+'ipfs repo du' reads every block in the repo, so it can take a while on
+large repos. Nothing is fetched from the network.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.IntOption("top", "n", "Number of pins to list, 0 for all.").Default(10),
+		cmds.BoolOption("human", "Print sizes in human readable form.").Default(false),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		top, _, err := req.Option("top").Int()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		du, err := corerepo.RepoDiskUsage(n, req.Context(), top)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		res.SetOutput(du)
+	},
+	Type: corerepo.DiskUsage{},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			du, ok := res.Output().(*corerepo.DiskUsage)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			human, _, err := res.Request().Option("human").Bool()
+			if err != nil {
+				return nil, err
+			}
+			size := func(s uint64) string {
+				if human {
+					return humanize.Bytes(s)
+				}
+				return fmt.Sprint(s)
+			}
+
+			buf := new(bytes.Buffer)
+			wtr := tabwriter.NewWriter(buf, 0, 0, 1, ' ', 0)
+			fmt.Fprintf(wtr, "Blocks:\t%s\n", size(du.Blocks))
+			fmt.Fprintf(wtr, "  Pinned:\t%s\n", size(du.Pinned))
+			fmt.Fprintf(wtr, "  PinIndex:\t%s\n", size(du.PinIndex))
+			fmt.Fprintf(wtr, "  MFS:\t%s\n", size(du.MFS))
+			fmt.Fprintf(wtr, "  Unpinned:\t%s\n", size(du.Unpinned))
+			wtr.Flush()
+
+			if len(du.Pins) > 0 {
+				fmt.Fprintln(buf)
+				wtr = tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(wtr, "Pin\tType\tSize\tUnique")
+				for _, p := range du.Pins {
+					fmt.Fprintf(wtr, "%s\t%s\t%s\t%s\n", p.Cid, p.Type, size(p.Size), size(p.Unique))
+				}
+				wtr.Flush()
+			}
+
+			if len(du.Namespaces) > 0 {
+				fmt.Fprintln(buf)
+				wtr = tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(wtr, "Namespace\tKeys\tSize")
+				for _, ns := range du.Namespaces {
+					fmt.Fprintf(wtr, "%s\t%d\t%s\n", ns.Namespace, ns.Keys, size(ns.Size))
+				}
+				wtr.Flush()
+			}
+
+			return buf, nil
+		},
+	},
+}
+
 var RepoFsckCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "Remove repo lockfiles.",
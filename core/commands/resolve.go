@@ -5,7 +5,7 @@ import (
 	"strings"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
-	"github.com/ipfs/go-ipfs/core"
+	coreapi "github.com/ipfs/go-ipfs/core/coreapi"
 	ns "github.com/ipfs/go-ipfs/namesys"
 	path "github.com/ipfs/go-ipfs/path"
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
@@ -52,6 +52,12 @@ Resolve the value of an IPFS DAG path:
   $ ipfs resolve /ipfs/QmeZy1fGbwgVSrqbfh9fKQrAWgeyRnj7h8fsHS1oy3k99x/beep/boop
   /ipfs/QmYRMjyvAiHKN9UTi8Bzt1HUspmSRD8T8DwxfSMzLgBon1
 
+Paths can go through the fields of dag-cbor objects. A path ending at a value
+inside an object resolves to the object and the path of the value in it:
+
+  $ ipfs resolve /ipfs/zdpuAsXfkHapxohc8LtsCzYiAsy84ESqKRD8eWuY64tt9r2CE/sub/beep/0
+  /ipfs/zdpuAsXfkHapxohc8LtsCzYiAsy84ESqKRD8eWuY64tt9r2CE/sub/beep/0
+
 `,
 	},
 
@@ -93,21 +99,30 @@ Resolve the value of an IPFS DAG path:
 		}
 
 		// else, ipfs path or ipns with recursive flag
-		p, err := path.ParsePath(name)
+		p, err := coreapi.ParsePath(name)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
-		node, err := core.Resolve(req.Context(), n.Namesys, n.Resolver, p)
+		node, rest, err := coreapi.NewCoreAPI(n).ResolveToLastNode(req.Context(), p)
 		if err != nil {
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
 
 		c := node.Cid()
+		if len(rest) == 0 {
+			res.SetOutput(&ResolvedPath{path.FromCid(c)})
+			return
+		}
 
-		res.SetOutput(&ResolvedPath{path.FromCid(c)})
+		rp, err := path.FromSegments("/ipfs/", append([]string{c.String()}, rest...)...)
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		res.SetOutput(&ResolvedPath{rp})
 	},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
@@ -45,6 +45,7 @@ ADVANCED COMMANDS
   key           Create and list IPNS name keypairs
   dns           Resolve DNS links
   pin           Pin objects to local storage
+  prefetch      Fetch objects to local storage without pinning
   repo          Manipulate the IPFS repository
   stats         Various operational stats
   filestore     Manage the filestore (experimental)
@@ -56,9 +57,13 @@ NETWORK COMMANDS
   dht           Query the DHT for values or peers
   ping          Measure the latency of a connection
   diag          Print diagnostics
+  power         Show or change the power mode
 
 TOOL COMMANDS
+  cid           Convert and discover properties of CIDs
   config        Manage configuration
+  features      List optional features and whether they are enabled
+  plugins       List loaded plugins
   version       Show ipfs version information
   update        Download and apply go-ipfs updates
   commands      List all available commands
@@ -96,12 +101,14 @@ var rootSubcommands = map[string]*cmds.Command{
 	"block":     BlockCmd,
 	"bootstrap": BootstrapCmd,
 	"cat":       CatCmd,
+	"cid":       CidCmd,
 	"commands":  CommandsDaemonCmd,
 	"config":    ConfigCmd,
 	"dag":       dag.DagCmd,
 	"dht":       DhtCmd,
 	"diag":      DiagCmd,
 	"dns":       DNSCmd,
+	"features":  FeaturesCmd,
 	"files":     files.FilesCmd,
 	"get":       GetCmd,
 	"id":        IDCmd,
@@ -113,6 +120,9 @@ var rootSubcommands = map[string]*cmds.Command{
 	"object":    ocmd.ObjectCmd,
 	"pin":       PinCmd,
 	"ping":      PingCmd,
+	"power":     PowerCmd,
+	"plugins":   PluginsCmd,
+	"prefetch":  PrefetchCmd,
 	"pubsub":    PubsubCmd,
 	"refs":      RefsCmd,
 	"repo":      RepoCmd,
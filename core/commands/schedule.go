@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+)
+
+// ScheduleStat is the output of 'ipfs stats schedule'
+type ScheduleStat struct {
+	Rule               string
+	MaxPeerUploadRate  uint64
+	MaxConnections     int
+	ReproviderInterval time.Duration
+}
+
+var statScheduleCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Print the schedule rule in effect.",
+		ShortDescription: `
+'ipfs stats schedule' prints the rule of the 'Schedule' config in effect and
+the limits the daemon applies: the upload rate per peer, the number of
+connections and the reprovide interval. The schedule is checked every
+minute, changes made with 'ipfs config' apply within a minute.
+`,
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if !n.OnlineMode() {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+
+		st := n.ScheduleStatus()
+		res.SetOutput(&ScheduleStat{
+			Rule:               st.Rule,
+			MaxPeerUploadRate:  st.MaxPeerUploadRate,
+			MaxConnections:     st.MaxConnections,
+			ReproviderInterval: st.ReproviderInterval,
+		})
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*ScheduleStat)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			rule, rate, conns, reprovide := out.Rule, "unlimited", "unlimited", "disabled"
+			if rule == "" {
+				rule = "none"
+			}
+			if out.MaxPeerUploadRate > 0 {
+				rate = humanize.Bytes(out.MaxPeerUploadRate) + "/s"
+			}
+			if out.MaxConnections > 0 {
+				conns = fmt.Sprint(out.MaxConnections)
+			}
+			if out.ReproviderInterval > 0 {
+				reprovide = out.ReproviderInterval.String()
+			}
+
+			buf := new(bytes.Buffer)
+			fmt.Fprintf(buf, "Rule: %s\n", rule)
+			fmt.Fprintf(buf, "MaxPeerUploadRate: %s\n", rate)
+			fmt.Fprintf(buf, "MaxConnections: %s\n", conns)
+			fmt.Fprintf(buf, "ReproviderInterval: %s\n", reprovide)
+			return buf, nil
+		},
+	},
+	Type: ScheduleStat{},
+}
@@ -10,6 +10,7 @@ import (
 	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
+	bwhistory "github.com/ipfs/go-ipfs/core/bwhistory"
 	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
 	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
@@ -27,9 +28,11 @@ for your IPFS node.`,
 	},
 
 	Subcommands: map[string]*cmds.Command{
-		"bw":      statBwCmd,
-		"repo":    repoStatCmd,
-		"bitswap": bitswapStatCmd,
+		"bw":       statBwCmd,
+		"repo":     repoStatCmd,
+		"bitswap":  bitswapStatCmd,
+		"dht":      statDhtCmd,
+		"schedule": statScheduleCmd,
 	},
 }
 
@@ -44,9 +47,9 @@ It displays: TotalIn, TotalOut, RateIn, RateOut.
 
 By default, overall bandwidth and all protocols are shown. To limit bandwidth
 to a particular peer, use the 'peer' option along with that peer's multihash
-id. To specify a specific protocol, use the 'proto' option. The 'peer' and
-'proto' options cannot be specified simultaneously. The protocols that are
-queried using this method are outlined in the specification:
+id. To specify a specific protocol, use the 'proto' option. Both options can
+be used together to show the bandwidth of one protocol with one peer. The
+protocols that are queried using this method are outlined in the specification:
 https://github.com/libp2p/specs/blob/master/7-properties.md#757-protocol-multicodecs
 
 Example protocol options:
@@ -68,12 +71,19 @@ Example:
     TotalOut: 12MB
     RateIn: 0B/s
     RateOut: 0B/s
+
+The daemon keeps a history of bandwidth samples, see the
+Swarm.BandwidthHistory config section for how much and how often. Use the
+'history' option to print it, oldest sample first. Combined with 'poll', new
+samples are printed as they are taken. Use '--enc=json' to get a stream of
+JSON objects, one per sample, suitable for dashboards.
 `,
 	},
 	Options: []cmds.Option{
 		cmds.StringOption("peer", "p", "Specify a peer to print bandwidth for."),
 		cmds.StringOption("proto", "t", "Specify a protocol to print bandwidth for."),
 		cmds.BoolOption("poll", "Print bandwidth at an interval.").Default(false),
+		cmds.BoolOption("history", "Print the retained bandwidth history.").Default(false),
 		cmds.StringOption("interval", "i", `Time interval to wait between updating output, if 'poll' is true.
 
     This accepts durations such as "300s", "1.5h" or "2h45m". Valid time units are:
@@ -109,11 +119,6 @@ Example:
 			res.SetError(err, cmds.ErrNormal)
 			return
 		}
-		if pfound && tfound {
-			res.SetError(errors.New("please only specify peer OR protocol"), cmds.ErrClient)
-			return
-		}
-
 		var pid peer.ID
 		if pfound {
 			checkpid, err := peer.IDB58Decode(pstr)
@@ -141,22 +146,72 @@ Example:
 			return
 		}
 
+		history, _, err := req.Option("history").Bool()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+
+		filter := bwhistory.Filter{Peer: pid, Protocol: protocol.ID(tstr)}
+		bwh, hasHistory := nd.Reporter.(*bwhistory.Reporter)
+		if (history || (pfound && tfound)) && !hasHistory {
+			res.SetError(errors.New("bandwidth history is not available"), cmds.ErrNormal)
+			return
+		}
+
 		out := make(chan interface{})
 		res.SetOutput((<-chan interface{})(out))
 
+		if history {
+			go func() {
+				defer close(out)
+				var last time.Time
+				for {
+					for _, p := range bwh.History(filter) {
+						if !p.Time.After(last) {
+							continue
+						}
+						last = p.Time
+						p := p
+						select {
+						case out <- &p:
+						case <-req.Context().Done():
+							return
+						}
+					}
+					if !doPoll {
+						return
+					}
+					select {
+					case <-time.After(bwh.Interval()):
+					case <-req.Context().Done():
+						return
+					}
+				}
+			}()
+			return
+		}
+
 		go func() {
 			defer close(out)
 			for {
-				if pfound {
-					stats := nd.Reporter.GetBandwidthForPeer(pid)
-					out <- &stats
-				} else if tfound {
-					protoId := protocol.ID(tstr)
-					stats := nd.Reporter.GetBandwidthForProtocol(protoId)
-					out <- &stats
-				} else {
-					totals := nd.Reporter.GetBandwidthTotals()
-					out <- &totals
+				var point bwhistory.Point
+				switch {
+				case pfound && tfound:
+					point = bwh.Current(filter)
+				case pfound:
+					point.Stats = nd.Reporter.GetBandwidthForPeer(pid)
+				case tfound:
+					point.Stats = nd.Reporter.GetBandwidthForProtocol(filter.Protocol)
+				default:
+					point.Stats = nd.Reporter.GetBandwidthTotals()
+				}
+				point.Time = time.Now()
+
+				select {
+				case out <- &point:
+				case <-req.Context().Done():
+					return
 				}
 				if !doPoll {
 					return
@@ -169,7 +224,7 @@ Example:
 			}
 		}()
 	},
-	Type: metrics.Stats{},
+	Type: bwhistory.Point{},
 	Marshalers: cmds.MarshalerMap{
 		cmds.Text: func(res cmds.Response) (io.Reader, error) {
 			outCh, ok := res.Output().(<-chan interface{})
@@ -182,14 +237,30 @@ Example:
 				return nil, err
 			}
 
+			history, _, err := res.Request().Option("history").Bool()
+			if err != nil {
+				return nil, err
+			}
+
 			first := true
 			marshal := func(v interface{}) (io.Reader, error) {
-				bs, ok := v.(*metrics.Stats)
+				p, ok := v.(*bwhistory.Point)
 				if !ok {
 					return nil, u.ErrCast()
 				}
+				bs := &p.Stats
 				out := new(bytes.Buffer)
-				if !polling {
+				if history {
+					if first {
+						fmt.Fprintln(out, "Time                 Total Up    Total Down  Rate Up     Rate Down")
+						first = false
+					}
+					fmt.Fprintf(out, "%s  ", p.Time.Format("2006-01-02 15:04:05"))
+					fmt.Fprintf(out, "%8s    ", humanize.Bytes(uint64(bs.TotalOut)))
+					fmt.Fprintf(out, "%8s    ", humanize.Bytes(uint64(bs.TotalIn)))
+					fmt.Fprintf(out, "%8s/s  ", humanize.Bytes(uint64(bs.RateOut)))
+					fmt.Fprintf(out, "%8s/s\n", humanize.Bytes(uint64(bs.RateIn)))
+				} else if !polling {
 					printStats(out, bs)
 				} else {
 					if first {
@@ -7,8 +7,11 @@ import (
 	"io"
 	"path"
 	"sort"
+	"strings"
+	"time"
 
 	cmds "github.com/ipfs/go-ipfs/commands"
+	core "github.com/ipfs/go-ipfs/core"
 	repo "github.com/ipfs/go-ipfs/repo"
 	config "github.com/ipfs/go-ipfs/repo/config"
 	"github.com/ipfs/go-ipfs/repo/fsrepo"
@@ -39,6 +42,7 @@ ipfs peers in the internet.
 	},
 	Subcommands: map[string]*cmds.Command{
 		"addrs":      swarmAddrsCmd,
+		"audit":      swarmAuditCmd,
 		"connect":    swarmConnectCmd,
 		"disconnect": swarmDisconnectCmd,
 		"filters":    swarmFiltersCmd,
@@ -51,12 +55,28 @@ var swarmPeersCmd = &cmds.Command{
 		Tagline: "List peers with open connections.",
 		ShortDescription: `
 'ipfs swarm peers' lists the set of peers this node is connected to.
+`,
+		LongDescription: `
+'ipfs swarm peers' lists the set of peers this node is connected to.
+
+The transport of each connection is always reported. More information about
+each connection can be requested:
+
+  --streams     the number of open streams, per protocol
+  --latency     the measured latency to the peer
+  --direction   whether the connection is inbound or outbound, and its age
+  --verbose     all of the above
+
+The direction is recorded when the node dials. It is unknown for relayed
+connections, whichever side opened them. Use '--enc=json' to get the
+connections in a machine readable form.
 `,
 	},
 	Options: []cmds.Option{
 		cmds.BoolOption("verbose", "v", "display all extra information"),
 		cmds.BoolOption("streams", "Also list information about open streams for each peer"),
 		cmds.BoolOption("latency", "Also list information about latency to each peer"),
+		cmds.BoolOption("direction", "Also list the direction and age of each connection"),
 	},
 	Run: func(req cmds.Request, res cmds.Response) {
 
@@ -75,6 +95,7 @@ var swarmPeersCmd = &cmds.Command{
 		verbose, _, _ := req.Option("verbose").Bool()
 		latency, _, _ := req.Option("latency").Bool()
 		streams, _, _ := req.Option("streams").Bool()
+		direction, _, _ := req.Option("direction").Bool()
 
 		conns := n.PeerHost.Network().Conns()
 
@@ -84,8 +105,9 @@ var swarmPeersCmd = &cmds.Command{
 			addr := c.RemoteMultiaddr()
 
 			ci := connInfo{
-				Addr: addr.String(),
-				Peer: pid.Pretty(),
+				Addr:      addr.String(),
+				Peer:      pid.Pretty(),
+				Transport: transportName(addr),
 			}
 
 			swcon, ok := c.(*swarm.Conn)
@@ -101,6 +123,13 @@ var swarmPeersCmd = &cmds.Command{
 					ci.Latency = lat.String()
 				}
 			}
+			if (verbose || direction) && n.ConnTracker != nil {
+				info := n.ConnTracker.Info(c)
+				ci.Direction = info.Direction.String()
+				if !info.Opened.IsZero() {
+					ci.Age = (time.Since(info.Opened) / time.Second * time.Second).String()
+				}
+			}
 			if verbose || streams {
 				strs, err := c.GetStreams()
 				if err != nil {
@@ -108,8 +137,12 @@ var swarmPeersCmd = &cmds.Command{
 					return
 				}
 
+				counts := make(map[string]int)
 				for _, s := range strs {
-					ci.Streams = append(ci.Streams, streamInfo{Protocol: string(s.Protocol())})
+					counts[string(s.Protocol())]++
+				}
+				for proto, count := range counts {
+					ci.Streams = append(ci.Streams, streamInfo{Protocol: proto, Count: count})
 				}
 			}
 			sort.Sort(&ci)
@@ -132,6 +165,12 @@ var swarmPeersCmd = &cmds.Command{
 				if info.Latency != "" {
 					fmt.Fprintf(buf, " %s", info.Latency)
 				}
+				if info.Direction != "" {
+					fmt.Fprintf(buf, " %s", info.Direction)
+				}
+				if info.Age != "" {
+					fmt.Fprintf(buf, " %s", info.Age)
+				}
 				fmt.Fprintln(buf)
 
 				for _, s := range info.Streams {
@@ -139,7 +178,7 @@ var swarmPeersCmd = &cmds.Command{
 						s.Protocol = "<no protocol name>"
 					}
 
-					fmt.Fprintf(buf, "  %s\n", s.Protocol)
+					fmt.Fprintf(buf, "  %s %d\n", s.Protocol, s.Count)
 				}
 			}
 
@@ -151,14 +190,32 @@ var swarmPeersCmd = &cmds.Command{
 
 type streamInfo struct {
 	Protocol string
+	Count    int // number of open streams using the protocol
 }
 
 type connInfo struct {
-	Addr    string
-	Peer    string
-	Latency string
-	Muxer   string
-	Streams []streamInfo
+	Addr      string
+	Peer      string
+	Latency   string
+	Muxer     string
+	Transport string
+	Direction string `json:",omitempty"`
+	Age       string `json:",omitempty"`
+	Streams   []streamInfo
+}
+
+// transportName returns the transport part of a connection address, e.g.
+// "tcp" or "udp/utp"
+func transportName(a ma.Multiaddr) string {
+	var names []string
+	for _, p := range a.Protocols() {
+		switch p.Code {
+		case ma.P_IP4, ma.P_IP6, ma.P_IPFS:
+			continue
+		}
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, "/")
 }
 
 func (ci *connInfo) Less(i, j int) bool {
@@ -326,7 +383,7 @@ ipfs swarm connect /ip4/104.131.131.82/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3
 			return
 		}
 
-		snet, ok := n.PeerHost.Network().(*swarm.Network)
+		snet, ok := core.SwarmNetwork(n.PeerHost.Network())
 		if !ok {
 			res.SetError(fmt.Errorf("peerhost network was not swarm"), cmds.ErrNormal)
 			return
@@ -511,7 +568,7 @@ Filters default to those specified under the "Swarm.AddrFilters" config key.
 			return
 		}
 
-		snet, ok := n.PeerHost.Network().(*swarm.Network)
+		snet, ok := core.SwarmNetwork(n.PeerHost.Network())
 		if !ok {
 			res.SetError(errors.New("failed to cast network to swarm network"), cmds.ErrNormal)
 			return
@@ -558,7 +615,7 @@ add your filters to the ipfs config file.
 			return
 		}
 
-		snet, ok := n.PeerHost.Network().(*swarm.Network)
+		snet, ok := core.SwarmNetwork(n.PeerHost.Network())
 		if !ok {
 			res.SetError(errors.New("failed to cast network to swarm network"), cmds.ErrNormal)
 			return
@@ -630,7 +687,7 @@ remove your filters from the ipfs config file.
 			return
 		}
 
-		snet, ok := n.PeerHost.Network().(*swarm.Network)
+		snet, ok := core.SwarmNetwork(n.PeerHost.Network())
 		if !ok {
 			res.SetError(errors.New("failed to cast network to swarm network"), cmds.ErrNormal)
 			return
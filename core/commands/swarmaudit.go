@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"text/tabwriter"
+
+	cmds "github.com/ipfs/go-ipfs/commands"
+
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+)
+
+// unknownValue is counted for the peers which didn't announce a value
+const unknownValue = "unknown"
+
+// AuditCount is the number of sampled peers sharing a value
+type AuditCount struct {
+	Value string
+	Peers int
+}
+
+// SwarmAuditOutput is the output of 'ipfs swarm audit'
+type SwarmAuditOutput struct {
+	Connected        int
+	Sampled          int
+	Agents           []AuditCount
+	ProtocolVersions []AuditCount
+	Transports       []AuditCount
+	Protocols        []AuditCount
+}
+
+var swarmAuditCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Summarize the agents, transports and protocols of connected peers.",
+		ShortDescription: `
+'ipfs swarm audit' samples the connected peers and counts, among them, the
+peers announcing each agent and protocol version, connected over each
+transport, and having open streams for each protocol. Only aggregates are
+reported, not the peers themselves.
+`,
+		LongDescription: `
+'ipfs swarm audit' samples the connected peers and counts, among them, the
+peers announcing each agent and protocol version, connected over each
+transport, and having open streams for each protocol. Only aggregates are
+reported, not the peers themselves.
+
+All the connected peers are sampled by default. Use --sample to pick a
+random subset on nodes with many connections. Versions are those announced
+by the identify protocol, peers that didn't complete it are counted as
+"unknown". Use '--enc=json' to export the census.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.IntOption("sample", "n", "Number of peers to sample, 0 for all.").Default(0),
+	},
+	Run: func(req cmds.Request, res cmds.Response) {
+		n, err := req.InvocContext().GetNode()
+		if err != nil {
+			res.SetError(err, cmds.ErrNormal)
+			return
+		}
+		if n.PeerHost == nil {
+			res.SetError(errNotOnline, cmds.ErrClient)
+			return
+		}
+
+		sample, _, _ := req.Option("sample").Int()
+		if sample < 0 {
+			res.SetError(fmt.Errorf("sample size must not be negative"), cmds.ErrClient)
+			return
+		}
+
+		conns := n.PeerHost.Network().Conns()
+		out := &SwarmAuditOutput{}
+
+		// a peer may have several connections, count it once
+		var peers []string
+		byPeer := make(map[string][]int)
+		for i, c := range conns {
+			p := c.RemotePeer().Pretty()
+			if _, ok := byPeer[p]; !ok {
+				peers = append(peers, p)
+			}
+			byPeer[p] = append(byPeer[p], i)
+		}
+		out.Connected = len(peers)
+		if sample > 0 && sample < len(peers) {
+			picked := make([]string, sample)
+			for i, j := range rand.Perm(len(peers))[:sample] {
+				picked[i] = peers[j]
+			}
+			peers = picked
+		}
+		out.Sampled = len(peers)
+
+		agents := make(map[string]int)
+		versions := make(map[string]int)
+		transports := make(map[string]int)
+		protocols := make(map[string]int)
+		for _, p := range peers {
+			pid := conns[byPeer[p][0]].RemotePeer()
+			agents[peerstoreString(n.Peerstore.Get(pid, "AgentVersion"))]++
+			versions[peerstoreString(n.Peerstore.Get(pid, "ProtocolVersion"))]++
+
+			seenTpt := make(map[string]bool)
+			seenProto := make(map[string]bool)
+			for _, i := range byPeer[p] {
+				c := conns[i]
+				seenTpt[transportName(c.RemoteMultiaddr())] = true
+
+				strs, err := c.GetStreams()
+				if err != nil {
+					res.SetError(err, cmds.ErrNormal)
+					return
+				}
+				for _, s := range strs {
+					if proto := string(s.Protocol()); proto != "" {
+						seenProto[proto] = true
+					}
+				}
+			}
+			for t := range seenTpt {
+				transports[t]++
+			}
+			for proto := range seenProto {
+				protocols[proto]++
+			}
+		}
+
+		out.Agents = auditCounts(agents)
+		out.ProtocolVersions = auditCounts(versions)
+		out.Transports = auditCounts(transports)
+		out.Protocols = auditCounts(protocols)
+		res.SetOutput(out)
+	},
+	Marshalers: cmds.MarshalerMap{
+		cmds.Text: func(res cmds.Response) (io.Reader, error) {
+			out, ok := res.Output().(*SwarmAuditOutput)
+			if !ok {
+				return nil, u.ErrCast()
+			}
+
+			buf := new(bytes.Buffer)
+			fmt.Fprintf(buf, "Sampled %d of %d connected peers\n", out.Sampled, out.Connected)
+			w := tabwriter.NewWriter(buf, 1, 2, 2, ' ', 0)
+			for _, sec := range []struct {
+				title  string
+				counts []AuditCount
+			}{
+				{"Agents", out.Agents},
+				{"Protocol versions", out.ProtocolVersions},
+				{"Transports", out.Transports},
+				{"Protocols", out.Protocols},
+			} {
+				fmt.Fprintf(w, "\n%s:\n", sec.title)
+				for _, c := range sec.counts {
+					fmt.Fprintf(w, "  %d\t%s\n", c.Peers, c.Value)
+				}
+			}
+			w.Flush()
+			return buf, nil
+		},
+	},
+	Type: SwarmAuditOutput{},
+}
+
+// peerstoreString returns the string value of a peerstore lookup
+func peerstoreString(v interface{}, err error) string {
+	if s, ok := v.(string); ok && err == nil && s != "" {
+		return s
+	}
+	return unknownValue
+}
+
+type byPeers []AuditCount
+
+func (c byPeers) Len() int      { return len(c) }
+func (c byPeers) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byPeers) Less(i, j int) bool {
+	if c[i].Peers != c[j].Peers {
+		return c[i].Peers > c[j].Peers
+	}
+	return c[i].Value < c[j].Value
+}
+
+// auditCounts returns the counts of m, the most common values first
+func auditCounts(m map[string]int) []AuditCount {
+	out := make([]AuditCount, 0, len(m))
+	for v, n := range m {
+		out = append(out, AuditCount{Value: v, Peers: n})
+	}
+	sort.Sort(byPeers(out))
+	return out
+}
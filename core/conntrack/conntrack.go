@@ -0,0 +1,184 @@
+// Package conntrack keeps track of when and how the connections of a libp2p
+// network were opened, information the network itself does not retain.
+package conntrack
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	host "gx/ipfs/QmUywuGNZoUKV8B9iyvup9bPkLiMrhTsyVMkeSXW5VxAfC/go-libp2p-host"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// Direction is the side that opened a connection
+type Direction int
+
+const (
+	DirUnknown Direction = iota
+	DirInbound
+	DirOutbound
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DirInbound:
+		return "inbound"
+	case DirOutbound:
+		return "outbound"
+	default:
+		return "unknown"
+	}
+}
+
+// Info is what is known about a connection
+type Info struct {
+	Opened    time.Time
+	Direction Direction
+}
+
+// Tracker records the opening time and direction of every connection of the
+// networks it is registered with. It must be registered before connections
+// are made, connections it did not see open are reported with a zero Info.
+//
+// The network does not tell who opened a connection, so the tracker records
+// the connections dialed through the host returned by Host as outbound. Once
+// such a host is in use, the other connections are inbound: all the dials of
+// the node must go through it. Without one, and for relayed connections, the
+// direction is unknown.
+type Tracker struct {
+	lk     sync.Mutex
+	conns  map[inet.Conn]Info
+	hosted bool // a host of Host makes the dials
+}
+
+// New creates a Tracker
+func New() *Tracker {
+	return &Tracker{conns: make(map[inet.Conn]Info)}
+}
+
+// Info returns what is known about the given connection
+func (t *Tracker) Info(c inet.Conn) Info {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	return t.conns[c]
+}
+
+// Host returns a host making its dials through the network of h, so that
+// the tracker knows the connections it opens.
+func (t *Tracker) Host(h host.Host) host.Host {
+	t.lk.Lock()
+	t.hosted = true
+	t.lk.Unlock()
+	return &dialHost{Host: h, t: t}
+}
+
+// dialed records that c was opened by a dial of ours. The network may
+// notify the tracker of c before or after this.
+func (t *Tracker) dialed(c inet.Conn) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	info, ok := t.conns[c]
+	if !ok {
+		info.Opened = time.Now()
+	}
+	if !isRelayAddr(c.RemoteMultiaddr()) {
+		info.Direction = DirOutbound
+	}
+	t.conns[c] = info
+}
+
+func (t *Tracker) Connected(n inet.Network, c inet.Conn) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	if _, ok := t.conns[c]; ok {
+		// dialed by us, and already recorded
+		return
+	}
+	info := Info{Opened: time.Now()}
+	if t.hosted && !isRelayAddr(c.RemoteMultiaddr()) {
+		info.Direction = DirInbound
+	}
+	t.conns[c] = info
+}
+
+func (t *Tracker) Disconnected(n inet.Network, c inet.Conn) {
+	t.lk.Lock()
+	delete(t.conns, c)
+	t.lk.Unlock()
+}
+
+func (t *Tracker) Listen(n inet.Network, a ma.Multiaddr)      {}
+func (t *Tracker) ListenClose(n inet.Network, a ma.Multiaddr) {}
+func (t *Tracker) OpenedStream(n inet.Network, s inet.Stream) {}
+func (t *Tracker) ClosedStream(n inet.Network, s inet.Stream) {}
+
+var _ inet.Notifiee = (*Tracker)(nil)
+
+// isRelayAddr returns whether a goes through a circuit relay, whose
+// connections may have been dialed by either side
+func isRelayAddr(a ma.Multiaddr) bool {
+	return a == nil || strings.Contains(a.String(), "/p2p-circuit")
+}
+
+// dialHost dials the peers it connects or opens streams to itself, through
+// a network telling the tracker about the connections it dials
+type dialHost struct {
+	host.Host
+	t *Tracker
+}
+
+func (h *dialHost) Network() inet.Network {
+	return &dialNetwork{Network: h.Host.Network(), t: h.t}
+}
+
+func (h *dialHost) Connect(ctx context.Context, pi pstore.PeerInfo) error {
+	if h.Host.Network().Connectedness(pi.ID) != inet.Connected {
+		h.Peerstore().AddAddrs(pi.ID, pi.Addrs, pstore.TempAddrTTL)
+		if _, err := h.Network().DialPeer(ctx, pi.ID); err != nil {
+			return err
+		}
+	}
+	// lets the host finish setting up the connection, e.g. identify it
+	return h.Host.Connect(ctx, pi)
+}
+
+func (h *dialHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.ID) (inet.Stream, error) {
+	if h.Host.Network().Connectedness(p) != inet.Connected {
+		if _, err := h.Network().DialPeer(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+	return h.Host.NewStream(ctx, p, pids...)
+}
+
+type dialNetwork struct {
+	inet.Network
+	t *Tracker
+}
+
+func (n *dialNetwork) DialPeer(ctx context.Context, p peer.ID) (inet.Conn, error) {
+	if n.Network.Connectedness(p) == inet.Connected {
+		return n.Network.DialPeer(ctx, p)
+	}
+	c, err := n.Network.DialPeer(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	n.t.dialed(c)
+	return c, nil
+}
+
+func (n *dialNetwork) NewStream(ctx context.Context, p peer.ID) (inet.Stream, error) {
+	if _, err := n.DialPeer(ctx, p); err != nil {
+		return nil, err
+	}
+	return n.Network.NewStream(ctx, p)
+}
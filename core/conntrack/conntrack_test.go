@@ -0,0 +1,110 @@
+package conntrack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mocknet "gx/ipfs/QmQA5mdxru8Bh6dpC9PJfSkumqnmHgJX7knxSgBo5Lpime/go-libp2p/p2p/net/mock"
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+)
+
+func waitInfo(t *testing.T, tr *Tracker, n inet.Network) Info {
+	for i := 0; i < 100; i++ {
+		conns := n.Conns()
+		if len(conns) == 1 {
+			if info := tr.Info(conns[0]); !info.Opened.IsZero() {
+				return info
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("connection was not tracked")
+	return Info{}
+}
+
+func TestDirection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn := mocknet.New(ctx)
+	a, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	ta := New()
+	a.Network().Notify(ta)
+	tb := New()
+	b.Network().Notify(tb)
+	ha := ta.Host(a)
+	tb.Host(b)
+
+	before := time.Now()
+	err = ha.Connect(ctx, pstore.PeerInfo{ID: b.ID(), Addrs: b.Addrs()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ia := waitInfo(t, ta, a.Network())
+	if ia.Direction != DirOutbound {
+		t.Fatalf("dialer saw %s connection", ia.Direction)
+	}
+	if ia.Opened.Before(before) {
+		t.Fatal("connection opened before it was dialed")
+	}
+
+	ib := waitInfo(t, tb, b.Network())
+	if ib.Direction != DirInbound {
+		t.Fatalf("listener saw %s connection", ib.Direction)
+	}
+
+	c := a.Network().Conns()[0]
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && !ta.Info(c).Opened.IsZero(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !ta.Info(c).Opened.IsZero() {
+		t.Fatal("closed connection is still tracked")
+	}
+}
+
+func TestDirectionUnknown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn := mocknet.New(ctx)
+	a, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := mn.GenPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the dials of a don't go through a host of the tracker
+	ta := New()
+	a.Network().Notify(ta)
+
+	err = a.Connect(ctx, pstore.PeerInfo{ID: b.ID(), Addrs: b.Addrs()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ia := waitInfo(t, ta, a.Network()); ia.Direction != DirUnknown {
+		t.Fatalf("expected an unknown direction, got %s", ia.Direction)
+	}
+}
@@ -23,10 +23,20 @@ import (
 
 	bstore "github.com/ipfs/go-ipfs/blocks/blockstore"
 	bserv "github.com/ipfs/go-ipfs/blockservice"
+	autonat "github.com/ipfs/go-ipfs/core/autonat"
+	bwhistory "github.com/ipfs/go-ipfs/core/bwhistory"
+	conntrack "github.com/ipfs/go-ipfs/core/conntrack"
+	hooks "github.com/ipfs/go-ipfs/core/hooks"
+	peercache "github.com/ipfs/go-ipfs/core/peercache"
+	probes "github.com/ipfs/go-ipfs/core/probes"
+	routingstats "github.com/ipfs/go-ipfs/core/routingstats"
 	exchange "github.com/ipfs/go-ipfs/exchange"
 	bitswap "github.com/ipfs/go-ipfs/exchange/bitswap"
+	decision "github.com/ipfs/go-ipfs/exchange/bitswap/decision"
 	bsnet "github.com/ipfs/go-ipfs/exchange/bitswap/network"
+	offline "github.com/ipfs/go-ipfs/exchange/offline"
 	rp "github.com/ipfs/go-ipfs/exchange/reprovide"
+	features "github.com/ipfs/go-ipfs/features"
 	filestore "github.com/ipfs/go-ipfs/filestore"
 	mount "github.com/ipfs/go-ipfs/fuse/mount"
 	merkledag "github.com/ipfs/go-ipfs/merkledag"
@@ -35,14 +45,17 @@ import (
 	ipnsrp "github.com/ipfs/go-ipfs/namesys/republisher"
 	path "github.com/ipfs/go-ipfs/path"
 	pin "github.com/ipfs/go-ipfs/pin"
+	gc "github.com/ipfs/go-ipfs/pin/gc"
 	repo "github.com/ipfs/go-ipfs/repo"
 	config "github.com/ipfs/go-ipfs/repo/config"
 	nilrouting "github.com/ipfs/go-ipfs/routing/none"
 	offroute "github.com/ipfs/go-ipfs/routing/offline"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
 	ft "github.com/ipfs/go-ipfs/unixfs"
 
 	routing "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing"
 	ic "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
 	ipnet "gx/ipfs/QmPsBptED6X43GYg3347TAUruN3UfsAhaGTP9xbinYX7uf/go-libp2p-interface-pnet"
 	mplex "gx/ipfs/QmQ3UABWTgK78utKeiVXaH9BrjC7Ydn1pRuwqnWHT3p4zh/go-smux-multiplex"
 	discovery "gx/ipfs/QmQA5mdxru8Bh6dpC9PJfSkumqnmHgJX7knxSgBo5Lpime/go-libp2p/p2p/discovery"
@@ -102,17 +115,21 @@ type IpfsNode struct {
 	Repo repo.Repo
 
 	// Local node
-	Pinning        pin.Pinner // the pinning manager
-	Mounts         Mounts     // current mount state, if any.
-	PrivateKey     ic.PrivKey // the local node's private Key
-	PNetFingerpint []byte     // fingerprint of private network
+	Pinning        pin.Pinner   // the pinning manager
+	PinHooks       []pin.Hook   // notified of pin changes before they happen
+	Hooks          *hooks.Hooks // run around pin, name and key operations
+	Mounts         Mounts       // current mount state, if any.
+	PrivateKey     ic.PrivKey   // the local node's private Key
+	PNetFingerpint []byte       // fingerprint of private network
 
 	// Services
 	Peerstore  pstore.Peerstore     // storage for other Peer instances
 	Blockstore bstore.GCBlockstore  // the block store (lower level)
 	Filestore  *filestore.Filestore // the filestore blockstore
+	Features   *features.Set        // the optional features enabled on this node
 	BaseBlocks bstore.Blockstore    // the raw blockstore, no filestore wrapping
 	GCLocker   bstore.GCLocker      // the locker used to protect the blockstore during gc
+	GCLeases   *gc.Leases           // temporary gc roots of the components storing blocks
 	Blocks     bserv.BlockService   // the block service, get/add blocks.
 	DAG        merkledag.DAGService // the merkle dag service, get/add objects.
 	Resolver   *path.Resolver       // the path resolution system
@@ -121,22 +138,42 @@ type IpfsNode struct {
 	FilesRoot  *mfs.Root
 
 	// Online
-	PeerHost     p2phost.Host        // the network host (server+client)
-	Bootstrapper io.Closer           // the periodic bootstrapper
-	Routing      routing.IpfsRouting // the routing system. recommend ipfs-dht
-	Exchange     exchange.Interface  // the block exchange + strategy (bitswap)
-	Namesys      namesys.NameSystem  // the name system, resolves paths to hashes
-	Ping         *ping.PingService
-	Reprovider   *rp.Reprovider // the value reprovider system
-	IpnsRepub    *ipnsrp.Republisher
+	PeerHost      p2phost.Host        // the network host (server+client)
+	Bootstrapper  io.Closer           // the periodic bootstrapper
+	Routing       routing.IpfsRouting // the routing system. recommend ipfs-dht
+	Exchange      exchange.Interface  // the block exchange + strategy (bitswap)
+	Namesys       namesys.NameSystem  // the name system, resolves paths to hashes
+	Ping          *ping.PingService
+	ConnTracker   *conntrack.Tracker // when and how connections were opened
+	PeerCache     *peercache.Cache   // the peers saved across restarts
+	AutoNAT       *autonat.Client    // whether the node is publicly reachable
+	Reprovider    *rp.Reprovider     // the value reprovider system
+	Prober        *probes.Prober     // the self-probes, if enabled
+	IpnsRepub     *ipnsrp.Republisher
+	IpnsValidator *namesys.Validator // checks the ipns records stored and resolved
 
 	Floodsub *floodsub.PubSub
 
-	proc goprocess.Process
-	ctx  context.Context
+	proc  goprocess.Process
+	ctx   context.Context
+	clock clock.Clock
 
 	mode         mode
 	localModeSet bool
+
+	// peers loaded from the peer cache, dialed before the bootstrap peers
+	cachedPeers []peer.ID
+
+	// power mode, and the intervals it restores in normal mode
+	power             *powerState
+	reprovideInterval time.Duration
+	republishInterval time.Duration
+
+	// rule of the time of day schedule in effect
+	schedule *scheduleState
+
+	// peers whose connections are never trimmed
+	protected protectedPeers
 }
 
 // Mounts defines what the node's mount state is. This should
@@ -172,9 +209,32 @@ func (n *IpfsNode) startOnlineServices(ctx context.Context, routingOption Routin
 		addrfilter = append(addrfilter, f)
 	}
 
+	n.power, err = newPowerState(cfg.Power)
+	if err != nil {
+		return err
+	}
+	startMode := PowerNormal
+	if cfg.Power.Mode != "" {
+		startMode, err = ParsePowerMode(cfg.Power.Mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := parseSchedule(cfg.Schedule); err != nil {
+		return err
+	}
+
 	if !cfg.Swarm.DisableBandwidthMetrics {
+		window, interval, err := bandwidthHistoryConfig(cfg.Swarm.BandwidthHistory)
+		if err != nil {
+			return err
+		}
+
 		// Set reporter
-		n.Reporter = metrics.NewBandwidthCounter()
+		bwh := bwhistory.New(metrics.NewBandwidthCounter(), window, interval)
+		go bwh.Run(ctx)
+		n.Reporter = bwh
 	}
 
 	tpt := makeSmuxTransport(mplex)
@@ -211,11 +271,33 @@ func (n *IpfsNode) startOnlineServices(ctx context.Context, routingOption Routin
 		}()
 	}
 
+	hostOpts := &ConstructPeerHostOpts{DisableNatPortMap: cfg.Swarm.DisableNatPortMap}
+	if err := addrPolicyOpts(cfg, hostOpts); err != nil {
+		return err
+	}
+
 	peerhost, err := hostOption(ctx, n.Identity, n.Peerstore, n.Reporter,
-		addrfilter, tpt, protec, &ConstructPeerHostOpts{DisableNatPortMap: cfg.Swarm.DisableNatPortMap})
+		addrfilter, tpt, protec, hostOpts)
 	if err != nil {
 		return err
 	}
+	go checkAnnounced(ctx, peerhost, hostOpts.Announce)
+
+	// keep track of connection directions and ages, before anything dials:
+	// every dial must go through the host of the tracker
+	n.ConnTracker = conntrack.New()
+	peerhost.Network().Notify(n.ConnTracker)
+	peerhost = n.ConnTracker.Host(peerhost)
+
+	if !cfg.Swarm.DisableAutoNATService {
+		autonat.NewService(peerhost, func() (p2phost.Host, error) {
+			return constructDialBackHost(ctx, tpt, protec)
+		})
+	}
+	if !cfg.Swarm.DisableAutoNAT {
+		n.AutoNAT = autonat.NewClient(peerhost)
+		go n.AutoNAT.Run(ctx)
+	}
 
 	if err := n.startOnlineServicesWithHost(ctx, peerhost, routingOption); err != nil {
 		return err
@@ -239,9 +321,21 @@ func (n *IpfsNode) startOnlineServices(ctx context.Context, routingOption Routin
 			interval = dur
 		}
 
+		n.reprovideInterval = interval
+		// ProvideEvery sets the interval once running, possibly after
+		// SetPowerMode below: start it at that of the initial power mode
+		if startMode == PowerLow {
+			interval = n.power.reprovide
+		}
 		go n.Reprovider.ProvideEvery(ctx, interval)
 	}
 
+	if cfg.Probes.Enabled {
+		if err := n.startProbes(ctx, cfg.Probes); err != nil {
+			return err
+		}
+	}
+
 	if pubsub {
 		n.Floodsub = floodsub.NewFloodSub(ctx, peerhost)
 	}
@@ -257,7 +351,18 @@ func (n *IpfsNode) startOnlineServices(ctx context.Context, routingOption Routin
 		}
 	}
 
-	return n.Bootstrap(DefaultBootstrapConfig)
+	if err := n.Bootstrap(DefaultBootstrapConfig); err != nil {
+		return err
+	}
+	if startMode != PowerNormal {
+		if err := n.SetPowerMode(startMode); err != nil {
+			return err
+		}
+	}
+
+	n.schedule = new(scheduleState)
+	go n.runSchedule(ctx)
+	return nil
 }
 
 func makeSmuxTransport(mplexExp bool) smux.Transport {
@@ -290,6 +395,76 @@ func makeSmuxTransport(mplexExp bool) smux.Transport {
 	return mstpt
 }
 
+func bandwidthHistoryConfig(c config.BandwidthHistory) (window, interval time.Duration, err error) {
+	window, interval = bwhistory.DefaultWindow, bwhistory.DefaultInterval
+	if c.Window != "" {
+		window, err = time.ParseDuration(c.Window)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Swarm.BandwidthHistory.Window: %s", err)
+		}
+	}
+	if c.Interval != "" {
+		interval, err = time.ParseDuration(c.Interval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Swarm.BandwidthHistory.Interval: %s", err)
+		}
+	}
+	return window, interval, nil
+}
+
+func peerCacheConfig(c config.PeerCache) (maxAge time.Duration, maxPeers int, err error) {
+	maxAge, maxPeers = peercache.DefaultMaxAge, peercache.DefaultMaxPeers
+	if c.MaxAge != "" {
+		maxAge, err = time.ParseDuration(c.MaxAge)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Swarm.PeerCache.MaxAge: %s", err)
+		}
+	}
+	if c.MaxPeers > 0 {
+		maxPeers = c.MaxPeers
+	}
+	return maxAge, maxPeers, nil
+}
+
+func bitswapServeConfig(c config.Bitswap) (decision.ServeConfig, error) {
+	var sc decision.ServeConfig
+
+	allowed := make(map[peer.ID]bool, len(c.ServeAllowlist))
+	for _, s := range c.ServeAllowlist {
+		p, err := peer.IDB58Decode(s)
+		if err != nil {
+			return sc, fmt.Errorf("invalid peer ID in Bitswap.ServeAllowlist: %s", s)
+		}
+		allowed[p] = true
+	}
+	switch c.ServeMode {
+	case "", "all":
+	case "allowlist":
+		sc.Filter = func(p peer.ID, _ uint64) bool {
+			return allowed[p]
+		}
+	case "known":
+		sc.Filter = func(p peer.ID, received uint64) bool {
+			return allowed[p] || received > 0
+		}
+	default:
+		return sc, fmt.Errorf("invalid Bitswap.ServeMode %q, expected \"all\", \"allowlist\" or \"known\"", c.ServeMode)
+	}
+
+	if c.MaxPeerUploadRate != "" {
+		rate, err := humanize.ParseBytes(c.MaxPeerUploadRate)
+		if err != nil {
+			return sc, fmt.Errorf("invalid Bitswap.MaxPeerUploadRate: %s", err)
+		}
+		sc.PeerBandwidth = rate
+	}
+	if c.MaxConcurrentSends < 0 {
+		return sc, fmt.Errorf("invalid Bitswap.MaxConcurrentSends: %d", c.MaxConcurrentSends)
+	}
+	sc.MaxConcurrentSends = c.MaxConcurrentSends
+	return sc, nil
+}
+
 func setupDiscoveryOption(d config.Discovery) DiscoveryOption {
 	if d.MDNS.Enabled {
 		return func(ctx context.Context, h p2phost.Host) (discovery.Service, error) {
@@ -317,28 +492,68 @@ func (n *IpfsNode) startOnlineServicesWithHost(ctx context.Context, host p2phost
 	// setup diagnostics service
 	n.Ping = ping.NewPingService(host)
 
-	// setup routing service
-	r, err := routingOption(ctx, host, n.Repo.Datastore())
+	// setup routing service, stop serving it if the node turns out to be
+	// unreachable
+	routingHost := host
+	if n.AutoNAT != nil {
+		routingHost = autonat.PublicOnly(host, n.AutoNAT)
+	}
+	routingHost = &lowPowerHost{Host: routingHost, n: n}
+	r, err := routingOption(ctx, routingHost, n.Repo.Datastore())
 	if err != nil {
 		return err
 	}
-	n.Routing = r
+
+	// check ipns records with the node's validator, before the node
+	// listens and records can be stored
+	if d, ok := r.(*dht.IpfsDHT); ok && n.IpnsValidator != nil {
+		d.Validator[IpnsValidatorTag] = n.IpnsValidator.ValidChecker()
+	}
+
+	// keep statistics of the queries
+	n.Routing = routingstats.Wrap(r)
 
 	// Wrap standard peer host with routing system to allow unknown peer lookups
 	n.PeerHost = rhost.Wrap(host, n.Routing)
 
 	// setup exchange service
 	const alwaysSendToPeer = true // use YesManStrategy
-	bitswapNetwork := bsnet.NewFromIpfsHost(n.PeerHost, n.Routing)
-	n.Exchange = bitswap.New(ctx, n.Identity, bitswapNetwork, n.Blockstore, alwaysSendToPeer)
-
-	size, err := n.getCacheSize()
+	cfg, err := n.Repo.Config()
 	if err != nil {
 		return err
 	}
+	serve, err := bitswapServeConfig(cfg.Bitswap)
+	if err != nil {
+		return err
+	}
+
+	// remember the peers of the previous runs, and keep saving them
+	if !cfg.Swarm.PeerCache.Disabled {
+		maxAge, maxPeers, err := peerCacheConfig(cfg.Swarm.PeerCache)
+		if err != nil {
+			return err
+		}
+		n.PeerCache = peercache.New(n.Repo.Datastore(), host.Peerstore(), maxAge, maxPeers)
+		n.cachedPeers, err = n.PeerCache.Load()
+		if err != nil {
+			log.Warningf("failed to load the cached peers: %s", err)
+		}
+		n.PeerCache.Start(host.Network(), peercache.DefaultInterval)
+	}
+
+	var bitswapNetwork bsnet.BitSwapNetwork
+	if cfg.Bitswap.Compression {
+		bitswapNetwork = bsnet.NewFromIpfsHostWithCompression(n.PeerHost, n.Routing)
+	} else {
+		bitswapNetwork = bsnet.NewFromIpfsHost(n.PeerHost, n.Routing)
+	}
+	n.Exchange = bitswap.NewWithServeConfig(ctx, n.Identity, bitswapNetwork, n.Blockstore, alwaysSendToPeer, serve, n.Features.Enabled(features.LowMem))
 
 	// setup name system
-	n.Namesys = namesys.NewNameSystem(n.Routing, n.Repo.Datastore(), size)
+	n.Namesys, err = n.newNameSystem(n.Routing)
+	if err != nil {
+		return err
+	}
 
 	// setup ipns republishing
 	err = n.setupIpnsRepublisher()
@@ -349,11 +564,12 @@ func (n *IpfsNode) startOnlineServicesWithHost(ctx context.Context, host p2phost
 	return nil
 }
 
-// getCacheSize returns cache life and cache size
-func (n *IpfsNode) getCacheSize() (int, error) {
+// newNameSystem returns a name system over r, with the cache configured in
+// the Ipns section of the config
+func (n *IpfsNode) newNameSystem(r routing.ValueStore) (namesys.NameSystem, error) {
 	cfg, err := n.Repo.Config()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	cs := cfg.Ipns.ResolveCacheSize
@@ -361,9 +577,54 @@ func (n *IpfsNode) getCacheSize() (int, error) {
 		cs = 128
 	}
 	if cs < 0 {
-		return 0, fmt.Errorf("cannot specify negative resolve cache size")
+		return nil, fmt.Errorf("cannot specify negative resolve cache size")
+	}
+
+	var min, max time.Duration
+	if cfg.Ipns.ResolveCacheTTLMin != "" {
+		min, err = time.ParseDuration(cfg.Ipns.ResolveCacheTTLMin)
+		if err != nil {
+			return nil, fmt.Errorf("failure to parse config setting Ipns.ResolveCacheTTLMin: %s", err)
+		}
+	}
+	if cfg.Ipns.ResolveCacheTTLMax != "" {
+		max, err = time.ParseDuration(cfg.Ipns.ResolveCacheTTLMax)
+		if err != nil {
+			return nil, fmt.Errorf("failure to parse config setting Ipns.ResolveCacheTTLMax: %s", err)
+		}
+	}
+	if min < 0 || max < 0 || (max > 0 && min > max) {
+		return nil, fmt.Errorf("config settings Ipns.ResolveCacheTTLMin (%s) and Ipns.ResolveCacheTTLMax (%s) are not a valid range", min, max)
+	}
+
+	ns := namesys.NewNameSystemWithClock(r, n.Repo.Datastore(), cs, n.Clock())
+	if cb, ok := ns.(namesys.CacheBounder); ok {
+		cb.SetCacheBounds(min, max)
+	}
+	if vs, ok := ns.(namesys.ValidatorSetter); ok && n.IpnsValidator != nil {
+		vs.SetValidator(n.IpnsValidator)
+	}
+	if hs, ok := ns.(namesys.HooksSetter); ok && n.Hooks != nil {
+		hs.SetHooks(n.Hooks)
+	}
+	return ns, nil
+}
+
+// newIpnsValidator returns the validator of ipns records, with the maximum
+// lifetime configured in the Ipns section of the config
+func newIpnsValidator(cfg *config.Config, clk clock.Clock) (*namesys.Validator, error) {
+	v := namesys.NewValidator(clk)
+	if cfg.Ipns.MaxRecordLifetime != "" {
+		max, err := time.ParseDuration(cfg.Ipns.MaxRecordLifetime)
+		if err != nil {
+			return nil, fmt.Errorf("failure to parse config setting Ipns.MaxRecordLifetime: %s", err)
+		}
+		if max <= 0 {
+			return nil, fmt.Errorf("config setting Ipns.MaxRecordLifetime must be positive, got %s", max)
+		}
+		v.MaxLifetime = max
 	}
-	return cs, nil
+	return v, nil
 }
 
 func (n *IpfsNode) setupIpnsRepublisher() error {
@@ -373,7 +634,23 @@ func (n *IpfsNode) setupIpnsRepublisher() error {
 	}
 
 	n.IpnsRepub = ipnsrp.NewRepublisher(n.Routing, n.Repo.Datastore(), n.Peerstore)
+	n.IpnsRepub.Clock = n.Clock()
 	n.IpnsRepub.AddName(n.Identity)
+	for _, name := range cfg.Ipns.RepublishKeys {
+		sk, err := n.Repo.Keystore().Get(name)
+		if err != nil {
+			log.Warningf("not republishing key %s: %s", name, err)
+			continue
+		}
+		id, err := peer.IDFromPrivateKey(sk)
+		if err != nil {
+			return err
+		}
+		// the republisher signs with the keys of the peerstore
+		n.Peerstore.AddPrivKey(id, sk)
+		n.Peerstore.AddPubKey(id, sk.GetPublic())
+		n.IpnsRepub.AddName(id)
+	}
 
 	if cfg.Ipns.RepublishPeriod != "" {
 		d, err := time.ParseDuration(cfg.Ipns.RepublishPeriod)
@@ -397,6 +674,11 @@ func (n *IpfsNode) setupIpnsRepublisher() error {
 		n.IpnsRepub.RecordLifetime = d
 	}
 
+	if cfg.Ipns.MaxSignedNames != 0 {
+		n.IpnsRepub.MaxSignedNames = cfg.Ipns.MaxSignedNames
+	}
+
+	n.republishInterval = n.IpnsRepub.Interval
 	n.Process().Go(n.IpnsRepub.Run)
 
 	return nil
@@ -407,6 +689,14 @@ func (n *IpfsNode) Process() goprocess.Process {
 	return n.proc
 }
 
+// Clock returns the clock driving the periodic tasks of the node.
+func (n *IpfsNode) Clock() clock.Clock {
+	if n.clock == nil {
+		return clock.New()
+	}
+	return n.clock
+}
+
 // Close calls Close() on the Process object
 func (n *IpfsNode) Close() error {
 	return n.proc.Close()
@@ -447,7 +737,7 @@ func (n *IpfsNode) teardown() error {
 		closers = append(closers, mount.Closer(n.Mounts.Ipns))
 	}
 
-	if dht, ok := n.Routing.(*dht.IpfsDHT); ok {
+	if dht, ok := n.DHT(); ok {
 		closers = append(closers, dht.Process())
 	}
 
@@ -459,6 +749,11 @@ func (n *IpfsNode) teardown() error {
 		closers = append(closers, n.Bootstrapper)
 	}
 
+	// saves the peers still connected
+	if n.PeerCache != nil {
+		closers = append(closers, n.PeerCache)
+	}
+
 	if n.PeerHost != nil {
 		closers = append(closers, n.PeerHost)
 	}
@@ -478,6 +773,16 @@ func (n *IpfsNode) teardown() error {
 	return nil
 }
 
+// DHT returns the DHT the node routes with, if it does
+func (n *IpfsNode) DHT() (*dht.IpfsDHT, bool) {
+	r := n.Routing
+	if s, ok := r.(*routingstats.Router); ok {
+		r = s.Unwrap()
+	}
+	d, ok := r.(*dht.IpfsDHT)
+	return d, ok
+}
+
 func (n *IpfsNode) OnlineMode() bool {
 	switch n.mode {
 	case onlineMode:
@@ -530,6 +835,21 @@ func (n *IpfsNode) Bootstrap(cfg BootstrapConfig) error {
 			return ps
 		}
 	}
+	if cfg.FallbackPeers == nil {
+		cfg.FallbackPeers = func() []pstore.PeerInfo {
+			ps, err := n.loadFallbackBootstrapPeers()
+			if err != nil {
+				log.Warningf("failed to load the fallback bootstrap peers: %s", err)
+				return nil
+			}
+			return ps
+		}
+	}
+	if cfg.CachedPeers == nil && len(n.cachedPeers) > 0 {
+		cfg.CachedPeers = func() []peer.ID {
+			return n.cachedPeers
+		}
+	}
 
 	var err error
 	n.Bootstrapper, err = Bootstrap(n, cfg)
@@ -604,14 +924,30 @@ func (n *IpfsNode) loadBootstrapPeers() ([]pstore.PeerInfo, error) {
 	return toPeerInfos(parsed), nil
 }
 
-func (n *IpfsNode) loadFilesRoot() error {
-	dsk := ds.NewKey("/local/filesroot")
+func (n *IpfsNode) loadFallbackBootstrapPeers() ([]pstore.PeerInfo, error) {
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := cfg.BootstrapFallbackPeers()
+	if err != nil {
+		return nil, err
+	}
+	return toPeerInfos(parsed), nil
+}
+
+// filesRootDatastoreKey is the datastore key holding the root of the files API
+var filesRootDatastoreKey = ds.NewKey("/local/filesroot")
+
+// loadFilesRoot loads the root of the files API, stored in d
+func (n *IpfsNode) loadFilesRoot(d ds.Datastore) error {
 	pf := func(ctx context.Context, c *cid.Cid) error {
-		return n.Repo.Datastore().Put(dsk, c.Bytes())
+		return d.Put(filesRootDatastoreKey, c.Bytes())
 	}
 
 	var nd *merkledag.ProtoNode
-	val, err := n.Repo.Datastore().Get(dsk)
+	val, err := d.Get(filesRootDatastoreKey)
 
 	switch {
 	case err == ds.ErrNotFound || val == nil:
@@ -665,14 +1001,33 @@ func (n *IpfsNode) SetupOfflineRouting() error {
 
 	n.Routing = offroute.NewOfflineRouter(n.Repo.Datastore(), n.PrivateKey)
 
-	size, err := n.getCacheSize()
+	n.Namesys, err = n.newNameSystem(n.Routing)
+	return err
+}
+
+// Offline returns a view of an online node that only uses the local repo:
+// blocks are never fetched from or announced to the network, and ipns
+// records are only read from and written to the local datastore. The view
+// shares the repo, pinner and files root with n, and must not be closed.
+// An offline node is returned as is.
+func (n *IpfsNode) Offline() (*IpfsNode, error) {
+	if !n.OnlineMode() {
+		return n, nil
+	}
+
+	off := *n
+	off.mode = offlineMode
+	off.Exchange = offline.Exchange(n.Blockstore)
+	off.Blocks = bserv.New(n.Blockstore, off.Exchange)
+	off.DAG = merkledag.NewDAGService(off.Blocks)
+	off.Resolver = path.NewBasicResolver(off.DAG)
+	off.Routing = offroute.NewOfflineRouter(n.Repo.Datastore(), n.PrivateKey)
+	ns, err := n.newNameSystem(off.Routing)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	n.Namesys = namesys.NewNameSystem(n.Routing, n.Repo.Datastore(), size)
-
-	return nil
+	off.Namesys = ns
+	return &off, nil
 }
 
 func loadPrivateKey(cfg *config.Identity, id peer.ID) (ic.PrivKey, error) {
@@ -708,6 +1063,9 @@ func listenAddresses(cfg *config.Config) ([]ma.Multiaddr, error) {
 
 type ConstructPeerHostOpts struct {
 	DisableNatPortMap bool
+	DialPolicy        *AddrPolicy    // addresses that may be dialed, nil for all
+	AnnouncePolicy    *AddrPolicy    // addresses that may be announced, nil for all
+	Announce          []ma.Multiaddr // announced instead of the listen addresses
 }
 
 type HostOption func(ctx context.Context, id peer.ID, ps pstore.Peerstore, bwr metrics.Reporter, fs []*net.IPNet, tpt smux.Transport, protc ipnet.Protector, opts *ConstructPeerHostOpts) (p2phost.Host, error)
@@ -717,8 +1075,13 @@ var DefaultHostOption HostOption = constructPeerHost
 // isolates the complex initialization steps
 func constructPeerHost(ctx context.Context, id peer.ID, ps pstore.Peerstore, bwr metrics.Reporter, fs []*net.IPNet, tpt smux.Transport, protec ipnet.Protector, opts *ConstructPeerHostOpts) (p2phost.Host, error) {
 
+	swarmps := ps
+	if opts.DialPolicy != nil {
+		swarmps = &policyPeerstore{Peerstore: ps, self: id, policy: opts.DialPolicy}
+	}
+
 	// no addresses to begin with. we'll start later.
-	swrm, err := swarm.NewSwarmWithProtector(ctx, nil, id, ps, protec, tpt, bwr)
+	swrm, err := swarm.NewSwarmWithProtector(ctx, nil, id, swarmps, protec, tpt, bwr)
 	if err != nil {
 		return nil, err
 	}
@@ -735,10 +1098,37 @@ func constructPeerHost(ctx context.Context, id peer.ID, ps pstore.Peerstore, bwr
 	}
 
 	host := p2pbhost.New(network, hostOpts...)
+	if opts.AnnouncePolicy != nil || len(opts.Announce) > 0 {
+		return NewAnnounceHost(host, opts.AnnouncePolicy, opts.Announce), nil
+	}
 
 	return host, nil
 }
 
+// constructDialBackHost creates the host the autonat service dials other
+// peers back from. It has its own identity, so that dial backs always use
+// new connections.
+func constructDialBackHost(ctx context.Context, tpt smux.Transport, protec ipnet.Protector) (p2phost.Host, error) {
+	sk, pk, err := ic.GenerateKeyPair(ic.RSA, 2048)
+	if err != nil {
+		return nil, err
+	}
+	id, err := peer.IDFromPublicKey(pk)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := pstore.NewPeerstore()
+	ps.AddPrivKey(id, sk)
+	ps.AddPubKey(id, pk)
+
+	swrm, err := swarm.NewSwarmWithProtector(ctx, nil, id, ps, protec, tpt, metrics.NewBandwidthCounter())
+	if err != nil {
+		return nil, err
+	}
+	return p2pbhost.New((*swarm.Network)(swrm)), nil
+}
+
 // startListening on the network addresses
 func startListening(ctx context.Context, host p2phost.Host, cfg *config.Config) error {
 	listenAddrs, err := listenAddresses(cfg)
@@ -770,14 +1160,14 @@ func startListening(ctx context.Context, host p2phost.Host, cfg *config.Config)
 
 func constructDHTRouting(ctx context.Context, host p2phost.Host, dstore repo.Datastore) (routing.IpfsRouting, error) {
 	dhtRouting := dht.NewDHT(ctx, host, dstore)
-	dhtRouting.Validator[IpnsValidatorTag] = namesys.IpnsRecordValidator
+	dhtRouting.Validator[IpnsValidatorTag] = namesys.DefaultValidator.ValidChecker()
 	dhtRouting.Selector[IpnsValidatorTag] = namesys.IpnsSelectorFunc
 	return dhtRouting, nil
 }
 
 func constructClientDHTRouting(ctx context.Context, host p2phost.Host, dstore repo.Datastore) (routing.IpfsRouting, error) {
 	dhtRouting := dht.NewDHTClient(ctx, host, dstore)
-	dhtRouting.Validator[IpnsValidatorTag] = namesys.IpnsRecordValidator
+	dhtRouting.Validator[IpnsValidatorTag] = namesys.DefaultValidator.ValidChecker()
 	dhtRouting.Selector[IpnsValidatorTag] = namesys.IpnsSelectorFunc
 	return dhtRouting, nil
 }
@@ -24,6 +24,10 @@ func (api *CoreAPI) Unixfs() coreiface.UnixfsAPI {
 	return (*UnixfsAPI)(api)
 }
 
+func (api *CoreAPI) Dag() coreiface.DagAPI {
+	return (*DagAPI)(api)
+}
+
 func (api *CoreAPI) ResolveNode(ctx context.Context, p coreiface.Path) (coreiface.Node, error) {
 	p, err := api.ResolvePath(ctx, p)
 	if err != nil {
@@ -43,13 +47,8 @@ func (api *CoreAPI) ResolvePath(ctx context.Context, p coreiface.Path) (coreifac
 		return p, nil
 	}
 
-	r := &ipfspath.Resolver{
-		DAG:         api.node.DAG,
-		ResolveOnce: uio.ResolveUnixfsOnce,
-	}
-
 	p2 := ipfspath.FromString(p.String())
-	node, err := core.Resolve(ctx, api.node.Namesys, r, p2)
+	node, err := core.Resolve(ctx, api.node.Namesys, api.resolver(), p2)
 	if err == core.ErrNoNamesys {
 		return nil, coreiface.ErrOffline
 	} else if err != nil {
@@ -64,6 +63,28 @@ func (api *CoreAPI) ResolvePath(ctx context.Context, p coreiface.Path) (coreifac
 	return ResolvedPath(p.String(), node.Cid(), root), nil
 }
 
+func (api *CoreAPI) ResolveToLastNode(ctx context.Context, p coreiface.Path) (coreiface.Node, []string, error) {
+	if p.Resolved() {
+		node, err := api.node.DAG.Get(ctx, p.Cid())
+		return node, nil, err
+	}
+
+	node, rest, err := core.ResolveToLastNode(ctx, api.node.Namesys, api.resolver(), ipfspath.FromString(p.String()))
+	if err == core.ErrNoNamesys {
+		return nil, nil, coreiface.ErrOffline
+	}
+	return node, rest, err
+}
+
+// resolver returns the resolver paths are resolved with, which goes through
+// sharded directories
+func (api *CoreAPI) resolver() *ipfspath.Resolver {
+	return &ipfspath.Resolver{
+		DAG:         api.node.DAG,
+		ResolveOnce: uio.ResolveUnixfsOnce,
+	}
+}
+
 // Implements coreiface.Path
 type path struct {
 	path ipfspath.Path
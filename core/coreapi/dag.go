@@ -0,0 +1,37 @@
+package coreapi
+
+import (
+	"context"
+
+	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+	dag "github.com/ipfs/go-ipfs/merkledag"
+
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+)
+
+type DagAPI CoreAPI
+
+func (api *DagAPI) Prefetch(ctx context.Context, p coreiface.Path, opts coreiface.PrefetchOptions) error {
+	p, err := api.core().ResolvePath(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	set := cid.NewSet()
+	visit := func(c *cid.Cid) bool {
+		if !set.Visit(c) {
+			return false
+		}
+		if opts.Progress != nil {
+			opts.Progress(set.Len())
+		}
+		return true
+	}
+
+	getLinks := dag.GetLinksDirect(api.node.DAG)
+	return dag.EnumerateChildrenAsyncConcurrent(ctx, getLinks, p.Cid(), visit, opts.Concurrency)
+}
+
+func (api *DagAPI) core() coreiface.CoreAPI {
+	return (*CoreAPI)(api)
+}
@@ -0,0 +1,80 @@
+package coreapi_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	coreapi "github.com/ipfs/go-ipfs/core/coreapi"
+	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
+
+	cbor "gx/ipfs/QmNrbCt8j9DT5W9Pmjy2SdudT9k8GpaDr4sRuFix3BXhgR/go-ipld-cbor"
+)
+
+func TestPrefetch(t *testing.T) {
+	ctx := context.Background()
+	node, api, err := makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := api.Add(ctx, strings.NewReader(helloStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var blocks int
+	opts := coreiface.PrefetchOptions{
+		Progress: func(n int) { blocks = n },
+	}
+
+	err = coreapi.NewCoreAPI(node).Dag().Prefetch(ctx, p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if blocks != 1 {
+		t.Fatalf("expected 1 block to be processed, got %d", blocks)
+	}
+}
+
+func TestResolveToLastNode(t *testing.T) {
+	ctx := context.Background()
+	node, _, err := makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nd, err := cbor.WrapObject(map[string]interface{}{"foo": []interface{}{"bar"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := node.DAG.Add(nd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := coreapi.ParsePath("/ipfs/" + c.String() + "/foo/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, rest, err := coreapi.NewCoreAPI(node).ResolveToLastNode(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !last.Cid().Equals(c) {
+		t.Fatalf("expected to stop at %s, got %s", c, last.Cid())
+	}
+	if len(rest) != 2 || rest[0] != "foo" || rest[1] != "0" {
+		t.Fatalf("unexpected rest of the path: %v", rest)
+	}
+
+	p, err = coreapi.ParsePath("/ipns/" + node.Identity.Pretty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = coreapi.NewCoreAPI(node).ResolveToLastNode(ctx, p)
+	if err != coreiface.ErrOffline {
+		t.Fatalf("expected ErrOffline, got %v", err)
+	}
+}
@@ -28,14 +28,71 @@ type Reader interface {
 
 type CoreAPI interface {
 	Unixfs() UnixfsAPI
+	Dag() DagAPI
 	ResolvePath(context.Context, Path) (Path, error)
 	ResolveNode(context.Context, Path) (Node, error)
+
+	// ResolveToLastNode is like ResolveNode, but also accepts paths ending
+	// inside a node, such as the fields of a dag-cbor node. It returns the
+	// last node of the path, and the rest of the path within that node.
+	ResolveToLastNode(context.Context, Path) (Node, []string, error)
 }
 
 type UnixfsAPI interface {
 	Add(context.Context, io.Reader) (Path, error)
 	Cat(context.Context, Path) (Reader, error)
+
+	// Open is like Cat, but only exposes the byte range of the file
+	// selected by the options. Only the blocks covering the range are
+	// fetched.
+	Open(context.Context, Path, OpenOptions) (Reader, error)
 	Ls(context.Context, Path) ([]*Link, error)
+
+	// LsPage is like Ls, but only returns the links selected by the
+	// options. Links come in the same order as with Ls, and the traversal
+	// of sharded directories stops as soon as the page is full.
+	LsPage(context.Context, Path, LsOptions) ([]*Link, error)
+}
+
+// DagAPI specifies the interface to IPLD DAGs
+type DagAPI interface {
+	// Prefetch fetches the whole DAG under the given path into the local
+	// blockstore without pinning it. Blocks already present are not
+	// fetched again, so an interrupted prefetch is resumed by simply
+	// calling Prefetch again.
+	Prefetch(context.Context, Path, PrefetchOptions) error
+}
+
+// OpenOptions are the options of UnixfsAPI.Open
+type OpenOptions struct {
+	// Offset is the position in the file the returned reader starts at.
+	// Offsets past the end of the file yield an empty reader.
+	Offset int64
+
+	// Length is the maximum number of bytes the returned reader exposes,
+	// zero means everything up to the end of the file
+	Length int64
+}
+
+// LsOptions are the options of UnixfsAPI.LsPage
+type LsOptions struct {
+	// Offset is the number of links skipped before the page starts
+	Offset int
+
+	// Limit is the maximum number of links returned, zero means all the
+	// links after Offset
+	Limit int
+}
+
+// PrefetchOptions are the options of DagAPI.Prefetch
+type PrefetchOptions struct {
+	// Concurrency is the number of blocks fetched in parallel, zero means
+	// the default
+	Concurrency int
+
+	// Progress, when set, is called with the number of blocks processed
+	// so far every time a new one is
+	Progress func(blocks int)
 }
 
 // type ObjectAPI interface {
@@ -62,3 +119,5 @@ type UnixfsAPI interface {
 
 var ErrIsDir = errors.New("object is a directory")
 var ErrOffline = errors.New("can't resolve, ipfs node is offline")
+var ErrInvalidRange = errors.New("offset and length must not be negative")
+var ErrInvalidPage = errors.New("offset and limit must not be negative")
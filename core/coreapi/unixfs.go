@@ -2,6 +2,7 @@ package coreapi
 
 import (
 	"context"
+	"errors"
 	"io"
 
 	coreiface "github.com/ipfs/go-ipfs/core/coreapi/interface"
@@ -27,46 +28,143 @@ func (api *UnixfsAPI) Add(ctx context.Context, r io.Reader) (coreiface.Path, err
 }
 
 func (api *UnixfsAPI) Cat(ctx context.Context, p coreiface.Path) (coreiface.Reader, error) {
-	dagnode, err := api.core().ResolveNode(ctx, p)
+	return api.dagReader(ctx, p)
+}
+
+func (api *UnixfsAPI) Open(ctx context.Context, p coreiface.Path, opts coreiface.OpenOptions) (coreiface.Reader, error) {
+	if opts.Offset < 0 || opts.Length < 0 {
+		return nil, coreiface.ErrInvalidRange
+	}
+
+	r, err := api.dagReader(ctx, p)
 	if err != nil {
 		return nil, err
 	}
 
-	r, err := uio.NewDagReader(ctx, dagnode, api.node.DAG)
-	if err == uio.ErrIsDir {
-		return nil, coreiface.ErrIsDir
-	} else if err != nil {
+	size := int64(r.Size())
+	start := opts.Offset
+	if start > size {
+		start = size
+	}
+	end := size
+	if opts.Length > 0 && opts.Length < end-start {
+		end = start + opts.Length
+	}
+
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		r.Close()
 		return nil, err
 	}
-	return r, nil
+	return &rangeReader{r: r, start: start, end: end, pos: start}, nil
+}
+
+// rangeReader exposes the [start, end) byte range of a file reader as if
+// it was a file of its own
+type rangeReader struct {
+	r          coreiface.Reader
+	start, end int64
+	pos        int64
+}
+
+func (rr *rangeReader) Read(p []byte) (int, error) {
+	if rr.pos >= rr.end {
+		return 0, io.EOF
+	}
+	if max := rr.end - rr.pos; int64(len(p)) > max {
+		p = p[:max]
+	}
+	n, err := rr.r.Read(p)
+	rr.pos += int64(n)
+	return n, err
+}
+
+func (rr *rangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += rr.pos - rr.start
+	case io.SeekEnd:
+		offset += rr.end - rr.start
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("seek before start of range")
+	}
+
+	if _, err := rr.r.Seek(rr.start+offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	rr.pos = rr.start + offset
+	return offset, nil
+}
+
+func (rr *rangeReader) Close() error {
+	return rr.r.Close()
 }
 
 func (api *UnixfsAPI) Ls(ctx context.Context, p coreiface.Path) ([]*coreiface.Link, error) {
+	return api.LsPage(ctx, p, coreiface.LsOptions{})
+}
+
+// errPageFull stops the traversal of a directory once the page is full
+var errPageFull = errors.New("page full")
+
+func (api *UnixfsAPI) LsPage(ctx context.Context, p coreiface.Path, opts coreiface.LsOptions) ([]*coreiface.Link, error) {
+	if opts.Offset < 0 || opts.Limit < 0 {
+		return nil, coreiface.ErrInvalidPage
+	}
+
 	dagnode, err := api.core().ResolveNode(ctx, p)
 	if err != nil {
 		return nil, err
 	}
 
-	var ndlinks []*node.Link
+	links := []*coreiface.Link{}
+	skip := opts.Offset
+	add := func(l *node.Link) error {
+		if skip > 0 {
+			skip--
+			return nil
+		}
+		links = append(links, &coreiface.Link{l.Name, l.Size, l.Cid})
+		if opts.Limit > 0 && len(links) == opts.Limit {
+			return errPageFull
+		}
+		return nil
+	}
+
 	dir, err := uio.NewDirectoryFromNode(api.node.DAG, dagnode)
 	switch err {
 	case nil:
-		l, err := dir.Links(ctx)
-		if err != nil {
-			return nil, err
-		}
-		ndlinks = l
+		err = dir.ForEachLink(ctx, add)
 	case uio.ErrNotADir:
-		ndlinks = dagnode.Links()
-	default:
+		err = nil
+		for _, l := range dagnode.Links() {
+			if err = add(l); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil && err != errPageFull {
 		return nil, err
 	}
+	return links, nil
+}
 
-	links := make([]*coreiface.Link, len(ndlinks))
-	for i, l := range ndlinks {
-		links[i] = &coreiface.Link{l.Name, l.Size, l.Cid}
+func (api *UnixfsAPI) dagReader(ctx context.Context, p coreiface.Path) (uio.DagReader, error) {
+	dagnode, err := api.core().ResolveNode(ctx, p)
+	if err != nil {
+		return nil, err
 	}
-	return links, nil
+
+	r, err := uio.NewDagReader(ctx, dagnode, api.node.DAG)
+	if err == uio.ErrIsDir {
+		return nil, coreiface.ErrIsDir
+	} else if err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 func (api *UnixfsAPI) core() coreiface.CoreAPI {
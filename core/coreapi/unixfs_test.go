@@ -3,7 +3,9 @@ package coreapi_test
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 	"testing"
 
@@ -16,7 +18,10 @@ import (
 	config "github.com/ipfs/go-ipfs/repo/config"
 	testutil "github.com/ipfs/go-ipfs/thirdparty/testutil"
 	unixfs "github.com/ipfs/go-ipfs/unixfs"
+	hamt "github.com/ipfs/go-ipfs/unixfs/hamt"
+
 	cbor "gx/ipfs/QmNrbCt8j9DT5W9Pmjy2SdudT9k8GpaDr4sRuFix3BXhgR/go-ipld-cbor"
+	ipld "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
 // `echo -n 'hello, world!' | ipfs add`
@@ -210,6 +215,61 @@ func TestCatOffline(t *testing.T) {
 	}
 }
 
+func TestOpenRange(t *testing.T) {
+	ctx := context.Background()
+	node, api, err := makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := coreunix.Add(node, strings.NewReader(helloStr)); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		opts coreiface.OpenOptions
+		out  string
+	}{
+		{coreiface.OpenOptions{}, helloStr},
+		{coreiface.OpenOptions{Offset: 7}, "world!"},
+		{coreiface.OpenOptions{Offset: 7, Length: 5}, "world"},
+		{coreiface.OpenOptions{Length: 5}, "hello"},
+		{coreiface.OpenOptions{Offset: 7, Length: 100}, "world!"},
+		{coreiface.OpenOptions{Offset: 100}, ""},
+	}
+	for _, c := range cases {
+		r, err := api.Open(ctx, hello, c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != c.out {
+			t.Fatalf("%+v: expected [%s], got [%s]", c.opts, c.out, out)
+		}
+
+		// seeking is relative to the range
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		out, err = ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != c.out {
+			t.Fatalf("%+v: expected [%s] after seek, got [%s]", c.opts, c.out, out)
+		}
+		r.Close()
+	}
+
+	_, err = api.Open(ctx, hello, coreiface.OpenOptions{Offset: -1})
+	if err != coreiface.ErrInvalidRange {
+		t.Fatalf("expected ErrInvalidRange, got: %s", err)
+	}
+}
+
 func TestLs(t *testing.T) {
 	ctx := context.Background()
 	node, api, err := makeAPI(ctx)
@@ -269,6 +329,80 @@ func TestLsEmptyDir(t *testing.T) {
 	}
 }
 
+func TestLsPage(t *testing.T) {
+	ctx := context.Background()
+	node, api, err := makeAPI(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the entries of the sharded directory aren't stored, listing it must
+	// not need them
+	shard, err := hamt.NewHamtShard(node.DAG, 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := unixfs.EmptyDirNode()
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("file%d", i)
+		entry := mdag.NodeWithData(unixfs.FilePBData([]byte(name), uint64(len(name))))
+		if err := dir.AddNodeLinkClean(name, entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := shard.Set(ctx, name, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := node.DAG.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	sharded, err := shard.Node()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, nd := range []ipld.Node{dir, sharded} {
+		p := coreapi.ParseCid(nd.Cid())
+		all, err := api.Ls(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 50 {
+			t.Fatalf("expected 50 links, got %d", len(all))
+		}
+
+		for _, c := range []struct {
+			opts     coreiface.LsOptions
+			from, to int
+		}{
+			{coreiface.LsOptions{}, 0, 50},
+			{coreiface.LsOptions{Limit: 10}, 0, 10},
+			{coreiface.LsOptions{Offset: 45}, 45, 50},
+			{coreiface.LsOptions{Offset: 20, Limit: 10}, 20, 30},
+			{coreiface.LsOptions{Offset: 40, Limit: 20}, 40, 50},
+			{coreiface.LsOptions{Offset: 60, Limit: 10}, 50, 50},
+		} {
+			links, err := api.LsPage(ctx, p, c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(links) != c.to-c.from {
+				t.Fatalf("%+v: expected %d links, got %d", c.opts, c.to-c.from, len(links))
+			}
+			for i, l := range links {
+				if l.Name != all[c.from+i].Name {
+					t.Fatalf("%+v: expected link %s, got %s", c.opts, all[c.from+i].Name, l.Name)
+				}
+			}
+		}
+	}
+
+	_, err = api.LsPage(ctx, coreapi.ParseCid(dir.Cid()), coreiface.LsOptions{Offset: -1})
+	if err != coreiface.ErrInvalidPage {
+		t.Fatalf("expected ErrInvalidPage, got: %s", err)
+	}
+}
+
 // TODO(lgierth) this should test properly, with len(links) > 0
 func TestLsNonUnixfs(t *testing.T) {
 	ctx := context.Background()
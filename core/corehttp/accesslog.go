@@ -0,0 +1,132 @@
+package corehttp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	core "github.com/ipfs/go-ipfs/core"
+)
+
+// AccessLogEntry is a line of the access log of the gateway.
+type AccessLogEntry struct {
+	Time       time.Time
+	Method     string
+	Host       string
+	Path       string
+	Status     int
+	Bytes      int64
+	DurationMs float64
+
+	// Cid is the resolved object served, if any.
+	Cid string `json:",omitempty"`
+
+	// ResolveMs is the time taken to resolve the requested path.
+	ResolveMs float64 `json:",omitempty"`
+
+	// Cache is "revalidated" when the client already had the response,
+	// otherwise "local" or "network" depending on whether the root of an
+	// /ipfs/ path was already in the blockstore.
+	Cache string `json:",omitempty"`
+
+	RemoteAddr   string
+	ForwardedFor string `json:",omitempty"`
+	UserAgent    string `json:",omitempty"`
+	Referer      string `json:",omitempty"`
+}
+
+type accessLogKey struct{}
+
+// accessLogEntry returns the log entry of the request, nil if the request
+// is not logged.
+func accessLogEntry(r *http.Request) *AccessLogEntry {
+	e, _ := r.Context().Value(accessLogKey{}).(*AccessLogEntry)
+	return e
+}
+
+// AccessLogOption writes a JSON line to w for every request handled by the
+// following options. Writes to w must be safe for concurrent use.
+func AccessLogOption(w io.Writer) ServeOption {
+	return func(_ *core.IpfsNode, _ net.Listener, mux *http.ServeMux) (*http.ServeMux, error) {
+		childMux := http.NewServeMux()
+		mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+			e := &AccessLogEntry{
+				Time:         time.Now(),
+				Method:       r.Method,
+				Host:         r.Host,
+				Path:         r.URL.Path,
+				RemoteAddr:   r.RemoteAddr,
+				ForwardedFor: r.Header.Get("X-Forwarded-For"),
+				UserAgent:    r.UserAgent(),
+				Referer:      r.Referer(),
+			}
+
+			lw := &loggedWriter{ResponseWriter: rw}
+			r = r.WithContext(context.WithValue(r.Context(), accessLogKey{}, e))
+			childMux.ServeHTTP(lw, r)
+
+			e.Status = lw.status
+			if e.Status == 0 {
+				e.Status = http.StatusOK
+			}
+			if e.Status == http.StatusNotModified {
+				e.Cache = "revalidated"
+			}
+			e.Bytes = lw.bytes
+			e.DurationMs = msSince(e.Time)
+
+			b, err := json.Marshal(e)
+			if err != nil {
+				log.Error("access log: ", err)
+				return
+			}
+			if _, err := w.Write(append(b, '\n')); err != nil {
+				log.Error("access log: ", err)
+			}
+		})
+		return childMux, nil
+	}
+}
+
+func msSince(t time.Time) float64 {
+	return float64(time.Since(t)) / float64(time.Millisecond)
+}
+
+// loggedWriter records the status and size of a response.
+type loggedWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *loggedWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *loggedWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *loggedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *loggedWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(chan bool)
+}
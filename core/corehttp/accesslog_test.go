@@ -0,0 +1,88 @@
+package corehttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	coreunix "github.com/ipfs/go-ipfs/core/coreunix"
+)
+
+type syncBuffer struct {
+	mu sync.Mutex
+	bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func TestAccessLog(t *testing.T) {
+	n, err := newNodeWithMockNamesys(mockNamesys{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := coreunix.Add(n, strings.NewReader("fnord"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf syncBuffer
+	dh := &delegatedHandler{}
+	ts := httptest.NewServer(dh)
+	defer ts.Close()
+
+	dh.Handler, err = makeHandler(n, ts.Listener,
+		AccessLogOption(&buf),
+		GatewayOption(false, "/ipfs", "/ipns"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(etag string) {
+		r, err := http.NewRequest("GET", ts.URL+"/ipfs/"+k, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("User-Agent", "test-agent")
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	get("")
+	get("\"" + k + "\"")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d", len(lines))
+	}
+
+	var e AccessLogEntry
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Status != 200 || e.Bytes != 5 || e.Cid != k || e.Cache != "local" || e.UserAgent != "test-agent" || e.Path != "/ipfs/"+k {
+		t.Fatalf("unexpected entry %s", lines[0])
+	}
+
+	e = AccessLogEntry{}
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Status != http.StatusNotModified || e.Cache != "revalidated" || e.Bytes != 0 {
+		t.Fatalf("unexpected entry %s", lines[1])
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	core "github.com/ipfs/go-ipfs/core"
 	coreapi "github.com/ipfs/go-ipfs/core/coreapi"
@@ -15,6 +17,16 @@ type GatewayConfig struct {
 	Headers      map[string][]string
 	Writable     bool
 	PathPrefixes []string
+
+	// Budgets of a request, zero for no limit. See config.GatewayTimeouts.
+	ResolveTimeout    time.Duration
+	FirstBlockTimeout time.Duration
+	RequestTimeout    time.Duration
+
+	// content applies to the hosts missing from contentHosts, keyed by
+	// lowercase hostname. See config.GatewayContent.
+	content      contentConfig
+	contentHosts map[string]contentConfig
 }
 
 func GatewayOption(writable bool, paths ...string) ServeOption {
@@ -24,11 +36,47 @@ func GatewayOption(writable bool, paths ...string) ServeOption {
 			return nil, err
 		}
 
-		gateway := newGatewayHandler(n, GatewayConfig{
+		gwCfg := GatewayConfig{
 			Headers:      cfg.Gateway.HTTPHeaders,
 			Writable:     writable,
 			PathPrefixes: cfg.Gateway.PathPrefixes,
-		}, coreapi.NewCoreAPI(n))
+		}
+		timeouts := []struct {
+			name string
+			val  string
+			d    *time.Duration
+		}{
+			{"Resolve", cfg.Gateway.Timeouts.Resolve, &gwCfg.ResolveTimeout},
+			{"FirstBlock", cfg.Gateway.Timeouts.FirstBlock, &gwCfg.FirstBlockTimeout},
+			{"Request", cfg.Gateway.Timeouts.Request, &gwCfg.RequestTimeout},
+		}
+		for _, t := range timeouts {
+			if t.val == "" {
+				continue
+			}
+			d, err := time.ParseDuration(t.val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Gateway.Timeouts.%s: %s", t.name, err)
+			}
+			*t.d = d
+		}
+
+		gwCfg.content, err = newContentConfig(cfg.Gateway.Content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Gateway.Content: %s", err)
+		}
+		if len(cfg.Gateway.ContentHosts) > 0 {
+			gwCfg.contentHosts = make(map[string]contentConfig, len(cfg.Gateway.ContentHosts))
+			for h, c := range cfg.Gateway.ContentHosts {
+				cc, err := newContentConfig(c)
+				if err != nil {
+					return nil, fmt.Errorf("invalid Gateway.ContentHosts[%s]: %s", h, err)
+				}
+				gwCfg.contentHosts[strings.ToLower(h)] = cc
+			}
+		}
+
+		gateway := newGatewayHandler(n, gwCfg, coreapi.NewCoreAPI(n))
 
 		for _, p := range paths {
 			mux.Handle(p+"/", gateway)
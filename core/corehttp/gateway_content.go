@@ -0,0 +1,104 @@
+package corehttp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	gopath "path"
+	"strings"
+	"time"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+	uio "github.com/ipfs/go-ipfs/unixfs/io"
+
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
+)
+
+// defaultIndexFiles are served for a directory when Gateway.Content doesn't
+// name any
+var defaultIndexFiles = []string{"index.html"}
+
+// contentConfig is a validated config.GatewayContent
+type contentConfig struct {
+	indexFiles []string
+
+	// types is keyed by lowercase extensions, with the leading dot
+	types   map[string]string
+	noSniff bool
+}
+
+func newContentConfig(c config.GatewayContent) (contentConfig, error) {
+	cc := contentConfig{
+		indexFiles: defaultIndexFiles,
+		types:      make(map[string]string, len(c.ContentTypes)),
+		noSniff:    c.NoSniff,
+	}
+	if len(c.IndexFiles) > 0 {
+		for _, f := range c.IndexFiles {
+			if f == "" || strings.Contains(f, "/") {
+				return cc, fmt.Errorf("invalid index file %q", f)
+			}
+		}
+		cc.indexFiles = c.IndexFiles
+	}
+	for ext, ctype := range c.ContentTypes {
+		if !strings.HasPrefix(ext, ".") || len(ext) < 2 {
+			return cc, fmt.Errorf("invalid extension %q, expected e.g. \".wasm\"", ext)
+		}
+		if ctype == "" {
+			return cc, fmt.Errorf("empty content type for extension %s", ext)
+		}
+		cc.types[strings.ToLower(ext)] = ctype
+	}
+	return cc, nil
+}
+
+// content returns the content config of the host of r
+func (i *gatewayHandler) content(r *http.Request) contentConfig {
+	if len(i.config.contentHosts) > 0 {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if cc, ok := i.config.contentHosts[strings.ToLower(host)]; ok {
+			return cc
+		}
+	}
+	return i.config.content
+}
+
+// serveContent serves the file name like http.ServeContent, with the
+// content type given by cc
+func serveContent(w http.ResponseWriter, r *http.Request, cc contentConfig, name string, modtime time.Time, content io.ReadSeeker) {
+	if ctype, ok := cc.types[strings.ToLower(gopath.Ext(name))]; ok {
+		w.Header().Set("Content-Type", ctype)
+	} else if cc.noSniff {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	if cc.noSniff {
+		// don't let browsers second guess it either
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	}
+	http.ServeContent(w, r, name, modtime, content)
+}
+
+// findIndex returns the first of files present in dir, and an error
+// satisfying os.IsNotExist when there is none
+func findIndex(ctx context.Context, dir *uio.Directory, files []string) (string, node.Node, error) {
+	if len(files) == 0 {
+		files = defaultIndexFiles
+	}
+	for _, f := range files {
+		nd, err := dir.Find(ctx, f)
+		switch {
+		case err == nil:
+			return f, nd, nil
+		case !os.IsNotExist(err):
+			return "", nil, err
+		}
+	}
+	return "", nil, os.ErrNotExist
+}
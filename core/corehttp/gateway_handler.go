@@ -1,7 +1,9 @@
 package corehttp
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -19,6 +21,7 @@ import (
 	chunk "github.com/ipfs/go-ipfs/importer/chunk"
 	dag "github.com/ipfs/go-ipfs/merkledag"
 	dagutils "github.com/ipfs/go-ipfs/merkledag/utils"
+	namesys "github.com/ipfs/go-ipfs/namesys"
 	path "github.com/ipfs/go-ipfs/path"
 	ft "github.com/ipfs/go-ipfs/unixfs"
 	uio "github.com/ipfs/go-ipfs/unixfs/io"
@@ -63,8 +66,13 @@ func (i *gatewayHandler) newDagFromReader(r io.Reader) (node.Node, error) {
 
 // TODO(btc): break this apart into separate handlers using a more expressive muxer
 func (i *gatewayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(i.node.Context(), time.Hour)
-	// the hour is a hard fallback, we don't expect it to happen, but just in case
+	// without a configured deadline, the hour is a hard fallback, we don't
+	// expect it to happen, but just in case
+	deadline := i.config.RequestTimeout
+	if deadline <= 0 {
+		deadline = time.Hour
+	}
+	ctx, cancel := context.WithTimeout(i.node.Context(), deadline)
 	defer cancel()
 
 	if cn, ok := w.(http.CloseNotifier); ok {
@@ -167,8 +175,28 @@ func (i *gatewayHandler) getOrHeadHandler(ctx context.Context, w http.ResponseWr
 		return
 	}
 
-	// Resolve path to the final DAG node for the ETag
-	resolvedPath, err := i.api.ResolvePath(ctx, parsedPath)
+	// Resolve path to the final DAG node for the ETag. The path may end
+	// inside a dag-cbor node, which is then served as JSON.
+	ipath := path.Path(parsedPath.String())
+	logEntry := accessLogEntry(r)
+	if logEntry != nil {
+		logEntry.Cache = i.cacheStatus(ipath)
+	}
+
+	// the TTL of the ipns records resolved bounds the caching of the response
+	rctx, ttl := namesys.ContextWithTTL(ctx)
+	resolveStart := time.Now()
+	nd, rest, err := i.resolvePath(rctx, ipath)
+	if logEntry != nil {
+		logEntry.ResolveMs = msSince(resolveStart)
+		if err == nil {
+			logEntry.Cid = nd.Cid().String()
+		}
+	}
+	if te, ok := err.(*timeoutError); ok {
+		gatewayTimeoutError(w, urlPath, te)
+		return
+	}
 	switch err {
 	case nil:
 	case coreiface.ErrOffline:
@@ -182,6 +210,17 @@ func (i *gatewayHandler) getOrHeadHandler(ctx context.Context, w http.ResponseWr
 		return
 	}
 
+	if len(rest) > 0 || nd.Cid().Type() == cid.DagCBOR {
+		i.serveDagJSON(w, r, nd, rest, urlPath)
+		return
+	}
+
+	var root *cid.Cid
+	if ipath.IsJustAKey() {
+		root = nd.Cid()
+	}
+	resolvedPath := coreapi.ResolvedPath(ipath.String(), nd.Cid(), root)
+
 	dr, err := i.api.Unixfs().Cat(ctx, resolvedPath)
 	dir := false
 	switch err {
@@ -255,17 +294,14 @@ func (i *gatewayHandler) getOrHeadHandler(ctx context.Context, w http.ResponseWr
 
 		// set modtime to a really long time ago, since files are immutable and should stay cached
 		modtime = time.Unix(1, 0)
+	} else if d, ok := ttl(); ok {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(d/time.Second)))
 	}
 
+	content := i.content(r)
 	if !dir {
 		name := gopath.Base(urlPath)
-		http.ServeContent(w, r, name, modtime, dr)
-		return
-	}
-
-	nd, err := i.api.ResolveNode(ctx, resolvedPath)
-	if err != nil {
-		internalWebError(w, err)
+		serveContent(w, r, content, name, modtime, dr)
 		return
 	}
 
@@ -275,10 +311,10 @@ func (i *gatewayHandler) getOrHeadHandler(ctx context.Context, w http.ResponseWr
 		return
 	}
 
-	ixnd, err := dirr.Find(ctx, "index.html")
+	index, ixnd, err := findIndex(ctx, dirr, content.indexFiles)
 	switch {
 	case err == nil:
-		log.Debugf("found index.html link for %s", urlPath)
+		log.Debugf("found %s link for %s", index, urlPath)
 
 		if urlPath[len(urlPath)-1] != '/' {
 			// See comment above where originalUrlPath is declared.
@@ -295,7 +331,7 @@ func (i *gatewayHandler) getOrHeadHandler(ctx context.Context, w http.ResponseWr
 		defer dr.Close()
 
 		// write to request
-		http.ServeContent(w, r, "index.html", modtime, dr)
+		serveContent(w, r, content, index, modtime, dr)
 		return
 	default:
 		internalWebError(w, err)
@@ -558,6 +594,145 @@ func (i *gatewayHandler) deleteHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, gopath.Join(ipfsPathPrefix+ncid.String(), path.Join(components[:len(components)-1])), http.StatusCreated)
 }
 
+// resolvePath resolves p to its last node, within the budgets of the
+// gateway: the name of an /ipns/ path must be resolved within
+// ResolveTimeout, then the blocks down to the last node fetched within
+// FirstBlockTimeout. A *timeoutError is returned when a budget, or the
+// deadline of the request, runs out.
+func (i *gatewayHandler) resolvePath(ctx context.Context, p path.Path) (node.Node, []string, error) {
+	rctx, cancel := withTimeout(ctx, i.config.ResolveTimeout)
+	defer cancel()
+	p, err := core.ResolveIPNS(rctx, i.node.Namesys, p)
+	if err == core.ErrNoNamesys {
+		return nil, nil, coreiface.ErrOffline
+	} else if err != nil {
+		return nil, nil, i.checkTimeout(ctx, rctx, "resolve-timeout", i.config.ResolveTimeout, err)
+	}
+
+	// the rest of the path is resolved like 'ipfs resolve' and 'dag get' do
+	ip, err := coreapi.ParsePath(p.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	bctx, cancel := withTimeout(ctx, i.config.FirstBlockTimeout)
+	defer cancel()
+	nd, rest, err := i.api.ResolveToLastNode(bctx, ip)
+	if err != nil {
+		return nil, nil, i.checkTimeout(ctx, bctx, "first-block-timeout", i.config.FirstBlockTimeout, err)
+	}
+	return nd, rest, nil
+}
+
+// checkTimeout turns err into a *timeoutError if it is due to the budget of
+// sub running out, or to the deadline of the request ctx.
+func (i *gatewayHandler) checkTimeout(ctx, sub context.Context, code string, budget time.Duration, err error) error {
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		d := i.config.RequestTimeout
+		if d <= 0 {
+			d = time.Hour
+		}
+		return &timeoutError{Code: "request-timeout", Timeout: d}
+	case ctx.Err() == nil && sub.Err() == context.DeadlineExceeded:
+		return &timeoutError{Code: code, Timeout: budget}
+	}
+	return err
+}
+
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// timeoutError is returned when a budget of the gateway runs out.
+type timeoutError struct {
+	Code    string
+	Timeout time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%s after %s", e.Code, e.Timeout)
+}
+
+// gatewayTimeoutError answers with a 504 and a JSON body describing the
+// budget that ran out.
+func gatewayTimeoutError(w http.ResponseWriter, urlPath string, e *timeoutError) {
+	log.Errorf("%s: %s", urlPath, e)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusGatewayTimeout)
+	json.NewEncoder(w).Encode(struct {
+		Message string
+		Code    string
+		Path    string
+		Timeout string
+	}{
+		Message: "gave up on " + urlPath + " after " + e.Timeout.String(),
+		Code:    e.Code,
+		Path:    urlPath,
+		Timeout: e.Timeout.String(),
+	})
+}
+
+// cacheStatus tells whether the root of an /ipfs/ path is stored locally,
+// for the access log.
+func (i *gatewayHandler) cacheStatus(p path.Path) string {
+	segs := p.Segments()
+	if len(segs) < 2 || segs[0] != "ipfs" {
+		return ""
+	}
+	c, err := cid.Decode(segs[1])
+	if err != nil {
+		return ""
+	}
+
+	if has, err := i.node.Blockstore.Has(c); err == nil && has {
+		return "local"
+	}
+	return "network"
+}
+
+// serveDagJSON serves a dag-cbor node, or the value inside it rest points
+// to, as JSON
+func (i *gatewayHandler) serveDagJSON(w http.ResponseWriter, r *http.Request, nd node.Node, rest []string, urlPath string) {
+	var val interface{} = nd
+	if len(rest) > 0 {
+		v, _, err := nd.Resolve(rest)
+		if err != nil {
+			webError(w, "ipfs dag get "+urlPath, err, http.StatusNotFound)
+			return
+		}
+		val = v
+	}
+
+	out, err := json.Marshal(val)
+	if err != nil {
+		internalWebError(w, err)
+		return
+	}
+
+	etag := "\"" + gopath.Join(append([]string{nd.Cid().String()}, rest...)...) + "\""
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	i.addUserHeaders(w)
+	w.Header().Set("X-IPFS-Path", urlPath)
+	w.Header().Set("Etag", etag)
+	w.Header().Set("Content-Type", "application/json")
+
+	modtime := time.Now()
+	if strings.HasPrefix(urlPath, ipfsPathPrefix) {
+		w.Header().Set("Cache-Control", "public, max-age=29030400, immutable")
+		modtime = time.Unix(1, 0)
+	}
+
+	http.ServeContent(w, r, "", modtime, bytes.NewReader(out))
+}
+
 func (i *gatewayHandler) addUserHeaders(w http.ResponseWriter) {
 	for k, v := range i.config.Headers {
 		w.Header()[k] = v
@@ -18,6 +18,8 @@ import (
 	repo "github.com/ipfs/go-ipfs/repo"
 	config "github.com/ipfs/go-ipfs/repo/config"
 	testutil "github.com/ipfs/go-ipfs/thirdparty/testutil"
+	ft "github.com/ipfs/go-ipfs/unixfs"
+	uio "github.com/ipfs/go-ipfs/unixfs/io"
 
 	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
 	id "gx/ipfs/QmQA5mdxru8Bh6dpC9PJfSkumqnmHgJX7knxSgBo5Lpime/go-libp2p/p2p/protocol/identify"
@@ -490,6 +492,108 @@ func TestCacheControlImmutable(t *testing.T) {
 	}
 }
 
+func TestGatewayContent(t *testing.T) {
+	n, err := newNodeWithMockNamesys(mockNamesys{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Gateway.Content = config.GatewayContent{
+		IndexFiles:   []string{"default.htm", "index.html"},
+		ContentTypes: map[string]string{".WASM": "application/wasm"},
+	}
+	cfg.Gateway.ContentHosts = map[string]config.GatewayContent{
+		"raw.example.com": {NoSniff: true},
+	}
+
+	dh := &delegatedHandler{}
+	ts := httptest.NewServer(dh)
+	defer ts.Close()
+	dh.Handler, err = makeHandler(n, ts.Listener, GatewayOption(false, "/ipfs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// create a directory with both index files and a wasm file
+	ctx := context.Background()
+	dir := uio.NewDirectory(n.DAG)
+	for name, data := range map[string]string{
+		"index.html":  "<p>index</p>",
+		"default.htm": "<p>default</p>",
+		"app.wasm":    "\x00asm",
+	} {
+		nd := dag.NodeWithData(ft.FilePBData([]byte(data), uint64(len(data))))
+		if _, err := n.DAG.Add(nd); err != nil {
+			t.Fatal(err)
+		}
+		if err := dir.AddChild(ctx, name, nd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirnd, err := dir.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.DAG.Add(dirnd); err != nil {
+		t.Fatal(err)
+	}
+	root := "/ipfs/" + dirnd.Cid().String()
+
+	for _, test := range []struct {
+		host  string
+		path  string
+		ctype string
+		body  string
+	}{
+		{"localhost", root + "/", "text/html; charset=utf-8", "<p>default</p>"},
+		{"localhost", root + "/index.html", "text/html; charset=utf-8", "<p>index</p>"},
+		{"localhost", root + "/app.wasm", "application/wasm", "\x00asm"},
+		{"raw.example.com", root + "/index.html", "application/octet-stream", "<p>index</p>"},
+		{"raw.example.com:8080", root + "/", "application/octet-stream", "<p>index</p>"},
+	} {
+		req, err := http.NewRequest("GET", ts.URL+test.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = test.host
+		res, err := doWithoutRedirect(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%s%s: status is %d", test.host, test.path, res.StatusCode)
+			continue
+		}
+		if ctype := res.Header.Get("Content-Type"); ctype != test.ctype {
+			t.Errorf("%s%s: Content-Type is %q, expected %q", test.host, test.path, ctype, test.ctype)
+		}
+		if string(body) != test.body {
+			t.Errorf("%s%s: body is %q, expected %q", test.host, test.path, body, test.body)
+		}
+	}
+}
+
+func TestGatewayContentInvalid(t *testing.T) {
+	for _, c := range []config.GatewayContent{
+		{IndexFiles: []string{"sub/index.html"}},
+		{ContentTypes: map[string]string{"wasm": "application/wasm"}},
+		{ContentTypes: map[string]string{".wasm": ""}},
+	} {
+		if _, err := newContentConfig(c); err == nil {
+			t.Errorf("expected %+v to be invalid", c)
+		}
+	}
+}
+
 func TestVersion(t *testing.T) {
 	config.CurrentCommit = "theshortcommithash"
 
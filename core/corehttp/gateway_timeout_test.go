@@ -0,0 +1,71 @@
+package corehttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	coreapi "github.com/ipfs/go-ipfs/core/coreapi"
+	path "github.com/ipfs/go-ipfs/path"
+
+	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+)
+
+// hangingNamesys never resolves a name.
+type hangingNamesys struct{}
+
+func (hangingNamesys) Resolve(ctx context.Context, name string) (path.Path, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (ns hangingNamesys) ResolveN(ctx context.Context, name string, depth int) (path.Path, error) {
+	return ns.Resolve(ctx, name)
+}
+
+func (hangingNamesys) Publish(ctx context.Context, name ci.PrivKey, value path.Path) error {
+	return errors.New("not implemented for hangingNamesys")
+}
+
+func (hangingNamesys) PublishWithEOL(ctx context.Context, name ci.PrivKey, value path.Path, _ time.Time) error {
+	return errors.New("not implemented for hangingNamesys")
+}
+
+func TestGatewayTimeouts(t *testing.T) {
+	n, err := newNodeWithMockNamesys(mockNamesys{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Namesys = hangingNamesys{}
+
+	for _, test := range []struct {
+		cfg  GatewayConfig
+		code string
+	}{
+		{GatewayConfig{ResolveTimeout: 50 * time.Millisecond}, "resolve-timeout"},
+		{GatewayConfig{ResolveTimeout: time.Minute, RequestTimeout: 50 * time.Millisecond}, "request-timeout"},
+	} {
+		h := newGatewayHandler(n, test.cfg, coreapi.NewCoreAPI(n))
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/ipns/example.com", nil))
+
+		if w.Code != http.StatusGatewayTimeout {
+			t.Fatalf("got %d, expected %d", w.Code, http.StatusGatewayTimeout)
+		}
+		var body struct {
+			Code string
+			Path string
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Code != test.code || body.Path != "/ipns/example.com" {
+			t.Fatalf("unexpected body %s", w.Body.String())
+		}
+	}
+}
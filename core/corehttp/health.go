@@ -0,0 +1,139 @@
+package corehttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	core "github.com/ipfs/go-ipfs/core"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+)
+
+// healthCheckKey is looked up in the datastore to make sure the repo is
+// still readable. It does not need to exist.
+var healthCheckKey = ds.NewKey("/local/healthcheck")
+
+// HealthCheck is the result of a single check reported by the health
+// endpoints.
+type HealthCheck struct {
+	OK      bool
+	Message string `json:",omitempty"`
+}
+
+// HealthStatus is the body served by /livez and /readyz.
+type HealthStatus struct {
+	OK     bool
+	Checks map[string]HealthCheck
+}
+
+// HealthOption adds the /livez and /readyz endpoints. /livez answers as long
+// as the node is running, /readyz only once it is able to serve requests:
+// the repo is readable, the swarm is listening and the node is connected to
+// the network. Both reply 200 when healthy and 503 otherwise.
+func HealthOption() ServeOption {
+	return func(n *core.IpfsNode, _ net.Listener, mux *http.ServeMux) (*http.ServeMux, error) {
+		mux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+			writeHealth(w, LiveStatus(n))
+		})
+		mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+			writeHealth(w, ReadyStatus(n))
+		})
+		return mux, nil
+	}
+}
+
+// LiveStatus reports whether the node is still running.
+func LiveStatus(n *core.IpfsNode) HealthStatus {
+	return newHealthStatus(map[string]HealthCheck{
+		"node": checkRunning(n),
+	})
+}
+
+// ReadyStatus reports whether the node is ready to serve requests.
+func ReadyStatus(n *core.IpfsNode) HealthStatus {
+	return newHealthStatus(map[string]HealthCheck{
+		"node":      checkRunning(n),
+		"repo":      checkRepo(n),
+		"swarm":     checkSwarm(n),
+		"bootstrap": checkBootstrap(n),
+	})
+}
+
+func newHealthStatus(checks map[string]HealthCheck) HealthStatus {
+	st := HealthStatus{OK: true, Checks: checks}
+	for _, c := range checks {
+		if !c.OK {
+			st.OK = false
+		}
+	}
+	return st
+}
+
+func writeHealth(w http.ResponseWriter, st HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if st.OK {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(st)
+}
+
+func checkRunning(n *core.IpfsNode) HealthCheck {
+	select {
+	case <-n.Process().Closing():
+		return HealthCheck{Message: "node is shutting down"}
+	default:
+		return HealthCheck{OK: true}
+	}
+}
+
+func checkRepo(n *core.IpfsNode) HealthCheck {
+	if n.Repo == nil {
+		return HealthCheck{Message: "no repo"}
+	}
+	if _, err := n.Repo.Datastore().Has(healthCheckKey); err != nil {
+		return HealthCheck{Message: fmt.Sprintf("datastore: %s", err)}
+	}
+	return HealthCheck{OK: true}
+}
+
+func checkSwarm(n *core.IpfsNode) HealthCheck {
+	if !n.OnlineMode() {
+		return HealthCheck{OK: true, Message: "offline"}
+	}
+	if n.PeerHost == nil {
+		return HealthCheck{Message: "no host"}
+	}
+	addrs := n.PeerHost.Network().ListenAddresses()
+	if len(addrs) == 0 {
+		return HealthCheck{Message: "not listening on any address"}
+	}
+	return HealthCheck{OK: true, Message: fmt.Sprintf("listening on %d addresses", len(addrs))}
+}
+
+func checkBootstrap(n *core.IpfsNode) HealthCheck {
+	if !n.OnlineMode() {
+		return HealthCheck{OK: true, Message: "offline"}
+	}
+	if n.Bootstrapper == nil || n.PeerHost == nil {
+		return HealthCheck{Message: "not bootstrapped"}
+	}
+
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		return HealthCheck{Message: fmt.Sprintf("config: %s", err)}
+	}
+	if len(cfg.Bootstrap) == 0 {
+		return HealthCheck{OK: true, Message: "no bootstrap peers configured"}
+	}
+
+	peers := len(n.PeerHost.Network().Peers())
+	if peers == 0 {
+		return HealthCheck{Message: "not connected to any peer"}
+	}
+	return HealthCheck{OK: true, Message: fmt.Sprintf("connected to %d peers", peers)}
+}
@@ -0,0 +1,58 @@
+package corehttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	n, err := newNodeWithMockNamesys(mockNamesys{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dh := &delegatedHandler{}
+	ts := httptest.NewServer(dh)
+	defer ts.Close()
+
+	dh.Handler, err = makeHandler(n, ts.Listener, HealthOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) (int, HealthStatus) {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		var st HealthStatus
+		if err := json.NewDecoder(res.Body).Decode(&st); err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, st
+	}
+
+	for _, p := range []string{"/livez", "/readyz"} {
+		code, st := get(p)
+		if code != http.StatusOK || !st.OK {
+			t.Fatalf("%s: expected a healthy offline node, got %d %+v", p, code, st)
+		}
+	}
+
+	if _, st := get("/readyz"); len(st.Checks) != 4 {
+		t.Fatalf("expected 4 readiness checks, got %+v", st.Checks)
+	}
+
+	n.Close()
+
+	for _, p := range []string{"/livez", "/readyz"} {
+		code, st := get(p)
+		if code != http.StatusServiceUnavailable || st.OK || st.Checks["node"].OK {
+			t.Fatalf("%s: expected a closed node to be unhealthy, got %d %+v", p, code, st)
+		}
+	}
+}
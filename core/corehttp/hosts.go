@@ -0,0 +1,184 @@
+package corehttp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	core "github.com/ipfs/go-ipfs/core"
+	path "github.com/ipfs/go-ipfs/path"
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
+// GatewayHostsOption serves a website on each of the given hosts: either
+// the configured path of the host or, by default, its dnslink record. The
+// requests for other hosts are answered with a 404. It must come before
+// GatewayOption.
+func GatewayHostsOption(hosts map[string]config.GatewayHost) ServeOption {
+	roots := make(map[string]string, len(hosts))
+	for h, hc := range hosts {
+		root := "/ipns/" + h
+		if hc.Path != "" {
+			p, err := path.ParsePath(hc.Path)
+			if err != nil {
+				return errorOption(fmt.Errorf("invalid path for host %s: %s", h, err))
+			}
+			root = p.String()
+		}
+		roots[strings.ToLower(h)] = strings.TrimSuffix(root, "/")
+	}
+
+	return func(n *core.IpfsNode, _ net.Listener, mux *http.ServeMux) (*http.ServeMux, error) {
+		childMux := http.NewServeMux()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+
+			root, ok := roots[strings.ToLower(host)]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+
+			r.Header["X-Ipns-Original-Path"] = []string{r.URL.Path}
+			r.URL.Path = root + r.URL.Path
+			childMux.ServeHTTP(w, r)
+		})
+		return childMux, nil
+	}
+}
+
+func errorOption(err error) ServeOption {
+	return func(*core.IpfsNode, net.Listener, *http.ServeMux) (*http.ServeMux, error) {
+		return nil, err
+	}
+}
+
+// DefaultACMECacheDir is the directory, relative to the repo, where the
+// ACME account key and certificates are kept unless configured otherwise.
+const DefaultACMECacheDir = "acme"
+
+// ACMEALPNProto is the ALPN protocol of the tls-alpn-01 challenges.
+const ACMEALPNProto = "acme-tls/1"
+
+// CertManager obtains certificates on demand, e.g. from an ACME certificate
+// authority. It also answers the tls-alpn-01 challenges of the authority.
+type CertManager interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+// ACMEHosts returns the hosts whose certificates are obtained with ACME,
+// those without CertFile, lower cased. It returns nil when ACME is disabled.
+func ACMEHosts(c config.GatewayACME, hosts map[string]config.GatewayHost) []string {
+	if !c.Enabled {
+		return nil
+	}
+	var names []string
+	for h, hc := range hosts {
+		if hc.CertFile == "" && hc.KeyFile == "" {
+			names = append(names, strings.ToLower(h))
+		}
+	}
+	return names
+}
+
+// HostsTLSConfig returns a TLS config presenting the certificate of each
+// of hosts, chosen with SNI. The certificates of the hosts with CertFile
+// are read once, then again whenever their files are modified; the others
+// are obtained from m, which also answers the tls-alpn-01 challenges of the
+// certificate authority. Handshakes for other hosts fail.
+func HostsTLSConfig(hosts map[string]config.GatewayHost, m CertManager) (*tls.Config, error) {
+	certs := make(map[string]*hostCert, len(hosts))
+	for h, hc := range hosts {
+		if hc.CertFile == "" && hc.KeyFile == "" {
+			if m == nil {
+				return nil, fmt.Errorf("no certificate for %s: set its CertFile and KeyFile, or enable Gateway.TLS.ACME", h)
+			}
+			continue
+		}
+		c := &hostCert{certFile: hc.CertFile, keyFile: hc.KeyFile}
+		if _, err := c.get(); err != nil {
+			return nil, fmt.Errorf("loading the certificate of %s: %s", h, err)
+		}
+		certs[strings.ToLower(h)] = c
+	}
+
+	tlsCfg := &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			c, ok := certs[strings.ToLower(hello.ServerName)]
+			if ok {
+				return c.get()
+			}
+			if m != nil {
+				return m.GetCertificate(hello)
+			}
+			return nil, fmt.Errorf("no certificate for %q", hello.ServerName)
+		},
+	}
+	if m != nil {
+		tlsCfg.NextProtos = []string{"http/1.1", ACMEALPNProto}
+	}
+	return tlsCfg, nil
+}
+
+// hostCert is a certificate loaded from disk.
+type hostCert struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// get returns the certificate, reloading it if its files changed. The last
+// certificate loaded is kept if a reload fails, e.g. while the files are
+// being replaced.
+func (c *hostCert) get() (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	mt, err := c.lastModified()
+	if err != nil {
+		if c.cert != nil {
+			return c.cert, nil
+		}
+		return nil, err
+	}
+	if c.cert != nil && !mt.After(c.modTime) {
+		return c.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		if c.cert != nil {
+			log.Errorf("reloading %s: %s", c.certFile, err)
+			c.modTime = mt
+			return c.cert, nil
+		}
+		return nil, err
+	}
+	c.cert = &cert
+	c.modTime = mt
+	return c.cert, nil
+}
+
+func (c *hostCert) lastModified() (time.Time, error) {
+	var last time.Time
+	for _, f := range []string{c.certFile, c.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if fi.ModTime().After(last) {
+			last = fi.ModTime()
+		}
+	}
+	return last, nil
+}
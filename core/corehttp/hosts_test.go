@@ -0,0 +1,237 @@
+package corehttp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	coreunix "github.com/ipfs/go-ipfs/core/coreunix"
+	path "github.com/ipfs/go-ipfs/path"
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
+func TestGatewayHosts(t *testing.T) {
+	ns := mockNamesys{}
+	n, err := newNodeWithMockNamesys(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := coreunix.Add(n, strings.NewReader("fnord"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns["/ipns/example.com"] = path.FromString("/ipfs/" + k)
+
+	hosts := map[string]config.GatewayHost{
+		"example.com": {},
+		"example.net": {Path: "/ipfs/" + k},
+	}
+
+	dh := &delegatedHandler{}
+	ts := httptest.NewServer(dh)
+	defer ts.Close()
+
+	dh.Handler, err = makeHandler(n, ts.Listener,
+		GatewayHostsOption(hosts),
+		GatewayOption(false, "/ipfs", "/ipns"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		host   string
+		status int
+	}{
+		{"example.com", http.StatusOK},
+		{"EXAMPLE.com:443", http.StatusOK},
+		{"example.net", http.StatusOK},
+		{"example.org", http.StatusNotFound},
+	} {
+		r, err := http.NewRequest("GET", ts.URL+"/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Host = test.host
+
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != test.status {
+			t.Errorf("got %d, expected %d for %s", resp.StatusCode, test.status, test.host)
+			continue
+		}
+		if test.status == http.StatusOK && string(body) != "fnord" {
+			t.Errorf("unexpected body %q for %s", body, test.host)
+		}
+	}
+
+	_, err = makeHandler(n, ts.Listener, GatewayHostsOption(map[string]config.GatewayHost{
+		"example.com": {Path: "not-a-path"},
+	}))
+	if err == nil {
+		t.Fatal("expected an error for an invalid host path")
+	}
+}
+
+// writeCert writes a self signed certificate for host to dir.
+func writeCert(t *testing.T, dir, host string) config.GatewayHost {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hc := config.GatewayHost{
+		CertFile: filepath.Join(dir, host+".crt"),
+		KeyFile:  filepath.Join(dir, host+".key"),
+	}
+	err = ioutil.WriteFile(hc.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(hc.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hc
+}
+
+func TestHostsTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gateway-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hosts := map[string]config.GatewayHost{
+		"example.com": writeCert(t, dir, "example.com"),
+		"example.net": writeCert(t, dir, "example.net"),
+	}
+	tlsCfg, err := HostsTLSConfig(hosts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.TLS = tlsCfg
+	ts.StartTLS()
+	defer ts.Close()
+
+	addr := ts.Listener.Addr().String()
+	for _, host := range []string{"example.com", "example.net"} {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := conn.ConnectionState().PeerCertificates[0].DNSNames
+		conn.Close()
+		if len(names) != 1 || names[0] != host {
+			t.Errorf("got certificate for %v, expected %s", names, host)
+		}
+	}
+
+	if _, err := tls.Dial("tcp", addr, &tls.Config{ServerName: "example.org", InsecureSkipVerify: true}); err == nil {
+		t.Fatal("handshake for an unknown host succeeded")
+	}
+
+	hosts["example.org"] = config.GatewayHost{CertFile: filepath.Join(dir, "missing.crt")}
+	if _, err := HostsTLSConfig(hosts, nil); err == nil {
+		t.Fatal("expected an error for a missing certificate")
+	}
+}
+
+type testCertManager struct {
+	cert  *tls.Certificate
+	hosts []string
+}
+
+func (m *testCertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.hosts = append(m.hosts, hello.ServerName)
+	return m.cert, nil
+}
+
+func TestHostsTLSConfigACME(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gateway-acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hosts := map[string]config.GatewayHost{
+		"example.com": writeCert(t, dir, "example.com"),
+		"Example.net": {},
+	}
+	if _, err := HostsTLSConfig(hosts, nil); err == nil {
+		t.Fatal("expected an error for a host without certificate and ACME disabled")
+	}
+	if names := ACMEHosts(config.GatewayACME{}, hosts); names != nil {
+		t.Fatalf("expected no ACME hosts when ACME is disabled, got %v", names)
+	}
+	names := ACMEHosts(config.GatewayACME{Enabled: true}, hosts)
+	if len(names) != 1 || names[0] != "example.net" {
+		t.Fatalf("expected example.net to use ACME, got %v", names)
+	}
+
+	hc := writeCert(t, dir, "example.net")
+	cert, err := tls.LoadX509KeyPair(hc.CertFile, hc.KeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &testCertManager{cert: &cert}
+	tlsCfg, err := HostsTLSConfig(hosts, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tlsCfg.NextProtos) != 2 || tlsCfg.NextProtos[1] != ACMEALPNProto {
+		t.Fatalf("expected the tls-alpn-01 protocol to be offered, got %v", tlsCfg.NextProtos)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.TLS = tlsCfg
+	ts.StartTLS()
+	defer ts.Close()
+
+	addr := ts.Listener.Addr().String()
+	for _, host := range []string{"example.com", "example.net"} {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+	if len(m.hosts) != 1 || m.hosts[0] != "example.net" {
+		t.Fatalf("expected only example.net to be asked to the manager, got %v", m.hosts)
+	}
+}
@@ -1,5 +1,13 @@
 package corehttp
 
+import (
+	"fmt"
+	"strings"
+
+	path "github.com/ipfs/go-ipfs/path"
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
 // TODO: move to IPNS
 const WebUIPath = "/ipfs/QmPhnvn747LqwPYMJmQVorMaGbMSgA7mRRoyyZYz3DoZRQ"
 
@@ -18,3 +26,21 @@ var WebUIPaths = []string{
 }
 
 var WebUIOption = RedirectOption("webui", WebUIPath)
+
+// WebUIPathFromConfig returns the path of the WebUI set in API.WebUI.Path,
+// or WebUIPath when none is set. Only immutable /ipfs paths are accepted, so
+// that the version served can't change behind the operator's back.
+func WebUIPathFromConfig(cfg *config.Config) (string, error) {
+	if cfg.API.WebUI.Path == "" {
+		return WebUIPath, nil
+	}
+
+	p, err := path.ParsePath(cfg.API.WebUI.Path)
+	if err != nil {
+		return "", fmt.Errorf("invalid API.WebUI.Path %q: %s", cfg.API.WebUI.Path, err)
+	}
+	if !strings.HasPrefix(p.String(), "/ipfs/") {
+		return "", fmt.Errorf("API.WebUI.Path must be an /ipfs path, got %q", p)
+	}
+	return strings.TrimSuffix(p.String(), "/"), nil
+}
@@ -0,0 +1,41 @@
+package corehttp
+
+import (
+	"testing"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
+func TestWebUIPathFromConfig(t *testing.T) {
+	const c = "QmXX7YRpU7nNBKfw75VG7Y1c3GwpSAGHRev67XVPgZFv9R"
+
+	cases := []struct {
+		path string
+		out  string
+		err  bool
+	}{
+		{"", WebUIPath, false},
+		{c, "/ipfs/" + c, false},
+		{"/ipfs/" + c, "/ipfs/" + c, false},
+		{"/ipfs/" + c + "/", "/ipfs/" + c, false},
+		{"/ipns/webui.ipfs.io", "", true},
+		{"not a path", "", true},
+	}
+	for _, tc := range cases {
+		cfg := &config.Config{API: config.API{WebUI: config.WebUI{Path: tc.path}}}
+		out, err := WebUIPathFromConfig(cfg)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", tc.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", tc.path, err)
+			continue
+		}
+		if out != tc.out {
+			t.Errorf("%q: expected %s, got %s", tc.path, tc.out, out)
+		}
+	}
+}
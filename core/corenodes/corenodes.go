@@ -0,0 +1,321 @@
+/*
+Package corenodes runs several isolated ipfs nodes in a single process.
+
+Each node managed by a Manager has its own repo, identity and swarm. The
+manager refuses to start two nodes that would step on each other: nodes
+sharing a repo, a peer identity or a fixed swarm listen address. All nodes
+share the lifetime of the manager, and are closed along with it.
+
+Some state in go-ipfs is still process wide and is therefore shared by all
+nodes: prometheus metrics, and the experimental flags of the unixfs
+importer (e.g. uio.UseHAMTSharding).
+*/
+package corenodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	core "github.com/ipfs/go-ipfs/core"
+	keystore "github.com/ipfs/go-ipfs/keystore"
+	repo "github.com/ipfs/go-ipfs/repo"
+	config "github.com/ipfs/go-ipfs/repo/config"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dsync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
+)
+
+var (
+	// ErrClosed is returned when using a manager after Close.
+	ErrClosed = errors.New("node manager is closed")
+
+	// ErrNotFound is returned when there is no node with the given name.
+	ErrNotFound = errors.New("no node with this name")
+)
+
+// Manager constructs and keeps track of named nodes.
+type Manager struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	closed bool
+	nodes  map[string]*entry
+}
+
+type entry struct {
+	// node is nil while the node is being constructed
+	node     *core.IpfsNode
+	repoPath string
+	peerID   string
+	swarm    []string
+}
+
+// NewManager returns a manager whose nodes live until ctx is done or the
+// manager is closed.
+func NewManager(ctx context.Context) *Manager {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Manager{
+		ctx:    ctx,
+		cancel: cancel,
+		nodes:  make(map[string]*entry),
+	}
+}
+
+// NewNode constructs a node named name. cfg.Repo must be set and must not
+// be used by any other node; see NewMemRepo for a repo suitable for tests.
+func (m *Manager) NewNode(name string, cfg *core.BuildCfg) (*core.IpfsNode, error) {
+	return m.newNode(name, "", cfg)
+}
+
+// OpenNode opens the repo at repoPath and constructs a node named name on
+// it. cfg may be nil; its Repo is ignored. The repo is closed with the node.
+func (m *Manager) OpenNode(name, repoPath string, cfg *core.BuildCfg) (*core.IpfsNode, error) {
+	var c core.BuildCfg
+	if cfg != nil {
+		c = *cfg
+	}
+	c.Repo = nil
+	return m.newNode(name, filepath.Clean(repoPath), &c)
+}
+
+func (m *Manager) newNode(name, repoPath string, cfg *core.BuildCfg) (*core.IpfsNode, error) {
+	if name == "" {
+		return nil, errors.New("node name cannot be empty")
+	}
+	if cfg == nil || (cfg.Repo == nil && repoPath == "") {
+		return nil, errors.New("a repo is required to construct a node")
+	}
+
+	e := &entry{repoPath: repoPath}
+	if err := m.reserve(name, e); err != nil {
+		return nil, err
+	}
+
+	n, err := m.construct(e, cfg)
+	if err != nil {
+		m.release(name)
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		delete(m.nodes, name)
+		n.Close()
+		return nil, ErrClosed
+	}
+	e.node = n
+	return n, nil
+}
+
+// construct opens the repo of e if needed, checks that it does not clash
+// with any other node and builds the node.
+func (m *Manager) construct(e *entry, cfg *core.BuildCfg) (*core.IpfsNode, error) {
+	if e.repoPath != "" {
+		r, err := fsrepo.Open(e.repoPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Repo = r
+	}
+
+	rcfg, err := cfg.Repo.Config()
+	if err != nil {
+		cfg.Repo.Close()
+		return nil, err
+	}
+	e.peerID = rcfg.Identity.PeerID
+	if cfg.Online {
+		e.swarm = fixedAddrs(rcfg.Addresses.Swarm)
+	}
+
+	if err := m.checkConflicts(e); err != nil {
+		cfg.Repo.Close()
+		return nil, err
+	}
+
+	return core.NewNode(m.ctx, cfg)
+}
+
+func (m *Manager) reserve(name string, e *entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if _, ok := m.nodes[name]; ok {
+		return fmt.Errorf("a node named %q already exists", name)
+	}
+	if e.repoPath != "" {
+		for other, o := range m.nodes {
+			if o.repoPath == e.repoPath {
+				return fmt.Errorf("repo %s is already used by node %q", e.repoPath, other)
+			}
+		}
+	}
+	m.nodes[name] = e
+	return nil
+}
+
+func (m *Manager) release(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.nodes, name)
+}
+
+func (m *Manager) checkConflicts(e *entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, o := range m.nodes {
+		if o == e {
+			continue
+		}
+		if e.peerID != "" && o.peerID == e.peerID {
+			return fmt.Errorf("peer id %s is already used by node %q", e.peerID, name)
+		}
+		for _, a := range e.swarm {
+			for _, b := range o.swarm {
+				if a == b {
+					return fmt.Errorf("swarm address %s is already used by node %q", a, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Node returns the node named name.
+func (m *Manager) Node(name string) (*core.IpfsNode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.nodes[name]
+	if !ok || e.node == nil {
+		return nil, ErrNotFound
+	}
+	return e.node, nil
+}
+
+// Names returns the names of all nodes, sorted.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.nodes))
+	for name, e := range m.nodes {
+		if e.node != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CloseNode closes the node named name and forgets about it, freeing its
+// name, repo, identity and swarm addresses for use by a new node.
+func (m *Manager) CloseNode(name string) error {
+	m.mu.Lock()
+	e, ok := m.nodes[name]
+	if !ok || e.node == nil {
+		m.mu.Unlock()
+		return ErrNotFound
+	}
+	delete(m.nodes, name)
+	m.mu.Unlock()
+
+	return e.node.Close()
+}
+
+// Close closes all nodes. The manager cannot be used afterwards.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrClosed
+	}
+	m.closed = true
+	var nodes []*core.IpfsNode
+	for _, e := range m.nodes {
+		if e.node != nil {
+			nodes = append(nodes, e.node)
+		}
+	}
+	m.nodes = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for _, n := range nodes {
+		if err := n.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	m.cancel()
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// NewMemRepo returns an in-memory repo with a fresh identity. Nodes built
+// on it listen on a random port, have no bootstrap peers and do not look
+// for peers on the local network, so they only connect to the peers they
+// are told about.
+func NewMemRepo(nBitsForKeypair int) (repo.Repo, error) {
+	c, err := config.Init(ioutil.Discard, nBitsForKeypair)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Addresses.Swarm = []string{"/ip4/127.0.0.1/tcp/0"}
+	c.Addresses.API = ""
+	c.Addresses.Gateway = ""
+	c.Bootstrap = nil
+	c.Discovery.MDNS.Enabled = false
+
+	return &memRepo{&repo.Mock{
+		C: *c,
+		D: dsync.MutexWrap(ds.NewMapDatastore()),
+		K: keystore.NewMemKeystore(),
+	}}, nil
+}
+
+// memRepo is a mock repo that can be closed without error, as nodes close
+// their repo on shutdown.
+type memRepo struct {
+	*repo.Mock
+}
+
+func (r *memRepo) Close() error {
+	return nil
+}
+
+// fixedAddrs returns the addresses that do not ask for a random port, and
+// so cannot be listened on by two nodes at once.
+func fixedAddrs(addrs []string) []string {
+	var fixed []string
+	for _, a := range addrs {
+		parts := strings.Split(a, "/")
+		random := false
+		for i := 0; i+1 < len(parts); i++ {
+			if (parts[i] == "tcp" || parts[i] == "udp") && parts[i+1] == "0" {
+				random = true
+				break
+			}
+		}
+		if !random {
+			fixed = append(fixed, a)
+		}
+	}
+	return fixed
+}
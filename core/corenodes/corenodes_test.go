@@ -0,0 +1,128 @@
+package corenodes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	core "github.com/ipfs/go-ipfs/core"
+)
+
+func newMemNode(t *testing.T, m *Manager, name string) *core.IpfsNode {
+	r, err := NewMemRepo(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := m.NewNode(name, &core.BuildCfg{Repo: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestManagerNodes(t *testing.T) {
+	m := NewManager(context.Background())
+	defer m.Close()
+
+	a := newMemNode(t, m, "a")
+	b := newMemNode(t, m, "b")
+	if a.Identity == b.Identity {
+		t.Fatal("expected nodes to have distinct identities")
+	}
+
+	if names := m.Names(); !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	if n, err := m.Node("a"); err != nil || n != a {
+		t.Fatalf("expected node a, got %v, %v", n, err)
+	}
+
+	r, err := NewMemRepo(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.NewNode("a", &core.BuildCfg{Repo: r}); err == nil {
+		t.Fatal("expected an error for a duplicate name")
+	}
+
+	if err := m.CloseNode("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Node("a"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := m.CloseNode("a"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// the name is free again
+	newMemNode(t, m, "a")
+}
+
+func TestManagerConflicts(t *testing.T) {
+	m := NewManager(context.Background())
+	defer m.Close()
+
+	r, err := NewMemRepo(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.NewNode("a", &core.BuildCfg{Repo: r}); err != nil {
+		t.Fatal(err)
+	}
+
+	// same identity
+	r2, err := NewMemRepo(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg2, err := r2.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg2.Identity = cfg.Identity
+	if _, err := m.NewNode("b", &core.BuildCfg{Repo: r2}); err == nil {
+		t.Fatal("expected an error for a duplicate peer id")
+	}
+	if len(m.Names()) != 1 {
+		t.Fatalf("failed node should not be registered: %v", m.Names())
+	}
+}
+
+func TestManagerClose(t *testing.T) {
+	m := NewManager(context.Background())
+	newMemNode(t, m, "a")
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+
+	r, err := NewMemRepo(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.NewNode("b", &core.BuildCfg{Repo: r}); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestFixedAddrs(t *testing.T) {
+	addrs := []string{
+		"/ip4/0.0.0.0/tcp/4001",
+		"/ip4/127.0.0.1/tcp/0",
+		"/ip6/::/tcp/4001",
+		"/ip4/0.0.0.0/udp/0/utp",
+	}
+	expected := []string{"/ip4/0.0.0.0/tcp/4001", "/ip6/::/tcp/4001"}
+	if got := fixedAddrs(addrs); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
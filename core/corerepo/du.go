@@ -0,0 +1,234 @@
+package corerepo
+
+import (
+	"context"
+	"sort"
+
+	bstore "github.com/ipfs/go-ipfs/blocks/blockstore"
+	core "github.com/ipfs/go-ipfs/core"
+	dag "github.com/ipfs/go-ipfs/merkledag"
+	gc "github.com/ipfs/go-ipfs/pin/gc"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dsq "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/query"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
+)
+
+// DiskUsage attributes the size of the blocks in the repo to what keeps
+// them from being garbage collected. Each block is counted once, in the
+// first of pins, pin index, MFS and unpinned it belongs to.
+type DiskUsage struct {
+	// Blocks is the size of all the blocks in the blockstore
+	Blocks uint64
+	// Pinned is the size of the blocks under a recursive or direct pin
+	Pinned uint64
+	// PinIndex is the size of the blocks storing the pin sets
+	PinIndex uint64
+	// MFS is the size of the blocks only kept by the MFS root
+	MFS uint64
+	// Unpinned is the size of the blocks the next gc would remove
+	Unpinned uint64
+
+	// Pins are the largest pins, by the size of the local blocks under them
+	Pins []PinUsage
+	// Namespaces is the size of the datastore, by top level namespace
+	Namespaces []NamespaceUsage
+}
+
+// PinUsage is the disk usage of a pin
+type PinUsage struct {
+	Cid  *cid.Cid
+	Type string
+	// Size is the size of the local blocks under the pin
+	Size uint64
+	// Unique is the size of the blocks no other pin references, which is
+	// roughly what removing the pin would let gc reclaim
+	Unique uint64
+}
+
+// NamespaceUsage is the disk usage of a datastore namespace
+type NamespaceUsage struct {
+	Namespace string
+	Keys      uint64
+	Size      uint64
+}
+
+// RepoDiskUsage computes the disk usage of the repo. Only the top pins are
+// returned, all of them when top is not positive. Blocks missing from the
+// repo are skipped, nothing is fetched from the network.
+func RepoDiskUsage(n *core.IpfsNode, ctx context.Context, top int) (*DiskUsage, error) {
+	du := new(DiskUsage)
+
+	sizes, err := blockSizes(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sizes {
+		du.Blocks += s
+	}
+
+	ls := n.DAG.GetOfflineLinkService()
+	getLinks := func(ctx context.Context, c *cid.Cid) ([]*node.Link, error) {
+		links, err := ls.GetLinks(ctx, c)
+		if err == dag.ErrNotFound {
+			return nil, nil
+		}
+		return links, err
+	}
+
+	// walk every pin, counting how many pins reference each block
+	var pins []PinUsage
+	var pinSets []*cid.Set
+	refs := make(map[string]int)
+	for _, c := range n.Pinning.RecursiveKeys() {
+		set := cid.NewSet()
+		if err := gc.Descendants(ctx, getLinks, set, []*cid.Cid{c}); err != nil {
+			return nil, err
+		}
+		pins = append(pins, PinUsage{Cid: c, Type: "recursive"})
+		pinSets = append(pinSets, set)
+	}
+	for _, c := range n.Pinning.DirectKeys() {
+		set := cid.NewSet()
+		set.Add(c)
+		pins = append(pins, PinUsage{Cid: c, Type: "direct"})
+		pinSets = append(pinSets, set)
+	}
+
+	counted := cid.NewSet()
+	for _, set := range pinSets {
+		for _, k := range set.Keys() {
+			refs[k.KeyString()]++
+			if counted.Visit(k) {
+				du.Pinned += sizes[k.KeyString()]
+			}
+		}
+	}
+	for i, set := range pinSets {
+		for _, k := range set.Keys() {
+			s := sizes[k.KeyString()]
+			pins[i].Size += s
+			if refs[k.KeyString()] == 1 {
+				pins[i].Unique += s
+			}
+		}
+	}
+
+	index := cid.NewSet()
+	if err := gc.Descendants(ctx, getLinks, index, n.Pinning.InternalPins()); err != nil {
+		return nil, err
+	}
+	for _, k := range index.Keys() {
+		if counted.Visit(k) {
+			du.PinIndex += sizes[k.KeyString()]
+		}
+	}
+
+	roots, err := BestEffortRoots(n.FilesRoot)
+	if err != nil {
+		return nil, err
+	}
+	mfs := cid.NewSet()
+	if err := gc.Descendants(ctx, getLinks, mfs, roots); err != nil {
+		return nil, err
+	}
+	for _, k := range mfs.Keys() {
+		if counted.Visit(k) {
+			du.MFS += sizes[k.KeyString()]
+		}
+	}
+
+	// blocks referenced but missing locally have no size, so this can't
+	// underflow
+	du.Unpinned = du.Blocks - du.Pinned - du.PinIndex - du.MFS
+
+	sort.Stable(pinsBySize(pins))
+	if top > 0 && len(pins) > top {
+		pins = pins[:top]
+	}
+	du.Pins = pins
+
+	du.Namespaces, err = namespaceUsage(n.Repo.Datastore(), NamespaceUsage{
+		Namespace: bstore.BlockPrefix.String(),
+		Keys:      uint64(len(sizes)),
+		Size:      du.Blocks,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return du, nil
+}
+
+// blockSizes returns the size of every block in the blockstore, by key
+func blockSizes(ctx context.Context, n *core.IpfsNode) (map[string]uint64, error) {
+	keys, err := n.Blockstore.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]uint64)
+	for c := range keys {
+		b, err := n.Blockstore.Get(c)
+		if err != nil {
+			// removed since it was listed
+			continue
+		}
+		sizes[c.KeyString()] = uint64(len(b.RawData()))
+	}
+	return sizes, ctx.Err()
+}
+
+// namespaceUsage sums the size of the values of the datastore by top level
+// namespace. The usage of the blocks namespace is passed in: it is already
+// known, and it usually lives in a separate mount the query does not reach.
+func namespaceUsage(d ds.Datastore, blocks NamespaceUsage) ([]NamespaceUsage, error) {
+	qr, err := d.Query(dsq.Query{})
+	if err != nil {
+		return nil, err
+	}
+	defer qr.Close()
+
+	byName := map[string]*NamespaceUsage{blocks.Namespace: &blocks}
+	names := []string{blocks.Namespace}
+	for {
+		e, ok := qr.NextSync()
+		if !ok {
+			break
+		}
+		if e.Error != nil {
+			return nil, e.Error
+		}
+
+		name := "/"
+		if l := ds.RawKey(e.Key).List(); len(l) > 1 {
+			name = "/" + l[0]
+		}
+		if name == blocks.Namespace {
+			continue
+		}
+		nu, ok := byName[name]
+		if !ok {
+			nu = &NamespaceUsage{Namespace: name}
+			byName[name] = nu
+			names = append(names, name)
+		}
+		nu.Keys++
+		if v, ok := e.Value.([]byte); ok {
+			nu.Size += uint64(len(v))
+		}
+	}
+
+	sort.Strings(names)
+	out := make([]NamespaceUsage, len(names))
+	for i, name := range names {
+		out[i] = *byName[name]
+	}
+	return out, nil
+}
+
+type pinsBySize []PinUsage
+
+func (p pinsBySize) Len() int           { return len(p) }
+func (p pinsBySize) Less(i, j int) bool { return p[i].Size > p[j].Size }
+func (p pinsBySize) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
@@ -86,7 +86,7 @@ func GarbageCollect(n *core.IpfsNode, ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	rmed := gc.GC(ctx, n.Blockstore, n.DAG, n.Pinning, roots)
+	rmed := gc.GCWithLeases(ctx, n.Blockstore, n.DAG, n.Pinning, roots, n.GCLeases)
 
 	return CollectResult(ctx, rmed, nil)
 }
@@ -154,7 +154,7 @@ func GarbageCollectAsync(n *core.IpfsNode, ctx context.Context) <-chan gc.Result
 		return out
 	}
 
-	return gc.GC(ctx, n.Blockstore, n.DAG, n.Pinning, roots)
+	return gc.GCWithLeases(ctx, n.Blockstore, n.DAG, n.Pinning, roots, n.GCLeases)
 }
 
 func PeriodicGC(ctx context.Context, node *core.IpfsNode) error {
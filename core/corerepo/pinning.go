@@ -18,14 +18,24 @@ import (
 	"fmt"
 
 	"github.com/ipfs/go-ipfs/core"
+	hooks "github.com/ipfs/go-ipfs/core/hooks"
 	path "github.com/ipfs/go-ipfs/path"
+	pin "github.com/ipfs/go-ipfs/pin"
 
 	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
-	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
 func Pin(n *core.IpfsNode, ctx context.Context, paths []string, recursive bool) ([]*cid.Cid, error) {
-	dagnodes := make([]node.Node, 0)
+	return PinWithAllocations(n, ctx, paths, recursive, nil)
+}
+
+// PinWithAllocations pins the given paths like Pin, and records the given
+// allocations (e.g. the cluster peers also pinning them) for every pin.
+// The node's hooks see the allocations as part of the pin intent. The paths
+// are all pinned, or none is: a veto of the hooks or a failure to pin one of
+// them leaves the pins as they were.
+func PinWithAllocations(n *core.IpfsNode, ctx context.Context, paths []string, recursive bool, allocations []string) ([]*cid.Cid, error) {
+	var intents []*pin.Intent
 	for _, fpath := range paths {
 		p, err := path.ParsePath(fpath)
 		if err != nil {
@@ -36,33 +46,47 @@ func Pin(n *core.IpfsNode, ctx context.Context, paths []string, recursive bool)
 		if err != nil {
 			return nil, fmt.Errorf("pin: %s", err)
 		}
-		dagnodes = append(dagnodes, dagnode)
+		intents = append(intents, &pin.Intent{
+			Op:          pin.IntentPin,
+			Cid:         dagnode.Cid(),
+			Recursive:   recursive,
+			Allocations: allocations,
+		})
 	}
 
-	var out []*cid.Cid
-	for _, dagnode := range dagnodes {
-		c := dagnode.Cid()
-
-		ctx, cancel := context.WithCancel(ctx)
-		defer cancel()
-		err := n.Pinning.Pin(ctx, dagnode, recursive)
-		if err != nil {
-			return nil, fmt.Errorf("pin: %s", err)
+	err := runHooks(n, ctx, hooks.OpPinAdd, intents, func() error {
+		txn := n.Pinning.Txn()
+		for _, in := range intents {
+			txn.Pin(in.Cid, recursive)
 		}
-		out = append(out, c)
-	}
-
-	err := n.Pinning.Flush()
+		if err := txn.Commit(ctx); err != nil {
+			return err
+		}
+		if len(allocations) == 0 {
+			return nil
+		}
+		for _, in := range intents {
+			if err := pin.SetAllocations(n.Repo.Datastore(), in.Cid, allocations); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("pin: %s", err)
 	}
 
+	out := make([]*cid.Cid, len(intents))
+	for i, in := range intents {
+		out[i] = in.Cid
+	}
 	return out, nil
 }
 
+// Unpin removes the pins of the given paths. They are all removed, or none
+// is.
 func Unpin(n *core.IpfsNode, ctx context.Context, paths []string, recursive bool) ([]*cid.Cid, error) {
-
-	var unpinned []*cid.Cid
+	var intents []*pin.Intent
 	for _, p := range paths {
 		p, err := path.ParsePath(p)
 		if err != nil {
@@ -74,18 +98,73 @@ func Unpin(n *core.IpfsNode, ctx context.Context, paths []string, recursive bool
 			return nil, err
 		}
 
-		ctx, cancel := context.WithCancel(ctx)
-		defer cancel()
-		err = n.Pinning.Unpin(ctx, k, recursive)
+		in := &pin.Intent{
+			Op:        pin.IntentUnpin,
+			Cid:       k,
+			Recursive: recursive,
+		}
+		in.Allocations, err = pin.Allocations(n.Repo.Datastore(), k)
 		if err != nil {
 			return nil, err
 		}
-		unpinned = append(unpinned, k)
+		intents = append(intents, in)
 	}
 
-	err := n.Pinning.Flush()
+	err := runHooks(n, ctx, hooks.OpPinRm, intents, func() error {
+		txn := n.Pinning.Txn()
+		for _, in := range intents {
+			txn.Unpin(in.Cid, recursive)
+		}
+		if err := txn.Commit(ctx); err != nil {
+			return err
+		}
+		for _, in := range intents {
+			if err := pin.SetAllocations(n.Repo.Datastore(), in.Cid, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+
+	unpinned := make([]*cid.Cid, len(intents))
+	for i, in := range intents {
+		unpinned[i] = in.Cid
+	}
 	return unpinned, nil
 }
+
+// Update replaces the recursive pin of from with one of to, like
+// pin.Pinner.Update, and flushes the pinner.
+func Update(n *core.IpfsNode, ctx context.Context, from, to *cid.Cid, unpin bool) error {
+	intent := &pin.Intent{
+		Op:        pin.IntentPin,
+		Cid:       to,
+		Recursive: true,
+		From:      from,
+	}
+	return runHooks(n, ctx, hooks.OpPinUpdate, []*pin.Intent{intent}, func() error {
+		if err := n.Pinning.Update(ctx, from, to, unpin); err != nil {
+			return err
+		}
+		return n.Pinning.Flush()
+	})
+}
+
+// runHooks runs the hooks of n for op before and after apply, for each of
+// intents. apply is only called if no hook vetoes any of the intents, and
+// must have flushed the pins when it returns.
+func runHooks(n *core.IpfsNode, ctx context.Context, op string, intents []*pin.Intent, apply func() error) error {
+	for _, in := range intents {
+		if err := n.Hooks.Pre(ctx, op, in); err != nil {
+			return err
+		}
+	}
+	err := apply()
+	for _, in := range intents {
+		n.Hooks.Post(ctx, op, in, nil, err)
+	}
+	return err
+}
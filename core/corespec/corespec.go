@@ -0,0 +1,377 @@
+/*
+Package corespec converges a node to a declarative spec file.
+
+A spec describes the state a node must be in: config values, keys imported
+into the keystore, pinned paths, peers to connect to and names to publish.
+It is applied in two steps. Apply writes the config and the keys to the repo,
+before the node is constructed from it. Converge then adds the missing pins,
+connects to the peers and publishes the names once the node runs. The node
+reconnects to the peers of the spec as long as it runs, and republishes its
+names like its own.
+
+Applying a spec is idempotent, and only adds state: pins, keys and config
+values not listed in the spec are left alone. Names already published with
+the path of the spec are not published again.
+
+Spec files are JSON documents.
+*/
+package corespec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	core "github.com/ipfs/go-ipfs/core"
+	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
+	hooks "github.com/ipfs/go-ipfs/core/hooks"
+	keystore "github.com/ipfs/go-ipfs/keystore"
+	namesys "github.com/ipfs/go-ipfs/namesys"
+	path "github.com/ipfs/go-ipfs/path"
+	repo "github.com/ipfs/go-ipfs/repo"
+	common "github.com/ipfs/go-ipfs/repo/common"
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+var log = logging.Logger("core/spec")
+
+// selfKey names the node's identity key in Names
+const selfKey = "self"
+
+// peeringInterval is the time between two checks of the connections to the
+// peers of a spec
+const peeringInterval = time.Minute
+
+// Spec is the state a node is converged to.
+type Spec struct {
+	// Config maps config keys, as given to 'ipfs config', to their values
+	Config map[string]interface{}
+
+	// Keys maps keystore names to the files holding the keys, in the format
+	// of the keystore. Relative paths are relative to the spec file.
+	Keys map[string]string
+
+	// Pins lists the paths pinned recursively
+	Pins []string
+
+	// Peering lists the addresses of the peers connected to, ending with
+	// their peer ID
+	Peering []string
+
+	// Names maps key names, or "self", to the paths published with them. The
+	// keys are those of Keys or already in the keystore.
+	Names map[string]string
+
+	// dir is the directory of the spec file
+	dir string
+}
+
+// Load reads and validates the spec file at p, a JSON document.
+func Load(p string) (*Spec, error) {
+	data, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	s := new(Spec)
+	if err := dec.Decode(s); err != nil {
+		return nil, fmt.Errorf("spec %s: %s (spec files are JSON documents)", p, err)
+	}
+	s.dir = filepath.Dir(p)
+
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("spec %s: %s", p, err)
+	}
+	return s, nil
+}
+
+func (s *Spec) validate() error {
+	for k := range s.Config {
+		if k == "" {
+			return errors.New("empty config key")
+		}
+		if k == config.IdentityTag || strings.HasPrefix(k, config.IdentityTag+".") {
+			return fmt.Errorf("config key %s: the identity can't be set by a spec", k)
+		}
+	}
+	for name := range s.Keys {
+		if name == selfKey {
+			return errors.New("the key 'self' can't be imported")
+		}
+	}
+	for _, p := range s.Pins {
+		if _, err := path.ParsePath(p); err != nil {
+			return fmt.Errorf("pin %s: %s", p, err)
+		}
+	}
+	if _, err := config.ParseBootstrapPeers(s.Peering); err != nil {
+		return fmt.Errorf("peering: %s", err)
+	}
+	for name, p := range s.Names {
+		if _, err := path.ParsePath(p); err != nil {
+			return fmt.Errorf("name %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Apply sets the config values of the spec in r and imports its keys into
+// the keystore of r, running the key/gen hooks of the config for them. It
+// must run before a node is constructed from r.
+func (s *Spec) Apply(r repo.Repo) error {
+	if err := s.applyConfig(r); err != nil {
+		return err
+	}
+
+	// no node runs yet, the hooks are the ones of the config
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+	hs, err := hooks.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	return s.importKeys(r.Keystore(), hs)
+}
+
+func (s *Spec) applyConfig(r repo.Repo) error {
+	if len(s.Config) == 0 {
+		return nil
+	}
+
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+	cur, err := config.ToMap(cfg)
+	if err != nil {
+		return err
+	}
+	m, err := config.ToMap(cfg)
+	if err != nil {
+		return err
+	}
+
+	// set the keys in order, so that a key set after one of its parents
+	// overrides it
+	keys := make([]string, 0, len(s.Config))
+	for k := range s.Config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := common.MapSetKV(m, k, s.Config[k]); err != nil {
+			return fmt.Errorf("config key %s: %s", k, err)
+		}
+	}
+
+	updated, err := config.FromMap(m)
+	if err != nil {
+		return err
+	}
+	// compare the round tripped values, the spec's numbers aren't typed
+	if norm, err := config.ToMap(updated); err == nil && reflect.DeepEqual(norm, cur) {
+		return nil
+	}
+	log.Infof("updating the config from the spec: %s", strings.Join(keys, ", "))
+	return r.SetConfig(updated)
+}
+
+// keyImportHookArgs are the arguments of the key/gen hooks run for the keys
+// imported from a spec
+type keyImportHookArgs struct {
+	Name string
+	Id   string
+
+	// File is the file the key is imported from
+	File string
+}
+
+func (s *Spec) importKeys(ks keystore.Keystore, hs *hooks.Hooks) error {
+	for name, file := range s.Keys {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(s.dir, file)
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("key %s: %s", name, err)
+		}
+		sk, err := ci.UnmarshalPrivateKey(data)
+		if err != nil {
+			return fmt.Errorf("key %s: %s", name, err)
+		}
+
+		old, err := ks.Get(name)
+		switch err {
+		case nil:
+			if !old.Equals(sk) {
+				return fmt.Errorf("key %s: the keystore holds another key by that name", name)
+			}
+			continue
+		case keystore.ErrNoSuchKey:
+		default:
+			return fmt.Errorf("key %s: %s", name, err)
+		}
+
+		id, err := peer.IDFromPrivateKey(sk)
+		if err != nil {
+			return fmt.Errorf("key %s: %s", name, err)
+		}
+		hookArgs := &keyImportHookArgs{Name: name, Id: id.Pretty(), File: file}
+		if err := hs.Pre(context.Background(), hooks.OpKeyGen, hookArgs); err != nil {
+			return fmt.Errorf("key %s: %s", name, err)
+		}
+
+		log.Infof("importing key %s from the spec", name)
+		err = ks.Put(name, sk)
+		hs.Post(context.Background(), hooks.OpKeyGen, hookArgs, nil, err)
+		if err != nil {
+			return fmt.Errorf("key %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Converge connects n to the peers of the spec, pins its paths and
+// publishes its names. It carries on after a failure, and returns the
+// first error. The node keeps reconnecting to the peers of the spec until
+// ctx is done or the node closes.
+func (s *Spec) Converge(ctx context.Context, n *core.IpfsNode) error {
+	var first error
+	fail := func(err error) {
+		log.Error(err)
+		if first == nil {
+			first = err
+		}
+	}
+
+	if len(s.Peering) > 0 {
+		if n.PeerHost == nil {
+			fail(errors.New("spec peering: the node is offline"))
+		} else {
+			peers, errs := s.connect(ctx, n)
+			for _, err := range errs {
+				fail(err)
+			}
+			go keepPeering(ctx, n, peers)
+		}
+	}
+
+	for _, p := range s.Pins {
+		if _, err := corerepo.Pin(n, ctx, []string{p}, true); err != nil {
+			fail(fmt.Errorf("spec pin %s: %s", p, err))
+		}
+	}
+
+	names := make([]string, 0, len(s.Names))
+	for name := range s.Names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := s.publish(ctx, n, name); err != nil {
+			fail(fmt.Errorf("spec name %s: %s", name, err))
+		}
+	}
+
+	return first
+}
+
+// connect connects n to the peers of the spec, and returns them
+func (s *Spec) connect(ctx context.Context, n *core.IpfsNode) ([]pstore.PeerInfo, []error) {
+	peers, err := config.ParseBootstrapPeers(s.Peering)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var pis []pstore.PeerInfo
+	var errs []error
+	for _, p := range peers {
+		pi := pstore.PeerInfo{ID: p.ID(), Addrs: []ma.Multiaddr{p.Transport()}}
+		n.Peerstore.AddAddrs(pi.ID, pi.Addrs, pstore.PermanentAddrTTL)
+		n.ProtectPeer(pi.ID)
+		if err := n.PeerHost.Connect(ctx, pi); err != nil {
+			errs = append(errs, fmt.Errorf("spec peering %s: %s", pi.ID.Pretty(), err))
+		}
+		pis = append(pis, pi)
+	}
+	return pis, errs
+}
+
+// keepPeering reconnects n to the peers it got disconnected from, until ctx
+// is done or n closes
+func keepPeering(ctx context.Context, n *core.IpfsNode, peers []pstore.PeerInfo) {
+	tick := time.NewTicker(peeringInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			for _, pi := range peers {
+				if n.PeerHost.Network().Connectedness(pi.ID) == inet.Connected {
+					continue
+				}
+				if err := n.PeerHost.Connect(ctx, pi); err != nil {
+					log.Debugf("spec peering %s: %s", pi.ID.Pretty(), err)
+				}
+			}
+		case <-ctx.Done():
+			return
+		case <-n.Process().Closing():
+			return
+		}
+	}
+}
+
+func (s *Spec) publish(ctx context.Context, n *core.IpfsNode, name string) error {
+	if n.Namesys == nil {
+		return errors.New("the node has no name system")
+	}
+	sk, err := n.GetKey(name)
+	if err != nil {
+		return err
+	}
+
+	p, err := path.ParsePath(s.Names[name])
+	if err != nil {
+		return err
+	}
+	id, err := peer.IDFromPrivateKey(sk)
+	if err != nil {
+		return err
+	}
+
+	// the republisher keeps the names of the spec alive like the node's
+	// own, publishing the same path again would only bump the sequence
+	// number of the record
+	if n.IpnsRepub != nil {
+		n.Peerstore.AddPrivKey(id, sk)
+		n.Peerstore.AddPubKey(id, sk.GetPublic())
+		n.IpnsRepub.AddName(id)
+	}
+	rec, err := namesys.GetLocalRecord(n.Repo.Datastore(), id)
+	if err == nil && path.Path(rec.Entry.GetValue()) == p {
+		if eol, ok := rec.EOL(); !ok || eol.After(n.Clock().Now()) {
+			log.Debugf("name %s already published with %s", name, p)
+			return nil
+		}
+	}
+	return n.Namesys.Publish(ctx, sk, p)
+}
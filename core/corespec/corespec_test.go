@@ -0,0 +1,208 @@
+package corespec
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	core "github.com/ipfs/go-ipfs/core"
+	hooks "github.com/ipfs/go-ipfs/core/hooks"
+	coremock "github.com/ipfs/go-ipfs/core/mock"
+	keystore "github.com/ipfs/go-ipfs/keystore"
+	dag "github.com/ipfs/go-ipfs/merkledag"
+	namesys "github.com/ipfs/go-ipfs/namesys"
+	repo "github.com/ipfs/go-ipfs/repo"
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+)
+
+func writeSpec(t *testing.T, dir, spec string) string {
+	p := filepath.Join(dir, "spec.json")
+	if err := ioutil.WriteFile(p, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func writeKey(t *testing.T, p string) ci.PrivKey {
+	sk, _, err := ci.GenerateKeyPair(ci.RSA, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := sk.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return sk
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "corespec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, spec := range []string{
+		`{"Config": {"Identity.PeerID": "foo"}}`,
+		`{"Keys": {"self": "self.key"}}`,
+		`{"Pins": ["not a path"]}`,
+		`{"Peering": ["/ip4/1.2.3.4/tcp/4001"]}`,
+		`{"Names": {"self": "not a path"}}`,
+		`{"Pins": "/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"}`,
+	} {
+		if _, err := Load(writeSpec(t, dir, spec)); err == nil {
+			t.Errorf("expected spec %s to be invalid", spec)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	dir, err := ioutil.TempDir("", "corespec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sk := writeKey(t, filepath.Join(dir, "site.key"))
+	s, err := Load(writeSpec(t, dir, `{
+		"Config": {
+			"Datastore.StorageMax": "50GB",
+			"Datastore.StorageGCWatermark": 80,
+			"Gateway.Writable": true
+		},
+		"Keys": {"site": "site.key"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &repo.Mock{K: keystore.NewMemKeystore()}
+	r.C.Identity.PeerID = "QmId"
+	if err := s.Apply(r); err != nil {
+		t.Fatal(err)
+	}
+	if r.C.Datastore.StorageMax != "50GB" || r.C.Datastore.StorageGCWatermark != 80 || !r.C.Gateway.Writable {
+		t.Fatalf("config not applied: %+v", r.C.Datastore)
+	}
+	if r.C.Identity.PeerID != "QmId" {
+		t.Fatal("expected the other config values to be kept")
+	}
+	if k, err := r.K.Get("site"); err != nil || !k.Equals(sk) {
+		t.Fatalf("key not imported: %v", err)
+	}
+
+	// applying again changes nothing
+	if err := s.Apply(r); err != nil {
+		t.Fatal(err)
+	}
+
+	// a different key by the same name is an error
+	writeKey(t, filepath.Join(dir, "site.key"))
+	if err := s.Apply(r); err == nil {
+		t.Fatal("expected a conflicting key to fail")
+	}
+}
+
+func TestApplyKeyHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "corespec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeKey(t, filepath.Join(dir, "site.key"))
+	s, err := Load(writeSpec(t, dir, `{"Keys": {"site": "site.key"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &repo.Mock{K: keystore.NewMemKeystore()}
+	r.C.Hooks = []config.Hook{{
+		Ops:  []string{hooks.OpKeyGen},
+		Exec: []string{"sh", "-c", "echo keys are managed centrally >&2; exit 1"},
+	}}
+	err = s.Apply(r)
+	if err == nil || !strings.Contains(err.Error(), "keys are managed centrally") {
+		t.Fatalf("expected the import to be vetoed, got %v", err)
+	}
+	if _, err := r.K.Get("site"); err != keystore.ErrNoSuchKey {
+		t.Fatalf("expected the key not to be imported, got %v", err)
+	}
+}
+
+func TestConvergePins(t *testing.T) {
+	n, err := coremock.NewMockNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	nd := dag.NodeWithData([]byte("spec"))
+	c, err := n.DAG.Add(nd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Spec{Pins: []string{"/ipfs/" + c.String()}}
+	if err := s.validate(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Converge(context.Background(), n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mode, pinned, err := n.Pinning.IsPinned(c); err != nil || !pinned || mode != "recursive" {
+		t.Fatalf("expected %s to be pinned recursively, got %s %t %v", c, mode, pinned, err)
+	}
+}
+
+func TestConvergeNames(t *testing.T) {
+	ctx := context.Background()
+	n, err := core.NewNode(ctx, &core.BuildCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	if err := n.SetupOfflineRouting(); err != nil {
+		t.Fatal(err)
+	}
+
+	seq := func() uint64 {
+		rec, err := namesys.GetLocalRecord(n.Repo.Datastore(), n.Identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rec.Entry.GetSequence()
+	}
+
+	s := &Spec{Names: map[string]string{"self": "/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"}}
+	if err := s.Converge(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	first := seq()
+
+	// the name already points to the path of the spec
+	if err := s.Converge(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	if seq() != first {
+		t.Fatal("expected an up to date name not to be published again")
+	}
+
+	s.Names["self"] = "/ipfs/QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN"
+	if err := s.Converge(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	if seq() <= first {
+		t.Fatal("expected a changed name to be published")
+	}
+}
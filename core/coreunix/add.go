@@ -7,12 +7,14 @@ import (
 	"io/ioutil"
 	"os"
 	gopath "path"
+	"time"
 
 	bs "github.com/ipfs/go-ipfs/blocks/blockstore"
 	bstore "github.com/ipfs/go-ipfs/blocks/blockstore"
 	bserv "github.com/ipfs/go-ipfs/blockservice"
 	"github.com/ipfs/go-ipfs/commands/files"
 	core "github.com/ipfs/go-ipfs/core"
+	hooks "github.com/ipfs/go-ipfs/core/hooks"
 	"github.com/ipfs/go-ipfs/exchange/offline"
 	balanced "github.com/ipfs/go-ipfs/importer/balanced"
 	"github.com/ipfs/go-ipfs/importer/chunk"
@@ -23,6 +25,7 @@ import (
 	"github.com/ipfs/go-ipfs/pin"
 	posinfo "github.com/ipfs/go-ipfs/thirdparty/posinfo"
 	unixfs "github.com/ipfs/go-ipfs/unixfs"
+	uio "github.com/ipfs/go-ipfs/unixfs/io"
 
 	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
 	syncds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
@@ -82,6 +85,8 @@ func NewAdder(ctx context.Context, p pin.Pinner, bs bstore.GCBlockstore, ds dag.
 		Trickle:    false,
 		Wrap:       false,
 		Chunker:    "",
+
+		ShardingSize: uio.HAMTShardingSize,
 	}, nil
 }
 
@@ -101,12 +106,25 @@ type Adder struct {
 	Wrap       bool
 	NoCopy     bool
 	Chunker    string
-	root       node.Node
-	mroot      *mfs.Root
-	unlocker   bs.Unlocker
-	tempRoot   *cid.Cid
-	Prefix     *cid.Prefix
-	liveNodes  uint64
+
+	// PreserveMode and PreserveMtime record the permission bits and the
+	// modification time of added files and directories in their nodes.
+	PreserveMode  bool
+	PreserveMtime bool
+
+	// ShardingSize is the size above which the added directories are
+	// sharded, see uio.Directory.SetShardingSize
+	ShardingSize int
+
+	// Hooks are run around the pin of the root by PinRoot
+	Hooks *hooks.Hooks
+
+	root      node.Node
+	mroot     *mfs.Root
+	unlocker  bs.Unlocker
+	tempRoot  *cid.Cid
+	Prefix    *cid.Prefix
+	liveNodes uint64
 }
 
 func (adder *Adder) mfsRoot() (*mfs.Root, error) {
@@ -116,10 +134,11 @@ func (adder *Adder) mfsRoot() (*mfs.Root, error) {
 	rnode := unixfs.EmptyDirNode()
 	rnode.SetPrefix(adder.Prefix)
 	mr, err := mfs.NewRoot(adder.ctx, adder.dagService, rnode, nil)
-	mr.Prefix = adder.Prefix
 	if err != nil {
 		return nil, err
 	}
+	mr.Prefix = adder.Prefix
+	mr.GetValue().(*mfs.Directory).SetShardingSize(adder.ShardingSize)
 	adder.mroot = mr
 	return adder.mroot, nil
 }
@@ -179,6 +198,8 @@ func (adder *Adder) RootNode() (node.Node, error) {
 	return root, err
 }
 
+// PinRoot pins the root of the added files recursively, if Pin is set,
+// between the pin/add hooks.
 func (adder *Adder) PinRoot() error {
 	root, err := adder.RootNode()
 	if err != nil {
@@ -188,6 +209,17 @@ func (adder *Adder) PinRoot() error {
 		return nil
 	}
 
+	intent := &pin.Intent{Op: pin.IntentPin, Cid: root.Cid(), Recursive: true}
+	if err := adder.Hooks.Pre(adder.ctx, hooks.OpPinAdd, intent); err != nil {
+		return err
+	}
+	err = adder.pinRoot(root)
+	adder.Hooks.Post(adder.ctx, hooks.OpPinAdd, intent, nil, err)
+	return err
+}
+
+// pinRoot pins root, replacing the temporary pin of a previous root
+func (adder *Adder) pinRoot(root node.Node) error {
 	rnk, err := adder.dagService.Add(root)
 	if err != nil {
 		return err
@@ -390,7 +422,7 @@ func (adder *Adder) addNode(node node.Node, path string) error {
 	}
 	dir := gopath.Dir(path)
 	if dir != "." {
-		if err := mfs.Mkdir(mr, dir, true, false); err != nil {
+		if err := mfs.Mkdir(mr, dir, mfs.MkdirOpts{Mkparents: true}); err != nil {
 			return err
 		}
 	}
@@ -477,10 +509,73 @@ func (adder *Adder) addFile(file files.File) error {
 		return err
 	}
 
+	if mode, mtime := adder.metadata(file); mode != 0 || !mtime.IsZero() {
+		dagnode, err = adder.withMetadata(dagnode, mode, mtime)
+		if err != nil {
+			return err
+		}
+	}
+
 	// patch it into the root
 	return adder.addNode(dagnode, file.FileName())
 }
 
+// metadata returns the permission bits and modification time of file that
+// should be recorded, zero values if none.
+func (adder *Adder) metadata(file files.File) (os.FileMode, time.Time) {
+	if !adder.PreserveMode && !adder.PreserveMtime {
+		return 0, time.Time{}
+	}
+
+	sf, ok := file.(files.StatFile)
+	if !ok || sf.Stat() == nil {
+		return 0, time.Time{}
+	}
+	st := sf.Stat()
+
+	var mode os.FileMode
+	var mtime time.Time
+	if adder.PreserveMode {
+		mode = st.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	}
+	if adder.PreserveMtime {
+		mtime = st.ModTime()
+	}
+	return mode, mtime
+}
+
+// withMetadata returns the root of a file with mode and mtime recorded in it.
+func (adder *Adder) withMetadata(nd node.Node, mode os.FileMode, mtime time.Time) (node.Node, error) {
+	var out *dag.ProtoNode
+	switch nd := nd.(type) {
+	case *dag.ProtoNode:
+		data, err := unixfs.WithMetadata(nd.Data(), mode, mtime)
+		if err != nil {
+			return nil, err
+		}
+		out = nd.Copy().(*dag.ProtoNode)
+		out.SetData(data)
+	default:
+		// raw leaves cannot carry metadata, so wrap them in a file node
+		fsn := &unixfs.FSNode{Type: unixfs.TFile, Mode: mode, ModTime: mtime}
+		fsn.AddBlockSize(uint64(len(nd.RawData())))
+		data, err := fsn.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+		out = dag.NodeWithData(data)
+		out.SetPrefix(adder.Prefix)
+		if err := out.AddNodeLinkClean("", nd); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := adder.dagService.Add(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (adder *Adder) addDir(dir files.File) error {
 	log.Infof("adding directory: %s", dir.FileName())
 
@@ -488,7 +583,7 @@ func (adder *Adder) addDir(dir files.File) error {
 	if err != nil {
 		return err
 	}
-	err = mfs.Mkdir(mr, dir.FileName(), true, false)
+	err = mfs.Mkdir(mr, dir.FileName(), mfs.MkdirOpts{Mkparents: true})
 	if err != nil {
 		return err
 	}
@@ -513,15 +608,34 @@ func (adder *Adder) addDir(dir files.File) error {
 		}
 	}
 
+	if mode, mtime := adder.metadata(dir); mode != 0 || !mtime.IsZero() {
+		fsn, err := mfs.Lookup(mr, dir.FileName())
+		if err != nil {
+			return err
+		}
+		mdir, ok := fsn.(*mfs.Directory)
+		if !ok {
+			return fmt.Errorf("%s is not a directory", dir.FileName())
+		}
+		return mdir.SetMetadata(mode, mtime)
+	}
+
 	return nil
 }
 
 func (adder *Adder) maybePauseForGC() error {
 	if adder.unlocker != nil && adder.blockstore.GCRequested() {
-		err := adder.PinRoot()
+		// the root is only pinned until the next one, or the final one
+		// pinned by PinRoot, so the hooks aren't run
+		root, err := adder.RootNode()
 		if err != nil {
 			return err
 		}
+		if adder.Pin {
+			if err := adder.pinRoot(root); err != nil {
+				return err
+			}
+		}
 
 		adder.unlocker.Unlock()
 		adder.unlocker = adder.blockstore.PinLock()
@@ -0,0 +1,267 @@
+package coreunix
+
+import (
+	"context"
+	"path"
+	"sort"
+
+	dag "github.com/ipfs/go-ipfs/merkledag"
+	dagutils "github.com/ipfs/go-ipfs/merkledag/utils"
+	ft "github.com/ipfs/go-ipfs/unixfs"
+	uio "github.com/ipfs/go-ipfs/unixfs/io"
+
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
+)
+
+// Change is a difference between two DAGs, at a unixfs path. Its Type is
+// one of dagutils.Add, dagutils.Remove and dagutils.Mod.
+type Change struct {
+	Type   int
+	Path   string
+	Before *cid.Cid `json:",omitempty"`
+	After  *cid.Cid `json:",omitempty"`
+
+	// Dir is set when the entry is a directory, on the side(s) it exists
+	Dir bool `json:",omitempty"`
+
+	// SizeBefore and SizeAfter are the cumulative DAG sizes of the entry
+	SizeBefore uint64 `json:",omitempty"`
+	SizeAfter  uint64 `json:",omitempty"`
+}
+
+// Diff returns the differences between the DAGs a and b. Unixfs
+// directories, sharded or not, are compared entry by entry and recursed
+// into; any other node, including files, is reported as a whole when it
+// changed. Changes are sorted by path.
+func Diff(ctx context.Context, ds dag.DAGService, a, b node.Node) ([]*Change, error) {
+	if a.Cid().Equals(b.Cid()) {
+		return nil, nil
+	}
+
+	adir, err := asDirectory(ds, a)
+	if err != nil {
+		return nil, err
+	}
+	bdir, err := asDirectory(ds, b)
+	if err != nil {
+		return nil, err
+	}
+
+	if adir == nil || bdir == nil {
+		sa, err := a.Size()
+		if err != nil {
+			return nil, err
+		}
+		sb, err := b.Size()
+		if err != nil {
+			return nil, err
+		}
+		return []*Change{{
+			Type:       dagutils.Mod,
+			Before:     a.Cid(),
+			After:      b.Cid(),
+			Dir:        adir != nil || bdir != nil,
+			SizeBefore: sa,
+			SizeAfter:  sb,
+		}}, nil
+	}
+
+	var out []*Change
+	if err := diffDirs(ctx, ds, "", adir, bdir, &out); err != nil {
+		return nil, err
+	}
+	sort.Sort(changesByPath(out))
+	return out, nil
+}
+
+func diffDirs(ctx context.Context, ds dag.DAGService, prefix string, a, b *uio.Directory, out *[]*Change) error {
+	alinks, err := a.Links(ctx)
+	if err != nil {
+		return err
+	}
+	blinks, err := b.Links(ctx)
+	if err != nil {
+		return err
+	}
+
+	bByName := make(map[string]*node.Link, len(blinks))
+	for _, l := range blinks {
+		bByName[l.Name] = l
+	}
+
+	for _, al := range alinks {
+		p := path.Join(prefix, al.Name)
+		bl, ok := bByName[al.Name]
+		if !ok {
+			c, err := linkChange(ctx, ds, dagutils.Remove, p, al)
+			if err != nil {
+				return err
+			}
+			*out = append(*out, c)
+			continue
+		}
+		delete(bByName, al.Name)
+
+		if al.Cid.Equals(bl.Cid) {
+			continue
+		}
+
+		and, err := al.GetNode(ctx, ds)
+		if err != nil {
+			return err
+		}
+		bnd, err := bl.GetNode(ctx, ds)
+		if err != nil {
+			return err
+		}
+		adir, err := asDirectory(ds, and)
+		if err != nil {
+			return err
+		}
+		bdir, err := asDirectory(ds, bnd)
+		if err != nil {
+			return err
+		}
+
+		if adir != nil && bdir != nil {
+			if err := diffDirs(ctx, ds, p, adir, bdir, out); err != nil {
+				return err
+			}
+			continue
+		}
+
+		*out = append(*out, &Change{
+			Type:       dagutils.Mod,
+			Path:       p,
+			Before:     al.Cid,
+			After:      bl.Cid,
+			Dir:        adir != nil || bdir != nil,
+			SizeBefore: al.Size,
+			SizeAfter:  bl.Size,
+		})
+	}
+
+	for _, bl := range blinks {
+		if _, ok := bByName[bl.Name]; !ok {
+			continue
+		}
+		c, err := linkChange(ctx, ds, dagutils.Add, path.Join(prefix, bl.Name), bl)
+		if err != nil {
+			return err
+		}
+		*out = append(*out, c)
+	}
+	return nil
+}
+
+// linkChange returns the addition or removal of the entry behind l
+func linkChange(ctx context.Context, ds dag.DAGService, typ int, p string, l *node.Link) (*Change, error) {
+	nd, err := l.GetNode(ctx, ds)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := asDirectory(ds, nd)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Change{Type: typ, Path: p, Dir: dir != nil}
+	if typ == dagutils.Add {
+		c.After = l.Cid
+		c.SizeAfter = l.Size
+	} else {
+		c.Before = l.Cid
+		c.SizeBefore = l.Size
+	}
+	return c, nil
+}
+
+// asDirectory returns nd as a unixfs directory, or nil if it isn't one
+func asDirectory(ds dag.DAGService, nd node.Node) (*uio.Directory, error) {
+	pbnd, ok := nd.(*dag.ProtoNode)
+	if !ok {
+		return nil, nil
+	}
+	pbd, err := ft.FromBytes(pbnd.Data())
+	if err != nil {
+		// not unixfs
+		return nil, nil
+	}
+
+	switch pbd.GetType() {
+	case ft.TDirectory, ft.THAMTShard:
+		return uio.NewDirectoryFromNode(ds, nd)
+	default:
+		return nil, nil
+	}
+}
+
+type changesByPath []*Change
+
+func (c changesByPath) Len() int           { return len(c) }
+func (c changesByPath) Less(i, j int) bool { return c[i].Path < c[j].Path }
+func (c changesByPath) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// BlockStats compares the blocks of two DAGs
+type BlockStats struct {
+	// Shared is the size of the blocks both DAGs reference
+	Shared uint64
+	// OnlyBefore is the size of the blocks only the first DAG references
+	OnlyBefore uint64
+	// OnlyAfter is the size of the blocks only the second DAG references,
+	// which is what a node holding the first needs to fetch to get the
+	// second
+	OnlyAfter uint64
+}
+
+// CompareBlocks walks the DAGs under a and b, fetching any missing block,
+// and sums the sizes of the blocks they share and of the ones they don't.
+func CompareBlocks(ctx context.Context, ds dag.DAGService, a, b *cid.Cid) (*BlockStats, error) {
+	asizes, err := blockSizes(ctx, ds, a)
+	if err != nil {
+		return nil, err
+	}
+	bsizes, err := blockSizes(ctx, ds, b)
+	if err != nil {
+		return nil, err
+	}
+
+	st := new(BlockStats)
+	for k, s := range asizes {
+		if _, ok := bsizes[k]; ok {
+			st.Shared += s
+		} else {
+			st.OnlyBefore += s
+		}
+	}
+	for k, s := range bsizes {
+		if _, ok := asizes[k]; !ok {
+			st.OnlyAfter += s
+		}
+	}
+	return st, nil
+}
+
+// blockSizes returns the size of each block of the DAG under root
+func blockSizes(ctx context.Context, ds dag.DAGService, root *cid.Cid) (map[string]uint64, error) {
+	sizes := make(map[string]uint64)
+	var walk func(c *cid.Cid) error
+	walk = func(c *cid.Cid) error {
+		if _, ok := sizes[c.KeyString()]; ok {
+			return nil
+		}
+		nd, err := ds.Get(ctx, c)
+		if err != nil {
+			return err
+		}
+		sizes[c.KeyString()] = uint64(len(nd.RawData()))
+		for _, l := range nd.Links() {
+			if err := walk(l.Cid); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return sizes, walk(root)
+}
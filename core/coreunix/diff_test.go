@@ -0,0 +1,85 @@
+package coreunix
+
+import (
+	"context"
+	"testing"
+
+	merkledag "github.com/ipfs/go-ipfs/merkledag"
+	dagutils "github.com/ipfs/go-ipfs/merkledag/utils"
+	ft "github.com/ipfs/go-ipfs/unixfs"
+)
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	ds := getDagserv(t)
+
+	file := func(data string) *merkledag.ProtoNode {
+		nd := merkledag.NodeWithData(ft.FilePBData([]byte(data), uint64(len(data))))
+		if _, err := ds.Add(nd); err != nil {
+			t.Fatal(err)
+		}
+		return nd
+	}
+	dir := func(entries map[string]*merkledag.ProtoNode) *merkledag.ProtoNode {
+		nd := ft.EmptyDirNode()
+		for name, child := range entries {
+			if err := nd.AddNodeLinkClean(name, child); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := ds.Add(nd); err != nil {
+			t.Fatal(err)
+		}
+		return nd
+	}
+
+	same := file("same")
+	before := dir(map[string]*merkledag.ProtoNode{
+		"index.html": file("old index"),
+		"old.html":   file("old"),
+		"same.html":  same,
+		"css":        dir(map[string]*merkledag.ProtoNode{"a.css": file("a")}),
+	})
+	after := dir(map[string]*merkledag.ProtoNode{
+		"index.html": file("new index"),
+		"same.html":  same,
+		"css":        dir(map[string]*merkledag.ProtoNode{"a.css": file("a"), "b.css": file("b")}),
+		"img":        dir(nil),
+	})
+
+	changes, err := Diff(ctx, ds, before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		typ  int
+		path string
+		dir  bool
+	}{
+		{dagutils.Add, "css/b.css", false},
+		{dagutils.Add, "img", true},
+		{dagutils.Mod, "index.html", false},
+		{dagutils.Remove, "old.html", false},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d", len(expected), len(changes))
+	}
+	for i, e := range expected {
+		c := changes[i]
+		if c.Type != e.typ || c.Path != e.path || c.Dir != e.dir {
+			t.Errorf("change %d: expected %d %q dir=%t, got %d %q dir=%t", i, e.typ, e.path, e.dir, c.Type, c.Path, c.Dir)
+		}
+	}
+	if changes[2].SizeBefore == 0 || changes[2].SizeAfter == 0 {
+		t.Error("sizes of the changed file are not set")
+	}
+
+	st, err := CompareBlocks(ctx, ds, before.Cid(), after.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Shared == 0 || st.OnlyBefore == 0 || st.OnlyAfter == 0 {
+		t.Fatalf("unexpected block stats: %+v", st)
+	}
+}
@@ -0,0 +1,119 @@
+package coreunix
+
+import (
+	"fmt"
+	"sort"
+
+	dag "github.com/ipfs/go-ipfs/merkledag"
+
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+)
+
+// CidProfile pins down every parameter of the importer that affects the
+// resulting CIDs. Adding the same data with the same profile produces the
+// same CIDs on any machine, regardless of local configuration.
+//
+// Profiles are frozen once released: a change in defaults gets a new
+// profile name rather than an edit to an existing one.
+type CidProfile struct {
+	Name         string
+	CidVersion   int
+	HashFunction string
+	Chunker      string
+	Trickle      bool
+	RawLeaves    bool
+
+	// ShardingThreshold is the estimated size, in bytes, above which the
+	// added directories are sharded, 0 to never shard them
+	ShardingThreshold int
+}
+
+// CidProfiles lists the known profiles by name.
+var CidProfiles = map[string]CidProfile{
+	// v0 is what 'ipfs add' has produced by default since the beginning.
+	"v0": {
+		Name:         "v0",
+		CidVersion:   0,
+		HashFunction: "sha2-256",
+		Chunker:      "size-262144",
+		Trickle:      false,
+		RawLeaves:    false,
+
+		ShardingThreshold: 256 * 1024,
+	},
+	// v1 uses CIDv1 with raw leaves, the defaults implied by --cid-version=1.
+	"v1": {
+		Name:         "v1",
+		CidVersion:   1,
+		HashFunction: "sha2-256",
+		Chunker:      "size-262144",
+		Trickle:      false,
+		RawLeaves:    true,
+
+		ShardingThreshold: 256 * 1024,
+	},
+	// v1-blake2b is v1 hashed with blake2b-256.
+	"v1-blake2b": {
+		Name:         "v1-blake2b",
+		CidVersion:   1,
+		HashFunction: "blake2b-256",
+		Chunker:      "size-262144",
+		Trickle:      false,
+		RawLeaves:    true,
+
+		ShardingThreshold: 256 * 1024,
+	},
+}
+
+// CidProfileNames returns the names of all known profiles, sorted.
+func CidProfileNames() []string {
+	names := make([]string, 0, len(CidProfiles))
+	for name := range CidProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupCidProfile returns the profile with the given name.
+func LookupCidProfile(name string) (CidProfile, error) {
+	p, ok := CidProfiles[name]
+	if !ok {
+		return CidProfile{}, fmt.Errorf("unknown cid profile %q, known profiles: %v", name, CidProfileNames())
+	}
+	return p, nil
+}
+
+// Prefix returns the cid prefix used for nodes created under the profile.
+func (p CidProfile) Prefix() (cid.Prefix, error) {
+	return dag.PrefixForHash(p.CidVersion, p.HashFunction)
+}
+
+// Layout returns the name of the dag layout used by the profile.
+func (p CidProfile) Layout() string {
+	if p.Trickle {
+		return "trickle"
+	}
+	return "balanced"
+}
+
+// String describes the profile and all of its parameters on one line.
+func (p CidProfile) String() string {
+	return fmt.Sprintf("%s (cidv%d, %s, %s, %s, raw-leaves=%t, sharding-threshold=%d)",
+		p.Name, p.CidVersion, p.HashFunction, p.Chunker, p.Layout(), p.RawLeaves, p.ShardingThreshold)
+}
+
+// Apply configures the adder to import data according to the profile.
+func (p CidProfile) Apply(adder *Adder) error {
+	prefix, err := p.Prefix()
+	if err != nil {
+		return err
+	}
+
+	adder.Chunker = p.Chunker
+	adder.Trickle = p.Trickle
+	adder.RawLeaves = p.RawLeaves
+	adder.ShardingSize = p.ShardingThreshold
+	adder.Prefix = &prefix
+	return nil
+}
@@ -0,0 +1,51 @@
+package coreunix
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLookupCidProfile(t *testing.T) {
+	if _, err := LookupCidProfile("nope"); err == nil {
+		t.Fatal("expected an error for an unknown profile")
+	}
+
+	for _, name := range CidProfileNames() {
+		p, err := LookupCidProfile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Name != name {
+			t.Fatalf("profile %q is registered as %q", p.Name, name)
+		}
+		if _, err := p.Prefix(); err != nil {
+			t.Fatalf("profile %q has an invalid prefix: %s", name, err)
+		}
+	}
+}
+
+func TestCidProfileApply(t *testing.T) {
+	p, err := LookupCidProfile("v1-blake2b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adder, err := NewAdder(context.Background(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adder.Chunker = "rabin"
+	adder.Trickle = true
+	adder.ShardingSize = 0
+
+	if err := p.Apply(adder); err != nil {
+		t.Fatal(err)
+	}
+
+	if adder.Chunker != "size-262144" || adder.Trickle || !adder.RawLeaves || adder.ShardingSize != 256*1024 {
+		t.Fatalf("adder not configured from profile: %+v", adder)
+	}
+	if adder.Prefix == nil || adder.Prefix.Version != 1 {
+		t.Fatal("expected a cidv1 prefix")
+	}
+}
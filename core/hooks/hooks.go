@@ -0,0 +1,305 @@
+// Package hooks runs the external programs and HTTP endpoints configured in
+// the Hooks section of the config around some operations of the node. Hooks
+// run before an operation may veto it.
+//
+// The hooks are run by the layers applying the operations, not by the
+// commands: corerepo and the adder for pins, the name system for the IPNS
+// records, and the key commands and the spec import for keys, so that every
+// path to an operation is covered.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+)
+
+var log = logging.Logger("hooks")
+
+// DefaultTimeout is the time a hook may run for when its config doesn't say
+const DefaultTimeout = 10 * time.Second
+
+// The operations hooks can be run for. The arguments of the pin operations
+// are pin.Intents, the ones of name/publish namesys.PublishHookArgs.
+const (
+	OpPinAdd    = "pin/add"
+	OpPinRm     = "pin/rm"
+	OpPinUpdate = "pin/update"
+
+	// OpNamePublish is namesys.PublishHookOp, run for every record the
+	// name system publishes
+	OpNamePublish = "name/publish"
+
+	OpKeyGen    = "key/gen"
+	OpKeyRotate = "key/rotate"
+)
+
+var knownOps = map[string]bool{
+	OpPinAdd:      true,
+	OpPinRm:       true,
+	OpPinUpdate:   true,
+	OpNamePublish: true,
+	OpKeyGen:      true,
+	OpKeyRotate:   true,
+	"*":           true,
+}
+
+// Stage tells whether a hook runs before or after the operation
+type Stage string
+
+const (
+	StagePre  Stage = "pre"
+	StagePost Stage = "post"
+)
+
+// Event is the payload passed to hooks.
+type Event struct {
+	Op    string
+	Stage Stage
+
+	// Args are the arguments of the operation, specific to each operation
+	Args interface{}
+
+	// Result is the output of the operation, and Error the reason it failed,
+	// for hooks run after it
+	Result interface{} `json:",omitempty"`
+	Error  string      `json:",omitempty"`
+}
+
+// Hook is run around operations. Returning an error from a hook run before
+// an operation vetoes it.
+type Hook interface {
+	Run(context.Context, *Event) error
+}
+
+// HTTPHook posts events as JSON to an HTTP endpoint. Any 2xx response
+// accepts the operation, anything else vetoes it, using the response body
+// as the reason.
+type HTTPHook struct {
+	URL string
+
+	// Client is the client used to post events, http.DefaultClient if nil
+	Client *http.Client
+}
+
+func (h *HTTPHook) Run(ctx context.Context, ev *Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(resp.Body)
+	if reason := strings.TrimSpace(string(msg)); reason != "" {
+		return fmt.Errorf("%s: %s", resp.Status, reason)
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
+
+// ExecHook runs a program with the event as JSON on its standard input, and
+// IPFS_HOOK_OP and IPFS_HOOK_STAGE in its environment. Exiting with a status
+// other than 0 vetoes the operation, using what the program wrote to its
+// standard error as the reason.
+type ExecHook struct {
+	Path string
+	Args []string
+}
+
+func (h *ExecHook) Run(ctx context.Context, ev *Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, h.Path, h.Args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"IPFS_HOOK_OP="+ev.Op,
+		"IPFS_HOOK_STAGE="+string(ev.Stage),
+	)
+
+	if err := cmd.Run(); err != nil {
+		if reason := strings.TrimSpace(stderr.String()); reason != "" {
+			return fmt.Errorf("%s: %s", err, reason)
+		}
+		return err
+	}
+	return nil
+}
+
+type entry struct {
+	hook    Hook
+	ops     []string
+	timeout time.Duration
+}
+
+func (e *entry) matches(op string) bool {
+	for _, o := range e.ops {
+		if o == "*" || o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Hooks are the hooks configured on a node. A nil *Hooks runs no hook.
+type Hooks struct {
+	pre, post []*entry
+}
+
+// FromConfig returns the hooks of the Hooks section of cfg. The URLs of
+// Pinning.Hooks are the node's PinHooks, which get the pin intents alone.
+func FromConfig(cfg *config.Config) (*Hooks, error) {
+	return New(cfg.Hooks)
+}
+
+// New returns the hooks of cfgs.
+func New(cfgs []config.Hook) (*Hooks, error) {
+	hs := new(Hooks)
+	for i, c := range cfgs {
+		e, err := newEntry(c)
+		if err != nil {
+			return nil, fmt.Errorf("hook %d: %s", i, err)
+		}
+		if c.Post {
+			hs.post = append(hs.post, e)
+		} else {
+			hs.pre = append(hs.pre, e)
+		}
+	}
+	return hs, nil
+}
+
+// Add registers h to run before the operations ops, or after them if post
+// is set, e.g. for the components embedding the node. h may run for
+// DefaultTimeout.
+func (hs *Hooks) Add(ops []string, post bool, h Hook) {
+	hs.AddWithTimeout(ops, post, DefaultTimeout, h)
+}
+
+// AddWithTimeout is like Add, with the time h may run for. Zero means h
+// bounds its own run time.
+func (hs *Hooks) AddWithTimeout(ops []string, post bool, timeout time.Duration, h Hook) {
+	e := &entry{hook: h, ops: ops, timeout: timeout}
+	if post {
+		hs.post = append(hs.post, e)
+	} else {
+		hs.pre = append(hs.pre, e)
+	}
+}
+
+func newEntry(c config.Hook) (*entry, error) {
+	if len(c.Ops) == 0 {
+		return nil, errors.New("no operation given in Ops")
+	}
+	for _, op := range c.Ops {
+		if !knownOps[op] {
+			return nil, fmt.Errorf("unknown operation %q", op)
+		}
+	}
+
+	e := &entry{ops: c.Ops, timeout: DefaultTimeout}
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %s", err)
+		}
+		e.timeout = d
+	}
+
+	switch {
+	case c.URL != "" && len(c.Exec) > 0:
+		return nil, errors.New("only one of URL and Exec may be set")
+	case c.URL != "":
+		e.hook = &HTTPHook{URL: c.URL}
+	case len(c.Exec) > 0:
+		e.hook = &ExecHook{Path: c.Exec[0], Args: c.Exec[1:]}
+	default:
+		return nil, errors.New("one of URL and Exec must be set")
+	}
+	return e, nil
+}
+
+// Pre runs the hooks configured before op, in turn, and returns the first
+// veto, if any.
+func (hs *Hooks) Pre(ctx context.Context, op string, args interface{}) error {
+	if hs == nil {
+		return nil
+	}
+
+	ev := &Event{Op: op, Stage: StagePre, Args: args}
+	for _, e := range hs.pre {
+		if !e.matches(op) {
+			continue
+		}
+		if err := e.run(ctx, ev); err != nil {
+			return fmt.Errorf("%s vetoed by hook: %s", op, err)
+		}
+	}
+	return nil
+}
+
+// Post runs the hooks configured after op, which ended with result or with
+// opErr. Their failures are logged.
+func (hs *Hooks) Post(ctx context.Context, op string, args, result interface{}, opErr error) {
+	if hs == nil {
+		return
+	}
+
+	ev := &Event{Op: op, Stage: StagePost, Args: args, Result: result}
+	if opErr != nil {
+		ev.Result = nil
+		ev.Error = opErr.Error()
+	}
+	for _, e := range hs.post {
+		if !e.matches(op) {
+			continue
+		}
+		if err := e.run(ctx, ev); err != nil {
+			log.Warningf("hook after %s failed: %s", op, err)
+		}
+	}
+}
+
+func (e *entry) run(ctx context.Context, ev *Event) error {
+	if e.timeout == 0 {
+		return e.hook.Run(ctx, ev)
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	return e.hook.Run(ctx, ev)
+}
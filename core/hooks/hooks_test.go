@@ -0,0 +1,127 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
+func TestHTTPHooks(t *testing.T) {
+	var got []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Error(err)
+		}
+		got = append(got, ev)
+		if ev.Op == OpKeyGen && ev.Stage == StagePre {
+			http.Error(w, "keys are managed centrally", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	hs, err := New([]config.Hook{
+		{Ops: []string{OpPinAdd, OpKeyGen}, URL: srv.URL},
+		{Ops: []string{"*"}, URL: srv.URL, Post: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := hs.Pre(ctx, OpPinAdd, []string{"/ipfs/foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := hs.Pre(ctx, OpNamePublish, nil); err != nil {
+		t.Fatal(err)
+	}
+	err = hs.Pre(ctx, OpKeyGen, nil)
+	if err == nil || !strings.Contains(err.Error(), "keys are managed centrally") {
+		t.Fatalf("expected key/gen to be vetoed, got %v", err)
+	}
+	hs.Post(ctx, OpNamePublish, nil, nil, errors.New("no route"))
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(got))
+	}
+	if got[0].Op != OpPinAdd || got[0].Stage != StagePre || got[0].Args == nil {
+		t.Fatalf("hook got wrong event: %#v", got[0])
+	}
+	if got[2].Op != OpNamePublish || got[2].Stage != StagePost || got[2].Error != "no route" {
+		t.Fatalf("hook got wrong event: %#v", got[2])
+	}
+}
+
+type hookFunc func(context.Context, *Event) error
+
+func (f hookFunc) Run(ctx context.Context, ev *Event) error {
+	return f(ctx, ev)
+}
+
+func TestAdd(t *testing.T) {
+	hs, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var post []string
+	hs.Add([]string{OpPinRm}, false, hookFunc(func(ctx context.Context, ev *Event) error {
+		return errors.New("still allocated")
+	}))
+	hs.Add([]string{"*"}, true, hookFunc(func(ctx context.Context, ev *Event) error {
+		post = append(post, ev.Op)
+		return nil
+	}))
+	ctx := context.Background()
+
+	if err := hs.Pre(ctx, OpPinAdd, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := hs.Pre(ctx, OpPinRm, nil); err == nil {
+		t.Fatal("expected pin/rm to be vetoed")
+	}
+	hs.Post(ctx, OpPinAdd, nil, nil, nil)
+	if len(post) != 1 || post[0] != OpPinAdd {
+		t.Fatalf("expected the hook to run after pin/add, got %v", post)
+	}
+}
+
+func TestExecHook(t *testing.T) {
+	h := &ExecHook{Path: "sh", Args: []string{"-c", `test "$IPFS_HOOK_OP" = pin/add || { echo denied >&2; exit 1; }`}}
+	ctx := context.Background()
+
+	if err := h.Run(ctx, &Event{Op: OpPinAdd, Stage: StagePre}); err != nil {
+		t.Fatal(err)
+	}
+	err := h.Run(ctx, &Event{Op: OpKeyGen, Stage: StagePre})
+	if err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Fatalf("expected a veto, got %v", err)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, c := range []config.Hook{
+		{URL: "http://localhost"},
+		{Ops: []string{"pin/ls"}, URL: "http://localhost"},
+		{Ops: []string{OpPinAdd}},
+		{Ops: []string{OpPinAdd}, URL: "http://localhost", Exec: []string{"true"}},
+		{Ops: []string{OpPinAdd}, URL: "http://localhost", Timeout: "soon"},
+	} {
+		if _, err := New([]config.Hook{c}); err == nil {
+			t.Errorf("expected an error for %#v", c)
+		}
+	}
+}
+
+func TestNilHooks(t *testing.T) {
+	var hs *Hooks
+	if err := hs.Pre(context.Background(), OpPinAdd, nil); err != nil {
+		t.Fatal(err)
+	}
+	hs.Post(context.Background(), OpPinAdd, nil, nil, nil)
+}
@@ -0,0 +1,215 @@
+package coremock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	core "github.com/ipfs/go-ipfs/core"
+	corenodes "github.com/ipfs/go-ipfs/core/corenodes"
+	namesys "github.com/ipfs/go-ipfs/namesys"
+	path "github.com/ipfs/go-ipfs/path"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
+
+	mocknet "gx/ipfs/QmQA5mdxru8Bh6dpC9PJfSkumqnmHgJX7knxSgBo5Lpime/go-libp2p/p2p/net/mock"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// HarnessOptions configure NewHarness.
+type HarnessOptions struct {
+	// Nodes is the number of nodes to start.
+	Nodes int
+
+	// RealNetwork makes the nodes talk over loopback TCP instead of an
+	// in-memory network.
+	RealNetwork bool
+
+	// Disconnected leaves the nodes unconnected, see Connect.
+	Disconnected bool
+
+	// KeyBits is the size of the RSA keys of the nodes, 1024 by default.
+	KeyBits int
+}
+
+// Harness runs a set of online nodes in the current process, for tests.
+// The nodes only know about each other: they have no bootstrap peers and
+// do not use local discovery.
+type Harness struct {
+	Nodes []*core.IpfsNode
+
+	// Clock drives the periodic tasks of all nodes, e.g. ipns republishing.
+	// It only moves when told to.
+	Clock *clock.Mock
+
+	// Net is the in-memory network of the nodes, nil with RealNetwork.
+	Net mocknet.Mocknet
+
+	manager *corenodes.Manager
+}
+
+// NewHarness starts the nodes described by opts. Unless opts.Disconnected
+// is set, every node is connected to every other node.
+func NewHarness(ctx context.Context, opts HarnessOptions) (*Harness, error) {
+	if opts.Nodes < 1 {
+		return nil, fmt.Errorf("a harness needs at least one node, not %d", opts.Nodes)
+	}
+	bits := opts.KeyBits
+	if bits == 0 {
+		bits = 1024
+	}
+
+	h := &Harness{
+		Clock:   clock.NewMock(time.Now()),
+		manager: corenodes.NewManager(ctx),
+	}
+	if !opts.RealNetwork {
+		h.Net = mocknet.New(ctx)
+	}
+
+	for i := 0; i < opts.Nodes; i++ {
+		r, err := corenodes.NewMemRepo(bits)
+		if err != nil {
+			h.Close()
+			return nil, err
+		}
+
+		cfg := &core.BuildCfg{
+			Online: true,
+			Repo:   r,
+			Clock:  h.Clock,
+		}
+		if h.Net != nil {
+			cfg.Host = MockHostOption(h.Net)
+		}
+
+		n, err := h.manager.NewNode(fmt.Sprintf("node%d", i), cfg)
+		if err != nil {
+			h.Close()
+			return nil, err
+		}
+
+		// resolve without caching, so that assertions see the records
+		// currently in the routing system
+		n.Namesys = namesys.NewNameSystemWithClock(n.Routing, n.Repo.Datastore(), 0, h.Clock)
+
+		h.Nodes = append(h.Nodes, n)
+	}
+
+	if h.Net != nil {
+		if err := h.Net.LinkAll(); err != nil {
+			h.Close()
+			return nil, err
+		}
+	}
+
+	if !opts.Disconnected {
+		if err := h.ConnectAll(ctx); err != nil {
+			h.Close()
+			return nil, err
+		}
+	}
+
+	// make sure moving the clock reaches every republisher
+	h.Clock.BlockUntil(len(h.Nodes))
+	return h, nil
+}
+
+// Connect connects node i to node j.
+func (h *Harness) Connect(ctx context.Context, i, j int) error {
+	a, b := h.Nodes[i], h.Nodes[j]
+	pi := pstore.PeerInfo{ID: b.Identity, Addrs: b.PeerHost.Addrs()}
+	return a.PeerHost.Connect(ctx, pi)
+}
+
+// ConnectAll connects every node to every other node.
+func (h *Harness) ConnectAll(ctx context.Context) error {
+	for i := range h.Nodes {
+		for j := i + 1; j < len(h.Nodes); j++ {
+			if err := h.Connect(ctx, i, j); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Advance moves the clock of the nodes forward by d, firing the periodic
+// tasks that are due. The tasks run asynchronously: use WaitFor to observe
+// their effects.
+func (h *Harness) Advance(d time.Duration) {
+	h.Clock.Add(d)
+}
+
+// WaitFor calls check until it succeeds or ctx is done, in which case the
+// last error of check is returned.
+func (h *Harness) WaitFor(ctx context.Context, check func() error) error {
+	for {
+		err := check()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// Resolves checks that name resolves to exp on every node.
+func (h *Harness) Resolves(ctx context.Context, name string, exp path.Path) error {
+	for i, n := range h.Nodes {
+		p, err := n.Namesys.Resolve(ctx, name)
+		if err != nil {
+			return fmt.Errorf("node %d: resolving %s: %s", i, name, err)
+		}
+		if p != exp {
+			return fmt.Errorf("node %d: %s resolved to %s, expected %s", i, name, p, exp)
+		}
+	}
+	return nil
+}
+
+// ResolveFails checks that name does not resolve on any node.
+func (h *Harness) ResolveFails(ctx context.Context, name string) error {
+	for i, n := range h.Nodes {
+		if p, err := n.Namesys.Resolve(ctx, name); err == nil {
+			return fmt.Errorf("node %d: %s resolved to %s, expected failure", i, name, p)
+		}
+	}
+	return nil
+}
+
+// Provides checks that every node other than p finds p as a provider of c.
+func (h *Harness) Provides(ctx context.Context, c *cid.Cid, p peer.ID) error {
+	for i, n := range h.Nodes {
+		if n.Identity == p {
+			continue
+		}
+
+		if !findsProvider(ctx, n, c, p, len(h.Nodes)) {
+			return fmt.Errorf("node %d: %s is not a provider of %s", i, p.Pretty(), c)
+		}
+	}
+	return nil
+}
+
+func findsProvider(ctx context.Context, n *core.IpfsNode, c *cid.Cid, p peer.ID, count int) bool {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for pi := range n.Routing.FindProvidersAsync(ctx, c, count) {
+		if pi.ID == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Close stops all nodes.
+func (h *Harness) Close() error {
+	return h.manager.Close()
+}
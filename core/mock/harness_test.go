@@ -0,0 +1,73 @@
+package coremock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	republisher "github.com/ipfs/go-ipfs/namesys/republisher"
+	path "github.com/ipfs/go-ipfs/path"
+)
+
+func TestHarnessRepublish(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := NewHarness(ctx, HarnessOptions{Nodes: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// publish a record that is only valid for a minute
+	publisher := h.Nodes[1]
+	p := path.FromString("/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn")
+	if err := publisher.Namesys.PublishWithEOL(ctx, publisher.PrivateKey, p, h.Clock.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "/ipns/" + publisher.Identity.Pretty()
+	if err := h.Resolves(ctx, name, p); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Advance(2 * time.Minute)
+	if err := h.ResolveFails(ctx, name); err != nil {
+		t.Fatal(err)
+	}
+
+	// the republisher of the node refreshes the record once its interval
+	// has passed, without waiting for hours
+	h.Advance(republisher.DefaultRebroadcastInterval)
+
+	err = h.WaitFor(ctx, func() error {
+		return h.Resolves(ctx, name, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHarnessConnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := NewHarness(ctx, HarnessOptions{Nodes: 3, Disconnected: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	for _, n := range h.Nodes {
+		if len(n.PeerHost.Network().Peers()) != 0 {
+			t.Fatal("expected disconnected nodes")
+		}
+	}
+
+	if err := h.Connect(ctx, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Nodes[0].PeerHost.Network().Peers()) != 1 {
+		t.Fatal("expected node 0 to be connected to node 2")
+	}
+}
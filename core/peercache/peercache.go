@@ -0,0 +1,267 @@
+// Package peercache saves the addresses and latencies of the peers a node
+// connects to in its datastore, so that after a restart the node can dial
+// them again instead of rediscovering the network from its bootstrap peers.
+package peercache
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dsq "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/query"
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+var log = logging.Logger("peercache")
+
+// DefaultMaxAge is how long a peer that was not seen is remembered
+const DefaultMaxAge = 72 * time.Hour
+
+// DefaultMaxPeers is the number of peers remembered
+const DefaultMaxPeers = 1000
+
+// DefaultInterval is the time between two saves of the connected peers
+const DefaultInterval = 10 * time.Minute
+
+// maxAddrs is the number of addresses saved per peer
+const maxAddrs = 8
+
+var prefix = ds.NewKey("/local/peers")
+
+// record is what is saved of a peer
+type record struct {
+	Addrs    []string
+	Latency  time.Duration `json:",omitempty"`
+	LastSeen time.Time
+}
+
+// Cache saves the peers of a network to a datastore, and loads them back
+// into a peerstore.
+type Cache struct {
+	ds       ds.Datastore
+	ps       pstore.Peerstore
+	maxAge   time.Duration
+	maxPeers int
+
+	lk     sync.Mutex
+	net    inet.Network
+	stop   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+// New creates a Cache saving peers to d, forgetting those not seen for
+// maxAge and keeping maxPeers of them at most.
+func New(d ds.Datastore, ps pstore.Peerstore, maxAge time.Duration, maxPeers int) *Cache {
+	return &Cache{
+		ds:       d,
+		ps:       ps,
+		maxAge:   maxAge,
+		maxPeers: maxPeers,
+	}
+}
+
+// Load adds the addresses and latencies of the saved peers to the
+// peerstore, and returns these peers, the most recently seen first. Their
+// addresses expire from the peerstore when the peers would be forgotten.
+// Stale peers are removed from the datastore.
+func (c *Cache) Load() ([]peer.ID, error) {
+	recs, err := c.gc()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	out := make([]peer.ID, 0, len(recs))
+	for _, r := range recs {
+		var addrs []ma.Multiaddr
+		for _, s := range r.Addrs {
+			a, err := ma.NewMultiaddr(s)
+			if err != nil {
+				continue
+			}
+			addrs = append(addrs, a)
+		}
+		if len(addrs) == 0 {
+			continue
+		}
+
+		c.ps.AddAddrs(r.id, addrs, c.maxAge-now.Sub(r.LastSeen))
+		if r.Latency > 0 {
+			c.ps.RecordLatency(r.id, r.Latency)
+		}
+		out = append(out, r.id)
+	}
+	return out, nil
+}
+
+// Start saves the peers of n every interval and when they disconnect,
+// until the cache is closed.
+func (c *Cache) Start(n inet.Network, interval time.Duration) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	c.net = n
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	n.Notify((*notifiee)(c))
+
+	go func() {
+		defer close(c.done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if err := c.Save(n); err != nil {
+					log.Warningf("failed to save the connected peers: %s", err)
+				}
+				if _, err := c.gc(); err != nil {
+					log.Warningf("failed to forget the stale peers: %s", err)
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops saving the peers, after saving those still connected
+func (c *Cache) Close() error {
+	c.lk.Lock()
+	if c.net == nil || c.closed {
+		c.lk.Unlock()
+		return nil
+	}
+	c.closed = true
+	n := c.net
+	c.lk.Unlock()
+
+	n.StopNotify((*notifiee)(c))
+	close(c.stop)
+	<-c.done
+	return c.Save(n)
+}
+
+// Save saves the peers n is connected to
+func (c *Cache) Save(n inet.Network) error {
+	now := time.Now()
+	for _, p := range n.Peers() {
+		if err := c.savePeer(p, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) savePeer(p peer.ID, seen time.Time) error {
+	addrs := c.ps.Addrs(p)
+	if len(addrs) == 0 {
+		return nil
+	}
+	if len(addrs) > maxAddrs {
+		addrs = addrs[:maxAddrs]
+	}
+
+	r := record{
+		Latency:  c.ps.LatencyEWMA(p),
+		LastSeen: seen,
+	}
+	for _, a := range addrs {
+		r.Addrs = append(r.Addrs, a.String())
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return c.ds.Put(prefix.ChildString(p.Pretty()), data)
+}
+
+type savedPeer struct {
+	record
+	id peer.ID
+}
+
+type byLastSeen []savedPeer
+
+func (s byLastSeen) Len() int           { return len(s) }
+func (s byLastSeen) Less(i, j int) bool { return s[i].LastSeen.After(s[j].LastSeen) }
+func (s byLastSeen) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// gc removes the peers not seen for maxAge, the invalid entries and the
+// least recently seen peers over maxPeers from the datastore. It returns
+// the remaining peers, the most recently seen first.
+func (c *Cache) gc() ([]savedPeer, error) {
+	qr, err := c.ds.Query(dsq.Query{Prefix: prefix.String()})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := qr.Rest()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var stale []ds.Key
+	var recs []savedPeer
+	for _, e := range entries {
+		k := ds.RawKey(e.Key)
+
+		var r savedPeer
+		data, ok := e.Value.([]byte)
+		if !ok || json.Unmarshal(data, &r.record) != nil {
+			stale = append(stale, k)
+			continue
+		}
+		r.id, err = peer.IDB58Decode(k.BaseNamespace())
+		if err != nil || now.Sub(r.LastSeen) > c.maxAge {
+			stale = append(stale, k)
+			continue
+		}
+		recs = append(recs, r)
+	}
+
+	sort.Sort(byLastSeen(recs))
+	if c.maxPeers > 0 && len(recs) > c.maxPeers {
+		for _, r := range recs[c.maxPeers:] {
+			stale = append(stale, prefix.ChildString(r.id.Pretty()))
+		}
+		recs = recs[:c.maxPeers]
+	}
+
+	for _, k := range stale {
+		if err := c.ds.Delete(k); err != nil && err != ds.ErrNotFound {
+			return nil, err
+		}
+	}
+	if len(stale) > 0 {
+		log.Debugf("forgot %d stale peers", len(stale))
+	}
+	return recs, nil
+}
+
+// notifiee saves peers as they disconnect
+type notifiee Cache
+
+func (nn *notifiee) Disconnected(n inet.Network, c inet.Conn) {
+	p := c.RemotePeer()
+	if n.Connectedness(p) == inet.Connected {
+		return
+	}
+	go func() {
+		if err := (*Cache)(nn).savePeer(p, time.Now()); err != nil {
+			log.Debugf("failed to save peer %s: %s", p, err)
+		}
+	}()
+}
+
+func (nn *notifiee) Connected(n inet.Network, c inet.Conn)      {}
+func (nn *notifiee) OpenedStream(n inet.Network, s inet.Stream) {}
+func (nn *notifiee) ClosedStream(n inet.Network, s inet.Stream) {}
+func (nn *notifiee) Listen(n inet.Network, a ma.Multiaddr)      {}
+func (nn *notifiee) ListenClose(n inet.Network, a ma.Multiaddr) {}
@@ -0,0 +1,81 @@
+package peercache
+
+import (
+	"testing"
+	"time"
+
+	tu "github.com/ipfs/go-ipfs/thirdparty/testutil"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+func TestLoad(t *testing.T) {
+	addr, err := ma.NewMultiaddr("/ip4/1.2.3.4/tcp/4001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dstore := ds.NewMapDatastore()
+	saved := pstore.NewPeerstore()
+	c := New(dstore, saved, time.Hour, 2)
+
+	// peers from the most recently seen to a stale one
+	now := time.Now()
+	seen := []time.Duration{0, time.Minute, 2 * time.Minute, 2 * time.Hour}
+	var ids []peer.ID
+	for _, ago := range seen {
+		p := tu.RandPeerIDFatal(t)
+		saved.AddAddr(p, addr, time.Hour)
+		saved.RecordLatency(p, 10*time.Millisecond)
+		if err := c.savePeer(p, now.Add(-ago)); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, p)
+	}
+
+	ps := pstore.NewPeerstore()
+	loaded, err := New(dstore, ps, time.Hour, 2).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the stale peer and the one over the limit are forgotten
+	if len(loaded) != 2 || loaded[0] != ids[0] || loaded[1] != ids[1] {
+		t.Fatalf("expected the 2 most recently seen peers, got %v", loaded)
+	}
+	for _, p := range ids[2:] {
+		if has, _ := dstore.Has(prefix.ChildString(p.Pretty())); has {
+			t.Fatalf("expected %s to be removed from the datastore", p)
+		}
+	}
+
+	addrs := ps.Addrs(ids[0])
+	if len(addrs) != 1 || !addrs[0].Equal(addr) {
+		t.Fatalf("expected the saved address to be loaded, got %v", addrs)
+	}
+	if ps.LatencyEWMA(ids[0]) != 10*time.Millisecond {
+		t.Fatalf("expected the saved latency to be loaded, got %s", ps.LatencyEWMA(ids[0]))
+	}
+}
+
+func TestLoadSkipsInvalidEntries(t *testing.T) {
+	dstore := ds.NewMapDatastore()
+	k := prefix.ChildString("notapeer")
+	if err := dstore.Put(k, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := New(dstore, pstore.NewPeerstore(), time.Hour, 0).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 0 {
+		t.Fatalf("expected no peers, got %v", loaded)
+	}
+	if has, _ := dstore.Has(k); has {
+		t.Fatal("expected the invalid entry to be removed")
+	}
+}
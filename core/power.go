@@ -0,0 +1,307 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	host "gx/ipfs/QmUywuGNZoUKV8B9iyvup9bPkLiMrhTsyVMkeSXW5VxAfC/go-libp2p-host"
+	protocol "gx/ipfs/QmZNkThpqfVXs9GNbexPrfBbXSLNYeKrE7jwFM2oqHbyqN/go-libp2p-protocol"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// PowerMode is the power profile of an online node
+type PowerMode string
+
+const (
+	// PowerNormal is the default mode
+	PowerNormal PowerMode = "normal"
+	// PowerLow stops serving DHT requests, reprovides and republishes less
+	// often, caps the number of connections and groups the background work
+	// in periodic wakeups, for nodes running on battery or metered networks.
+	PowerLow PowerMode = "low"
+)
+
+// ParsePowerMode returns the mode named s
+func ParsePowerMode(s string) (PowerMode, error) {
+	switch m := PowerMode(s); m {
+	case PowerNormal, PowerLow:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown power mode %q, use %q or %q", s, PowerNormal, PowerLow)
+	}
+}
+
+// Default settings of the low power mode
+const (
+	DefaultLowPowerMaxConnections = 32
+	DefaultLowPowerReprovide      = 48 * time.Hour
+	DefaultLowPowerRepublish      = 12 * time.Hour
+	DefaultLowPowerWakeup         = 5 * time.Minute
+)
+
+type powerState struct {
+	lk   sync.Mutex
+	mode PowerMode
+
+	// low power settings
+	maxConns  int
+	reprovide time.Duration
+	republish time.Duration
+	wakeup    time.Duration
+
+	// stops the low power background work
+	stop chan struct{}
+}
+
+func newPowerState(c config.Power) (*powerState, error) {
+	ps := &powerState{
+		mode:      PowerNormal,
+		maxConns:  DefaultLowPowerMaxConnections,
+		reprovide: DefaultLowPowerReprovide,
+		republish: DefaultLowPowerRepublish,
+		wakeup:    DefaultLowPowerWakeup,
+	}
+	if c.MaxConnections > 0 {
+		ps.maxConns = c.MaxConnections
+	}
+
+	for _, d := range []struct {
+		name string
+		val  string
+		dst  *time.Duration
+	}{
+		{"ReproviderInterval", c.ReproviderInterval, &ps.reprovide},
+		{"RepublishPeriod", c.RepublishPeriod, &ps.republish},
+		{"WakeupInterval", c.WakeupInterval, &ps.wakeup},
+	} {
+		if d.val == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.val)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid Power.%s: %q", d.name, d.val)
+		}
+		*d.dst = v
+	}
+	return ps, nil
+}
+
+// PowerMode returns the power mode of the node
+func (n *IpfsNode) PowerMode() PowerMode {
+	if n.power == nil {
+		return PowerNormal
+	}
+	n.power.lk.Lock()
+	defer n.power.lk.Unlock()
+	return n.power.mode
+}
+
+// SetPowerMode switches the node to the given power mode. Embedders call it
+// as their battery or network state changes.
+func (n *IpfsNode) SetPowerMode(m PowerMode) error {
+	if !n.OnlineMode() || n.power == nil {
+		return fmt.Errorf("power modes only apply to online nodes")
+	}
+	if _, err := ParsePowerMode(string(m)); err != nil {
+		return err
+	}
+
+	ps := n.power
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+	if ps.mode == m {
+		return nil
+	}
+	ps.mode = m
+	log.Infof("switching to %s power mode", m)
+
+	bcfg := DefaultBootstrapConfig
+	reprovide, republish := n.reprovideInterval, n.republishInterval
+	if m == PowerLow {
+		bcfg.Period = ps.wakeup
+		reprovide, republish = ps.reprovide, ps.republish
+
+		ps.stop = make(chan struct{})
+		go n.lowPowerLoop(ps.stop, ps.wakeup, ps.maxConns)
+	} else {
+		close(ps.stop)
+	}
+
+	if n.Reprovider != nil && reprovide > 0 {
+		n.Reprovider.SetInterval(reprovide)
+	}
+	if n.IpnsRepub != nil {
+		n.IpnsRepub.SetInterval(republish)
+	}
+	if n.Bootstrapper != nil {
+		return n.Bootstrap(bcfg)
+	}
+	return nil
+}
+
+// lowPowerLoop does the background work of the low power mode every wakeup
+func (n *IpfsNode) lowPowerLoop(stop chan struct{}, wakeup time.Duration, maxConns int) {
+	n.trimConnections(maxConns)
+
+	tick := time.NewTicker(wakeup)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			n.trimConnections(maxConns)
+		case <-stop:
+			return
+		case <-n.ctx.Done():
+			return
+		}
+	}
+}
+
+// trimConnections closes the most recent connections over max. The
+// connections to protected peers, to the bootstrap peers and with open
+// streams are kept, and count towards max.
+func (n *IpfsNode) trimConnections(max int) {
+	keep := n.protected.set()
+	if bps, err := n.loadBootstrapPeers(); err == nil {
+		for _, pi := range bps {
+			keep[pi.ID] = struct{}{}
+		}
+	}
+
+	network := n.PeerHost.Network()
+	opened := make(map[peer.ID]time.Time)
+	for _, c := range network.Conns() {
+		p := c.RemotePeer()
+		if strs, err := c.GetStreams(); err == nil && len(strs) > 0 {
+			keep[p] = struct{}{}
+		}
+		var t time.Time
+		if n.ConnTracker != nil {
+			t = n.ConnTracker.Info(c).Opened
+		}
+		if o, ok := opened[p]; !ok || (!t.IsZero() && t.Before(o)) {
+			opened[p] = t
+		}
+	}
+	for p := range keep {
+		if _, ok := opened[p]; ok {
+			delete(opened, p)
+			max--
+		}
+	}
+	if max < 0 {
+		max = 0
+	}
+
+	trim := peersToTrim(opened, max)
+	for _, p := range trim {
+		for _, c := range network.ConnsToPeer(p) {
+			c.Close()
+		}
+	}
+	if len(trim) > 0 {
+		log.Debugf("closed the connections to %d peers", len(trim))
+	}
+}
+
+// ProtectPeer keeps the connections to p open when the node trims its
+// connections, in low power mode or under a schedule. It is meant for the
+// peers the node reconnects to anyway.
+func (n *IpfsNode) ProtectPeer(p peer.ID) {
+	n.protected.lk.Lock()
+	defer n.protected.lk.Unlock()
+	if n.protected.peers == nil {
+		n.protected.peers = make(map[peer.ID]struct{})
+	}
+	n.protected.peers[p] = struct{}{}
+}
+
+// UnprotectPeer undoes ProtectPeer
+func (n *IpfsNode) UnprotectPeer(p peer.ID) {
+	n.protected.lk.Lock()
+	defer n.protected.lk.Unlock()
+	delete(n.protected.peers, p)
+}
+
+// protectedPeers are the peers set with ProtectPeer
+type protectedPeers struct {
+	lk    sync.Mutex
+	peers map[peer.ID]struct{}
+}
+
+// set returns a copy of the protected peers
+func (pp *protectedPeers) set() map[peer.ID]struct{} {
+	pp.lk.Lock()
+	defer pp.lk.Unlock()
+	out := make(map[peer.ID]struct{}, len(pp.peers))
+	for p := range pp.peers {
+		out[p] = struct{}{}
+	}
+	return out
+}
+
+type peerOpened struct {
+	id     peer.ID
+	opened time.Time
+}
+
+type byOpened []peerOpened
+
+func (s byOpened) Len() int { return len(s) }
+func (s byOpened) Less(i, j int) bool {
+	if !s[i].opened.Equal(s[j].opened) {
+		return s[i].opened.Before(s[j].opened)
+	}
+	return s[i].id < s[j].id
+}
+func (s byOpened) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// peersToTrim returns the peers to disconnect from to keep max of them,
+// keeping those connected the longest. Unknown connection times count as
+// the oldest.
+func peersToTrim(opened map[peer.ID]time.Time, max int) []peer.ID {
+	if len(opened) <= max {
+		return nil
+	}
+	peers := make([]peerOpened, 0, len(opened))
+	for p, t := range opened {
+		peers = append(peers, peerOpened{id: p, opened: t})
+	}
+	sort.Sort(byOpened(peers))
+
+	var out []peer.ID
+	for _, p := range peers[max:] {
+		out = append(out, p.id)
+	}
+	return out
+}
+
+// lowPowerHost refuses the inbound streams of the protocols it serves while
+// the node is in low power mode, the DHT stops answering other peers
+type lowPowerHost struct {
+	host.Host
+	n *IpfsNode
+}
+
+func (h *lowPowerHost) gate(handler inet.StreamHandler) inet.StreamHandler {
+	return func(s inet.Stream) {
+		if h.n.PowerMode() == PowerLow {
+			s.Close()
+			return
+		}
+		handler(s)
+	}
+}
+
+func (h *lowPowerHost) SetStreamHandler(pid protocol.ID, handler inet.StreamHandler) {
+	h.Host.SetStreamHandler(pid, h.gate(handler))
+}
+
+func (h *lowPowerHost) SetStreamHandlerMatch(pid protocol.ID, m func(string) bool, handler inet.StreamHandler) {
+	h.Host.SetStreamHandlerMatch(pid, m, h.gate(handler))
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+	tu "github.com/ipfs/go-ipfs/thirdparty/testutil"
+
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+func TestPeersToTrim(t *testing.T) {
+	now := time.Now()
+	oldest, older, newer, newest := tu.RandPeerIDFatal(t), tu.RandPeerIDFatal(t), tu.RandPeerIDFatal(t), tu.RandPeerIDFatal(t)
+	unknown := tu.RandPeerIDFatal(t)
+	opened := map[peer.ID]time.Time{
+		newest:  now,
+		older:   now.Add(-2 * time.Hour),
+		newer:   now.Add(-time.Minute),
+		oldest:  now.Add(-3 * time.Hour),
+		unknown: {},
+	}
+
+	if trim := peersToTrim(opened, 5); len(trim) != 0 {
+		t.Fatalf("expected nothing to trim under the limit, got %v", trim)
+	}
+
+	trim := peersToTrim(opened, 3)
+	if len(trim) != 2 || trim[0] != newer || trim[1] != newest {
+		t.Fatalf("expected the 2 most recent peers to be trimmed, got %v", trim)
+	}
+}
+
+func TestProtectPeer(t *testing.T) {
+	n := &IpfsNode{}
+	p := tu.RandPeerIDFatal(t)
+	n.ProtectPeer(p)
+	keep := n.protected.set()
+	if _, ok := keep[p]; !ok {
+		t.Fatal("expected the peer to be protected")
+	}
+	delete(keep, p)
+	n.UnprotectPeer(p)
+	n.UnprotectPeer(p)
+	if len(n.protected.set()) != 0 {
+		t.Fatal("expected no protected peer")
+	}
+}
+
+func TestPowerConfig(t *testing.T) {
+	ps, err := newPowerState(config.Power{WakeupInterval: "1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps.wakeup != time.Minute || ps.reprovide != DefaultLowPowerReprovide || ps.maxConns != DefaultLowPowerMaxConnections {
+		t.Fatalf("unexpected settings: %+v", ps)
+	}
+
+	if _, err := newPowerState(config.Power{RepublishPeriod: "soon"}); err == nil {
+		t.Fatal("expected an invalid duration to be rejected")
+	}
+	if _, err := ParsePowerMode("eco"); err == nil {
+		t.Fatal("expected an unknown mode to be rejected")
+	}
+}
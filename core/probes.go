@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	bserv "github.com/ipfs/go-ipfs/blockservice"
+	probes "github.com/ipfs/go-ipfs/core/probes"
+	offline "github.com/ipfs/go-ipfs/exchange/offline"
+	merkledag "github.com/ipfs/go-ipfs/merkledag"
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+func probesConfig(c config.Probes) (interval, timeout time.Duration, err error) {
+	interval, timeout = probes.DefaultInterval, probes.DefaultTimeout
+	if c.Interval != "" {
+		interval, err = time.ParseDuration(c.Interval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Probes.Interval: %s", err)
+		}
+	}
+	if c.Timeout != "" {
+		timeout, err = time.ParseDuration(c.Timeout)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid Probes.Timeout: %s", err)
+		}
+	}
+	return interval, timeout, nil
+}
+
+// startProbes starts the self-probes. They measure through the DHT, and
+// are not started when the node routes otherwise. They pause in low power
+// mode.
+func (n *IpfsNode) startProbes(ctx context.Context, c config.Probes) error {
+	interval, timeout, err := probesConfig(c)
+	if err != nil {
+		return err
+	}
+
+	d, ok := n.DHT()
+	if !ok {
+		log.Warning("the self-probes need the DHT, not starting them")
+		return nil
+	}
+
+	// only walk through the local blocks
+	dag := merkledag.NewDAGService(bserv.New(n.Blockstore, offline.Exchange(n.Blockstore)))
+	pick := func(ctx context.Context) (*cid.Cid, error) {
+		return probes.RandomWalk(ctx, n.Pinning, dag, probes.DefaultWalkDepth)
+	}
+
+	n.Prober = probes.New(interval, timeout,
+		&probes.ProviderProbe{
+			Host:    n.PeerHost,
+			Peers:   d,
+			Routing: n.Routing,
+			Pick:    pick,
+		},
+		&probes.IPNSProbe{
+			Host:      n.PeerHost,
+			Peers:     d,
+			Datastore: n.Repo.Datastore(),
+			Names:     n.probedNames,
+		},
+	)
+	n.Prober.Paused = func() bool {
+		return n.PowerMode() == PowerLow
+	}
+	go n.Prober.Run(ctx)
+	return nil
+}
+
+// probedNames returns the names the node may have published: its own and
+// those of the keys of its keystore
+func (n *IpfsNode) probedNames() ([]peer.ID, error) {
+	ids := []peer.ID{n.Identity}
+	names, err := n.Repo.Keystore().List()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		sk, err := n.Repo.Keystore().Get(name)
+		if err != nil {
+			log.Warningf("not probing key %s: %s", name, err)
+			continue
+		}
+		id, err := peer.IDFromPrivateKey(sk)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,219 @@
+package probes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	namesys "github.com/ipfs/go-ipfs/namesys"
+	dshelp "github.com/ipfs/go-ipfs/thirdparty/ds-help"
+
+	routing "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing"
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dht "gx/ipfs/QmRmroYSdievxnjiuy99C8BzShNstdEWcEF3LQHF7fUbez/go-libp2p-kad-dht"
+	dhtpb "gx/ipfs/QmRmroYSdievxnjiuy99C8BzShNstdEWcEF3LQHF7fUbez/go-libp2p-kad-dht/pb"
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	host "gx/ipfs/QmUywuGNZoUKV8B9iyvup9bPkLiMrhTsyVMkeSXW5VxAfC/go-libp2p-host"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	ggio "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/io"
+	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// PeerFinder finds the peers closest to a key, as the DHT does
+type PeerFinder interface {
+	GetClosestPeers(ctx context.Context, key string) (<-chan peer.ID, error)
+}
+
+// The DHT answers the queries of the node for its own content and names
+// from its local records first, so the probes walk to the peers closest to
+// the key themselves and ask them directly, as a remote node would.
+
+// ProviderProbe measures the time from providing a block of the node to a
+// peer of the network listing the node as its provider.
+type ProviderProbe struct {
+	Host    host.Host
+	Peers   PeerFinder
+	Routing routing.ContentRouting
+
+	// Pick picks the block to probe, e.g. with RandomWalk
+	Pick func(ctx context.Context) (*cid.Cid, error)
+}
+
+func (pp *ProviderProbe) Kind() string {
+	return KindProvider
+}
+
+func (pp *ProviderProbe) Run(ctx context.Context) (string, error) {
+	c, err := pp.Pick(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if err := pp.Routing.Provide(ctx, c, true); err != nil {
+		return c.String(), err
+	}
+
+	self := pp.Host.ID()
+	req := dhtpb.NewMessage(dhtpb.Message_GET_PROVIDERS, c.KeyString(), 0)
+	err = askClosest(ctx, pp.Host, pp.Peers, c.KeyString(), req, func(resp *dhtpb.Message) bool {
+		for _, pi := range dhtpb.PBPeersToPeerInfos(resp.GetProviderPeers()) {
+			if pi.ID == self {
+				return true
+			}
+		}
+		return false
+	})
+	return c.String(), err
+}
+
+// IPNSProbe measures the time to resolve a name of the node through the
+// peers of the network. It succeeds when one of the peers closest to the
+// name returns the record the node published last, which is kept in its
+// datastore.
+type IPNSProbe struct {
+	Host      host.Host
+	Peers     PeerFinder
+	Datastore ds.Datastore
+
+	// Names returns the names of the node, a random one of those
+	// published from this node is probed
+	Names func() ([]peer.ID, error)
+}
+
+func (ip *IPNSProbe) Kind() string {
+	return KindIPNS
+}
+
+func (ip *IPNSProbe) Run(ctx context.Context) (string, error) {
+	ids, err := ip.Names()
+	if err != nil {
+		return "", err
+	}
+
+	type published struct {
+		id    peer.ID
+		value []byte
+	}
+	var names []published
+	for _, id := range ids {
+		_, ipnskey := namesys.IpnsKeysForID(id)
+		val, err := ip.Datastore.Get(dshelp.NewKeyFromBinary([]byte(ipnskey)))
+		if err == ds.ErrNotFound {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+
+		data, ok := val.([]byte)
+		if !ok {
+			return "", fmt.Errorf("unexpected type returned from datastore: %#v", val)
+		}
+		rec := new(dhtpb.Record)
+		if err := proto.Unmarshal(data, rec); err != nil {
+			return "", err
+		}
+		names = append(names, published{id: id, value: rec.GetValue()})
+	}
+	if len(names) == 0 {
+		return "", ErrNothingToProbe
+	}
+
+	name := names[rand.Intn(len(names))]
+	_, ipnskey := namesys.IpnsKeysForID(name.id)
+	req := dhtpb.NewMessage(dhtpb.Message_GET_VALUE, ipnskey, 0)
+	err = askClosest(ctx, ip.Host, ip.Peers, ipnskey, req, func(resp *dhtpb.Message) bool {
+		return bytes.Equal(resp.GetRecord().GetValue(), name.value)
+	})
+	return "/ipns/" + name.id.Pretty(), err
+}
+
+// askClosest walks the DHT to the peers closest to key and sends req to
+// all of them at once. It returns as soon as one of the answers satisfies
+// found.
+func askClosest(ctx context.Context, h host.Host, pf PeerFinder, key string, req *dhtpb.Message, found func(*dhtpb.Message) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	peers, err := pf.GetClosestPeers(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	success := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	asked := 0
+	for p := range peers {
+		if p == h.ID() {
+			continue
+		}
+		asked++
+		wg.Add(1)
+		go func(p peer.ID) {
+			defer wg.Done()
+			resp, err := sendRequest(ctx, h, p, req)
+			if err != nil {
+				log.Debugf("probe request to %s failed: %s", p, err)
+				return
+			}
+			if found(resp) {
+				select {
+				case success <- struct{}{}:
+				default:
+				}
+			}
+		}(p)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-success:
+		return nil
+	case <-done:
+		select {
+		case <-success:
+			return nil
+		default:
+		}
+		return fmt.Errorf("not found by any of the %d closest peers", asked)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendRequest sends req to p over the DHT protocol and returns its answer
+func sendRequest(ctx context.Context, h host.Host, p peer.ID, req *dhtpb.Message) (*dhtpb.Message, error) {
+	s, err := h.NewStream(ctx, p, dht.ProtocolDHT)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	resp := new(dhtpb.Message)
+	errc := make(chan error, 1)
+	go func() {
+		if err := ggio.NewDelimitedWriter(s).WriteMsg(req); err != nil {
+			errc <- err
+			return
+		}
+		errc <- ggio.NewDelimitedReader(s, inet.MessageSizeMax).ReadMsg(resp)
+	}()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			return nil, err
+		}
+		return resp, nil
+	case <-ctx.Done():
+		// closing the stream unblocks the goroutine
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,250 @@
+// Package probes implements the self-probes of a node: periodic
+// measurements of how long its own content takes to be found on the
+// network, and how long its own IPNS names take to resolve, as another
+// node would see them rather than from the local records.
+package probes
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	metrics "gx/ipfs/QmRg1gKTHzc3CZXSKzem8aR4E3TubFhbgXwfVuWnSK5CC5/go-metrics-interface"
+	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+)
+
+var log = logging.Logger("probes")
+
+// Probe kinds
+const (
+	KindProvider = "provider"
+	KindIPNS     = "ipns"
+)
+
+// DefaultInterval is the time between two rounds of probes
+const DefaultInterval = time.Hour
+
+// DefaultTimeout is the time a probe is given before it is counted as
+// failed
+const DefaultTimeout = 5 * time.Minute
+
+// keptResults is the number of results kept per kind of probe
+const keptResults = 32
+
+// ErrNothingToProbe is returned by probes having nothing to measure, e.g.
+// the provider probe of a node without pinned content. Such runs are not
+// recorded.
+var ErrNothingToProbe = errors.New("nothing to probe")
+
+// Probe is one kind of measurement
+type Probe interface {
+	Kind() string
+
+	// Run measures once, and returns what it measured, a CID or a name.
+	// The latency of the probe is the time Run takes.
+	Run(ctx context.Context) (key string, err error)
+}
+
+// Result is the outcome of one run of a probe
+type Result struct {
+	Kind    string
+	Key     string
+	Time    time.Time
+	Latency time.Duration
+	Error   string `json:",omitempty"`
+}
+
+// Stats are the statistics of one kind of probe
+type Stats struct {
+	Kind       string
+	Runs       uint64
+	Failures   uint64
+	Last       time.Duration // latency of the last successful run
+	AvgLatency time.Duration // of the successful runs
+	MaxLatency time.Duration
+
+	total time.Duration
+}
+
+// Prober runs probes periodically and keeps their results
+type Prober struct {
+	// Paused, when set, is called before every round of probes, and the
+	// round is skipped if it returns true
+	Paused func() bool
+
+	probes   []Probe
+	interval time.Duration
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	stats   map[string]*Stats
+	results map[string][]Result
+}
+
+// New returns a Prober running the probes every interval, giving each of
+// them timeout to complete
+func New(interval, timeout time.Duration, probes ...Probe) *Prober {
+	initMetrics()
+	return &Prober{
+		probes:   probes,
+		interval: interval,
+		timeout:  timeout,
+		stats:    make(map[string]*Stats),
+		results:  make(map[string][]Result),
+	}
+}
+
+// Run runs the probes every interval until ctx is done. The first round
+// starts after one interval, to leave the node time to connect to the
+// network and provide its content.
+func (p *Prober) Run(ctx context.Context) {
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if p.Paused != nil && p.Paused() {
+				continue
+			}
+			p.RunOnce(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RunOnce runs every probe once, one after the other
+func (p *Prober) RunOnce(ctx context.Context) {
+	for _, pr := range p.probes {
+		if ctx.Err() != nil {
+			return
+		}
+		p.run(ctx, pr)
+	}
+}
+
+func (p *Prober) run(ctx context.Context, pr Probe) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	key, err := pr.Run(ctx)
+	if err == ErrNothingToProbe {
+		return
+	}
+	r := Result{
+		Kind:    pr.Kind(),
+		Key:     key,
+		Time:    start,
+		Latency: time.Since(start),
+	}
+	if err != nil {
+		r.Error = err.Error()
+		log.Infof("%s probe of %s failed: %s", r.Kind, key, err)
+	}
+	p.record(r)
+}
+
+func (p *Prober) record(r Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.stats[r.Kind]
+	if !ok {
+		s = &Stats{Kind: r.Kind}
+		p.stats[r.Kind] = s
+	}
+	s.Runs++
+	if r.Error != "" {
+		s.Failures++
+		if m, ok := failureMetrics[r.Kind]; ok {
+			m.Inc()
+		}
+	} else {
+		s.Last = r.Latency
+		s.total += r.Latency
+		s.AvgLatency = s.total / time.Duration(s.Runs-s.Failures)
+		if r.Latency > s.MaxLatency {
+			s.MaxLatency = r.Latency
+		}
+		if m, ok := latencyMetrics[r.Kind]; ok {
+			m.Observe(r.Latency.Seconds())
+		}
+	}
+
+	rs := append(p.results[r.Kind], r)
+	if len(rs) > keptResults {
+		rs = rs[len(rs)-keptResults:]
+	}
+	p.results[r.Kind] = rs
+}
+
+// Stats returns the statistics of every kind of probe run so far, sorted
+// by kind
+func (p *Prober) Stats() []Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := make([]Stats, 0, len(p.stats))
+	for _, s := range p.stats {
+		out = append(out, *s)
+	}
+	sort.Sort(byKind(out))
+	return out
+}
+
+type byKind []Stats
+
+func (s byKind) Len() int           { return len(s) }
+func (s byKind) Less(i, j int) bool { return s[i].Kind < s[j].Kind }
+func (s byKind) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// Results returns the most recent results of every kind of probe, oldest
+// first
+func (p *Prober) Results() []Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var out []Result
+	for _, rs := range p.results {
+		out = append(out, rs...)
+	}
+	sort.Sort(byTime(out))
+	return out
+}
+
+type byTime []Result
+
+func (s byTime) Len() int           { return len(s) }
+func (s byTime) Less(i, j int) bool { return s[i].Time.Before(s[j].Time) }
+func (s byTime) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// the probe metrics are shared by all probers, and created on first use,
+// as metrics created before the daemon injects its implementation are
+// discarded
+var (
+	metricsOnce    sync.Once
+	latencyMetrics map[string]metrics.Histogram
+	failureMetrics map[string]metrics.Counter
+)
+
+var latencyBuckets = []float64{0.5, 1, 2, 5, 10, 30, 60, 120, 300}
+
+func initMetrics() {
+	metricsOnce.Do(func() {
+		latencyMetrics = map[string]metrics.Histogram{
+			KindProvider: metrics.New("probes.provider_seconds",
+				"Time from providing a local block to a peer of the network listing this node as its provider").Histogram(latencyBuckets),
+			KindIPNS: metrics.New("probes.ipns_seconds",
+				"Time to resolve an IPNS name of this node through the peers of the network").Histogram(latencyBuckets),
+		}
+		failureMetrics = map[string]metrics.Counter{
+			KindProvider: metrics.New("probes.provider_failures_total",
+				"Number of provider probes that failed").Counter(),
+			KindIPNS: metrics.New("probes.ipns_failures_total",
+				"Number of IPNS probes that failed").Counter(),
+		}
+	})
+}
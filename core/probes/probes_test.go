@@ -0,0 +1,132 @@
+package probes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mdag "github.com/ipfs/go-ipfs/merkledag"
+	mdtest "github.com/ipfs/go-ipfs/merkledag/test"
+	pin "github.com/ipfs/go-ipfs/pin"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dssync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
+)
+
+type fakeProbe struct {
+	kind string
+	run  func(ctx context.Context) (string, error)
+}
+
+func (f *fakeProbe) Kind() string                            { return f.kind }
+func (f *fakeProbe) Run(ctx context.Context) (string, error) { return f.run(ctx) }
+
+func TestProber(t *testing.T) {
+	fail := false
+	p := New(time.Hour, 50*time.Millisecond,
+		&fakeProbe{KindProvider, func(ctx context.Context) (string, error) {
+			if fail {
+				return "QmFoo", errors.New("not found")
+			}
+			return "QmFoo", nil
+		}},
+		&fakeProbe{KindIPNS, func(ctx context.Context) (string, error) {
+			return "", ErrNothingToProbe
+		}},
+		&fakeProbe{"slow", func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "QmBar", ctx.Err()
+		}},
+	)
+
+	ctx := context.Background()
+	p.RunOnce(ctx)
+	fail = true
+	p.RunOnce(ctx)
+
+	stats := p.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected the stats of 2 kinds of probes, got %v", stats)
+	}
+	if s := stats[0]; s.Kind != KindProvider || s.Runs != 2 || s.Failures != 1 {
+		t.Fatalf("wrong provider stats: %+v", s)
+	}
+	if s := stats[1]; s.Kind != "slow" || s.Runs != 2 || s.Failures != 2 {
+		t.Fatalf("wrong stats of the probe timing out: %+v", s)
+	}
+
+	results := p.Results()
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	last := results[len(results)-1]
+	if last.Kind != "slow" || last.Error == "" || last.Latency < 50*time.Millisecond {
+		t.Fatalf("wrong result of the probe timing out: %+v", last)
+	}
+}
+
+func TestProberKeptResults(t *testing.T) {
+	p := New(time.Hour, time.Second, &fakeProbe{KindProvider, func(ctx context.Context) (string, error) {
+		return "QmFoo", nil
+	}})
+	for i := 0; i < keptResults+5; i++ {
+		p.RunOnce(context.Background())
+	}
+	if n := len(p.Results()); n != keptResults {
+		t.Fatalf("expected %d results, got %d", keptResults, n)
+	}
+	if s := p.Stats()[0]; s.Runs != keptResults+5 {
+		t.Fatalf("expected %d runs, got %d", keptResults+5, s.Runs)
+	}
+}
+
+func TestRandomWalk(t *testing.T) {
+	ctx := context.Background()
+	dserv := mdtest.Mock()
+	pins := pin.NewPinner(dssync.MutexWrap(ds.NewMapDatastore()), dserv, dserv)
+
+	if _, err := RandomWalk(ctx, pins, dserv, DefaultWalkDepth); err != ErrNothingToProbe {
+		t.Fatalf("expected ErrNothingToProbe, got %v", err)
+	}
+
+	// root -> a -> b, with b missing from the walked dag
+	b := mdag.NodeWithData([]byte("b"))
+	a := mdag.NodeWithData([]byte("a"))
+	if err := a.AddNodeLinkClean("b", b); err != nil {
+		t.Fatal(err)
+	}
+	root := mdag.NodeWithData([]byte("root"))
+	if err := root.AddNodeLinkClean("a", a); err != nil {
+		t.Fatal(err)
+	}
+	walked := mdtest.Mock()
+	for _, nd := range []*mdag.ProtoNode{root, a, b} {
+		if _, err := dserv.Add(nd); err != nil {
+			t.Fatal(err)
+		}
+		if nd != b {
+			if _, err := walked.Add(nd); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := pins.Pin(ctx, root, true); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		c, err := RandomWalk(ctx, pins, walked, DefaultWalkDepth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[c.KeyString()]++
+	}
+	if seen[b.Cid().KeyString()] != 0 {
+		t.Fatal("walked to a block missing from the dag")
+	}
+	if seen[root.Cid().KeyString()] == 0 || seen[a.Cid().KeyString()] == 0 {
+		t.Fatalf("expected to walk to both the root and its child, got %v", seen)
+	}
+}
@@ -0,0 +1,56 @@
+package probes
+
+import (
+	"context"
+	"math/rand"
+
+	merkledag "github.com/ipfs/go-ipfs/merkledag"
+	pin "github.com/ipfs/go-ipfs/pin"
+
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+)
+
+// DefaultWalkDepth is the maximum number of links followed by RandomWalk
+const DefaultWalkDepth = 8
+
+// RandomWalk picks a block of the pinned content at random: it starts from
+// a random pin and follows random links for up to maxDepth steps, stopping
+// early at leaves and at blocks that can't be fetched from dag. Pass an
+// offline DAGService to only walk through local blocks. It returns
+// ErrNothingToProbe if nothing is pinned.
+func RandomWalk(ctx context.Context, pins pin.Pinner, dag merkledag.DAGService, maxDepth int) (*cid.Cid, error) {
+	recursive := pins.RecursiveKeys()
+	roots := append(recursive, pins.DirectKeys()...)
+	if len(roots) == 0 {
+		return nil, ErrNothingToProbe
+	}
+
+	i := rand.Intn(len(roots))
+	c := roots[i]
+	if i >= len(recursive) {
+		// only the root of direct pins is pinned
+		return c, nil
+	}
+
+	depth := rand.Intn(maxDepth + 1)
+	if depth == 0 {
+		return c, nil
+	}
+	nd, err := dag.Get(ctx, c)
+	if err != nil {
+		return c, nil
+	}
+	for ; depth > 0; depth-- {
+		links := nd.Links()
+		if len(links) == 0 {
+			break
+		}
+		next := links[rand.Intn(len(links))].Cid
+		child, err := dag.Get(ctx, next)
+		if err != nil {
+			break
+		}
+		c, nd = next, child
+	}
+	return c, nil
+}
@@ -0,0 +1,291 @@
+// Package routingstats implements a routing system wrapper that keeps
+// statistics on the queries made through it: how many succeed, how long
+// they take and how many peers answer them.
+package routingstats
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	routing "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing"
+	notif "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing/notifications"
+	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// Query types
+const (
+	FindPeer      = "FindPeer"
+	FindProviders = "FindProviders"
+	GetValue      = "GetValue"
+	GetValues     = "GetValues"
+	PutValue      = "PutValue"
+	Provide       = "Provide"
+
+	GetClosestPeers = "GetClosestPeers"
+)
+
+// ErrNotSupported is returned for the queries the wrapped routing system
+// can't make
+var ErrNotSupported = errors.New("routing system does not support this query")
+
+// closestPeersFinder is implemented by the routing systems able to look up
+// the peers closest to a key, such as the DHT
+type closestPeersFinder interface {
+	GetClosestPeers(ctx context.Context, key string) (<-chan peer.ID, error)
+}
+
+// QueryStats are the statistics of one type of query
+type QueryStats struct {
+	Type      string
+	Queries   uint64
+	Succeeded uint64
+	Failed    uint64
+	// Hops is the number of peers that answered the queries. Each answer
+	// is one step of the query towards the closest peers.
+	Hops       uint64
+	AvgLatency time.Duration
+	MaxLatency time.Duration
+
+	total time.Duration
+}
+
+// SuccessRate returns the fraction of the queries that succeeded
+func (s QueryStats) SuccessRate() float64 {
+	if s.Queries == 0 {
+		return 0
+	}
+	return float64(s.Succeeded) / float64(s.Queries)
+}
+
+// AvgHops returns the average number of peers that answered a query
+func (s QueryStats) AvgHops() float64 {
+	if s.Queries == 0 {
+		return 0
+	}
+	return float64(s.Hops) / float64(s.Queries)
+}
+
+// Router is a routing.IpfsRouting that records statistics on the queries
+// made through the wrapped routing system.
+type Router struct {
+	routing.IpfsRouting
+
+	mu    sync.Mutex
+	stats map[string]*QueryStats
+}
+
+// Wrap returns a Router recording the queries made through r
+func Wrap(r routing.IpfsRouting) *Router {
+	return &Router{
+		IpfsRouting: r,
+		stats:       make(map[string]*QueryStats),
+	}
+}
+
+// Unwrap returns the wrapped routing system
+func (r *Router) Unwrap() routing.IpfsRouting {
+	return r.IpfsRouting
+}
+
+// Stats returns the statistics of every type of query made so far, sorted
+// by type
+func (r *Router) Stats() []QueryStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]QueryStats, 0, len(r.stats))
+	for _, s := range r.stats {
+		out = append(out, *s)
+	}
+	sort.Sort(byType(out))
+	return out
+}
+
+type byType []QueryStats
+
+func (s byType) Len() int           { return len(s) }
+func (s byType) Less(i, j int) bool { return s[i].Type < s[j].Type }
+func (s byType) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (r *Router) record(typ string, d time.Duration, hops int, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, found := r.stats[typ]
+	if !found {
+		s = &QueryStats{Type: typ}
+		r.stats[typ] = s
+	}
+	s.Queries++
+	if ok {
+		s.Succeeded++
+	} else {
+		s.Failed++
+	}
+	s.Hops += uint64(hops)
+	s.total += d
+	s.AvgLatency = s.total / time.Duration(s.Queries)
+	if d > s.MaxLatency {
+		s.MaxLatency = d
+	}
+}
+
+// query is one query in progress
+type query struct {
+	r      *Router
+	typ    string
+	start  time.Time
+	took   time.Duration
+	cancel func()
+	events chan *notif.QueryEvent
+	stop   chan struct{}
+	hops   chan int
+}
+
+// startQuery tracks one query of type typ. It returns the query and the
+// context to run it with. The query events published on that context are
+// counted and passed on to the listener of ctx, if any.
+func (r *Router) startQuery(ctx context.Context, typ string) (*query, context.Context) {
+	q := &query{
+		r:      r,
+		typ:    typ,
+		start:  time.Now(),
+		events: make(chan *notif.QueryEvent),
+		stop:   make(chan struct{}),
+		hops:   make(chan int, 1),
+	}
+	qctx, cancel := context.WithCancel(ctx)
+	q.cancel = cancel
+	qctx = notif.RegisterForQueryEvents(qctx, q.events)
+
+	go func() {
+		n := 0
+		defer func() { q.hops <- n }()
+		for {
+			select {
+			case e := <-q.events:
+				if e.Type == notif.PeerResponse {
+					n++
+				}
+				notif.PublishQueryEvent(ctx, e)
+			case <-q.stop:
+				return
+			}
+		}
+	}()
+	return q, qctx
+}
+
+// answered sets the latency of the query to the time it took so far
+func (q *query) answered() {
+	if q.took == 0 {
+		q.took = time.Since(q.start)
+	}
+}
+
+// done records the outcome of the query
+func (q *query) done(ok bool) {
+	q.answered()
+	close(q.stop)
+	// unblocks the publishers still running
+	q.cancel()
+	q.r.record(q.typ, q.took, <-q.hops, ok)
+}
+
+func (r *Router) FindPeer(ctx context.Context, p peer.ID) (pstore.PeerInfo, error) {
+	q, ctx := r.startQuery(ctx, FindPeer)
+	pi, err := r.IpfsRouting.FindPeer(ctx, p)
+	q.done(err == nil)
+	return pi, err
+}
+
+// FindProvidersAsync counts the query as succeeded if a provider was
+// found. Its latency is the time it took to find the first one.
+func (r *Router) FindProvidersAsync(ctx context.Context, k *cid.Cid, max int) <-chan pstore.PeerInfo {
+	q, qctx := r.startQuery(ctx, FindProviders)
+	in := r.IpfsRouting.FindProvidersAsync(qctx, k, max)
+
+	out := make(chan pstore.PeerInfo)
+	go func() {
+		defer close(out)
+		found := false
+		defer func() { q.done(found) }()
+		for pi := range in {
+			if !found {
+				found = true
+				q.answered()
+			}
+			select {
+			case out <- pi:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (r *Router) GetValue(ctx context.Context, key string) ([]byte, error) {
+	q, ctx := r.startQuery(ctx, GetValue)
+	val, err := r.IpfsRouting.GetValue(ctx, key)
+	q.done(err == nil)
+	return val, err
+}
+
+func (r *Router) GetValues(ctx context.Context, key string, count int) ([]routing.RecvdVal, error) {
+	q, ctx := r.startQuery(ctx, GetValues)
+	vals, err := r.IpfsRouting.GetValues(ctx, key, count)
+	q.done(err == nil)
+	return vals, err
+}
+
+func (r *Router) PutValue(ctx context.Context, key string, val []byte) error {
+	q, ctx := r.startQuery(ctx, PutValue)
+	err := r.IpfsRouting.PutValue(ctx, key, val)
+	q.done(err == nil)
+	return err
+}
+
+func (r *Router) Provide(ctx context.Context, k *cid.Cid, brdcst bool) error {
+	q, ctx := r.startQuery(ctx, Provide)
+	err := r.IpfsRouting.Provide(ctx, k, brdcst)
+	q.done(err == nil)
+	return err
+}
+
+// GetClosestPeers counts the query as succeeded if a peer was found. Its
+// latency is the time it took to find all of them.
+func (r *Router) GetClosestPeers(ctx context.Context, key string) (<-chan peer.ID, error) {
+	cpf, ok := r.IpfsRouting.(closestPeersFinder)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	q, qctx := r.startQuery(ctx, GetClosestPeers)
+	in, err := cpf.GetClosestPeers(qctx, key)
+	if err != nil {
+		q.done(false)
+		return nil, err
+	}
+
+	out := make(chan peer.ID)
+	go func() {
+		defer close(out)
+		found := false
+		defer func() { q.done(found) }()
+		for p := range in {
+			found = true
+			select {
+			case out <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,112 @@
+package routingstats
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	routing "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing"
+	notif "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing/notifications"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// fakeRouting answers GetValue after two peers responded, and fails
+// PutValue
+type fakeRouting struct {
+	routing.IpfsRouting
+}
+
+func (fakeRouting) GetValue(ctx context.Context, key string) ([]byte, error) {
+	for i := 0; i < 2; i++ {
+		notif.PublishQueryEvent(ctx, &notif.QueryEvent{Type: notif.PeerResponse})
+	}
+	return []byte("value"), nil
+}
+
+func (fakeRouting) PutValue(ctx context.Context, key string, val []byte) error {
+	return errors.New("no peers")
+}
+
+func TestStats(t *testing.T) {
+	r := Wrap(fakeRouting{})
+	ctx := context.Background()
+
+	// events still reach the caller's listener
+	events := make(chan *notif.QueryEvent)
+	ectx := notif.RegisterForQueryEvents(ctx, events)
+	received := make(chan int)
+	go func() {
+		n := 0
+		for range events {
+			n++
+		}
+		received <- n
+	}()
+	if _, err := r.GetValue(ectx, "/a"); err != nil {
+		t.Fatal(err)
+	}
+	close(events)
+	if n := <-received; n != 2 {
+		t.Fatalf("expected the listener to get 2 events, got %d", n)
+	}
+
+	if _, err := r.GetValue(ctx, "/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.PutValue(ctx, "/a", nil); err == nil {
+		t.Fatal("expected PutValue to fail")
+	}
+
+	stats := r.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for 2 query types, got %d", len(stats))
+	}
+	get, put := stats[0], stats[1]
+	if get.Type != GetValue || get.Queries != 2 || get.Succeeded != 2 || get.Hops != 4 {
+		t.Fatalf("unexpected GetValue stats: %+v", get)
+	}
+	if get.AvgHops() != 2 || get.SuccessRate() != 1 {
+		t.Fatalf("unexpected GetValue averages: %v hops, %v success", get.AvgHops(), get.SuccessRate())
+	}
+	if put.Type != PutValue || put.Queries != 1 || put.Failed != 1 || put.SuccessRate() != 0 {
+		t.Fatalf("unexpected PutValue stats: %+v", put)
+	}
+}
+
+// closestRouting finds the same two peers for every key
+type closestRouting struct {
+	fakeRouting
+}
+
+func (closestRouting) GetClosestPeers(ctx context.Context, key string) (<-chan peer.ID, error) {
+	out := make(chan peer.ID, 2)
+	out <- peer.ID("a")
+	out <- peer.ID("b")
+	close(out)
+	return out, nil
+}
+
+func TestGetClosestPeers(t *testing.T) {
+	ctx := context.Background()
+	if _, err := Wrap(fakeRouting{}).GetClosestPeers(ctx, "k"); err != ErrNotSupported {
+		t.Fatalf("expected ErrNotSupported, got %v", err)
+	}
+
+	r := Wrap(closestRouting{})
+	peers, err := r.GetClosestPeers(ctx, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for range peers {
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 peers, got %d", n)
+	}
+
+	stats := r.Stats()
+	if len(stats) != 1 || stats[0].Type != GetClosestPeers || stats[0].Succeeded != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
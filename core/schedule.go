@@ -0,0 +1,238 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	bitswap "github.com/ipfs/go-ipfs/exchange/bitswap"
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	humanize "gx/ipfs/QmPSBJL4momYnE7DcUyk2DVhD6rH488ZmHBGLbxNdhU44K/go-humanize"
+)
+
+// scheduleCheckInterval is the time between two checks of the schedule. The
+// rules are read from the config on every check, so that they can be changed
+// without restarting the daemon.
+const scheduleCheckInterval = time.Minute
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// scheduleRule is a parsed config.ScheduleRule
+type scheduleRule struct {
+	name       string
+	days       [7]bool
+	start, end int // minutes since midnight
+
+	rate      uint64
+	hasRate   bool
+	maxConns  int
+	reprovide time.Duration
+}
+
+// parseSchedule checks the rules of c
+func parseSchedule(c config.Schedule) ([]scheduleRule, error) {
+	rules := make([]scheduleRule, 0, len(c.Rules))
+	for i, cr := range c.Rules {
+		r := scheduleRule{name: cr.Name, maxConns: cr.MaxConnections}
+		if r.name == "" {
+			r.name = fmt.Sprintf("%s-%s", cr.Start, cr.End)
+		}
+		invalid := func(format string, args ...interface{}) error {
+			return fmt.Errorf("invalid Schedule.Rules[%d]: %s", i, fmt.Sprintf(format, args...))
+		}
+
+		if len(cr.Days) == 0 {
+			for d := range r.days {
+				r.days[d] = true
+			}
+		}
+		for _, s := range cr.Days {
+			d, ok := weekdays[strings.ToLower(s)]
+			if !ok {
+				return nil, invalid("unknown day %q", s)
+			}
+			r.days[d] = true
+		}
+
+		var err error
+		if r.start, err = parseTimeOfDay(cr.Start); err != nil {
+			return nil, invalid("Start: %s", err)
+		}
+		if r.end, err = parseTimeOfDay(cr.End); err != nil {
+			return nil, invalid("End: %s", err)
+		}
+
+		if cr.MaxPeerUploadRate != "" {
+			r.hasRate = true
+			if cr.MaxPeerUploadRate != "0" {
+				r.rate, err = humanize.ParseBytes(cr.MaxPeerUploadRate)
+				if err != nil {
+					return nil, invalid("MaxPeerUploadRate: %s", err)
+				}
+			}
+		}
+		if r.maxConns < 0 {
+			return nil, invalid("MaxConnections: %d", r.maxConns)
+		}
+		if cr.ReproviderInterval != "" {
+			r.reprovide, err = time.ParseDuration(cr.ReproviderInterval)
+			if err != nil || r.reprovide <= 0 {
+				return nil, invalid("ReproviderInterval: %q", cr.ReproviderInterval)
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// parseTimeOfDay parses "HH:MM" into minutes since midnight
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day (HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// matches returns whether t is in the window of r. A window spanning
+// midnight belongs to the day it starts on, and a window starting and ending
+// at the same time lasts the whole day.
+func (r *scheduleRule) matches(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case r.start == r.end:
+		return r.days[day]
+	case r.start < r.end:
+		return r.days[day] && m >= r.start && m < r.end
+	case m >= r.start:
+		return r.days[day]
+	case m < r.end:
+		return r.days[(day+6)%7]
+	default:
+		return false
+	}
+}
+
+// activeRule returns the first of rules matching t, nil if none does
+func activeRule(rules []scheduleRule, t time.Time) *scheduleRule {
+	for i := range rules {
+		if rules[i].matches(t) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// ScheduleStatus is the rule of the schedule in effect, and the limits it
+// sets
+type ScheduleStatus struct {
+	Rule               string // empty outside of all rules
+	MaxPeerUploadRate  uint64 // 0 for no limit
+	MaxConnections     int    // 0 for no limit
+	ReproviderInterval time.Duration
+}
+
+type scheduleState struct {
+	lk     sync.Mutex
+	status ScheduleStatus
+}
+
+// ScheduleStatus returns the rule of the schedule in effect
+func (n *IpfsNode) ScheduleStatus() ScheduleStatus {
+	if n.schedule == nil {
+		return ScheduleStatus{}
+	}
+	n.schedule.lk.Lock()
+	defer n.schedule.lk.Unlock()
+	return n.schedule.status
+}
+
+// runSchedule applies the limits of the rule matching the time of day every
+// scheduleCheckInterval, until ctx is done
+func (n *IpfsNode) runSchedule(ctx context.Context) {
+	n.checkSchedule()
+
+	tick := n.Clock().NewTicker(scheduleCheckInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C():
+			n.checkSchedule()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (n *IpfsNode) checkSchedule() {
+	cfg, err := n.Repo.Config()
+	if err != nil {
+		log.Errorf("failed to read the schedule: %s", err)
+		return
+	}
+	rules, err := parseSchedule(cfg.Schedule)
+	if err != nil {
+		log.Errorf("keeping the current limits: %s", err)
+		return
+	}
+	serve, err := bitswapServeConfig(cfg.Bitswap)
+	if err != nil {
+		log.Errorf("keeping the current limits: %s", err)
+		return
+	}
+
+	st := ScheduleStatus{
+		MaxPeerUploadRate:  serve.PeerBandwidth,
+		ReproviderInterval: n.reprovideInterval,
+	}
+	if r := activeRule(rules, n.Clock().Now()); r != nil {
+		st.Rule = r.name
+		if r.hasRate {
+			st.MaxPeerUploadRate = r.rate
+		}
+		st.MaxConnections = r.maxConns
+		if r.reprovide > 0 {
+			st.ReproviderInterval = r.reprovide
+		}
+	}
+
+	n.schedule.lk.Lock()
+	if n.schedule.status.Rule != st.Rule {
+		if st.Rule == "" {
+			log.Infof("schedule: leaving rule %q", n.schedule.status.Rule)
+		} else {
+			log.Infof("schedule: applying rule %q", st.Rule)
+		}
+	}
+	n.schedule.status = st
+	n.schedule.lk.Unlock()
+
+	if bs, ok := n.Exchange.(*bitswap.Bitswap); ok && bs.PeerBandwidth() != st.MaxPeerUploadRate {
+		bs.SetPeerBandwidth(st.MaxPeerUploadRate)
+	}
+
+	// the low power mode sets its own connection limit and reprovide
+	// interval
+	if n.PowerMode() == PowerLow {
+		return
+	}
+	// a disabled reprovider stays disabled
+	if n.reprovideInterval > 0 && n.Reprovider.Interval() != st.ReproviderInterval {
+		n.Reprovider.SetInterval(st.ReproviderInterval)
+	}
+	if st.MaxConnections > 0 {
+		n.trimConnections(st.MaxConnections)
+	}
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
+func TestActiveRule(t *testing.T) {
+	rules, err := parseSchedule(config.Schedule{Rules: []config.ScheduleRule{
+		{Name: "night", Days: []string{"Mon", "tue"}, Start: "23:00", End: "06:30", MaxPeerUploadRate: "0"},
+		{Name: "day", Start: "09:00", End: "18:00", MaxPeerUploadRate: "64KB", MaxConnections: 50},
+		{Name: "sunday", Days: []string{"sun"}, Start: "00:00", End: "00:00", ReproviderInterval: "6h"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2017-05-01 is a monday
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2017, 5, day, hour, min, 0, 0, time.Local)
+	}
+	for _, c := range []struct {
+		t    time.Time
+		rule string
+	}{
+		{at(1, 23, 0), "night"},
+		{at(2, 3, 0), "night"},   // tuesday morning, the monday night window
+		{at(2, 6, 30), ""},       // end excluded
+		{at(1, 3, 0), ""},        // monday morning, the sunday night window
+		{at(3, 2, 0), "night"},   // wednesday morning, the tuesday night window
+		{at(3, 23, 30), ""},      // wednesday night
+		{at(1, 12, 0), "day"},    // every day
+		{at(7, 12, 0), "day"},    // first match wins
+		{at(7, 20, 0), "sunday"}, // whole day
+		{at(2, 8, 59), ""},
+	} {
+		r := activeRule(rules, c.t)
+		name := ""
+		if r != nil {
+			name = r.name
+		}
+		if name != c.rule {
+			t.Errorf("at %s: expected rule %q, got %q", c.t.Format("Mon 15:04"), c.rule, name)
+		}
+	}
+
+	if r := rules[1]; !r.hasRate || r.rate != 64000 || r.maxConns != 50 {
+		t.Fatalf("unexpected limits of the day rule: %+v", r)
+	}
+	if r := rules[0]; !r.hasRate || r.rate != 0 {
+		t.Fatalf("expected the night rule to lift the upload limit: %+v", r)
+	}
+	if r := rules[2]; r.hasRate || r.reprovide != 6*time.Hour {
+		t.Fatalf("unexpected limits of the sunday rule: %+v", r)
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, r := range []config.ScheduleRule{
+		{Start: "9:00pm", End: "06:00"},
+		{Start: "21:00", End: "24:00"},
+		{Days: []string{"monday"}, Start: "21:00", End: "06:00"},
+		{Start: "21:00", End: "06:00", MaxPeerUploadRate: "fast"},
+		{Start: "21:00", End: "06:00", MaxConnections: -1},
+		{Start: "21:00", End: "06:00", ReproviderInterval: "0"},
+	} {
+		if _, err := parseSchedule(config.Schedule{Rules: []config.ScheduleRule{r}}); err == nil {
+			t.Errorf("expected an error for %+v", r)
+		}
+	}
+}
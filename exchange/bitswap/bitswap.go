@@ -49,18 +49,15 @@ var (
 	provideKeysBufferSize = 2048
 	provideWorkerMax      = 512
 
+	// the sizes above, in low memory mode
+	lowMemHasBlockBufferSize    = 64
+	lowMemProvideKeysBufferSize = 512
+	lowMemProvideWorkerMax      = 16
+
 	// the 1<<18+15 is to observe old file chunks that are 1<<18 + 14 in size
 	metricsBuckets = []float64{1 << 6, 1 << 10, 1 << 14, 1 << 18, 1<<18 + 15, 1 << 22}
 )
 
-func init() {
-	if flags.LowMemMode {
-		HasBlockBufferSize = 64
-		provideKeysBufferSize = 512
-		provideWorkerMax = 16
-	}
-}
-
 var rebroadcastDelay = delay.Fixed(time.Minute)
 
 // New initializes a BitSwap instance that communicates over the provided
@@ -69,6 +66,14 @@ var rebroadcastDelay = delay.Fixed(time.Minute)
 // Runs until context is cancelled.
 func New(parent context.Context, p peer.ID, network bsnet.BitSwapNetwork,
 	bstore blockstore.Blockstore, nice bool) exchange.Interface {
+	return NewWithServeConfig(parent, p, network, bstore, nice, decision.ServeConfig{}, flags.LowMemMode)
+}
+
+// NewWithServeConfig is like New, but serves blocks to other peers within
+// the limits of sc. When lowMem is set, the buffers of the instance and its
+// number of provide workers are reduced, at the cost of performance.
+func NewWithServeConfig(parent context.Context, p peer.ID, network bsnet.BitSwapNetwork,
+	bstore blockstore.Blockstore, nice bool, sc decision.ServeConfig, lowMem bool) exchange.Interface {
 
 	// important to use provided parent context (since it may include important
 	// loggable data). It's probably not a good idea to allow bitswap to be
@@ -84,6 +89,11 @@ func New(parent context.Context, p peer.ID, network bsnet.BitSwapNetwork,
 	allHist := metrics.NewCtx(ctx, "recv_all_blocks_bytes", "Summary of all"+
 		" data blocks recived").Histogram(metricsBuckets)
 
+	hasBlockBufferSize, provideKeysBufSize, provideWorkers := HasBlockBufferSize, provideKeysBufferSize, provideWorkerMax
+	if lowMem {
+		hasBlockBufferSize, provideKeysBufSize, provideWorkers = lowMemHasBlockBufferSize, lowMemProvideKeysBufferSize, lowMemProvideWorkerMax
+	}
+
 	notif := notifications.New()
 	px := process.WithTeardown(func() error {
 		notif.Shutdown()
@@ -91,19 +101,25 @@ func New(parent context.Context, p peer.ID, network bsnet.BitSwapNetwork,
 	})
 
 	bs := &Bitswap{
-		blockstore:    bstore,
-		notifications: notif,
-		engine:        decision.NewEngine(ctx, bstore), // TODO close the engine with Close() method
-		network:       network,
-		findKeys:      make(chan *blockRequest, sizeBatchRequestChan),
-		process:       px,
-		newBlocks:     make(chan *cid.Cid, HasBlockBufferSize),
-		provideKeys:   make(chan *cid.Cid, provideKeysBufferSize),
-		wm:            NewWantManager(ctx, network),
+		blockstore:     bstore,
+		notifications:  notif,
+		engine:         decision.NewEngineWithConfig(ctx, bstore, sc), // TODO close the engine with Close() method
+		network:        network,
+		findKeys:       make(chan *blockRequest, sizeBatchRequestChan),
+		process:        px,
+		newBlocks:      make(chan *cid.Cid, hasBlockBufferSize),
+		provideKeys:    make(chan *cid.Cid, provideKeysBufSize),
+		wm:             NewWantManager(ctx, network),
+		taskWorkers:    TaskWorkerCount,
+		provideWorkers: provideWorkers,
 
 		dupMetric: dupHist,
 		allMetric: allHist,
 	}
+	if sc.MaxConcurrentSends > 0 && sc.MaxConcurrentSends < bs.taskWorkers {
+		// blocks are sent synchronously by the task workers
+		bs.taskWorkers = sc.MaxConcurrentSends
+	}
 	go bs.wm.Run()
 	network.SetDelegate(bs)
 
@@ -152,6 +168,11 @@ type Bitswap struct {
 
 	process process.Process
 
+	// taskWorkers is the number of workers sending blocks to other peers
+	taskWorkers int
+	// provideWorkers is the maximum number of blocks provided at once
+	provideWorkers int
+
 	// Counters for various statistics
 	counterLk      sync.Mutex
 	blocksRecvd    int
@@ -227,6 +248,18 @@ func (bs *Bitswap) LedgerForPeer(p peer.ID) *decision.Receipt {
 	return bs.engine.LedgerForPeer(p)
 }
 
+// PeerBandwidth returns the bytes per second sent to each peer, 0 for no
+// limit
+func (bs *Bitswap) PeerBandwidth() uint64 {
+	return bs.engine.PeerBandwidth()
+}
+
+// SetPeerBandwidth changes the bytes per second sent to each peer, 0 for no
+// limit
+func (bs *Bitswap) SetPeerBandwidth(rate uint64) {
+	bs.engine.SetPeerBandwidth(rate)
+}
+
 // GetBlocks returns a channel where the caller may receive blocks that
 // correspond to the provided |keys|. Returns an error if BitSwap is unable to
 // begin this request within the deadline enforced by the context.
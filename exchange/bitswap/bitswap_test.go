@@ -19,6 +19,8 @@ import (
 
 	detectrace "github.com/ipfs/go-ipfs/Godeps/_workspace/src/github.com/jbenet/go-detect-race"
 
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	ds_sync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
 	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 	p2ptestutil "gx/ipfs/Qma2j8dYePrvN5DoNgwh1uAuu3FFtEtrUQFmr737ws8nCp/go-libp2p-netutil"
 )
@@ -44,6 +46,30 @@ func TestClose(t *testing.T) {
 	bitswap.Exchange.GetBlock(context.Background(), block.Cid())
 }
 
+func TestLowMem(t *testing.T) {
+	vnet := getVirtualNetwork()
+	ctx := context.Background()
+	for _, lowMem := range []bool{false, true} {
+		pinfo := p2ptestutil.RandTestBogusIdentityOrFatal(t)
+		bstore := blockstore.NewBlockstore(ds_sync.MutexWrap(ds.NewMapDatastore()))
+		bs := NewWithServeConfig(ctx, pinfo.ID(), vnet.Adapter(pinfo), bstore, true, decision.ServeConfig{}, lowMem).(*Bitswap)
+
+		st, err := bs.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bufSize, workers := HasBlockBufferSize, provideWorkerMax
+		if lowMem {
+			bufSize, workers = lowMemHasBlockBufferSize, lowMemProvideWorkerMax
+		}
+		if st.ProvideBufCap != bufSize || bs.provideWorkers != workers {
+			t.Fatalf("lowMem %t: expected a buffer of %d and %d provide workers, got %d and %d",
+				lowMem, bufSize, workers, st.ProvideBufCap, bs.provideWorkers)
+		}
+		bs.Close()
+	}
+}
+
 func TestProviderForKeyButNetworkCannotFind(t *testing.T) { // TODO revisit this
 
 	rs := mockrouting.NewServer()
@@ -3,6 +3,7 @@ package decision
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	context "context"
@@ -65,7 +66,28 @@ type Envelope struct {
 	Sent func()
 }
 
+// ServeConfig limits how the engine serves blocks to other peers. The zero
+// value serves every peer as fast as possible.
+type ServeConfig struct {
+	// Filter, if set, is called when a peer wants a block; the requests of
+	// peers it returns false for are ignored. received is the number of bytes
+	// the peer sent us since it connected.
+	Filter func(p peer.ID, received uint64) bool
+
+	// PeerBandwidth caps the bytes per second sent to each peer, 0 for no
+	// limit.
+	PeerBandwidth uint64
+
+	// MaxConcurrentSends caps the number of blocks sent at the same time, 0
+	// for the default. It is enforced by the caller running the outbox.
+	MaxConcurrentSends int
+}
+
 type Engine struct {
+	// peerBandwidth is serve.PeerBandwidth, changed with SetPeerBandwidth.
+	// It is accessed atomically, and first for its 64 bit alignment.
+	peerBandwidth uint64
+
 	// peerRequestQueue is a priority queue of requests received from peers.
 	// Requests are popped from the queue, packaged up, and placed in the
 	// outbox.
@@ -89,10 +111,19 @@ type Engine struct {
 	ledgerMap map[peer.ID]*ledger
 
 	ticker *time.Ticker
+
+	serve ServeConfig
 }
 
 func NewEngine(ctx context.Context, bs bstore.Blockstore) *Engine {
+	return NewEngineWithConfig(ctx, bs, ServeConfig{})
+}
+
+// NewEngineWithConfig returns an engine serving blocks according to sc
+func NewEngineWithConfig(ctx context.Context, bs bstore.Blockstore, sc ServeConfig) *Engine {
 	e := &Engine{
+		serve:            sc,
+		peerBandwidth:    sc.PeerBandwidth,
 		ledgerMap:        make(map[peer.ID]*ledger),
 		bs:               bs,
 		peerRequestQueue: newPRQ(),
@@ -240,6 +271,9 @@ func (e *Engine) MessageReceived(p peer.ID, m bsmsg.BitSwapMessage) error {
 		} else {
 			log.Debugf("wants %s - %d", entry.Cid, entry.Priority)
 			l.Wants(entry.Cid, entry.Priority)
+			if !e.serves(l) {
+				continue
+			}
 			if exists, err := e.bs.Has(entry.Cid); err == nil && exists {
 				e.peerRequestQueue.Push(entry.Entry, p)
 				newWorkExists = true
@@ -259,7 +293,7 @@ func (e *Engine) addBlock(block blocks.Block) {
 
 	for _, l := range e.ledgerMap {
 		l.lk.Lock()
-		if entry, ok := l.WantListContains(block.Cid()); ok {
+		if entry, ok := l.WantListContains(block.Cid()); ok && e.serves(l) {
 			e.peerRequestQueue.Push(entry, l.Partner)
 			work = true
 		}
@@ -295,9 +329,44 @@ func (e *Engine) MessageSent(p peer.ID, m bsmsg.BitSwapMessage) error {
 		e.peerRequestQueue.Remove(block.Cid(), p)
 	}
 
+	if rate := atomic.LoadUint64(&e.peerBandwidth); rate > 0 {
+		// sending more is allowed once the bytes sent so far are paid for
+		now := time.Now()
+		if l.paidUntil.Before(now) {
+			l.paidUntil = now
+		}
+		for _, block := range m.Blocks() {
+			l.paidUntil = l.paidUntil.Add(time.Duration(uint64(len(block.RawData())) * uint64(time.Second) / rate))
+		}
+		if l.paidUntil.After(now) {
+			e.peerRequestQueue.throttle(p, l.paidUntil)
+		}
+	}
+
 	return nil
 }
 
+// PeerBandwidth returns the bytes per second sent to each peer, 0 for no
+// limit
+func (e *Engine) PeerBandwidth() uint64 {
+	return atomic.LoadUint64(&e.peerBandwidth)
+}
+
+// SetPeerBandwidth changes the bytes per second sent to each peer, 0 for no
+// limit. The blocks already sent are paid for at the previous rate.
+func (e *Engine) SetPeerBandwidth(rate uint64) {
+	atomic.StoreUint64(&e.peerBandwidth, rate)
+}
+
+// serves returns whether the requests of the partner of l are served.
+// l must be locked.
+func (e *Engine) serves(l *ledger) bool {
+	if e.serve.Filter == nil {
+		return true
+	}
+	return e.serve.Filter(l.Partner, l.Accounting.BytesRecv)
+}
+
 func (e *Engine) PeerConnected(p peer.ID) {
 	e.lock.Lock()
 	l, ok := e.ledgerMap[p]
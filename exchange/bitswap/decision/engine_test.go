@@ -212,3 +212,38 @@ func stringsComplement(set, subset []string) []string {
 	}
 	return complement
 }
+
+func TestServeFilter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore()))
+	for _, letter := range []string{"a", "b"} {
+		if err := bs.Put(blocks.NewBlock([]byte(letter))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	allowed := testutil.RandPeerIDFatal(t)
+	stranger := testutil.RandPeerIDFatal(t)
+	e := NewEngineWithConfig(ctx, bs, ServeConfig{
+		Filter: func(p peer.ID, received uint64) bool {
+			return p == allowed || received > 0
+		},
+	})
+
+	partnerWants(e, []string{"a"}, stranger)
+	partnerWants(e, []string{"b"}, allowed)
+	if err := checkHandledInOrder(t, e, []string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// once the stranger sent us a block, its requests are served
+	m := message.New(false)
+	m.AddBlock(blocks.NewBlock([]byte("z")))
+	e.MessageReceived(stranger, m)
+	partnerWants(e, []string{"a"}, stranger)
+	if err := checkHandledInOrder(t, e, []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+}
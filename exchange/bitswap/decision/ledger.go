@@ -43,6 +43,10 @@ type ledger struct {
 	// to a given peer
 	sentToPeer map[string]time.Time
 
+	// paidUntil is the time at which the bytes sent to Partner are within
+	// the bandwidth limit, if there is one
+	paidUntil time.Time
+
 	// ref is the reference count for this ledger, its used to ensure we
 	// don't drop the reference to this ledger in multi-connection scenarios
 	ref int
@@ -23,10 +23,11 @@ type peerRequestQueue interface {
 
 func newPRQ() *prq {
 	return &prq{
-		taskMap:  make(map[string]*peerRequestTask),
-		partners: make(map[peer.ID]*activePartner),
-		frozen:   make(map[peer.ID]*activePartner),
-		pQueue:   pq.New(partnerCompare),
+		taskMap:   make(map[string]*peerRequestTask),
+		partners:  make(map[peer.ID]*activePartner),
+		frozen:    make(map[peer.ID]*activePartner),
+		throttled: make(map[peer.ID]*activePartner),
+		pQueue:    pq.New(partnerCompare),
 	}
 }
 
@@ -43,18 +44,28 @@ type prq struct {
 	partners map[peer.ID]*activePartner
 
 	frozen map[peer.ID]*activePartner
+
+	// throttled lists the partners that went over their bandwidth limit
+	throttled map[peer.ID]*activePartner
 }
 
-// Push currently adds a new peerRequestTask to the end of the list
-func (tl *prq) Push(entry *wantlist.Entry, to peer.ID) {
-	tl.lock.Lock()
-	defer tl.lock.Unlock()
-	partner, ok := tl.partners[to]
+// partner returns the activePartner of p, creating it if needed. tl must be
+// locked.
+func (tl *prq) partner(p peer.ID) *activePartner {
+	partner, ok := tl.partners[p]
 	if !ok {
 		partner = newActivePartner()
 		tl.pQueue.Push(partner)
-		tl.partners[to] = partner
+		tl.partners[p] = partner
 	}
+	return partner
+}
+
+// Push currently adds a new peerRequestTask to the end of the list
+func (tl *prq) Push(entry *wantlist.Entry, to peer.ID) {
+	tl.lock.Lock()
+	defer tl.lock.Unlock()
+	partner := tl.partner(to)
 
 	partner.activelk.Lock()
 	defer partner.activelk.Unlock()
@@ -96,7 +107,7 @@ func (tl *prq) Pop() *peerRequestTask {
 	partner := tl.pQueue.Pop().(*activePartner)
 
 	var out *peerRequestTask
-	for partner.taskQueue.Len() > 0 && partner.freezeVal == 0 {
+	for partner.taskQueue.Len() > 0 && partner.freezeVal == 0 && partner.throttledUntil.IsZero() {
 		out = partner.taskQueue.Pop().(*peerRequestTask)
 		delete(tl.taskMap, out.Key())
 		if out.trash {
@@ -141,6 +152,17 @@ func (tl *prq) Remove(k *cid.Cid, p peer.ID) {
 	tl.lock.Unlock()
 }
 
+// throttle stops sending blocks to p until the given time
+func (tl *prq) throttle(p peer.ID, until time.Time) {
+	tl.lock.Lock()
+	defer tl.lock.Unlock()
+
+	partner := tl.partner(p)
+	partner.throttledUntil = until
+	tl.throttled[p] = partner
+	tl.pQueue.Update(partner.index)
+}
+
 func (tl *prq) fullThaw() {
 	tl.lock.Lock()
 	defer tl.lock.Unlock()
@@ -163,6 +185,16 @@ func (tl *prq) thawRound() {
 		}
 		tl.pQueue.Update(partner.index)
 	}
+
+	now := time.Now()
+	for id, partner := range tl.throttled {
+		if partner.throttledUntil.After(now) {
+			continue
+		}
+		partner.throttledUntil = time.Time{}
+		delete(tl.throttled, id)
+		tl.pQueue.Update(partner.index)
+	}
 }
 
 type peerRequestTask struct {
@@ -238,6 +270,9 @@ type activePartner struct {
 
 	freezeVal int
 
+	// throttledUntil is set while the peer is over its bandwidth limit
+	throttledUntil time.Time
+
 	// priority queue of tasks belonging to this peer
 	taskQueue pq.PQ
 }
@@ -264,6 +299,11 @@ func partnerCompare(a, b pq.Elem) bool {
 		return true
 	}
 
+	// throttled peers can't be served anyway
+	if pa.throttledUntil.IsZero() != pb.throttledUntil.IsZero() {
+		return pa.throttledUntil.IsZero()
+	}
+
 	if pa.freezeVal > pb.freezeVal {
 		return false
 	}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ipfs/go-ipfs/exchange/bitswap/wantlist"
 	"github.com/ipfs/go-ipfs/thirdparty/testutil"
@@ -73,6 +74,30 @@ func TestPushPop(t *testing.T) {
 	}
 }
 
+func TestThrottle(t *testing.T) {
+	prq := newPRQ()
+	fast := testutil.RandPeerIDFatal(t)
+	slow := testutil.RandPeerIDFatal(t)
+
+	c := cid.NewCidV0(u.Hash([]byte("a")))
+	prq.Push(&wantlist.Entry{Cid: c, Priority: 1}, slow)
+	prq.Push(&wantlist.Entry{Cid: c, Priority: 1}, fast)
+	prq.throttle(slow, time.Now().Add(time.Hour))
+
+	if task := prq.Pop(); task == nil || task.Target != fast {
+		t.Fatal("expected the task of the peer that isn't throttled")
+	}
+	if task := prq.Pop(); task != nil {
+		t.Fatal("expected no task while the peer is throttled")
+	}
+
+	prq.throttle(slow, time.Now().Add(-time.Second))
+	prq.thawRound()
+	if task := prq.Pop(); task == nil || task.Target != slow {
+		t.Fatal("expected the task of the peer once it isn't throttled anymore")
+	}
+}
+
 // This test checks that peers wont starve out other peers
 func TestPeerRepeats(t *testing.T) {
 	prq := newPRQ()
@@ -191,6 +191,11 @@ func (m *impl) ToProtoV0() *pb.Message {
 	}
 	pbm.Wantlist.Full = proto.Bool(m.full)
 	for _, b := range m.Blocks() {
+		// the receiver hashes the blocks of V0 messages into CIDv0s with
+		// sha2-256, any other block would end up under the wrong CID
+		if b.Cid().Prefix().Version != 0 {
+			continue
+		}
 		pbm.Blocks = append(pbm.Blocks, b.RawData())
 	}
 	return pbm
@@ -198,3 +198,26 @@ func TestDuplicates(t *testing.T) {
 		t.Fatal("Duplicate in BitSwapMessage")
 	}
 }
+
+func TestToProtoV0SkipsNonV0Blocks(t *testing.T) {
+	v0 := blocks.NewBlock([]byte("v0 block"))
+
+	data := []byte("v1 block")
+	v1, err := blocks.NewBlockWithCid(data, cid.NewCidV1(cid.Raw, u.Hash(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(true)
+	m.AddBlock(v0)
+	m.AddBlock(v1)
+
+	pbm := m.ToProtoV0()
+	if len(pbm.GetBlocks()) != 1 || !bytes.Equal(pbm.GetBlocks()[0], v0.RawData()) {
+		t.Fatal("expected only the CIDv0 block in a V0 message")
+	}
+
+	if len(m.ToProtoV1().GetPayload()) != 2 {
+		t.Fatal("expected both blocks in a V1 message")
+	}
+}
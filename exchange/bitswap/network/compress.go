@@ -0,0 +1,156 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"gx/ipfs/QmRg1gKTHzc3CZXSKzem8aR4E3TubFhbgXwfVuWnSK5CC5/go-metrics-interface"
+	inet "gx/ipfs/QmRscs8KxrSmSv4iuevHv8JfuUzHBMoqiaHzxfDRiksd6e/go-libp2p-net"
+	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
+)
+
+// Messages on compressed streams are framed as the uvarint length of the
+// frame, a byte telling whether the rest is deflated, and the protobuf
+// message itself. Messages that don't compress are sent as is.
+const (
+	frameRaw     = 0
+	frameDeflate = 1
+)
+
+// ErrMessageTooLarge is returned when a message inflates past the maximum
+// message size.
+var ErrMessageTooLarge = errors.New("bitswap message too large")
+
+var (
+	compressMetricsOnce sync.Once
+
+	// raw and compressed sizes of the messages sent and received on
+	// compressed streams, their ratio is the compression ratio
+	sentRawBytes  metrics.Counter
+	sentBytes     metrics.Counter
+	recvRawBytes  metrics.Counter
+	recvBytes     metrics.Counter
+	sentRatioHist metrics.Histogram
+)
+
+func initCompressMetrics() {
+	compressMetricsOnce.Do(func() {
+		sentRawBytes = metrics.New("bitswap.compress.sent_raw_bytes_total",
+			"Size of the messages sent on compressed streams, before compression").Counter()
+		sentBytes = metrics.New("bitswap.compress.sent_bytes_total",
+			"Size of the messages sent on compressed streams, after compression").Counter()
+		recvRawBytes = metrics.New("bitswap.compress.recv_raw_bytes_total",
+			"Size of the messages received on compressed streams, after decompression").Counter()
+		recvBytes = metrics.New("bitswap.compress.recv_bytes_total",
+			"Size of the messages received on compressed streams, before decompression").Counter()
+		sentRatioHist = metrics.New("bitswap.compress.sent_ratio",
+			"Compression ratio of the messages sent on compressed streams").Histogram([]float64{1, 1.1, 1.5, 2, 4, 8, 16})
+	})
+}
+
+var deflaters = sync.Pool{
+	New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+// writeDeflated writes m to w as one frame, deflated if that makes it
+// smaller
+func writeDeflated(w io.Writer, m proto.Message) error {
+	initCompressMetrics()
+
+	raw, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fw := deflaters.Get().(*flate.Writer)
+	fw.Reset(&buf)
+	_, err = fw.Write(raw)
+	if err == nil {
+		err = fw.Close()
+	}
+	deflaters.Put(fw)
+	if err != nil {
+		return err
+	}
+
+	kind, payload := byte(frameDeflate), buf.Bytes()
+	if len(payload) >= len(raw) {
+		kind, payload = frameRaw, raw
+	}
+
+	hdr := make([]byte, binary.MaxVarintLen64+1)
+	n := binary.PutUvarint(hdr, uint64(len(payload)+1))
+	hdr[n] = kind
+	if _, err := w.Write(hdr[:n+1]); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+
+	sentRawBytes.Add(float64(len(raw)))
+	sentBytes.Add(float64(len(payload)))
+	if len(payload) > 0 {
+		sentRatioHist.Observe(float64(len(raw)) / float64(len(payload)))
+	}
+	return nil
+}
+
+// deflateReader reads the frames written by writeDeflated. It implements
+// the gogo-protobuf io.Reader interface.
+type deflateReader struct {
+	r *bufio.Reader
+}
+
+func newDeflateReader(r io.Reader) *deflateReader {
+	initCompressMetrics()
+	return &deflateReader{r: bufio.NewReader(r)}
+}
+
+func (d *deflateReader) ReadMsg(m proto.Message) error {
+	size, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return err
+	}
+	if size == 0 || size > inet.MessageSizeMax {
+		return fmt.Errorf("invalid bitswap frame size %d", size)
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(d.r, frame); err != nil {
+		return err
+	}
+	kind, payload := frame[0], frame[1:]
+
+	raw := payload
+	switch kind {
+	case frameRaw:
+	case frameDeflate:
+		fr := flate.NewReader(bytes.NewReader(payload))
+		raw, err = ioutil.ReadAll(io.LimitReader(fr, inet.MessageSizeMax+1))
+		fr.Close()
+		if err != nil {
+			return err
+		}
+		if len(raw) > inet.MessageSizeMax {
+			return ErrMessageTooLarge
+		}
+	default:
+		return fmt.Errorf("unknown bitswap frame type %d", kind)
+	}
+
+	recvBytes.Add(float64(len(payload)))
+	recvRawBytes.Add(float64(len(raw)))
+	return proto.Unmarshal(raw, m)
+}
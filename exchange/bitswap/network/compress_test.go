@@ -0,0 +1,60 @@
+package network
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	blocks "github.com/ipfs/go-ipfs/blocks"
+	bsmsg "github.com/ipfs/go-ipfs/exchange/bitswap/message"
+)
+
+func TestDeflateRoundTrip(t *testing.T) {
+	random := make([]byte, 4096)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+	compressible := blocks.NewBlock(bytes.Repeat([]byte("ipfs"), 4096))
+	incompressible := blocks.NewBlock(random)
+
+	var buf bytes.Buffer
+	for _, b := range []blocks.Block{compressible, incompressible} {
+		m := bsmsg.New(false)
+		m.AddBlock(b)
+		if err := writeDeflated(&buf, m.ToProtoV1()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := newDeflateReader(&buf)
+	for _, b := range []blocks.Block{compressible, incompressible} {
+		m, err := bsmsg.FromPBReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := m.Blocks()
+		if len(got) != 1 || !bytes.Equal(got[0].RawData(), b.RawData()) {
+			t.Fatal("received block differs from the sent one")
+		}
+	}
+}
+
+func TestDeflateFrameSizes(t *testing.T) {
+	b := blocks.NewBlock(bytes.Repeat([]byte("a"), 1<<16))
+	m := bsmsg.New(false)
+	m.AddBlock(b)
+
+	var buf bytes.Buffer
+	if err := writeDeflated(&buf, m.ToProtoV1()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(b.RawData()) {
+		t.Fatalf("expected the message to be compressed, got %d bytes", buf.Len())
+	}
+
+	// a frame announcing more than the maximum message size is rejected
+	bad := bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0x0f, frameRaw})
+	if _, err := bsmsg.FromPBReader(newDeflateReader(bad)); err == nil {
+		t.Fatal("expected an oversized frame to be rejected")
+	}
+}
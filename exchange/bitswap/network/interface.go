@@ -15,6 +15,10 @@ var (
 	ProtocolBitswapNoVers protocol.ID = "/ipfs/bitswap"
 
 	ProtocolBitswap protocol.ID = "/ipfs/bitswap/1.1.0"
+
+	// ProtocolBitswapDeflate carries the messages of ProtocolBitswap,
+	// compressed with deflate. It is only spoken by nodes that enable it.
+	ProtocolBitswapDeflate protocol.ID = "/ipfs/bitswap/1.1.0/deflate"
 )
 
 // BitSwapNetwork provides network connectivity for BitSwap sessions
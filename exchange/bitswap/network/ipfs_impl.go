@@ -38,12 +38,24 @@ func NewFromIpfsHost(host host.Host, r routing.ContentRouting) BitSwapNetwork {
 	return &bitswapNetwork
 }
 
+// NewFromIpfsHostWithCompression returns a BitSwapNetwork that compresses
+// the messages exchanged with the peers that support it too.
+func NewFromIpfsHostWithCompression(host host.Host, r routing.ContentRouting) BitSwapNetwork {
+	bsnet := NewFromIpfsHost(host, r).(*impl)
+	bsnet.compress = true
+	host.SetStreamHandler(ProtocolBitswapDeflate, bsnet.handleNewStream)
+	return bsnet
+}
+
 // impl transforms the ipfs network interface, which sends and receives
 // NetMessage objects, into the bitswap network interface.
 type impl struct {
 	host    host.Host
 	routing routing.ContentRouting
 
+	// offer ProtocolBitswapDeflate when opening streams
+	compress bool
+
 	// inbound messages from the network are forwarded to the receiver
 	receiver Receiver
 }
@@ -71,6 +83,11 @@ func msgToStream(ctx context.Context, s inet.Stream, msg bsmsg.BitSwapMessage) e
 	}
 
 	switch s.Protocol() {
+	case ProtocolBitswapDeflate:
+		if err := writeDeflated(s, msg.ToProtoV1()); err != nil {
+			log.Debugf("error: %s", err)
+			return err
+		}
 	case ProtocolBitswap:
 		if err := msg.ToNetV1(s); err != nil {
 			log.Debugf("error: %s", err)
@@ -109,6 +126,9 @@ func (bsnet *impl) newStreamToPeer(ctx context.Context, p peer.ID) (inet.Stream,
 		return nil, err
 	}
 
+	if bsnet.compress {
+		return bsnet.host.NewStream(ctx, p, ProtocolBitswapDeflate, ProtocolBitswap, ProtocolBitswapOne, ProtocolBitswapNoVers)
+	}
 	return bsnet.host.NewStream(ctx, p, ProtocolBitswap, ProtocolBitswapOne, ProtocolBitswapNoVers)
 }
 
@@ -183,7 +203,12 @@ func (bsnet *impl) handleNewStream(s inet.Stream) {
 		return
 	}
 
-	reader := ggio.NewDelimitedReader(s, inet.MessageSizeMax)
+	var reader ggio.Reader
+	if s.Protocol() == ProtocolBitswapDeflate {
+		reader = newDeflateReader(s)
+	} else {
+		reader = ggio.NewDelimitedReader(s, inet.MessageSizeMax)
+	}
 	for {
 		received, err := bsmsg.FromPBReader(reader)
 		if err != nil {
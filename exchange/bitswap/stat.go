@@ -8,6 +8,7 @@ import (
 
 type Stat struct {
 	ProvideBufLen   int
+	ProvideBufCap   int
 	Wantlist        []*cid.Cid
 	Peers           []string
 	BlocksReceived  int
@@ -21,6 +22,7 @@ type Stat struct {
 func (bs *Bitswap) Stat() (*Stat, error) {
 	st := new(Stat)
 	st.ProvideBufLen = len(bs.newBlocks)
+	st.ProvideBufCap = cap(bs.newBlocks)
 	st.Wantlist = bs.GetWantlist()
 	bs.counterLk.Lock()
 	st.BlocksReceived = bs.blocksRecvd
@@ -24,7 +24,7 @@ func (bs *Bitswap) startWorkers(px process.Process, ctx context.Context) {
 	})
 
 	// Start up workers to handle requests from other nodes for the data on this node
-	for i := 0; i < TaskWorkerCount; i++ {
+	for i := 0; i < bs.taskWorkers; i++ {
 		i := i
 		px.Go(func(px process.Process) {
 			bs.taskWorker(ctx, i)
@@ -87,7 +87,7 @@ func (bs *Bitswap) taskWorker(ctx context.Context, id int) {
 
 func (bs *Bitswap) provideWorker(px process.Process) {
 
-	limit := make(chan struct{}, provideWorkerMax)
+	limit := make(chan struct{}, bs.provideWorkers)
 
 	limitedGoProvide := func(k *cid.Cid, wid int) {
 		defer func() {
@@ -3,6 +3,7 @@ package reprovide
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	blocks "github.com/ipfs/go-ipfs/blocks/blockstore"
@@ -19,16 +20,25 @@ type Reprovider struct {
 
 	// The backing store for blocks to be provided
 	bstore blocks.Blockstore
+
+	lk       sync.Mutex
+	interval time.Duration
+	reset    chan struct{}
 }
 
 func NewReprovider(rsys routing.ContentRouting, bstore blocks.Blockstore) *Reprovider {
 	return &Reprovider{
 		rsys:   rsys,
 		bstore: bstore,
+		reset:  make(chan struct{}, 1),
 	}
 }
 
 func (rp *Reprovider) ProvideEvery(ctx context.Context, tick time.Duration) {
+	rp.lk.Lock()
+	rp.interval = tick
+	rp.lk.Unlock()
+
 	// dont reprovide immediately.
 	// may have just started the daemon and shutting it down immediately.
 	// probability( up another minute | uptime ) increases with uptime.
@@ -37,16 +47,39 @@ func (rp *Reprovider) ProvideEvery(ctx context.Context, tick time.Duration) {
 		select {
 		case <-ctx.Done():
 			return
+		case <-rp.reset:
+			// the new interval counts from now
+			after = time.After(rp.Interval())
 		case <-after:
 			err := rp.Reprovide(ctx)
 			if err != nil {
 				log.Debug(err)
 			}
-			after = time.After(tick)
+			after = time.After(rp.Interval())
 		}
 	}
 }
 
+// Interval returns the time between two reprovides
+func (rp *Reprovider) Interval() time.Duration {
+	rp.lk.Lock()
+	defer rp.lk.Unlock()
+	return rp.interval
+}
+
+// SetInterval changes the time between two reprovides of a running
+// ProvideEvery. The next reprovide happens d from now.
+func (rp *Reprovider) SetInterval(d time.Duration) {
+	rp.lk.Lock()
+	rp.interval = d
+	rp.lk.Unlock()
+
+	select {
+	case rp.reset <- struct{}{}:
+	default:
+	}
+}
+
 func (rp *Reprovider) Reprovide(ctx context.Context) error {
 	keychan, err := rp.bstore.AllKeysChan(ctx)
 	if err != nil {
@@ -0,0 +1,178 @@
+// Package features keeps track of the optional features of go-ipfs, most of
+// them experimental, and of whether they are enabled.
+//
+// A feature can be enabled, by order of precedence, from the command line
+// (e.g. 'ipfs daemon --enable-pubsub-experiment'), from its environment
+// variable if it has one, or from the Experimental section of the config.
+package features
+
+import (
+	"os"
+	"sort"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+)
+
+var log = logging.Logger("features")
+
+// Names of the built in features
+const (
+	Filestore = "filestore"
+	Sharding  = "sharding"
+	Pubsub    = "pubsub"
+	Mplex     = "mplex"
+	LowMem    = "lowmem"
+)
+
+// Stability tells how much a feature can be relied upon
+type Stability string
+
+const (
+	Experimental Stability = "experimental"
+	Stable       Stability = "stable"
+	Deprecated   Stability = "deprecated"
+)
+
+// Feature describes an optional feature
+type Feature struct {
+	Name        string
+	Description string
+	Stability   Stability
+	Default     bool   // whether the feature is enabled when nothing says otherwise
+	Env         string // environment variable enabling the feature, if any
+}
+
+var registry = make(map[string]Feature)
+
+func init() {
+	Register(Feature{
+		Name:        Filestore,
+		Description: "Add files without copying them into the blockstore ('ipfs add --nocopy').",
+		Stability:   Experimental,
+	})
+	Register(Feature{
+		Name:        Sharding,
+		Description: "Shard all directories, regardless of their size.",
+		Stability:   Experimental,
+	})
+	Register(Feature{
+		Name:        Pubsub,
+		Description: "Publish-subscribe messaging ('ipfs pubsub').",
+		Stability:   Experimental,
+	})
+	Register(Feature{
+		Name:        Mplex,
+		Description: "Offer the go-multiplex stream muxer to peers.",
+		Stability:   Experimental,
+		Default:     true,
+	})
+	Register(Feature{
+		Name:        LowMem,
+		Description: "Reduce memory usage, at the cost of performance.",
+		Stability:   Experimental,
+		Env:         "IPFS_LOW_MEM",
+	})
+}
+
+// Register adds a feature to the registry. It panics if a feature with
+// the same name is already registered, and should be called from init.
+func Register(f Feature) {
+	if _, ok := registry[f.Name]; ok {
+		panic("feature registered twice: " + f.Name)
+	}
+	registry[f.Name] = f
+}
+
+// All returns all registered features, sorted by name
+func All() []Feature {
+	out := make([]Feature, 0, len(registry))
+	for _, f := range registry {
+		out = append(out, f)
+	}
+	sort.Sort(byName(out))
+	return out
+}
+
+type byName []Feature
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// Where a feature state comes from
+const (
+	SourceDefault = "default"
+	SourceConfig  = "config"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+type state struct {
+	enabled bool
+	source  string
+}
+
+// Set holds whether each feature is enabled for a node
+type Set struct {
+	states map[string]state
+}
+
+// New computes the state of every feature from the config, the environment
+// and the given command line overrides. Unknown feature names are ignored
+// with a warning.
+func New(exp config.Experiments, overrides map[string]bool) *Set {
+	s := &Set{states: make(map[string]state)}
+	for name, f := range registry {
+		s.states[name] = state{enabled: f.Default, source: SourceDefault}
+	}
+
+	// the flags predating the registry
+	if exp.FilestoreEnabled {
+		s.set(Filestore, true, SourceConfig)
+	}
+	if exp.ShardingEnabled {
+		s.set(Sharding, true, SourceConfig)
+	}
+
+	for name, on := range exp.Features {
+		s.set(name, on, SourceConfig)
+	}
+
+	for name, f := range registry {
+		if f.Env != "" && os.Getenv(f.Env) != "" {
+			s.set(name, true, SourceEnv)
+		}
+	}
+
+	for name, on := range overrides {
+		s.set(name, on, SourceFlag)
+	}
+	return s
+}
+
+func (s *Set) set(name string, on bool, source string) {
+	if _, ok := registry[name]; !ok {
+		log.Warningf("unknown feature %q in %s", name, source)
+		return
+	}
+	s.states[name] = state{enabled: on, source: source}
+}
+
+// Enabled returns whether the named feature is enabled. A nil Set has all
+// features in their default state.
+func (s *Set) Enabled(name string) bool {
+	if s == nil {
+		return registry[name].Default
+	}
+	return s.states[name].enabled
+}
+
+// Source returns where the state of the named feature comes from
+func (s *Set) Source(name string) string {
+	if s == nil {
+		return SourceDefault
+	}
+	return s.states[name].source
+}
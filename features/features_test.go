@@ -0,0 +1,79 @@
+package features
+
+import (
+	"os"
+	"testing"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
+func TestDefaults(t *testing.T) {
+	s := New(config.Experiments{}, nil)
+	for _, f := range All() {
+		if f.Env != "" && os.Getenv(f.Env) != "" {
+			continue
+		}
+		if s.Enabled(f.Name) != f.Default {
+			t.Errorf("%s: expected default state %t", f.Name, f.Default)
+		}
+		if s.Source(f.Name) != SourceDefault {
+			t.Errorf("%s: expected default source, got %s", f.Name, s.Source(f.Name))
+		}
+	}
+
+	var nilSet *Set
+	if nilSet.Enabled(Pubsub) || !nilSet.Enabled(Mplex) {
+		t.Fatal("nil set should report the defaults")
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	exp := config.Experiments{
+		FilestoreEnabled: true,
+		Features: map[string]bool{
+			Pubsub:    true,
+			Mplex:     false,
+			"unknown": true,
+		},
+	}
+
+	s := New(exp, map[string]bool{Pubsub: false})
+
+	if !s.Enabled(Filestore) || s.Source(Filestore) != SourceConfig {
+		t.Fatal("legacy config flag should enable the filestore")
+	}
+	if s.Enabled(Mplex) || s.Source(Mplex) != SourceConfig {
+		t.Fatal("config should disable mplex")
+	}
+	if s.Enabled(Pubsub) || s.Source(Pubsub) != SourceFlag {
+		t.Fatal("flag should override the config")
+	}
+	if s.Enabled("unknown") {
+		t.Fatal("unknown features should be ignored")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	old := os.Getenv("IPFS_LOW_MEM")
+	defer os.Setenv("IPFS_LOW_MEM", old)
+
+	os.Setenv("IPFS_LOW_MEM", "1")
+	s := New(config.Experiments{}, nil)
+	if !s.Enabled(LowMem) || s.Source(LowMem) != SourceEnv {
+		t.Fatal("environment variable should enable lowmem")
+	}
+
+	s = New(config.Experiments{}, map[string]bool{LowMem: false})
+	if s.Enabled(LowMem) {
+		t.Fatal("flag should override the environment")
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	Register(Feature{Name: Pubsub})
+}
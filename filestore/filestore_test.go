@@ -5,6 +5,7 @@ import (
 	"context"
 	"io/ioutil"
 	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/ipfs/go-ipfs/blocks/blockstore"
@@ -162,3 +163,46 @@ func TestDeletes(t *testing.T) {
 		}
 	}
 }
+
+func TestDenyList(t *testing.T) {
+	dir, fs := newTestFilestore(t)
+	fname, cids := randomFileAdd(t, fs, dir, 100)
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	denied := &posinfo.FilestoreNode{
+		PosInfo: &posinfo.PosInfo{FullPath: fname, Offset: 0},
+		Node:    dag.NewRawNode(append([]byte(nil), data[:20]...)),
+	}
+	list := cids[0].String() + "\n" + denied.Cid().String() + "\n"
+	deny, err := blockstore.ParseDenyList(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs := blockstore.NewDenyBlockstore(context.Background(), fs, deny)
+
+	// references stored before the list are neither served nor kept
+	if has, err := bs.Has(cids[0]); err != nil || has {
+		t.Fatalf("expected a denied reference to be missing, got %v, %v", has, err)
+	}
+	if _, err := bs.Get(cids[0]); err != blockstore.ErrBlockDenied {
+		t.Fatalf("expected ErrBlockDenied, got %v", err)
+	}
+	if has, err := fs.FileManager().Has(cids[0]); err != nil || has {
+		t.Fatalf("expected the denied reference to be deleted, got %v, %v", has, err)
+	}
+
+	// new references can't be added
+	if err := bs.Put(denied); err != blockstore.ErrBlockDenied {
+		t.Fatalf("expected ErrBlockDenied, got %v", err)
+	}
+	if has, err := fs.Has(denied.Cid()); err != nil || has {
+		t.Fatalf("expected the denied reference not to be stored, got %v, %v", has, err)
+	}
+
+	if _, err := bs.Get(cids[1]); err != nil {
+		t.Fatal(err)
+	}
+}
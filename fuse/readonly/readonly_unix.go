@@ -13,6 +13,7 @@ import (
 	core "github.com/ipfs/go-ipfs/core"
 	mdag "github.com/ipfs/go-ipfs/merkledag"
 	path "github.com/ipfs/go-ipfs/path"
+	ft "github.com/ipfs/go-ipfs/unixfs"
 	uio "github.com/ipfs/go-ipfs/unixfs/io"
 	ftpb "github.com/ipfs/go-ipfs/unixfs/pb"
 
@@ -128,6 +129,15 @@ func (s *Node) Attr(ctx context.Context, a *fuse.Attr) error {
 	default:
 		return fmt.Errorf("Invalid data type - %s", s.cached.GetType())
 	}
+
+	// show recorded metadata, without the write bits of this read-only
+	// filesystem
+	if s.cached.Mode != nil && s.cached.GetType() != ftpb.Data_Symlink {
+		a.Mode = a.Mode&os.ModeType | ft.Mode(s.cached)&^0222
+	}
+	if mtime := ft.ModTime(s.cached); !mtime.IsZero() {
+		a.Mtime = mtime
+	}
 	return nil
 }
 
@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs/go-ipfs/commands/files"
+	bal "github.com/ipfs/go-ipfs/importer/balanced"
+	chunk "github.com/ipfs/go-ipfs/importer/chunk"
+	h "github.com/ipfs/go-ipfs/importer/helpers"
+	trickle "github.com/ipfs/go-ipfs/importer/trickle"
+	mdtest "github.com/ipfs/go-ipfs/merkledag/test"
+)
+
+// The add benchmarks measure the throughput of the importer, from reading
+// the data to storing the blocks in an in-memory blockstore, so that the
+// cost of chunking, building and hashing the dag shows without the disk.
+// The larger sizes are skipped with -short.
+
+const (
+	benchSmall = 1 << 20
+	benchLarge = 64 << 20
+	benchHuge  = 512 << 20
+)
+
+func benchData(b *testing.B, size int64) []byte {
+	if size > benchSmall && testing.Short() {
+		b.Skip("large add benchmark skipped with -short")
+	}
+	data := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+type benchLayout func(db *h.DagBuilderHelper) error
+
+func balancedLayout(db *h.DagBuilderHelper) error {
+	_, err := bal.BalancedLayout(db)
+	return err
+}
+
+func trickleLayout(db *h.DagBuilderHelper) error {
+	_, err := trickle.TrickleLayout(db)
+	return err
+}
+
+// runAddBench adds what newReader returns b.N times, with the given layout
+// and leaves
+func runAddBench(b *testing.B, size int64, layout benchLayout, rawLeaves bool, newReader func() io.Reader) {
+	b.SetBytes(size)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dbp := h.DagBuilderParams{
+			Dagserv:   mdtest.Mock(),
+			Maxlinks:  h.DefaultLinksPerBlock,
+			RawLeaves: rawLeaves,
+		}
+		spl := chunk.NewSizeSplitter(newReader(), chunk.DefaultBlockSize)
+		if err := layout(dbp.New(spl)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func runAddMemBench(b *testing.B, size int64, layout benchLayout, rawLeaves bool) {
+	data := benchData(b, size)
+	runAddBench(b, size, layout, rawLeaves, func() io.Reader {
+		return bytes.NewReader(data)
+	})
+}
+
+// runAddFileBench adds a file of the given size, read as 'ipfs add' reads
+// the files it is given
+func runAddFileBench(b *testing.B, size int64, rawLeaves bool) {
+	data := benchData(b, size)
+	dir, err := ioutil.TempDir("", "add-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fpath := filepath.Join(dir, "data")
+	if err := ioutil.WriteFile(fpath, data, 0600); err != nil {
+		b.Fatal(err)
+	}
+	stat, err := os.Stat(fpath)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var open []files.File
+	defer func() {
+		for _, f := range open {
+			f.Close()
+		}
+	}()
+	runAddBench(b, size, balancedLayout, rawLeaves, func() io.Reader {
+		f, err := files.NewSerialFile("data", fpath, false, stat)
+		if err != nil {
+			b.Fatal(err)
+		}
+		open = append(open, f)
+		return f
+	})
+}
+
+func BenchmarkAddBalancedSmall(b *testing.B) {
+	runAddMemBench(b, benchSmall, balancedLayout, false)
+}
+
+func BenchmarkAddBalancedLarge(b *testing.B) {
+	runAddMemBench(b, benchLarge, balancedLayout, false)
+}
+
+func BenchmarkAddBalancedHuge(b *testing.B) {
+	runAddMemBench(b, benchHuge, balancedLayout, false)
+}
+
+func BenchmarkAddBalancedRawLeavesLarge(b *testing.B) {
+	runAddMemBench(b, benchLarge, balancedLayout, true)
+}
+
+func BenchmarkAddTrickleLarge(b *testing.B) {
+	runAddMemBench(b, benchLarge, trickleLayout, false)
+}
+
+func BenchmarkAddTrickleRawLeavesLarge(b *testing.B) {
+	runAddMemBench(b, benchLarge, trickleLayout, true)
+}
+
+func BenchmarkAddFileLarge(b *testing.B) {
+	runAddFileBench(b, benchLarge, false)
+}
+
+func BenchmarkAddFileRawLeavesLarge(b *testing.B) {
+	runAddFileBench(b, benchLarge, true)
+}
+
+func BenchmarkAddFileHuge(b *testing.B) {
+	runAddFileBench(b, benchHuge, true)
+}
@@ -26,8 +26,18 @@ type Keystore interface {
 var ErrNoSuchKey = fmt.Errorf("no key by the given name was found")
 var ErrKeyExists = fmt.Errorf("key by that name already exists, refusing to overwrite")
 
+// Sealer encrypts the keys stored on disk. The name of a key is passed as
+// additional data to authenticate along with it.
+type Sealer interface {
+	Seal(data, ad []byte) ([]byte, error)
+	Open(sealed, ad []byte) ([]byte, error)
+}
+
 type FSKeystore struct {
 	dir string
+
+	// sealer encrypts the keys, nil to store them in the clear
+	sealer Sealer
 }
 
 func validateName(name string) error {
@@ -57,7 +67,18 @@ func NewFSKeystore(dir string) (*FSKeystore, error) {
 		}
 	}
 
-	return &FSKeystore{dir}, nil
+	return &FSKeystore{dir: dir}, nil
+}
+
+// NewSealedFSKeystore returns a keystore storing the keys in dir encrypted
+// with s
+func NewSealedFSKeystore(dir string, s Sealer) (*FSKeystore, error) {
+	ks, err := NewFSKeystore(dir)
+	if err != nil {
+		return nil, err
+	}
+	ks.sealer = s
+	return ks, nil
 }
 
 // Has return whether or not a key exist in the Keystore
@@ -87,6 +108,12 @@ func (ks *FSKeystore) Put(name string, k ci.PrivKey) error {
 	if err != nil {
 		return err
 	}
+	if ks.sealer != nil {
+		b, err = ks.sealer.Seal(b, []byte(name))
+		if err != nil {
+			return err
+		}
+	}
 
 	kp := filepath.Join(ks.dir, name)
 
@@ -126,6 +153,12 @@ func (ks *FSKeystore) Get(name string) (ci.PrivKey, error) {
 		}
 		return nil, err
 	}
+	if ks.sealer != nil {
+		data, err = ks.sealer.Open(data, []byte(name))
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %s", name, err)
+		}
+	}
 
 	return ci.UnmarshalPrivateKey(data)
 }
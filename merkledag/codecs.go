@@ -0,0 +1,43 @@
+package merkledag
+
+import (
+	"fmt"
+	"sync"
+
+	blocks "github.com/ipfs/go-ipfs/blocks"
+
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
+)
+
+// DecodeBlockFunc decodes a block into a node
+type DecodeBlockFunc func(b blocks.Block) (node.Node, error)
+
+var decodersLk sync.RWMutex
+var decoders = make(map[uint64]DecodeBlockFunc)
+
+// RegisterDecoder registers the decoder used for blocks with the given
+// codec. The built in codecs cannot be overridden, and each codec can only
+// be registered once.
+func RegisterDecoder(codec uint64, dec DecodeBlockFunc) error {
+	switch codec {
+	case cid.DagProtobuf, cid.Raw, cid.DagCBOR:
+		return fmt.Errorf("codec %x is built in", codec)
+	}
+
+	decodersLk.Lock()
+	defer decodersLk.Unlock()
+
+	if _, ok := decoders[codec]; ok {
+		return fmt.Errorf("a decoder is already registered for codec %x", codec)
+	}
+	decoders[codec] = dec
+	return nil
+}
+
+func registeredDecoder(codec uint64) (DecodeBlockFunc, bool) {
+	decodersLk.RLock()
+	defer decodersLk.RUnlock()
+	dec, ok := decoders[codec]
+	return dec, ok
+}
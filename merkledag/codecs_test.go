@@ -0,0 +1,46 @@
+package merkledag
+
+import (
+	"testing"
+
+	blocks "github.com/ipfs/go-ipfs/blocks"
+
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
+)
+
+func TestRegisterDecoder(t *testing.T) {
+	const codec = 0x3f0001
+
+	data := []byte("some custom format")
+	blk, err := blocks.NewBlockWithCid(data, cid.NewCidV1(codec, u.Hash(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := decodeBlock(blk); err == nil {
+		t.Fatal("expected an error decoding an unknown format")
+	}
+
+	dec := func(b blocks.Block) (node.Node, error) {
+		return &RawNode{b}, nil
+	}
+	if err := RegisterDecoder(cid.DagProtobuf, dec); err == nil {
+		t.Fatal("should not be able to override a built in codec")
+	}
+	if err := RegisterDecoder(codec, dec); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDecoder(codec, dec); err == nil {
+		t.Fatal("should not be able to register a codec twice")
+	}
+
+	nd, err := decodeBlock(blk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nd.Cid().Equals(blk.Cid()) {
+		t.Fatal("decoded node has the wrong cid")
+	}
+}
@@ -120,6 +120,9 @@ func decodeBlock(b blocks.Block) (node.Node, error) {
 	case cid.DagCBOR:
 		return ipldcbor.Decode(b.RawData())
 	default:
+		if dec, ok := registeredDecoder(c.Type()); ok {
+			return dec(b)
+		}
 		return nil, fmt.Errorf("unrecognized object type: %s", c.Type())
 	}
 }
@@ -454,6 +457,16 @@ func (p *ProgressTracker) Value() int {
 var FetchGraphConcurrency = 8
 
 func EnumerateChildrenAsync(ctx context.Context, getLinks GetLinks, c *cid.Cid, visit func(*cid.Cid) bool) error {
+	return EnumerateChildrenAsyncConcurrent(ctx, getLinks, c, visit, FetchGraphConcurrency)
+}
+
+// EnumerateChildrenAsyncConcurrent is like EnumerateChildrenAsync, but starts
+// the given number of concurrent fetches instead of FetchGraphConcurrency.
+func EnumerateChildrenAsyncConcurrent(ctx context.Context, getLinks GetLinks, c *cid.Cid, visit func(*cid.Cid) bool, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = FetchGraphConcurrency
+	}
+
 	feed := make(chan *cid.Cid)
 	out := make(chan []*node.Link)
 	done := make(chan struct{})
@@ -465,7 +478,7 @@ func EnumerateChildrenAsync(ctx context.Context, getLinks GetLinks, c *cid.Cid,
 
 	defer cancel()
 
-	for i := 0; i < FetchGraphConcurrency; i++ {
+	for i := 0; i < concurrency; i++ {
 		go func() {
 			for ic := range feed {
 				links, err := getLinks(ctx, ic)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	mh "gx/ipfs/QmVGtdTZdTFaLsaj2RwdVG8jcjNNcp1DE914DKZ2kHmXHw/go-multihash"
 	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
@@ -54,6 +55,44 @@ func PrefixForCidVersion(version int) (cid.Prefix, error) {
 	}
 }
 
+// DefaultHashFunction is the hash function nodes are hashed with by default
+const DefaultHashFunction = "sha2-256"
+
+// PrefixForHash returns the Protobuf prefix for a given CID version and hash
+// function name. CIDv0 only supports sha2-256, so any other hash function
+// requires CIDv1.
+func PrefixForHash(version int, hashFunc string) (cid.Prefix, error) {
+	prefix, err := PrefixForCidVersion(version)
+	if err != nil {
+		return cid.Prefix{}, err
+	}
+
+	code, err := HashFunctionCode(hashFunc)
+	if err != nil {
+		return cid.Prefix{}, err
+	}
+	if version == 0 && code != mh.SHA2_256 {
+		return cid.Prefix{}, fmt.Errorf("CIDv0 only supports sha2-256, use CIDv1 with %s", hashFunc)
+	}
+
+	prefix.MhType = code
+	prefix.MhLength = -1
+	return prefix, nil
+}
+
+// HashFunctionCode returns the multihash code of the named hash function,
+// or an error if nodes cannot be hashed with it
+func HashFunctionCode(hashFunc string) (int, error) {
+	code, ok := mh.Names[strings.ToLower(hashFunc)]
+	if !ok {
+		return 0, fmt.Errorf("unrecognized hash function: %s", strings.ToLower(hashFunc))
+	}
+	if _, err := mh.Sum(nil, code, -1); err != nil {
+		return 0, fmt.Errorf("unsupported hash function %s: %s", hashFunc, err)
+	}
+	return code, nil
+}
+
 // SetPrefix sets the CID prefix if it is non nil, if prefix is nil then
 // it resets it the default value
 func (n *ProtoNode) SetPrefix(prefix *cid.Prefix) {
@@ -8,6 +8,7 @@ import (
 	. "github.com/ipfs/go-ipfs/merkledag"
 	mdtest "github.com/ipfs/go-ipfs/merkledag/test"
 
+	mh "gx/ipfs/QmVGtdTZdTFaLsaj2RwdVG8jcjNNcp1DE914DKZ2kHmXHw/go-multihash"
 	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
@@ -158,3 +159,34 @@ func TestJsonRoundtrip(t *testing.T) {
 		t.Fatal("objects differed after marshaling")
 	}
 }
+
+func TestPrefixForHash(t *testing.T) {
+	p, err := PrefixForHash(0, "sha2-256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != 0 || p.MhType != mh.SHA2_256 {
+		t.Fatalf("unexpected prefix: %v", p)
+	}
+
+	p, err = PrefixForHash(1, "blake2b-256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != 1 || p.MhType != mh.Names["blake2b-256"] || p.MhLength != -1 {
+		t.Fatalf("unexpected prefix: %v", p)
+	}
+
+	nd := NodeWithData([]byte("data"))
+	nd.SetPrefix(&p)
+	if nd.Cid().Prefix().MhType != p.MhType {
+		t.Fatal("node was not hashed with blake2b-256")
+	}
+
+	if _, err := PrefixForHash(0, "blake2b-256"); err == nil {
+		t.Fatal("expected an error for a CIDv0 with blake2b-256")
+	}
+	if _, err := PrefixForHash(1, "not-a-hash"); err == nil {
+		t.Fatal("expected an error for an unknown hash function")
+	}
+}
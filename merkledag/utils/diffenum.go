@@ -12,8 +12,21 @@ import (
 
 // DiffEnumerate fetches every object in the graph pointed to by 'to' that is
 // not in 'from'. This can be used to more efficiently fetch a graph if you can
-// guarantee you already have the entirety of 'from'
+// guarantee you already have the entirety of 'from'. If the context carries a
+// ProgressTracker, it is incremented for every object fetched.
 func DiffEnumerate(ctx context.Context, dserv node.NodeGetter, from, to *cid.Cid) error {
+	if from.Equals(to) {
+		return nil
+	}
+
+	prog, _ := ctx.Value("progress").(*mdag.ProgressTracker)
+	return diffEnumerate(ctx, dserv, from, to, cid.NewSet(), prog)
+}
+
+// diffEnumerate does the work of DiffEnumerate. The 'seen' set is shared
+// across the whole walk so that subgraphs reachable through several changed
+// links are only enumerated once.
+func diffEnumerate(ctx context.Context, dserv node.NodeGetter, from, to *cid.Cid, seen *cid.Set, prog *mdag.ProgressTracker) error {
 	fnd, err := dserv.Get(ctx, from)
 	if err != nil {
 		return fmt.Errorf("get %s: %s", from, err)
@@ -24,28 +37,42 @@ func DiffEnumerate(ctx context.Context, dserv node.NodeGetter, from, to *cid.Cid
 		return fmt.Errorf("get %s: %s", to, err)
 	}
 
+	visit := func(c *cid.Cid) bool {
+		if !seen.Visit(c) {
+			return false
+		}
+		if prog != nil {
+			prog.Increment()
+		}
+		return true
+	}
+
 	diff := getLinkDiff(fnd, tnd)
 
-	sset := cid.NewSet()
 	for _, c := range diff {
 		// Since we're already assuming we have everything in the 'from' graph,
 		// add all those cids to our 'already seen' set to avoid potentially
 		// enumerating them later
 		if c.bef != nil {
-			sset.Add(c.bef)
+			seen.Add(c.bef)
 		}
 	}
 	for _, c := range diff {
 		if c.bef == nil {
-			if sset.Has(c.aft) {
+			if seen.Has(c.aft) {
 				continue
 			}
-			err := mdag.EnumerateChildrenAsync(ctx, mdag.GetLinksDirect(dserv), c.aft, sset.Visit)
+			// the walker visits the root of the added subgraph itself,
+			// marking it beforehand would skip its children
+			err := mdag.EnumerateChildrenAsync(ctx, mdag.GetLinksDirect(dserv), c.aft, visit)
 			if err != nil {
 				return err
 			}
 		} else {
-			err := DiffEnumerate(ctx, dserv, c.bef, c.aft)
+			if !visit(c.aft) {
+				continue
+			}
+			err := diffEnumerate(ctx, dserv, c.bef, c.aft, seen, prog)
 			if err != nil {
 				return err
 			}
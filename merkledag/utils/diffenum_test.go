@@ -188,3 +188,28 @@ func TestDiffEnumRecurse(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestDiffEnumProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	nds := mkGraph(tg2)
+
+	ds := mdtest.Mock()
+	for _, nd := range nds {
+		_, err := ds.Add(nd)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v := new(dag.ProgressTracker)
+	err := DiffEnumerate(v.DeriveContext(ctx), ds, nds["a1"].Cid(), nds["a2"].Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only c and d are new in a2
+	if v.Value() != 2 {
+		t.Fatalf("expected 2 fetched nodes, got %d", v.Value())
+	}
+}
@@ -63,6 +63,13 @@ func (d *Directory) SetPrefix(prefix *cid.Prefix) {
 	d.dirbuilder.SetPrefix(prefix)
 }
 
+// SetShardingSize sets the size above which the directory is sharded, see
+// uio.Directory.SetShardingSize. The directories created or loaded under d
+// afterwards inherit it.
+func (d *Directory) SetShardingSize(size int) {
+	d.dirbuilder.SetShardingSize(size)
+}
+
 // closeChild updates the child by the given name to the dag node 'nd'
 // and changes its own dag node
 func (d *Directory) closeChild(name string, nd node.Node, sync bool) error {
@@ -153,6 +160,7 @@ func (d *Directory) cacheNode(name string, nd node.Node) (FSNode, error) {
 			if err != nil {
 				return nil, err
 			}
+			ndir.SetShardingSize(d.dirbuilder.ShardingSize())
 
 			d.childDirs[name] = ndir
 			return ndir, nil
@@ -314,6 +322,7 @@ func (d *Directory) Mkdir(name string) (*Directory, error) {
 	if err != nil {
 		return nil, err
 	}
+	dirobj.SetShardingSize(d.dirbuilder.ShardingSize())
 
 	d.childDirs[name] = dirobj
 	return dirobj, nil
@@ -343,6 +352,25 @@ func (d *Directory) Flush() error {
 	return d.parent.closeChild(d.name, nd, true)
 }
 
+// SetMetadata records the permission bits mode and the modification time
+// mtime in the directory node. A zero mode or mtime clears the respective
+// field.
+func (d *Directory) SetMetadata(mode os.FileMode, mtime time.Time) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.dirbuilder.SetMetadata(mode, mtime)
+}
+
+// Metadata returns the permission bits and modification time recorded for
+// the directory, zero if none were recorded.
+func (d *Directory) Metadata() (os.FileMode, time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.dirbuilder.Metadata()
+}
+
 // AddChild adds the node 'nd' under this directory giving it the name 'name'
 func (d *Directory) AddChild(name string, nd node.Node) error {
 	d.lock.Lock()
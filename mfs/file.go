@@ -3,7 +3,9 @@ package mfs
 import (
 	"context"
 	"fmt"
+	"os"
 	"sync"
+	"time"
 
 	chunk "github.com/ipfs/go-ipfs/importer/chunk"
 	dag "github.com/ipfs/go-ipfs/merkledag"
@@ -113,6 +115,88 @@ func (fi *File) GetNode() (node.Node, error) {
 	return fi.node, nil
 }
 
+// Metadata returns the permission bits and modification time recorded for
+// the file, zero if none were recorded.
+func (fi *File) Metadata() (os.FileMode, time.Time, error) {
+	fi.nodelk.Lock()
+	defer fi.nodelk.Unlock()
+	switch nd := fi.node.(type) {
+	case *dag.ProtoNode:
+		pbd, err := ft.FromBytes(nd.Data())
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		return ft.Mode(pbd), ft.ModTime(pbd), nil
+	case *dag.RawNode:
+		return 0, time.Time{}, nil
+	default:
+		return 0, time.Time{}, fmt.Errorf("unrecognized node type in mfs/file.Metadata()")
+	}
+}
+
+// SetMetadata records the permission bits mode and the modification time
+// mtime in the file node, and updates the parent directory. A zero mode or
+// mtime clears the respective field.
+func (fi *File) SetMetadata(mode os.FileMode, mtime time.Time) error {
+	// the node only changes under the write lock of the descriptors
+	fi.desclock.Lock()
+	defer fi.desclock.Unlock()
+
+	fi.nodelk.Lock()
+	cur := fi.node
+	fi.nodelk.Unlock()
+
+	nd, err := withMetadata(cur, mode, mtime)
+	if err != nil {
+		return err
+	}
+	if _, err := fi.dserv.Add(nd); err != nil {
+		return err
+	}
+
+	fi.nodelk.Lock()
+	fi.node = nd
+	name := fi.name
+	parent := fi.parent
+	fi.nodelk.Unlock()
+
+	return parent.closeChild(name, nd, false)
+}
+
+// withMetadata returns the file node nd with mode and mtime recorded in it. A
+// file made of a single raw block is wrapped in a unixfs node to hold them.
+func withMetadata(nd node.Node, mode os.FileMode, mtime time.Time) (node.Node, error) {
+	switch nd := nd.(type) {
+	case *dag.ProtoNode:
+		data, err := ft.WithMetadata(nd.Data(), mode, mtime)
+		if err != nil {
+			return nil, err
+		}
+		out := nd.Copy().(*dag.ProtoNode)
+		out.SetData(data)
+		return out, nil
+	case *dag.RawNode:
+		if mode == 0 && mtime.IsZero() {
+			return nd, nil
+		}
+		fsn := &ft.FSNode{Type: ft.TFile, Mode: mode, ModTime: mtime}
+		fsn.AddBlockSize(uint64(len(nd.RawData())))
+		data, err := fsn.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+		out := dag.NodeWithData(data)
+		prefix := nd.Cid().Prefix()
+		out.SetPrefix(&prefix)
+		if err := out.AddNodeLinkClean("", nd); err != nil {
+			return nil, err
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unrecognized node type in mfs/file.SetMetadata()")
+	}
+}
+
 func (fi *File) Flush() error {
 	// open the file in fullsync mode
 	fd, err := fi.Open(OpenWriteOnly, true)
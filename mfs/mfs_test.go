@@ -756,7 +756,7 @@ func TestMfsHugeDir(t *testing.T) {
 	_, rt := setupRoot(ctx, t)
 
 	for i := 0; i < 10000; i++ {
-		err := Mkdir(rt, fmt.Sprintf("/dir%d", i), false, false)
+		err := Mkdir(rt, fmt.Sprintf("/dir%d", i), MkdirOpts{})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -768,7 +768,7 @@ func TestMkdirP(t *testing.T) {
 	defer cancel()
 	_, rt := setupRoot(ctx, t)
 
-	err := Mkdir(rt, "/a/b/c/d/e/f", true, true)
+	err := Mkdir(rt, "/a/b/c/d/e/f", MkdirOpts{Mkparents: true, Flush: true})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1127,3 +1127,110 @@ func TestFileDescriptors(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestChmodTouch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, rt := setupRoot(ctx, t)
+
+	dir := rt.GetValue().(*Directory)
+	a := mkdirP(t, dir, "a")
+
+	data := []byte("this is a test\n")
+	fnd := dag.NodeWithData(ft.FilePBData(data, uint64(len(data))))
+	if err := a.AddChild("file", fnd); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddChild("raw", dag.NewRawNode(data)); err != nil {
+		t.Fatal(err)
+	}
+	before, err := dir.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mtime := time.Unix(1500000000, 42)
+	for _, p := range []string{"/a", "/a/file", "/a/raw"} {
+		if err := Chmod(rt, p, 0750|os.ModeSetgid, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := Touch(rt, p, mtime, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the changes reach the root
+	after, err := dir.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Cid().Equals(before.Cid()) {
+		t.Fatal("expected the root to change")
+	}
+
+	for _, p := range []string{"/a", "/a/file", "/a/raw"} {
+		fsn, err := Lookup(rt, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nd, err := fsn.GetNode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pbnd, ok := nd.(*dag.ProtoNode)
+		if !ok {
+			t.Fatalf("%s: expected a unixfs node, got %T", p, nd)
+		}
+		pbd, err := ft.FromBytes(pbnd.Data())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := ft.Mode(pbd); mode != 0750|os.ModeSetgid {
+			t.Fatalf("%s: expected mode %s, got %s", p, 0750|os.ModeSetgid, mode)
+		}
+		if got := ft.ModTime(pbd); !got.Equal(mtime) {
+			t.Fatalf("%s: expected mtime %s, got %s", p, mtime, got)
+		}
+	}
+
+	// the content of the wrapped raw file is unchanged
+	fsn, err := Lookup(rt, "/a/raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rfd, err := fsn.(*File).Open(OpenReadOnly, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(rfd)
+	rfd.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatalf("expected %q, got %q", data, out)
+	}
+
+	// clearing the metadata restores the original hash
+	if err := Chmod(rt, "/a/file", 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := Touch(rt, "/a/file", time.Time{}, true); err != nil {
+		t.Fatal(err)
+	}
+	fsn, err = Lookup(rt, "/a/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nd, err := fsn.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nd.Cid().Equals(fnd.Cid()) {
+		t.Fatalf("expected %s, got %s", fnd.Cid(), nd.Cid())
+	}
+
+	if err := Touch(rt, "/a/nothere", mtime, true); err == nil {
+		t.Fatal("expected touching a missing path to fail")
+	}
+}
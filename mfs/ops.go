@@ -6,9 +6,11 @@ import (
 	"os"
 	gopath "path"
 	"strings"
+	"time"
 
 	path "github.com/ipfs/go-ipfs/path"
 
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
 
@@ -102,12 +104,24 @@ func PutNode(r *Root, path string, nd node.Node) error {
 	return pdir.AddChild(filename, nd)
 }
 
+// MkdirOpts is used by Mkdir
+type MkdirOpts struct {
+	Mkparents bool        // create intermediary directories as needed
+	Flush     bool        // flush the new directory
+	Prefix    *cid.Prefix // CID prefix of the new directories, the root's if nil
+}
+
 // Mkdir creates a directory at 'path' under the directory 'd', creating
-// intermediary directories as needed if 'mkparents' is set to true
-func Mkdir(r *Root, pth string, mkparents bool, flush bool) error {
+// intermediary directories as needed if 'opts.Mkparents' is set to true
+func Mkdir(r *Root, pth string, opts MkdirOpts) error {
 	if pth == "" {
 		return fmt.Errorf("no path given to Mkdir")
 	}
+	mkparents := opts.Mkparents
+	prefix := opts.Prefix
+	if prefix == nil {
+		prefix = r.Prefix
+	}
 	parts := path.SplitList(pth)
 	if parts[0] == "" {
 		parts = parts[1:]
@@ -134,7 +148,7 @@ func Mkdir(r *Root, pth string, mkparents bool, flush bool) error {
 			if err != nil {
 				return err
 			}
-			mkd.SetPrefix(r.Prefix)
+			mkd.SetPrefix(prefix)
 			fsn = mkd
 		} else if err != nil {
 			return err
@@ -153,9 +167,9 @@ func Mkdir(r *Root, pth string, mkparents bool, flush bool) error {
 			return err
 		}
 	}
-	final.SetPrefix(r.Prefix)
+	final.SetPrefix(prefix)
 
-	if flush {
+	if opts.Flush {
 		err := final.Flush()
 		if err != nil {
 			return err
@@ -202,6 +216,53 @@ func DirLookup(d *Directory, pth string) (FSNode, error) {
 	return cur, nil
 }
 
+// Chmod records the permission bits mode in the file or directory at pth,
+// keeping its modification time. A zero mode clears the permission bits.
+func Chmod(r *Root, pth string, mode os.FileMode, flush bool) error {
+	return updateMetadata(r, pth, flush, func(_ os.FileMode, mtime time.Time) (os.FileMode, time.Time) {
+		return mode, mtime
+	})
+}
+
+// Touch records the modification time mtime in the file or directory at pth,
+// keeping its permission bits. A zero mtime clears the modification time.
+func Touch(r *Root, pth string, mtime time.Time, flush bool) error {
+	return updateMetadata(r, pth, flush, func(mode os.FileMode, _ time.Time) (os.FileMode, time.Time) {
+		return mode, mtime
+	})
+}
+
+// updateMetadata replaces the metadata of the file or directory at pth by
+// what update returns for the current one
+func updateMetadata(r *Root, pth string, flush bool, update func(os.FileMode, time.Time) (os.FileMode, time.Time)) error {
+	fsn, err := Lookup(r, pth)
+	if err != nil {
+		return err
+	}
+
+	switch fsn := fsn.(type) {
+	case *Directory:
+		if err := fsn.SetMetadata(update(fsn.Metadata())); err != nil {
+			return err
+		}
+	case *File:
+		mode, mtime, err := fsn.Metadata()
+		if err != nil {
+			return err
+		}
+		if err := fsn.SetMetadata(update(mode, mtime)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("cannot set the metadata of %s", pth)
+	}
+
+	if flush {
+		return fsn.Flush()
+	}
+	return nil
+}
+
 func FlushPath(rt *Root, pth string) error {
 	nd, err := Lookup(rt, pth)
 	if err != nil {
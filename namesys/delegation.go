@@ -0,0 +1,173 @@
+package namesys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
+	path "github.com/ipfs/go-ipfs/path"
+
+	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// ErrDelegationExpired is returned for records signed under a delegation
+// that is no longer valid
+var ErrDelegationExpired = errors.New("expired delegation")
+
+// ErrInvalidDelegation is returned for delegations that were not signed by
+// the key of the name they are used for
+var ErrInvalidDelegation = errors.New("invalid delegation")
+
+// DelegatedPublisher is implemented by the publishers able to publish
+// records for a name with a key it delegated to. Resolvers that don't know
+// delegations check these records against the key of the name, and reject
+// them.
+type DelegatedPublisher interface {
+	// PublishDelegated publishes value for the owner of d, signing the
+	// record with k, the key d delegates to.
+	PublishDelegated(ctx context.Context, k ci.PrivKey, d *pb.Delegation, value path.Path, eol time.Time) error
+}
+
+// CreateDelegation returns a delegation, signed by owner, letting the holder
+// of the private key of delegate publish records for owner's name until eol.
+func CreateDelegation(owner ci.PrivKey, delegate ci.PubKey, eol time.Time) (*pb.Delegation, error) {
+	ownerb, err := ci.MarshalPublicKey(owner.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+	delegateb, err := ci.MarshalPublicKey(delegate)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &pb.Delegation{
+		Owner:    ownerb,
+		PubKey:   delegateb,
+		Validity: []byte(u.FormatRFC3339(eol)),
+	}
+	d.Signature, err = owner.Sign(delegationDataForSig(d))
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DelegationName returns the name d delegates publishing rights for
+func DelegationName(d *pb.Delegation) (peer.ID, error) {
+	owner, err := ci.UnmarshalPublicKey(d.GetOwner())
+	if err != nil {
+		return "", err
+	}
+	return peer.IDFromPublicKey(owner)
+}
+
+// ValidateDelegation checks that d was signed by owner and is valid at
+// time now, and returns the key it delegates to.
+func ValidateDelegation(owner ci.PubKey, d *pb.Delegation, now time.Time) (ci.PubKey, error) {
+	downer, err := ci.UnmarshalPublicKey(d.GetOwner())
+	if err != nil {
+		return nil, err
+	}
+	if !downer.Equals(owner) {
+		return nil, ErrInvalidDelegation
+	}
+	if ok, err := owner.Verify(delegationDataForSig(d), d.GetSignature()); err != nil || !ok {
+		return nil, ErrInvalidDelegation
+	}
+
+	eol, err := delegationEOL(d)
+	if err != nil {
+		return nil, err
+	}
+	if now.After(eol) {
+		return nil, ErrDelegationExpired
+	}
+
+	return ci.UnmarshalPublicKey(d.GetPubKey())
+}
+
+func delegationEOL(d *pb.Delegation) (time.Time, error) {
+	eol, err := u.ParseRFC3339(string(d.GetValidity()))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid delegation validity: %s", err)
+	}
+	return eol, nil
+}
+
+func delegationDataForSig(d *pb.Delegation) []byte {
+	return bytes.Join([][]byte{
+		[]byte("ipns-delegation:"),
+		d.Owner,
+		d.PubKey,
+		d.Validity,
+	},
+		[]byte{})
+}
+
+// PublishDelegated implements DelegatedPublisher
+func (p *ipnsPublisher) PublishDelegated(ctx context.Context, k ci.PrivKey, d *pb.Delegation, value path.Path, eol time.Time) error {
+	delegate, err := ci.UnmarshalPublicKey(d.GetPubKey())
+	if err != nil {
+		return err
+	}
+	if !delegate.Equals(k.GetPublic()) {
+		return errors.New("the delegation is for another key")
+	}
+
+	deol, err := delegationEOL(d)
+	if err != nil {
+		return err
+	}
+	if eol.After(deol) {
+		return fmt.Errorf("record lifetime exceeds the delegation, which expires at %s", u.FormatRFC3339(deol))
+	}
+
+	owner, err := ci.UnmarshalPublicKey(d.GetOwner())
+	if err != nil {
+		return err
+	}
+	id, err := peer.IDFromPublicKey(owner)
+	if err != nil {
+		return err
+	}
+
+	_, ipnskey := IpnsKeysForID(id)
+	seqnum, err := p.getPreviousSeqNo(ctx, ipnskey)
+	if err != nil {
+		return err
+	}
+	seqnum++
+
+	entry, err := CreateRoutingEntryData(k, value, seqnum, eol)
+	if err != nil {
+		return err
+	}
+	entry.Delegation = d
+
+	return putEntryToRouting(ctx, entry, owner, p.routing, id)
+}
+
+// PublishDelegated implements DelegatedPublisher
+func (ns *mpns) PublishDelegated(ctx context.Context, k ci.PrivKey, d *pb.Delegation, value path.Path, eol time.Time) error {
+	dp, ok := ns.publishers["/ipns/"].(DelegatedPublisher)
+	if !ok {
+		return errors.New("publisher does not support delegations")
+	}
+	id, err := DelegationName(d)
+	if err != nil {
+		return err
+	}
+	err = ns.withHooks(ctx, id, value, eol, "delegation", func() error {
+		return dp.PublishDelegated(ctx, k, d, value, eol)
+	})
+	if err != nil {
+		return err
+	}
+	ns.addToDHTCache(id, value, eol)
+	return nil
+}
@@ -0,0 +1,116 @@
+package namesys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	path "github.com/ipfs/go-ipfs/path"
+	mockrouting "github.com/ipfs/go-ipfs/routing/mock"
+	testutil "github.com/ipfs/go-ipfs/thirdparty/testutil"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dssync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+func TestDelegatedPublish(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	d := mockrouting.NewServer().ClientWithDatastore(ctx, testutil.RandIdentityOrFatal(t), dstore)
+
+	resolver := NewRoutingResolver(d, 0)
+	publisher := NewRoutingPublisher(d, dstore)
+
+	owner, ownerPub, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delegate, _, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := peer.IDFromPublicKey(ownerPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h1 := path.FromString("/ipfs/QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN")
+	if err := publisher.Publish(ctx, owner, h1); err != nil {
+		t.Fatal(err)
+	}
+
+	del, err := CreateDelegation(owner, delegate.GetPublic(), time.Now().Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := DelegationName(del); err != nil || id != name {
+		t.Fatalf("delegation is for %s, expected %s (err: %v)", id, name, err)
+	}
+
+	h2 := path.FromString("/ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy")
+	if err := publisher.PublishDelegated(ctx, other, del, h2, time.Now().Add(time.Hour)); err == nil {
+		t.Fatal("expected publishing with a key the delegation isn't for to fail")
+	}
+	if err := publisher.PublishDelegated(ctx, delegate, del, h2, time.Now().Add(72*time.Hour)); err == nil {
+		t.Fatal("expected a record outliving the delegation to be refused")
+	}
+	if err := publisher.PublishDelegated(ctx, delegate, del, h2, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := resolver.Resolve(ctx, name.Pretty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != h2 {
+		t.Fatalf("resolved to %s, expected the delegated record %s", res, h2)
+	}
+}
+
+func TestValidateDelegation(t *testing.T) {
+	owner, ownerPub, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, delegatePub, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPub, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	del, err := CreateDelegation(owner, delegatePub, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := ValidateDelegation(ownerPub, del, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !k.Equals(delegatePub) {
+		t.Fatal("delegation validated to the wrong key")
+	}
+
+	if _, err := ValidateDelegation(ownerPub, del, now.Add(2*time.Hour)); err != ErrDelegationExpired {
+		t.Fatalf("expected %s, got %v", ErrDelegationExpired, err)
+	}
+	if _, err := ValidateDelegation(otherPub, del, now); err != ErrInvalidDelegation {
+		t.Fatalf("expected %s for another name, got %v", ErrInvalidDelegation, err)
+	}
+
+	// tampering with the delegate breaks the signature
+	del.PubKey, _ = otherPub.Bytes()
+	if _, err := ValidateDelegation(ownerPub, del, now); err != ErrInvalidDelegation {
+		t.Fatalf("expected %s for a tampered delegation, got %v", ErrInvalidDelegation, err)
+	}
+}
@@ -0,0 +1,120 @@
+package dnslink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CloudflareAPI is the base URL of the Cloudflare v4 API.
+const CloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// Cloudflare sets records of a zone hosted by Cloudflare.
+type Cloudflare struct {
+	// BaseURL is the API endpoint, CloudflareAPI by default.
+	BaseURL string
+	Client  *http.Client
+
+	token  string
+	zoneID string
+}
+
+// NewCloudflare returns a provider for the zone zoneID, authenticated by
+// an API token allowed to edit its DNS records.
+func NewCloudflare(token, zoneID string) *Cloudflare {
+	return &Cloudflare{
+		BaseURL: CloudflareAPI,
+		Client:  http.DefaultClient,
+		token:   token,
+		zoneID:  zoneID,
+	}
+}
+
+type cfRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+}
+
+type cfResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+// SetTXT implements Provider. The first TXT record of name is updated,
+// or a new one is created if there is none; other TXT records of name are
+// deleted.
+func (c *Cloudflare) SetTXT(ctx context.Context, name, value string, ttl int) error {
+	var existing []cfRecord
+	q := url.Values{"type": {"TXT"}, "name": {name}}
+	if err := c.do(ctx, "GET", "/dns_records?"+q.Encode(), nil, &existing); err != nil {
+		return err
+	}
+
+	rec := cfRecord{Type: "TXT", Name: name, Content: value, TTL: ttl}
+	if len(existing) == 0 {
+		return c.do(ctx, "POST", "/dns_records", rec, nil)
+	}
+
+	if err := c.do(ctx, "PUT", "/dns_records/"+existing[0].ID, rec, nil); err != nil {
+		return err
+	}
+	for _, r := range existing[1:] {
+		if err := c.do(ctx, "DELETE", "/dns_records/"+r.ID, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cloudflare) do(ctx context.Context, method, p string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	u := strings.TrimSuffix(c.BaseURL, "/") + "/zones/" + c.zoneID + p
+	req, err := http.NewRequest(method, u, &body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var r cfResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("cloudflare: %s: %s", resp.Status, err)
+	}
+	if !r.Success {
+		if len(r.Errors) > 0 {
+			return fmt.Errorf("cloudflare: %s (code %d)", r.Errors[0].Message, r.Errors[0].Code)
+		}
+		return fmt.Errorf("cloudflare: %s", resp.Status)
+	}
+
+	if out != nil {
+		return json.Unmarshal(r.Result, out)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package dnslink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCloudflareSetTXT(t *testing.T) {
+	records := map[string]cfRecord{
+		"a": {ID: "a", Type: "TXT", Name: "_dnslink.example.com", Content: "dnslink=/ipfs/old"},
+		"b": {ID: "b", Type: "TXT", Name: "_dnslink.example.com", Content: "dnslink=/ipfs/older"},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"errors":  []map[string]interface{}{{"code": 9109, "message": "Invalid access token"}},
+			})
+			return
+		}
+
+		var result interface{}
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/zones/zone/dns_records":
+			if r.URL.Query().Get("name") != "_dnslink.example.com" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+			var list []cfRecord
+			for _, id := range []string{"a", "b"} {
+				if rec, ok := records[id]; ok {
+					list = append(list, rec)
+				}
+			}
+			result = list
+		case r.Method == "PUT" && r.URL.Path == "/zones/zone/dns_records/a":
+			var rec cfRecord
+			if err := json.NewDecoder(r.Body).Decode(&rec); err != nil {
+				t.Error(err)
+			}
+			rec.ID = "a"
+			records["a"] = rec
+			result = rec
+		case r.Method == "DELETE" && r.URL.Path == "/zones/zone/dns_records/b":
+			delete(records, "b")
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": result})
+	}))
+	defer ts.Close()
+
+	c := NewCloudflare("secret", "zone")
+	c.BaseURL = ts.URL
+	if err := c.SetTXT(context.Background(), "_dnslink.example.com", "dnslink=/ipfs/new", 120); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("expected a single record left, got %d", len(records))
+	}
+	if rec := records["a"]; rec.Content != "dnslink=/ipfs/new" || rec.TTL != 120 {
+		t.Fatalf("record not updated: %+v", rec)
+	}
+
+	c = NewCloudflare("wrong", "zone")
+	c.BaseURL = ts.URL
+	err := c.SetTXT(context.Background(), "_dnslink.example.com", "dnslink=/ipfs/new", 120)
+	if err == nil || err.Error() != "cloudflare: Invalid access token (code 9109)" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
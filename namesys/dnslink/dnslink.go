@@ -0,0 +1,119 @@
+// Package dnslink keeps the dnslink TXT record of a domain pointing at the
+// latest path published under an ipns key, through the API of the DNS
+// provider hosting the domain.
+package dnslink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	path "github.com/ipfs/go-ipfs/path"
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
+// DefaultTTL is the TTL, in seconds, of the records set when none is
+// configured.
+const DefaultTTL = 60
+
+// DefaultKey is the name of the ipns key followed when none is configured.
+const DefaultKey = "self"
+
+// Provider sets TXT records through a DNS provider.
+type Provider interface {
+	// SetTXT replaces the TXT records of the fully qualified name with a
+	// single record holding value.
+	SetTXT(ctx context.Context, name, value string, ttl int) error
+}
+
+// Updater updates the _dnslink TXT record of a domain.
+type Updater struct {
+	Domain string
+	Key    string
+	TTL    int
+
+	// DryRun makes Update only report the change it would make.
+	DryRun bool
+
+	// Provider may be nil with DryRun.
+	Provider Provider
+}
+
+// Update describes a change of a dnslink record.
+type Update struct {
+	Record string
+	Value  string
+	DryRun bool `json:",omitempty"`
+}
+
+// New returns the updater described by cfg, or nil if cfg has no domain.
+func New(cfg config.DNSLink) (*Updater, error) {
+	if cfg.Domain == "" {
+		return nil, nil
+	}
+
+	u := &Updater{
+		Domain: strings.TrimSuffix(cfg.Domain, "."),
+		Key:    cfg.Key,
+		TTL:    cfg.TTL,
+		DryRun: cfg.DryRun,
+	}
+	if u.Key == "" {
+		u.Key = DefaultKey
+	}
+	if u.TTL <= 0 {
+		u.TTL = DefaultTTL
+	}
+
+	switch cfg.Provider {
+	case "cloudflare":
+		c := cfg.Cloudflare
+		if c.APIToken == "" || c.ZoneID == "" {
+			return nil, errors.New("dnslink: cloudflare needs an APIToken and a ZoneID")
+		}
+		u.Provider = NewCloudflare(c.APIToken, c.ZoneID)
+	case "rfc2136":
+		r := cfg.RFC2136
+		if r.Server == "" || r.Zone == "" {
+			return nil, errors.New("dnslink: rfc2136 needs a Server and a Zone")
+		}
+		p, err := NewRFC2136(r.Server, r.Zone, r.KeyName, r.KeyAlgorithm, r.Secret)
+		if err != nil {
+			return nil, err
+		}
+		u.Provider = p
+	case "":
+		if !u.DryRun {
+			return nil, errors.New("dnslink: no provider configured")
+		}
+	default:
+		return nil, fmt.Errorf("dnslink: unknown provider %q", cfg.Provider)
+	}
+	return u, nil
+}
+
+// RecordName returns the name of the TXT record updated.
+func (u *Updater) RecordName() string {
+	return "_dnslink." + u.Domain
+}
+
+// Update points the dnslink record at p.
+func (u *Updater) Update(ctx context.Context, p path.Path) (*Update, error) {
+	up := &Update{
+		Record: u.RecordName(),
+		Value:  "dnslink=" + p.String(),
+		DryRun: u.DryRun,
+	}
+	if u.DryRun {
+		return up, nil
+	}
+	if u.Provider == nil {
+		return nil, errors.New("dnslink: no provider configured")
+	}
+
+	if err := u.Provider.SetTXT(ctx, up.Record, up.Value, u.TTL); err != nil {
+		return nil, fmt.Errorf("updating %s: %s", up.Record, err)
+	}
+	return up, nil
+}
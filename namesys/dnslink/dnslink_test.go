@@ -0,0 +1,68 @@
+package dnslink
+
+import (
+	"context"
+	"testing"
+
+	path "github.com/ipfs/go-ipfs/path"
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
+type fakeProvider map[string]string
+
+func (f fakeProvider) SetTXT(ctx context.Context, name, value string, ttl int) error {
+	f[name] = value
+	return nil
+}
+
+func TestUpdate(t *testing.T) {
+	p := path.FromString("/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn")
+	f := make(fakeProvider)
+
+	u := &Updater{Domain: "example.com", Provider: f, DryRun: true}
+	up, err := u.Update(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !up.DryRun || len(f) != 0 {
+		t.Fatal("dry run updated the record")
+	}
+
+	u.DryRun = false
+	up, err = u.Update(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up.Record != "_dnslink.example.com" || f[up.Record] != "dnslink="+p.String() {
+		t.Fatalf("unexpected update %+v", up)
+	}
+}
+
+func TestNew(t *testing.T) {
+	u, err := New(config.DNSLink{})
+	if u != nil || err != nil {
+		t.Fatal("expected no updater without a domain")
+	}
+
+	u, err = New(config.DNSLink{Domain: "example.com.", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.RecordName() != "_dnslink.example.com" || u.Key != DefaultKey || u.TTL != DefaultTTL {
+		t.Fatalf("unexpected defaults %+v", u)
+	}
+
+	bad := []config.DNSLink{
+		{Domain: "example.com"},
+		{Domain: "example.com", Provider: "route53"},
+		{Domain: "example.com", Provider: "cloudflare"},
+		{Domain: "example.com", Provider: "rfc2136", RFC2136: config.DNSLinkRFC2136{
+			Server: "ns1.example.com", Zone: "example.com", KeyName: "k", KeyAlgorithm: "hmac-sha3",
+		}},
+	}
+	for _, cfg := range bad {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package dnslink
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	dnsTypeSOA  = 6
+	dnsTypeTXT  = 16
+	dnsTypeTSIG = 250
+
+	dnsClassIN  = 1
+	dnsClassANY = 255
+
+	dnsOpcodeUpdate = 5
+
+	tsigFudge = 300
+)
+
+var tsigAlgorithms = map[string]func() hash.Hash{
+	"hmac-md5.sig-alg.reg.int": md5.New,
+	"hmac-sha1":                sha1.New,
+	"hmac-sha256":              sha256.New,
+	"hmac-sha512":              sha512.New,
+}
+
+var rcodeNames = map[int]string{
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+// RFC2136 sets records with DNS dynamic updates (RFC 2136) sent over TCP
+// to the primary server of a zone. Updates are signed with TSIG (RFC 2845)
+// when a key is given. The answer of the server is not authenticated.
+type RFC2136 struct {
+	Server  string
+	Zone    string
+	Timeout time.Duration
+
+	keyName string
+	alg     string
+	secret  []byte
+	now     func() time.Time
+}
+
+// NewRFC2136 returns a provider updating zone through server, a host with
+// an optional port. keyName, alg and the base64 encoded secret describe
+// the TSIG key; an empty keyName sends unsigned updates. alg defaults to
+// hmac-sha256.
+func NewRFC2136(server, zone, keyName, alg, secret string) (*RFC2136, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+
+	r := &RFC2136{
+		Server:  server,
+		Zone:    canonicalName(zone),
+		Timeout: 10 * time.Second,
+		now:     time.Now,
+	}
+	if keyName == "" {
+		return r, nil
+	}
+
+	if alg == "" {
+		alg = "hmac-sha256"
+	}
+	alg = canonicalName(alg)
+	if alg == "hmac-md5" {
+		alg = "hmac-md5.sig-alg.reg.int"
+	}
+	if _, ok := tsigAlgorithms[alg]; !ok {
+		return nil, fmt.Errorf("dnslink: unsupported tsig algorithm %q", alg)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("dnslink: invalid tsig secret: %s", err)
+	}
+
+	r.keyName = canonicalName(keyName)
+	r.alg = alg
+	r.secret = key
+	return r, nil
+}
+
+// SetTXT implements Provider.
+func (r *RFC2136) SetTXT(ctx context.Context, name, value string, ttl int) error {
+	name = canonicalName(name)
+	if name != r.Zone && !strings.HasSuffix(name, "."+r.Zone) {
+		return fmt.Errorf("%s is not in zone %s", name, r.Zone)
+	}
+	if !validName(name) {
+		return fmt.Errorf("invalid domain name %q", name)
+	}
+
+	msg, id, err := r.updateMsg(name, value, ttl)
+	if err != nil {
+		return err
+	}
+
+	d := net.Dialer{Timeout: r.Timeout}
+	if dl, ok := ctx.Deadline(); ok {
+		d.Deadline = dl
+	}
+	conn, err := d.Dial("tcp", r.Server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	if r.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(r.Timeout))
+	}
+
+	resp, err := exchange(conn, msg)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return checkResponse(resp, id)
+}
+
+// updateMsg builds an update replacing the TXT records of name.
+func (r *RFC2136) updateMsg(name, value string, ttl int) ([]byte, uint16, error) {
+	var idb [2]byte
+	if _, err := io.ReadFull(rand.Reader, idb[:]); err != nil {
+		return nil, 0, err
+	}
+	id := binary.BigEndian.Uint16(idb[:])
+
+	rdata, err := txtRdata(value)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var b []byte
+	b = appendUint16(b, id)
+	b = appendUint16(b, dnsOpcodeUpdate<<11)
+	b = appendUint16(b, 1) // zone
+	b = appendUint16(b, 0) // prerequisites
+	b = appendUint16(b, 2) // updates
+	b = appendUint16(b, 0) // additional
+
+	b = appendName(b, r.Zone)
+	b = appendUint16(b, dnsTypeSOA)
+	b = appendUint16(b, dnsClassIN)
+
+	// delete the TXT RRset of name...
+	b = appendRR(b, name, dnsTypeTXT, dnsClassANY, 0, nil)
+	// ...and add the new record
+	b = appendRR(b, name, dnsTypeTXT, dnsClassIN, uint32(ttl), rdata)
+
+	if r.keyName != "" {
+		b = r.sign(b, id)
+	}
+	return b, id, nil
+}
+
+// sign appends a TSIG record to msg.
+func (r *RFC2136) sign(msg []byte, id uint16) []byte {
+	signed := uint64(r.now().Unix())
+
+	var timers []byte
+	timers = appendUint16(timers, uint16(signed>>32))
+	timers = appendUint32(timers, uint32(signed))
+	timers = appendUint16(timers, tsigFudge)
+
+	mac := hmac.New(tsigAlgorithms[r.alg], r.secret)
+	mac.Write(msg)
+	var vars []byte
+	vars = appendName(vars, r.keyName)
+	vars = appendUint16(vars, dnsClassANY)
+	vars = appendUint32(vars, 0)
+	vars = appendName(vars, r.alg)
+	vars = append(vars, timers...)
+	vars = appendUint16(vars, 0) // error
+	vars = appendUint16(vars, 0) // other len
+	mac.Write(vars)
+	sum := mac.Sum(nil)
+
+	var rdata []byte
+	rdata = appendName(rdata, r.alg)
+	rdata = append(rdata, timers...)
+	rdata = appendUint16(rdata, uint16(len(sum)))
+	rdata = append(rdata, sum...)
+	rdata = appendUint16(rdata, id)
+	rdata = appendUint16(rdata, 0) // error
+	rdata = appendUint16(rdata, 0) // other len
+
+	msg = appendRR(msg, r.keyName, dnsTypeTSIG, dnsClassANY, 0, rdata)
+	binary.BigEndian.PutUint16(msg[10:], binary.BigEndian.Uint16(msg[10:])+1)
+	return msg
+}
+
+// exchange sends msg and reads the answer, with the framing of DNS over
+// TCP.
+func exchange(conn net.Conn, msg []byte) ([]byte, error) {
+	if _, err := conn.Write(appendUint16(nil, uint16(len(msg)))); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	var l [2]byte
+	if _, err := io.ReadFull(conn, l[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func checkResponse(resp []byte, id uint16) error {
+	if len(resp) < 12 {
+		return errors.New("short dns response")
+	}
+	if binary.BigEndian.Uint16(resp) != id {
+		return errors.New("dns response does not match the update")
+	}
+
+	flags := binary.BigEndian.Uint16(resp[2:])
+	if flags&0x8000 == 0 {
+		return errors.New("dns server sent a query instead of a response")
+	}
+	if rcode := int(flags & 0xf); rcode != 0 {
+		name, ok := rcodeNames[rcode]
+		if !ok {
+			name = fmt.Sprintf("rcode %d", rcode)
+		}
+		return fmt.Errorf("dns update refused: %s", name)
+	}
+	return nil
+}
+
+// txtRdata encodes value as the character strings of a TXT record.
+func txtRdata(value string) ([]byte, error) {
+	var b []byte
+	for {
+		n := len(value)
+		if n > 255 {
+			n = 255
+		}
+		b = append(b, byte(n))
+		b = append(b, value[:n]...)
+		value = value[n:]
+		if value == "" {
+			break
+		}
+	}
+	if len(b) > 65535 {
+		return nil, errors.New("txt record too long")
+	}
+	return b, nil
+}
+
+func appendRR(b []byte, name string, typ, class uint16, ttl uint32, rdata []byte) []byte {
+	b = appendName(b, name)
+	b = appendUint16(b, typ)
+	b = appendUint16(b, class)
+	b = appendUint32(b, ttl)
+	b = appendUint16(b, uint16(len(rdata)))
+	return append(b, rdata...)
+}
+
+// appendName appends name in the uncompressed wire format. name must be
+// canonical, see canonicalName.
+func appendName(b []byte, name string) []byte {
+	if name != "" {
+		for _, l := range strings.Split(name, ".") {
+			b = append(b, byte(len(l)))
+			b = append(b, l...)
+		}
+	}
+	return append(b, 0)
+}
+
+// validName checks that a canonical name fits in the wire format.
+func validName(name string) bool {
+	if len(name) > 253 {
+		return false
+	}
+	for _, l := range strings.Split(name, ".") {
+		if len(l) == 0 || len(l) > 63 {
+			return false
+		}
+	}
+	return true
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// canonicalName lowercases name and strips its trailing dot.
+func canonicalName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
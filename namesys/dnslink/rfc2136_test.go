@@ -0,0 +1,138 @@
+package dnslink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveOnce answers a single update on l with rcode, after handing it to
+// check.
+func serveOnce(t *testing.T, l net.Listener, rcode uint16, check func(msg []byte)) {
+	conn, err := l.Accept()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	var lb [2]byte
+	if _, err := io.ReadFull(conn, lb[:]); err != nil {
+		t.Error(err)
+		return
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(lb[:]))
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		t.Error(err)
+		return
+	}
+	check(msg)
+
+	resp := make([]byte, 12)
+	copy(resp, msg[:2])
+	binary.BigEndian.PutUint16(resp[2:], 0x8000|dnsOpcodeUpdate<<11|rcode)
+	conn.Write(appendUint16(nil, uint16(len(resp))))
+	conn.Write(resp)
+}
+
+func TestRFC2136SetTXT(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// "secret" in base64
+	r, err := NewRFC2136(l.Addr().String(), "Example.com.", "update-key", "", "c2VjcmV0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1500000000, 0)
+	r.now = func() time.Time { return now }
+
+	go serveOnce(t, l, 0, func(msg []byte) {
+		if op := binary.BigEndian.Uint16(msg[2:]) >> 11; op != dnsOpcodeUpdate {
+			t.Errorf("unexpected opcode %d", op)
+		}
+		if n := binary.BigEndian.Uint16(msg[8:]); n != 2 {
+			t.Errorf("expected 2 updates, got %d", n)
+		}
+		if n := binary.BigEndian.Uint16(msg[10:]); n != 1 {
+			t.Errorf("expected a tsig record, got %d additional records", n)
+		}
+		if !bytes.Contains(msg, []byte("\x11dnslink=/ipfs/QmX")) {
+			t.Error("update does not carry the new record")
+		}
+
+		// recompute the mac of the unsigned message
+		keyName := appendName(nil, "update-key")
+		i := bytes.LastIndex(msg, keyName)
+		unsigned := append([]byte(nil), msg[:i]...)
+		binary.BigEndian.PutUint16(unsigned[10:], 0)
+
+		var vars []byte
+		vars = append(vars, keyName...)
+		vars = appendUint16(vars, dnsClassANY)
+		vars = appendUint32(vars, 0)
+		vars = appendName(vars, "hmac-sha256")
+		vars = appendUint16(vars, 0)
+		vars = appendUint32(vars, uint32(now.Unix()))
+		vars = appendUint16(vars, tsigFudge)
+		vars = appendUint16(vars, 0)
+		vars = appendUint16(vars, 0)
+
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(unsigned)
+		mac.Write(vars)
+		if !bytes.Contains(msg[i:], mac.Sum(nil)) {
+			t.Error("invalid tsig mac")
+		}
+	})
+
+	err = r.SetTXT(context.Background(), "_dnslink.example.com", "dnslink=/ipfs/QmX", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRFC2136Refused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	r, err := NewRFC2136(l.Addr().String(), "example.com", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go serveOnce(t, l, 5, func([]byte) {})
+
+	err = r.SetTXT(context.Background(), "_dnslink.example.com", "dnslink=/ipfs/QmX", 60)
+	if err == nil || err.Error() != "dns update refused: REFUSED" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = r.SetTXT(context.Background(), "_dnslink.example.org", "dnslink=/ipfs/QmX", 60)
+	if err == nil {
+		t.Fatal("updated a name out of the zone")
+	}
+}
+
+func TestTxtRdata(t *testing.T) {
+	long := string(bytes.Repeat([]byte{'a'}, 300))
+	b, err := txtRdata(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 302 || b[0] != 255 || b[256] != 45 {
+		t.Fatalf("unexpected character strings, length %d", len(b))
+	}
+}
@@ -0,0 +1,63 @@
+package namesys
+
+import (
+	"context"
+	"time"
+
+	path "github.com/ipfs/go-ipfs/path"
+
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// PublishHookOp is the operation the hooks of a name system are run for,
+// around every record it publishes.
+const PublishHookOp = "name/publish"
+
+// Hooks are run around the publishes of a name system, e.g. the *Hooks of
+// core/hooks. An error returned by Pre vetoes the publish.
+type Hooks interface {
+	Pre(ctx context.Context, op string, args interface{}) error
+	Post(ctx context.Context, op string, args, result interface{}, err error)
+}
+
+// HooksSetter is implemented by the name systems running hooks.
+type HooksSetter interface {
+	SetHooks(Hooks)
+}
+
+// PublishHookArgs describe a publish to the hooks.
+type PublishHookArgs struct {
+	Name  string
+	Value string
+	EOL   string `json:",omitempty"`
+
+	// How is "key" for records signed by the node with the key of the
+	// name, "delegation", "policy" or "signed" for records signed with
+	// other keys, or elsewhere.
+	How string
+}
+
+// SetHooks implements HooksSetter
+func (ns *mpns) SetHooks(h Hooks) {
+	ns.hooks = h
+}
+
+// withHooks runs publish between the hooks of ns. eol is zero when the
+// publisher picks it.
+func (ns *mpns) withHooks(ctx context.Context, id peer.ID, value path.Path, eol time.Time, how string, publish func() error) error {
+	if ns.hooks == nil {
+		return publish()
+	}
+
+	args := &PublishHookArgs{Name: id.Pretty(), Value: value.String(), How: how}
+	if !eol.IsZero() {
+		args.EOL = u.FormatRFC3339(eol)
+	}
+	if err := ns.hooks.Pre(ctx, PublishHookOp, args); err != nil {
+		return err
+	}
+	err := publish()
+	ns.hooks.Post(ctx, PublishHookOp, args, nil, err)
+	return err
+}
@@ -0,0 +1,118 @@
+package namesys
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
+	dshelp "github.com/ipfs/go-ipfs/thirdparty/ds-help"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dsq "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/query"
+	recpb "gx/ipfs/QmWYCqr6UDqqD1bfRybaAPtbAqcN3TSJpveaBXMwbQ3ePZ/go-libp2p-record/pb"
+	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// LocalRecord is an ipns record stored in the local datastore, either
+// published by this node or kept for other nodes by the DHT
+type LocalRecord struct {
+	Name  peer.ID
+	Entry *pb.IpnsEntry
+}
+
+// EOL returns the end of validity of the record, if it has one
+func (r *LocalRecord) EOL() (time.Time, bool) {
+	return checkEOL(r.Entry)
+}
+
+// LocalRecords returns the ipns records stored in d. Records that can't be
+// decoded are skipped.
+func LocalRecords(d ds.Datastore) ([]*LocalRecord, error) {
+	qr, err := d.Query(dsq.Query{})
+	if err != nil {
+		return nil, err
+	}
+	defer qr.Close()
+
+	var out []*LocalRecord
+	for {
+		e, ok := qr.NextSync()
+		if !ok {
+			break
+		}
+		if e.Error != nil {
+			return nil, e.Error
+		}
+
+		// records are stored under the base32 encoding of their routing
+		// key, which other keys fail to decode as
+		k, err := dshelp.BinaryFromDsKey(ds.RawKey(e.Key))
+		if err != nil || !strings.HasPrefix(string(k), "/ipns/") {
+			continue
+		}
+		data, ok := e.Value.([]byte)
+		if !ok {
+			continue
+		}
+
+		id, err := peer.IDFromBytes(k[len("/ipns/"):])
+		if err != nil {
+			log.Debugf("skipping ipns record with invalid name %q", k)
+			continue
+		}
+		rec := new(recpb.Record)
+		if err := proto.Unmarshal(data, rec); err != nil {
+			log.Debugf("skipping invalid ipns record for %s: %s", id, err)
+			continue
+		}
+		entry := new(pb.IpnsEntry)
+		if err := proto.Unmarshal(rec.GetValue(), entry); err != nil {
+			log.Debugf("skipping invalid ipns record for %s: %s", id, err)
+			continue
+		}
+
+		out = append(out, &LocalRecord{Name: id, Entry: entry})
+	}
+	return out, nil
+}
+
+// GetLocalRecord returns the record of the name id stored in d, or
+// ds.ErrNotFound
+func GetLocalRecord(d ds.Datastore, id peer.ID) (*LocalRecord, error) {
+	_, ipnskey := IpnsKeysForID(id)
+	v, err := d.Get(dshelp.NewKeyFromBinary([]byte(ipnskey)))
+	if err != nil {
+		return nil, err
+	}
+	data, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type returned from datastore: %#v", v)
+	}
+
+	rec := new(recpb.Record)
+	if err := proto.Unmarshal(data, rec); err != nil {
+		return nil, err
+	}
+	entry := new(pb.IpnsEntry)
+	if err := proto.Unmarshal(rec.GetValue(), entry); err != nil {
+		return nil, err
+	}
+	return &LocalRecord{Name: id, Entry: entry}, nil
+}
+
+// RemoveLocalRecord removes the record of the name id from d
+func RemoveLocalRecord(d ds.Datastore, id peer.ID) error {
+	_, ipnskey := IpnsKeysForID(id)
+	k := dshelp.NewKeyFromBinary([]byte(ipnskey))
+
+	has, err := d.Has(k)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return ds.ErrNotFound
+	}
+	return d.Delete(k)
+}
@@ -0,0 +1,76 @@
+package namesys
+
+import (
+	"context"
+	"testing"
+
+	path "github.com/ipfs/go-ipfs/path"
+	mockrouting "github.com/ipfs/go-ipfs/routing/mock"
+	testutil "github.com/ipfs/go-ipfs/thirdparty/testutil"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dssync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+func TestLocalRecords(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	d := mockrouting.NewServer().ClientWithDatastore(ctx, testutil.RandIdentityOrFatal(t), dstore)
+	publisher := NewRoutingPublisher(d, dstore)
+
+	// unrelated keys are skipped
+	if err := dstore.Put(ds.NewKey("/local/filesroot"), []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	h := path.FromString("/ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy")
+	names := make(map[peer.ID]bool)
+	for i := 0; i < 2; i++ {
+		sk, pk, err := testutil.RandTestKeyPair(512)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := publisher.Publish(ctx, sk, h); err != nil {
+			t.Fatal(err)
+		}
+		id, err := peer.IDFromPublicKey(pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names[id] = true
+	}
+
+	recs, err := LocalRecords(dstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(recs))
+	}
+	var removed peer.ID
+	for _, r := range recs {
+		if !names[r.Name] {
+			t.Fatalf("unexpected record for %s", r.Name)
+		}
+		if path.Path(r.Entry.GetValue()) != h || r.Entry.GetSequence() != 1 {
+			t.Fatalf("unexpected record %v", r.Entry)
+		}
+		removed = r.Name
+	}
+
+	if err := RemoveLocalRecord(dstore, removed); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveLocalRecord(dstore, removed); err != ds.ErrNotFound {
+		t.Fatalf("expected %s removing a missing record, got %v", ds.ErrNotFound, err)
+	}
+
+	recs, err = LocalRecords(dstore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].Name == removed {
+		t.Fatalf("expected the other record only, got %v", recs)
+	}
+}
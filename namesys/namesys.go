@@ -6,6 +6,7 @@ import (
 	"time"
 
 	path "github.com/ipfs/go-ipfs/path"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
 
 	routing "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing"
 	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
@@ -25,19 +26,33 @@ import (
 type mpns struct {
 	resolvers  map[string]resolver
 	publishers map[string]Publisher
+	clock      clock.Clock
+	hooks      Hooks
 }
 
 // NewNameSystem will construct the IPFS naming system based on Routing
 func NewNameSystem(r routing.ValueStore, ds ds.Datastore, cachesize int) NameSystem {
+	return NewNameSystemWithClock(r, ds, cachesize, clock.New())
+}
+
+// NewNameSystemWithClock constructs the IPFS naming system, using clk to
+// date published records and to expire resolved ones.
+func NewNameSystemWithClock(r routing.ValueStore, ds ds.Datastore, cachesize int, clk clock.Clock) NameSystem {
+	resolver := NewRoutingResolver(r, cachesize)
+	resolver.Clock = clk
+	publisher := NewRoutingPublisher(r, ds)
+	publisher.Clock = clk
+
 	return &mpns{
 		resolvers: map[string]resolver{
 			"dns":      newDNSResolver(),
 			"proquint": new(ProquintResolver),
-			"dht":      NewRoutingResolver(r, cachesize),
+			"dht":      resolver,
 		},
 		publishers: map[string]Publisher{
-			"/ipns/": NewRoutingPublisher(r, ds),
+			"/ipns/": publisher,
 		},
+		clock: clk,
 	}
 }
 
@@ -89,24 +104,46 @@ func (ns *mpns) resolveOnce(ctx context.Context, name string) (path.Path, error)
 
 // Publish implements Publisher
 func (ns *mpns) Publish(ctx context.Context, name ci.PrivKey, value path.Path) error {
-	err := ns.publishers["/ipns/"].Publish(ctx, name, value)
+	id, err := peer.IDFromPrivateKey(name)
+	if err != nil {
+		return err
+	}
+	err = ns.withHooks(ctx, id, value, time.Time{}, "key", func() error {
+		return ns.publishers["/ipns/"].Publish(ctx, name, value)
+	})
 	if err != nil {
 		return err
 	}
-	ns.addToDHTCache(name, value, time.Now().Add(DefaultRecordTTL))
+	ns.addToDHTCache(id, value, ns.clock.Now().Add(DefaultRecordTTL))
 	return nil
 }
 
 func (ns *mpns) PublishWithEOL(ctx context.Context, name ci.PrivKey, value path.Path, eol time.Time) error {
-	err := ns.publishers["/ipns/"].PublishWithEOL(ctx, name, value, eol)
+	id, err := peer.IDFromPrivateKey(name)
 	if err != nil {
 		return err
 	}
-	ns.addToDHTCache(name, value, eol)
+	err = ns.withHooks(ctx, id, value, eol, "key", func() error {
+		return ns.publishers["/ipns/"].PublishWithEOL(ctx, name, value, eol)
+	})
+	if err != nil {
+		return err
+	}
+	ns.addToDHTCache(id, value, eol)
 	return nil
 }
 
-func (ns *mpns) addToDHTCache(key ci.PrivKey, value path.Path, eol time.Time) {
+// SetValidator implements ValidatorSetter
+func (ns *mpns) SetValidator(v *Validator) {
+	if rr, ok := ns.resolvers["dht"].(*routingResolver); ok {
+		rr.Validator = v
+	}
+	if p, ok := ns.publishers["/ipns/"].(*ipnsPublisher); ok {
+		p.Validator = v
+	}
+}
+
+func (ns *mpns) addToDHTCache(name peer.ID, value path.Path, eol time.Time) {
 	rr, ok := ns.resolvers["dht"].(*routingResolver)
 	if !ok {
 		// should never happen, purely for sanity
@@ -124,14 +161,9 @@ func (ns *mpns) addToDHTCache(key ci.PrivKey, value path.Path, eol time.Time) {
 		return
 	}
 
-	name, err := peer.IDFromPrivateKey(key)
-	if err != nil {
-		log.Error("while adding to cache, could not get peerid from private key")
-		return
-	}
-
-	if time.Now().Add(DefaultResolverCacheTTL).Before(eol) {
-		eol = time.Now().Add(DefaultResolverCacheTTL)
+	ttl := boundTTL(DefaultResolverCacheTTL, rr.MinTTL, rr.MaxTTL)
+	if now := ns.clock.Now(); now.Add(ttl).Before(eol) {
+		eol = now.Add(ttl)
 	}
 	rr.cache.Add(name.Pretty(), cacheEntry{
 		val: value,
@@ -10,6 +10,9 @@ It is generated from these files:
 
 It has these top-level messages:
 	IpnsEntry
+	Delegation
+	SignaturePolicy
+	PolicySignature
 */
 package namesys_pb
 
@@ -58,6 +61,9 @@ type IpnsEntry struct {
 	Validity         []byte                  `protobuf:"bytes,4,opt,name=validity" json:"validity,omitempty"`
 	Sequence         *uint64                 `protobuf:"varint,5,opt,name=sequence" json:"sequence,omitempty"`
 	Ttl              *uint64                 `protobuf:"varint,6,opt,name=ttl" json:"ttl,omitempty"`
+	Delegation       *Delegation             `protobuf:"bytes,7,opt,name=delegation" json:"delegation,omitempty"`
+	Policy           *SignaturePolicy        `protobuf:"bytes,8,opt,name=policy" json:"policy,omitempty"`
+	PolicySignatures []*PolicySignature      `protobuf:"bytes,9,rep,name=policySignatures" json:"policySignatures,omitempty"`
 	XXX_unrecognized []byte                  `json:"-"`
 }
 
@@ -107,6 +113,139 @@ func (m *IpnsEntry) GetTtl() uint64 {
 	return 0
 }
 
+func (m *IpnsEntry) GetDelegation() *Delegation {
+	if m != nil {
+		return m.Delegation
+	}
+	return nil
+}
+
+func (m *IpnsEntry) GetPolicy() *SignaturePolicy {
+	if m != nil {
+		return m.Policy
+	}
+	return nil
+}
+
+func (m *IpnsEntry) GetPolicySignatures() []*PolicySignature {
+	if m != nil {
+		return m.PolicySignatures
+	}
+	return nil
+}
+
+type Delegation struct {
+	Owner            []byte `protobuf:"bytes,1,req,name=owner" json:"owner,omitempty"`
+	PubKey           []byte `protobuf:"bytes,2,req,name=pubKey" json:"pubKey,omitempty"`
+	Validity         []byte `protobuf:"bytes,3,req,name=validity" json:"validity,omitempty"`
+	Signature        []byte `protobuf:"bytes,4,req,name=signature" json:"signature,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *Delegation) Reset()         { *m = Delegation{} }
+func (m *Delegation) String() string { return proto.CompactTextString(m) }
+func (*Delegation) ProtoMessage()    {}
+
+func (m *Delegation) GetOwner() []byte {
+	if m != nil {
+		return m.Owner
+	}
+	return nil
+}
+
+func (m *Delegation) GetPubKey() []byte {
+	if m != nil {
+		return m.PubKey
+	}
+	return nil
+}
+
+func (m *Delegation) GetValidity() []byte {
+	if m != nil {
+		return m.Validity
+	}
+	return nil
+}
+
+func (m *Delegation) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+type SignaturePolicy struct {
+	Owner            []byte   `protobuf:"bytes,1,req,name=owner" json:"owner,omitempty"`
+	Keys             [][]byte `protobuf:"bytes,2,rep,name=keys" json:"keys,omitempty"`
+	Threshold        *uint32  `protobuf:"varint,3,req,name=threshold" json:"threshold,omitempty"`
+	Validity         []byte   `protobuf:"bytes,4,req,name=validity" json:"validity,omitempty"`
+	Signature        []byte   `protobuf:"bytes,5,req,name=signature" json:"signature,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *SignaturePolicy) Reset()         { *m = SignaturePolicy{} }
+func (m *SignaturePolicy) String() string { return proto.CompactTextString(m) }
+func (*SignaturePolicy) ProtoMessage()    {}
+
+func (m *SignaturePolicy) GetOwner() []byte {
+	if m != nil {
+		return m.Owner
+	}
+	return nil
+}
+
+func (m *SignaturePolicy) GetKeys() [][]byte {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *SignaturePolicy) GetThreshold() uint32 {
+	if m != nil && m.Threshold != nil {
+		return *m.Threshold
+	}
+	return 0
+}
+
+func (m *SignaturePolicy) GetValidity() []byte {
+	if m != nil {
+		return m.Validity
+	}
+	return nil
+}
+
+func (m *SignaturePolicy) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+type PolicySignature struct {
+	Key              *uint32 `protobuf:"varint,1,req,name=key" json:"key,omitempty"`
+	Signature        []byte  `protobuf:"bytes,2,req,name=signature" json:"signature,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *PolicySignature) Reset()         { *m = PolicySignature{} }
+func (m *PolicySignature) String() string { return proto.CompactTextString(m) }
+func (*PolicySignature) ProtoMessage()    {}
+
+func (m *PolicySignature) GetKey() uint32 {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return 0
+}
+
+func (m *PolicySignature) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("namesys.pb.IpnsEntry_ValidityType", IpnsEntry_ValidityType_name, IpnsEntry_ValidityType_value)
 }
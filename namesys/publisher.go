@@ -11,6 +11,7 @@ import (
 	pb "github.com/ipfs/go-ipfs/namesys/pb"
 	path "github.com/ipfs/go-ipfs/path"
 	pin "github.com/ipfs/go-ipfs/pin"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
 	dshelp "github.com/ipfs/go-ipfs/thirdparty/ds-help"
 	ft "github.com/ipfs/go-ipfs/unixfs"
 
@@ -40,6 +41,12 @@ const DefaultRecordTTL = 24 * time.Hour
 type ipnsPublisher struct {
 	routing routing.ValueStore
 	ds      ds.Datastore
+
+	// Clock dates the records published without an explicit EOL.
+	Clock clock.Clock
+
+	// Validator bounds the lifetime of the records published.
+	Validator *Validator
 }
 
 // NewRoutingPublisher constructs a publisher for the IPFS Routing name system.
@@ -47,19 +54,26 @@ func NewRoutingPublisher(route routing.ValueStore, ds ds.Datastore) *ipnsPublish
 	if ds == nil {
 		panic("nil datastore")
 	}
-	return &ipnsPublisher{routing: route, ds: ds}
+	return &ipnsPublisher{routing: route, ds: ds, Clock: clock.New(), Validator: DefaultValidator}
 }
 
 // Publish implements Publisher. Accepts a keypair and a value,
 // and publishes it out to the routing system
 func (p *ipnsPublisher) Publish(ctx context.Context, k ci.PrivKey, value path.Path) error {
 	log.Debugf("Publish %s", value)
-	return p.PublishWithEOL(ctx, k, value, time.Now().Add(DefaultRecordTTL))
+	return p.PublishWithEOL(ctx, k, value, p.Clock.Now().Add(DefaultRecordTTL))
 }
 
 // PublishWithEOL is a temporary stand in for the ipns records implementation
 // see here for more details: https://github.com/ipfs/specs/tree/master/records
+//
+// eol must be within the MaxLifetime of the publisher's validator, two
+// years by default, as the nodes of the network reject records living
+// longer: ErrLifetimeTooLong is returned otherwise.
 func (p *ipnsPublisher) PublishWithEOL(ctx context.Context, k ci.PrivKey, value path.Path, eol time.Time) error {
+	if max := p.Validator.MaxLifetime; max > 0 && eol.After(p.Clock.Now().Add(max)) {
+		return ErrLifetimeTooLong
+	}
 
 	id, err := peer.IDFromPrivateKey(k)
 	if err != nil {
@@ -81,21 +95,31 @@ func (p *ipnsPublisher) PublishWithEOL(ctx context.Context, k ci.PrivKey, value
 }
 
 func (p *ipnsPublisher) getPreviousSeqNo(ctx context.Context, ipnskey string) (uint64, error) {
-	prevrec, err := p.ds.Get(dshelp.NewKeyFromBinary([]byte(ipnskey)))
-	if err != nil && err != ds.ErrNotFound {
+	e, err := p.getPreviousEntry(ctx, ipnskey)
+	if err != nil || e == nil {
 		// None found, lets start at zero!
 		return 0, err
 	}
+	return e.GetSequence(), nil
+}
+
+// getPreviousEntry returns the last record of ipnskey, stored locally or
+// found in the routing system, nil if there is none
+func (p *ipnsPublisher) getPreviousEntry(ctx context.Context, ipnskey string) (*pb.IpnsEntry, error) {
+	prevrec, err := p.ds.Get(dshelp.NewKeyFromBinary([]byte(ipnskey)))
+	if err != nil && err != ds.ErrNotFound {
+		return nil, err
+	}
 	var val []byte
 	if err == nil {
 		prbytes, ok := prevrec.([]byte)
 		if !ok {
-			return 0, fmt.Errorf("unexpected type returned from datastore: %#v", prevrec)
+			return nil, fmt.Errorf("unexpected type returned from datastore: %#v", prevrec)
 		}
 		dhtrec := new(dhtpb.Record)
 		err := proto.Unmarshal(prbytes, dhtrec)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
 
 		val = dhtrec.GetValue()
@@ -106,8 +130,8 @@ func (p *ipnsPublisher) getPreviousSeqNo(ctx context.Context, ipnskey string) (u
 
 		rv, err := p.routing.GetValue(ctx, ipnskey)
 		if err != nil {
-			// no such record found, start at zero!
-			return 0, nil
+			// no such record found
+			return nil, nil
 		}
 
 		val = rv
@@ -116,10 +140,10 @@ func (p *ipnsPublisher) getPreviousSeqNo(ctx context.Context, ipnskey string) (u
 	e := new(pb.IpnsEntry)
 	err = proto.Unmarshal(val, e)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return e.GetSequence(), nil
+	return e, nil
 }
 
 // setting the TTL on published records is an experimental feature.
@@ -136,15 +160,22 @@ func checkCtxTTL(ctx context.Context) (time.Duration, bool) {
 }
 
 func PutRecordToRouting(ctx context.Context, k ci.PrivKey, value path.Path, seqnum uint64, eol time.Time, r routing.ValueStore, id peer.ID) error {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	namekey, ipnskey := IpnsKeysForID(id)
 	entry, err := CreateRoutingEntryData(k, value, seqnum, eol)
 	if err != nil {
 		return err
 	}
 
+	return putEntryToRouting(ctx, entry, k.GetPublic(), r, id)
+}
+
+// putEntryToRouting stores entry and pubk, the key of the name id, in the
+// routing system
+func putEntryToRouting(ctx context.Context, entry *pb.IpnsEntry, pubk ci.PubKey, r routing.ValueStore, id peer.ID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	namekey, ipnskey := IpnsKeysForID(id)
+
 	ttl, ok := checkCtxTTL(ctx)
 	if ok {
 		entry.Ttl = proto.Uint64(uint64(ttl.Nanoseconds()))
@@ -157,20 +188,13 @@ func PutRecordToRouting(ctx context.Context, k ci.PrivKey, value path.Path, seqn
 	}()
 
 	go func() {
-		errs <- PublishPublicKey(ctx, r, namekey, k.GetPublic())
+		errs <- PublishPublicKey(ctx, r, namekey, pubk)
 	}()
 
-	err = waitOnErrChan(ctx, errs)
-	if err != nil {
-		return err
-	}
-
-	err = waitOnErrChan(ctx, errs)
-	if err != nil {
+	if err := waitOnErrChan(ctx, errs); err != nil {
 		return err
 	}
-
-	return nil
+	return waitOnErrChan(ctx, errs)
 }
 
 func waitOnErrChan(ctx context.Context, errs chan error) error {
@@ -244,9 +268,10 @@ func ipnsEntryDataForSig(e *pb.IpnsEntry) []byte {
 		[]byte{})
 }
 
-var IpnsRecordValidator = &record.ValidChecker{
-	Func: ValidateIpnsRecord,
-	Sign: true,
+// NewIpnsRecordValidator returns a validator for ipns records which checks
+// their EOL against clk.
+func NewIpnsRecordValidator(clk clock.Clock) *record.ValidChecker {
+	return NewValidator(clk).ValidChecker()
 }
 
 func IpnsSelectorFunc(k string, vals [][]byte) (int, error) {
@@ -306,11 +331,21 @@ func selectRecord(recs []*pb.IpnsEntry, vals [][]byte) (int, error) {
 // ValidateIpnsRecord implements ValidatorFunc and verifies that the
 // given 'val' is an IpnsEntry and that that entry is valid.
 func ValidateIpnsRecord(k string, val []byte) error {
+	return validateIpnsRecord(val, time.Now())
+}
+
+// validateIpnsRecord verifies that val is an IpnsEntry valid at time now.
+func validateIpnsRecord(val []byte, now time.Time) error {
 	entry := new(pb.IpnsEntry)
 	err := proto.Unmarshal(val, entry)
 	if err != nil {
 		return err
 	}
+	return validateIpnsEntry(entry, now)
+}
+
+// validateIpnsEntry verifies that entry is valid at time now.
+func validateIpnsEntry(entry *pb.IpnsEntry, now time.Time) error {
 	switch entry.GetValidityType() {
 	case pb.IpnsEntry_EOL:
 		t, err := u.ParseRFC3339(string(entry.GetValidity()))
@@ -318,12 +353,34 @@ func ValidateIpnsRecord(k string, val []byte) error {
 			log.Debug("failed parsing time for ipns record EOL")
 			return err
 		}
-		if time.Now().After(t) {
+		if now.After(t) {
 			return ErrExpiredRecord
 		}
 	default:
 		return ErrUnrecognizedValidity
 	}
+
+	// the signatures can only be checked against the key of the name, on
+	// resolve, but an expired delegation or policy makes the record useless
+	// already
+	if d := entry.GetDelegation(); d != nil {
+		eol, err := delegationEOL(d)
+		if err != nil {
+			return err
+		}
+		if now.After(eol) {
+			return ErrDelegationExpired
+		}
+	}
+	if p := entry.GetPolicy(); p != nil {
+		eol, err := policyEOL(p)
+		if err != nil {
+			return err
+		}
+		if now.After(eol) {
+			return ErrPolicyExpired
+		}
+	}
 	return nil
 }
 
@@ -9,10 +9,13 @@ import (
 	namesys "github.com/ipfs/go-ipfs/namesys"
 	pb "github.com/ipfs/go-ipfs/namesys/pb"
 	path "github.com/ipfs/go-ipfs/path"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
 	dshelp "github.com/ipfs/go-ipfs/thirdparty/ds-help"
 
 	routing "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing"
+	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
 	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dsq "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/query"
 	goprocess "gx/ipfs/QmSF8fPo3jgVBAy8fpdjjYqgG87dkJgUprRBHRd2tmfgpP/goprocess"
 	gpctx "gx/ipfs/QmSF8fPo3jgVBAy8fpdjjYqgG87dkJgUprRBHRd2tmfgpP/goprocess/context"
 	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
@@ -28,8 +31,20 @@ var log = logging.Logger("ipns-repub")
 
 var DefaultRebroadcastInterval = time.Hour * 4
 
+// signedNamesPrefix is the datastore prefix of the names whose records are
+// signed elsewhere, the values are the public keys of the names
+var signedNamesPrefix = ds.NewKey("/local/ipns-signed")
+
 const DefaultRecordLifetime = time.Hour * 24
 
+// DefaultMaxSignedNames is the number of names signed elsewhere a
+// republisher keeps at most
+const DefaultMaxSignedNames = 256
+
+// ErrTooManySignedNames is returned when adding a signed name to a
+// republisher which already keeps MaxSignedNames of them
+var ErrTooManySignedNames = errors.New("too many signed names republished")
+
 type Republisher struct {
 	r  routing.ValueStore
 	ds ds.Datastore
@@ -40,8 +55,20 @@ type Republisher struct {
 	// how long records that are republished should be valid for
 	RecordLifetime time.Duration
 
+	// Clock drives the republishing ticker and dates the records. It must
+	// be set before Run is called.
+	Clock clock.Clock
+
+	// MaxSignedNames bounds the number of names added with AddSignedName,
+	// 0 means no bound.
+	MaxSignedNames int
+	signedLk       sync.Mutex
+
 	entrylock sync.Mutex
 	entries   map[peer.ID]struct{}
+
+	intervalLk sync.Mutex
+	reset      chan struct{}
 }
 
 func NewRepublisher(r routing.ValueStore, ds ds.Datastore, ps pstore.Peerstore) *Republisher {
@@ -50,8 +77,11 @@ func NewRepublisher(r routing.ValueStore, ds ds.Datastore, ps pstore.Peerstore)
 		ps:             ps,
 		ds:             ds,
 		entries:        make(map[peer.ID]struct{}),
+		reset:          make(chan struct{}, 1),
 		Interval:       DefaultRebroadcastInterval,
 		RecordLifetime: DefaultRecordLifetime,
+		Clock:          clock.New(),
+		MaxSignedNames: DefaultMaxSignedNames,
 	}
 }
 
@@ -61,13 +91,108 @@ func (rp *Republisher) AddName(id peer.ID) {
 	rp.entries[id] = struct{}{}
 }
 
+// AddSignedName adds the name of pk, whose records are signed elsewhere and
+// given to the node. The last record is rebroadcast as is until it expires.
+// The names are kept in the datastore, across restarts, up to
+// MaxSignedNames of them.
+func (rp *Republisher) AddSignedName(pk ci.PubKey) error {
+	_, err := rp.ReserveSignedName(pk)
+	return err
+}
+
+// ReserveSignedName adds the name of pk like AddSignedName, before its
+// record is published, so that concurrent publications can't add more than
+// MaxSignedNames names. The returned function removes the name again if it
+// wasn't kept before, for when the publication fails.
+func (rp *Republisher) ReserveSignedName(pk ci.PubKey) (func(), error) {
+	id, err := peer.IDFromPublicKey(pk)
+	if err != nil {
+		return nil, err
+	}
+	pkb, err := pk.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	k := signedNamesPrefix.ChildString(id.Pretty())
+
+	rp.signedLk.Lock()
+	defer rp.signedLk.Unlock()
+	has, err := rp.ds.Has(k)
+	if err != nil {
+		return nil, err
+	}
+	if has {
+		return func() {}, nil
+	}
+	if err := rp.checkSignedName(); err != nil {
+		return nil, err
+	}
+	if err := rp.ds.Put(k, pkb); err != nil {
+		return nil, err
+	}
+	return func() {
+		rp.signedLk.Lock()
+		defer rp.signedLk.Unlock()
+		if err := rp.ds.Delete(k); err != nil {
+			log.Errorf("removing the signed name %s: %s", id, err)
+		}
+	}, nil
+}
+
+// checkSignedName returns ErrTooManySignedNames if no name can be added. It
+// is called with signedLk held.
+func (rp *Republisher) checkSignedName() error {
+	if rp.MaxSignedNames <= 0 {
+		return nil
+	}
+
+	res, err := rp.ds.Query(dsq.Query{Prefix: signedNamesPrefix.String(), KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	count := 0
+	for r := range res.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		count++
+		if count >= rp.MaxSignedNames {
+			return ErrTooManySignedNames
+		}
+	}
+	return nil
+}
+
+// SetInterval changes the time between two republishes of a running
+// republisher. The next republish happens d from now.
+func (rp *Republisher) SetInterval(d time.Duration) {
+	rp.intervalLk.Lock()
+	rp.Interval = d
+	rp.intervalLk.Unlock()
+
+	select {
+	case rp.reset <- struct{}{}:
+	default:
+	}
+}
+
+func (rp *Republisher) interval() time.Duration {
+	rp.intervalLk.Lock()
+	defer rp.intervalLk.Unlock()
+	return rp.Interval
+}
+
 func (rp *Republisher) Run(proc goprocess.Process) {
-	tick := time.NewTicker(rp.Interval)
-	defer tick.Stop()
+	tick := rp.Clock.NewTicker(rp.interval())
+	defer func() { tick.Stop() }()
 
 	for {
 		select {
-		case <-tick.C:
+		case <-rp.reset:
+			tick.Stop()
+			tick = rp.Clock.NewTicker(rp.interval())
+		case <-tick.C():
 			err := rp.republishEntries(proc)
 			if err != nil {
 				log.Error("Republisher failed to republish: ", err)
@@ -97,35 +222,97 @@ func (rp *Republisher) republishEntries(p goprocess.Process) error {
 		}
 
 		// update record with same sequence number
-		eol := time.Now().Add(rp.RecordLifetime)
+		eol := rp.Clock.Now().Add(rp.RecordLifetime)
 		err = namesys.PutRecordToRouting(ctx, priv, p, seq, eol, rp.r, id)
 		if err != nil {
 			return err
 		}
 	}
 
+	return rp.republishSigned(ctx)
+}
+
+// republishSigned rebroadcasts the last records of the names added with
+// AddSignedName, and forgets the names whose record expired
+func (rp *Republisher) republishSigned(ctx context.Context) error {
+	res, err := rp.ds.Query(dsq.Query{Prefix: signedNamesPrefix.String()})
+	if err != nil {
+		return err
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		return err
+	}
+
+	for _, qe := range entries {
+		pkb, ok := qe.Value.([]byte)
+		if !ok {
+			return ds.ErrInvalidType
+		}
+		pk, err := ci.UnmarshalPublicKey(pkb)
+		if err != nil {
+			return err
+		}
+		id, err := peer.IDFromPublicKey(pk)
+		if err != nil {
+			return err
+		}
+
+		namekey, ipnskey := namesys.IpnsKeysForID(id)
+		e, err := rp.getLastEntry(ipnskey)
+		if err == errNoEntry {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := namesys.VerifyEntry(pk, e, rp.Clock.Now()); err != nil {
+			log.Infof("not republishing %s anymore: %s", id, err)
+			if err := rp.ds.Delete(ds.NewKey(qe.Key)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		log.Debugf("rebroadcasting signed ipns entry for %s", id)
+		if err := namesys.PublishEntry(ctx, rp.r, ipnskey, e); err != nil {
+			return err
+		}
+		if err := namesys.PublishPublicKey(ctx, rp.r, namekey, pk); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (rp *Republisher) getLastVal(k string) (path.Path, uint64, error) {
+	e, err := rp.getLastEntry(k)
+	if err != nil {
+		return "", 0, err
+	}
+	return path.Path(e.Value), e.GetSequence(), nil
+}
+
+// getLastEntry returns the record stored locally at the ipns key k
+func (rp *Republisher) getLastEntry(k string) (*pb.IpnsEntry, error) {
 	ival, err := rp.ds.Get(dshelp.NewKeyFromBinary([]byte(k)))
 	if err != nil {
 		// not found means we dont have a previously published entry
-		return "", 0, errNoEntry
+		return nil, errNoEntry
 	}
 
 	val := ival.([]byte)
 	dhtrec := new(recpb.Record)
 	err = proto.Unmarshal(val, dhtrec)
 	if err != nil {
-		return "", 0, err
+		return nil, err
 	}
 
 	// extract published data from record
 	e := new(pb.IpnsEntry)
 	err = proto.Unmarshal(dhtrec.GetValue(), e)
 	if err != nil {
-		return "", 0, err
+		return nil, err
 	}
-	return path.Path(e.Value), e.GetSequence(), nil
+	return e, nil
 }
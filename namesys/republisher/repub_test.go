@@ -13,8 +13,16 @@ import (
 	namesys "github.com/ipfs/go-ipfs/namesys"
 	. "github.com/ipfs/go-ipfs/namesys/republisher"
 	path "github.com/ipfs/go-ipfs/path"
+	mockrouting "github.com/ipfs/go-ipfs/routing/mock"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
+	testutil "github.com/ipfs/go-ipfs/thirdparty/testutil"
+
+	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
 	mocknet "gx/ipfs/QmQA5mdxru8Bh6dpC9PJfSkumqnmHgJX7knxSgBo5Lpime/go-libp2p/p2p/net/mock"
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dssync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
 	pstore "gx/ipfs/QmXZSd1qR5BxZkPyuwfT5jpqQFScZccoZvDneXsKzCNHWX/go-libp2p-peerstore"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
 )
 
 func TestRepublish(t *testing.T) {
@@ -95,6 +103,128 @@ func TestRepublish(t *testing.T) {
 	}
 }
 
+func TestRepublishMockClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	r := mockrouting.NewServer().ClientWithDatastore(ctx, testutil.RandIdentityOrFatal(t), dstore)
+	clk := clock.NewMock(time.Now())
+
+	ns := namesys.NewNameSystemWithClock(r, dstore, 0, clk)
+
+	privk, pubk, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPublicKey(pubk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := pstore.NewPeerstore()
+	if err := ps.AddPrivKey(id, privk); err != nil {
+		t.Fatal(err)
+	}
+
+	p := path.FromString("/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn")
+	if err := ns.PublishWithEOL(ctx, privk, p, clk.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "/ipns/" + id.Pretty()
+	if _, err := ns.Resolve(ctx, name); err != nil {
+		t.Fatal(err)
+	}
+
+	clk.Add(2 * time.Minute)
+	if _, err := ns.Resolve(ctx, name); err == nil {
+		t.Fatal("expected the record to have expired")
+	}
+
+	repub := NewRepublisher(r, dstore, ps)
+	repub.Clock = clk
+	repub.AddName(id)
+
+	proc := goprocess.Go(repub.Run)
+	defer proc.Close()
+
+	clk.BlockUntil(1)
+	clk.Add(repub.Interval)
+
+	// the republisher runs in its own goroutine
+	timeout := time.After(5 * time.Second)
+	for {
+		if _, err := ns.Resolve(ctx, name); err == nil {
+			break
+		}
+		select {
+		case <-timeout:
+			t.Fatal("record was not republished")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestMaxSignedNames(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	r := mockrouting.NewServer().ClientWithDatastore(ctx, testutil.RandIdentityOrFatal(t), dstore)
+	repub := NewRepublisher(r, dstore, pstore.NewPeerstore())
+	repub.MaxSignedNames = 2
+
+	var first, last ci.PubKey
+	for i := 0; i < 3; i++ {
+		_, pubk, err := testutil.RandTestKeyPair(512)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = pubk
+		}
+		if i < 2 {
+			if err := repub.AddSignedName(pubk); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if _, err := repub.ReserveSignedName(pubk); err != ErrTooManySignedNames {
+			t.Fatalf("expected %s, got %v", ErrTooManySignedNames, err)
+		}
+		if err := repub.AddSignedName(pubk); err != ErrTooManySignedNames {
+			t.Fatalf("expected %s, got %v", ErrTooManySignedNames, err)
+		}
+		last = pubk
+	}
+
+	// the names already kept can still be updated, and releasing them
+	// keeps them
+	release, err := repub.ReserveSignedName(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if _, err := repub.ReserveSignedName(last); err != ErrTooManySignedNames {
+		t.Fatalf("expected %s, got %v", ErrTooManySignedNames, err)
+	}
+
+	// a name whose publication failed is released
+	repub.MaxSignedNames = 3
+	release, err = repub.ReserveSignedName(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	_, other, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repub.AddSignedName(other); err != nil {
+		t.Fatalf("expected the released name to leave room for another: %s", err)
+	}
+}
+
 func verifyResolution(nodes []*core.IpfsNode, key string, exp path.Path) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
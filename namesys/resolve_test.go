@@ -8,6 +8,7 @@ import (
 
 	path "github.com/ipfs/go-ipfs/path"
 	mockrouting "github.com/ipfs/go-ipfs/routing/mock"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
 	testutil "github.com/ipfs/go-ipfs/thirdparty/testutil"
 
 	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
@@ -50,6 +51,41 @@ func TestRoutingResolve(t *testing.T) {
 	}
 }
 
+func TestPublishLifetime(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	d := mockrouting.NewServer().ClientWithDatastore(ctx, testutil.RandIdentityOrFatal(t), dstore)
+	publisher := NewRoutingPublisher(d, dstore)
+
+	privk, _, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := path.FromString("/ipfs/QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN")
+
+	// two years by default
+	long := time.Now().Add(DefaultMaxRecordLifetime + time.Hour)
+	if err := publisher.PublishWithEOL(ctx, privk, h, long); err != ErrLifetimeTooLong {
+		t.Fatalf("expected ErrLifetimeTooLong, got %v", err)
+	}
+	if err := publisher.PublishWithEOL(ctx, privk, h, time.Now().Add(DefaultMaxRecordLifetime-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// or the limit of the validator of the name system
+	v := NewValidator(clock.New())
+	v.MaxLifetime = 3 * DefaultMaxRecordLifetime
+	ns := NewNameSystem(d, dstore, 0)
+	ns.(ValidatorSetter).SetValidator(v)
+	if err := ns.PublishWithEOL(ctx, privk, h, long); err != nil {
+		t.Fatal(err)
+	}
+	v.MaxLifetime = time.Hour
+	if err := ns.PublishWithEOL(ctx, privk, h, time.Now().Add(2*time.Hour)); err != ErrLifetimeTooLong {
+		t.Fatalf("expected ErrLifetimeTooLong, got %v", err)
+	}
+}
+
 func TestPrexistingExpiredRecord(t *testing.T) {
 	dstore := dssync.MutexWrap(ds.NewMapDatastore())
 	d := mockrouting.NewServer().ClientWithDatastore(context.Background(), testutil.RandIdentityOrFatal(t), dstore)
@@ -124,6 +160,84 @@ func TestPrexistingRecord(t *testing.T) {
 	}
 }
 
+func TestResolveExpiredRecord(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	d := mockrouting.NewServer().ClientWithDatastore(ctx, testutil.RandIdentityOrFatal(t), dstore)
+	clk := clock.NewMock(time.Now())
+
+	resolver := NewRoutingResolver(d, 0)
+	resolver.Clock = clk
+
+	privk, pubk, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := peer.IDFromPublicKey(pubk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := path.FromString("/ipfs/QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN")
+	err = PutRecordToRouting(ctx, privk, h, 0, clk.Now().Add(time.Hour), d, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyCanResolve(resolver, id.Pretty(), h); err != nil {
+		t.Fatal(err)
+	}
+
+	clk.Add(2 * time.Hour)
+	if _, err := resolver.Resolve(ctx, id.Pretty()); err != ErrExpiredRecord {
+		t.Fatalf("expected ErrExpiredRecord, got %v", err)
+	}
+}
+
+func TestResolverCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	d := mockrouting.NewServer().ClientWithDatastore(ctx, testutil.RandIdentityOrFatal(t), dstore)
+	clk := clock.NewMock(time.Now())
+
+	resolver := NewRoutingResolver(d, 16)
+	resolver.Clock = clk
+
+	privk, pubk, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := peer.IDFromPublicKey(pubk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eol := clk.Now().Add(time.Hour)
+	h1 := path.FromString("/ipfs/QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN")
+	if err := PutRecordToRouting(ctx, privk, h1, 0, eol, d, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyCanResolve(resolver, id.Pretty(), h1); err != nil {
+		t.Fatal(err)
+	}
+
+	// a newer record is not seen until the cached one expires
+	h2 := path.FromString("/ipfs/QmPXME1oRtoT627YKaDPDQ3PwA8tdP9rWuAAweLzqSwAWT")
+	if err := PutRecordToRouting(ctx, privk, h2, 1, eol, d, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyCanResolve(resolver, id.Pretty(), h1); err != nil {
+		t.Fatal(err)
+	}
+
+	clk.Add(DefaultResolverCacheTTL + time.Second)
+	if err := verifyCanResolve(resolver, id.Pretty(), h2); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func verifyCanResolve(r Resolver, name string, exp path.Path) error {
 	res, err := r.Resolve(context.Background(), name)
 	if err != nil {
@@ -2,12 +2,12 @@ package namesys
 
 import (
 	"context"
-	"fmt"
 	"strings"
 	"time"
 
 	pb "github.com/ipfs/go-ipfs/namesys/pb"
 	path "github.com/ipfs/go-ipfs/path"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
 
 	routing "gx/ipfs/QmNdaQ8itUU9jEZUwTsG4gHMaPmRfi6FEe89QjQAFbep3M/go-libp2p-routing"
 	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
@@ -26,16 +26,29 @@ type routingResolver struct {
 	routing routing.ValueStore
 
 	cache *lru.Cache
+
+	// Clock decides when records and cache entries expire.
+	Clock clock.Clock
+
+	// Validator, if set, learns the sequence numbers of the records
+	// resolved, to reject older ones.
+	Validator *Validator
+
+	// MinTTL and MaxTTL bound the time records are cached for, whatever
+	// their TTL. Zero means no bound.
+	MinTTL time.Duration
+	MaxTTL time.Duration
 }
 
-func (r *routingResolver) cacheGet(name string) (path.Path, bool) {
+// cacheGet returns the cached value of name, and until when it is cached
+func (r *routingResolver) cacheGet(name string) (path.Path, time.Time, bool) {
 	if r.cache == nil {
-		return "", false
+		return "", time.Time{}, false
 	}
 
 	ientry, ok := r.cache.Get(name)
 	if !ok {
-		return "", false
+		return "", time.Time{}, false
 	}
 
 	entry, ok := ientry.(cacheEntry)
@@ -44,20 +57,30 @@ func (r *routingResolver) cacheGet(name string) (path.Path, bool) {
 		log.Panicf("unexpected type %T in cache for %q.", ientry, name)
 	}
 
-	if time.Now().Before(entry.eol) {
-		return entry.val, true
+	if r.Clock.Now().Before(entry.eol) {
+		return entry.val, entry.eol, true
 	}
 
 	r.cache.Remove(name)
 
-	return "", false
+	return "", time.Time{}, false
 }
 
-func (r *routingResolver) cacheSet(name string, val path.Path, rec *pb.IpnsEntry) {
+func (r *routingResolver) cacheSet(name string, val path.Path, cacheTil time.Time) {
 	if r.cache == nil {
 		return
 	}
 
+	r.cache.Add(name, cacheEntry{
+		val: val,
+		eol: cacheTil,
+	})
+}
+
+// cacheEOL returns until when rec can be cached: for its TTL, within the
+// bounds of the resolver, and no longer than it and its delegation or
+// policy are valid
+func (r *routingResolver) cacheEOL(rec *pb.IpnsEntry) time.Time {
 	// if completely unspecified, just use one minute
 	ttl := DefaultResolverCacheTTL
 	if rec.Ttl != nil {
@@ -66,17 +89,24 @@ func (r *routingResolver) cacheSet(name string, val path.Path, rec *pb.IpnsEntry
 			ttl = recttl
 		}
 	}
+	ttl = boundTTL(ttl, r.MinTTL, r.MaxTTL)
 
-	cacheTil := time.Now().Add(ttl)
+	cacheTil := r.Clock.Now().Add(ttl)
 	eol, ok := checkEOL(rec)
 	if ok && eol.Before(cacheTil) {
 		cacheTil = eol
 	}
-
-	r.cache.Add(name, cacheEntry{
-		val: val,
-		eol: cacheTil,
-	})
+	if d := rec.GetDelegation(); d != nil {
+		if deol, err := delegationEOL(d); err == nil && deol.Before(cacheTil) {
+			cacheTil = deol
+		}
+	}
+	if p := rec.GetPolicy(); p != nil {
+		if peol, err := policyEOL(p); err == nil && peol.Before(cacheTil) {
+			cacheTil = peol
+		}
+	}
+	return cacheTil
 }
 
 type cacheEntry struct {
@@ -99,8 +129,10 @@ func NewRoutingResolver(route routing.ValueStore, cachesize int) *routingResolve
 	}
 
 	return &routingResolver{
-		routing: route,
-		cache:   cache,
+		routing:   route,
+		cache:     cache,
+		Clock:     clock.New(),
+		Validator: DefaultValidator,
 	}
 }
 
@@ -118,8 +150,9 @@ func (r *routingResolver) ResolveN(ctx context.Context, name string, depth int)
 // resolve SFS-like names.
 func (r *routingResolver) resolveOnce(ctx context.Context, name string) (path.Path, error) {
 	log.Debugf("RoutingResolve: '%s'", name)
-	cached, ok := r.cacheGet(name)
+	cached, cacheTil, ok := r.cacheGet(name)
 	if ok {
+		reportTTL(ctx, cacheTil.Sub(r.Clock.Now()))
 		return cached, nil
 	}
 
@@ -177,12 +210,16 @@ func (r *routingResolver) resolveOnce(ctx context.Context, name string) (path.Pa
 		}
 	}
 
-	// check sig with pk
-	if ok, err := pubkey.Verify(ipnsEntryDataForSig(entry), entry.GetSignature()); err != nil || !ok {
-		return "", fmt.Errorf("Invalid value. Not signed by PrivateKey corresponding to %v", pubkey)
+	if err := VerifyEntry(pubkey, entry, r.Clock.Now()); err != nil {
+		return "", err
+	}
+
+	if r.Validator != nil {
+		r.Validator.Observe(string(h), entry.GetSequence())
 	}
 
-	// ok sig checks out. this is a valid name.
+	cacheTil := r.cacheEOL(entry)
+	reportTTL(ctx, cacheTil.Sub(r.Clock.Now()))
 
 	// check for old style record:
 	valh, err := mh.Cast(entry.GetValue())
@@ -193,13 +230,13 @@ func (r *routingResolver) resolveOnce(ctx context.Context, name string) (path.Pa
 			return "", err
 		}
 
-		r.cacheSet(name, p, entry)
+		r.cacheSet(name, p, cacheTil)
 		return p, nil
 	} else {
 		// Its an old style multihash record
 		log.Warning("Detected old style multihash record")
 		p := path.FromCid(cid.NewCidV0(valh))
-		r.cacheSet(name, p, entry)
+		r.cacheSet(name, p, cacheTil)
 		return p, nil
 	}
 }
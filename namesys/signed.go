@@ -0,0 +1,117 @@
+package namesys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
+	path "github.com/ipfs/go-ipfs/path"
+
+	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// SignedPublisher is implemented by the publishers able to publish records
+// signed elsewhere, e.g. by a key holder which doesn't run a node and only
+// hands the signed records over.
+type SignedPublisher interface {
+	// PublishSignedEntry publishes e, a record of the name of pubk, if it is
+	// valid and not older than the last one published.
+	PublishSignedEntry(ctx context.Context, pubk ci.PubKey, e *pb.IpnsEntry) error
+}
+
+// VerifyEntry checks that e is a valid record of the name of pubk at now.
+// It must be signed by pubk, by a key pubk delegated to, or by enough of the
+// keys of the signature policy of the name.
+func VerifyEntry(pubk ci.PubKey, e *pb.IpnsEntry, now time.Time) error {
+	if e.GetPolicy() != nil {
+		// the record is signed by the keys the policy of the name lists
+		if e.GetDelegation() != nil {
+			return ErrInvalidPolicy
+		}
+		if err := CheckThresholdEntry(pubk, e, now); err != nil {
+			return err
+		}
+	} else {
+		// a delegated record is signed by the key the name's key delegated to
+		signer := pubk
+		if d := e.GetDelegation(); d != nil {
+			var err error
+			signer, err = ValidateDelegation(pubk, d, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		// check sig with pk
+		if ok, err := signer.Verify(ipnsEntryDataForSig(e), e.GetSignature()); err != nil || !ok {
+			return fmt.Errorf("Invalid value. Not signed by PrivateKey corresponding to %v", signer)
+		}
+	}
+
+	// ok sig checks out. this is a valid name, if it has not expired.
+	if eol, ok := checkEOL(e); ok && now.After(eol) {
+		return ErrExpiredRecord
+	}
+	return nil
+}
+
+// PublishSignedEntry implements SignedPublisher
+func (p *ipnsPublisher) PublishSignedEntry(ctx context.Context, pubk ci.PubKey, e *pb.IpnsEntry) error {
+	now := p.Clock.Now()
+	if err := VerifyEntry(pubk, e, now); err != nil {
+		return err
+	}
+	eol, ok := checkEOL(e)
+	if !ok {
+		return ErrMissingEOL
+	}
+	if err := validateIpnsEntry(e, now); err != nil {
+		return err
+	}
+	if max := p.Validator.MaxLifetime; max > 0 && eol.After(now.Add(max)) {
+		return ErrEOLTooFar
+	}
+
+	id, err := peer.IDFromPublicKey(pubk)
+	if err != nil {
+		return err
+	}
+	_, ipnskey := IpnsKeysForID(id)
+	prev, err := p.getPreviousEntry(ctx, ipnskey)
+	if err != nil {
+		return err
+	}
+	if prev != nil && e.GetSequence() <= prev.GetSequence() {
+		return ErrStaleSequence
+	}
+
+	return putEntryToRouting(ctx, e, pubk, p.routing, id)
+}
+
+// PublishSignedEntry implements SignedPublisher
+func (ns *mpns) PublishSignedEntry(ctx context.Context, pubk ci.PubKey, e *pb.IpnsEntry) error {
+	sp, ok := ns.publishers["/ipns/"].(SignedPublisher)
+	if !ok {
+		return errors.New("publisher does not support signed records")
+	}
+	id, err := peer.IDFromPublicKey(pubk)
+	if err != nil {
+		return err
+	}
+	value, err := path.ParsePath(string(e.GetValue()))
+	if err != nil {
+		return err
+	}
+	eol, _ := checkEOL(e)
+	err = ns.withHooks(ctx, id, value, eol, "signed", func() error {
+		return sp.PublishSignedEntry(ctx, pubk, e)
+	})
+	if err != nil {
+		return err
+	}
+	ns.addToDHTCache(id, value, eol)
+	return nil
+}
@@ -0,0 +1,97 @@
+package namesys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	path "github.com/ipfs/go-ipfs/path"
+	mockrouting "github.com/ipfs/go-ipfs/routing/mock"
+	testutil "github.com/ipfs/go-ipfs/thirdparty/testutil"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dssync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+func TestPublishSignedEntry(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	d := mockrouting.NewServer().ClientWithDatastore(ctx, testutil.RandIdentityOrFatal(t), dstore)
+
+	resolver := NewRoutingResolver(d, 0)
+	publisher := NewRoutingPublisher(d, dstore)
+
+	sk, pk, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPk, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := peer.IDFromPublicKey(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := path.FromString("/ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy")
+	e, err := CreateRoutingEntryData(sk, h, 2, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.PublishSignedEntry(ctx, otherPk, e); err == nil {
+		t.Fatal("expected a record signed by another key to be refused")
+	}
+	if err := publisher.PublishSignedEntry(ctx, pk, e); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := resolver.Resolve(ctx, name.Pretty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != h {
+		t.Fatalf("expected %s, got %s", h, res)
+	}
+
+	for _, seq := range []uint64{1, 2} {
+		old, err := CreateRoutingEntryData(sk, h, seq, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := publisher.PublishSignedEntry(ctx, pk, old); err != ErrStaleSequence {
+			t.Fatalf("seq %d: expected %s, got %v", seq, ErrStaleSequence, err)
+		}
+	}
+
+	noeol, err := CreateRoutingEntryData(sk, h, 3, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	noeol.Validity = nil
+	noeol.Signature, err = sk.Sign(ipnsEntryDataForSig(noeol))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.PublishSignedEntry(ctx, pk, noeol); err != ErrMissingEOL {
+		t.Fatalf("expected %s, got %v", ErrMissingEOL, err)
+	}
+
+	far, err := CreateRoutingEntryData(sk, h, 3, time.Now().Add(DefaultMaxRecordLifetime+time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.PublishSignedEntry(ctx, pk, far); err != ErrEOLTooFar {
+		t.Fatalf("expected %s, got %v", ErrEOLTooFar, err)
+	}
+
+	expired, err := CreateRoutingEntryData(sk, h, 3, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.PublishSignedEntry(ctx, pk, expired); err != ErrExpiredRecord {
+		t.Fatalf("expected %s, got %v", ErrExpiredRecord, err)
+	}
+}
@@ -0,0 +1,310 @@
+package namesys
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
+	path "github.com/ipfs/go-ipfs/path"
+
+	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+// ErrPolicyExpired is returned for records signed under a signature policy
+// that is no longer valid
+var ErrPolicyExpired = errors.New("expired signature policy")
+
+// ErrInvalidPolicy is returned for signature policies that were not signed
+// by the key of the name they are used for, or that can't be met
+var ErrInvalidPolicy = errors.New("invalid signature policy")
+
+// ErrThresholdNotMet is returned for records carrying fewer valid
+// signatures than their policy requires
+var ErrThresholdNotMet = errors.New("not enough signatures for the signature policy")
+
+// ThresholdPublisher is implemented by the publishers able to publish
+// records that must be signed by several keys, as listed in a signature
+// policy of the name. Resolvers that don't know signature policies check
+// these records against the key of the name, and reject them.
+type ThresholdPublisher interface {
+	// NewThresholdEntry returns a record of value for the name of p,
+	// following the last one published, with no signatures yet.
+	NewThresholdEntry(ctx context.Context, p *pb.SignaturePolicy, value path.Path, eol time.Time) (*pb.IpnsEntry, error)
+
+	// PublishThresholdEntry publishes e if it carries enough signatures
+	// for its policy.
+	PublishThresholdEntry(ctx context.Context, e *pb.IpnsEntry) error
+}
+
+// CreatePolicy returns a signature policy, signed by owner, requiring the
+// records of owner's name to be signed by threshold of keys until eol.
+func CreatePolicy(owner ci.PrivKey, keys []ci.PubKey, threshold int, eol time.Time) (*pb.SignaturePolicy, error) {
+	if threshold < 1 || threshold > len(keys) {
+		return nil, fmt.Errorf("threshold must be between 1 and the number of keys, %d", len(keys))
+	}
+
+	ownerb, err := ci.MarshalPublicKey(owner.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+
+	p := &pb.SignaturePolicy{
+		Owner:     ownerb,
+		Threshold: proto.Uint32(uint32(threshold)),
+		Validity:  []byte(u.FormatRFC3339(eol)),
+	}
+	for _, k := range keys {
+		kb, err := ci.MarshalPublicKey(k)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range p.Keys {
+			if bytes.Equal(kb, other) {
+				return nil, errors.New("keys of a signature policy must be distinct")
+			}
+		}
+		p.Keys = append(p.Keys, kb)
+	}
+
+	p.Signature, err = owner.Sign(policyDataForSig(p))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PolicyName returns the name p applies to
+func PolicyName(p *pb.SignaturePolicy) (peer.ID, error) {
+	owner, err := ci.UnmarshalPublicKey(p.GetOwner())
+	if err != nil {
+		return "", err
+	}
+	return peer.IDFromPublicKey(owner)
+}
+
+// ValidatePolicy checks that p was signed by owner, can be met and is valid
+// at time now, and returns the keys it lists.
+func ValidatePolicy(owner ci.PubKey, p *pb.SignaturePolicy, now time.Time) ([]ci.PubKey, error) {
+	powner, err := ci.UnmarshalPublicKey(p.GetOwner())
+	if err != nil {
+		return nil, err
+	}
+	if !powner.Equals(owner) {
+		return nil, ErrInvalidPolicy
+	}
+	if ok, err := owner.Verify(policyDataForSig(p), p.GetSignature()); err != nil || !ok {
+		return nil, ErrInvalidPolicy
+	}
+	if t := p.GetThreshold(); t < 1 || int(t) > len(p.GetKeys()) {
+		return nil, ErrInvalidPolicy
+	}
+
+	eol, err := policyEOL(p)
+	if err != nil {
+		return nil, err
+	}
+	if now.After(eol) {
+		return nil, ErrPolicyExpired
+	}
+
+	keys := make([]ci.PubKey, len(p.GetKeys()))
+	for i, kb := range p.GetKeys() {
+		keys[i], err = ci.UnmarshalPublicKey(kb)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// SignThresholdEntry adds the signature of k, which must be one of the keys
+// of the policy of e, to e.
+func SignThresholdEntry(k ci.PrivKey, e *pb.IpnsEntry) error {
+	p := e.GetPolicy()
+	if p == nil {
+		return errors.New("record has no signature policy")
+	}
+
+	idx := -1
+	for i, kb := range p.GetKeys() {
+		pk, err := ci.UnmarshalPublicKey(kb)
+		if err != nil {
+			return err
+		}
+		if pk.Equals(k.GetPublic()) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return errors.New("key is not part of the signature policy")
+	}
+
+	sig, err := k.Sign(ipnsEntryDataForSig(e))
+	if err != nil {
+		return err
+	}
+
+	for _, s := range e.PolicySignatures {
+		if int(s.GetKey()) == idx {
+			s.Signature = sig
+			return nil
+		}
+	}
+	e.PolicySignatures = append(e.PolicySignatures, &pb.PolicySignature{
+		Key:       proto.Uint32(uint32(idx)),
+		Signature: sig,
+	})
+	if len(e.Signature) == 0 {
+		// Signature is a required field; the first co-signer fills it in
+		e.Signature = sig
+	}
+	return nil
+}
+
+// CheckThresholdEntry checks that e carries signatures, valid at time now,
+// from at least as many keys as its policy, signed by owner, requires.
+func CheckThresholdEntry(owner ci.PubKey, e *pb.IpnsEntry, now time.Time) error {
+	p := e.GetPolicy()
+	if p == nil {
+		return errors.New("record has no signature policy")
+	}
+	keys, err := ValidatePolicy(owner, p, now)
+	if err != nil {
+		return err
+	}
+
+	data := ipnsEntryDataForSig(e)
+	signed := make(map[uint32]bool)
+	for _, s := range e.GetPolicySignatures() {
+		i := s.GetKey()
+		if int(i) >= len(keys) || signed[i] {
+			continue
+		}
+		if ok, err := keys[i].Verify(data, s.GetSignature()); err == nil && ok {
+			signed[i] = true
+		}
+	}
+
+	if len(signed) < int(p.GetThreshold()) {
+		return ErrThresholdNotMet
+	}
+	return nil
+}
+
+func policyEOL(p *pb.SignaturePolicy) (time.Time, error) {
+	eol, err := u.ParseRFC3339(string(p.GetValidity()))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid signature policy validity: %s", err)
+	}
+	return eol, nil
+}
+
+// policyDataForSig length-prefixes every field, as the number of keys
+// varies
+func policyDataForSig(p *pb.SignaturePolicy) []byte {
+	buf := bytes.NewBufferString("ipns-policy:")
+	lenbuf := make([]byte, binary.MaxVarintLen64)
+	write := func(b []byte) {
+		n := binary.PutUvarint(lenbuf, uint64(len(b)))
+		buf.Write(lenbuf[:n])
+		buf.Write(b)
+	}
+
+	write(p.Owner)
+	write([]byte(fmt.Sprint(p.GetThreshold())))
+	write(p.Validity)
+	for _, k := range p.Keys {
+		write(k)
+	}
+	return buf.Bytes()
+}
+
+// NewThresholdEntry implements ThresholdPublisher
+func (p *ipnsPublisher) NewThresholdEntry(ctx context.Context, pol *pb.SignaturePolicy, value path.Path, eol time.Time) (*pb.IpnsEntry, error) {
+	peol, err := policyEOL(pol)
+	if err != nil {
+		return nil, err
+	}
+	if eol.After(peol) {
+		return nil, fmt.Errorf("record lifetime exceeds the signature policy, which expires at %s", u.FormatRFC3339(peol))
+	}
+
+	id, err := PolicyName(pol)
+	if err != nil {
+		return nil, err
+	}
+	_, ipnskey := IpnsKeysForID(id)
+	seqnum, err := p.getPreviousSeqNo(ctx, ipnskey)
+	if err != nil {
+		return nil, err
+	}
+
+	typ := pb.IpnsEntry_EOL
+	return &pb.IpnsEntry{
+		Value:        []byte(value),
+		Signature:    []byte{},
+		ValidityType: &typ,
+		Validity:     []byte(u.FormatRFC3339(eol)),
+		Sequence:     proto.Uint64(seqnum + 1),
+		Policy:       pol,
+	}, nil
+}
+
+// PublishThresholdEntry implements ThresholdPublisher
+func (p *ipnsPublisher) PublishThresholdEntry(ctx context.Context, e *pb.IpnsEntry) error {
+	owner, err := ci.UnmarshalPublicKey(e.GetPolicy().GetOwner())
+	if err != nil {
+		return err
+	}
+	if err := CheckThresholdEntry(owner, e, p.Clock.Now()); err != nil {
+		return err
+	}
+
+	id, err := peer.IDFromPublicKey(owner)
+	if err != nil {
+		return err
+	}
+	return putEntryToRouting(ctx, e, owner, p.routing, id)
+}
+
+// NewThresholdEntry implements ThresholdPublisher
+func (ns *mpns) NewThresholdEntry(ctx context.Context, p *pb.SignaturePolicy, value path.Path, eol time.Time) (*pb.IpnsEntry, error) {
+	tp, ok := ns.publishers["/ipns/"].(ThresholdPublisher)
+	if !ok {
+		return nil, errors.New("publisher does not support signature policies")
+	}
+	return tp.NewThresholdEntry(ctx, p, value, eol)
+}
+
+// PublishThresholdEntry implements ThresholdPublisher
+func (ns *mpns) PublishThresholdEntry(ctx context.Context, e *pb.IpnsEntry) error {
+	tp, ok := ns.publishers["/ipns/"].(ThresholdPublisher)
+	if !ok {
+		return errors.New("publisher does not support signature policies")
+	}
+	id, err := PolicyName(e.GetPolicy())
+	if err != nil {
+		return err
+	}
+	value, err := path.ParsePath(string(e.GetValue()))
+	if err != nil {
+		return err
+	}
+	eol, _ := checkEOL(e)
+	err = ns.withHooks(ctx, id, value, eol, "policy", func() error {
+		return tp.PublishThresholdEntry(ctx, e)
+	})
+	if err != nil {
+		return err
+	}
+	ns.addToDHTCache(id, value, eol)
+	return nil
+}
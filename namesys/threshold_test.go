@@ -0,0 +1,144 @@
+package namesys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	path "github.com/ipfs/go-ipfs/path"
+	mockrouting "github.com/ipfs/go-ipfs/routing/mock"
+	testutil "github.com/ipfs/go-ipfs/thirdparty/testutil"
+
+	ci "gx/ipfs/QmP1DfoUjiWH2ZBo1PBH6FupdBucbDepx3HpWmEY6JMUpY/go-libp2p-crypto"
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dssync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+func TestThresholdPublish(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	d := mockrouting.NewServer().ClientWithDatastore(ctx, testutil.RandIdentityOrFatal(t), dstore)
+
+	resolver := NewRoutingResolver(d, 0)
+	publisher := NewRoutingPublisher(d, dstore)
+
+	owner, ownerPub, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signers []ci.PrivKey
+	var keys []ci.PubKey
+	for i := 0; i < 3; i++ {
+		sk, pk, err := testutil.RandTestKeyPair(512)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, sk)
+		keys = append(keys, pk)
+	}
+	outsider, _, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := peer.IDFromPublicKey(ownerPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CreatePolicy(owner, keys, 4, time.Now().Add(time.Hour)); err == nil {
+		t.Fatal("expected a policy that can't be met to be refused")
+	}
+	pol, err := CreatePolicy(owner, keys, 2, time.Now().Add(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := path.FromString("/ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy")
+	if _, err := publisher.NewThresholdEntry(ctx, pol, h, time.Now().Add(72*time.Hour)); err == nil {
+		t.Fatal("expected a record outliving the policy to be refused")
+	}
+	e, err := publisher.NewThresholdEntry(ctx, pol, h, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SignThresholdEntry(outsider, e); err == nil {
+		t.Fatal("expected a key outside the policy not to be able to sign")
+	}
+	if err := SignThresholdEntry(signers[0], e); err != nil {
+		t.Fatal(err)
+	}
+	// signing twice with the same key doesn't count twice
+	if err := SignThresholdEntry(signers[0], e); err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.PublishThresholdEntry(ctx, e); err != ErrThresholdNotMet {
+		t.Fatalf("expected %s, got %v", ErrThresholdNotMet, err)
+	}
+
+	if err := SignThresholdEntry(signers[2], e); err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.PublishThresholdEntry(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := resolver.Resolve(ctx, name.Pretty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != h {
+		t.Fatalf("resolved to %s, expected %s", res, h)
+	}
+}
+
+func TestCheckThresholdEntry(t *testing.T) {
+	owner, ownerPub, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPub, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, signerPub, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	pol, err := CreatePolicy(owner, []ci.PubKey{signerPub}, 1, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := CreateRoutingEntryData(signer, path.FromString("/ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy"), 1, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Policy = pol
+	if err := CheckThresholdEntry(ownerPub, e, now); err != ErrThresholdNotMet {
+		t.Fatalf("expected %s without policy signatures, got %v", ErrThresholdNotMet, err)
+	}
+
+	if err := SignThresholdEntry(signer, e); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckThresholdEntry(ownerPub, e, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckThresholdEntry(otherPub, e, now); err != ErrInvalidPolicy {
+		t.Fatalf("expected %s for another name, got %v", ErrInvalidPolicy, err)
+	}
+	if err := CheckThresholdEntry(ownerPub, e, now.Add(2*time.Hour)); err != ErrPolicyExpired {
+		t.Fatalf("expected %s, got %v", ErrPolicyExpired, err)
+	}
+
+	// changing the value invalidates the signatures
+	e.Value = []byte("/ipfs/QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN")
+	if err := CheckThresholdEntry(ownerPub, e, now); err != ErrThresholdNotMet {
+		t.Fatalf("expected %s for a tampered record, got %v", ErrThresholdNotMet, err)
+	}
+}
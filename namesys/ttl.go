@@ -0,0 +1,78 @@
+package namesys
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CacheBounder is implemented by the name systems caching the records they
+// resolve, to bound the time the records are cached for, whatever their TTL.
+type CacheBounder interface {
+	// SetCacheBounds sets the least and the most time a record is cached
+	// for. Zero means no bound. A record is never cached past its EOL.
+	SetCacheBounds(min, max time.Duration)
+}
+
+type ttlCollectorKey struct{}
+
+// ttlCollector keeps the smallest TTL reported during a resolution
+type ttlCollector struct {
+	lk  sync.Mutex
+	ttl time.Duration
+	set bool
+}
+
+// ContextWithTTL returns a context collecting the TTLs of the records
+// resolved with it, and a function returning the time the result of the
+// resolution may be cached for: the smallest of the TTLs, as bounded by
+// the cache of the resolver. The function returns false when no record with
+// a TTL was resolved, e.g. for dnslink names.
+func ContextWithTTL(ctx context.Context) (context.Context, func() (time.Duration, bool)) {
+	c := new(ttlCollector)
+	return context.WithValue(ctx, ttlCollectorKey{}, c), func() (time.Duration, bool) {
+		c.lk.Lock()
+		defer c.lk.Unlock()
+		return c.ttl, c.set
+	}
+}
+
+// reportTTL records the TTL of a record resolved with ctx
+func reportTTL(ctx context.Context, ttl time.Duration) {
+	c, ok := ctx.Value(ttlCollectorKey{}).(*ttlCollector)
+	if !ok {
+		return
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	if !c.set || ttl < c.ttl {
+		c.ttl = ttl
+		c.set = true
+	}
+}
+
+// boundTTL applies the bounds min and max to ttl, zero meaning no bound
+func boundTTL(ttl, min, max time.Duration) time.Duration {
+	if min > 0 && ttl < min {
+		ttl = min
+	}
+	if max > 0 && ttl > max {
+		ttl = max
+	}
+	return ttl
+}
+
+// SetCacheBounds implements CacheBounder
+func (ns *mpns) SetCacheBounds(min, max time.Duration) {
+	rr, ok := ns.resolvers["dht"].(*routingResolver)
+	if !ok {
+		// should never happen, purely for sanity
+		log.Panicf("unexpected type %T as DHT resolver.", ns.resolvers["dht"])
+	}
+	rr.MinTTL = min
+	rr.MaxTTL = max
+}
@@ -0,0 +1,70 @@
+package namesys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	path "github.com/ipfs/go-ipfs/path"
+	mockrouting "github.com/ipfs/go-ipfs/routing/mock"
+	testutil "github.com/ipfs/go-ipfs/thirdparty/testutil"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dssync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
+	peer "gx/ipfs/QmdS9KpbDyPrieswibZhkod1oXqRwZJrUPzxCofAMWpFGq/go-libp2p-peer"
+)
+
+func TestBoundTTL(t *testing.T) {
+	for _, c := range []struct{ ttl, min, max, out time.Duration }{
+		{time.Minute, 0, 0, time.Minute},
+		{time.Second, time.Minute, 0, time.Minute},
+		{time.Hour, 0, time.Minute, time.Minute},
+		{time.Minute, time.Second, time.Hour, time.Minute},
+	} {
+		if out := boundTTL(c.ttl, c.min, c.max); out != c.out {
+			t.Errorf("boundTTL(%s, %s, %s): expected %s, got %s", c.ttl, c.min, c.max, c.out, out)
+		}
+	}
+}
+
+func TestContextWithTTL(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	d := mockrouting.NewServer().ClientWithDatastore(ctx, testutil.RandIdentityOrFatal(t), dstore)
+
+	resolver := NewRoutingResolver(d, 8)
+	resolver.MaxTTL = 10 * time.Second
+	publisher := NewRoutingPublisher(d, dstore)
+
+	sk, pk, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := peer.IDFromPublicKey(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := path.FromString("/ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy")
+	if err := publisher.PublishWithEOL(ctx, sk, h, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// once resolving, once from the cache
+	for i := 0; i < 2; i++ {
+		rctx, ttl := ContextWithTTL(ctx)
+		if _, ok := ttl(); ok {
+			t.Fatal("expected no TTL before resolving")
+		}
+		if _, err := resolver.Resolve(rctx, name.Pretty()); err != nil {
+			t.Fatal(err)
+		}
+		d, ok := ttl()
+		if !ok {
+			t.Fatal("expected a TTL to be reported")
+		}
+		if d <= 0 || d > resolver.MaxTTL {
+			t.Fatalf("expected a TTL within (0, %s], got %s", resolver.MaxTTL, d)
+		}
+	}
+}
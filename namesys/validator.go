@@ -0,0 +1,242 @@
+package namesys
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
+
+	metrics "gx/ipfs/QmRg1gKTHzc3CZXSKzem8aR4E3TubFhbgXwfVuWnSK5CC5/go-metrics-interface"
+	lru "gx/ipfs/QmVYxfoJQiZijTgPNHCHgHELvQpbsJNTg6Crmc3dQkj3yy/golang-lru"
+	record "gx/ipfs/QmWYCqr6UDqqD1bfRybaAPtbAqcN3TSJpveaBXMwbQ3ePZ/go-libp2p-record"
+	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
+)
+
+const (
+	// DefaultMaxRecordSize is the size of the largest ipns record accepted,
+	// with room for a signature policy listing a few RSA keys
+	DefaultMaxRecordSize = 10 << 10
+
+	// DefaultMaxRecordLifetime bounds how far in the future the EOL of an
+	// ipns record may be
+	DefaultMaxRecordLifetime = 2 * 365 * 24 * time.Hour
+
+	// DefaultQuarantineSize is the number of rejected records a Validator
+	// keeps for inspection
+	DefaultQuarantineSize = 64
+
+	// seqCacheSize is the number of names a Validator remembers the last
+	// sequence number of
+	seqCacheSize = 1024
+)
+
+// ErrRecordTooLarge is returned for records larger than the validator's
+// MaxRecordSize
+var ErrRecordTooLarge = errors.New("record too large")
+
+// ErrEOLTooFar is returned for records whose EOL is further in the future
+// than the validator's MaxLifetime allows
+var ErrEOLTooFar = errors.New("record EOL too far in the future")
+
+// ErrMissingEOL is returned for records published without an EOL, which
+// could live forever
+var ErrMissingEOL = errors.New("record has no EOL")
+
+// ErrLifetimeTooLong is returned when publishing a record whose EOL is
+// further in the future than the validator's MaxLifetime allows, as the
+// nodes of the network would reject it
+var ErrLifetimeTooLong = errors.New("record lifetime exceeds the maximum other nodes accept")
+
+// ErrStaleSequence is returned for records older than one already resolved
+// for the same name
+var ErrStaleSequence = errors.New("record sequence number is older than the last one seen")
+
+// DefaultValidator is the validator of the routing resolvers and publishers
+// not given their own, e.g. with SetValidator.
+var DefaultValidator = NewValidator(clock.New())
+
+// ValidatorSetter is implemented by the name systems checking records with
+// a Validator
+type ValidatorSetter interface {
+	// SetValidator sets the validator learning the sequence numbers of the
+	// records resolved, and bounding the lifetime of those published. It
+	// should be the one the DHT checks records with.
+	SetValidator(v *Validator)
+}
+
+// RejectedRecord is a record a Validator rejected
+type RejectedRecord struct {
+	// Key is the routing key of the record, "/ipns/" and a multihash
+	Key    string
+	Reason string
+	Time   time.Time
+	Record []byte
+}
+
+// Validator validates ipns records before they are stored or used: on top of
+// the EOL of a record and of its delegation or signature policy, it bounds
+// its size and lifetime, and rejects records older than one it saw
+// resolved. Signatures can only be checked with the key of the name, when a
+// record is resolved. Rejected records are counted, and the last ones kept
+// for inspection.
+//
+// The limits can be changed before the validator is used. Check, if set,
+// runs after the built in checks, to add custom ones.
+type Validator struct {
+	MaxRecordSize int
+	MaxLifetime   time.Duration
+	Clock         clock.Clock
+
+	Check func(key string, e *pb.IpnsEntry) error
+
+	seqs *lru.Cache
+
+	mu         sync.Mutex
+	quarantine []RejectedRecord
+	next       int
+}
+
+// NewValidator returns a Validator with the default limits, checking EOLs
+// against clk.
+func NewValidator(clk clock.Clock) *Validator {
+	seqs, _ := lru.New(seqCacheSize)
+	return &Validator{
+		MaxRecordSize: DefaultMaxRecordSize,
+		MaxLifetime:   DefaultMaxRecordLifetime,
+		Clock:         clk,
+		seqs:          seqs,
+		quarantine:    make([]RejectedRecord, 0, DefaultQuarantineSize),
+	}
+}
+
+// ValidChecker returns v as a record validator for the DHT
+func (v *Validator) ValidChecker() *record.ValidChecker {
+	return &record.ValidChecker{
+		Func: v.Validate,
+		Sign: true,
+	}
+}
+
+// Validate checks the record val stored under key, and quarantines it if it
+// is invalid.
+func (v *Validator) Validate(key string, val []byte) error {
+	reason, err := v.validate(key, val)
+	if err != nil {
+		v.reject(key, val, reason, err)
+	}
+	return err
+}
+
+// validate checks val, and returns the reason it was rejected for, as used
+// in metric names, along with the error
+func (v *Validator) validate(key string, val []byte) (string, error) {
+	if v.MaxRecordSize > 0 && len(val) > v.MaxRecordSize {
+		return "too_large", ErrRecordTooLarge
+	}
+
+	entry := new(pb.IpnsEntry)
+	if err := proto.Unmarshal(val, entry); err != nil {
+		return "malformed", err
+	}
+
+	now := v.Clock.Now()
+	switch err := validateIpnsEntry(entry, now); err {
+	case nil:
+	case ErrExpiredRecord, ErrDelegationExpired, ErrPolicyExpired:
+		return "expired", err
+	default:
+		return "malformed", err
+	}
+
+	if v.MaxLifetime > 0 {
+		if eol, ok := checkEOL(entry); ok && eol.After(now.Add(v.MaxLifetime)) {
+			return "eol_too_far", ErrEOLTooFar
+		}
+	}
+
+	if seen, ok := v.seqs.Get(key); ok && entry.GetSequence() < seen.(uint64) {
+		return "stale_sequence", ErrStaleSequence
+	}
+
+	if v.Check != nil {
+		if err := v.Check(key, entry); err != nil {
+			return "invalid", err
+		}
+	}
+	return "", nil
+}
+
+// Observe records that the record of sequence number seq was resolved, its
+// signatures verified, for key. Older records of key are rejected from then
+// on.
+func (v *Validator) Observe(key string, seq uint64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if seen, ok := v.seqs.Get(key); ok && seen.(uint64) >= seq {
+		return
+	}
+	v.seqs.Add(key, seq)
+}
+
+// Rejected returns the last records v rejected, oldest first
+func (v *Validator) Rejected() []RejectedRecord {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	out := make([]RejectedRecord, 0, len(v.quarantine))
+	if len(v.quarantine) == cap(v.quarantine) {
+		out = append(out, v.quarantine[v.next:]...)
+		out = append(out, v.quarantine[:v.next]...)
+	} else {
+		out = append(out, v.quarantine...)
+	}
+	return out
+}
+
+func (v *Validator) reject(key string, val []byte, reason string, err error) {
+	log.Debugf("rejected ipns record for %q: %s", key, err)
+
+	rejectedOnce.Do(initRejectedMetrics)
+	rejectedRecords[reason].Inc()
+
+	r := RejectedRecord{
+		Key:    key,
+		Reason: err.Error(),
+		Time:   v.Clock.Now(),
+		Record: append([]byte(nil), val...),
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if cap(v.quarantine) == 0 {
+		return
+	}
+	if len(v.quarantine) < cap(v.quarantine) {
+		v.quarantine = append(v.quarantine, r)
+		return
+	}
+	v.quarantine[v.next] = r
+	v.next = (v.next + 1) % len(v.quarantine)
+}
+
+var rejectReasons = []string{"too_large", "malformed", "expired", "eol_too_far", "stale_sequence", "invalid"}
+
+// the rejection counters are shared by all validators, and created on
+// first use, as metrics created before the daemon injects its
+// implementation are discarded
+var (
+	rejectedOnce    sync.Once
+	rejectedRecords map[string]metrics.Counter
+)
+
+func initRejectedMetrics() {
+	rejectedRecords = make(map[string]metrics.Counter, len(rejectReasons))
+	for _, r := range rejectReasons {
+		rejectedRecords[r] = metrics.New(fmt.Sprintf("ipns.rejected_%s_total", r),
+			fmt.Sprintf("Number of ipns records rejected as %s", r)).Counter()
+	}
+}
@@ -0,0 +1,102 @@
+package namesys
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/ipfs/go-ipfs/namesys/pb"
+	path "github.com/ipfs/go-ipfs/path"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
+	testutil "github.com/ipfs/go-ipfs/thirdparty/testutil"
+
+	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
+)
+
+func TestValidator(t *testing.T) {
+	clk := clock.NewMock(time.Now())
+	v := NewValidator(clk)
+
+	sk, _, err := testutil.RandTestKeyPair(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := path.FromString("/ipfs/QmatmE9msSfkKxoffpHwNLNKgwZG8eT9Bud6YoPab52vpy")
+	record := func(seq uint64, eol time.Time) []byte {
+		e, err := CreateRoutingEntryData(sk, p, seq, eol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := proto.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	const key = "/ipns/name"
+	now := clk.Now()
+
+	if err := v.Validate(key, record(2, now.Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Validate(key, record(2, now.Add(-time.Hour))); err != ErrExpiredRecord {
+		t.Fatalf("expected %s, got %v", ErrExpiredRecord, err)
+	}
+	if err := v.Validate(key, record(2, now.Add(DefaultMaxRecordLifetime+time.Hour))); err != ErrEOLTooFar {
+		t.Fatalf("expected %s, got %v", ErrEOLTooFar, err)
+	}
+	if err := v.Validate(key, make([]byte, DefaultMaxRecordSize+1)); err != ErrRecordTooLarge {
+		t.Fatalf("expected %s, got %v", ErrRecordTooLarge, err)
+	}
+	if err := v.Validate(key, []byte("not a record")); err == nil {
+		t.Fatal("expected a malformed record to be rejected")
+	}
+
+	// once a sequence number was resolved, older records are stale
+	v.Observe(key, 2)
+	v.Observe(key, 1)
+	if err := v.Validate(key, record(1, now.Add(time.Hour))); err != ErrStaleSequence {
+		t.Fatalf("expected %s, got %v", ErrStaleSequence, err)
+	}
+	if err := v.Validate(key, record(2, now.Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Validate("/ipns/other", record(1, now.Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	errCustom := errors.New("custom check")
+	v.Check = func(k string, e *pb.IpnsEntry) error {
+		if e.GetSequence() > 5 {
+			return errCustom
+		}
+		return nil
+	}
+	if err := v.Validate(key, record(6, now.Add(time.Hour))); err != errCustom {
+		t.Fatalf("expected the custom check to run, got %v", err)
+	}
+
+	rejected := v.Rejected()
+	if len(rejected) != 6 {
+		t.Fatalf("expected 6 rejected records, got %d", len(rejected))
+	}
+	if rejected[0].Reason != ErrExpiredRecord.Error() || rejected[5].Reason != errCustom.Error() {
+		t.Fatalf("rejected records out of order: %v", rejected)
+	}
+}
+
+func TestValidatorQuarantineWraps(t *testing.T) {
+	v := NewValidator(clock.NewMock(time.Now()))
+	for i := 0; i < DefaultQuarantineSize+10; i++ {
+		v.Validate("/ipns/name", []byte{byte(i)})
+	}
+
+	rejected := v.Rejected()
+	if len(rejected) != DefaultQuarantineSize {
+		t.Fatalf("expected %d rejected records, got %d", DefaultQuarantineSize, len(rejected))
+	}
+	if rejected[0].Record[0] != 10 || rejected[len(rejected)-1].Record[0] != byte(DefaultQuarantineSize+9) {
+		t.Fatal("expected the oldest records to be dropped first")
+	}
+}
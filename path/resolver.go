@@ -73,6 +73,10 @@ func SplitAbsPath(fpath Path) (*cid.Cid, []string, error) {
 	return c, parts[1:], nil
 }
 
+// ResolveToLastNode walks the given path and returns the last node it
+// reaches, along with the rest of the path within that node. The rest is
+// empty when the path points to a node, and names a value inside the node
+// otherwise, e.g. a map key or list index of a dag-cbor node.
 func (r *Resolver) ResolveToLastNode(ctx context.Context, fpath Path) (node.Node, []string, error) {
 	c, p, err := SplitAbsPath(fpath)
 	if err != nil {
@@ -85,27 +89,47 @@ func (r *Resolver) ResolveToLastNode(ctx context.Context, fpath Path) (node.Node
 	}
 
 	for len(p) > 0 {
-		val, rest, err := nd.Resolve(p)
-		if err != nil {
+		lnk, rest, err := r.ResolveOnce(ctx, r.DAG, nd, p)
+		if err == dag.ErrLinkNotFound {
+			return nil, nil, ErrNoLink{Name: p[0], Node: nd.Cid()}
+		} else if err != nil {
+			// the path may point to a value inside the node rather
+			// than to a link
+			if val, _, verr := nd.Resolve(p); verr == nil {
+				if _, ok := val.(*node.Link); !ok {
+					return nd, p, nil
+				}
+			}
 			return nil, nil, err
 		}
 
-		switch val := val.(type) {
-		case *node.Link:
-			next, err := val.GetNode(ctx, r.DAG)
-			if err != nil {
-				return nil, nil, err
-			}
-			nd = next
-			p = rest
-		default:
-			return nd, p, nil
+		next, err := lnk.GetNode(ctx, r.DAG)
+		if err != nil {
+			return nil, nil, err
 		}
+		nd = next
+		p = rest
 	}
 
 	return nd, nil, nil
 }
 
+// ResolveToValue walks the given path like ResolveToLastNode, and returns
+// the value it points to: the last node itself, or the value inside it the
+// rest of the path names.
+func (r *Resolver) ResolveToValue(ctx context.Context, fpath Path) (interface{}, error) {
+	nd, rest, err := r.ResolveToLastNode(ctx, fpath)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) == 0 {
+		return nd, nil
+	}
+
+	val, _, err := nd.Resolve(rest)
+	return val, err
+}
+
 // ResolvePath fetches the node for given path. It returns the last item
 // returned by ResolvePathComponents.
 func (s *Resolver) ResolvePath(ctx context.Context, fpath Path) (node.Node, error) {
@@ -3,12 +3,14 @@ package path_test
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	merkledag "github.com/ipfs/go-ipfs/merkledag"
 	dagmock "github.com/ipfs/go-ipfs/merkledag/test"
 	path "github.com/ipfs/go-ipfs/path"
 
+	cbor "gx/ipfs/QmNrbCt8j9DT5W9Pmjy2SdudT9k8GpaDr4sRuFix3BXhgR/go-ipld-cbor"
 	util "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
 	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
 )
@@ -67,3 +69,67 @@ func TestRecurivePathResolution(t *testing.T) {
 			p.String(), key.String(), cKey.String()))
 	}
 }
+
+func TestCborPathResolution(t *testing.T) {
+	ctx := context.Background()
+	dagService := dagmock.Mock()
+
+	leaf := randNode()
+	if _, err := dagService.Add(leaf); err != nil {
+		t.Fatal(err)
+	}
+
+	obj := fmt.Sprintf(`{"foo": [{"/": "%s"}, "bar"], "baz": {"n": 1}}`, leaf.Cid())
+	nd, err := cbor.FromJson(strings.NewReader(obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dagService.Add(nd); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := path.NewBasicResolver(dagService)
+
+	// through a link in a list
+	p, err := path.FromSegments("/ipfs/", nd.Cid().String(), "foo", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, rest, err := resolver.ResolveToLastNode(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !last.Cid().Equals(leaf.Cid()) || len(rest) != 0 {
+		t.Fatalf("expected to resolve to %s, got %s and %v", leaf.Cid(), last.Cid(), rest)
+	}
+
+	// to a value inside the node
+	p, err = path.FromSegments("/ipfs/", nd.Cid().String(), "baz", "n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, rest, err = resolver.ResolveToLastNode(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !last.Cid().Equals(nd.Cid()) || strings.Join(rest, "/") != "baz/n" {
+		t.Fatalf("expected to stop at %s with baz/n, got %s and %v", nd.Cid(), last.Cid(), rest)
+	}
+
+	val, err := resolver.ResolveToValue(ctx, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(val) != "1" {
+		t.Fatalf("expected 1, got %v", val)
+	}
+
+	// to a missing value
+	p, err = path.FromSegments("/ipfs/", nd.Cid().String(), "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := resolver.ResolveToLastNode(ctx, p); err == nil {
+		t.Fatal("expected an error resolving a missing field")
+	}
+}
@@ -0,0 +1,53 @@
+package pin
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+)
+
+var allocationsKey = ds.NewKey("/local/pinallocs")
+
+// SetAllocations records which peers (or other cluster members) the given
+// pin is allocated to. An empty list removes the record.
+func SetAllocations(d ds.Datastore, c *cid.Cid, allocations []string) error {
+	k := allocationsKey.ChildString(c.String())
+	if len(allocations) == 0 {
+		err := d.Delete(k)
+		if err == ds.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	b, err := json.Marshal(allocations)
+	if err != nil {
+		return err
+	}
+	return d.Put(k, b)
+}
+
+// Allocations returns the allocations recorded for the given pin, if any.
+func Allocations(d ds.Datastore, c *cid.Cid) ([]string, error) {
+	v, err := d.Get(allocationsKey.ChildString(c.String()))
+	switch err {
+	case nil:
+	case ds.ErrNotFound:
+		return nil, nil
+	default:
+		return nil, err
+	}
+
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("allocations for %s were not bytes", c)
+	}
+
+	var allocations []string
+	if err := json.Unmarshal(b, &allocations); err != nil {
+		return nil, err
+	}
+	return allocations, nil
+}
@@ -0,0 +1,44 @@
+package pin
+
+import (
+	"testing"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+)
+
+func TestAllocations(t *testing.T) {
+	dstore := ds.NewMapDatastore()
+	_, c := randNode()
+
+	check := func(exp []string) {
+		allocs, err := Allocations(dstore, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(allocs) != len(exp) {
+			t.Fatalf("expected %v, got %v", exp, allocs)
+		}
+		for i := range exp {
+			if allocs[i] != exp[i] {
+				t.Fatalf("expected %v, got %v", exp, allocs)
+			}
+		}
+	}
+
+	check(nil)
+
+	if err := SetAllocations(dstore, c, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	check([]string{"a", "b"})
+
+	if err := SetAllocations(dstore, c, nil); err != nil {
+		t.Fatal(err)
+	}
+	check(nil)
+
+	// removing twice is fine
+	if err := SetAllocations(dstore, c, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -34,6 +34,12 @@ type Result struct {
 // deletes any block that is not found in the marked set.
 //
 func GC(ctx context.Context, bs bstore.GCBlockstore, ls dag.LinkService, pn pin.Pinner, bestEffortRoots []*cid.Cid) <-chan Result {
+	return GCWithLeases(ctx, bs, ls, pn, bestEffortRoots, nil)
+}
+
+// GCWithLeases is like GC, but also keeps the roots of the leases that haven't
+// expired, and their descendants present locally.
+func GCWithLeases(ctx context.Context, bs bstore.GCBlockstore, ls dag.LinkService, pn pin.Pinner, bestEffortRoots []*cid.Cid, leases *Leases) <-chan Result {
 	unlocker := bs.GCLock()
 	ls = ls.GetOfflineLinkService()
 
@@ -43,7 +49,9 @@ func GC(ctx context.Context, bs bstore.GCBlockstore, ls dag.LinkService, pn pin.
 		defer close(output)
 		defer unlocker.Unlock()
 
-		gcs, err := ColoredSet(ctx, pn, ls, bestEffortRoots, output)
+		// the leases are read under the gc lock, new ones wait for it
+		roots := append(bestEffortRoots[:len(bestEffortRoots):len(bestEffortRoots)], leases.Roots()...)
+		gcs, err := ColoredSet(ctx, pn, ls, roots, output)
 		if err != nil {
 			output <- Result{Error: err}
 			return
@@ -0,0 +1,225 @@
+package gc
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	blocks "github.com/ipfs/go-ipfs/blocks"
+	bstore "github.com/ipfs/go-ipfs/blocks/blockstore"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
+
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+)
+
+// ErrLeaseExpired is returned when renewing a lease that expired or was
+// released
+var ErrLeaseExpired = errors.New("gc lease expired")
+
+// Leases are temporary gc roots. Components that store blocks before they
+// are pinned or referenced from the files root, e.g. a transfer in progress
+// or a staged import, take a lease on the roots of these blocks so that a
+// concurrent garbage collection keeps them. The blocks under a leased root
+// are kept as far as they are present locally; the blocks written before
+// the root they are under, as the importer does, must be written through
+// the Blockstore of the lease to be kept. A lease ends when it is released
+// or when it expires, so that a crashed component doesn't keep its blocks
+// forever.
+type Leases struct {
+	gcl   bstore.GCLocker
+	clock clock.Clock
+
+	lk     sync.Mutex
+	next   uint64
+	leases map[uint64]*Lease
+}
+
+// NewLeases returns the leases of the blockstore locked by gcl
+func NewLeases(gcl bstore.GCLocker, clk clock.Clock) *Leases {
+	return &Leases{
+		gcl:    gcl,
+		clock:  clk,
+		leases: make(map[uint64]*Lease),
+	}
+}
+
+// Lease protects its roots from garbage collection until it is released or
+// expires
+type Lease struct {
+	leases *Leases
+	id     uint64
+	owner  string
+	roots  *cid.Set
+
+	// protected by leases.lk
+	expires time.Time
+}
+
+// LeaseInfo describes a lease
+type LeaseInfo struct {
+	ID      uint64
+	Owner   string
+	Roots   []*cid.Cid
+	Expires time.Time
+}
+
+// Acquire takes a lease on roots for ttl, on behalf of owner, a name used in
+// logs and listings. If a garbage collection is running, Acquire waits for
+// it to finish: the blocks already present are either kept or gone when it
+// returns.
+func (ls *Leases) Acquire(owner string, ttl time.Duration, roots ...*cid.Cid) *Lease {
+	// a garbage collection reads the leases under the gc lock
+	defer ls.gcl.PinLock().Unlock()
+
+	ls.lk.Lock()
+	defer ls.lk.Unlock()
+
+	ls.next++
+	l := &Lease{
+		leases:  ls,
+		id:      ls.next,
+		owner:   owner,
+		roots:   cid.NewSet(),
+		expires: ls.clock.Now().Add(ttl),
+	}
+	for _, c := range roots {
+		l.roots.Add(c)
+	}
+	ls.leases[l.id] = l
+	log.Debugf("%s took gc lease %d on %d roots for %s", owner, l.id, len(roots), ttl)
+	return l
+}
+
+// ID returns the identifier of the lease
+func (l *Lease) ID() uint64 {
+	return l.id
+}
+
+// Renew extends the lease to ttl from now. It fails if the lease expired or
+// was released, the roots may then have been collected.
+func (l *Lease) Renew(ttl time.Duration) error {
+	ls := l.leases
+	ls.lk.Lock()
+	defer ls.lk.Unlock()
+
+	now := ls.clock.Now()
+	if _, ok := ls.leases[l.id]; !ok || !now.Before(l.expires) {
+		delete(ls.leases, l.id)
+		return ErrLeaseExpired
+	}
+	l.expires = now.Add(ttl)
+	return nil
+}
+
+// Release ends the lease, its roots may be collected by the next garbage
+// collection
+func (l *Lease) Release() {
+	ls := l.leases
+	ls.lk.Lock()
+	defer ls.lk.Unlock()
+	delete(ls.leases, l.id)
+}
+
+// Roots returns the roots of the leases that haven't expired, and forgets
+// the expired ones. A nil Leases has no roots.
+func (ls *Leases) Roots() []*cid.Cid {
+	if ls == nil {
+		return nil
+	}
+
+	ls.lk.Lock()
+	defer ls.lk.Unlock()
+
+	now := ls.clock.Now()
+	var out []*cid.Cid
+	for id, l := range ls.leases {
+		if !now.Before(l.expires) {
+			log.Warningf("gc lease %d of %s expired", id, l.owner)
+			delete(ls.leases, id)
+			continue
+		}
+		out = append(out, l.roots.Keys()...)
+	}
+	return out
+}
+
+// List returns the leases that haven't expired, by identifier
+func (ls *Leases) List() []LeaseInfo {
+	ls.lk.Lock()
+	defer ls.lk.Unlock()
+
+	now := ls.clock.Now()
+	var out []LeaseInfo
+	for _, l := range ls.leases {
+		if !now.Before(l.expires) {
+			continue
+		}
+		out = append(out, LeaseInfo{
+			ID:      l.id,
+			Owner:   l.owner,
+			Roots:   l.roots.Keys(),
+			Expires: l.expires,
+		})
+	}
+	sort.Sort(byID(out))
+	return out
+}
+
+// Blockstore returns bs, adding the blocks put through it to the roots of
+// the lease. Writes hold the pin lock, so that no garbage collection runs
+// between the time a block is written and the time it is a root: callers
+// must not hold it already. Writes fail with ErrLeaseExpired once the lease
+// ended.
+func (l *Lease) Blockstore(bs bstore.Blockstore) bstore.Blockstore {
+	return &leasedBlockstore{Blockstore: bs, lease: l}
+}
+
+// add adds roots to the lease, if it hasn't ended. The blocks written more
+// than once under the lease are only kept once.
+func (l *Lease) add(roots []*cid.Cid) error {
+	ls := l.leases
+	ls.lk.Lock()
+	defer ls.lk.Unlock()
+
+	if _, ok := ls.leases[l.id]; !ok || !ls.clock.Now().Before(l.expires) {
+		return ErrLeaseExpired
+	}
+	for _, c := range roots {
+		l.roots.Add(c)
+	}
+	return nil
+}
+
+type leasedBlockstore struct {
+	bstore.Blockstore
+	lease *Lease
+}
+
+func (b *leasedBlockstore) Put(blk blocks.Block) error {
+	defer b.lease.leases.gcl.PinLock().Unlock()
+
+	if err := b.lease.add([]*cid.Cid{blk.Cid()}); err != nil {
+		return err
+	}
+	return b.Blockstore.Put(blk)
+}
+
+func (b *leasedBlockstore) PutMany(blks []blocks.Block) error {
+	defer b.lease.leases.gcl.PinLock().Unlock()
+
+	roots := make([]*cid.Cid, len(blks))
+	for i, blk := range blks {
+		roots[i] = blk.Cid()
+	}
+	if err := b.lease.add(roots); err != nil {
+		return err
+	}
+	return b.Blockstore.PutMany(blks)
+}
+
+type byID []LeaseInfo
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,180 @@
+package gc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-ipfs/blocks"
+	bstore "github.com/ipfs/go-ipfs/blocks/blockstore"
+	bserv "github.com/ipfs/go-ipfs/blockservice"
+	offline "github.com/ipfs/go-ipfs/exchange/offline"
+	dag "github.com/ipfs/go-ipfs/merkledag"
+	pin "github.com/ipfs/go-ipfs/pin"
+	clock "github.com/ipfs/go-ipfs/thirdparty/clock"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dssync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
+)
+
+func TestGCKeepsLeasedRoots(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	gcl := bstore.NewGCLocker()
+	bs := bstore.NewGCBlockstore(bstore.NewBlockstore(dstore), gcl)
+	dserv := dag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))
+	pinner := pin.NewPinner(dstore, dserv, dserv)
+
+	// a parent and its child, and an unrelated node
+	child := dag.NodeWithData([]byte("child"))
+	parent := dag.NodeWithData([]byte("parent"))
+	if err := parent.AddNodeLinkClean("child", child); err != nil {
+		t.Fatal(err)
+	}
+	other := dag.NodeWithData([]byte("other"))
+	for _, nd := range []*dag.ProtoNode{child, parent, other} {
+		if _, err := dserv.Add(nd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clk := clock.NewMock(time.Now())
+	leases := NewLeases(gcl, clk)
+	lease := leases.Acquire("test", time.Minute, parent.Cid())
+
+	gc := func() {
+		if err := collect(GCWithLeases(ctx, bs, dserv, pinner, nil, leases)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	has := func(nd *dag.ProtoNode) bool {
+		ok, err := bs.Has(nd.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	gc()
+	if !has(parent) || !has(child) {
+		t.Fatal("expected the leased root and its child to be kept")
+	}
+	if has(other) {
+		t.Fatal("expected the unleased node to be collected")
+	}
+	if l := leases.List(); len(l) != 1 || l[0].ID != lease.ID() || l[0].Owner != "test" {
+		t.Fatalf("unexpected leases %v", l)
+	}
+
+	// a renewed lease outlives its first ttl
+	clk.Add(50 * time.Second)
+	if err := lease.Renew(time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	clk.Add(50 * time.Second)
+	gc()
+	if !has(parent) {
+		t.Fatal("expected the renewed lease to keep its root")
+	}
+
+	// an expired lease can't be renewed, and its roots are collected
+	clk.Add(time.Minute)
+	gc()
+	if has(parent) || has(child) {
+		t.Fatal("expected the roots of the expired lease to be collected")
+	}
+	if err := lease.Renew(time.Minute); err != ErrLeaseExpired {
+		t.Fatalf("expected ErrLeaseExpired, got %v", err)
+	}
+	if l := leases.List(); len(l) != 0 {
+		t.Fatalf("expected no leases, got %v", l)
+	}
+
+	// a released lease stops protecting its roots
+	if _, err := dserv.Add(other); err != nil {
+		t.Fatal(err)
+	}
+	leases.Acquire("test", time.Minute, other.Cid()).Release()
+	gc()
+	if has(other) {
+		t.Fatal("expected the roots of the released lease to be collected")
+	}
+}
+
+func TestLeaseBlockstore(t *testing.T) {
+	ctx := context.Background()
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	gcl := bstore.NewGCLocker()
+	bs := bstore.NewGCBlockstore(bstore.NewBlockstore(dstore), gcl)
+	dserv := dag.NewDAGService(bserv.New(bs, offline.Exchange(bs)))
+	pinner := pin.NewPinner(dstore, dserv, dserv)
+
+	clk := clock.NewMock(time.Now())
+	leases := NewLeases(gcl, clk)
+	lease := leases.Acquire("import", time.Minute)
+	lbs := lease.Blockstore(bs)
+
+	// the leaves of an import are written before their root exists
+	leaf := dag.NodeWithData([]byte("leaf"))
+	if err := lbs.Put(leaf); err != nil {
+		t.Fatal(err)
+	}
+	// blocks written again are only kept once
+	if err := lbs.PutMany([]blocks.Block{leaf, leaf}); err != nil {
+		t.Fatal(err)
+	}
+	if err := collect(GCWithLeases(ctx, bs, dserv, pinner, nil, leases)); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := bs.Has(leaf.Cid()); err != nil || !ok {
+		t.Fatal("expected the block written under the lease to be kept")
+	}
+	if l := leases.List(); len(l) != 1 || len(l[0].Roots) != 1 || !l[0].Roots[0].Equals(leaf.Cid()) {
+		t.Fatalf("expected the block to be a root of the lease, got %v", l)
+	}
+
+	// nothing is written once the lease expired
+	clk.Add(time.Minute)
+	other := dag.NodeWithData([]byte("other"))
+	if err := lbs.Put(other); err != ErrLeaseExpired {
+		t.Fatalf("expected ErrLeaseExpired, got %v", err)
+	}
+	if ok, err := bs.Has(other.Cid()); err != nil || ok {
+		t.Fatal("expected nothing to be written under an expired lease")
+	}
+}
+
+func TestLeaseWaitsForGC(t *testing.T) {
+	gcl := bstore.NewGCLocker()
+	leases := NewLeases(gcl, clock.New())
+
+	unlock := gcl.GCLock()
+	acquired := make(chan struct{})
+	go func() {
+		leases.Acquire("test", time.Minute)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("expected Acquire to wait for the gc")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock.Unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("expected Acquire to return after the gc")
+	}
+}
+
+func collect(out <-chan Result) error {
+	var err error
+	for r := range out {
+		if r.Error != nil && err == nil {
+			err = r.Error
+		}
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package pin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+)
+
+// IntentOp is the kind of change described by an Intent
+type IntentOp string
+
+const (
+	IntentPin   IntentOp = "pin"
+	IntentUnpin IntentOp = "unpin"
+)
+
+// Intent describes a pin change that is about to be applied. Intents are
+// passed to the registered hooks, so that external components (e.g. a
+// cluster orchestrator) can veto or mirror them. They are also the arguments
+// of the pin operations passed to the hooks of the node (see core/hooks).
+type Intent struct {
+	Op        IntentOp
+	Cid       *cid.Cid
+	Recursive bool
+
+	// From is the pin replaced by Cid, for updates
+	From *cid.Cid `json:",omitempty"`
+
+	Allocations []string `json:",omitempty"`
+}
+
+// Hook is notified of pin intents before they are applied.
+type Hook interface {
+	// PinIntent is called before the intent is applied. Returning an
+	// error vetoes it.
+	PinIntent(context.Context, *Intent) error
+}
+
+// RunHooks passes the intent to each hook in turn, and returns the first
+// veto, if any.
+func RunHooks(ctx context.Context, hooks []Hook, in *Intent) error {
+	for _, h := range hooks {
+		if err := h.PinIntent(ctx, in); err != nil {
+			return fmt.Errorf("%s of %s vetoed: %s", in.Op, in.Cid, err)
+		}
+	}
+	return nil
+}
+
+// HTTPHook posts intents as JSON to an HTTP endpoint. Any 2xx response
+// accepts the intent, anything else vetoes it, using the response body as
+// the reason.
+type HTTPHook struct {
+	URL string
+
+	// Client is the client used to post intents, http.DefaultClient if nil
+	Client *http.Client
+}
+
+func (h *HTTPHook) PinIntent(ctx context.Context, in *Intent) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(resp.Body)
+	if reason := strings.TrimSpace(string(msg)); reason != "" {
+		return fmt.Errorf("%s: %s", resp.Status, reason)
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
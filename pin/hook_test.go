@@ -0,0 +1,41 @@
+package pin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPHook(t *testing.T) {
+	_, c := randNode()
+
+	var got Intent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		if got.Op == IntentUnpin {
+			http.Error(w, "still allocated", http.StatusConflict)
+		}
+	}))
+	defer srv.Close()
+
+	hooks := []Hook{&HTTPHook{URL: srv.URL}}
+	ctx := context.Background()
+
+	in := &Intent{Op: IntentPin, Cid: c, Recursive: true, Allocations: []string{"peer1"}}
+	if err := RunHooks(ctx, hooks, in); err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.Cid.Equals(c) || !got.Recursive || len(got.Allocations) != 1 {
+		t.Fatalf("hook got wrong intent: %#v", got)
+	}
+
+	in = &Intent{Op: IntentUnpin, Cid: c}
+	if err := RunHooks(ctx, hooks, in); err == nil {
+		t.Fatal("expected unpin to be vetoed")
+	}
+}
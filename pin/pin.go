@@ -20,7 +20,8 @@ import (
 
 var log = logging.Logger("pin")
 
-var pinDatastoreKey = ds.NewKey("/local/pins")
+// DatastoreKey is the datastore key holding the root of the pin set
+var DatastoreKey = ds.NewKey("/local/pins")
 
 var emptyKey *cid.Cid
 
@@ -92,6 +93,10 @@ type Pinner interface {
 	// old one
 	Update(ctx context.Context, from, to *cid.Cid, unpin bool) error
 
+	// Txn starts a new transaction that pins and unpins a set of keys
+	// atomically, see Txn for details
+	Txn() *Txn
+
 	// Check if a set of keys are pinned, more efficient than
 	// calling IsPinned for each key
 	CheckIfPinned(cids ...*cid.Cid) ([]Pinned, error)
@@ -210,6 +215,11 @@ var ErrNotPinned = fmt.Errorf("not pinned")
 func (p *pinner) Unpin(ctx context.Context, c *cid.Cid, recursive bool) error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
+	return p.unpin(c, recursive)
+}
+
+// unpin is the implementation of Unpin that does not lock.
+func (p *pinner) unpin(c *cid.Cid, recursive bool) error {
 	reason, pinned, err := p.isPinnedWithType(c, Any)
 	if err != nil {
 		return err
@@ -387,13 +397,13 @@ func cidSetWithValues(cids []*cid.Cid) *cid.Set {
 func LoadPinner(d ds.Datastore, dserv, internal mdag.DAGService) (Pinner, error) {
 	p := new(pinner)
 
-	rootKeyI, err := d.Get(pinDatastoreKey)
+	rootKeyI, err := d.Get(DatastoreKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot load pin state: %v", err)
 	}
 	rootKeyBytes, ok := rootKeyI.([]byte)
 	if !ok {
-		return nil, fmt.Errorf("cannot load pin state: %s was not bytes", pinDatastoreKey)
+		return nil, fmt.Errorf("cannot load pin state: %s was not bytes", DatastoreKey)
 	}
 
 	rootCid, err := cid.Cast(rootKeyBytes)
@@ -468,7 +478,7 @@ func (p *pinner) Update(ctx context.Context, from, to *cid.Cid, unpin bool) erro
 	}
 
 	p.recursePin.Add(to)
-	if unpin {
+	if unpin && !from.Equals(to) {
 		p.recursePin.Remove(from)
 	}
 	return nil
@@ -478,9 +488,11 @@ func (p *pinner) Update(ctx context.Context, from, to *cid.Cid, unpin bool) erro
 func (p *pinner) Flush() error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
+	return p.flush(context.TODO())
+}
 
-	ctx := context.TODO()
-
+// flush is the implementation of Flush that does not lock.
+func (p *pinner) flush(ctx context.Context) error {
 	internalset := cid.NewSet()
 	recordInternal := internalset.Add
 
@@ -517,7 +529,7 @@ func (p *pinner) Flush() error {
 	}
 
 	internalset.Add(k)
-	if err := p.dstore.Put(pinDatastoreKey, k.Bytes()); err != nil {
+	if err := p.dstore.Put(DatastoreKey, k.Bytes()); err != nil {
 		return fmt.Errorf("cannot store pin state: %v", err)
 	}
 	p.internalPin = internalset
@@ -400,3 +400,56 @@ func TestPinUpdate(t *testing.T) {
 	assertPinned(t, p, c2, "c2 should be pinned still")
 	assertPinned(t, p, c1, "c1 should be pinned now")
 }
+
+func TestPinTxn(t *testing.T) {
+	dstore := dssync.MutexWrap(ds.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(dstore)
+	bserv := bs.New(bstore, offline.Exchange(bstore))
+
+	dserv := mdag.NewDAGService(bserv)
+	p := NewPinner(dstore, dserv, dserv)
+	n1, c1 := randNode()
+	n2, c2 := randNode()
+	_, c3 := randNode()
+
+	dserv.Add(n1)
+	dserv.Add(n2)
+
+	ctx := context.Background()
+	if err := p.Pin(ctx, n1, true); err != nil {
+		t.Fatal(err)
+	}
+
+	txn := p.Txn()
+	txn.Pin(c2, true)
+	txn.Unpin(c1, true)
+	if err := txn.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	assertPinned(t, p, c2, "c2 should be pinned now")
+	assertUnpinned(t, p, c1, "c1 should no longer be pinned")
+
+	if err := txn.Commit(ctx); err == nil {
+		t.Fatal("expected second commit to fail")
+	}
+
+	// unpinning c3 fails, so pinning c1 must be rolled back
+	txn = p.Txn()
+	txn.Pin(c1, true)
+	txn.Unpin(c2, true)
+	txn.Unpin(c3, true)
+	if err := txn.Commit(ctx); err != ErrNotPinned {
+		t.Fatalf("expected ErrNotPinned, got %v", err)
+	}
+
+	assertPinned(t, p, c2, "c2 should still be pinned")
+	assertUnpinned(t, p, c1, "c1 should not be pinned")
+
+	np, err := LoadPinner(dstore, dserv, dserv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPinned(t, np, c2, "c2 should be pinned after reload")
+	assertUnpinned(t, np, c1, "c1 should not be pinned after reload")
+}
@@ -0,0 +1,117 @@
+package pin
+
+import (
+	"context"
+	"fmt"
+
+	mdag "github.com/ipfs/go-ipfs/merkledag"
+
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+)
+
+// Txn batches a set of pin and unpin operations so that they are applied
+// to the pinner all at once, or not at all. This allows, for example,
+// replacing one version of a website with another without a window where
+// neither root is pinned.
+//
+// Operations are applied in the order they were added. Nothing is changed
+// until Commit is called, and a Txn must not be reused after that.
+type Txn struct {
+	p    *pinner
+	ops  []txnOp
+	done bool
+}
+
+type txnOp struct {
+	c         *cid.Cid
+	recursive bool
+	unpin     bool
+}
+
+// Txn starts a new pin transaction
+func (p *pinner) Txn() *Txn {
+	return &Txn{p: p}
+}
+
+// Pin queues pinning the given key, optionally recursively
+func (t *Txn) Pin(c *cid.Cid, recursive bool) {
+	t.ops = append(t.ops, txnOp{c: c, recursive: recursive})
+}
+
+// Unpin queues removing the pin on the given key
+func (t *Txn) Unpin(c *cid.Cid, recursive bool) {
+	t.ops = append(t.ops, txnOp{c: c, recursive: recursive, unpin: true})
+}
+
+// Commit fetches everything needed by the queued pins, then applies all
+// queued operations and flushes the new pin state to the datastore. If any
+// operation or the flush fails, the pinner is rolled back to the state it
+// had before Commit was called.
+func (t *Txn) Commit(ctx context.Context) error {
+	if t.done {
+		return fmt.Errorf("pin transaction already committed")
+	}
+	t.done = true
+
+	p := t.p
+
+	// Fetch the graphs before taking the lock, this may take a while and
+	// does not touch the pin state.
+	for _, op := range t.ops {
+		if op.unpin {
+			continue
+		}
+		if op.recursive {
+			if err := mdag.FetchGraph(ctx, op.c, p.dserv); err != nil {
+				return err
+			}
+		} else {
+			if _, err := p.dserv.Get(ctx, op.c); err != nil {
+				return err
+			}
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	recurse := cidSetWithValues(p.recursePin.Keys())
+	direct := cidSetWithValues(p.directPin.Keys())
+	rollback := func() {
+		p.recursePin = recurse
+		p.directPin = direct
+	}
+
+	for _, op := range t.ops {
+		if err := p.applyTxnOp(op); err != nil {
+			rollback()
+			return err
+		}
+	}
+
+	if err := p.flush(ctx); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+// applyTxnOp applies a single operation of a transaction. The caller must
+// hold the lock.
+func (p *pinner) applyTxnOp(op txnOp) error {
+	if op.unpin {
+		return p.unpin(op.c, op.recursive)
+	}
+
+	if op.recursive {
+		p.directPin.Remove(op.c)
+		p.recursePin.Add(op.c)
+		return nil
+	}
+
+	if p.recursePin.Has(op.c) {
+		return fmt.Errorf("%s already pinned recursively", op.c.String())
+	}
+	p.directPin.Add(op.c)
+	return nil
+}
@@ -0,0 +1,24 @@
+package plugin
+
+import (
+	"crypto/tls"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+)
+
+// CertManager obtains certificates on demand, and answers the tls-alpn-01
+// challenges of the certificate authority
+type CertManager interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+// PluginACME obtains the certificates of the hosts of the TLS gateway from
+// an ACME certificate authority, when Gateway.TLS.ACME is enabled
+type PluginACME interface {
+	Plugin
+
+	// ACMEManager returns the manager of the certificates of hosts, which
+	// keeps the account key and the certificates in cacheDir. It must
+	// refuse to obtain certificates for other hosts.
+	ACMEManager(c config.GatewayACME, hosts []string, cacheDir string) (CertManager, error)
+}
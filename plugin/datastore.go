@@ -0,0 +1,16 @@
+package plugin
+
+import (
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+)
+
+// PluginDatastore adds a datastore type, which can then be used as the
+// Datastore.Type of the config
+type PluginDatastore interface {
+	Plugin
+
+	// DatastoreTypeName returns the name of the datastore type
+	DatastoreTypeName() string
+	// DatastoreOpener returns the function opening datastores of the type
+	DatastoreOpener() fsrepo.DatastoreOpener
+}
@@ -0,0 +1,13 @@
+package plugin
+
+import (
+	dag "github.com/ipfs/go-ipfs/merkledag"
+)
+
+// PluginIPLD adds support for IPLD formats
+type PluginIPLD interface {
+	Plugin
+
+	// BlockDecoders returns the decoders of the formats, by codec
+	BlockDecoders() map[uint64]dag.DecodeBlockFunc
+}
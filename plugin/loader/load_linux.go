@@ -0,0 +1,45 @@
+// +build cgo,linux,go1.8,!noplugin
+
+package loader
+
+import (
+	"fmt"
+	goplugin "plugin"
+
+	plugin "github.com/ipfs/go-ipfs/plugin"
+)
+
+func init() {
+	loadPluginFunc = linuxLoadFunc
+}
+
+// linuxLoadFunc opens a shared object exporting an APIVersion int, which
+// must match plugin.APIVersion, and a Plugins []plugin.Plugin.
+func linuxLoadFunc(path string) ([]plugin.Plugin, error) {
+	pl, err := goplugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vsym, err := pl.Lookup("APIVersion")
+	if err != nil {
+		return nil, err
+	}
+	version, ok := vsym.(*int)
+	if !ok {
+		return nil, fmt.Errorf("APIVersion has type %T, expected *int", vsym)
+	}
+	if *version != plugin.APIVersion {
+		return nil, fmt.Errorf("plugin was built against plugin API version %d, expected %d", *version, plugin.APIVersion)
+	}
+
+	psym, err := pl.Lookup("Plugins")
+	if err != nil {
+		return nil, err
+	}
+	plugins, ok := psym.(*[]plugin.Plugin)
+	if !ok {
+		return nil, fmt.Errorf("Plugins has type %T, expected *[]plugin.Plugin", psym)
+	}
+	return *plugins, nil
+}
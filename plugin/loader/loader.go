@@ -0,0 +1,177 @@
+// Package loader loads go-ipfs plugins, compiled in or from the plugins
+// directory of the repo, and registers them with the parts of ipfs they
+// extend.
+package loader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	dag "github.com/ipfs/go-ipfs/merkledag"
+	plugin "github.com/ipfs/go-ipfs/plugin"
+	config "github.com/ipfs/go-ipfs/repo/config"
+	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
+
+	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+)
+
+var log = logging.Logger("plugin/loader")
+
+// loadPluginFunc loads the plugins of a shared object. It is nil when this
+// build of ipfs cannot load shared objects.
+var loadPluginFunc func(path string) ([]plugin.Plugin, error)
+
+var (
+	lk     sync.Mutex
+	loaded []plugin.Plugin
+	done   bool
+)
+
+// LoadPlugins initializes and registers the compiled in plugins, and the
+// plugins found in pluginDir. It only does so once; later calls return the
+// plugins loaded by the first one.
+func LoadPlugins(pluginDir string) ([]plugin.Plugin, error) {
+	lk.Lock()
+	defer lk.Unlock()
+
+	if done {
+		return loaded, nil
+	}
+
+	plugins := append([]plugin.Plugin{}, preloadPlugins...)
+
+	external, err := loadDynamicPlugins(pluginDir)
+	if err != nil {
+		return nil, err
+	}
+	plugins = append(plugins, external...)
+
+	seen := make(map[string]bool)
+	for _, pl := range plugins {
+		if seen[pl.Name()] {
+			return nil, fmt.Errorf("plugin %q is loaded twice", pl.Name())
+		}
+		seen[pl.Name()] = true
+	}
+
+	for _, pl := range plugins {
+		if err := pl.Init(); err != nil {
+			return nil, fmt.Errorf("failed to initialize plugin %q: %s", pl.Name(), err)
+		}
+		if err := register(pl); err != nil {
+			return nil, fmt.Errorf("failed to register plugin %q: %s", pl.Name(), err)
+		}
+		log.Debugf("loaded plugin %s %s", pl.Name(), pl.Version())
+	}
+
+	loaded = plugins
+	done = true
+	return loaded, nil
+}
+
+func loadDynamicPlugins(pluginDir string) ([]plugin.Plugin, error) {
+	files, err := filepath.Glob(filepath.Join(pluginDir, "*.so"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	if loadPluginFunc == nil {
+		return nil, fmt.Errorf("found plugins in %s, but this build of ipfs cannot load plugins", pluginDir)
+	}
+
+	var plugins []plugin.Plugin
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		if fi.IsDir() {
+			continue
+		}
+
+		pls, err := loadPluginFunc(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load plugin %s: %s", f, err)
+		}
+		plugins = append(plugins, pls...)
+	}
+	return plugins, nil
+}
+
+func register(pl plugin.Plugin) error {
+	if p, ok := pl.(plugin.PluginIPLD); ok {
+		for codec, dec := range p.BlockDecoders() {
+			if err := dag.RegisterDecoder(codec, dec); err != nil {
+				return err
+			}
+		}
+	}
+	if p, ok := pl.(plugin.PluginDatastore); ok {
+		if err := fsrepo.RegisterDatastore(p.DatastoreTypeName(), p.DatastoreOpener()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Loaded returns the plugins loaded by LoadPlugins
+func Loaded() []plugin.Plugin {
+	lk.Lock()
+	defer lk.Unlock()
+	return loaded
+}
+
+// InjectMetrics lets the first loaded metrics plugin install its metrics
+// implementation. It returns false if no metrics plugin is loaded.
+func InjectMetrics() (bool, error) {
+	for _, pl := range Loaded() {
+		if p, ok := pl.(plugin.PluginMetrics); ok {
+			return true, p.InjectMetrics()
+		}
+	}
+	return false, nil
+}
+
+// ACMELoaded returns whether an ACME plugin is loaded
+func ACMELoaded() bool {
+	for _, pl := range Loaded() {
+		if _, ok := pl.(plugin.PluginACME); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ACMEManager returns the certificate manager of the first loaded ACME
+// plugin for hosts. It returns false if no ACME plugin is loaded.
+func ACMEManager(c config.GatewayACME, hosts []string, cacheDir string) (plugin.CertManager, bool, error) {
+	for _, pl := range Loaded() {
+		if p, ok := pl.(plugin.PluginACME); ok {
+			m, err := p.ACMEManager(c, hosts, cacheDir)
+			return m, true, err
+		}
+	}
+	return nil, false, nil
+}
+
+// Types returns the kinds of extension a plugin provides
+func Types(pl plugin.Plugin) []string {
+	var out []string
+	if _, ok := pl.(plugin.PluginIPLD); ok {
+		out = append(out, "ipld")
+	}
+	if _, ok := pl.(plugin.PluginDatastore); ok {
+		out = append(out, "datastore")
+	}
+	if _, ok := pl.(plugin.PluginMetrics); ok {
+		out = append(out, "metrics")
+	}
+	if _, ok := pl.(plugin.PluginACME); ok {
+		out = append(out, "acme")
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package loader
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	blocks "github.com/ipfs/go-ipfs/blocks"
+	dag "github.com/ipfs/go-ipfs/merkledag"
+	plugin "github.com/ipfs/go-ipfs/plugin"
+	config "github.com/ipfs/go-ipfs/repo/config"
+
+	node "gx/ipfs/Qmb3Hm9QDFmfYuET4pu7Kyg8JV78jFa1nvZx5vnCZsK4ck/go-ipld-format"
+)
+
+type testPlugin struct {
+	inited bool
+}
+
+func (p *testPlugin) Name() string    { return "test" }
+func (p *testPlugin) Version() string { return "0.1.0" }
+func (p *testPlugin) Init() error {
+	p.inited = true
+	return nil
+}
+
+func (p *testPlugin) BlockDecoders() map[uint64]dag.DecodeBlockFunc {
+	return map[uint64]dag.DecodeBlockFunc{
+		0x3f0002: func(b blocks.Block) (node.Node, error) {
+			return nil, nil
+		},
+	}
+}
+
+func (p *testPlugin) InjectMetrics() error { return nil }
+
+func (p *testPlugin) ACMEManager(c config.GatewayACME, hosts []string, cacheDir string) (plugin.CertManager, error) {
+	if len(hosts) == 0 {
+		return nil, errors.New("no hosts")
+	}
+	return nil, nil
+}
+
+func TestLoadPlugins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tp := &testPlugin{}
+	preloadPlugins = []plugin.Plugin{tp}
+
+	plugins, err := LoadPlugins(filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugins) != 1 || !tp.inited {
+		t.Fatal("compiled in plugin was not loaded")
+	}
+	if len(Loaded()) != 1 {
+		t.Fatal("loaded plugins are not remembered")
+	}
+
+	types := Types(tp)
+	if len(types) != 3 || types[0] != "ipld" || types[1] != "metrics" || types[2] != "acme" {
+		t.Fatalf("unexpected plugin types: %v", types)
+	}
+
+	ok, err := InjectMetrics()
+	if !ok || err != nil {
+		t.Fatal("metrics plugin was not used")
+	}
+
+	_, ok, err = ACMEManager(config.GatewayACME{Enabled: true}, []string{"example.com"}, dir)
+	if !ok || err != nil || !ACMELoaded() {
+		t.Fatal("ACME plugin was not used")
+	}
+
+	// the decoders are registered, so registering them again fails
+	if err := register(tp); err == nil {
+		t.Fatal("expected an error registering a plugin twice")
+	}
+}
+
+func TestLoadUnsupported(t *testing.T) {
+	if loadPluginFunc != nil {
+		t.Skip("this build can load plugins")
+	}
+
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "foo.so"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadDynamicPlugins(dir); err == nil {
+		t.Fatal("expected an error when plugins cannot be loaded")
+	}
+}
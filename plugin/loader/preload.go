@@ -0,0 +1,9 @@
+package loader
+
+import (
+	plugin "github.com/ipfs/go-ipfs/plugin"
+)
+
+// preloadPlugins are the plugins compiled into ipfs. To compile a plugin
+// in, add it to this list.
+var preloadPlugins = []plugin.Plugin{}
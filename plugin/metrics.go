@@ -0,0 +1,11 @@
+package plugin
+
+// PluginMetrics exports metrics, in place of the built in prometheus
+// exporter of the daemon
+type PluginMetrics interface {
+	Plugin
+
+	// InjectMetrics installs the plugin's go-metrics-interface
+	// implementation. It is called before any metric is created.
+	InjectMetrics() error
+}
@@ -0,0 +1,22 @@
+// Package plugin defines the interfaces implemented by go-ipfs plugins.
+//
+// A plugin implements Plugin, and one or more of the typed interfaces
+// (PluginIPLD, PluginDatastore, PluginMetrics) to extend ipfs. Plugins are
+// either compiled in, or built as Go shared objects and placed in the
+// plugins directory of the repo, see the loader package.
+package plugin
+
+// APIVersion is the version of the plugin interfaces. Shared object plugins
+// export the version they were built against, and are only loaded if it
+// matches.
+const APIVersion = 1
+
+// Plugin is the interface implemented by all plugins
+type Plugin interface {
+	// Name returns the name of the plugin, which must be unique
+	Name() string
+	// Version returns the version of the plugin
+	Version() string
+	// Init is called once, when the plugin is loaded
+	Init() error
+}
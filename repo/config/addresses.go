@@ -2,7 +2,8 @@ package config
 
 // Addresses stores the (string) multiaddr addresses for the node.
 type Addresses struct {
-	Swarm   []string // addresses for the swarm network
-	API     string   // address for the local API (RPC)
-	Gateway string   // address to listen on for IPFS HTTP object gateway
+	Swarm    []string // addresses for the swarm network
+	Announce []string // swarm addresses to announce instead of the listening ones
+	API      string   // address for the local API (RPC)
+	Gateway  string   // address to listen on for IPFS HTTP object gateway
 }
@@ -2,4 +2,16 @@ package config
 
 type API struct {
 	HTTPHeaders map[string][]string // HTTP headers to return with the API.
+	WebUI       WebUI
+}
+
+// WebUI configures the WebUI served by the API server
+type WebUI struct {
+	// Path is the /ipfs path, or CID, of the WebUI version to serve. The
+	// version bundled with this release is served when it is empty.
+	Path string
+
+	// Pin makes the daemon fetch and pin the WebUI on start, so that it is
+	// served from the local blockstore and survives garbage collection.
+	Pin bool
 }
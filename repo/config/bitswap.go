@@ -0,0 +1,18 @@
+package config
+
+// Bitswap limits how the node serves blocks to other peers and how blocks
+// are transferred.
+type Bitswap struct {
+	// ServeMode selects the peers served: "all" (the default), "allowlist"
+	// for the peers of ServeAllowlist only, or "known" for those and the
+	// peers that sent this node blocks since they connected.
+	ServeMode      string   `json:",omitempty"`
+	ServeAllowlist []string `json:",omitempty"` // peer IDs
+
+	MaxPeerUploadRate  string `json:",omitempty"` // bytes per second sent to each peer, e.g. "1MB"
+	MaxConcurrentSends int    `json:",omitempty"` // blocks sent at the same time
+
+	// Compression compresses the messages exchanged with the peers that
+	// enable it too.
+	Compression bool `json:",omitempty"`
+}
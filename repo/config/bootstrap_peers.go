@@ -43,6 +43,24 @@ func (c *Config) BootstrapPeers() ([]BootstrapPeer, error) {
 	return ParseBootstrapPeers(c.Bootstrap)
 }
 
+// BootstrapProfilePeers returns the peers of the named bootstrap profile.
+func (c *Config) BootstrapProfilePeers(name string) ([]BootstrapPeer, error) {
+	addrs, ok := c.BootstrapProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("no bootstrap profile named %q", name)
+	}
+	return ParseBootstrapPeers(addrs)
+}
+
+// BootstrapFallbackPeers returns the peers of the BootstrapFallback profile,
+// if one is set.
+func (c *Config) BootstrapFallbackPeers() ([]BootstrapPeer, error) {
+	if c.BootstrapFallback == "" {
+		return nil, nil
+	}
+	return c.BootstrapProfilePeers(c.BootstrapFallback)
+}
+
 // DefaultBootstrapPeers returns the (parsed) set of default bootstrap peers.
 // if it fails, it returns a meaningful error for the user.
 // This is here (and not inside cmd/ipfs/init) because of module dependency problems.
@@ -0,0 +1,391 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	ma "gx/ipfs/QmcyqRMCAXVtYPS4DiBrA7sezL9rRGfW8Ctx7cywL4TXJj/go-multiaddr"
+)
+
+// Problem is an issue found in a config file by Check.
+type Problem struct {
+	// Key is the location of the problem, e.g. "Addresses.Swarm[1]", or
+	// "line 12, column 3" for syntax errors
+	Key     string
+	Message string
+
+	// Warning is set for the problems the node runs with anyway, such as
+	// unknown fields, which are ignored
+	Warning bool `json:",omitempty"`
+}
+
+func (p Problem) String() string {
+	level := "error"
+	if p.Warning {
+		level = "warning"
+	}
+	if p.Key == "" {
+		return fmt.Sprintf("%s: %s", level, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", level, p.Key, p.Message)
+}
+
+// Errors returns the problems that aren't warnings
+func Errors(ps []Problem) []Problem {
+	var out []Problem
+	for _, p := range ps {
+		if !p.Warning {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// ProblemsAt returns the problems of ps concerning key: at key, under it,
+// or at one of its parents, e.g. an unknown field containing it
+func ProblemsAt(ps []Problem, key string) []Problem {
+	var out []Problem
+	for _, p := range ps {
+		if p.Key == key || isUnder(p.Key, key) || isUnder(key, p.Key) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// isUnder returns whether key is a field or an element of parent
+func isUnder(key, parent string) bool {
+	return strings.HasPrefix(key, parent+".") || strings.HasPrefix(key, parent+"[")
+}
+
+// ProblemsError returns an error listing ps, nil if ps is empty
+func ProblemsError(ps []Problem) error {
+	if len(ps) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(ps))
+	for _, p := range ps {
+		lines = append(lines, p.String())
+	}
+	return fmt.Errorf("invalid config:\n%s", strings.Join(lines, "\n"))
+}
+
+// valueChecks validate the string values of the keys given, with "[]"
+// standing for any element of an array. Empty values are not checked, they
+// mean the default.
+var valueChecks = map[string]func(string) error{
+	"Addresses.API":                       checkMultiaddr,
+	"Addresses.Gateway":                   checkMultiaddr,
+	"Addresses.Swarm[]":                   checkMultiaddr,
+	"Addresses.Announce[]":                checkMultiaddr,
+	"Bootstrap[]":                         checkBootstrapPeer,
+	"Datastore.GCPeriod":                  checkDuration,
+	"Gateway.Timeouts.Resolve":            checkDuration,
+	"Gateway.Timeouts.FirstBlock":         checkDuration,
+	"Gateway.Timeouts.Request":            checkDuration,
+	"Hooks[].Timeout":                     checkDuration,
+	"Ipns.RepublishPeriod":                checkDuration,
+	"Ipns.RecordLifetime":                 checkDuration,
+	"Ipns.MaxRecordLifetime":              checkDuration,
+	"Ipns.ResolveCacheTTLMin":             checkDuration,
+	"Ipns.ResolveCacheTTLMax":             checkDuration,
+	"Power.ReproviderInterval":            checkDuration,
+	"Power.RepublishPeriod":               checkDuration,
+	"Power.WakeupInterval":                checkDuration,
+	"Probes.Interval":                     checkDuration,
+	"Probes.Timeout":                      checkDuration,
+	"Reprovider.Interval":                 checkDuration,
+	"Schedule.Rules[].ReproviderInterval": checkDuration,
+	"Swarm.BandwidthHistory.Window":       checkDuration,
+	"Swarm.BandwidthHistory.Interval":     checkDuration,
+	"Swarm.PeerCache.MaxAge":              checkDuration,
+}
+
+func checkDuration(s string) error {
+	_, err := time.ParseDuration(s)
+	return err
+}
+
+func checkMultiaddr(s string) error {
+	_, err := ma.NewMultiaddr(s)
+	return err
+}
+
+func checkBootstrapPeer(s string) error {
+	_, err := ParseBootstrapPeer(s)
+	return err
+}
+
+// Check validates m, a config file decoded as JSON, against the schema of
+// Config. Type mismatches and invalid values are errors; unknown fields are
+// warnings, as they are ignored and may be set on purpose by the user.
+func Check(m map[string]interface{}) []Problem {
+	c := new(checker)
+	c.value("", "", reflect.TypeOf(Config{}), m)
+	return c.problems
+}
+
+// CheckBytes checks the config file data, reporting syntax errors with
+// their line and column.
+func CheckBytes(data []byte) []Problem {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return []Problem{{Key: jsonErrorLocation(data, err), Message: err.Error()}}
+	}
+	return Check(m)
+}
+
+// DecodeError adds the line and column where decoding data failed to err,
+// when it is a JSON syntax or type error.
+func DecodeError(data []byte, err error) error {
+	if loc := jsonErrorLocation(data, err); loc != "" {
+		return fmt.Errorf("%s: %s", loc, err)
+	}
+	return err
+}
+
+func jsonErrorLocation(data []byte, err error) string {
+	var off int64
+	switch err := err.(type) {
+	case *json.SyntaxError:
+		off = err.Offset
+	case *json.UnmarshalTypeError:
+		off = err.Offset
+	default:
+		return ""
+	}
+	// the offset is past the byte the decoder failed on
+	if off > int64(len(data)) {
+		off = int64(len(data))
+	}
+	if off > 0 {
+		off--
+	}
+	before := data[:off]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(off) - bytes.LastIndexByte(before, '\n')
+	return fmt.Sprintf("line %d, column %d", line, col)
+}
+
+type checker struct {
+	problems []Problem
+}
+
+func (c *checker) errorf(key, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) warnf(key, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Key: key, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// value checks v against t. key is where v is, pattern the same without the
+// array indexes and map keys, as in valueChecks.
+func (c *checker) value(key, pattern string, t reflect.Type, v interface{}) {
+	// null is accepted, and leaves the default value
+	if v == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == rawMessageType {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			c.errorf(key, "expected an object, got %s", jsonKind(v))
+			return
+		}
+		c.fields(key, pattern, t, m)
+	case reflect.Map:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			c.errorf(key, "expected an object, got %s", jsonKind(v))
+			return
+		}
+		for _, k := range sortedKeys(m) {
+			c.value(join(key, k), pattern+".*", t.Elem(), m[k])
+		}
+	case reflect.Slice, reflect.Array:
+		a, ok := v.([]interface{})
+		if !ok {
+			c.errorf(key, "expected an array, got %s", jsonKind(v))
+			return
+		}
+		for i, e := range a {
+			c.value(fmt.Sprintf("%s[%d]", key, i), pattern+"[]", t.Elem(), e)
+		}
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			c.errorf(key, "expected a string, got %s", jsonKind(v))
+			return
+		}
+		if check, ok := valueChecks[pattern]; ok && s != "" {
+			if err := check(s); err != nil {
+				c.errorf(key, "invalid value %q: %s", s, err)
+			}
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			c.errorf(key, "expected a boolean, got %s", jsonKind(v))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := v.(float64)
+		if !ok || f != float64(int64(f)) {
+			c.errorf(key, "expected an integer, got %s", jsonKind(v))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, ok := v.(float64)
+		if !ok || f < 0 || f != float64(uint64(f)) {
+			c.errorf(key, "expected a positive integer, got %s", jsonKind(v))
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := v.(float64); !ok {
+			c.errorf(key, "expected a number, got %s", jsonKind(v))
+		}
+	}
+}
+
+// fields checks the fields m of the struct type t
+func (c *checker) fields(key, pattern string, t reflect.Type, m map[string]interface{}) {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			tname := strings.Split(tag, ",")[0]
+			if tname == "-" {
+				continue
+			}
+			if tname != "" {
+				name = tname
+			}
+		}
+		fields[name] = f.Type
+	}
+
+	for _, k := range sortedKeys(m) {
+		if ft, ok := fields[k]; ok {
+			c.value(join(key, k), join(pattern, k), ft, m[k])
+			continue
+		}
+
+		// encoding/json matches the names of fields case insensitively
+		if name, ok := foldedField(fields, k); ok {
+			c.warnf(join(key, k), "should be spelled %s", name)
+			c.value(join(key, k), join(pattern, name), fields[name], m[k])
+			continue
+		}
+
+		if name := closestField(fields, k); name != "" {
+			c.warnf(join(key, k), "unknown field, did you mean %s?", join(key, name))
+		} else {
+			c.warnf(join(key, k), "unknown field")
+		}
+	}
+}
+
+func foldedField(fields map[string]reflect.Type, k string) (string, bool) {
+	for name := range fields {
+		if strings.EqualFold(name, k) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// closestField returns the name of the field closest to k, if it is close
+// enough to be a typo
+func closestField(fields map[string]reflect.Type, k string) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		d := editDistance(strings.ToLower(name), strings.ToLower(k))
+		if d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	// don't suggest fields sharing little with short keys
+	if best == "" || bestDist*2 >= len(k) {
+		return ""
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func jsonKind(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("the string %q", v)
+	case bool:
+		return fmt.Sprintf("the boolean %t", v)
+	case float64:
+		return fmt.Sprintf("the number %v", v)
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func join(key, k string) string {
+	if key == "" {
+		return k
+	}
+	return key + "." + k
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckBytes(t *testing.T) {
+	data := []byte(`{
+  "Addresses": {
+    "Swarm": ["/ip4/0.0.0.0/tcp/4001", "/ip4/0.0.0.0/tcp/port"],
+    "API": "/ip4/127.0.0.1/tcp/5001"
+  },
+  "Datastore": {
+    "StorageMax": 10,
+    "GCPeriod": "1hour",
+    "Params": {"anything": true}
+  },
+  "Ipns": {"RepublishPerod": "12h"},
+  "gateway": {"Writable": "yes"},
+  "Experimental": {"Features": {"pubsub": true}},
+  "Hooks": [{"Ops": ["pin/add"], "Timeout": "10"}],
+  "Foo": {"Bar": "baz"}
+}`)
+
+	expected := []string{
+		"error: Addresses.Swarm[1]: invalid value",
+		"error: Datastore.GCPeriod: invalid value \"1hour\"",
+		"error: Datastore.StorageMax: expected a string, got the number 10",
+		"warning: Foo: unknown field",
+		"error: Hooks[0].Timeout: invalid value \"10\"",
+		"warning: Ipns.RepublishPerod: unknown field, did you mean Ipns.RepublishPeriod?",
+		"warning: gateway: should be spelled Gateway",
+		"error: gateway.Writable: expected a boolean, got the string \"yes\"",
+	}
+
+	problems := CheckBytes(data)
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, p := range problems {
+		if !strings.HasPrefix(p.String(), expected[i]) {
+			t.Errorf("expected %q, got %q", expected[i], p)
+		}
+	}
+
+	if errs := Errors(problems); len(errs) != 5 {
+		t.Errorf("expected 5 errors, got %d", len(errs))
+	}
+	if ps := ProblemsAt(problems, "Foo.Bar"); len(ps) != 1 || ps[0].Key != "Foo" {
+		t.Errorf("expected the problem of Foo for Foo.Bar, got %v", ps)
+	}
+	if ps := ProblemsAt(problems, "Addresses"); len(ps) != 1 {
+		t.Errorf("expected the problem of Addresses.Swarm[1] for Addresses, got %v", ps)
+	}
+}
+
+func TestCheckBytesSyntax(t *testing.T) {
+	problems := CheckBytes([]byte("{\n  \"Bootstrap\": [],\n  \"API\": {,\n}"))
+	if len(problems) != 1 {
+		t.Fatalf("expected 1 problem, got %v", problems)
+	}
+	if problems[0].Key != "line 3, column 11" {
+		t.Fatalf("expected the problem on line 3, column 11, got %q", problems[0].Key)
+	}
+}
+
+func TestCheckDefault(t *testing.T) {
+	cfg := &Config{Addresses: Addresses{
+		Swarm: []string{"/ip4/0.0.0.0/tcp/4001"},
+		API:   "/ip4/127.0.0.1/tcp/5001",
+	}}
+	cfg.Bootstrap = DefaultBootstrapAddresses
+
+	m, err := ToMap(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := Check(m); len(problems) != 0 {
+		t.Fatalf("expected no problem, got %v", problems)
+	}
+}
@@ -17,20 +17,30 @@ var log = logging.Logger("config")
 
 // Config is used to load ipfs config files.
 type Config struct {
-	Identity         Identity              // local node's peer identity
-	Datastore        Datastore             // local node's storage
-	Addresses        Addresses             // local node's addresses
-	Mounts           Mounts                // local node's mount points
-	Discovery        Discovery             // local node's discovery mechanisms
-	Ipns             Ipns                  // Ipns settings
-	Bootstrap        []string              // local nodes's bootstrap peer addresses
-	Tour             Tour                  // local node's tour position
-	Gateway          Gateway               // local node's gateway server options
-	SupernodeRouting SupernodeClientConfig // local node's routing servers (if SupernodeRouting enabled)
-	API              API                   // local node's API settings
-	Swarm            SwarmConfig
+	Identity          Identity              // local node's peer identity
+	Datastore         Datastore             // local node's storage
+	Addresses         Addresses             // local node's addresses
+	Mounts            Mounts                // local node's mount points
+	Discovery         Discovery             // local node's discovery mechanisms
+	Ipns              Ipns                  // Ipns settings
+	Bootstrap         []string              // local nodes's bootstrap peer addresses
+	BootstrapProfiles map[string][]string   `json:",omitempty"` // named alternative bootstrap lists
+	BootstrapFallback string                `json:",omitempty"` // profile used when no bootstrap peer is reachable
+	Tour              Tour                  // local node's tour position
+	Gateway           Gateway               // local node's gateway server options
+	SupernodeRouting  SupernodeClientConfig // local node's routing servers (if SupernodeRouting enabled)
+	API               API                   // local node's API settings
+	Swarm             SwarmConfig
 
 	Reprovider   Reprovider
+	Bitswap      Bitswap
+	Power        Power
+	Probes       Probes
+	Schedule     Schedule
+	Unixfs       Unixfs
+	Pinning      Pinning
+	Hooks        []Hook `json:",omitempty"`
+	DNSLink      DNSLink
 	Experimental Experiments
 }
 
@@ -19,6 +19,25 @@ type Datastore struct {
 	NoSync          bool
 	HashOnRead      bool
 	BloomFilterSize int
+
+	// DenyList is the path of a file listing blocks never to store or serve
+	DenyList string `json:",omitempty"`
+
+	// Encryption encrypts the datastore and the keystore on disk
+	Encryption Encryption
+}
+
+// Encryption configures the encryption of the repo at rest. It can only be
+// enabled when the repo is initialized. The private key of the node, in
+// Identity.PrivKey, is not encrypted.
+type Encryption struct {
+	Enabled bool
+
+	// PassphraseCommand is run to get the passphrase unlocking the repo key,
+	// e.g. from a key management service, when $IPFS_REPO_PASSPHRASE is not
+	// set. The first line of its output is the passphrase. It can't be set
+	// through the API.
+	PassphraseCommand []string `json:",omitempty"`
 }
 
 func (d *Datastore) ParamData() []byte {
@@ -0,0 +1,48 @@
+package config
+
+// DNSLink configures the update of the dnslink TXT record of a domain after
+// 'ipfs name publish'.
+type DNSLink struct {
+	// Domain whose _dnslink TXT record is updated. Empty disables updates.
+	Domain string
+
+	// Key is the name of the key whose publications are followed, "self"
+	// by default.
+	Key string
+
+	// Provider hosting the domain, "cloudflare" or "rfc2136".
+	Provider string
+
+	// TTL of the record in seconds, 60 by default.
+	TTL int
+
+	// DryRun only reports the updates that would be made.
+	DryRun bool
+
+	Cloudflare DNSLinkCloudflare
+	RFC2136    DNSLinkRFC2136
+}
+
+// DNSLinkSecretSelectors are the config keys of the DNSLink credentials,
+// which 'ipfs config' does not show, like the private key.
+var DNSLinkSecretSelectors = []string{
+	"DNSLink.Cloudflare.APIToken",
+	"DNSLink.RFC2136.Secret",
+}
+
+// DNSLinkCloudflare holds the credentials of a zone hosted by Cloudflare.
+type DNSLinkCloudflare struct {
+	APIToken string
+	ZoneID   string
+}
+
+// DNSLinkRFC2136 describes a zone updated with DNS dynamic updates.
+type DNSLinkRFC2136 struct {
+	Server string // host[:port] of the primary server
+	Zone   string
+
+	// TSIG key, unsigned updates are sent if KeyName is empty.
+	KeyName      string
+	KeyAlgorithm string // hmac-sha256 by default
+	Secret       string // base64
+}
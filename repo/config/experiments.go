@@ -3,4 +3,7 @@ package config
 type Experiments struct {
 	FilestoreEnabled bool
 	ShardingEnabled  bool
+
+	// Features enables or disables features by name, see 'ipfs features ls'
+	Features map[string]bool `json:",omitempty"`
 }
@@ -6,4 +6,111 @@ type Gateway struct {
 	RootRedirect string
 	Writable     bool
 	PathPrefixes []string
+
+	// TLS serves websites on the domains of the operator over HTTPS.
+	TLS GatewayTLS
+
+	// Timeouts bound the time spent on a request.
+	Timeouts GatewayTimeouts
+
+	// AccessLog records every request served by the gateways.
+	AccessLog GatewayAccessLog
+
+	// Content configures how files and directories are served.
+	Content GatewayContent
+
+	// ContentHosts replaces Content for the requests of the given
+	// hostnames.
+	ContentHosts map[string]GatewayContent
+}
+
+// GatewayContent configures how the gateways serve files and directories.
+type GatewayContent struct {
+	// IndexFiles are the names of the files served for a directory, in
+	// order of preference. Empty means index.html.
+	IndexFiles []string
+
+	// ContentTypes maps file extensions, e.g. ".wasm", to the Content-Type
+	// the files are served with, overriding the detection.
+	ContentTypes map[string]string
+
+	// NoSniff disables the detection of the Content-Type: the files whose
+	// extension isn't in ContentTypes are served as
+	// application/octet-stream.
+	NoSniff bool
+}
+
+// GatewayTimeouts are the budgets of a gateway request, as durations such as
+// "30s". Empty means no limit.
+type GatewayTimeouts struct {
+	// Resolve bounds the resolution of an IPNS or dnslink name.
+	Resolve string
+
+	// FirstBlock bounds the fetching of the blocks down to the object
+	// requested, i.e. before the response starts.
+	FirstBlock string
+
+	// Request bounds the whole request, including the transfer of the
+	// response. Empty means one hour.
+	Request string
+}
+
+// GatewayAccessLog configures the access log of the gateways.
+type GatewayAccessLog struct {
+	// Path of the log file, relative to the repo. Empty disables the log.
+	Path string
+
+	// MaxSize is the size above which the file is rotated, e.g. "100MB".
+	// Empty disables rotation.
+	MaxSize string
+
+	// MaxFiles is the number of rotated files kept.
+	MaxFiles int
+}
+
+// GatewayTLS configures the TLS gateway server.
+type GatewayTLS struct {
+	// Address is the multiaddr the server listens on, e.g.
+	// /ip4/0.0.0.0/tcp/443. Empty disables the server.
+	Address string
+
+	// Hosts maps the domains served to their settings. Requests for other
+	// domains are refused.
+	Hosts map[string]GatewayHost
+
+	// ACME obtains the certificates of the hosts without CertFile from an
+	// ACME certificate authority. It needs an ACME plugin.
+	ACME GatewayACME
+}
+
+// GatewayACME configures the automatic certificates of the TLS gateway.
+type GatewayACME struct {
+	// Enabled requests certificates for the hosts without CertFile.
+	// Enabling it accepts the terms of service of the certificate
+	// authority.
+	Enabled bool
+
+	// Email is the contact address given to the certificate authority.
+	Email string `json:",omitempty"`
+
+	// DirectoryURL is the ACME directory of the certificate authority,
+	// Let's Encrypt when empty.
+	DirectoryURL string `json:",omitempty"`
+
+	// CacheDir is where the account key and the certificates are kept,
+	// relative to the repo unless absolute. Empty means "acme".
+	CacheDir string `json:",omitempty"`
+}
+
+// GatewayHost configures a domain served by the TLS gateway.
+type GatewayHost struct {
+	// CertFile and KeyFile are the PEM encoded certificate chain and key
+	// of the domain. They are reloaded when they change on disk. When
+	// they are empty, the certificate is obtained with ACME.
+	CertFile string
+	KeyFile  string
+
+	// Path is served at the root of the domain. Empty serves the dnslink
+	// record of the domain.
+	Path string `json:",omitempty"`
 }
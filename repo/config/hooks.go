@@ -0,0 +1,24 @@
+package config
+
+// Hook configures an external program or HTTP endpoint that is run around
+// some operations of the node, e.g. to enforce a policy in a managed
+// deployment.
+type Hook struct {
+	// Ops are the operations the hook is run for, "pin/add", "name/publish"
+	// or "key/gen". "*" matches all of them.
+	Ops []string
+
+	// URL is POSTed the event as JSON.
+	URL string `json:",omitempty"`
+
+	// Exec is the program, and its arguments, that is run with the event as
+	// JSON on its standard input. It can't be set through the API.
+	Exec []string `json:",omitempty"`
+
+	// Post runs the hook after the operation instead of before. Hooks run
+	// after the operation can't veto it.
+	Post bool `json:",omitempty"`
+
+	// Timeout bounds how long the hook may run, "10s" by default.
+	Timeout string `json:",omitempty"`
+}
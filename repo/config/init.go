@@ -36,8 +36,9 @@ func Init(out io.Writer, nBitsForKeypair int) (*Config, error) {
 				// "/ip4/0.0.0.0/udp/4002/utp", // disabled for now.
 				"/ip6/::/tcp/4001",
 			},
-			API:     "/ip4/127.0.0.1/tcp/5001",
-			Gateway: "/ip4/127.0.0.1/tcp/8080",
+			Announce: []string{},
+			API:      "/ip4/127.0.0.1/tcp/5001",
+			Gateway:  "/ip4/127.0.0.1/tcp/8080",
 		},
 
 		Datastore: datastore,
@@ -71,6 +72,9 @@ func Init(out io.Writer, nBitsForKeypair int) (*Config, error) {
 		Reprovider: Reprovider{
 			Interval: "12h",
 		},
+		Unixfs: Unixfs{
+			ShardingThreshold: "256KiB",
+		},
 	}
 
 	return conf, nil
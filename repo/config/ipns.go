@@ -4,5 +4,25 @@ type Ipns struct {
 	RepublishPeriod string
 	RecordLifetime  string
 
+	// MaxRecordLifetime bounds how far in the future the EOL of the ipns
+	// records the node accepts, and publishes, may be, e.g. "17520h"
+	MaxRecordLifetime string `json:",omitempty"`
+
 	ResolveCacheSize int
+
+	// ResolveCacheTTLMin and ResolveCacheTTLMax bound the time resolved
+	// records are cached for, whatever their TTL, e.g. "30s" and "1h".
+	// The gateway lets clients cache /ipns/ paths for as long.
+	ResolveCacheTTLMin string `json:",omitempty"`
+	ResolveCacheTTLMax string `json:",omitempty"`
+
+	// RepublishKeys are the names of keystore keys whose last published
+	// record is republished along with the node's own, such as the old
+	// identities of the node
+	RepublishKeys []string `json:",omitempty"`
+
+	// MaxSignedNames bounds the number of names whose records, signed
+	// elsewhere, are given with 'ipfs name put' and rebroadcast. 0 means
+	// the default, a negative value no bound.
+	MaxSignedNames int `json:",omitempty"`
 }
@@ -0,0 +1,14 @@
+package config
+
+// Pinning tracks the configuration of the pinner.
+type Pinning struct {
+	// Hooks is a list of URLs that pin and unpin intents are POSTed to
+	// before they are applied. Any non-2xx response vetoes the change. The
+	// hooks of Config.Hooks run after the pin operations see the intents
+	// once applied.
+	Hooks []string
+
+	// HooksTimeout is how long each of Hooks may take to answer, as a
+	// duration. Empty means 10s.
+	HooksTimeout string `json:",omitempty"`
+}
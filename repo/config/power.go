@@ -0,0 +1,12 @@
+package config
+
+// Power configures the low power mode, for nodes running on battery or on
+// metered networks. The settings other than Mode apply in low power mode.
+type Power struct {
+	Mode string `json:",omitempty"` // mode the node starts in, "normal" or "low"
+
+	MaxConnections     int    `json:",omitempty"` // connections kept, 32 if unset
+	ReproviderInterval string `json:",omitempty"` // "48h" if unset
+	RepublishPeriod    string `json:",omitempty"` // "12h" if unset
+	WakeupInterval     string `json:",omitempty"` // time between background work, "5m" if unset
+}
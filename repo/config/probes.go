@@ -0,0 +1,10 @@
+package config
+
+// Probes configures the self-probes, which periodically measure how long
+// the content and the IPNS names of the node take to be found through the
+// network. They are off by default.
+type Probes struct {
+	Enabled  bool
+	Interval string `json:",omitempty"` // time between two rounds of probes, "1h" if unset
+	Timeout  string `json:",omitempty"` // time a probe is given, "5m" if unset
+}
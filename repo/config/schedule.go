@@ -0,0 +1,22 @@
+package config
+
+// Schedule changes the bandwidth and connection limits of the node by time
+// of day. The first rule matching the local time applies; outside of all
+// rules the limits of the rest of the config apply.
+type Schedule struct {
+	Rules []ScheduleRule `json:",omitempty"`
+}
+
+// ScheduleRule is a time window and the limits applying during it. Unset
+// limits keep the value of the rest of the config.
+type ScheduleRule struct {
+	Name string `json:",omitempty"`
+
+	Days  []string `json:",omitempty"` // "mon" to "sun", every day if empty
+	Start string   // "HH:MM", local time
+	End   string   // "HH:MM", before Start for windows spanning midnight
+
+	MaxPeerUploadRate  string `json:",omitempty"` // e.g. "512KB", "0" for no limit
+	MaxConnections     int    `json:",omitempty"`
+	ReproviderInterval string `json:",omitempty"`
+}
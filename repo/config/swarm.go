@@ -4,4 +4,33 @@ type SwarmConfig struct {
 	AddrFilters             []string
 	DisableBandwidthMetrics bool
 	DisableNatPortMap       bool
+	DisableAutoNAT          bool // don't check whether the node is reachable
+	DisableAutoNATService   bool // don't dial back peers checking their reachability
+
+	AddrPolicy       AddrPolicy
+	BandwidthHistory BandwidthHistory
+	PeerCache        PeerCache
+}
+
+// AddrPolicy restricts the addresses the swarm dials and announces. Each
+// list holds multiaddr netmasks, in the same format as AddrFilters.
+type AddrPolicy struct {
+	DialAllow     []string // if set, only addresses in these are dialed
+	DialDeny      []string // addresses never dialed
+	AnnounceAllow []string // if set, only addresses in these are announced
+	AnnounceDeny  []string // addresses never announced
+}
+
+// BandwidthHistory configures how much bandwidth history is retained.
+type BandwidthHistory struct {
+	Window   string // how long the history covers, "1h" if unset
+	Interval string // time between two samples, "1m" if unset
+}
+
+// PeerCache configures the peers saved in the datastore, dialed first when
+// the node restarts.
+type PeerCache struct {
+	Disabled bool   // don't save nor load peers
+	MaxAge   string // peers not seen for longer are forgotten, "72h" if unset
+	MaxPeers int    // peers saved, the most recently seen ones, 1000 if unset
 }
@@ -0,0 +1,18 @@
+package config
+
+// Unixfs tracks the configuration of the unixfs objects the node builds.
+type Unixfs struct {
+	// ShardingThreshold is the estimated directory size above which
+	// directories are automatically sharded. Empty uses the default, "0"
+	// disables automatic sharding.
+	ShardingThreshold string // in B, kB, kiB, MB, ...
+
+	// HashFunction is the hash function new objects are hashed with when
+	// none is given. Empty uses sha2-256.
+	HashFunction string `json:",omitempty"`
+
+	// PrefetchWindow is the number of blocks fetched ahead of the one being
+	// read when reading files. Zero uses the default, a negative value
+	// disables prefetching.
+	PrefetchWindow int `json:",omitempty"`
+}
@@ -0,0 +1,160 @@
+// Package crypt encrypts the data of a repo at rest. The values of the
+// datastore and the keys of the keystore are encrypted with AES-256-GCM
+// under a random repo key, itself stored in the repo encrypted with a key
+// derived from a passphrase.
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// KeyFile is the name of the file holding the repo key, in the repo
+const KeyFile = "repokey"
+
+// keySize is the size of the AES-256 keys
+const keySize = 32
+
+// kdfIterations is the number of PBKDF2 iterations deriving the key that
+// encrypts the repo key from the passphrase
+const kdfIterations = 200000
+
+var (
+	// ErrBadPassphrase is returned when the passphrase doesn't unlock the
+	// repo key
+	ErrBadPassphrase = errors.New("wrong passphrase for the repo key")
+
+	// ErrCorrupted is returned when encrypted data can't be decrypted
+	ErrCorrupted = errors.New("encrypted data is corrupted or was encrypted with another key")
+)
+
+// Key encrypts and decrypts data
+type Key struct {
+	aead cipher.AEAD
+}
+
+// NewKey returns a Key encrypting with the 32 bytes of k
+func NewKey(k []byte) (*Key, error) {
+	if len(k) != keySize {
+		return nil, fmt.Errorf("invalid key size %d, expected %d", len(k), keySize)
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{aead: aead}, nil
+}
+
+// Seal encrypts and authenticates data, and authenticates ad, the additional
+// data the encrypted data is bound to, e.g. the name it is stored under.
+func (k *Key) Seal(data, ad []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(data)+k.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return k.aead.Seal(nonce, nonce, data, ad), nil
+}
+
+// Open decrypts data sealed with the same additional data ad
+func (k *Key) Open(sealed, ad []byte) ([]byte, error) {
+	ns := k.aead.NonceSize()
+	if len(sealed) < ns+k.aead.Overhead() {
+		return nil, ErrCorrupted
+	}
+	out, err := k.aead.Open(nil, sealed[:ns], sealed[ns:], ad)
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	return out, nil
+}
+
+// keyFile is the content of the KeyFile
+type keyFile struct {
+	KDF        string
+	Iterations int
+	Salt       []byte
+	Key        []byte // the repo key, sealed
+}
+
+// CreateKeyFile generates a repo key, and saves it at path encrypted with
+// passphrase. It fails if the file exists.
+func CreateKeyFile(path string, passphrase []byte) (*Key, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("the passphrase of the repo key is empty")
+	}
+
+	kf := keyFile{
+		KDF:        "pbkdf2-sha256",
+		Iterations: kdfIterations,
+		Salt:       make([]byte, 16),
+	}
+	rk := make([]byte, keySize)
+	for _, b := range [][]byte{kf.Salt, rk} {
+		if _, err := io.ReadFull(rand.Reader, b); err != nil {
+			return nil, err
+		}
+	}
+
+	kek, err := NewKey(pbkdf2(passphrase, kf.Salt, kf.Iterations, keySize, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	kf.Key, err = kek.Seal(rk, []byte(KeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(kf, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return NewKey(rk)
+}
+
+// OpenKeyFile returns the repo key saved at path, decrypted with passphrase
+func OpenKeyFile(path string, passphrase []byte) (*Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("invalid repo key file: %s", err)
+	}
+	if kf.KDF != "pbkdf2-sha256" || kf.Iterations <= 0 {
+		return nil, fmt.Errorf("unsupported repo key derivation %s (%d iterations)", kf.KDF, kf.Iterations)
+	}
+
+	kek, err := NewKey(pbkdf2(passphrase, kf.Salt, kf.Iterations, keySize, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	rk, err := kek.Open(kf.Key, []byte(KeyFile))
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return NewKey(rk)
+}
@@ -0,0 +1,174 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dsq "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/query"
+)
+
+func testKey(t *testing.T) *Key {
+	k, err := NewKey(bytes.Repeat([]byte{1}, keySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestPBKDF2(t *testing.T) {
+	// test vectors of PBKDF2-HMAC-SHA256
+	for _, c := range []struct {
+		iter int
+		out  string
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+	} {
+		dk := pbkdf2([]byte("password"), []byte("salt"), c.iter, 32, sha256.New)
+		if hex.EncodeToString(dk) != c.out {
+			t.Fatalf("%d iterations: expected %s, got %x", c.iter, c.out, dk)
+		}
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	k := testKey(t)
+	data := []byte("some data")
+
+	sealed, err := k.Seal(data, []byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, data) {
+		t.Fatal("expected the data to be encrypted")
+	}
+
+	out, err := k.Open(sealed, []byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Fatalf("expected %q, got %q", data, out)
+	}
+
+	if _, err := k.Open(sealed, []byte("b")); err != ErrCorrupted {
+		t.Fatalf("expected data bound to other additional data to fail, got %v", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := k.Open(sealed, []byte("a")); err != ErrCorrupted {
+		t.Fatalf("expected modified data to fail, got %v", err)
+	}
+	if _, err := k.Open([]byte("short"), nil); err != ErrCorrupted {
+		t.Fatalf("expected truncated data to fail, got %v", err)
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, KeyFile)
+
+	k, err := CreateKeyFile(path, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateKeyFile(path, []byte("secret")); err == nil {
+		t.Fatal("expected an existing key file not to be overwritten")
+	}
+
+	opened, err := OpenKeyFile(path, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := k.Seal([]byte("data"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opened.Open(sealed, nil); err != nil {
+		t.Fatal("expected the opened key to be the created one")
+	}
+
+	if _, err := OpenKeyFile(path, []byte("wrong")); err != ErrBadPassphrase {
+		t.Fatalf("expected ErrBadPassphrase, got %v", err)
+	}
+}
+
+func TestDatastore(t *testing.T) {
+	child := ds.NewMapDatastore()
+	d := WrapDatastore(child, testKey(t))
+
+	for _, s := range []string{"/a/1", "/a/2", "/b/1"} {
+		if err := d.Put(ds.NewKey(s), []byte("value"+s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	raw, err := child.Get(ds.NewKey("/a/1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw.([]byte), []byte("value")) {
+		t.Fatal("expected the stored value to be encrypted")
+	}
+	v, err := d.Get(ds.NewKey("/a/1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v.([]byte)) != "value/a/1" {
+		t.Fatalf("unexpected value %q", v)
+	}
+
+	// a value moved to another key doesn't decrypt
+	if err := child.Put(ds.NewKey("/a/2"), raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Get(ds.NewKey("/a/2")); err != ErrCorrupted {
+		t.Fatalf("expected ErrCorrupted, got %v", err)
+	}
+	if err := d.Put(ds.NewKey("/a/2"), []byte("value/a/2")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the filters see the decrypted values
+	res, err := d.Query(dsq.Query{
+		Prefix:  "/a",
+		Filters: []dsq.Filter{dsq.FilterValueCompare{Op: dsq.Equal, Value: []byte("value/a/2")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Key != "/a/2" || string(entries[0].Value.([]byte)) != "value/a/2" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	b, err := d.Batch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Put(ds.NewKey("/c"), []byte("batched")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := d.Get(ds.NewKey("/c")); err != nil || string(v.([]byte)) != "batched" {
+		t.Fatalf("unexpected batched value %q, %v", v, err)
+	}
+
+	if err := d.Put(ds.NewKey("/d"), "not bytes"); err != ds.ErrInvalidType {
+		t.Fatalf("expected ErrInvalidType, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package crypt
+
+import (
+	"io"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dsq "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/query"
+)
+
+// Datastore encrypts the values of a datastore. The keys are stored in the
+// clear: for blocks they are the hashes of the content.
+type Datastore struct {
+	child ds.Batching
+	key   *Key
+}
+
+// WrapDatastore returns a datastore storing the values in d encrypted with k.
+// The values must be byte slices.
+func WrapDatastore(d ds.Batching, k *Key) *Datastore {
+	return &Datastore{child: d, key: k}
+}
+
+func (d *Datastore) seal(k ds.Key, value interface{}) ([]byte, error) {
+	b, ok := value.([]byte)
+	if !ok {
+		return nil, ds.ErrInvalidType
+	}
+	// bind the value to its key, so that values can't be swapped on disk
+	return d.key.Seal(b, k.Bytes())
+}
+
+func (d *Datastore) open(k ds.Key, value interface{}) ([]byte, error) {
+	b, ok := value.([]byte)
+	if !ok {
+		return nil, ds.ErrInvalidType
+	}
+	return d.key.Open(b, k.Bytes())
+}
+
+func (d *Datastore) Put(k ds.Key, value interface{}) error {
+	sealed, err := d.seal(k, value)
+	if err != nil {
+		return err
+	}
+	return d.child.Put(k, sealed)
+}
+
+func (d *Datastore) Get(k ds.Key) (interface{}, error) {
+	v, err := d.child.Get(k)
+	if err != nil {
+		return nil, err
+	}
+	return d.open(k, v)
+}
+
+func (d *Datastore) Has(k ds.Key) (bool, error) {
+	return d.child.Has(k)
+}
+
+func (d *Datastore) Delete(k ds.Key) error {
+	return d.child.Delete(k)
+}
+
+// Query decrypts the values of the entries before applying the filters and
+// orders of q, which may look at them.
+func (d *Datastore) Query(q dsq.Query) (dsq.Results, error) {
+	inner, err := d.child.Query(dsq.Query{Prefix: q.Prefix, KeysOnly: q.KeysOnly})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan dsq.Result)
+	qr := dsq.ResultsWithChan(q, out)
+	go func() {
+		defer close(out)
+		defer inner.Close()
+		for r := range inner.Next() {
+			if r.Error == nil && !q.KeysOnly {
+				r.Value, r.Error = d.open(ds.RawKey(r.Key), r.Value)
+			}
+			select {
+			case out <- r:
+			case <-qr.Process().Closing():
+				return
+			}
+		}
+	}()
+	return dsq.NaiveQueryApply(q, qr), nil
+}
+
+func (d *Datastore) Batch() (ds.Batch, error) {
+	b, err := d.child.Batch()
+	if err != nil {
+		return nil, err
+	}
+	return &batch{b: b, d: d}, nil
+}
+
+// Close closes the wrapped datastore
+func (d *Datastore) Close() error {
+	if c, ok := d.child.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type batch struct {
+	b ds.Batch
+	d *Datastore
+}
+
+func (b *batch) Put(k ds.Key, value interface{}) error {
+	sealed, err := b.d.seal(k, value)
+	if err != nil {
+		return err
+	}
+	return b.b.Put(k, sealed)
+}
+
+func (b *batch) Delete(k ds.Key) error {
+	return b.b.Delete(k)
+}
+
+func (b *batch) Commit() error {
+	return b.b.Commit()
+}
+
+var _ ds.Batching = (*Datastore)(nil)
@@ -3,6 +3,7 @@ package fsrepo
 import (
 	"fmt"
 	"path"
+	"sync"
 
 	repo "github.com/ipfs/go-ipfs/repo"
 	config "github.com/ipfs/go-ipfs/repo/config"
@@ -82,3 +83,35 @@ func initDefaultDatastore(repoPath string, conf *config.Config) error {
 	}
 	return nil
 }
+
+// DatastoreOpener opens a datastore of a registered type, given the path of
+// the repo and the datastore config
+type DatastoreOpener func(repoPath string, cfg config.Datastore) (repo.Datastore, error)
+
+var datastoresLk sync.RWMutex
+var datastores = make(map[string]DatastoreOpener)
+
+// RegisterDatastore registers a datastore type, which can then be used as
+// the Datastore.Type of the config. The default type cannot be overridden.
+func RegisterDatastore(typ string, open DatastoreOpener) error {
+	switch typ {
+	case "default", "leveldb", "":
+		return fmt.Errorf("datastore type %q is built in", typ)
+	}
+
+	datastoresLk.Lock()
+	defer datastoresLk.Unlock()
+
+	if _, ok := datastores[typ]; ok {
+		return fmt.Errorf("datastore type %q is already registered", typ)
+	}
+	datastores[typ] = open
+	return nil
+}
+
+func registeredDatastore(typ string) (DatastoreOpener, bool) {
+	datastoresLk.RLock()
+	defer datastoresLk.RUnlock()
+	open, ok := datastores[typ]
+	return open, ok
+}
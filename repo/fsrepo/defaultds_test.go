@@ -0,0 +1,42 @@
+package fsrepo
+
+import (
+	"testing"
+
+	repo "github.com/ipfs/go-ipfs/repo"
+	config "github.com/ipfs/go-ipfs/repo/config"
+	ds2 "github.com/ipfs/go-ipfs/thirdparty/datastore2"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	dssync "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore/sync"
+)
+
+func TestRegisteredDatastore(t *testing.T) {
+	open := func(repoPath string, cfg config.Datastore) (repo.Datastore, error) {
+		return ds2.CloserWrap(dssync.MutexWrap(ds.NewMapDatastore())), nil
+	}
+	if err := RegisterDatastore("default", open); err == nil {
+		t.Fatal("should not be able to override the default datastore")
+	}
+	if err := RegisterDatastore("testmem", open); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDatastore("testmem", open); err == nil {
+		t.Fatal("should not be able to register a datastore type twice")
+	}
+
+	path := testRepoPath("registered", t)
+	cfg := &config.Config{Datastore: config.Datastore{Type: "testmem"}}
+	if err := Init(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if err := r.Datastore().Put(ds.NewKey("foo"), []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+}
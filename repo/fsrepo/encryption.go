@@ -0,0 +1,90 @@
+package fsrepo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	config "github.com/ipfs/go-ipfs/repo/config"
+	crypt "github.com/ipfs/go-ipfs/repo/crypt"
+)
+
+// EnvPassphrase is the environment variable holding the passphrase of the
+// repo key of an encrypted repo
+const EnvPassphrase = "IPFS_REPO_PASSPHRASE"
+
+var errNoPassphrase = errors.New("the repo is encrypted: set $" + EnvPassphrase + " or Datastore.Encryption.PassphraseCommand to unlock it")
+
+// repoPassphrase returns the passphrase of the repo key, from the
+// environment or the output of the configured command
+func repoPassphrase(c config.Encryption) ([]byte, error) {
+	if p := os.Getenv(EnvPassphrase); p != "" {
+		return []byte(p), nil
+	}
+	if len(c.PassphraseCommand) == 0 {
+		return nil, errNoPassphrase
+	}
+
+	cmd := exec.Command(c.PassphraseCommand[0], c.PassphraseCommand[1:]...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Datastore.Encryption.PassphraseCommand failed: %s", err)
+	}
+	line, _ := bufio.NewReader(bytes.NewReader(out)).ReadBytes('\n')
+	line = bytes.TrimRight(line, "\r\n")
+	if len(line) == 0 {
+		return nil, errors.New("Datastore.Encryption.PassphraseCommand printed no passphrase")
+	}
+	return line, nil
+}
+
+// initRepoKey generates the repo key of a new encrypted repo. The key left
+// by an initialization that failed before writing the config is replaced:
+// nothing was encrypted with it.
+func initRepoKey(repoPath string, c config.Encryption) error {
+	pass, err := repoPassphrase(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(repoPath, 0775); err != nil {
+		return err
+	}
+	kp := filepath.Join(repoPath, crypt.KeyFile)
+	if err := os.Remove(kp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	_, err = crypt.CreateKeyFile(kp, pass)
+	return err
+}
+
+// openRepoKey unlocks the repo key if the repo is encrypted, and checks that
+// the config agrees on whether it is
+func (r *FSRepo) openRepoKey() error {
+	kp := filepath.Join(r.path, crypt.KeyFile)
+	_, err := os.Stat(kp)
+	hasKey := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	switch enabled := r.config.Datastore.Encryption.Enabled; {
+	case !enabled && !hasKey:
+		return nil
+	case !enabled:
+		return errors.New("the repo is encrypted but Datastore.Encryption.Enabled is false")
+	case !hasKey:
+		return errors.New("Datastore.Encryption is enabled but the repo has no key: encryption can only be enabled by 'ipfs init --encrypt'")
+	}
+
+	pass, err := repoPassphrase(r.config.Datastore.Encryption)
+	if err != nil {
+		return err
+	}
+	r.key, err = crypt.OpenKeyFile(kp, pass)
+	return err
+}
@@ -11,11 +11,13 @@ import (
 	"strings"
 	"sync"
 
+	features "github.com/ipfs/go-ipfs/features"
 	filestore "github.com/ipfs/go-ipfs/filestore"
 	keystore "github.com/ipfs/go-ipfs/keystore"
 	repo "github.com/ipfs/go-ipfs/repo"
 	"github.com/ipfs/go-ipfs/repo/common"
 	config "github.com/ipfs/go-ipfs/repo/config"
+	crypt "github.com/ipfs/go-ipfs/repo/crypt"
 	lockfile "github.com/ipfs/go-ipfs/repo/fsrepo/lock"
 	mfsr "github.com/ipfs/go-ipfs/repo/fsrepo/migrations"
 	serialize "github.com/ipfs/go-ipfs/repo/fsrepo/serialize"
@@ -102,6 +104,9 @@ type FSRepo struct {
 	ds       repo.Datastore
 	keystore keystore.Keystore
 	filemgr  *filestore.FileManager
+	// key encrypts the datastore and the keystore, nil if the repo is not
+	// encrypted
+	key *crypt.Key
 }
 
 var _ repo.Repo = (*FSRepo)(nil)
@@ -166,6 +171,10 @@ func open(repoPath string) (repo.Repo, error) {
 		return nil, err
 	}
 
+	if err := r.openRepoKey(); err != nil {
+		return nil, err
+	}
+
 	if err := r.openDatastore(); err != nil {
 		return nil, err
 	}
@@ -174,7 +183,7 @@ func open(repoPath string) (repo.Repo, error) {
 		return nil, err
 	}
 
-	if r.config.Experimental.FilestoreEnabled {
+	if features.New(r.config.Experimental, nil).Enabled(features.Filestore) {
 		r.filemgr = filestore.NewFileManager(r.ds, filepath.Dir(r.path))
 	}
 
@@ -261,7 +270,19 @@ func Init(repoPath string, conf *config.Config) error {
 		return nil
 	}
 
+	// the repo key is created before the config, as a repo with a config
+	// is initialized: one without its key could be neither opened nor
+	// initialized again
+	if conf.Datastore.Encryption.Enabled {
+		if err := initRepoKey(repoPath, conf.Datastore.Encryption); err != nil {
+			return err
+		}
+	}
+
 	if err := initConfig(repoPath, conf); err != nil {
+		if conf.Datastore.Encryption.Enabled {
+			os.Remove(filepath.Join(repoPath, crypt.KeyFile))
+		}
 		return err
 	}
 
@@ -354,7 +375,13 @@ func (r *FSRepo) openConfig() error {
 
 func (r *FSRepo) openKeystore() error {
 	ksp := filepath.Join(r.path, "keystore")
-	ks, err := keystore.NewFSKeystore(ksp)
+	var ks *keystore.FSKeystore
+	var err error
+	if r.key != nil {
+		ks, err = keystore.NewSealedFSKeystore(ksp, r.key)
+	} else {
+		ks, err = keystore.NewFSKeystore(ksp)
+	}
 	if err != nil {
 		return err
 	}
@@ -374,7 +401,19 @@ func (r *FSRepo) openDatastore() error {
 		}
 		r.ds = d
 	default:
-		return fmt.Errorf("unknown datastore type: %s", r.config.Datastore.Type)
+		open, ok := registeredDatastore(r.config.Datastore.Type)
+		if !ok {
+			return fmt.Errorf("unknown datastore type: %s", r.config.Datastore.Type)
+		}
+		d, err := open(r.path, r.config.Datastore)
+		if err != nil {
+			return err
+		}
+		r.ds = d
+	}
+
+	if r.key != nil {
+		r.ds = crypt.WrapDatastore(r.ds, r.key)
 	}
 
 	// Wrap it with metrics gathering
@@ -567,6 +606,12 @@ func (r *FSRepo) SetConfigKey(key string, value interface{}) error {
 		return err
 	}
 
+	// refuse invalid values for the key, leaving the problems elsewhere
+	// in the file to 'ipfs config check'
+	if err := config.ProblemsError(config.ProblemsAt(config.Errors(config.Check(mapconf)), key)); err != nil {
+		return err
+	}
+
 	// This step doubles as to validate the map against the struct
 	// before serialization
 	conf, err := config.FromMap(mapconf)
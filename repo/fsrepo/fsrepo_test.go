@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/ipfs/go-ipfs/repo/config"
+	crypt "github.com/ipfs/go-ipfs/repo/crypt"
 	"github.com/ipfs/go-ipfs/thirdparty/assert"
 	datastore "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
 )
@@ -117,3 +118,58 @@ func TestOpenMoreThanOnceInSameProcess(t *testing.T) {
 	assert.Nil(r1.Close(), t)
 	assert.Nil(r2.Close(), t)
 }
+
+func TestEncryptedRepo(t *testing.T) {
+	t.Parallel()
+	path := testRepoPath("test", t)
+	defer Remove(path)
+
+	conf := &config.Config{}
+	conf.Datastore.Encryption = config.Encryption{
+		Enabled:           true,
+		PassphraseCommand: []string{"echo", "secret"},
+	}
+	assert.Nil(Init(path, conf), t)
+	_, err := os.Stat(filepath.Join(path, crypt.KeyFile))
+	assert.Nil(err, t, "init should create the repo key")
+
+	r1, err := Open(path)
+	assert.Nil(err, t)
+	k := datastore.NewKey("key")
+	expected := []byte("value")
+	assert.Nil(r1.Datastore().Put(k, expected), t)
+	assert.Nil(r1.Close(), t)
+
+	r2, err := Open(path)
+	assert.Nil(err, t)
+	v, err := r2.Datastore().Get(k)
+	assert.Nil(err, t, "Get should decrypt the value")
+	assert.True(bytes.Equal(v.([]byte), expected), t, "value should be the same")
+
+	conf.Datastore.Encryption.PassphraseCommand = []string{"echo", "wrong"}
+	assert.Nil(r2.SetConfig(conf), t)
+	assert.Nil(r2.Close(), t)
+
+	_, err = Open(path)
+	assert.Err(err, t, "Open should fail with a wrong passphrase")
+}
+
+func TestEncryptedRepoInitFails(t *testing.T) {
+	t.Parallel()
+	path := testRepoPath("test", t)
+	defer Remove(path)
+
+	conf := &config.Config{}
+	conf.Datastore.Encryption = config.Encryption{
+		Enabled:           true,
+		PassphraseCommand: []string{"false"},
+	}
+	assert.Err(Init(path, conf), t, "Init should fail without a passphrase")
+	assert.False(IsInitialized(path), t, "a failed init should leave the repo uninitialized")
+
+	conf.Datastore.Encryption.PassphraseCommand = []string{"echo", "secret"}
+	assert.Nil(Init(path, conf), t, "init should succeed once the passphrase is available")
+	r, err := Open(path)
+	assert.Nil(err, t)
+	assert.Nil(r.Close(), t)
+}
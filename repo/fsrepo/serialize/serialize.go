@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
@@ -18,13 +19,12 @@ var log = logging.Logger("fsrepo")
 
 // ReadConfigFile reads the config from `filename` into `cfg`.
 func ReadConfigFile(filename string, cfg interface{}) error {
-	f, err := os.Open(filename)
+	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	if err := json.NewDecoder(f).Decode(cfg); err != nil {
-		return fmt.Errorf("Failure to decode config: %s", err)
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("Failure to decode config: %s", config.DecodeError(data, err))
 	}
 	return nil
 }
@@ -63,11 +63,26 @@ func Load(filename string) (*config.Config, error) {
 		return nil, errors.New("ipfs not initialized, please run 'ipfs init'")
 	}
 
-	var cfg config.Config
-	err := ReadConfigFile(filename, &cfg)
+	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	return &cfg, err
+	problems := config.CheckBytes(data)
+	var cfg config.Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		// the problems found by the schema are more precise than the
+		// errors of the decoder
+		if errs := config.Errors(problems); len(errs) > 0 {
+			return nil, config.ProblemsError(errs)
+		}
+		return nil, fmt.Errorf("Failure to decode config: %s", config.DecodeError(data, err))
+	}
+
+	// the node runs with the other problems, 'ipfs config check' and the
+	// preflight checks of the daemon report them
+	for _, p := range problems {
+		log.Warning(p)
+	}
+	return &cfg, nil
 }
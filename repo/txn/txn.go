@@ -0,0 +1,317 @@
+// Package txn makes the updates of root pointers atomic with the writes of
+// the blocks they reference. A root pointer is a datastore key holding the
+// CID of a DAG, such as the pin set or the root of the files API.
+//
+// Each update of a root pointer is first appended to a journal file, synced
+// to disk, along with the previous value, and marked done once the pointer
+// is written. After a crash, Recover points each root whose update wasn't
+// marked done back to its previous value if the block written with the new
+// root didn't reach the disk.
+package txn
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	logging "gx/ipfs/QmSpJByNKFX1sCsHBEp3R73FL4NF6FnQTEGyNAXHm2GS52/go-log"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+)
+
+var log = logging.Logger("repo/txn")
+
+// JournalFile is the name of the journal of the root pointers, in the repo
+const JournalFile = "roots.journal"
+
+// Check returns whether the root block c is on disk
+type Check func(c *cid.Cid) bool
+
+// entry is a line of the journal
+type entry struct {
+	Key string
+	// Root is the new value of the pointer, Prev the one before
+	Root string
+	Prev string `json:",omitempty"`
+	// Done marks the update of Key to Root as written
+	Done bool `json:",omitempty"`
+}
+
+// Roots is a datastore writing the values of root pointers atomically.
+// Other keys are passed through to the wrapped datastore.
+type Roots struct {
+	ds.Datastore
+
+	keys map[ds.Key]bool
+
+	// journal is the path of the journal, "" if there is none
+	journal string
+
+	lk sync.Mutex
+	// pending are the updates read from the journal that weren't marked
+	// done, by key
+	pending map[string]entry
+	// created is whether the journal file is known to exist
+	created bool
+	// size is the size of the journal
+	size int64
+}
+
+// maxJournalSize is the size over which the journal is emptied when no
+// update is pending
+const maxJournalSize = 1 << 20
+
+// NewRoots wraps d, whose keys are root pointers. The updates of the
+// pointers are recorded in the journal file at the given path, unless the
+// path is empty.
+func NewRoots(d ds.Datastore, journal string, keys ...ds.Key) (*Roots, error) {
+	r := &Roots{
+		Datastore: d,
+		keys:      make(map[ds.Key]bool, len(keys)),
+		pending:   make(map[string]entry),
+	}
+	for _, k := range keys {
+		r.keys[k] = true
+	}
+	if journal == "" {
+		return r, nil
+	}
+
+	r.journal = journal
+	data, err := ioutil.ReadFile(journal)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, err
+	default:
+		r.created = true
+		r.size = int64(len(data))
+		if err := r.readJournal(data); err != nil {
+			return nil, fmt.Errorf("corrupted journal %s: %s", journal, err)
+		}
+	}
+	return r, nil
+}
+
+// readJournal replays the lines of the journal. A broken last line is an
+// append that didn't reach the disk, and is ignored.
+func (r *Roots) readJournal(data []byte) error {
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			if len(bytes.TrimSpace(bytes.Join(lines[i+1:], nil))) == 0 {
+				log.Warningf("ignoring the broken end of the journal")
+				return nil
+			}
+			return err
+		}
+		if !e.Done {
+			r.pending[e.Key] = e
+		} else if p, ok := r.pending[e.Key]; ok && p.Root == e.Root {
+			delete(r.pending, e.Key)
+		}
+	}
+	return nil
+}
+
+// Put writes value at k. If k is a root pointer, value must be the bytes of
+// a CID whose blocks have all been written.
+func (r *Roots) Put(k ds.Key, value interface{}) error {
+	if !r.keys[k] {
+		return r.Datastore.Put(k, value)
+	}
+	if r.journal == "" {
+		return r.Datastore.Put(k, value)
+	}
+
+	b, ok := value.([]byte)
+	if !ok {
+		return ds.ErrInvalidType
+	}
+	c, err := cid.Cast(b)
+	if err != nil {
+		return err
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	e := entry{Key: k.String(), Root: c.String()}
+	if cur, err := r.current(k); err == nil && cur != nil {
+		e.Prev = cur.String()
+	}
+
+	// the updates of this process are all done, the journal is only
+	// needed for the pending ones of the previous run
+	if r.size > maxJournalSize && len(r.pending) == 0 {
+		if err := os.Truncate(r.journal, 0); err != nil {
+			return err
+		}
+		r.size = 0
+	}
+
+	// record the new root before writing it, so that it can be undone if
+	// its block is lost
+	if err := r.appendJournal(e, true); err != nil {
+		return err
+	}
+	if err := r.Datastore.Put(k, value); err != nil {
+		return err
+	}
+
+	// a lost mark only makes Recover check the update, it isn't synced:
+	// the next update syncs it with its own entry
+	e.Done = true
+	if err := r.appendJournal(e, false); err != nil {
+		log.Warningf("marking the update of %s done: %s", k, err)
+	}
+	return nil
+}
+
+// current returns the CID held by k, nil if k is unset
+func (r *Roots) current(k ds.Key) (*cid.Cid, error) {
+	v, err := r.Datastore.Get(k)
+	if err == ds.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, ds.ErrInvalidType
+	}
+	return cid.Cast(b)
+}
+
+// Recover checks the updates of the root pointers that weren't marked done
+// in the journal, and points back to its previous value each root whose
+// block fails the check. Updates marked done, and updates whose pointer
+// wasn't written, are left alone. The journal is then emptied. It must be
+// called before the pointers are read.
+func (r *Roots) Recover(checks map[ds.Key]Check) error {
+	if r.journal == "" {
+		return nil
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	keys := make([]string, 0, len(r.pending))
+	for k := range r.pending {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, ks := range keys {
+		k := ds.NewKey(ks)
+		check, ok := checks[k]
+		if !ok {
+			continue
+		}
+		if err := r.recover(k, r.pending[ks], check); err != nil {
+			return err
+		}
+	}
+
+	r.pending = make(map[string]entry)
+	if !r.created {
+		return nil
+	}
+	if err := os.Remove(r.journal); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	r.created = false
+	r.size = 0
+	return syncDir(filepath.Dir(r.journal))
+}
+
+// recover checks the update e of k, which wasn't marked done
+func (r *Roots) recover(k ds.Key, e entry, check Check) error {
+	root, err := cid.Decode(e.Root)
+	if err != nil {
+		return fmt.Errorf("corrupted journal %s: %s", r.journal, err)
+	}
+	cur, err := r.current(k)
+	if err != nil {
+		return err
+	}
+	if cur == nil || !cur.Equals(root) {
+		// the pointer wasn't written, it still holds its previous value
+		return nil
+	}
+	if check(root) {
+		return nil
+	}
+	if e.Prev == "" {
+		log.Errorf("the root block of %s at %s is missing, keeping it", k, root)
+		return nil
+	}
+
+	prev, err := cid.Decode(e.Prev)
+	if err != nil {
+		return fmt.Errorf("corrupted journal %s: %s", r.journal, err)
+	}
+	log.Warningf("restoring %s to %s after an interrupted update to %s", k, prev, root)
+	return r.Datastore.Put(k, prev.Bytes())
+}
+
+// appendJournal appends e to the journal, and syncs it to disk if sync is
+// set
+func (r *Roots) appendJournal(e entry, sync bool) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.journal, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	n, err := f.Write(append(data, '\n'))
+	r.size += int64(n)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if sync {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if sync && !r.created {
+		// make the creation of the journal durable
+		if err := syncDir(filepath.Dir(r.journal)); err != nil {
+			return err
+		}
+		r.created = true
+	}
+	return nil
+}
+
+// syncDir makes a rename in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		// some platforms, e.g. windows, can't sync directories
+		log.Debugf("syncing %s: %s", dir, err)
+	}
+	return nil
+}
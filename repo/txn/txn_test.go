@@ -0,0 +1,180 @@
+package txn
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ds "gx/ipfs/QmRWDav6mzWseLWeYfVd5fvUKiVe9xNH29YfMF438fG364/go-datastore"
+	u "gx/ipfs/QmWbjfz3u6HkAdPh34dgPchGbQjob6LXLhAeCGii2TX69n/go-ipfs-util"
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
+)
+
+var rootKey = ds.NewKey("/local/root")
+
+func testCid(s string) *cid.Cid {
+	return cid.NewCidV0(u.Hash([]byte(s)))
+}
+
+func tempJournal(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "txn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, JournalFile), func() { os.RemoveAll(dir) }
+}
+
+func assertRoot(t *testing.T, d ds.Datastore, expected *cid.Cid) {
+	v, err := d.Get(rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := cid.Cast(v.([]byte))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Equals(expected) {
+		t.Fatalf("expected root %s, got %s", expected, c)
+	}
+}
+
+func completeExcept(missing ...*cid.Cid) Check {
+	return func(c *cid.Cid) bool {
+		for _, m := range missing {
+			if c.Equals(m) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// interrupt records the update of the root to c in the journal, like Put,
+// and writes the pointer if written is set, but doesn't mark it done
+func interrupt(t *testing.T, r *Roots, c *cid.Cid, written bool) {
+	e := entry{Key: rootKey.String(), Root: c.String()}
+	if cur, err := r.current(rootKey); err == nil && cur != nil {
+		e.Prev = cur.String()
+	}
+	if err := r.appendJournal(e, true); err != nil {
+		t.Fatal(err)
+	}
+	if written {
+		if err := r.Datastore.Put(rootKey, c.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	journal, cleanup := tempJournal(t)
+	defer cleanup()
+
+	a, b := testCid("a"), testCid("b")
+	child := ds.NewMapDatastore()
+	if err := child.Put(rootKey, a.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	reopen := func(check Check) *Roots {
+		r, err := NewRoots(child, journal, rootKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Recover(map[ds.Key]Check{rootKey: check}); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	r, err := NewRoots(child, journal, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Put(rootKey, b.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Put(ds.NewKey("/other"), []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	assertRoot(t, child, b)
+
+	// a completed update is never rolled back, e.g. when the DAG under the
+	// root isn't local
+	r = reopen(completeExcept(b))
+	assertRoot(t, child, b)
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Fatal("expected the journal to be removed once recovered")
+	}
+
+	// the block of the new root was lost
+	interrupt(t, r, a, true)
+	r = reopen(completeExcept(a))
+	assertRoot(t, child, b)
+
+	// the block of the new root is there
+	interrupt(t, r, a, true)
+	r = reopen(completeExcept())
+	assertRoot(t, child, a)
+
+	// the pointer wasn't written
+	interrupt(t, r, b, false)
+	r = reopen(completeExcept())
+	assertRoot(t, child, a)
+}
+
+func TestBrokenJournal(t *testing.T) {
+	journal, cleanup := tempJournal(t)
+	defer cleanup()
+
+	a := testCid("a")
+	child := ds.NewMapDatastore()
+	r, err := NewRoots(child, journal, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Put(rootKey, a.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	// an append cut by a crash
+	f, err := os.OpenFile(journal, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte(`{"Key":"/local/ro`)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := NewRoots(child, journal, rootKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// a broken line followed by others is corruption
+	if err := r.Put(rootKey, a.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRoots(child, journal, rootKey); err == nil {
+		t.Fatal("expected a corrupted journal to be refused")
+	}
+}
+
+func BenchmarkPut(b *testing.B) {
+	dir, err := ioutil.TempDir("", "txn")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := NewRoots(ds.NewMapDatastore(), filepath.Join(dir, JournalFile), rootKey)
+	if err != nil {
+		b.Fatal(err)
+	}
+	c := testCid("a")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := r.Put(rootKey, c.Bytes()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
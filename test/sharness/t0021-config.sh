@@ -151,6 +151,41 @@ test_config_cmd() {
        echo "Error: setting private key with API is not supported" > replace_expected
        test_cmp replace_out replace_expected
   '
+
+  test_expect_success "'ipfs config' sets but doesn't show the DNSLink credentials" '
+       ipfs config DNSLink.Cloudflare.APIToken secret-token &&
+       ipfs config DNSLink.RFC2136.Secret c2VjcmV0 &&
+       test_expect_code 1 ipfs config DNSLink.Cloudflare.APIToken 2> secret_out &&
+       grep "cannot show DNSLink credentials through API" secret_out &&
+       test_expect_code 1 ipfs config dnslink.rfc2136.secret &&
+       ipfs config DNSLink > dnslink_out &&
+       test_expect_code 1 grep -e secret-token -e c2VjcmV0 dnslink_out
+  '
+
+  test_expect_success "'ipfs config show' doesn't include the DNSLink credentials" '
+       ipfs config show > show_config &&
+       test_expect_code 1 grep -e secret-token -e c2VjcmV0 show_config
+  '
+
+  test_expect_success "'ipfs config replace' keeps the DNSLink credentials" '
+       ipfs config replace show_config &&
+       grep secret-token "$IPFS_PATH/config" &&
+       grep c2VjcmV0 "$IPFS_PATH/config"
+  '
+
+  test_expect_success "'ipfs config' doesn't set the passphrase command" '
+       test_expect_code 1 ipfs config --json Datastore.Encryption.PassphraseCommand "[\"echo\", \"pass\"]" 2> program_out &&
+       grep "cannot change Datastore.Encryption.PassphraseCommand" program_out &&
+       test_expect_code 1 grep PassphraseCommand "$IPFS_PATH/config"
+  '
+
+  test_expect_success "'ipfs config replace' doesn't set the passphrase command" '
+       ipfs config show > show_config &&
+       sed -i"~" -e '\''s/"Encryption": {/"Encryption": {"PassphraseCommand": ["echo", "pass"],/'\'' show_config &&
+       grep PassphraseCommand show_config &&
+       test_expect_code 1 ipfs config replace show_config 2> program_out &&
+       grep "cannot change Datastore.Encryption.PassphraseCommand" program_out
+  '
 }
 
 test_init_ipfs
@@ -158,6 +193,30 @@ test_init_ipfs
 # should work offline
 test_config_cmd
 
+test_expect_success "'ipfs config check' reports unknown fields as warnings" '
+  ipfs config check >check_out &&
+  grep "warning: beep: unknown field" check_out &&
+  test_must_fail ipfs config check --strict
+'
+
+test_expect_success "'ipfs config' suggests the field of a typo" '
+  ipfs config Ipns.RepublishPerod 1h >typo_out &&
+  grep "warning: Ipns.RepublishPerod: unknown field, did you mean Ipns.RepublishPeriod?" typo_out
+'
+
+test_expect_success "'ipfs config' refuses invalid values" '
+  test_must_fail ipfs config Ipns.RecordLifetime forever 2>invalid_out &&
+  grep "Ipns.RecordLifetime: invalid value \"forever\"" invalid_out &&
+  test_must_fail ipfs config --json Ipns.ResolveCacheTTLMin 10 2>invalid_out &&
+  grep "Ipns.ResolveCacheTTLMin: expected a string, got the number 10" invalid_out
+'
+
+test_expect_success "'ipfs config replace' reports where the syntax error is" '
+  printf "{\n  \"API\": {,\n}\n" >broken_config &&
+  test_must_fail ipfs config replace broken_config 2>broken_out &&
+  grep "line 2, column 11" broken_out
+'
+
 # should work online
 test_launch_ipfs_daemon
 test_config_cmd
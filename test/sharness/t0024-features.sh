@@ -0,0 +1,42 @@
+#!/bin/sh
+
+test_description="Test features command"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "features ls lists the features" '
+	ipfs features ls >features_out &&
+	grep "^pubsub  *false  *experimental  *default" features_out &&
+	grep "^mplex  *true" features_out &&
+	grep "IPFS_LOW_MEM" features_out
+'
+
+test_expect_success "features can be enabled in the config" '
+	ipfs config --json Experimental.Features.pubsub true &&
+	ipfs features ls >features_out &&
+	grep "^pubsub  *true  *experimental  *config" features_out
+'
+
+test_expect_success "legacy config flags are honored" '
+	ipfs config --json Experimental.FilestoreEnabled true &&
+	ipfs features ls --enc=json >features_out &&
+	grep "\"Name\":\"filestore\",[^}]*\"Enabled\":true" features_out
+'
+
+test_expect_success "features can be enabled from the environment" '
+	IPFS_LOW_MEM=1 ipfs features ls >features_out &&
+	grep "^lowmem  *true  *experimental  *env" features_out
+'
+
+test_launch_ipfs_daemon --enable-pubsub-experiment=false
+
+test_expect_success "daemon flags override the config" '
+	ipfs features ls >features_out &&
+	grep "^pubsub  *false  *experimental  *flag" features_out
+'
+
+test_kill_ipfs_daemon
+
+test_done
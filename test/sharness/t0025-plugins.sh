@@ -0,0 +1,30 @@
+#!/bin/sh
+
+test_description="Test plugins command"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "plugins ls works" '
+	ipfs plugins ls >plugins_out &&
+	grep "^NAME  *VERSION  *TYPES" plugins_out
+'
+
+test_expect_success "plugins ls reports the plugin API version" '
+	ipfs plugins ls --enc=json >plugins_out &&
+	grep "\"APIVersion\":1" plugins_out
+'
+
+test_expect_success "unloadable plugins are an error" '
+	mkdir -p "$IPFS_PATH/plugins" &&
+	echo "not a plugin" >"$IPFS_PATH/plugins/bad.so" &&
+	test_must_fail ipfs plugins ls 2>plugins_err &&
+	grep "bad.so" plugins_err
+'
+
+test_expect_success "cleanup plugins dir" '
+	rm -r "$IPFS_PATH/plugins"
+'
+
+test_done
@@ -54,6 +54,45 @@ test_add_cat_file() {
     	test_cmp expected actual
     '
 
+    test_expect_success "ipfs cat --offset --length succeeds" '
+    	ipfs cat --offset 6 --length 5 "$HASH" >actual
+    '
+
+    test_expect_success "ipfs cat --offset --length output looks good" '
+    	printf "World" >expected &&
+    	test_cmp expected actual
+    '
+
+    test_expect_success "ipfs cat --offset spans several paths" '
+    	ipfs cat --offset 10 --length 10 "$HASH" "$HASH" >actual &&
+    	printf "ds!\nHello " >expected &&
+    	test_cmp expected actual
+    '
+
+    test_expect_success "ipfs cat --offset past the end is empty" '
+    	ipfs cat --offset 100 "$HASH" >actual &&
+    	test_must_be_empty actual
+    '
+
+    test_expect_success "ipfs cat --tail rejects /ipfs paths" '
+    	test_must_fail ipfs cat --tail "$HASH" 2>tail_err &&
+    	grep "only works on /ipns paths" tail_err
+    '
+
+    test_expect_success "ipfs add --events succeeds" '
+    	ipfs add --events --enc=json mountdir/hello.txt >actual
+    '
+
+    test_expect_success "ipfs add --events output looks good" '
+    	grep "\"Type\":\"progress\"" actual &&
+    	grep "\"Type\":\"result\"" actual | grep "$HASH"
+    '
+
+    test_expect_success "ipfs add --events requires json" '
+    	test_must_fail ipfs add --events mountdir/hello.txt 2>err &&
+    	grep "requires --encoding=json" err
+    '
+
     test_expect_success "ipfs add -t succeeds" '
         ipfs add -t mountdir/hello.txt >actual
     '
@@ -499,6 +538,104 @@ test_expect_success "ipfs cat file fails" '
     test_must_fail ipfs cat $(cat oh_hash)
 '
 
+test_expect_success "ipfs add uses the configured hash function" '
+    ipfs config Unixfs.HashFunction blake2b-256 &&
+    echo "hash config" | ipfs add -q > hashconf_out &&
+    ipfs cid format -f "%v %h" $(cat hashconf_out) > hashconf_actual &&
+    echo "cidv1 blake2b-256" > hashconf_expected &&
+    test_cmp hashconf_expected hashconf_actual
+'
+
+test_expect_success "ipfs add --hash overrides the configured hash function" '
+    echo "hash config" | ipfs add -q --hash=sha2-256 > hashconf_out &&
+    ipfs cid format -f "%v %h" $(cat hashconf_out) > hashconf_actual &&
+    echo "cidv1 sha2-256" > hashconf_expected &&
+    test_cmp hashconf_expected hashconf_actual &&
+    ipfs config --json Unixfs.HashFunction "\"\""
+'
+
+test_expect_success "ipfs add --cid-profile=v0 matches the default" '
+    echo "profile data" > profile_file &&
+    ipfs add -q profile_file > profile_default &&
+    ipfs add -q --cid-profile=v0 profile_file > profile_v0 &&
+    test_cmp profile_default profile_v0
+'
+
+test_expect_success "ipfs add --cid-profile=v1 uses cidv1 raw leaves" '
+    ipfs add -q --cid-profile=v1 profile_file > profile_v1 &&
+    ipfs cid format -f "%v %c %h" $(cat profile_v1) > profile_v1_fmt &&
+    echo "cidv1 raw sha2-256" > profile_v1_exp &&
+    test_cmp profile_v1_exp profile_v1_fmt
+'
+
+test_expect_success "ipfs add --cid-profile ignores the configured hash function" '
+    ipfs config Unixfs.HashFunction blake2b-256 &&
+    ipfs add -q --cid-profile=v1 profile_file > profile_v1_conf &&
+    ipfs config --json Unixfs.HashFunction "\"\"" &&
+    test_cmp profile_v1 profile_v1_conf
+'
+
+test_expect_success "ipfs add --cid-profile ignores the configured sharding threshold" '
+    mkdir -p profile_dir &&
+    echo "a" > profile_dir/a &&
+    echo "b" > profile_dir/b &&
+    ipfs add -r -Q --cid-profile=v0 profile_dir > profile_dir_v0 &&
+    ipfs config Unixfs.ShardingThreshold 10B &&
+    ipfs add -r -Q --cid-profile=v0 profile_dir > profile_dir_conf &&
+    ipfs add -r -Q profile_dir > profile_dir_sharded &&
+    ipfs config --json Unixfs.ShardingThreshold "\"\"" &&
+    test_cmp profile_dir_v0 profile_dir_conf &&
+    test_must_fail test_cmp profile_dir_v0 profile_dir_sharded
+'
+
+test_expect_success "ipfs add prints the active cid profile" '
+    ipfs add --cid-profile=v1 profile_file 2> profile_err &&
+    grep "using cid profile v1 (cidv1, sha2-256, size-262144, balanced, raw-leaves=true, sharding-threshold=262144)" profile_err
+'
+
+test_expect_success "ipfs add --cid-profile rejects options it controls" '
+    test_must_fail ipfs add --cid-profile=v1 --raw-leaves=false profile_file 2> profile_err &&
+    grep "cannot be combined" profile_err &&
+    test_must_fail ipfs add --cid-profile=v1 --chunker=size-10 profile_file
+'
+
+test_expect_success "ipfs add --cid-profile rejects unknown profiles" '
+    test_must_fail ipfs add --cid-profile=nope profile_file 2> profile_err &&
+    grep "unknown cid profile" profile_err
+'
+
+test_expect_success "setup trees for ignore rules" '
+    mkdir -p ignore_tree/src ignore_tree/build ignore_expected/src &&
+    echo "main" > ignore_tree/src/main.c &&
+    echo "obj" > ignore_tree/src/main.o &&
+    echo "bin" > ignore_tree/build/main &&
+    echo "main" > ignore_expected/src/main.c &&
+    IGNORE_EXP=$(ipfs add -r -Q ignore_expected)
+'
+
+test_expect_success "ipfs add -r --exclude leaves out matching paths" '
+    HASH=$(ipfs add -r -Q --exclude="*.o,build/" ignore_tree) &&
+    test "$HASH" = "$IGNORE_EXP" ||
+    test_fsh echo "$HASH != $IGNORE_EXP"
+'
+
+test_expect_success "ipfs add -r honors .ipfsignore files" '
+    printf "build/\n" > ignore_tree/.ipfsignore &&
+    printf "*.o\n" > ignore_tree/src/.ipfsignore &&
+    HASH=$(ipfs add -r -Q ignore_tree) &&
+    test "$HASH" = "$IGNORE_EXP" ||
+    test_fsh echo "$HASH != $IGNORE_EXP"
+'
+
+test_expect_success "negated patterns include paths again" '
+    HASH=$(ipfs add -r -Q --exclude="!main.o" ignore_tree) &&
+    test "$HASH" != "$IGNORE_EXP"
+'
+
+test_expect_success "ipfs add -r fails on invalid exclude patterns" '
+    test_must_fail ipfs add -r --exclude="[" ignore_tree
+'
+
 test_add_named_pipe ""
 
 test_add_pwd_is_symlink
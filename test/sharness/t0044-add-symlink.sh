@@ -65,16 +65,104 @@ adding with no symlink" '
 		ipfs add -rq files2/a/d/c > sym &&
 		test_cmp no_sym sym
 	'
+
+	test_expect_success "ipfs add --symlinks=skip leaves symlinks out" '
+		mkdir -p files3/foo &&
+		echo "some text" > files3/foo/baz &&
+		mkdir -p files3/bar &&
+		ipfs add -q -r --symlinks=skip files >skip_all &&
+		tail -n 1 skip_all >skip_out &&
+		ipfs add -q -r files3 >skip_exp_all &&
+		tail -n 1 skip_exp_all >skip_exp &&
+		test_cmp skip_exp skip_out
+	'
+
+	test_expect_success "ipfs add --symlinks=follow adds link targets" '
+		mkdir -p files4/foo files4/bar &&
+		echo "some text" > files4/foo/baz &&
+		echo "some text" > files4/bar/baz &&
+		ln -sfn ../foo files4/dirlink &&
+		mkdir -p files4_exp/foo files4_exp/bar files4_exp/dirlink &&
+		echo "some text" > files4_exp/foo/baz &&
+		echo "some text" > files4_exp/bar/baz &&
+		echo "some text" > files4_exp/dirlink/baz &&
+		ipfs add -q -r --symlinks=follow files4 >follow_all &&
+		tail -n 1 follow_all >follow_out &&
+		ipfs add -q -r files4_exp >follow_exp_all &&
+		tail -n 1 follow_exp_all >follow_exp &&
+		test_cmp follow_exp follow_out
+	'
+
+	test_expect_success "ipfs add --symlinks=follow fails on broken symlinks" '
+		test_must_fail ipfs add -q -r --symlinks=follow files
+	'
+
+	test_expect_success "ipfs add --symlinks rejects unknown modes" '
+		test_must_fail ipfs add -q -r --symlinks=nope files
+	'
+
+	test_expect_success "ipfs add --skip-special leaves out named pipes" '
+		mkdir -p special/dir &&
+		echo "some text" > special/file &&
+		mkfifo special/pipe &&
+		test_must_fail ipfs add -q -r special &&
+		ipfs add -q -r --skip-special special >special_all &&
+		rm special/pipe &&
+		ipfs add -q -r special >special_exp_all &&
+		tail -n 1 special_all >special_out &&
+		tail -n 1 special_exp_all >special_exp &&
+		test_cmp special_exp special_out
+	'
+}
+
+test_get_symlinks() {
+	test_expect_success "setup symlinks to get" '
+		mkdir -p getlinks/sub &&
+		echo "text" > getlinks/sub/file &&
+		ln -sfn file getlinks/sub/inside &&
+		ln -sfn ../../outside getlinks/sub/outside &&
+		ln -sfn /etc/passwd getlinks/abs &&
+		GETHASH=$(ipfs add -Q -r getlinks)
+	'
+
+	test_expect_success "ipfs get only creates symlinks inside the output" '
+		ipfs get -o got_safe $GETHASH &&
+		test -L got_safe/sub/inside &&
+		test ! -e got_safe/sub/outside && test ! -L got_safe/sub/outside &&
+		test ! -L got_safe/abs
+	'
+
+	test_expect_success "ipfs get --symlinks=preserve creates all symlinks" '
+		ipfs get -o got_preserve --symlinks=preserve $GETHASH &&
+		test -L got_preserve/sub/inside &&
+		test -L got_preserve/sub/outside &&
+		test -L got_preserve/abs
+	'
+
+	test_expect_success "ipfs get --symlinks=skip creates no symlinks" '
+		ipfs get -o got_skip --symlinks=skip $GETHASH &&
+		test -f got_skip/sub/file &&
+		test ! -L got_skip/sub/inside &&
+		test ! -L got_skip/abs
+	'
+
+	test_expect_success "clean up" '
+		rm -rf got_safe got_preserve got_skip
+	'
 }
 
 test_init_ipfs
 
 test_add_symlinks
 
+test_get_symlinks
+
 test_launch_ipfs_daemon
 
 test_add_symlinks
 
+test_get_symlinks
+
 test_kill_ipfs_daemon
 
 test_done
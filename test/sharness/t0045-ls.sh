@@ -88,6 +88,44 @@ test_ls_cmd() {
 		EOF
 		test_cmp expected_ls_headers actual_ls_headers
 	'
+
+	test_expect_success "'ipfs ls --stream <three dir hashes>' succeeds" '
+		ipfs ls --stream QmfNy183bXiRVyrhyWtq3TwHn79yHEkiAGFr18P7YNzESj QmR3jhV4XpxxPjPT3Y8vNnWvWNvakdcT3H6vqpRBsX1MLy QmSix55yz8CzWXf5ZVM9vgEvijnEeeXiTSarVtsqiiCJss >actual_ls_stream
+	'
+
+	test_expect_success "'ipfs ls --stream <three dir hashes>' output looks good" '
+		cat <<-\EOF >expected_ls_stream &&
+			QmfNy183bXiRVyrhyWtq3TwHn79yHEkiAGFr18P7YNzESj:
+			QmSix55yz8CzWXf5ZVM9vgEvijnEeeXiTSarVtsqiiCJss 246 d1/
+			QmR3jhV4XpxxPjPT3Y8vNnWvWNvakdcT3H6vqpRBsX1MLy 1143 d2/
+			QmeomffUNfmQy76CQGy9NdmqEnnHU9soCexBnGU3ezPHVH 13 f1
+			QmNtocSs7MoDkJMc1RkyisCSKvLadujPsfJfSdJ3e1eA1M 13 f2
+
+			QmR3jhV4XpxxPjPT3Y8vNnWvWNvakdcT3H6vqpRBsX1MLy:
+			QmbQBUSRL9raZtNXfpTDeaxQapibJEG6qEY8WqAN22aUzd 1035 1024
+			QmaRGe7bVmVaLmxbrMiVNXqW4pRNNp3xq7hFtyRKA3mtJL 14 a
+
+			QmSix55yz8CzWXf5ZVM9vgEvijnEeeXiTSarVtsqiiCJss:
+			QmQNd6ubRXaNG6Prov8o6vk3bn6eWsj9FxLGrAVDUAGkGe 139 128
+			QmZULkCELmmk5XNfCgTnCyFgAVxBRBXyDHGGMVoLFLiXEN 14 a
+		EOF
+		test_cmp expected_ls_stream actual_ls_stream
+	'
+
+	test_expect_success "'ipfs ls --size=false --headers <dir hash>' output looks good" '
+		ipfs ls --size=false --headers QmR3jhV4XpxxPjPT3Y8vNnWvWNvakdcT3H6vqpRBsX1MLy >actual_ls_nosize &&
+		cat <<-\EOF >expected_ls_nosize &&
+			Hash                                           Name
+			QmbQBUSRL9raZtNXfpTDeaxQapibJEG6qEY8WqAN22aUzd 1024
+			QmaRGe7bVmVaLmxbrMiVNXqW4pRNNp3xq7hFtyRKA3mtJL a
+		EOF
+		test_cmp expected_ls_nosize actual_ls_nosize
+	'
+
+	test_expect_success "'ipfs ls --stream --enc=json' outputs an object per entry" '
+		ipfs ls --stream --enc=json QmR3jhV4XpxxPjPT3Y8vNnWvWNvakdcT3H6vqpRBsX1MLy >actual_ls_stream_json &&
+		test $(grep -c "\"Objects\"" actual_ls_stream_json) -eq 2
+	'
 }
 
 test_ls_cmd_raw_leaves() {
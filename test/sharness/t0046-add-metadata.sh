@@ -0,0 +1,74 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test add --preserve-mode and --preserve-mtime"
+
+. lib/test-lib.sh
+
+test_expect_success "creating files succeeds" '
+	mkdir -p meta/sub &&
+	echo "#!/bin/sh" > meta/script &&
+	echo "some text" > meta/sub/text &&
+	chmod 0750 meta/script &&
+	chmod 0600 meta/sub/text &&
+	chmod 0711 meta/sub &&
+	touch -t 201707140240.00 meta/script meta/sub/text meta/sub
+'
+
+test_add_metadata() {
+	test_expect_success "ipfs add without metadata flags records none" '
+		PLAIN=$(ipfs add -Q -r meta) &&
+		ipfs files stat /ipfs/$PLAIN/script > plain_stat &&
+		test_must_fail grep "Mode:" plain_stat
+	'
+
+	test_expect_success "ipfs add --preserve-mode --preserve-mtime succeeds" '
+		HASH=$(ipfs add -Q -r --preserve-mode --preserve-mtime meta) &&
+		test "$HASH" != "$PLAIN"
+	'
+
+	test_expect_success "ipfs files stat shows the recorded metadata" '
+		ipfs files stat --format="<mode>" /ipfs/$HASH/script > mode_out &&
+		echo 0750 > mode_exp &&
+		test_cmp mode_exp mode_out &&
+		ipfs files stat /ipfs/$HASH/sub > sub_stat &&
+		grep "Mode: 0711" sub_stat &&
+		grep "Mtime: " sub_stat
+	'
+
+	test_expect_success "ipfs get restores mode and mtime" '
+		rm -rf got &&
+		ipfs get -o got $HASH &&
+		test "$(generic_stat got/script)" = "$(generic_stat meta/script)" &&
+		test "$(generic_stat got/sub/text)" = "$(generic_stat meta/sub/text)" &&
+		test "$(generic_stat got/sub)" = "$(generic_stat meta/sub)" &&
+		test ! got/script -nt meta/script && test ! meta/script -nt got/script &&
+		test ! got/sub -nt meta/sub && test ! meta/sub -nt got/sub
+	'
+
+	test_expect_success "ipfs add --preserve-mode records single raw leaf files" '
+		FILE=$(ipfs add -Q --raw-leaves --preserve-mode meta/script) &&
+		ipfs files stat --format="<mode>" /ipfs/$FILE > mode_out &&
+		test_cmp mode_exp mode_out &&
+		ipfs cat $FILE > cat_out &&
+		test_cmp meta/script cat_out
+	'
+
+	test_expect_success "ipfs add --cid-profile rejects metadata flags" '
+		test_must_fail ipfs add -Q -r --cid-profile=v0 --preserve-mode meta
+	'
+}
+
+test_init_ipfs
+
+test_add_metadata
+
+test_launch_ipfs_daemon
+
+test_add_metadata
+
+test_kill_ipfs_daemon
+
+test_done
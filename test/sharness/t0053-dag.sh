@@ -112,11 +112,114 @@ test_dag_cmd() {
 		test_cmp cat_exp cat_out
 	'
 
+	test_expect_success "resolve goes through cbor objects" '
+		ipfs resolve /ipfs/$IPLDHASH/cats/1/water > resolve_out &&
+		echo "/ipfs/$HASH2" > resolve_exp &&
+		test_cmp resolve_exp resolve_out
+	'
+
+	test_expect_success "resolve stops at values inside cbor objects" '
+		ipfs resolve /ipfs/$IPLDHASH/sub/beep/1 > resolve_out &&
+		echo "/ipfs/$IPLDHASH/sub/beep/1" > resolve_exp &&
+		test_cmp resolve_exp resolve_out
+	'
+
+	test_expect_success "resolve fails on missing cbor fields" '
+		test_must_fail ipfs resolve /ipfs/$IPLDHASH/sub/missing
+	'
+
 	test_expect_success "non-canonical cbor input is normalized" '
 	HASH=$(cat ../t0053-dag-data/non-canon.cbor | ipfs dag put --format=cbor --input-enc=raw) &&
 	test $HASH = "zdpuAmxF8q6iTUtkB3xtEYzmc5Sw762qwQJftt5iW8NTWLtjC" ||
 	test_fsh echo $HASH
 	'
+
+	test_expect_success "dag put with a hash function" '
+	HASH=$(cat ipld_object | ipfs dag put --hash=blake2b-256) &&
+	ipfs cid format -f "%c %h" $HASH > dag_hash_out &&
+	echo "dag-cbor blake2b-256" > dag_hash_exp &&
+	test_cmp dag_hash_exp dag_hash_out &&
+	ipfs dag get $HASH/sub > dag_hash_get &&
+	ipfs dag get $IPLDHASH/sub > dag_hash_get_exp &&
+	test_cmp dag_hash_get_exp dag_hash_get
+	'
+
+	test_expect_success "dag put rejects unknown hash functions" '
+	test_must_fail ipfs dag put --hash=nope < ipld_object
+	'
+
+	test_expect_success "setup dag diff" '
+	rm -rf site && mkdir -p site/css &&
+	echo "index" > site/index.html &&
+	echo "old" > site/old.html &&
+	echo "a" > site/css/a.css &&
+	SITE_A=$(ipfs add -r -q site | tail -n1) &&
+	echo "new index" > site/index.html &&
+	rm site/old.html &&
+	echo "b" > site/css/b.css &&
+	SITE_B=$(ipfs add -r -q site | tail -n1)
+	'
+
+	test_expect_success "dag diff succeeds" '
+	ipfs dag diff $SITE_A $SITE_B > dag_diff_out
+	'
+
+	test_expect_success "dag diff output looks good" '
+	grep "^+ css/b.css " dag_diff_out &&
+	grep "^~ index.html " dag_diff_out &&
+	grep "^- old.html " dag_diff_out &&
+	grep "^Total: " dag_diff_out &&
+	test $(grep -c "^[-+~]" dag_diff_out) -eq 3
+	'
+
+	test_expect_success "dag diff --blocks compares blocks" '
+	ipfs dag diff --blocks $SITE_A $SITE_B > dag_diff_blocks &&
+	grep "^Blocks: .* shared" dag_diff_blocks
+	'
+
+	test_expect_success "dag patch add-link adds a directory entry" '
+	PATCHED=$(ipfs dag patch add-link $SITE_A css/c.css $HASH1) &&
+	ipfs cat $PATCHED/css/c.css > patch_out &&
+	test_cmp file1 patch_out &&
+	ipfs cat $PATCHED/index.html > patch_index &&
+	ipfs cat $SITE_A/index.html > patch_index_exp &&
+	test_cmp patch_index_exp patch_index
+	'
+
+	test_expect_success "dag patch add-link --create makes directories" '
+	test_must_fail ipfs dag patch add-link $SITE_A new/dir/f $HASH1 &&
+	PATCHED=$(ipfs dag patch add-link --create $SITE_A new/dir/f $HASH1) &&
+	ipfs cat $PATCHED/new/dir/f > patch_out &&
+	test_cmp file1 patch_out
+	'
+
+	test_expect_success "dag patch rm-link removes a directory entry" '
+	PATCHED=$(ipfs dag patch rm-link $SITE_A old.html) &&
+	test_must_fail ipfs cat $PATCHED/old.html &&
+	test_must_fail ipfs dag patch rm-link $SITE_A missing.html
+	'
+
+	test_expect_success "dag patch set sets cbor fields" '
+	PATCHED=$(ipfs dag patch set $IPLDHASH sub/dict "\"changed\"") &&
+	ipfs dag get $PATCHED/sub/dict > patch_out &&
+	echo "\"changed\"" > patch_exp &&
+	test_cmp patch_exp patch_out &&
+	ipfs dag get $PATCHED/hello > patch_out &&
+	echo "\"world\"" > patch_exp &&
+	test_cmp patch_exp patch_out
+	'
+
+	test_expect_success "dag patch follows links out of cbor nodes" '
+	SITE_LINK=$(printf "{\"site\":{\"/\":\"%s\"}}" $SITE_A | ipfs dag put) &&
+	PATCHED=$(ipfs dag patch add-link $SITE_LINK site/extra $HASH4) &&
+	ipfs cat $PATCHED/site/extra > patch_out &&
+	test_cmp file4 patch_out
+	'
+
+	test_expect_success "dag patch rm-link removes cbor fields" '
+	PATCHED=$(ipfs dag patch rm-link $IPLDHASH sub/beep) &&
+	test_must_fail ipfs dag get $PATCHED/sub/beep
+	'
 }
 
 # should work offline
@@ -125,6 +228,28 @@ test_dag_cmd
 # should work online
 test_launch_ipfs_daemon
 test_dag_cmd
+
+test_expect_success "gateway serves cbor objects as json" '
+	curl -sfo gw_out "http://127.0.0.1:$GWAY_PORT/ipfs/$IPLDHASH/sub" &&
+	printf "{\"beep\":[0,\"bop\"],\"dict\":\"ionary\"}" > gw_exp &&
+	test_cmp gw_exp gw_out
+'
+
+test_expect_success "gateway serves values inside cbor objects" '
+	curl -sfo gw_out "http://127.0.0.1:$GWAY_PORT/ipfs/$IPLDHASH/sub/beep/1" &&
+	printf "\"bop\"" > gw_exp &&
+	test_cmp gw_exp gw_out
+'
+
+test_expect_success "gateway follows links out of cbor objects" '
+	curl -sfo gw_out "http://127.0.0.1:$GWAY_PORT/ipfs/$IPLDHASH/cats/1/water" &&
+	test_cmp file2 gw_out
+'
+
+test_expect_success "gateway 404s on missing cbor fields" '
+	test_must_fail curl -sf "http://127.0.0.1:$GWAY_PORT/ipfs/$IPLDHASH/sub/missing"
+'
+
 test_kill_ipfs_daemon
 
 test_done
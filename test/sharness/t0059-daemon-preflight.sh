@@ -0,0 +1,62 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test the preflight checks of the daemon"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "listen on the same address for the API and the gateway" '
+	test_config_set Addresses.API /ip4/127.0.0.1/tcp/5099 &&
+	test_config_set Addresses.Gateway /ip4/127.0.0.1/tcp/5099
+'
+
+test_expect_success "the daemon doesn't start" '
+	test_expect_code 1 ipfs daemon >daemon_out 2>daemon_err
+'
+
+test_expect_success "the problem is explained" '
+	grep "Preflight error: Addresses.API and Addresses.Gateway both listen on /ip4/127.0.0.1/tcp/5099" daemon_out &&
+	grep "To fix it: give Addresses.Gateway another port" daemon_out &&
+	grep "preflight checks failed" daemon_err ||
+	test_fsh cat daemon_out daemon_err
+'
+
+test_expect_success "the checks can be skipped" '
+	test_expect_code 1 ipfs daemon --preflight=false >daemon_out 2>daemon_err &&
+	test_expect_code 1 grep "Preflight" daemon_out
+'
+
+test_expect_success "restore the addresses" '
+	test_config_set Addresses.API /ip4/127.0.0.1/tcp/0 &&
+	test_config_set Addresses.Gateway /ip4/127.0.0.1/tcp/0
+'
+
+test_launch_ipfs_daemon
+
+test_expect_success "the daemon started without problems" '
+	test_expect_code 1 grep "Preflight error" actual_daemon
+'
+
+test_expect_success "init a second repo using the API port of the daemon" '
+	IPFS_PATH="$(pwd)/.ipfs2" ipfs init -b=1024 >/dev/null &&
+	IPFS_PATH="$(pwd)/.ipfs2" ipfs config Addresses.API "$API_MADDR" &&
+	IPFS_PATH="$(pwd)/.ipfs2" ipfs config Addresses.Gateway /ip4/127.0.0.1/tcp/0 &&
+	IPFS_PATH="$(pwd)/.ipfs2" ipfs config --json Addresses.Swarm "[\"/ip4/127.0.0.1/tcp/0\"]"
+'
+
+test_expect_success "a busy port is reported" '
+	(
+		export IPFS_PATH="$(pwd)/.ipfs2" &&
+		test_expect_code 1 ipfs daemon >daemon_out 2>daemon_err
+	) &&
+	grep "Preflight error: can.t listen on Addresses.API $API_MADDR" daemon_out ||
+	test_fsh cat daemon_out daemon_err
+'
+
+test_kill_ipfs_daemon
+
+test_done
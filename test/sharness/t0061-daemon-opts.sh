@@ -49,4 +49,18 @@ test_expect_success 'output contains info about dht option' '
   test_fsh cat daemon_output
 '
 
+test_expect_success 'daemon should not start with ACME and no ACME plugin' '
+  ipfs config --json Gateway.TLS.ACME.Enabled true &&
+  test_must_fail ipfs daemon > daemon_output 2>&1
+'
+
+test_expect_success 'output says an ACME plugin is needed' '
+  grep "no ACME plugin is loaded" daemon_output ||
+  test_fsh cat daemon_output
+'
+
+test_expect_success 'disable ACME again' '
+  ipfs config --json Gateway.TLS.ACME.Enabled false
+'
+
 test_done
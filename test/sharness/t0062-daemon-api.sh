@@ -80,6 +80,21 @@ test_expect_success "'ipfs daemon' creates api file" '
 
 test_client_suite "(daemon on, no --api, /api file from cfg)" true false "$API_MADDR" "$api_other"
 
+test_expect_success "daemon is live" '
+	curl -s -o livez -w "%{http_code}" "http://$API_ADDR/livez" >livez_code &&
+	echo 200 >expected_code &&
+	test_cmp expected_code livez_code &&
+	grep "\"OK\":true" livez
+'
+
+test_expect_success "daemon is ready" '
+	curl -s -o readyz -w "%{http_code}" "http://$API_ADDR/readyz" >readyz_code &&
+	test_cmp expected_code readyz_code &&
+	grep "\"repo\":{\"OK\":true" readyz &&
+	grep "\"swarm\":{\"OK\":true" readyz &&
+	grep "\"bootstrap\":{\"OK\":true" readyz
+'
+
 # then, test things without daemon, with /api file
 
 test_kill_ipfs_daemon
@@ -0,0 +1,58 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test converging the daemon to a node spec"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "add an unpinned file" '
+	echo "spec content" >file &&
+	HASH=$(ipfs add -q --pin=false file)
+'
+
+test_expect_success "write the spec" '
+	cat >spec.json <<-EOF
+	{
+	  "Config": {"Datastore.StorageMax": "42GB"},
+	  "Pins": ["/ipfs/$HASH"]
+	}
+	EOF
+'
+
+test_launch_ipfs_daemon --spec=spec.json
+
+test_expect_success "the spec is applied" '
+	for i in $(test_seq 1 100); do
+		grep -q "Node spec applied" actual_daemon && break
+		go-sleep 100ms
+	done &&
+	grep "Node spec applied" actual_daemon
+'
+
+test_expect_success "the config was updated" '
+	echo 42GB >expected &&
+	ipfs config Datastore.StorageMax >actual &&
+	test_cmp expected actual
+'
+
+test_expect_success "the file was pinned" '
+	ipfs pin ls --type=recursive >actual &&
+	grep "$HASH" actual
+'
+
+test_kill_ipfs_daemon
+
+test_expect_success "write an invalid spec" '
+	echo "{\"Config\": {\"Identity.PeerID\": \"foo\"}}" >bad.json
+'
+
+test_expect_success "the daemon refuses to start" '
+	test_expect_code 1 ipfs daemon --spec=bad.json >daemon_out 2>daemon_err &&
+	grep "the identity can.t be set by a spec" daemon_err
+'
+
+test_done
@@ -0,0 +1,64 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test the --offline flag of commands against an online daemon"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+test_launch_ipfs_daemon
+
+test_expect_success "get a hash that is not in the repo" '
+	MISSING=$(echo "not stored here" | ipfs add -q -n)
+'
+
+test_expect_success "ipfs add --offline succeeds" '
+	echo "offline content" >offline.txt &&
+	HASH=$(ipfs add -q --offline offline.txt)
+'
+
+test_expect_success "ipfs cat --offline reads local blocks" '
+	ipfs cat --offline "$HASH" >actual &&
+	test_cmp offline.txt actual
+'
+
+test_expect_success "ipfs cat --offline fails for missing blocks" '
+	test_expect_code 1 ipfs cat --offline "$MISSING"
+'
+
+test_expect_success "ipfs pin ls --offline works" '
+	ipfs pin ls --offline --type=recursive "$HASH" >actual &&
+	grep "$HASH" actual
+'
+
+test_expect_success "ipfs files --offline works with local data" '
+	ipfs files cp --offline "/ipfs/$HASH" /offline.txt &&
+	ipfs files read --offline /offline.txt >actual &&
+	test_cmp offline.txt actual
+'
+
+test_expect_success "ipfs files stat --offline fails for missing blocks" '
+	test_expect_code 1 ipfs files stat --offline "/ipfs/$MISSING"
+'
+
+test_expect_success "ipfs dag --offline works with local data" '
+	DAG=$(echo "{\"a\": 1}" | ipfs dag put --offline) &&
+	ipfs dag get --offline "$DAG/a" >actual &&
+	echo 1 >expected &&
+	test_cmp expected actual
+'
+
+test_expect_success "ipfs dag get --offline fails for missing blocks" '
+	test_expect_code 1 ipfs dag get --offline "$MISSING"
+'
+
+test_expect_success "ipfs key list --offline works" '
+	ipfs key list --offline >actual &&
+	grep self actual
+'
+
+test_kill_ipfs_daemon
+
+test_done
@@ -0,0 +1,63 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test the power modes of the daemon"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "power mode requires the daemon" '
+	test_must_fail ipfs power 2>power_err &&
+	grep "must be run in online mode" power_err
+'
+
+test_launch_ipfs_daemon
+
+test_expect_success "the daemon starts in normal mode" '
+	echo normal >expected &&
+	ipfs power >actual &&
+	test_cmp expected actual
+'
+
+test_expect_success "switch to low power mode" '
+	echo low >expected &&
+	ipfs power low >actual &&
+	test_cmp expected actual &&
+	ipfs power >actual &&
+	test_cmp expected actual
+'
+
+test_expect_success "unknown modes are rejected" '
+	test_must_fail ipfs power eco 2>power_err &&
+	grep "unknown power mode" power_err &&
+	ipfs power >actual &&
+	test_cmp expected actual
+'
+
+test_expect_success "switch back to normal mode" '
+	echo normal >expected &&
+	ipfs power normal >actual &&
+	test_cmp expected actual
+'
+
+test_kill_ipfs_daemon
+
+test_expect_success "configure the daemon to start in low power mode" '
+	ipfs config Power.Mode low &&
+	ipfs config Power.WakeupInterval 1m
+'
+
+test_launch_ipfs_daemon
+
+test_expect_success "the daemon starts in low power mode" '
+	echo low >expected &&
+	ipfs power >actual &&
+	test_cmp expected actual
+'
+
+test_kill_ipfs_daemon
+
+test_done
@@ -0,0 +1,40 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test the time of day schedule of the daemon"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "configure a rule lasting all day" '
+	ipfs config --json Schedule.Rules "[{\"Name\": \"always\", \"Start\": \"00:00\", \"End\": \"00:00\", \"MaxPeerUploadRate\": \"64KB\", \"MaxConnections\": 50, \"ReproviderInterval\": \"6h\"}]"
+'
+
+test_launch_ipfs_daemon
+
+test_expect_success "the rule applies" '
+	cat >expected <<-\EOF &&
+	Rule: always
+	MaxPeerUploadRate: 64 kB/s
+	MaxConnections: 50
+	ReproviderInterval: 6h0m0s
+	EOF
+	ipfs stats schedule >actual &&
+	test_cmp expected actual
+'
+
+test_kill_ipfs_daemon
+
+test_expect_success "configure an invalid rule" '
+	ipfs config --json Schedule.Rules "[{\"Start\": \"25:00\", \"End\": \"06:00\"}]"
+'
+
+test_expect_success "the daemon refuses to start" '
+	test_expect_code 1 ipfs daemon >daemon_out 2>daemon_err &&
+	grep "invalid Schedule.Rules\[0\]" daemon_err
+'
+
+test_done
@@ -193,6 +193,23 @@ test_expect_success "'ipfs refs --unique --recursive' is correct" '
 	test_cmp expected line_count || test_fsh cat add_output || test_fsh cat refs_output
 '
 
+test_expect_success "'ipfs refs --recursive --max-depth=1' lists direct refs only" '
+	ipfs refs $ROOT >expected &&
+	ipfs refs -r --max-depth=1 $ROOT >actual &&
+	test_cmp expected actual
+'
+
+test_expect_success "'ipfs refs --recursive --max-depth=0' lists nothing" '
+	ipfs refs -r --max-depth=0 $ROOT >actual &&
+	test_must_be_empty actual
+'
+
+test_expect_success "'ipfs refs --recursive --select' follows matching links only" '
+	ipfs refs -r --select=b --format="<linkname>" $ROOT >actual &&
+	printf "b\nc\nf1\nf2\nf1\n" >expected &&
+	test_cmp expected actual
+'
+
 test_expect_success "'ipfs refs --recursive (bigger)'" '
 	mkdir -p b/c/d/e &&
 	echo "content1" >b/f &&
@@ -246,6 +263,26 @@ test_expect_success "repo stats are updated correctly" '
   test $(get_field_num "RepoSize" repo-stats-2) -ge $(get_field_num "RepoSize" repo-stats)
 '
 
+test_expect_success "'ipfs repo du' succeeds" '
+  DU_HASH=$(ipfs add -q repo-stats-2) &&
+  ipfs repo du > repo-du
+'
+
+test_expect_success "repo du came out correct" '
+  grep "Pinned" repo-du &&
+  grep "Unpinned" repo-du &&
+  grep "MFS" repo-du &&
+  grep "$DU_HASH *recursive" repo-du &&
+  grep "^/blocks" repo-du
+'
+
+test_expect_success "'ipfs repo du' attributes unpinned blocks" '
+  ipfs pin rm "$DU_HASH" &&
+  ipfs repo du --top=0 > repo-du-2 &&
+  test_must_fail grep "$DU_HASH" repo-du-2 &&
+  test $(get_field_num "Unpinned" repo-du-2) -gt $(get_field_num "Unpinned" repo-du)
+'
+
 test_expect_success "'ipfs repo version' succeeds" '
   ipfs repo version > repo-version
 '
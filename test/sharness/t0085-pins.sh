@@ -97,6 +97,40 @@ test_pin_progress() {
 	'
 }
 
+test_pin_update() {
+	test_expect_success "create two versions of a directory" '
+		mkdir -p site &&
+		echo "index" > site/index.html &&
+		echo "style" > site/style.css &&
+		SITE_V1=`ipfs add -r -q site | tail -n1` &&
+		echo "index v2" > site/index.html &&
+		SITE_V2=`ipfs add -r -q --pin=false site | tail -n1`
+	'
+
+	test_expect_success "'ipfs pin update' succeeds" '
+		ipfs pin update $SITE_V1 $SITE_V2 > update_out &&
+		echo "updated /ipfs/$SITE_V1 to /ipfs/$SITE_V2" > update_exp &&
+		test_cmp update_exp update_out
+	'
+
+	test_expect_success "new version is pinned, old one is not" '
+		ipfs pin ls --type=recursive > pinned &&
+		grep -q $SITE_V2 pinned &&
+		test_must_fail grep -q $SITE_V1 pinned
+	'
+
+	test_expect_success "'ipfs pin update --unpin=false' keeps the old pin" '
+		ipfs pin update --unpin=false $SITE_V2 $SITE_V1 &&
+		ipfs pin ls --type=recursive > pinned &&
+		grep -q $SITE_V1 pinned &&
+		grep -q $SITE_V2 pinned
+	'
+
+	test_expect_success "cleanup site pins" '
+		ipfs pin rm $SITE_V1 $SITE_V2
+	'
+}
+
 test_init_ipfs
 
 test_pins
@@ -110,6 +144,8 @@ test_pin_dag --raw-leaves
 
 test_pin_progress
 
+test_pin_update
+
 test_launch_ipfs_daemon --offline
 
 test_pins
@@ -123,6 +159,8 @@ test_pin_dag --raw-leaves
 
 test_pin_progress
 
+test_pin_update
+
 test_kill_ipfs_daemon
 
 test_done
@@ -0,0 +1,83 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test the blockstore deny list"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "add some blocks" '
+	HASH_STORED=$(echo "stored then denied" | ipfs add -q) &&
+	HASH_DENIED=$(echo "denied" | ipfs add -q --only-hash) &&
+	HASH_OTHER=$(echo "allowed" | ipfs add -q)
+'
+
+test_expect_success "configure a deny list" '
+	echo "# blocked content" >"$IPFS_PATH/denylist" &&
+	echo "$HASH_STORED" >>"$IPFS_PATH/denylist" &&
+	echo "$HASH_DENIED" >>"$IPFS_PATH/denylist" &&
+	ipfs config Datastore.DenyList denylist
+'
+
+test_expect_success "denied blocks can't be added" '
+	echo "denied" | test_must_fail ipfs add -q 2>add_err &&
+	grep "deny list" add_err
+'
+
+test_expect_success "denied blocks stored earlier are not served" '
+	test_must_fail ipfs cat "$HASH_STORED" &&
+	test_must_fail ipfs block get "$HASH_STORED"
+'
+
+test_expect_success "denied blocks are not listed" '
+	ipfs refs local >refs_out &&
+	test_must_fail grep "$HASH_STORED" refs_out &&
+	grep "$HASH_OTHER" refs_out
+'
+
+test_expect_success "other blocks are still served" '
+	ipfs cat "$HASH_OTHER" >cat_out &&
+	echo "allowed" >cat_exp &&
+	test_cmp cat_exp cat_out
+'
+
+test_expect_success "denied blocks stored earlier were deleted" '
+	ipfs config Datastore.DenyList "" &&
+	test_must_fail ipfs block stat "$HASH_STORED"
+'
+
+test_expect_success "enable the filestore" '
+	ipfs config --json Experimental.FilestoreEnabled true
+'
+
+test_expect_success "denied blocks can't be added with --nocopy" '
+	echo "denied" >denied_file &&
+	HASH_DENIED_RAW=$(ipfs add -q --only-hash --raw-leaves denied_file) &&
+	echo "$HASH_DENIED_RAW" >>"$IPFS_PATH/denylist" &&
+	test_must_fail ipfs add -q --nocopy --raw-leaves denied_file 2>nocopy_err &&
+	grep "deny list" nocopy_err &&
+	ipfs filestore ls >filestore_out &&
+	test_must_fail grep "$HASH_DENIED_RAW" filestore_out
+'
+
+test_expect_success "references added with --nocopy then denied are not served" '
+	echo "nocopy then denied" >nocopy_file &&
+	HASH_NOCOPY=$(ipfs add -q --nocopy --raw-leaves nocopy_file) &&
+	echo "$HASH_NOCOPY" >>"$IPFS_PATH/denylist" &&
+	test_must_fail ipfs cat "$HASH_NOCOPY" &&
+	test_must_fail ipfs block stat "$HASH_NOCOPY" &&
+	ipfs config --json Experimental.FilestoreEnabled false
+'
+
+test_expect_success "an invalid deny list keeps the node from starting" '
+	echo "notacid" >"$IPFS_PATH/denylist" &&
+	ipfs config Datastore.DenyList denylist &&
+	test_must_fail ipfs refs local 2>start_err &&
+	grep "invalid Datastore.DenyList" start_err &&
+	ipfs config Datastore.DenyList ""
+'
+
+test_done
@@ -0,0 +1,75 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test the hooks run around pin, name and key operations"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "write the hook programs" '
+	printf "#!/bin/sh\necho \"key creation is managed centrally\" >&2\nexit 1\n" >veto.sh &&
+	printf "#!/bin/sh\n{ echo \"\$IPFS_HOOK_STAGE \$IPFS_HOOK_OP\"; cat; echo; } >>\"%s\"\n" "$(pwd)/hook_log" >log.sh &&
+	chmod +x veto.sh log.sh
+'
+
+test_expect_success "programs can't be configured through 'ipfs config'" '
+	test_must_fail ipfs config --json Hooks "[{\"Ops\": [\"pin/add\"], \"Exec\": [\"$(pwd)/log.sh\"]}]" 2>config_err &&
+	grep "edit the config file" config_err
+'
+
+test_expect_success "configure the hooks in the config file" '
+	{
+		echo "{\"Hooks\": [" &&
+		echo "{\"Ops\": [\"key/gen\"], \"Exec\": [\"$(pwd)/veto.sh\"]}," &&
+		echo "{\"Ops\": [\"pin/add\"], \"Exec\": [\"$(pwd)/log.sh\"]}," &&
+		echo "{\"Ops\": [\"*\"], \"Exec\": [\"$(pwd)/log.sh\"], \"Post\": true}" &&
+		echo "]," &&
+		sed 1d "$IPFS_PATH/config"
+	} >hooked_config &&
+	mv hooked_config "$IPFS_PATH/config"
+'
+
+test_expect_success "a hook vetoes 'ipfs key gen'" '
+	test_must_fail ipfs key gen --type=ed25519 denied 2>key_err &&
+	grep "key/gen vetoed by hook" key_err &&
+	grep "key creation is managed centrally" key_err &&
+	ipfs key list >keys &&
+	test_must_fail grep denied keys
+'
+
+test_expect_success "'ipfs pin add' runs the hooks before and after" '
+	HASH=$(echo "hooked" | ipfs add -q --pin=false) &&
+	ipfs pin add "$HASH" &&
+	grep "^pre pin/add" hook_log &&
+	grep "^post pin/add" hook_log &&
+	grep "\"Cid\":{\"/\":\"$HASH\"},\"Recursive\":true" hook_log
+'
+
+test_expect_success "'ipfs add' runs the pin hooks" '
+	ADDED=$(echo "added and hooked" | ipfs add -q) &&
+	grep "\"Cid\":{\"/\":\"$ADDED\"}" hook_log
+'
+
+test_expect_success "'ipfs name publish' runs the hooks around the record" '
+	ipfs name publish "/ipfs/$HASH" &&
+	grep "^post name/publish" hook_log &&
+	grep "\"Value\":\"/ipfs/$HASH\",.*\"How\":\"key\"" hook_log
+'
+
+test_expect_success "the hooks after an operation get its error" '
+	UNPINNED=$(echo "not pinned" | ipfs add -q --pin=false) &&
+	test_must_fail ipfs pin rm "$UNPINNED" &&
+	grep "^post pin/rm" hook_log &&
+	grep "\"Cid\":{\"/\":\"$UNPINNED\"}.*\"Error\":" hook_log
+'
+
+test_expect_success "invalid hooks are reported" '
+	sed -i"~" -e "s|\"pin/add\"|\"pin/ls\"|" "$IPFS_PATH/config" &&
+	test_must_fail ipfs pin add "$HASH" 2>invalid_err &&
+	grep "unknown operation" invalid_err
+'
+
+test_done
@@ -90,4 +90,182 @@ test_expect_success "publish an explicit node ID as key name looks good" '
 	test_cmp expected_node_id_publish actual_node_id_publish
 '
 
+# update of a dnslink record
+
+test_expect_success "configure a dnslink domain" '
+	ipfs config --json DNSLink "{\"Domain\": \"example.com\", \"DryRun\": true}"
+'
+
+test_expect_success "'ipfs name publish' reports the dnslink update" '
+	PEERID=`ipfs id --format="<id>"` &&
+	ipfs name publish "/ipfs/$HASH_WELCOME_DOCS" >actual_dnslink &&
+	echo "Published to ${PEERID}: /ipfs/$HASH_WELCOME_DOCS" >expected_dnslink &&
+	echo "Would update _dnslink.example.com: dnslink=/ipfs/$HASH_WELCOME_DOCS" >>expected_dnslink &&
+	test_cmp expected_dnslink actual_dnslink
+'
+
+test_expect_success "publishing with another key leaves the record alone" '
+	ipfs name publish --key=keyname "/ipfs/$HASH_WELCOME_DOCS" >actual_dnslink &&
+	test_must_fail grep _dnslink actual_dnslink
+'
+
+test_expect_success "--dnslink=false leaves the record alone" '
+	ipfs name publish --dnslink=false "/ipfs/$HASH_WELCOME_DOCS" >actual_dnslink &&
+	test_must_fail grep _dnslink actual_dnslink
+'
+
+test_expect_success "publishing fails early without a dnslink provider" '
+	ipfs config --json DNSLink.DryRun false &&
+	test_must_fail ipfs name publish "/ipfs/$HASH_WELCOME_DOCS" 2>dnslink_err &&
+	grep "no provider configured" dnslink_err
+'
+
+test_expect_success "--dnslink-dry-run does not need a provider" '
+	ipfs name publish --dnslink-dry-run "/ipfs/$HASH_WELCOME_DOCS" >actual_dnslink &&
+	test_cmp expected_dnslink actual_dnslink
+'
+
+# publish under a delegation
+
+test_expect_success "'ipfs name delegate' succeeds" '
+	ipfs key gen --type=ed25519 deploy &&
+	DEPLOY_PUBKEY=$(ipfs name delegate pubkey --key=deploy) &&
+	DELEGATION=$(ipfs name delegate --key=keyname --lifetime=48h "$DEPLOY_PUBKEY")
+'
+
+test_expect_success "'ipfs name publish --delegation' publishes to the delegating name" '
+	ipfs name publish --key=deploy --delegation="$DELEGATION" "/ipfs/$HASH_WELCOME_DOCS/about" >actual_delegated &&
+	echo "Published to ${NEWID}: /ipfs/$HASH_WELCOME_DOCS/about" >expected_delegated &&
+	test_cmp expected_delegated actual_delegated
+'
+
+test_expect_success "the delegated record resolves" '
+	ipfs name resolve "$NEWID" >actual_delegated &&
+	echo "/ipfs/$HASH_WELCOME_DOCS/about" >expected_delegated &&
+	test_cmp expected_delegated actual_delegated
+'
+
+test_expect_success "records can't outlive their delegation" '
+	test_must_fail ipfs name publish --key=deploy --delegation="$DELEGATION" --lifetime=72h "/ipfs/$HASH_WELCOME_DOCS" 2>delegated_err &&
+	grep "exceeds the delegation" delegated_err
+'
+
+test_expect_success "delegations only work with the key they are for" '
+	test_must_fail ipfs name publish --key=keyname --delegation="$DELEGATION" "/ipfs/$HASH_WELCOME_DOCS"
+'
+
+# publish records signed by several keys
+
+test_expect_success "'ipfs name multisig policy' succeeds" '
+	REGISTRY_ID=$(ipfs key gen --type=ed25519 registry) &&
+	for k in alice bob carol; do
+		ipfs key gen --type=ed25519 $k || return 1
+	done &&
+	POLICY=$(ipfs name multisig policy --key=registry --threshold=2 \
+		$(ipfs name delegate pubkey --key=alice) \
+		$(ipfs name delegate pubkey --key=bob) \
+		$(ipfs name delegate pubkey --key=carol))
+'
+
+test_expect_success "a record with too few signatures can't be published" '
+	RECORD=$(ipfs name multisig propose --key=alice --policy="$POLICY" "/ipfs/$HASH_WELCOME_DOCS/about") &&
+	test_must_fail ipfs name multisig publish "$RECORD" 2>multisig_err &&
+	grep "not enough signatures" multisig_err
+'
+
+test_expect_success "keys outside the policy can't sign" '
+	test_must_fail ipfs name multisig sign --key=deploy "$RECORD"
+'
+
+test_expect_success "a record with enough signatures is published" '
+	RECORD=$(ipfs name multisig sign --key=carol "$RECORD") &&
+	ipfs name multisig publish "$RECORD" >actual_multisig &&
+	echo "Published to ${REGISTRY_ID}: /ipfs/$HASH_WELCOME_DOCS/about" >expected_multisig &&
+	test_cmp expected_multisig actual_multisig
+'
+
+test_expect_success "the multisig record resolves" '
+	ipfs name resolve "$REGISTRY_ID" >actual_multisig &&
+	echo "/ipfs/$HASH_WELCOME_DOCS/about" >expected_multisig &&
+	test_cmp expected_multisig actual_multisig
+'
+
+# publish records signed elsewhere
+
+test_expect_success "'ipfs name put' refuses a record already published" '
+	test_must_fail ipfs name put "$REGISTRY_ID" "$RECORD" 2>put_err &&
+	grep "record sequence number is older than the last one seen" put_err
+'
+
+test_expect_success "'ipfs name put' publishes a signed record" '
+	RECORD=$(ipfs name multisig propose --key=alice --policy="$POLICY" "/ipfs/$HASH_WELCOME_DOCS/about") &&
+	RECORD=$(ipfs name multisig sign --key=bob "$RECORD") &&
+	ipfs name put "$REGISTRY_ID" "$RECORD" >actual_put &&
+	echo "Published to ${REGISTRY_ID}: /ipfs/$HASH_WELCOME_DOCS/about" >expected_put &&
+	test_cmp expected_put actual_put
+'
+
+test_expect_success "'ipfs name put' refuses a record of another name" '
+	test_must_fail ipfs name put "$PEERID" "$RECORD" 2>put_err &&
+	grep "the public key is not the one of $PEERID" put_err
+'
+
+test_expect_success "'ipfs name put' refuses an invalid record" '
+	test_must_fail ipfs name put "$REGISTRY_ID" "bm90IGEgcmVjb3Jk"
+'
+
+# inspect the records stored locally
+
+test_expect_success "'ipfs name local ls' lists the published records" '
+	ipfs name local ls >local_ls &&
+	grep "^$PEERID .* own" local_ls &&
+	grep "^$REGISTRY_ID .*/ipfs/$HASH_WELCOME_DOCS/about" local_ls
+'
+
+test_expect_success "'ipfs name local rm' keeps own records without --force" '
+	test_must_fail ipfs name local rm "$REGISTRY_ID" 2>local_rm_err &&
+	grep "use --force" local_rm_err &&
+	ipfs name local ls >local_ls &&
+	grep "^$REGISTRY_ID " local_ls
+'
+
+test_expect_success "'ipfs name local rm --force' removes own records" '
+	ipfs name local rm --force "$REGISTRY_ID" &&
+	ipfs name local ls >local_ls &&
+	test_must_fail grep "^$REGISTRY_ID " local_ls
+'
+
+test_expect_success "'ipfs name local rm' needs names or --expired" '
+	test_must_fail ipfs name local rm &&
+	ipfs name local rm --expired
+'
+
+# test the global timeout on a command that never ends by itself
+
+test_expect_success "'ipfs name publish' of a log file succeeds" '
+	echo "first line" >log_file &&
+	LOG_HASH=$(ipfs add -q log_file) &&
+	ipfs name publish "/ipfs/$LOG_HASH"
+'
+
+test_expect_success "'ipfs cat --tail' stops at the --timeout deadline" '
+	test_expect_code 1 ipfs --timeout=1s cat --tail --tail-interval=100ms "/ipns/$PEERID" >tail_out 2>tail_err
+'
+
+test_expect_success "'ipfs cat --tail' output looks good" '
+	test_cmp log_file tail_out &&
+	grep "command timed out after 1s" tail_err
+'
+
+test_expect_success "a lifetime longer than Ipns.MaxRecordLifetime is refused" '
+	ipfs config Ipns.MaxRecordLifetime 24h &&
+	test_must_fail ipfs name publish --lifetime=48h "/ipfs/$HASH_WELCOME_DOCS" 2>lifetime_err &&
+	grep "record lifetime exceeds the maximum" lifetime_err
+'
+
+test_expect_success "a lifetime within Ipns.MaxRecordLifetime is accepted" '
+	ipfs name publish --lifetime=12h "/ipfs/$HASH_WELCOME_DOCS" &&
+	ipfs config --json Ipns.MaxRecordLifetime "\"\""
+'
+
 test_done
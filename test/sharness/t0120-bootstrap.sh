@@ -170,6 +170,45 @@ test_bootstrap_cmd() {
 # should work offline
 test_bootstrap_cmd
 
+test_expect_success "'ipfs bootstrap profile save' succeeds" '
+  ipfs bootstrap add "$BP1" "$BP2" &&
+  ipfs bootstrap profile save main &&
+  ipfs bootstrap profile save other "$BP3" >save_actual &&
+  echo "$BP3" >save_expected &&
+  test_cmp save_expected save_actual
+'
+
+test_expect_success "'ipfs bootstrap profile ls' lists the profiles" '
+  ipfs config BootstrapFallback other &&
+  ipfs bootstrap profile ls >profiles_actual &&
+  printf "main\nother (fallback)\n" >profiles_expected &&
+  test_cmp profiles_expected profiles_actual
+'
+
+test_expect_success "'ipfs bootstrap profile show' shows the peers" '
+  ipfs bootstrap profile show main >show_actual &&
+  printf "$BP1\n$BP2\n" >show_expected &&
+  test_cmp show_expected show_actual
+'
+
+test_expect_success "'ipfs bootstrap profile use' replaces the bootstrap list" '
+  ipfs bootstrap profile use other &&
+  ipfs bootstrap list >list_actual &&
+  test_cmp save_expected list_actual
+'
+
+test_expect_success "the fallback profile can't be removed" '
+  test_must_fail ipfs bootstrap profile rm other &&
+  ipfs bootstrap profile rm main &&
+  test_must_fail ipfs bootstrap profile show main
+'
+
+test_expect_success "clean up the profiles" '
+  ipfs config BootstrapFallback "" &&
+  ipfs bootstrap profile rm other &&
+  ipfs bootstrap rm --all
+'
+
 # should work online
 test_launch_ipfs_daemon
 test_bootstrap_cmd
@@ -66,6 +66,16 @@ test_expect_success "set bootstrap addrs" '
 	ipfsi 4 bootstrap add $BADDR
 '
 
+# node 4 only reaches the bootstrap node through its fallback profile
+BADPEER="/ip4/127.0.0.1/tcp/1/ipfs/QmaCpDMGvV2BGHeYERUEnRQAwe3N8SzbUtfsmvsqQLuvuJ"
+
+test_expect_success "set a fallback profile" '
+	ipfsi 4 bootstrap profile save local $BADDR &&
+	ipfsi 4 config BootstrapFallback local &&
+	ipfsi 4 bootstrap rm --all &&
+	ipfsi 4 bootstrap add $BADPEER
+'
+
 test_expect_success "start up iptb nodes" '
 	iptb start --wait
 '
@@ -78,6 +88,21 @@ test_expect_success "correct number of peers" '
 	test `cat peers_out | wc -l` = 5
 '
 
+test_expect_success "'ipfs bootstrap check' reports reachable peers" '
+	ipfsi 0 bootstrap check >check_out &&
+	grep "^$BADDR  *ok " check_out
+'
+
+test_expect_success "'ipfs bootstrap check' reports unreachable peers" '
+	ipfsi 4 bootstrap check --timeout=2s >check_out &&
+	grep "^$BADPEER  *unreachable" check_out
+'
+
+test_expect_success "'ipfs bootstrap check --profile' checks the profile" '
+	ipfsi 4 bootstrap check --profile=local >check_out &&
+	grep "^$BADDR  *ok " check_out
+'
+
 test_kill_ipfs_daemon
 
 test_expect_success "bring down iptb nodes" '
@@ -0,0 +1,54 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test the peers saved across restarts"
+
+. lib/test-lib.sh
+
+test_init_ipfs
+
+test_expect_success "disable mdns and bootstrap" '
+	ipfs config Discovery.MDNS.Enabled false --json &&
+	ipfs bootstrap rm --all
+'
+
+test_expect_success "start an iptb node" '
+	iptb init -n 1 -f --bootstrap=none --port=0 &&
+	iptb start &&
+	PEERID_0=$(iptb get id 0) &&
+	ADDR_0="$(ipfsi 0 id -f "<addrs>" | head -n 1)/ipfs/$PEERID_0"
+'
+
+test_launch_ipfs_daemon
+
+test_expect_success "connect to the iptb node" '
+	ipfs swarm connect "$ADDR_0"
+'
+
+# the peers still connected are saved on shutdown
+test_kill_ipfs_daemon
+
+test_launch_ipfs_daemon
+
+wait_for_peer() {
+	for i in $(test_seq 1 20); do
+		ipfs swarm peers | grep "$1" && return 0
+		sleep 0.5
+	done
+	return 1
+}
+
+test_expect_success "the restarted node reconnects to the saved peer" '
+	wait_for_peer "$PEERID_0" ||
+	test_fsh ipfs swarm peers
+'
+
+test_kill_ipfs_daemon
+
+test_expect_success "stop the iptb node" '
+	iptb stop
+'
+
+test_done
@@ -38,6 +38,12 @@ test_expect_success "output looks good" '
 	grep PublicKey output
 '
 
+test_expect_success "ipfs id shows the reachability" '
+	ipfs id -f="<reach>\\n" >reach_out &&
+	echo unknown >reach_exp &&
+	test_cmp reach_exp reach_out
+'
+
 addr="/ip4/127.0.0.1/tcp/9898/ipfs/QmUWKoHbjsqsSMesRC2Zoscs8edyFz6F77auBB1YBBhgpX"
 
 test_expect_success "cant trigger a dial backoff with swarm connect" '
@@ -0,0 +1,67 @@
+#!/bin/sh
+
+test_description="Test ipfs swarm peers connection details"
+
+. lib/test-lib.sh
+
+NUM_NODES=2
+test_expect_success 'init iptb' '
+	iptb init -n $NUM_NODES --bootstrap=none --port=0
+'
+
+startup_cluster $NUM_NODES
+
+test_expect_success 'peer ids' '
+	PEERID_0=$(iptb get id 0) &&
+	PEERID_1=$(iptb get id 1)
+'
+
+test_expect_success 'swarm peers lists transport' '
+	ipfsi 1 swarm peers --enc=json >peers_out &&
+	grep "\"Transport\":\"tcp\"" peers_out
+'
+
+test_expect_success 'direction is hidden by default' '
+	test_expect_code 1 grep "Direction" peers_out
+'
+
+test_expect_success 'dialing node sees an outbound connection' '
+	ipfsi 1 swarm peers --direction >peers_out &&
+	grep "$PEERID_0 outbound" peers_out
+'
+
+test_expect_success 'dialed node sees an inbound connection' '
+	ipfsi 0 swarm peers --direction --enc=json >peers_out &&
+	grep "\"Direction\":\"inbound\"" peers_out &&
+	grep "\"Age\":" peers_out
+'
+
+test_expect_success 'swarm peers --streams works' '
+	ipfsi 0 swarm peers --streams --enc=json >peers_out &&
+	grep "\"Streams\":" peers_out
+'
+
+test_expect_success 'swarm audit counts the connected peers' '
+	ipfsi 0 swarm audit >audit_out &&
+	grep "Sampled 1 of 1 connected peers" audit_out &&
+	grep "1  tcp" audit_out &&
+	grep "1  go-ipfs/" audit_out ||
+	test_fsh cat audit_out
+'
+
+test_expect_success 'swarm audit exports json' '
+	ipfsi 1 swarm audit --enc=json >audit_out &&
+	grep "\"Connected\":1" audit_out &&
+	grep "\"Agents\":\[{\"Value\":\"go-ipfs/" audit_out ||
+	test_fsh cat audit_out
+'
+
+test_expect_success 'swarm audit rejects a negative sample' '
+	test_must_fail ipfsi 0 swarm audit --sample=-1
+'
+
+test_expect_success 'stop iptb' '
+	iptb stop
+'
+
+test_done
@@ -0,0 +1,55 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test the self-probes and 'ipfs diag probes'"
+
+. lib/test-lib.sh
+
+NUM_NODES=4
+test_expect_success 'init iptb' '
+	iptb init -n $NUM_NODES --bootstrap=none --port=0
+'
+
+test_expect_success 'enable the probes of node 0' '
+	ipfsi 0 config --json Probes.Enabled true &&
+	ipfsi 0 config Probes.Timeout 30s
+'
+
+startup_cluster $NUM_NODES
+
+test_expect_success "'ipfs diag probes' fails when the probes are disabled" '
+	test_must_fail ipfsi 1 diag probes 2>probes_err &&
+	grep "Probes.Enabled" probes_err
+'
+
+test_expect_success 'nothing was probed yet' '
+	ipfsi 0 diag probes >probes_out &&
+	test_line_count = 1 probes_out
+'
+
+test_expect_success 'add and publish content on node 0' '
+	echo "probed content" >afile &&
+	HASH=$(ipfsi 0 add -q afile) &&
+	ipfsi 0 name publish $HASH
+'
+
+test_expect_success "'ipfs diag probes --run' runs the probes" '
+	ipfsi 0 diag probes --run -v >probes_out &&
+	grep "^provider *1 *0 " probes_out &&
+	grep "^ipns *1 *0 " probes_out
+'
+
+# the probed block is picked at random among the pinned ones
+test_expect_success "'ipfs diag probes -v' lists the probed block and name" '
+	PEERID_0=$(iptb get id 0) &&
+	grep " provider *Qm[1-9A-Za-z]* " probes_out &&
+	grep " ipns */ipns/$PEERID_0 " probes_out
+'
+
+test_expect_success 'stop iptb' '
+	iptb stop
+'
+
+test_done
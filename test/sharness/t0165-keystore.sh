@@ -67,6 +67,33 @@ test_key_cmd() {
 		test_must_fail ipfs key rename -f fooed self 2>&1 | tee key_rename_out &&
 		grep -q "Error: cannot overwrite key with name" key_rename_out
 	'
+
+	test_expect_success "key rotate requires the old key name" '
+		test_must_fail ipfs key rotate --type=ed25519 2>&1 | tee key_rotate_out &&
+		grep -q "Error: please name the old key with --oldkey" key_rotate_out
+	'
+
+	test_expect_success "key rotate replaces the identity" '
+		OLD_ID="$(ipfs config Identity.PeerID)" &&
+		ipfs key rotate --type=ed25519 -o oldself &&
+		NEW_ID="$(ipfs config Identity.PeerID)" &&
+		test "$OLD_ID" != "$NEW_ID" &&
+		ipfs id -f "<id>" >id_out &&
+		echo "$NEW_ID" >id_exp &&
+		test_cmp id_exp id_out
+	'
+
+	test_expect_success "key rotate keeps the old key" '
+		ipfs key list -l | grep "$OLD_ID oldself" &&
+		ipfs config Ipns.RepublishKeys >republish_out &&
+		grep -q oldself republish_out
+	'
+
+	test_expect_success "key rotate can't overwrite a key" '
+		test_must_fail ipfs key rotate -o oldself 2>&1 | tee key_rotate_out &&
+		grep -q "Error: a key named oldself already exists" key_rotate_out &&
+		test "$(ipfs config Identity.PeerID)" = "$NEW_ID"
+	'
 }
 
 test_key_cmd
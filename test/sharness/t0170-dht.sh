@@ -51,6 +51,55 @@ test_expect_success 'get' '
 	test_fsh cat actual
 '
 
+# ipfs dht table, ipfs stats dht
+test_expect_success 'table lists the connected peers' '
+  ipfsi 0 dht table >table &&
+  grep "$(iptb get id 1)" table &&
+  grep "$(iptb get id 2)" table &&
+  grep "$(iptb get id 3)" table &&
+  grep "$(iptb get id 4)" table ||
+	test_fsh cat table
+'
+
+test_expect_success 'table filters by bucket' '
+  BUCKET=$(head -n 1 table | cut -d " " -f 1) &&
+  ipfsi 0 dht table --bucket=$BUCKET >bucket &&
+  grep -v "^$BUCKET " bucket >others;
+  [ -s bucket ] && [ ! -s others ] ||
+	test_fsh cat bucket
+'
+
+test_expect_success 'stats dht counts the routing table peers' '
+  ipfsi 0 stats dht >stats &&
+  grep "Routing table" stats &&
+  grep "total *4" stats ||
+	test_fsh cat stats
+'
+
+test_expect_success 'stats dht has a json output' '
+  ipfsi 3 stats dht --enc=json >stats.json &&
+  grep "\"Buckets\"" stats.json &&
+  grep "\"Queries\"" stats.json ||
+	test_fsh cat stats.json
+'
+
+# ipfs ping <peerID>
+test_expect_success 'ping reports statistics' '
+  ipfsi 1 ping -n 2 $PEERID_0 >ping &&
+  grep "2 pings sent, 2 pongs received, 0% loss" ping ||
+	test_fsh cat ping
+'
+
+test_expect_success 'ping streams json' '
+  ipfsi 1 ping -n 2 --enc=json $PEERID_0 >ping.json &&
+  grep "\"Received\":2" ping.json ||
+	test_fsh cat ping.json
+'
+
+test_expect_success 'ping fails for an unreachable peer' '
+  test_must_fail ipfsi 1 ping -n 1 QmSoLer265NRgSp2LA3dPaeykiS1J6DifTC88f5uVQKNAd
+'
+
 # ipfs dht query <peerID>
 ## We query 3 different keys, to statisically lower the chance that the queryer
 ## turns out to be the closest to what a key hashes to.
@@ -0,0 +1,44 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test the bitswap serving limits"
+
+. lib/test-lib.sh
+
+NUM_NODES=3
+test_expect_success 'init iptb' '
+	iptb init -n $NUM_NODES --bootstrap=none --port=0
+'
+
+test_expect_success 'node 0 only serves node 1' '
+	PEERID_1=$(iptb get id 1) &&
+	ipfsi 0 config Bitswap.ServeMode allowlist &&
+	ipfsi 0 config --json Bitswap.ServeAllowlist "[\"$PEERID_1\"]" &&
+	ipfsi 0 config Bitswap.MaxPeerUploadRate 1MB &&
+	ipfsi 0 config --json Bitswap.MaxConcurrentSends 2
+'
+
+startup_cluster $NUM_NODES
+
+test_expect_success 'add a file on node 0' '
+	random 100000 42 >file &&
+	HASH=$(ipfsi 0 add -q file)
+'
+
+# before node 1 has the file, or node 2 would get it from there
+test_expect_success 'other peers are not served' '
+	test_must_fail ipfsi 2 cat --timeout=2s $HASH
+'
+
+test_expect_success 'peers on the allowlist are served' '
+	ipfsi 1 cat --timeout=10s $HASH >file1 &&
+	test_cmp file file1
+'
+
+test_expect_success 'stop iptb' '
+	iptb stop
+'
+
+test_done
@@ -128,6 +128,23 @@ test_files_api() {
 		test_cmp expected actual
 	'
 
+	test_expect_success "stat --with-local reports everything local" '
+		ipfs files stat --with-local / >stat_local &&
+		grep -q "^Local: .* (100.00%)$" stat_local
+	'
+
+	test_expect_success "stat --with-local counts repeated blocks" '
+		ZEROS=$(dd if=/dev/zero bs=1024 count=64 | ipfs add -q --chunker=size-1024) &&
+		ipfs files stat --with-local "/ipfs/$ZEROS" >stat_zeros &&
+		grep -q "^Local: .* (100.00%)$" stat_zeros
+	'
+
+	test_expect_success "stat works on /ipfs/ paths" '
+		ipfs files stat --hash / >expected &&
+		ipfs files stat --hash "/ipfs/$(cat expected)" >actual &&
+		test_cmp expected actual
+	'
+
 	test_expect_success "check root hash" '
 		ipfs files stat --hash / > roothash
 	'
@@ -534,4 +551,35 @@ test_launch_ipfs_daemon
 test_sharding
 test_kill_ipfs_daemon
 
+test_expect_success "files mkdir and write accept a hash function" '
+	ipfs files mkdir --hash=blake2b-256 /blakedir &&
+	echo "blake" | ipfs files write --create --hash=blake2b-256 /blakedir/file &&
+	ipfs files stat --hash /blakedir | ipfs cid format -f "%v %h" > blake_dir_out &&
+	ipfs files stat --hash /blakedir/file | ipfs cid format -f "%v %h" > blake_file_out &&
+	echo "cidv1 blake2b-256" > blake_exp &&
+	test_cmp blake_exp blake_dir_out &&
+	test_cmp blake_exp blake_file_out
+'
+
+test_expect_success "rewriting a file keeps its hash function" '
+	echo "blake again" | ipfs files write --truncate /blakedir/file &&
+	ipfs files stat --hash /blakedir/file | ipfs cid format -f "%v %h" > blake_file_out &&
+	test_cmp blake_exp blake_file_out &&
+	ipfs files read /blakedir/file > blake_read &&
+	echo "blake again" > blake_read_exp &&
+	test_cmp blake_read_exp blake_read
+'
+
+test_expect_success "files use the configured hash function" '
+	ipfs config Unixfs.HashFunction blake2b-256 &&
+	ipfs files mkdir /confdir &&
+	ipfs files stat --hash /confdir | ipfs cid format -f "%v %h" > conf_dir_out &&
+	test_cmp blake_exp conf_dir_out
+'
+
+test_expect_success "clean up" '
+	ipfs config Unixfs.HashFunction sha2-256 &&
+	ipfs files rm -r /blakedir /confdir
+'
+
 test_done
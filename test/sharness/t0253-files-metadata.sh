@@ -0,0 +1,78 @@
+#!/bin/sh
+#
+# MIT Licensed; see the LICENSE file in this repository.
+#
+
+test_description="Test ipfs files chmod and touch"
+
+. lib/test-lib.sh
+
+test_files_metadata() {
+	test_expect_success "create a file and a directory" '
+		{ ipfs files rm -r /meta 2>/dev/null || true; } &&
+		ipfs files mkdir /meta &&
+		echo "some text" | ipfs files write --create /meta/text &&
+		ROOT=$(ipfs files stat --hash /)
+	'
+
+	test_expect_success "ipfs files chmod records the mode" '
+		ipfs files chmod 0640 /meta/text &&
+		echo 0640 > mode_exp &&
+		ipfs files stat --format="<mode>" /meta/text > mode_out &&
+		test_cmp mode_exp mode_out &&
+		test "$(ipfs files stat --hash /)" != "$ROOT"
+	'
+
+	test_expect_success "ipfs files touch records the mtime and keeps the mode" '
+		ipfs files touch --mtime=1500000000 /meta/text &&
+		echo 2017-07-14T02:40:00Z > mtime_exp &&
+		ipfs files stat --format="<mtime>" /meta/text > mtime_out &&
+		test_cmp mtime_exp mtime_out &&
+		ipfs files stat --format="<mode>" /meta/text > mode_out &&
+		test_cmp mode_exp mode_out
+	'
+
+	test_expect_success "ipfs files chmod keeps the mtime" '
+		ipfs files chmod 4755 /meta &&
+		ipfs files touch -m 1500000000 /meta &&
+		ipfs files chmod 0711 /meta &&
+		ipfs files stat /meta > dir_stat &&
+		grep "Mode: 0711" dir_stat &&
+		grep "Mtime: 2017-07-14T02:40:00Z" dir_stat
+	'
+
+	test_expect_success "the content is unchanged" '
+		echo "some text" > text_exp &&
+		ipfs files read /meta/text > text_out &&
+		test_cmp text_exp text_out
+	'
+
+	test_expect_success "clearing the metadata restores the hash" '
+		ipfs files chmod 0 /meta/text &&
+		ipfs files touch --clear /meta/text &&
+		ipfs files chmod 0 /meta &&
+		ipfs files touch --clear /meta &&
+		test "$(ipfs files stat --hash /)" = "$ROOT"
+	'
+
+	test_expect_success "invalid modes are rejected" '
+		test_must_fail ipfs files chmod 0999 /meta 2> chmod_err &&
+		grep "invalid mode" chmod_err
+	'
+
+	test_expect_success "missing paths are rejected" '
+		test_must_fail ipfs files touch /meta/nothere
+	'
+}
+
+test_init_ipfs
+
+test_files_metadata
+
+test_launch_ipfs_daemon
+
+test_files_metadata
+
+test_kill_ipfs_daemon
+
+test_done
@@ -55,6 +55,17 @@ test_expect_success "sharded and unsharded output look the same" '
 	test_cmp sharded_out unsharded_out
 '
 
+test_expect_success "streamed sharded output looks the same" '
+	ipfs ls --stream "$SHARDED" | sort > sharded_stream_out &&
+	test_cmp sharded_stream_out unsharded_out
+'
+
+test_expect_success "streamed sharded listing without sizes nor types succeeds" '
+	ipfs ls --stream --size=false --resolve-type=false "$SHARDED" > sharded_bare_out &&
+	test_line_count = 2000 sharded_bare_out &&
+	grep -q " file20$" sharded_bare_out
+'
+
 test_expect_success "ipfs cat error output the same" '
 	test_expect_code 1 ipfs cat "$SHARDED" 2> sharded_err &&
 	test_expect_code 1 ipfs cat "$UNSHARDED" 2> unsharded_err &&
@@ -0,0 +1,57 @@
+#!/bin/sh
+
+test_description="Test cid commands"
+
+. lib/test-lib.sh
+
+# note: all "ipfs cid" commands should work without requiring a repo
+
+CIDv0="QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"
+CIDv1="bafybeiczsscdsbs7ffqz55asqdf3smv6klcw3gofszvwlyarci47bgf354"
+
+test_expect_success "cid format works" '
+	echo "cidv0-protobuf-sha2-256-32" > prefix_exp &&
+	ipfs cid format -f "%P" $CIDv0 > prefix_actual &&
+	test_cmp prefix_exp prefix_actual
+'
+
+test_expect_success "cid format shows the multihash" '
+	echo "sha2-256 32 59948439065f29619ef41280cbb932be52c56d99c5966b65e0111239f098bbef" > mh_exp &&
+	ipfs cid format -f "%h %L %d" $CIDv0 > mh_actual &&
+	test_cmp mh_exp mh_actual
+'
+
+test_expect_success "cid format converts between versions" '
+	ipfs cid format -v 1 -b base32 $CIDv0 > v1_actual &&
+	echo $CIDv1 > v1_exp &&
+	test_cmp v1_exp v1_actual &&
+	ipfs cid format -v 0 $CIDv1 > v0_actual &&
+	echo $CIDv0 > v0_exp &&
+	test_cmp v0_exp v0_actual
+'
+
+test_expect_success "cid base32 works" '
+	ipfs cid base32 $CIDv0 > base32_actual &&
+	test_cmp v1_exp base32_actual
+'
+
+test_expect_success "cid format reads cids from stdin" '
+	printf "%s\n%s\n" $CIDv0 $CIDv1 | ipfs cid format -f "%v" > stdin_actual &&
+	printf "cidv0\ncidv1\n" > stdin_exp &&
+	test_cmp stdin_exp stdin_actual
+'
+
+test_expect_success "cid format reports bad cids and goes on" '
+	test_must_fail ipfs cid format $CIDv0 notacid $CIDv1 > bad_actual 2> bad_err &&
+	printf "%s\n%s\n" $CIDv0 $CIDv1 > bad_exp &&
+	test_cmp bad_exp bad_actual &&
+	grep notacid bad_err
+'
+
+test_expect_success "cid bases, codecs and hashes work" '
+	ipfs cid bases | grep "^z  *base58btc" &&
+	ipfs cid codecs | grep "^112  *protobuf" &&
+	ipfs cid hashes | grep "^18  *sha2-256"
+'
+
+test_done
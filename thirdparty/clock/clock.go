@@ -0,0 +1,144 @@
+// Package clock abstracts the passing of time, so that periodic tasks can
+// be driven deterministically in tests.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and makes tickers.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, like a time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// New returns a clock backed by the time package.
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTicker) Stop() {
+	t.t.Stop()
+}
+
+// Mock is a clock that only moves forward when Add is called. Its tickers
+// fire as the time passes their deadlines. Like a time.Ticker, a mock ticker
+// drops ticks if its receiver is not keeping up.
+type Mock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	tickers []*mockTicker
+}
+
+// NewMock returns a mock clock set to now.
+func NewMock(now time.Time) *Mock {
+	m := &Mock{now: now}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// Now returns the current time of the clock.
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// NewTicker returns a ticker firing every d of mock time. It panics if d is
+// not positive.
+func (m *Mock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := &mockTicker{
+		m:    m,
+		c:    make(chan time.Time, 1),
+		d:    d,
+		next: m.now.Add(d),
+	}
+	m.tickers = append(m.tickers, t)
+	m.cond.Broadcast()
+	return t
+}
+
+// Add moves the clock forward by d, firing the tickers whose deadlines
+// have passed.
+func (m *Mock) Add(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = m.now.Add(d)
+	for _, t := range m.tickers {
+		for !t.next.After(m.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.d)
+		}
+	}
+}
+
+// BlockUntil waits until at least n tickers are running. It lets a test
+// make sure the goroutines it drives are ready before moving the clock.
+func (m *Mock) BlockUntil(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.tickers) < n {
+		m.cond.Wait()
+	}
+}
+
+type mockTicker struct {
+	m    *Mock
+	c    chan time.Time
+	d    time.Duration
+	next time.Time
+}
+
+func (t *mockTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *mockTicker) Stop() {
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+
+	for i, o := range t.m.tickers {
+		if o == t {
+			t.m.tickers = append(t.m.tickers[:i], t.m.tickers[i+1:]...)
+			break
+		}
+	}
+	t.m.cond.Broadcast()
+}
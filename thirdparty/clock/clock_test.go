@@ -0,0 +1,85 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMockTicker(t *testing.T) {
+	start := time.Unix(1000, 0)
+	m := NewMock(start)
+
+	tk := m.NewTicker(time.Minute)
+	defer tk.Stop()
+
+	m.Add(59 * time.Second)
+	select {
+	case <-tk.C():
+		t.Fatal("ticker fired early")
+	default:
+	}
+
+	m.Add(time.Second)
+	select {
+	case tick := <-tk.C():
+		if !tick.Equal(start.Add(time.Minute)) {
+			t.Fatalf("unexpected tick time %s", tick)
+		}
+	default:
+		t.Fatal("ticker did not fire")
+	}
+
+	// ticks are dropped when nobody is receiving
+	m.Add(10 * time.Minute)
+	if n := len(tk.C()); n != 1 {
+		t.Fatalf("expected a single pending tick, got %d", n)
+	}
+	<-tk.C()
+
+	if !m.Now().Equal(start.Add(11 * time.Minute)) {
+		t.Fatalf("unexpected time %s", m.Now())
+	}
+
+	m.Add(time.Minute)
+	if n := len(tk.C()); n != 1 {
+		t.Fatal("ticker should keep its schedule after dropping ticks")
+	}
+}
+
+func TestMockStop(t *testing.T) {
+	m := NewMock(time.Now())
+
+	tk := m.NewTicker(time.Second)
+	tk.Stop()
+
+	m.Add(time.Minute)
+	select {
+	case <-tk.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
+
+func TestMockBlockUntil(t *testing.T) {
+	m := NewMock(time.Now())
+
+	done := make(chan struct{})
+	go func() {
+		m.BlockUntil(2)
+		close(done)
+	}()
+
+	m.NewTicker(time.Second)
+	select {
+	case <-done:
+		t.Fatal("BlockUntil returned with a single ticker")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	m.NewTicker(time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("BlockUntil did not return")
+	}
+}
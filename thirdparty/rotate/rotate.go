@@ -0,0 +1,117 @@
+// Package rotate implements a log file that rotates itself when it grows
+// too large.
+package rotate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrClosed is returned when using a closed file.
+var ErrClosed = errors.New("file already closed")
+
+// File is an append-only file which, once it reaches MaxSize, is renamed
+// to path.1 (path.1 becoming path.2, and so on) and replaced by an empty
+// file. At most MaxFiles old files are kept. It is safe for concurrent use;
+// each Write ends up whole in a single file.
+type File struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// Open opens, or creates, the file at path. A maxSize of 0 disables
+// rotation.
+func Open(path string, maxSize int64, maxFiles int) (*File, error) {
+	r := &File{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *File) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f = f
+	r.size = fi.Size()
+	return nil
+}
+
+// Write appends p to the file, rotating it first if p would take it over
+// its maximum size.
+func (r *File) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return 0, ErrClosed
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *File) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+
+	if r.maxFiles > 0 {
+		os.Remove(r.name(r.maxFiles))
+		for i := r.maxFiles - 1; i > 0; i-- {
+			err := os.Rename(r.name(i), r.name(i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(r.path, r.name(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return err
+	}
+
+	return r.open()
+}
+
+func (r *File) name(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
+
+// Close closes the file.
+func (r *File) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return ErrClosed
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
@@ -0,0 +1,79 @@
+package rotate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "access.log")
+	f, err := Open(p, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n", "ffff\n", "gggg\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, exp := range map[string]string{
+		p:        "gggg\n",
+		p + ".1": "eeee\nffff\n",
+		p + ".2": "cccc\ndddd\n",
+	} {
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != exp {
+			t.Errorf("%s: expected %q, got %q", name, exp, b)
+		}
+	}
+	if _, err := os.Stat(p + ".3"); !os.IsNotExist(err) {
+		t.Fatal("kept too many files")
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "access.log")
+	if err := ioutil.WriteFile(p, []byte("123456789\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the existing content counts towards the size of the file
+	f, err := Open(p, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("x\n"))
+	f.Close()
+
+	b, err := ioutil.ReadFile(p + ".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "123456789\n" {
+		t.Fatalf("unexpected rotated content %q", b)
+	}
+
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Fatal("wrote to a closed file")
+	}
+}
@@ -10,9 +10,42 @@ import (
 	"strings"
 )
 
+// SymlinkMode selects which symlinks in the archive are created.
+type SymlinkMode int
+
+const (
+	// SymlinksSafe creates the symlinks that point inside the output
+	// directory, and leaves out the others.
+	SymlinksSafe SymlinkMode = iota
+	// SymlinksPreserve creates all symlinks as they are.
+	SymlinksPreserve
+	// SymlinksSkip creates no symlinks.
+	SymlinksSkip
+)
+
+// ParseSymlinkMode parses the name of a SymlinkMode: "safe", "preserve" or
+// "skip".
+func ParseSymlinkMode(s string) (SymlinkMode, error) {
+	switch s {
+	case "safe":
+		return SymlinksSafe, nil
+	case "preserve":
+		return SymlinksPreserve, nil
+	case "skip":
+		return SymlinksSkip, nil
+	default:
+		return 0, fmt.Errorf("unknown symlink mode %q, expected safe, preserve or skip", s)
+	}
+}
+
 type Extractor struct {
 	Path     string
 	Progress func(int64) int64
+	Symlinks SymlinkMode
+
+	// dirs holds the extracted directories, whose mode and modification
+	// time are set once their contents are in place
+	dirs []*tar.Header
 }
 
 func (te *Extractor) Extract(reader io.Reader) error {
@@ -51,48 +84,194 @@ func (te *Extractor) Extract(reader io.Reader) error {
 				return err
 			}
 		case tar.TypeSymlink:
-			if err := te.extractSymlink(header); err != nil {
+			if err := te.extractSymlink(header, i); err != nil {
 				return err
 			}
 		default:
 			return fmt.Errorf("unrecognized tar header type: %d", header.Typeflag)
 		}
 	}
+	return te.finishDirs()
+}
+
+// finishDirs sets the mode and modification time of the extracted
+// directories, innermost first so setting them is not undone by changes to
+// their contents.
+func (te *Extractor) finishDirs() error {
+	for i := len(te.dirs) - 1; i >= 0; i-- {
+		h := te.dirs[i]
+		path, err := te.outputPath(h.Name)
+		if err != nil {
+			return err
+		}
+
+		// directories are created with owner access so they can be filled,
+		// take it away again if it should not be there
+		if missing := 0700 &^ os.FileMode(h.Mode); missing != 0 {
+			fi, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Chmod(path, fi.Mode().Perm()&^missing); err != nil {
+				return err
+			}
+		}
+
+		if err := os.Chtimes(path, h.ModTime, h.ModTime); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // outputPath returns the path at whicht o place tarPath
-func (te *Extractor) outputPath(tarPath string) string {
+func (te *Extractor) outputPath(tarPath string) (string, error) {
 	elems := strings.Split(tarPath, "/") // break into elems
 	elems = elems[1:]                    // remove original root
 
 	path := fp.Join(elems...)     // join elems
 	path = fp.Join(te.Path, path) // rebase on extractor root
-	return path
+	if !within(te.Path, path) {
+		return "", fmt.Errorf("refusing to extract %q outside of %s", tarPath, te.Path)
+	}
+	if err := te.checkNoSymlinks(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// checkNoSymlinks returns an error if path, or one of its parents below the
+// output directory, is a symlink. Writing through a symlink created by the
+// archive could write outside of the output directory, as links are only
+// checked against the paths known when they are created.
+func (te *Extractor) checkNoSymlinks(path string) error {
+	for p := path; within(te.Path, p) && p != fp.Clean(te.Path); p = fp.Dir(p) {
+		fi, err := os.Lstat(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to extract %s through the symlink %s", path, p)
+		}
+	}
+	return nil
+}
+
+// resolveLink returns where target, the target of a symlink in dir, leads.
+// Its components are followed one by one, so that the symlinks already
+// extracted are followed before ".." is applied, like the system does.
+// Components that don't exist yet are taken as they are.
+func resolveLink(dir, target string) (string, error) {
+	cur, err := fp.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if cur, err = fp.EvalSymlinks(cur); err != nil {
+		return "", err
+	}
+
+	for _, c := range strings.Split(target, string(fp.Separator)) {
+		switch c {
+		case "", ".":
+			continue
+		case "..":
+			cur = fp.Dir(cur)
+			continue
+		}
+
+		next := fp.Join(cur, c)
+		fi, err := os.Lstat(next)
+		if err == nil && fi.Mode()&os.ModeSymlink != 0 {
+			if next, err = fp.EvalSymlinks(next); err != nil {
+				return "", err
+			}
+		} else if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+// within reports whether the path p is root or below it.
+func within(root, p string) bool {
+	rel, err := fp.Rel(root, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(fp.Separator))
 }
 
 func (te *Extractor) extractDir(h *tar.Header, depth int) error {
-	path := te.outputPath(h.Name)
+	path, err := te.outputPath(h.Name)
+	if err != nil {
+		return err
+	}
 
 	if depth == 0 {
 		// if this is the root root directory, use it as the output path for remaining files
 		te.Path = path
 	}
 
-	err := os.MkdirAll(path, 0755)
+	err = os.MkdirAll(path, os.FileMode(h.Mode)&0777|0700)
 	if err != nil {
 		return err
 	}
 
+	te.dirs = append(te.dirs, h)
 	return nil
 }
 
-func (te *Extractor) extractSymlink(h *tar.Header) error {
-	return os.Symlink(h.Linkname, te.outputPath(h.Name))
+func (te *Extractor) extractSymlink(h *tar.Header, depth int) error {
+	path, err := te.outputPath(h.Name)
+	if err != nil {
+		return err
+	}
+
+	switch te.Symlinks {
+	case SymlinksSkip:
+		return nil
+	case SymlinksSafe:
+		// a symlink extracted on its own may point next to itself
+		root := te.Path
+		if depth == 0 {
+			root = fp.Dir(path)
+		}
+
+		target := fp.FromSlash(h.Linkname)
+		if fp.IsAbs(target) || !within(root, fp.Join(fp.Dir(path), target)) {
+			return nil
+		}
+
+		// the target may go through symlinks already extracted, e.g.
+		// y/.. with y -> . is the parent of the link
+		resolved, err := resolveLink(fp.Dir(path), target)
+		if err != nil {
+			return nil
+		}
+		realRoot, err := fp.EvalSymlinks(root)
+		if err != nil {
+			return err
+		}
+		if realRoot, err = fp.Abs(realRoot); err != nil {
+			return err
+		}
+		if !within(realRoot, resolved) {
+			return nil
+		}
+	}
+
+	return os.Symlink(h.Linkname, path)
 }
 
 func (te *Extractor) extractFile(h *tar.Header, r *tar.Reader, depth int, rootExists bool, rootIsDir bool) error {
-	path := te.outputPath(h.Name)
+	path, err := te.outputPath(h.Name)
+	if err != nil {
+		return err
+	}
 
 	if depth == 0 { // if depth is 0, this is the only file (we aren't 'ipfs get'ing a directory)
 		if rootExists && rootIsDir {
@@ -106,18 +285,21 @@ func (te *Extractor) extractFile(h *tar.Header, r *tar.Reader, depth int, rootEx
 		} // else if old file exists, just overwrite it.
 	}
 
-	file, err := os.Create(path)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(h.Mode)&0777)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
 	err = copyWithProgress(file, r, te.Progress)
 	if err != nil {
+		file.Close()
 		return err
 	}
 
-	return nil
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(path, h.ModTime, h.ModTime)
 }
 
 func copyWithProgress(to io.Writer, from io.Reader, cb func(int64) int64) error {
@@ -0,0 +1,230 @@
+package tar
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	fp "path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func buildArchive(t *testing.T, headers ...*tar.Header) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	w := tar.NewWriter(buf)
+	for _, h := range headers {
+		if err := w.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestExtractSymlinkModes(t *testing.T) {
+	archive := func() *bytes.Buffer {
+		return buildArchive(t,
+			&tar.Header{Name: "root", Typeflag: tar.TypeDir, Mode: 0755},
+			&tar.Header{Name: "root/sub", Typeflag: tar.TypeDir, Mode: 0755},
+			&tar.Header{Name: "root/sub/inside", Typeflag: tar.TypeSymlink, Linkname: "../other"},
+			&tar.Header{Name: "root/sub/outside", Typeflag: tar.TypeSymlink, Linkname: "../../other"},
+			&tar.Header{Name: "root/abs", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+		)
+	}
+
+	cases := []struct {
+		mode    SymlinkMode
+		created map[string]bool
+	}{
+		{SymlinksSafe, map[string]bool{"sub/inside": true, "sub/outside": false, "abs": false}},
+		{SymlinksPreserve, map[string]bool{"sub/inside": true, "sub/outside": true, "abs": true}},
+		{SymlinksSkip, map[string]bool{"sub/inside": false, "sub/outside": false, "abs": false}},
+	}
+
+	for _, c := range cases {
+		dir, err := ioutil.TempDir("", "extractor")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		out := fp.Join(dir, "out")
+		te := &Extractor{Path: out, Progress: func(n int64) int64 { return n }, Symlinks: c.mode}
+		if err := te.Extract(archive()); err != nil {
+			t.Fatal(err)
+		}
+
+		for name, created := range c.created {
+			_, err := os.Lstat(fp.Join(out, fp.FromSlash(name)))
+			if created && err != nil {
+				t.Errorf("mode %d: expected %s to be created: %s", c.mode, name, err)
+			}
+			if !created && err == nil {
+				t.Errorf("mode %d: expected %s not to be created", c.mode, name)
+			}
+		}
+	}
+}
+
+func TestExtractRejectsEscapingPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extractor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := buildArchive(t,
+		&tar.Header{Name: "root", Typeflag: tar.TypeDir, Mode: 0755},
+		&tar.Header{Name: "root/../../escaped", Typeflag: tar.TypeDir, Mode: 0755},
+	)
+
+	te := &Extractor{Path: fp.Join(dir, "out"), Progress: func(n int64) int64 { return n }}
+	if err := te.Extract(archive); err == nil {
+		t.Fatal("expected an error for a path outside of the output directory")
+	}
+	if _, err := os.Stat(fp.Join(dir, "escaped")); err == nil {
+		t.Fatal("extracted a directory outside of the output directory")
+	}
+}
+
+func TestExtractChainedSymlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "extractor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// x -> y/.. looks like it points to the output directory, but y -> .
+	// makes it point to its parent
+	archive := buildArchive(t,
+		&tar.Header{Name: "root", Typeflag: tar.TypeDir, Mode: 0755},
+		&tar.Header{Name: "root/y", Typeflag: tar.TypeSymlink, Linkname: "."},
+		&tar.Header{Name: "root/x", Typeflag: tar.TypeSymlink, Linkname: "y/.."},
+		&tar.Header{Name: "root/x/evil", Typeflag: tar.TypeReg, Mode: 0644},
+	)
+
+	out := fp.Join(dir, "out")
+	te := &Extractor{Path: out, Progress: func(n int64) int64 { return n }, Symlinks: SymlinksSafe}
+	te.Extract(archive)
+
+	if _, err := os.Lstat(fp.Join(out, "y")); err != nil {
+		t.Fatalf("expected the link to the output directory to be created: %s", err)
+	}
+	if _, err := os.Lstat(fp.Join(out, "x")); err == nil {
+		t.Fatal("expected the link to the parent of the output directory not to be created")
+	}
+	if _, err := os.Lstat(fp.Join(dir, "evil")); err == nil {
+		t.Fatal("extracted a file outside of the output directory")
+	}
+}
+
+func TestExtractRefusesWritingThroughSymlinks(t *testing.T) {
+	for _, typ := range []byte{tar.TypeReg, tar.TypeDir} {
+		dir, err := ioutil.TempDir("", "extractor")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		// the link is preserved, and an entry of the same name tries to
+		// go through it
+		archive := buildArchive(t,
+			&tar.Header{Name: "root", Typeflag: tar.TypeDir, Mode: 0755},
+			&tar.Header{Name: "root/x", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			&tar.Header{Name: "root/x", Typeflag: tar.TypeDir, Mode: 0755},
+			&tar.Header{Name: "root/x/evil", Typeflag: typ, Mode: 0755},
+		)
+
+		out := fp.Join(dir, "out")
+		te := &Extractor{Path: out, Progress: func(n int64) int64 { return n }, Symlinks: SymlinksPreserve}
+		if err := te.Extract(archive); err == nil {
+			t.Fatal("expected an error for a path going through a symlink")
+		}
+		if _, err := os.Lstat(fp.Join(dir, "evil")); err == nil {
+			t.Fatal("extracted outside of the output directory")
+		}
+	}
+}
+
+func TestParseSymlinkMode(t *testing.T) {
+	for s, m := range map[string]SymlinkMode{"safe": SymlinksSafe, "preserve": SymlinksPreserve, "skip": SymlinksSkip} {
+		got, err := ParseSymlinkMode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != m {
+			t.Fatalf("ParseSymlinkMode(%q) = %d, expected %d", s, got, m)
+		}
+	}
+
+	if _, err := ParseSymlinkMode("follow"); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
+
+func TestExtractModeAndMtime(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions are not supported on windows")
+	}
+
+	dir, err := ioutil.TempDir("", "extractor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mtime := time.Unix(1500000000, 0)
+	data := []byte("#!/bin/sh\n")
+
+	buf := new(bytes.Buffer)
+	w := tar.NewWriter(buf)
+	headers := []*tar.Header{
+		{Name: "root", Typeflag: tar.TypeDir, Mode: 0755, ModTime: mtime},
+		{Name: "root/ro", Typeflag: tar.TypeDir, Mode: 0555, ModTime: mtime},
+		{Name: "root/ro/script", Typeflag: tar.TypeReg, Mode: 0700, ModTime: mtime, Size: int64(len(data))},
+	}
+	for _, h := range headers {
+		if err := w.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Size > 0 {
+			if _, err := w.Write(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := fp.Join(dir, "out")
+	te := &Extractor{Path: out, Progress: func(n int64) int64 { return n }}
+	if err := te.Extract(buf); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(fp.Join(out, "ro"), 0755)
+
+	for _, c := range []struct {
+		name string
+		mode os.FileMode
+	}{
+		{"", 0755},
+		{"ro", 0555},
+		{"ro/script", 0700},
+	} {
+		fi, err := os.Stat(fp.Join(out, fp.FromSlash(c.name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != c.mode {
+			t.Errorf("%s: expected mode %s, got %s", c.name, c.mode, fi.Mode().Perm())
+		}
+		if !fi.ModTime().Equal(mtime) {
+			t.Errorf("%s: expected mtime %s, got %s", c.name, mtime, fi.ModTime())
+		}
+	}
+}
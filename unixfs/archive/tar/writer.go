@@ -36,8 +36,8 @@ func NewWriter(ctx context.Context, dag mdag.DAGService, archive bool, compressi
 	}, nil
 }
 
-func (w *Writer) writeDir(nd *mdag.ProtoNode, fpath string) error {
-	if err := writeDirHeader(w.TarW, fpath); err != nil {
+func (w *Writer) writeDir(nd *mdag.ProtoNode, pb *upb.Data, fpath string) error {
+	if err := writeDirHeader(w.TarW, fpath, pb); err != nil {
 		return err
 	}
 
@@ -57,7 +57,7 @@ func (w *Writer) writeDir(nd *mdag.ProtoNode, fpath string) error {
 }
 
 func (w *Writer) writeFile(nd *mdag.ProtoNode, pb *upb.Data, fpath string) error {
-	if err := writeFileHeader(w.TarW, fpath, pb.GetFilesize()); err != nil {
+	if err := writeFileHeader(w.TarW, fpath, pb.GetFilesize(), pb); err != nil {
 		return err
 	}
 
@@ -81,7 +81,7 @@ func (w *Writer) WriteNode(nd node.Node, fpath string) error {
 		case upb.Data_Metadata:
 			fallthrough
 		case upb.Data_Directory:
-			return w.writeDir(nd, fpath)
+			return w.writeDir(nd, pb, fpath)
 		case upb.Data_Raw:
 			fallthrough
 		case upb.Data_File:
@@ -92,7 +92,7 @@ func (w *Writer) WriteNode(nd node.Node, fpath string) error {
 			return ft.ErrUnrecognizedType
 		}
 	case *mdag.RawNode:
-		if err := writeFileHeader(w.TarW, fpath, uint64(len(nd.RawData()))); err != nil {
+		if err := writeFileHeader(w.TarW, fpath, uint64(len(nd.RawData())), nil); err != nil {
 			return err
 		}
 
@@ -110,27 +110,43 @@ func (w *Writer) Close() error {
 	return w.TarW.Close()
 }
 
-func writeDirHeader(w *tar.Writer, fpath string) error {
+func writeDirHeader(w *tar.Writer, fpath string, pb *upb.Data) error {
+	mode, mtime := metadata(pb, 0777)
 	return w.WriteHeader(&tar.Header{
 		Name:     fpath,
 		Typeflag: tar.TypeDir,
-		Mode:     0777,
-		ModTime:  time.Now(),
-		// TODO: set mode, dates, etc. when added to unixFS
+		Mode:     mode,
+		ModTime:  mtime,
 	})
 }
 
-func writeFileHeader(w *tar.Writer, fpath string, size uint64) error {
+func writeFileHeader(w *tar.Writer, fpath string, size uint64, pb *upb.Data) error {
+	mode, mtime := metadata(pb, 0644)
 	return w.WriteHeader(&tar.Header{
 		Name:     fpath,
 		Size:     int64(size),
 		Typeflag: tar.TypeReg,
-		Mode:     0644,
-		ModTime:  time.Now(),
-		// TODO: set mode, dates, etc. when added to unixFS
+		Mode:     mode,
+		ModTime:  mtime,
 	})
 }
 
+// metadata returns the mode and modification time recorded in pb, falling
+// back to defaultMode and the current time. pb may be nil.
+func metadata(pb *upb.Data, defaultMode int64) (int64, time.Time) {
+	mode, mtime := defaultMode, time.Now()
+	if pb == nil {
+		return mode, mtime
+	}
+	if pb.Mode != nil {
+		mode = int64(pb.GetMode())
+	}
+	if t := ft.ModTime(pb); !t.IsZero() {
+		mtime = t
+	}
+	return mode, mtime
+}
+
 func writeSymlinkHeader(w *tar.Writer, target, fpath string) error {
 	return w.WriteHeader(&tar.Header{
 		Name:     fpath,
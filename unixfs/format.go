@@ -5,6 +5,8 @@ package unixfs
 
 import (
 	"errors"
+	"os"
+	"time"
 
 	dag "github.com/ipfs/go-ipfs/merkledag"
 	pb "github.com/ipfs/go-ipfs/unixfs/pb"
@@ -137,6 +139,14 @@ type FSNode struct {
 
 	// node type of this node
 	Type pb.Data_DataType
+
+	// Mode holds the permission bits recorded for the node, zero if none
+	// were recorded
+	Mode os.FileMode
+
+	// ModTime is the modification time recorded for the node, the zero
+	// time if none was recorded
+	ModTime time.Time
 }
 
 func FSNodeFromBytes(b []byte) (*FSNode, error) {
@@ -151,6 +161,8 @@ func FSNodeFromBytes(b []byte) (*FSNode, error) {
 	n.blocksizes = pbn.Blocksizes
 	n.subtotal = pbn.GetFilesize() - uint64(len(n.Data))
 	n.Type = pbn.GetType()
+	n.Mode = Mode(pbn)
+	n.ModTime = ModTime(pbn)
 	return n, nil
 }
 
@@ -171,6 +183,7 @@ func (n *FSNode) GetBytes() ([]byte, error) {
 	pbn.Filesize = proto.Uint64(uint64(len(n.Data)) + n.subtotal)
 	pbn.Blocksizes = n.blocksizes
 	pbn.Data = n.Data
+	SetMetadata(pbn, n.Mode, n.ModTime)
 	return proto.Marshal(pbn)
 }
 
@@ -182,6 +195,79 @@ func (n *FSNode) NumChildren() int {
 	return len(n.blocksizes)
 }
 
+// Mode returns the permission bits recorded in pbd, or zero if there are
+// none.
+func Mode(pbd *pb.Data) os.FileMode {
+	if pbd.Mode == nil {
+		return 0
+	}
+	return FileMode(*pbd.Mode)
+}
+
+// FileMode returns the unix mode m, the permission, setuid, setgid and
+// sticky bits, as an os.FileMode.
+func FileMode(m uint32) os.FileMode {
+	mode := os.FileMode(m & 0777)
+	if m&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if m&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if m&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// ModTime returns the modification time recorded in pbd, or the zero time if
+// there is none.
+func ModTime(pbd *pb.Data) time.Time {
+	if pbd.Mtime == nil {
+		return time.Time{}
+	}
+	return time.Unix(pbd.Mtime.GetSeconds(), int64(pbd.Mtime.GetFractionalNanoseconds()))
+}
+
+// SetMetadata records the permission bits of mode and the modification time
+// mtime in pbd. A zero mode or mtime clears the respective field.
+func SetMetadata(pbd *pb.Data, mode os.FileMode, mtime time.Time) {
+	pbd.Mode = nil
+	if mode != 0 {
+		m := uint32(mode.Perm())
+		if mode&os.ModeSetuid != 0 {
+			m |= 04000
+		}
+		if mode&os.ModeSetgid != 0 {
+			m |= 02000
+		}
+		if mode&os.ModeSticky != 0 {
+			m |= 01000
+		}
+		pbd.Mode = proto.Uint32(m)
+	}
+
+	pbd.Mtime = nil
+	if !mtime.IsZero() {
+		pbd.Mtime = &pb.UnixTime{Seconds: proto.Int64(mtime.Unix())}
+		if ns := mtime.Nanosecond(); ns != 0 {
+			pbd.Mtime.FractionalNanoseconds = proto.Uint32(uint32(ns))
+		}
+	}
+}
+
+// WithMetadata returns the unixfs data b with mode and mtime recorded in it,
+// as for SetMetadata.
+func WithMetadata(b []byte, mode os.FileMode, mtime time.Time) ([]byte, error) {
+	pbd, err := FromBytes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	SetMetadata(pbd, mode, mtime)
+	return proto.Marshal(pbd)
+}
+
 type Metadata struct {
 	MimeType string
 	Size     uint64
@@ -2,7 +2,9 @@ package unixfs
 
 import (
 	"bytes"
+	"os"
 	"testing"
+	"time"
 
 	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
 
@@ -159,3 +161,46 @@ func TestMetadata(t *testing.T) {
 	}
 
 }
+
+func TestFSNodeMetadata(t *testing.T) {
+	mtime := time.Unix(1500000000, 42)
+	fsn := &FSNode{Type: TFile, Data: []byte("data"), Mode: 0755 | os.ModeSetuid, ModTime: mtime}
+
+	b, err := fsn.GetBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pbn, err := FromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pbn.GetMode() != 04755 {
+		t.Fatalf("expected mode 04755, got %o", pbn.GetMode())
+	}
+
+	nfsn, err := FSNodeFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nfsn.Mode != fsn.Mode {
+		t.Fatalf("expected mode %s, got %s", fsn.Mode, nfsn.Mode)
+	}
+	if !nfsn.ModTime.Equal(mtime) {
+		t.Fatalf("expected mtime %s, got %s", mtime, nfsn.ModTime)
+	}
+
+	// clearing the metadata leaves the node as it would be without it
+	b, err = WithMetadata(b, 0, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsn.Mode, fsn.ModTime = 0, time.Time{}
+	plain, err := fsn.GetBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, plain) {
+		t.Fatal("expected cleared metadata to match a node without metadata")
+	}
+}
@@ -279,13 +279,13 @@ func (ds *HamtShard) loadChild(ctx context.Context, i int) (child, error) {
 		return nil, fmt.Errorf("invalid link name '%s'", lnk.Name)
 	}
 
-	nd, err := lnk.GetNode(ctx, ds.dserv)
-	if err != nil {
-		return nil, err
-	}
-
 	var c child
 	if len(lnk.Name) == ds.maxpadlen {
+		nd, err := lnk.GetNode(ctx, ds.dserv)
+		if err != nil {
+			return nil, err
+		}
+
 		pbnd, ok := nd.(*dag.ProtoNode)
 		if !ok {
 			return nil, dag.ErrNotProtobuf
@@ -307,6 +307,8 @@ func (ds *HamtShard) loadChild(ctx context.Context, i int) (child, error) {
 
 		c = cds
 	} else {
+		// values are links to the entries of the directory, there is no
+		// need to fetch them to enumerate or find entries
 		lnk2 := *lnk
 		c = &shardValue{
 			key: lnk.Name[ds.maxpadlen:],
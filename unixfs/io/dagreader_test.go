@@ -2,17 +2,23 @@ package io
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 
+	importer "github.com/ipfs/go-ipfs/importer"
+	chunk "github.com/ipfs/go-ipfs/importer/chunk"
 	mdag "github.com/ipfs/go-ipfs/merkledag"
 	"github.com/ipfs/go-ipfs/unixfs"
 
 	context "context"
 
 	testu "github.com/ipfs/go-ipfs/unixfs/test"
+
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 )
 
 func TestBasicRead(t *testing.T) {
@@ -158,6 +164,29 @@ func TestBadPBData(t *testing.T) {
 	}
 }
 
+func TestSeekMoreBlocksizesThanLinks(t *testing.T) {
+	dserv := testu.GetDAGServ()
+	ctx, closer := context.WithCancel(context.Background())
+	defer closer()
+
+	fsn := &unixfs.FSNode{Type: unixfs.TFile}
+	fsn.AddBlockSize(10)
+	fsn.AddBlockSize(10)
+	data, err := fsn.GetBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the node lists the sizes of two blocks, but links to none
+	reader, err := NewDagReader(ctx, mdag.NodeWithData(data), dserv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Seek(15, os.SEEK_SET); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
 func TestMetadataNode(t *testing.T) {
 	dserv := testu.GetDAGServ()
 	rdata, rnode := testu.GetRandomNode(t, dserv, 512)
@@ -252,3 +281,93 @@ func readByte(t testing.TB, reader DagReader) byte {
 
 	return out[0]
 }
+
+// countingDAGService counts the nodes requested with GetMany.
+type countingDAGService struct {
+	mdag.DAGService
+
+	mu      sync.Mutex
+	fetched int
+}
+
+func (ds *countingDAGService) GetMany(ctx context.Context, keys []*cid.Cid) <-chan *mdag.NodeOption {
+	ds.mu.Lock()
+	ds.fetched += len(keys)
+	ds.mu.Unlock()
+	return ds.DAGService.GetMany(ctx, keys)
+}
+
+func (ds *countingDAGService) count() int {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.fetched
+}
+
+func TestSeekFetchesOnlyNeededBlocks(t *testing.T) {
+	ds := &countingDAGService{DAGService: testu.GetDAGServ()}
+	inbuf := make([]byte, 100*100)
+	for i := range inbuf {
+		inbuf[i] = byte(i % 251)
+	}
+
+	// a single level of 100 leaves of 100 bytes
+	nd, err := importer.BuildDagFromReader(ds, chunk.NewSizeSplitter(bytes.NewReader(inbuf), 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pbn := nd.(*mdag.ProtoNode)
+	pb, err := unixfs.FromBytes(pbn.Data())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reader := newPBFileReader(ctx, pbn, pb, ds, 4)
+	if _, err := reader.Seek(0, os.SEEK_END); err != nil {
+		t.Fatal(err)
+	}
+	if n := ds.count(); n != 0 {
+		t.Fatalf("seeking to the end fetched %d blocks", n)
+	}
+
+	if _, err := reader.Seek(5050, os.SEEK_SET); err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, 100)
+	if _, err := io.ReadFull(reader, out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, inbuf[5050:5150]) {
+		t.Fatal("read wrong data after seek")
+	}
+
+	// the two blocks read, and the prefetch window after the first one
+	if n := ds.count(); n > 6 {
+		t.Fatalf("reading 100 bytes fetched %d blocks", n)
+	}
+
+	// seeking within the block being read does not fetch it again
+	before := ds.count()
+	if _, err := reader.Seek(5120, os.SEEK_SET); err != nil {
+		t.Fatal(err)
+	}
+	if ds.count() != before {
+		t.Fatal("seeking within the current block fetched blocks")
+	}
+
+	// reading on from anywhere still yields the right data
+	for _, off := range []int64{9990, 0, 4321, 99} {
+		if _, err := reader.Seek(off, os.SEEK_SET); err != nil {
+			t.Fatal(err)
+		}
+		rest, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rest, inbuf[off:]) {
+			t.Fatalf("read wrong data from offset %d", off)
+		}
+	}
+}
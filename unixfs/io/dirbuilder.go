@@ -2,8 +2,10 @@ package io
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	mdag "github.com/ipfs/go-ipfs/merkledag"
 	format "github.com/ipfs/go-ipfs/unixfs"
@@ -18,8 +20,17 @@ import (
 // result in the node being restructured into a sharded object.
 var ShardSplitThreshold = 1000
 
+// HAMTShardingSize is the estimated size, in bytes, above which a plain
+// directory is converted into a HAMT shard. A sharded directory that shrinks
+// back to half of it is converted back into a plain directory; the gap keeps
+// a directory hovering around the mark from being converted back and forth
+// on every change. This keeps huge directories from producing blocks larger
+// than what can be transferred. Setting it to zero disables automatic
+// sharding.
+var HAMTShardingSize = 256 * 1024
+
 // UseHAMTSharding is a global flag that signifies whether or not to use the
-// HAMT sharding scheme for directory creation
+// HAMT sharding scheme for all directories, regardless of their size
 var UseHAMTSharding = false
 
 // DefaultShardWidth is the default value used for hamt sharding width.
@@ -30,12 +41,29 @@ type Directory struct {
 	dirnode *mdag.ProtoNode
 
 	shard *hamt.HamtShard
+
+	prefix *cid.Prefix
+
+	// estimated size of the directory entries, -1 if unknown
+	size int
+
+	// sizeLowerBound is set when size is only a lower bound, see computeSize
+	sizeLowerBound bool
+
+	// shardingSize is the size above which the directory is sharded,
+	// HAMTShardingSize unless changed with SetShardingSize
+	shardingSize int
+
+	// metadata recorded in the directory node, see SetMetadata
+	mode  os.FileMode
+	mtime time.Time
 }
 
 // NewDirectory returns a Directory. It needs a DAGService to add the Children
 func NewDirectory(dserv mdag.DAGService) *Directory {
 	db := new(Directory)
 	db.dserv = dserv
+	db.shardingSize = HAMTShardingSize
 	if UseHAMTSharding {
 		s, err := hamt.NewHamtShard(dserv, DefaultShardWidth)
 		if err != nil {
@@ -64,19 +92,40 @@ func NewDirectoryFromNode(dserv mdag.DAGService, nd node.Node) (*Directory, erro
 
 	switch pbd.GetType() {
 	case format.TDirectory:
-		return &Directory{
+		d := &Directory{
 			dserv:   dserv,
 			dirnode: pbnd.Copy().(*mdag.ProtoNode),
-		}, nil
+			mode:    format.Mode(pbd),
+			mtime:   format.ModTime(pbd),
+
+			shardingSize: HAMTShardingSize,
+		}
+		prefix := d.dirnode.Prefix
+		d.prefix = &prefix
+		for _, l := range d.dirnode.Links() {
+			d.size += linkSize(l.Name, l.Cid)
+		}
+		return d, nil
 	case format.THAMTShard:
 		shard, err := hamt.NewHamtFromDag(dserv, nd)
 		if err != nil {
 			return nil, err
 		}
 
+		// keep the CID version and hash function of the shard, also for
+		// the plain directory it may be converted back into
+		prefix := nd.Cid().Prefix()
+		shard.SetPrefix(&prefix)
+
 		return &Directory{
-			dserv: dserv,
-			shard: shard,
+			dserv:  dserv,
+			shard:  shard,
+			prefix: &prefix,
+			size:   -1,
+			mode:   format.Mode(pbd),
+			mtime:  format.ModTime(pbd),
+
+			shardingSize: HAMTShardingSize,
 		}, nil
 	default:
 		return nil, ErrNotADir
@@ -85,6 +134,7 @@ func NewDirectoryFromNode(dserv mdag.DAGService, nd node.Node) (*Directory, erro
 
 // SetPrefix sets the prefix of the root node
 func (d *Directory) SetPrefix(prefix *cid.Prefix) {
+	d.prefix = prefix
 	if d.dirnode != nil {
 		d.dirnode.SetPrefix(prefix)
 	}
@@ -93,28 +143,96 @@ func (d *Directory) SetPrefix(prefix *cid.Prefix) {
 	}
 }
 
+// SetShardingSize sets the estimated size, in bytes, above which the
+// directory is converted into a HAMT shard. The directory is converted back
+// once it shrinks to half of it. Zero disables automatic sharding. It takes effect on the next change
+// of the directory.
+func (d *Directory) SetShardingSize(size int) {
+	d.shardingSize = size
+}
+
+// ShardingSize returns the size above which the directory is sharded
+func (d *Directory) ShardingSize() int {
+	return d.shardingSize
+}
+
+// SetMetadata records the permission bits mode and the modification time
+// mtime in the directory node. A zero mode or mtime clears the respective
+// field.
+func (d *Directory) SetMetadata(mode os.FileMode, mtime time.Time) error {
+	d.mode, d.mtime = mode, mtime
+	if d.dirnode != nil {
+		return d.applyMetadata(d.dirnode)
+	}
+	return nil
+}
+
+// Metadata returns the permission bits and modification time recorded for
+// the directory.
+func (d *Directory) Metadata() (os.FileMode, time.Time) {
+	return d.mode, d.mtime
+}
+
+func (d *Directory) applyMetadata(nd *mdag.ProtoNode) error {
+	data, err := format.WithMetadata(nd.Data(), d.mode, d.mtime)
+	if err != nil {
+		return err
+	}
+	nd.SetData(data)
+	return nil
+}
+
+// linkSize estimates how many bytes a directory entry takes up
+func linkSize(name string, c *cid.Cid) int {
+	return len(name) + len(c.Bytes())
+}
+
 // AddChild adds a (name, key)-pair to the root node.
 func (d *Directory) AddChild(ctx context.Context, name string, nd node.Node) error {
 	if d.shard == nil {
-		if !UseHAMTSharding {
-			_ = d.dirnode.RemoveNodeLink(name)
-			return d.dirnode.AddNodeLinkClean(name, nd)
+		if lnk, err := d.dirnode.GetNodeLink(name); err == nil {
+			d.size -= linkSize(name, lnk.Cid)
 		}
-
-		err := d.switchToSharding(ctx)
-		if err != nil {
+		_ = d.dirnode.RemoveNodeLink(name)
+		if err := d.dirnode.AddNodeLinkClean(name, nd); err != nil {
 			return err
 		}
+		d.size += linkSize(name, nd.Cid())
+
+		if !UseHAMTSharding && !d.overThreshold() {
+			return nil
+		}
+
+		return d.switchToSharding(ctx)
+	}
+
+	if d.size >= 0 {
+		if lnk, err := d.shard.Find(ctx, name); err == nil {
+			d.size -= linkSize(name, lnk.Cid)
+		}
+		d.size += linkSize(name, nd.Cid())
 	}
 
 	return d.shard.Set(ctx, name, nd)
 }
 
+// overThreshold returns whether the directory should be sharded
+func (d *Directory) overThreshold() bool {
+	return d.shardingSize > 0 && d.size > d.shardingSize
+}
+
+// underUnshardThreshold returns whether a sharded directory should be
+// converted back into a plain one, once it shrank to half the sharding size
+func (d *Directory) underUnshardThreshold() bool {
+	return d.size <= d.shardingSize/2
+}
+
 func (d *Directory) switchToSharding(ctx context.Context) error {
 	s, err := hamt.NewHamtShard(d.dserv, DefaultShardWidth)
 	if err != nil {
 		return err
 	}
+	s.SetPrefix(d.prefix)
 
 	d.shard = s
 	for _, lnk := range d.dirnode.Links() {
@@ -133,6 +251,55 @@ func (d *Directory) switchToSharding(ctx context.Context) error {
 	return nil
 }
 
+// switchToBasic converts a sharded directory back into a plain one
+func (d *Directory) switchToBasic(ctx context.Context) error {
+	dirnode := format.EmptyDirNode()
+	dirnode.SetPrefix(d.prefix)
+	if err := d.applyMetadata(dirnode); err != nil {
+		return err
+	}
+
+	err := d.shard.ForEachLink(ctx, func(lnk *node.Link) error {
+		return dirnode.AddRawLink(lnk.Name, lnk)
+	})
+	if err != nil {
+		return err
+	}
+
+	d.dirnode = dirnode
+	d.shard = nil
+	return nil
+}
+
+var errSizeBound = errors.New("size bound reached")
+
+// computeSize walks a sharded directory to estimate its size. The walk stops
+// once the size passes twice the sharding size, so that removing entries
+// from a huge shard doesn't walk all of it: size is then only a lower bound,
+// computed again when the removals bring it to the unsharding mark.
+func (d *Directory) computeSize(ctx context.Context) error {
+	bound := 2 * d.shardingSize
+	size := 0
+	err := d.shard.ForEachLink(ctx, func(lnk *node.Link) error {
+		size += linkSize(lnk.Name, lnk.Cid)
+		if size > bound {
+			return errSizeBound
+		}
+		return nil
+	})
+	switch err {
+	case nil:
+		d.sizeLowerBound = false
+	case errSizeBound:
+		d.sizeLowerBound = true
+	default:
+		return err
+	}
+
+	d.size = size
+	return nil
+}
+
 func (d *Directory) ForEachLink(ctx context.Context, f func(*node.Link) error) error {
 	if d.shard == nil {
 		for _, l := range d.dirnode.Links() {
@@ -178,10 +345,40 @@ func (d *Directory) Find(ctx context.Context, name string) (node.Node, error) {
 
 func (d *Directory) RemoveChild(ctx context.Context, name string) error {
 	if d.shard == nil {
+		if lnk, err := d.dirnode.GetNodeLink(name); err == nil {
+			d.size -= linkSize(name, lnk.Cid)
+		}
 		return d.dirnode.RemoveNodeLink(name)
 	}
 
-	return d.shard.Remove(ctx, name)
+	if UseHAMTSharding || d.shardingSize <= 0 {
+		return d.shard.Remove(ctx, name)
+	}
+
+	if d.size < 0 {
+		if err := d.computeSize(ctx); err != nil {
+			return err
+		}
+	}
+
+	if lnk, err := d.shard.Find(ctx, name); err == nil {
+		d.size -= linkSize(name, lnk.Cid)
+	}
+
+	if err := d.shard.Remove(ctx, name); err != nil {
+		return err
+	}
+
+	if d.underUnshardThreshold() && d.sizeLowerBound {
+		if err := d.computeSize(ctx); err != nil {
+			return err
+		}
+	}
+	if !d.underUnshardThreshold() {
+		return nil
+	}
+
+	return d.switchToBasic(ctx)
 }
 
 // GetNode returns the root of this Directory
@@ -190,5 +387,22 @@ func (d *Directory) GetNode() (node.Node, error) {
 		return d.dirnode, nil
 	}
 
-	return d.shard.Node()
+	nd, err := d.shard.Node()
+	if err != nil || (d.mode == 0 && d.mtime.IsZero()) {
+		return nd, err
+	}
+
+	// the shard builds its own node data, so record the metadata on a copy
+	pbnd, ok := nd.(*mdag.ProtoNode)
+	if !ok {
+		return nd, nil
+	}
+	pbnd = pbnd.Copy().(*mdag.ProtoNode)
+	if err := d.applyMetadata(pbnd); err != nil {
+		return nil, err
+	}
+	if _, err := d.dserv.Add(pbnd); err != nil {
+		return nil, err
+	}
+	return pbnd, nil
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	mdag "github.com/ipfs/go-ipfs/merkledag"
 	mdtest "github.com/ipfs/go-ipfs/merkledag/test"
 	ft "github.com/ipfs/go-ipfs/unixfs"
 )
@@ -156,3 +157,188 @@ func TestDirBuilder(t *testing.T) {
 		t.Fatal("wrong number of links", len(links), count)
 	}
 }
+
+func TestDirectoryAdaptiveSharding(t *testing.T) {
+	oldSize := HAMTShardingSize
+	defer func() { HAMTShardingSize = oldSize }()
+	HAMTShardingSize = 1000
+
+	ds := mdtest.Mock()
+	dir := NewDirectory(ds)
+	ctx := context.Background()
+
+	child := ft.EmptyDirNode()
+	_, err := ds.Add(child)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dir.AddChild(ctx, "keep", child)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	basic, err := dir.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	basicCid := basic.Cid()
+
+	count := 100
+	for i := 0; i < count; i++ {
+		err := dir.AddChild(ctx, fmt.Sprintf("entry %d", i), child)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if dir.shard == nil {
+		t.Fatal("expected directory to be sharded")
+	}
+
+	for i := 0; i < count; i++ {
+		err := dir.RemoveChild(ctx, fmt.Sprintf("entry %d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if dir.shard != nil {
+		t.Fatal("expected directory to be unsharded")
+	}
+
+	nd, err := dir.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !nd.Cid().Equals(basicCid) {
+		t.Fatalf("expected %s after unsharding, got %s", basicCid, nd.Cid())
+	}
+}
+
+func TestDirectoryUnshardKeepsPrefix(t *testing.T) {
+	oldSize := HAMTShardingSize
+	defer func() { HAMTShardingSize = oldSize }()
+	HAMTShardingSize = 1000
+
+	ds := mdtest.Mock()
+	ctx := context.Background()
+	prefix, err := mdag.PrefixForCidVersion(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := NewDirectory(ds)
+	dir.SetPrefix(&prefix)
+
+	child := ft.EmptyDirNode()
+	_, err = ds.Add(child)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 100
+	for i := 0; i < count; i++ {
+		err := dir.AddChild(ctx, fmt.Sprintf("entry %d", i), child)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nd, err := dir.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ds.Add(nd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// load the shard back, as MFS does
+	dir, err = NewDirectoryFromNode(ds, nd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir.shard == nil {
+		t.Fatal("expected directory to be sharded")
+	}
+
+	for i := 1; i < count; i++ {
+		err := dir.RemoveChild(ctx, fmt.Sprintf("entry %d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if dir.shard != nil {
+		t.Fatal("expected directory to be unsharded")
+	}
+
+	nd, err = dir.GetNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nd.Cid().Prefix().Version != 1 {
+		t.Fatalf("expected a CIDv1 directory after unsharding, got %s", nd.Cid())
+	}
+}
+
+func TestDirectoryUnshardHysteresis(t *testing.T) {
+	oldSize := HAMTShardingSize
+	defer func() { HAMTShardingSize = oldSize }()
+	HAMTShardingSize = 1000
+
+	ds := mdtest.Mock()
+	dir := NewDirectory(ds)
+	ctx := context.Background()
+
+	child := ft.EmptyDirNode()
+	_, err := ds.Add(child)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	for dir.shard == nil {
+		err := dir.AddChild(ctx, fmt.Sprintf("entry %d", count), child)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+
+	// removing entries right below the sharding size keeps the shard
+	err = dir.RemoveChild(ctx, fmt.Sprintf("entry %d", count-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	count--
+	if dir.shard == nil {
+		t.Fatal("expected directory to stay sharded right below the sharding size")
+	}
+
+	for dir.shard != nil {
+		if dir.size <= HAMTShardingSize/2 {
+			t.Fatalf("expected directory to be unsharded at size %d", dir.size)
+		}
+		count--
+		err := dir.RemoveChild(ctx, fmt.Sprintf("entry %d", count))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if dir.size > HAMTShardingSize/2 {
+		t.Fatalf("directory unsharded at size %d, above half the sharding size", dir.size)
+	}
+
+	// adding entries back doesn't shard it again before the sharding size
+	err = dir.AddChild(ctx, fmt.Sprintf("entry %d", count), child)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir.shard != nil {
+		t.Fatal("expected directory to stay unsharded below the sharding size")
+	}
+}
@@ -11,9 +11,14 @@ import (
 	ft "github.com/ipfs/go-ipfs/unixfs"
 	ftpb "github.com/ipfs/go-ipfs/unixfs/pb"
 
+	cid "gx/ipfs/QmYhQaCYEcaPPjxJX7YcPcVKkQfRy6sJ7B3XmGFk82XYdQ/go-cid"
 	proto "gx/ipfs/QmZ4Qi3GaRbjcx28Sme5eMH7RQjGkt8wHxt2a65oLaeFEV/gogo-protobuf/proto"
 )
 
+// DefaultPrefetchWindow is the number of child blocks a reader fetches
+// ahead of the one it is reading. Zero disables prefetching.
+var DefaultPrefetchWindow = 10
+
 // DagReader provides a way to easily read the data contained in a dag.
 type pbDagReader struct {
 	serv mdag.DAGService
@@ -28,12 +33,23 @@ type pbDagReader struct {
 	// will either be a bytes.Reader or a child DagReader
 	buf ReadSeekCloser
 
-	// NodeGetters for each of 'nodes' child links
+	// the child links of node, and the NodeGetters of the ones being
+	// fetched. Links are only fetched once the read head gets within
+	// 'prefetch' links of them, and forgotten once read.
+	links    []*cid.Cid
 	promises []mdag.NodeGetter
+	prefetch int
+
+	// context of the pending fetches, cancelled when seeking away from them
+	fetchCtx    context.Context
+	fetchCancel func()
 
-	// the index of the child link currently being read from
+	// the index of the next child link to read from
 	linkPosition int
 
+	// the index of the child link buf reads from, -1 for the data of node
+	bufLink int
+
 	// current offset for the read head within the 'file'
 	offset int64
 
@@ -46,32 +62,84 @@ type pbDagReader struct {
 
 var _ DagReader = (*pbDagReader)(nil)
 
+// NewPBFileReader returns a reader of the file n, which only fetches the
+// blocks around the read head, see DefaultPrefetchWindow.
 func NewPBFileReader(ctx context.Context, n *mdag.ProtoNode, pb *ftpb.Data, serv mdag.DAGService) *pbDagReader {
+	return newPBFileReader(ctx, n, pb, serv, DefaultPrefetchWindow)
+}
+
+func newPBFileReader(ctx context.Context, n *mdag.ProtoNode, pb *ftpb.Data, serv mdag.DAGService, prefetch int) *pbDagReader {
 	fctx, cancel := context.WithCancel(ctx)
-	promises := mdag.GetDAG(fctx, serv, n)
-	return &pbDagReader{
+	links := make([]*cid.Cid, len(n.Links()))
+	for i, l := range n.Links() {
+		links[i] = l.Cid
+	}
+	if prefetch < 0 {
+		prefetch = 0
+	}
+
+	dr := &pbDagReader{
 		node:     n,
 		serv:     serv,
 		buf:      NewBufDagReader(pb.GetData()),
-		promises: promises,
+		links:    links,
+		promises: make([]mdag.NodeGetter, len(links)),
+		prefetch: prefetch,
+		bufLink:  -1,
 		ctx:      fctx,
 		cancel:   cancel,
 		pbdata:   pb,
 	}
+	dr.fetchCtx, dr.fetchCancel = context.WithCancel(fctx)
+	return dr
+}
+
+// preload makes sure that the links from pos to the end of the prefetch
+// window are being fetched. The window is refilled once half of it has
+// been read, so that sequential reads do not request blocks one by one.
+func (dr *pbDagReader) preload(pos int) {
+	end := pos + dr.prefetch + 1
+	if end > len(dr.links) {
+		end = len(dr.links)
+	}
+
+	beg := pos
+	for beg < end && dr.promises[beg] != nil {
+		beg++
+	}
+	if beg == end || (beg > pos && end-beg < (dr.prefetch+1)/2) {
+		return
+	}
+
+	copy(dr.promises[beg:end], mdag.GetNodes(dr.fetchCtx, dr.serv, dr.links[beg:end]))
+}
+
+// resetFetches cancels all pending fetches.
+func (dr *pbDagReader) resetFetches() {
+	dr.fetchCancel()
+	for i := range dr.promises {
+		dr.promises[i] = nil
+	}
+	dr.fetchCtx, dr.fetchCancel = context.WithCancel(dr.ctx)
 }
 
 // precalcNextBuf follows the next link in line and loads it from the
 // DAGService, setting the next buffer to read from
 func (dr *pbDagReader) precalcNextBuf(ctx context.Context) error {
 	dr.buf.Close() // Just to make sure
-	if dr.linkPosition >= len(dr.promises) {
+	dr.bufLink = -1
+	if dr.linkPosition >= len(dr.links) {
 		return io.EOF
 	}
 
+	dr.preload(dr.linkPosition)
 	nxt, err := dr.promises[dr.linkPosition].Get(ctx)
+	// a promise only yields once, the link is fetched again if needed
+	dr.promises[dr.linkPosition] = nil
 	if err != nil {
 		return err
 	}
+	dr.bufLink = dr.linkPosition
 	dr.linkPosition++
 
 	switch nxt := nxt.(type) {
@@ -87,7 +155,7 @@ func (dr *pbDagReader) precalcNextBuf(ctx context.Context) error {
 			// A directory should not exist within a file
 			return ft.ErrInvalidDirLocation
 		case ftpb.Data_File:
-			dr.buf = NewPBFileReader(dr.ctx, nxt, pb, dr.serv)
+			dr.buf = newPBFileReader(dr.ctx, nxt, pb, dr.serv, dr.prefetch)
 			return nil
 		case ftpb.Data_Raw:
 			dr.buf = NewBufDagReader(pb.GetData())
@@ -181,8 +249,8 @@ func (dr *pbDagReader) Offset() int64 {
 
 // Seek implements io.Seeker, and will seek to a given offset in the file
 // interface matches standard unix seek
-// TODO: check if we can do relative seeks, to reduce the amount of dagreader
-// recreations that need to happen.
+// Only the block holding the new offset, and the ones in the prefetch window
+// after it, are fetched. Seeking within the block being read reuses it.
 func (dr *pbDagReader) Seek(offset int64, whence int) (int64, error) {
 	switch whence {
 	case os.SEEK_SET:
@@ -199,6 +267,7 @@ func (dr *pbDagReader) Seek(offset int64, whence int) (int64, error) {
 			// Close current buf to close potential child dagreader
 			dr.buf.Close()
 			dr.buf = NewBufDagReader(pb.GetData()[offset:])
+			dr.bufLink = -1
 
 			// start reading links from the beginning
 			dr.linkPosition = 0
@@ -210,19 +279,43 @@ func (dr *pbDagReader) Seek(offset int64, whence int) (int64, error) {
 		}
 
 		// iterate through links and find where we need to be
+		link := len(pb.Blocksizes)
 		for i := 0; i < len(pb.Blocksizes); i++ {
 			if pb.Blocksizes[i] > uint64(left) {
-				dr.linkPosition = i
+				link = i
 				break
 			} else {
 				left -= int64(pb.Blocksizes[i])
 			}
 		}
 
-		// start sub-block request
-		err := dr.precalcNextBuf(dr.ctx)
-		if err != nil {
-			return 0, err
+		if link == len(pb.Blocksizes) {
+			// at or past the end of the file, nothing to fetch
+			dr.buf.Close()
+			dr.buf = NewBufDagReader(nil)
+			dr.bufLink = -1
+			dr.linkPosition = len(dr.links)
+			dr.offset = offset
+			return offset, nil
+		}
+
+		if link >= len(dr.links) {
+			// malformed node, with more block sizes than links
+			return -1, io.EOF
+		}
+
+		if link != dr.bufLink {
+			// drop the fetches that the new position does not need
+			if dr.promises[link] == nil {
+				dr.resetFetches()
+			}
+
+			// start sub-block request
+			dr.linkPosition = link
+			err := dr.precalcNextBuf(dr.ctx)
+			if err != nil {
+				return 0, err
+			}
 		}
 
 		// set proper offset within child readseeker
@@ -239,7 +332,6 @@ func (dr *pbDagReader) Seek(offset int64, whence int) (int64, error) {
 		dr.offset = offset
 		return offset, nil
 	case os.SEEK_CUR:
-		// TODO: be smarter here
 		noffset := dr.offset + offset
 		return dr.Seek(noffset, os.SEEK_SET)
 	case os.SEEK_END:
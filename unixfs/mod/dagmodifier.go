@@ -247,6 +247,7 @@ func (dm *DagModifier) modifyDag(node *mdag.ProtoNode, offset uint64, data io.Re
 
 		nd := new(mdag.ProtoNode)
 		nd.SetData(b)
+		nd.SetPrefix(prefixOf(node))
 		k, err := dm.dagserv.Add(nd)
 		if err != nil {
 			return nil, false, err
@@ -304,11 +305,23 @@ func (dm *DagModifier) modifyDag(node *mdag.ProtoNode, offset uint64, data io.Re
 	return k, done, err
 }
 
+// prefixOf returns the CID prefix of n, or nil if it has none yet, so that
+// modified and appended nodes keep the hash function and CID version of the
+// file
+func prefixOf(n *mdag.ProtoNode) *cid.Prefix {
+	if n.Prefix.Codec == 0 {
+		return nil
+	}
+	prefix := n.Prefix
+	return &prefix
+}
+
 // appendData appends the blocks from the given chan to the end of this dag
 func (dm *DagModifier) appendData(node *mdag.ProtoNode, spl chunk.Splitter) (node.Node, error) {
 	dbp := &help.DagBuilderParams{
 		Dagserv:  dm.dagserv,
 		Maxlinks: help.DefaultLinksPerBlock,
+		Prefix:   prefixOf(node),
 	}
 
 	return trickle.TrickleAppend(dm.ctx, node, dbp.New(spl))